@@ -30,6 +30,7 @@ type ABI struct {
 	Constructor Method
 	Methods     map[string]Method
 	Events      map[string]Event
+	Errors      map[string]Error
 }
 
 // JSON returns a parsed ABI interface and error if it failed.
@@ -108,6 +109,7 @@ func (abi *ABI) UnmarshalJSON(data []byte) error {
 
 	abi.Methods = make(map[string]Method)
 	abi.Events = make(map[string]Event)
+	abi.Errors = make(map[string]Error)
 	for _, field := range fields {
 		switch field.Type {
 		case "constructor":
@@ -128,6 +130,11 @@ func (abi *ABI) UnmarshalJSON(data []byte) error {
 				Anonymous: field.Anonymous,
 				Inputs:    field.Inputs,
 			}
+		case "error":
+			abi.Errors[field.Name] = Error{
+				Name:   field.Name,
+				Inputs: field.Inputs,
+			}
 		}
 	}
 
@@ -144,3 +151,30 @@ func (abi *ABI) MethodById(sigdata []byte) (Method, error) {
 	}
 	return Method{}, fmt.Errorf("no method with id: %#x", sigdata[:4])
 }
+
+// ErrorById looks up a custom error by the 4-byte selector of revert data
+func (abi *ABI) ErrorById(sigdata []byte) (Error, error) {
+	if len(sigdata) < 4 {
+		return Error{}, fmt.Errorf("revert data too short (%d bytes) for error lookup", len(sigdata))
+	}
+	for name := range abi.Errors {
+		if bytes.Equal(abi.Errors[name].Id(), sigdata[:4]) {
+			return abi.Errors[name], nil
+		}
+	}
+	return Error{}, fmt.Errorf("no error with id: %#x", sigdata[:4])
+}
+
+// UnpackError decodes revert data into the custom error of the contract it
+// was raised with, returning the error along with its inputs.
+func (abi ABI) UnpackError(data []byte) (Error, []interface{}, error) {
+	e, err := abi.ErrorById(data)
+	if err != nil {
+		return Error{}, nil, err
+	}
+	values, err := e.Unpack(data)
+	if err != nil {
+		return Error{}, nil, err
+	}
+	return e, values, nil
+}
@@ -703,3 +703,55 @@ func TestABI_MethodById(t *testing.T) {
 	}
 
 }
+
+// Tests that custom errors are parsed and decoded from revert data.
+func TestCustomErrors(t *testing.T) {
+	const def = `[
+		{"type":"error","name":"Insufficient","inputs":[{"name":"available","type":"uint256"},{"name":"required","type":"uint256"}]},
+		{"type":"error","name":"Unauthorized","inputs":[]}
+	]`
+	abi, err := JSON(strings.NewReader(def))
+	if err != nil {
+		t.Fatal(err)
+	}
+	insufficient, ok := abi.Errors["Insufficient"]
+	if !ok {
+		t.Fatal("error not parsed")
+	}
+	if sig := insufficient.Sig(); sig != "Insufficient(uint256,uint256)" {
+		t.Errorf("unexpected signature %s", sig)
+	}
+	args, _ := insufficient.Inputs.Pack(big.NewInt(1), big.NewInt(2))
+	data := append(crypto.Keccak256([]byte("Insufficient(uint256,uint256)"))[:4], args...)
+
+	e, values, err := abi.UnpackError(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if e.Name != "Insufficient" || len(values) != 2 || values[0].(*big.Int).Int64() != 1 || values[1].(*big.Int).Int64() != 2 {
+		t.Errorf("unexpected error %s%v", e.Name, values)
+	}
+	if _, _, err := abi.UnpackError(common.Hex2Bytes("deadbeef")); err == nil {
+		t.Error("expected error for unknown selector")
+	}
+	if _, err := abi.Errors["Unauthorized"].Unpack(data); err == nil {
+		t.Error("expected error for mismatching selector")
+	}
+}
+
+func TestUnpackRevert(t *testing.T) {
+	data := common.Hex2Bytes("08c379a0" +
+		"0000000000000000000000000000000000000000000000000000000000000020" +
+		"000000000000000000000000000000000000000000000000000000000000000d" +
+		"72657665727420726561736f6e00000000000000000000000000000000000000")
+	reason, err := UnpackRevert(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if reason != "revert reason" {
+		t.Errorf("unexpected reason %q", reason)
+	}
+	if _, err := UnpackRevert(data[4:]); err == nil {
+		t.Error("expected error for missing selector")
+	}
+}
@@ -23,6 +23,7 @@ import (
 	"github.com/btcsuite/btcd/btcec/v2"
 
 	"gitlab.com/aquachain/aquachain/aqua/accounts/keystore"
+	"gitlab.com/aquachain/aquachain/aqua/accounts/typeddata"
 	"gitlab.com/aquachain/aquachain/common"
 	"gitlab.com/aquachain/aquachain/core/types"
 	"gitlab.com/aquachain/aquachain/crypto"
@@ -60,5 +61,11 @@ func NewKeyedTransactor(key *PrivateKey) *TransactOpts {
 			}
 			return tx.WithSignature(signer, signature)
 		},
+		SignTypedData: func(address common.Address, data *typeddata.TypedData) ([]byte, error) {
+			if address != keyAddr {
+				return nil, errors.New("not authorized to sign this account")
+			}
+			return typeddata.Sign(data, key)
+		},
 	}
 }
@@ -0,0 +1,70 @@
+// Copyright 2018 The aquachain Authors
+// This file is part of the aquachain library.
+//
+// The aquachain library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The aquachain library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the aquachain library. If not, see <http://www.gnu.org/licenses/>.
+
+package bind_test
+
+import (
+	"encoding/json"
+	"testing"
+
+	"gitlab.com/aquachain/aquachain/aqua/accounts/abi/bind"
+	"gitlab.com/aquachain/aquachain/aqua/accounts/typeddata"
+	"gitlab.com/aquachain/aquachain/common"
+)
+
+const permitJSON = `{
+	"types": {
+		"EIP712Domain": [
+			{"name": "name", "type": "string"},
+			{"name": "chainId", "type": "uint256"},
+			{"name": "verifyingContract", "type": "address"}
+		],
+		"Permit": [
+			{"name": "owner", "type": "address"},
+			{"name": "spender", "type": "address"},
+			{"name": "value", "type": "uint256"},
+			{"name": "nonce", "type": "uint256"},
+			{"name": "deadline", "type": "uint256"}
+		]
+	},
+	"primaryType": "Permit",
+	"domain": {"name": "Token", "chainId": 61717561, "verifyingContract": "0x0000000000000000000000000000000000000aaa"},
+	"message": {
+		"owner": "0x71562b71999873DB5b286dF957af199Ec94617F7",
+		"spender": "0x0000000000000000000000000000000000000bbb",
+		"value": "1000000000000000000",
+		"nonce": 0,
+		"deadline": "0xffffffff"
+	}
+}`
+
+func TestKeyedTransactorSignTypedData(t *testing.T) {
+	var data typeddata.TypedData
+	if err := json.Unmarshal([]byte(permitJSON), &data); err != nil {
+		t.Fatal(err)
+	}
+	opts := bind.NewKeyedTransactor(testKey)
+	sig, err := opts.SignTypedData(opts.From, &data)
+	if err != nil {
+		t.Fatalf("failed to sign: %v", err)
+	}
+	if signer, err := typeddata.Recover(&data, sig); err != nil || signer != opts.From {
+		t.Fatalf("signer mismatch: have %x (%v), want %x", signer, err, opts.From)
+	}
+	if _, err := opts.SignTypedData(common.Address{1}, &data); err == nil {
+		t.Fatalf("signed typed data for foreign account")
+	}
+}
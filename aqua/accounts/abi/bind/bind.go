@@ -24,6 +24,7 @@ import (
 	"bytes"
 	"fmt"
 	"regexp"
+	"sort"
 	"strings"
 	"text/template"
 	"unicode"
@@ -47,7 +48,10 @@ const (
 // manually maintain hard coded strings that break on runtime.
 func Bind(types []string, abis []string, bytecodes []string, pkg string, lang Lang) (string, error) {
 	// Process each individual contract requested binding
-	contracts := make(map[string]*tmplContract)
+	var (
+		contracts = make(map[string]*tmplContract)
+		structs   = make(map[string]*tmplStruct) // Tuple types shared by all contracts
+	)
 
 	for i := 0; i < len(types); i++ {
 		// Parse the actual ABI to generate the binding for
@@ -63,6 +67,10 @@ func Bind(types []string, abis []string, bytecodes []string, pkg string, lang La
 			return r
 		}, abis[i])
 
+		// Generate the structs of all the tuple types used by the contract
+		if lang == LangGo {
+			registerStructs(evmABI, structs)
+		}
 		// Extract the call and transact methods; events; and sort them alphabetically
 		var (
 			calls     = make(map[string]*tmplMethod)
@@ -131,12 +139,13 @@ func Bind(types []string, abis []string, bytecodes []string, pkg string, lang La
 	data := &tmplData{
 		Package:   pkg,
 		Contracts: contracts,
+		Structs:   structs,
 	}
 	buffer := new(bytes.Buffer)
 
 	funcs := map[string]interface{}{
-		"bindtype":      bindType[lang],
-		"bindtopictype": bindTopicType[lang],
+		"bindtype":      func(kind abi.Type) string { return bindType[lang](kind, structs) },
+		"bindtopictype": func(kind abi.Type) string { return bindTopicType[lang](kind, structs) },
 		"namedtype":     namedType[lang],
 		"capitalise":    capitalise,
 		"decapitalise":  decapitalise,
@@ -159,15 +168,29 @@ func Bind(types []string, abis []string, bytecodes []string, pkg string, lang La
 
 // bindType is a set of type binders that convert Solidity types to some supported
 // programming language types.
-var bindType = map[Lang]func(kind abi.Type) string{
+var bindType = map[Lang]func(kind abi.Type, structs map[string]*tmplStruct) string{
 	LangGo:   bindTypeGo,
-	LangJava: bindTypeJava,
+	LangJava: func(kind abi.Type, _ map[string]*tmplStruct) string { return bindTypeJava(kind) },
 }
 
 // bindTypeGo converts a Solidity type to a Go one. Since there is no clear mapping
 // from all Solidity types to Go ones (e.g. uint17), those that cannot be exactly
-// mapped will use an upscaled type (e.g. *big.Int).
-func bindTypeGo(kind abi.Type) string {
+// mapped will use an upscaled type (e.g. *big.Int). Tuples are bound to the
+// generated structs.
+func bindTypeGo(kind abi.Type, structs map[string]*tmplStruct) string {
+	switch {
+	case kind.T == abi.TupleTy:
+		return bindStructTypeGo(kind, structs)
+	case kind.T == abi.ArrayTy && hasTuple(kind):
+		return fmt.Sprintf("[%d]", kind.Size) + bindTypeGo(*kind.Elem, structs)
+	case kind.T == abi.SliceTy && hasTuple(kind):
+		return "[]" + bindTypeGo(*kind.Elem, structs)
+	}
+	return bindBasicTypeGo(kind)
+}
+
+// bindBasicTypeGo converts a Solidity type without tuples to a Go one.
+func bindBasicTypeGo(kind abi.Type) string {
 	stringKind := kind.String()
 
 	switch {
@@ -208,6 +231,81 @@ func bindTypeGo(kind abi.Type) string {
 	}
 }
 
+// bindStructTypeGo returns the name of the struct generated for a tuple type,
+// generating it along with the structs of nested tuples if not yet done.
+func bindStructTypeGo(kind abi.Type, structs map[string]*tmplStruct) string {
+	id := kind.TupleRawName + kind.String()
+	if s, exist := structs[id]; exist {
+		return s.Name
+	}
+	fields := make([]*tmplField, len(kind.TupleElems))
+	for i, elem := range kind.TupleElems {
+		fields[i] = &tmplField{
+			Type:    bindTypeGo(*elem, structs),
+			Name:    abi.ToCamelCase(kind.TupleRawNames[i]),
+			SolKind: *elem,
+		}
+	}
+	// Name the struct after the Solidity one, falling back to a numbered
+	// name for anonymous ones and for different structs of the same name
+	name := capitalise(kind.TupleRawName)
+	if name == "" || structNameTaken(structs, name) {
+		name = fmt.Sprintf("%sStruct%d", name, len(structs))
+	}
+	structs[id] = &tmplStruct{Name: name, Fields: fields}
+	return name
+}
+
+// structNameTaken checks whether a struct of the given name was generated.
+func structNameTaken(structs map[string]*tmplStruct, name string) bool {
+	for _, s := range structs {
+		if s.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// hasTuple checks whether an array or slice type has tuple elements.
+func hasTuple(kind abi.Type) bool {
+	for kind.Elem != nil {
+		kind = *kind.Elem
+	}
+	return kind.T == abi.TupleTy
+}
+
+// registerStructs generates the structs of all the tuple types used in the
+// arguments of a contract, before rendering its binding. Arguments are walked
+// in a fixed order to keep the numbered struct names stable.
+func registerStructs(evmABI abi.ABI, structs map[string]*tmplStruct) {
+	register := func(args abi.Arguments) {
+		for _, arg := range args {
+			bindTypeGo(arg.Type, structs)
+		}
+	}
+	register(evmABI.Constructor.Inputs)
+	for _, name := range sortedKeys(evmABI.Methods) {
+		register(evmABI.Methods[name].Inputs)
+		register(evmABI.Methods[name].Outputs)
+	}
+	for _, name := range sortedKeys(evmABI.Events) {
+		register(evmABI.Events[name].Inputs)
+	}
+	for _, name := range sortedKeys(evmABI.Errors) {
+		register(evmABI.Errors[name].Inputs)
+	}
+}
+
+// sortedKeys returns the keys of a map in alphabetical order.
+func sortedKeys[T any](m map[string]T) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
 // bindTypeJava converts a Solidity type to a Java one. Since there is no clear mapping
 // from all Solidity types to Java ones (e.g. uint17), those that cannot be exactly
 // mapped will use an upscaled type (e.g. BigDecimal).
@@ -281,15 +379,18 @@ func bindTypeJava(kind abi.Type) string {
 
 // bindTopicType is a set of type binders that convert Solidity types to some
 // supported programming language topic types.
-var bindTopicType = map[Lang]func(kind abi.Type) string{
+var bindTopicType = map[Lang]func(kind abi.Type, structs map[string]*tmplStruct) string{
 	LangGo:   bindTopicTypeGo,
-	LangJava: bindTopicTypeJava,
+	LangJava: func(kind abi.Type, _ map[string]*tmplStruct) string { return bindTopicTypeJava(kind) },
 }
 
 // bindTypeGo converts a Solidity topic type to a Go one. It is almost the same
 // funcionality as for simple types, but dynamic types get converted to hashes.
-func bindTopicTypeGo(kind abi.Type) string {
-	bound := bindTypeGo(kind)
+func bindTopicTypeGo(kind abi.Type, structs map[string]*tmplStruct) string {
+	if kind.T == abi.TupleTy || hasTuple(kind) {
+		return "common.Hash"
+	}
+	bound := bindTypeGo(kind, structs)
 	if bound == "string" || bound == "[]byte" {
 		bound = "common.Hash"
 	}
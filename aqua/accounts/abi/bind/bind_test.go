@@ -795,3 +795,33 @@ func TestBindings(t *testing.T) {
 		t.Fatalf("failed to run binding test: %v\n%s", err, out)
 	}
 }
+
+// Tests that tuple arguments are bound to generated structs, named after the
+// Solidity ones when known.
+func TestBindStructs(t *testing.T) {
+	const def = `[
+		{"constant":true,"name":"getPoint","inputs":[],"outputs":[{"name":"","type":"tuple","internalType":"struct Geometry.Point","components":[{"name":"x","type":"uint256"},{"name":"y","type":"uint256"}]}],"type":"function"},
+		{"constant":false,"name":"setPoints","inputs":[{"name":"points","type":"tuple[]","internalType":"struct Geometry.Point[]","components":[{"name":"x","type":"uint256"},{"name":"y","type":"uint256"}]}],"outputs":[],"type":"function"},
+		{"constant":false,"name":"setPair","inputs":[{"name":"pair","type":"tuple","components":[{"name":"key","type":"string"},{"name":"value","type":"bytes32"}]}],"outputs":[],"type":"function"},
+		{"anonymous":false,"name":"Moved","inputs":[{"indexed":true,"name":"from","type":"tuple","internalType":"struct Geometry.Point","components":[{"name":"x","type":"uint256"},{"name":"y","type":"uint256"}]},{"indexed":false,"name":"to","type":"tuple","internalType":"struct Geometry.Point","components":[{"name":"x","type":"uint256"},{"name":"y","type":"uint256"}]}],"type":"event"}
+	]`
+	code, err := Bind([]string{"Geometry"}, []string{def}, []string{""}, "bindtest", LangGo)
+	if err != nil {
+		t.Fatalf("failed to generate binding: %v", err)
+	}
+	for _, want := range []string{
+		"type Point struct {\n\tX *big.Int\n\tY *big.Int\n}",
+		"type Struct1 struct {\n\tKey   string\n\tValue [32]byte\n}",
+		") GetPoint(opts *bind.CallOpts) (Point, error)",
+		") SetPoints(opts *bind.TransactOpts, points []Point) (*types.Transaction, error)",
+		") SetPair(opts *bind.TransactOpts, pair Struct1) (*types.Transaction, error)",
+		"\tFrom common.Hash\n\tTo   Point\n",
+	} {
+		if !strings.Contains(code, want) {
+			t.Errorf("binding misses %q:\n%s", want, code)
+		}
+	}
+	if strings.Count(code, "type Point struct") != 1 {
+		t.Error("struct generated more than once")
+	}
+}
@@ -436,3 +436,71 @@ func TestPackNumber(t *testing.T) {
 		}
 	}
 }
+
+// Tests that tuples, including nested dynamic arrays of tuples, are packed and
+// unpacked according to the ABI specification.
+func TestPackTuple(t *testing.T) {
+	const def = `[{"name":"settle","type":"function","inputs":[
+		{"name":"point","type":"tuple","components":[{"name":"x","type":"uint256"},{"name":"y","type":"uint256"}]},
+		{"name":"order","type":"tuple","components":[
+			{"name":"amount","type":"uint256"},
+			{"name":"fills","type":"tuple[]","components":[{"name":"maker","type":"address"},{"name":"memo","type":"string"}]}
+		]}
+	],"outputs":[
+		{"name":"point","type":"tuple","components":[{"name":"x","type":"uint256"},{"name":"y","type":"uint256"}]},
+		{"name":"order","type":"tuple","components":[
+			{"name":"amount","type":"uint256"},
+			{"name":"fills","type":"tuple[]","components":[{"name":"maker","type":"address"},{"name":"memo","type":"string"}]}
+		]}
+	]}]`
+	abi, err := JSON(strings.NewReader(def))
+	if err != nil {
+		t.Fatal(err)
+	}
+	type Point struct {
+		X, Y *big.Int
+	}
+	type Fill struct {
+		Maker common.Address
+		Note  string `abi:"memo"`
+	}
+	type Order struct {
+		Amount *big.Int
+		Fills  []Fill
+	}
+	var (
+		maker = common.HexToAddress("0x0102030405060708090a0b0c0d0e0f1011121314")
+		point = Point{big.NewInt(1), big.NewInt(2)}
+		order = Order{big.NewInt(3), []Fill{{maker, "hi"}}}
+	)
+	if sig := abi.Methods["settle"].Sig(); sig != "settle((uint256,uint256),(uint256,(address,string)[]))" {
+		t.Errorf("unexpected signature %s", sig)
+	}
+	packed, err := abi.Methods["settle"].Inputs.Pack(point, order)
+	if err != nil {
+		t.Fatal(err)
+	}
+	word := func(n int) string { return common.Bytes2Hex(U256(big.NewInt(int64(n)))) }
+	want := word(1) + word(2) + word(0x60) + // static point in place, order by offset
+		word(3) + word(0x40) + // order: amount, offset of fills
+		word(1) + word(0x20) + // fills: length, offset of the first fill
+		common.Bytes2Hex(common.LeftPadBytes(maker.Bytes(), 32)) + word(0x40) + // fill: maker, offset of memo
+		word(2) + common.Bytes2Hex(common.RightPadBytes([]byte("hi"), 32))
+	if have := common.Bytes2Hex(packed); have != want {
+		t.Fatalf("pack mismatch:\nhave %s\nwant %s", have, want)
+	}
+
+	var out struct {
+		Point Point
+		Order Order
+	}
+	if err := abi.Unpack(&out, "settle", packed); err != nil {
+		t.Fatal(err)
+	}
+	if out.Point.X.Cmp(point.X) != 0 || out.Point.Y.Cmp(point.Y) != 0 {
+		t.Errorf("point mismatch: have %v, want %v", out.Point, point)
+	}
+	if out.Order.Amount.Cmp(order.Amount) != 0 || !reflect.DeepEqual(out.Order.Fills, order.Fills) {
+		t.Errorf("order mismatch: have %v, want %v", out.Order, order)
+	}
+}
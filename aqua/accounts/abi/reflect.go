@@ -19,6 +19,7 @@ package abi
 import (
 	"fmt"
 	"reflect"
+	"strings"
 )
 
 // indirect recursively dereferences the value until it either gets the value
@@ -80,12 +81,73 @@ func set(dst, src reflect.Value, output Argument) error {
 		dst.Set(src)
 	case dstType.Kind() == reflect.Ptr:
 		return set(dst.Elem(), src, output)
+	case dstType.Kind() == reflect.Struct && srcType.Kind() == reflect.Struct:
+		return setStruct(dst, src, output)
+	case dstType.Kind() == reflect.Slice && srcType.Kind() == reflect.Slice:
+		slice := reflect.MakeSlice(dstType, src.Len(), src.Len())
+		for i := 0; i < src.Len(); i++ {
+			if err := set(slice.Index(i), src.Index(i), output); err != nil {
+				return err
+			}
+		}
+		dst.Set(slice)
+	case dstType.Kind() == reflect.Array && srcType.Kind() == reflect.Array:
+		if dst.Len() != src.Len() {
+			return fmt.Errorf("abi: cannot unmarshal %v in to %v", src.Type(), dst.Type())
+		}
+		for i := 0; i < src.Len(); i++ {
+			if err := set(dst.Index(i), src.Index(i), output); err != nil {
+				return err
+			}
+		}
 	default:
 		return fmt.Errorf("abi: cannot unmarshal %v in to %v", src.Type(), dst.Type())
 	}
 	return nil
 }
 
+// setStruct assigns an unpacked tuple to a struct of the caller, field by
+// field, matching them by their raw ABI names.
+func setStruct(dst, src reflect.Value, output Argument) error {
+	srcType := src.Type()
+	for i := 0; i < srcType.NumField(); i++ {
+		name := srcType.Field(i).Tag.Get("abi")
+		field := structFieldByArgName(dst, name)
+		if !field.IsValid() {
+			return fmt.Errorf("abi: field %s for tuple not found in %v", name, dst.Type())
+		}
+		if err := set(field, src.Field(i), output); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// structFieldByArgName returns the field of a struct holding the argument of
+// the given raw name: the one tagged `+"`"+`abi:"name"`+"`"+`, or else the one named after
+// the camel cased argument. The returned value is invalid if there is none.
+func structFieldByArgName(v reflect.Value, name string) reflect.Value {
+	typ := v.Type()
+	for i := 0; i < typ.NumField(); i++ {
+		if tag, ok := typ.Field(i).Tag.Lookup("abi"); ok && tag == name {
+			return v.Field(i)
+		}
+	}
+	return v.FieldByName(ToCamelCase(name))
+}
+
+// ToCamelCase converts an under-score string to a camel-case string, the way
+// tuple fields are named in Go structs.
+func ToCamelCase(input string) string {
+	parts := strings.Split(input, "_")
+	for i, s := range parts {
+		if len(s) > 0 {
+			parts[i] = strings.ToUpper(s[:1]) + s[1:]
+		}
+	}
+	return strings.Join(parts, "")
+}
+
 // requireAssignable assures that `dest` is a pointer and it's not an interface.
 func requireAssignable(dst, src reflect.Value) error {
 	if dst.Kind() != reflect.Ptr && dst.Kind() != reflect.Interface {
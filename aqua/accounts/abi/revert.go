@@ -0,0 +1,80 @@
+// Copyright 2018 The aquachain Authors
+// This file is part of the aquachain library.
+//
+// The aquachain library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The aquachain library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the aquachain library. If not, see <http://www.gnu.org/licenses/>.
+
+package abi
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+
+	"gitlab.com/aquachain/aquachain/crypto"
+)
+
+// revertSelector is the selector of the builtin Error(string) error, used by
+// require and revert with a reason string.
+var revertSelector = crypto.Keccak256([]byte("Error(string)"))[:4]
+
+// Error is a custom error declared by a contract. Calls reverting with it
+// return its selector followed by its packed inputs as revert data.
+type Error struct {
+	Name   string
+	Inputs Arguments
+}
+
+// Sig returns the error string signature according to the ABI spec.
+func (e Error) Sig() string {
+	types := make([]string, len(e.Inputs))
+	for i, input := range e.Inputs {
+		types[i] = input.Type.String()
+	}
+	return fmt.Sprintf("%v(%v)", e.Name, strings.Join(types, ","))
+}
+
+func (e Error) String() string {
+	inputs := make([]string, len(e.Inputs))
+	for i, input := range e.Inputs {
+		inputs[i] = fmt.Sprintf("%v %v", input.Name, input.Type)
+	}
+	return fmt.Sprintf("error %v(%v)", e.Name, strings.Join(inputs, ", "))
+}
+
+// Id returns the 4 byte selector of the error.
+func (e Error) Id() []byte {
+	return crypto.Keccak256([]byte(e.Sig()))[:4]
+}
+
+// Unpack decodes the inputs of the error from revert data.
+func (e Error) Unpack(data []byte) ([]interface{}, error) {
+	if len(data) < 4 || !bytes.Equal(data[:4], e.Id()) {
+		return nil, fmt.Errorf("abi: revert data is not a %s error", e.Name)
+	}
+	return e.Inputs.UnpackValues(data[4:])
+}
+
+// UnpackRevert decodes the reason string of revert data returned by the
+// builtin Error(string) error.
+func UnpackRevert(data []byte) (string, error) {
+	if len(data) < 4 || !bytes.Equal(data[:4], revertSelector) {
+		return "", fmt.Errorf("abi: revert data is not an Error(string)")
+	}
+	typ, _ := NewType("string")
+	reason, err := (Arguments{{Type: typ}}).UnpackValues(data[4:])
+	if err != nil {
+		return "", err
+	}
+	return reason[0].(string), nil
+}
@@ -278,3 +278,41 @@ func TestTypeCheck(t *testing.T) {
 		}
 	}
 }
+
+// Tests that tuple types are built from their components, with the canonical
+// signature and struct name of the Solidity type.
+func TestTupleType(t *testing.T) {
+	var arg Argument
+	def := `{"name":"order","type":"tuple","internalType":"struct Exchange.Order","components":[
+		{"name":"amount","type":"uint256"},
+		{"name":"fills","type":"tuple[]","internalType":"struct Exchange.Fill[]","components":[
+			{"name":"maker","type":"address"},
+			{"name":"memo_text","type":"string"}
+		]}
+	]}`
+	if err := arg.UnmarshalJSON([]byte(def)); err != nil {
+		t.Fatal(err)
+	}
+	typ := arg.Type
+	if typ.T != TupleTy || typ.String() != "(uint256,(address,string)[])" {
+		t.Fatalf("unexpected type %v (%d)", typ, typ.T)
+	}
+	if typ.TupleRawName != "Order" || !reflect.DeepEqual(typ.TupleRawNames, []string{"amount", "fills"}) {
+		t.Errorf("unexpected names %q, %q", typ.TupleRawName, typ.TupleRawNames)
+	}
+	fills := typ.TupleElems[1]
+	if fills.T != SliceTy || fills.Elem.T != TupleTy || fills.Elem.TupleRawName != "Fill" {
+		t.Errorf("unexpected fills type %v", fills)
+	}
+	if field, ok := fills.Elem.Type.FieldByName("MemoText"); !ok || field.Type.Kind() != reflect.String {
+		t.Errorf("unexpected generated struct %v", fills.Elem.Type)
+	}
+	if !isDynamicType(typ) {
+		t.Error("tuple with a slice reported static")
+	}
+
+	// Fields must be named to be mapped to a struct
+	if _, err := NewType("tuple", ArgumentMarshaling{Name: "_", Type: "uint256"}); err == nil {
+		t.Error("expected error for anonymous tuple field")
+	}
+}
@@ -0,0 +1,85 @@
+// Copyright 2018 The aquachain Authors
+// This file is part of the aquachain library.
+//
+// The aquachain library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The aquachain library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the aquachain library. If not, see <http://www.gnu.org/licenses/>.
+
+// Package external implements an account backend forwarding all signing
+// requests to an external signer process over JSON-RPC.
+//
+// The signer is expected to serve the following methods in the "account"
+// namespace:
+//
+//	account_version                          returns the signer version
+//	account_list                             returns the managed addresses
+//	account_signTx(args SignTxArgs)          returns the signed raw transaction
+//	account_signHash(address, hash)          returns a [R || S || V] signature
+package external
+
+import (
+	"math/big"
+
+	"gitlab.com/aquachain/aquachain/common"
+	"gitlab.com/aquachain/aquachain/common/hexutil"
+	"gitlab.com/aquachain/aquachain/core/types"
+)
+
+// SignTxArgs is the transaction signing request sent to the external signer.
+type SignTxArgs struct {
+	From       common.Address    `json:"from"`
+	To         *common.Address   `json:"to"`
+	Gas        hexutil.Uint64    `json:"gas"`
+	GasPrice   hexutil.Big       `json:"gasPrice"`
+	Value      hexutil.Big       `json:"value"`
+	Nonce      hexutil.Uint64    `json:"nonce"`
+	Data       hexutil.Bytes     `json:"data"`
+	AccessList *types.AccessList `json:"accessList,omitempty"`
+	ChainID    *hexutil.Big      `json:"chainId,omitempty"`
+}
+
+// NewSignTxArgs assembles the signing request of the given unsigned transaction.
+func NewSignTxArgs(from common.Address, tx *types.Transaction, chainID *big.Int) *SignTxArgs {
+	args := &SignTxArgs{
+		From:     from,
+		To:       tx.To(),
+		Gas:      hexutil.Uint64(tx.Gas()),
+		GasPrice: hexutil.Big(*tx.GasPrice()),
+		Value:    hexutil.Big(*tx.Value()),
+		Nonce:    hexutil.Uint64(tx.Nonce()),
+		Data:     tx.Data(),
+	}
+	if tx.Type() == types.AccessListTxType {
+		accessList := tx.AccessList()
+		args.AccessList = &accessList
+	}
+	if chainID != nil {
+		args.ChainID = (*hexutil.Big)(chainID)
+	}
+	return args
+}
+
+// ToTransaction rebuilds the unsigned transaction described by the request.
+func (args *SignTxArgs) ToTransaction() *types.Transaction {
+	if args.AccessList != nil {
+		return types.NewAccessListTransaction(args.ChainID.ToInt(), uint64(args.Nonce), args.To, args.Value.ToInt(), uint64(args.Gas), args.GasPrice.ToInt(), args.Data, *args.AccessList)
+	}
+	if args.To == nil {
+		return types.NewContractCreation(uint64(args.Nonce), args.Value.ToInt(), uint64(args.Gas), args.GasPrice.ToInt(), args.Data)
+	}
+	return types.NewTransaction(uint64(args.Nonce), *args.To, args.Value.ToInt(), uint64(args.Gas), args.GasPrice.ToInt(), args.Data)
+}
+
+// Signer returns the transaction signer matching the chain of the request.
+func (args *SignTxArgs) Signer() types.Signer {
+	return types.LatestSignerForChainID(args.ChainID.ToInt())
+}
@@ -0,0 +1,238 @@
+// Copyright 2018 The aquachain Authors
+// This file is part of the aquachain library.
+//
+// The aquachain library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The aquachain library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the aquachain library. If not, see <http://www.gnu.org/licenses/>.
+
+package external
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"reflect"
+	"sync"
+	"time"
+
+	aquachain "gitlab.com/aquachain/aquachain"
+	"gitlab.com/aquachain/aquachain/aqua/accounts"
+	"gitlab.com/aquachain/aquachain/aqua/event"
+	"gitlab.com/aquachain/aquachain/common"
+	"gitlab.com/aquachain/aquachain/common/hexutil"
+	"gitlab.com/aquachain/aquachain/common/log"
+	"gitlab.com/aquachain/aquachain/core/types"
+	rpc "gitlab.com/aquachain/aquachain/rpc/rpcclient"
+)
+
+// Scheme is the URL scheme of the external signer wallet, its path being the
+// endpoint of the signer.
+const Scheme = "extapi"
+
+// BackendType is the reflect type of the external signer backend, which can be
+// used to retrieve it from an accounts.Manager.
+var BackendType = reflect.TypeOf(&ExternalBackend{})
+
+// requestTimeout is the time allowed for the external signer to answer a
+// single request, including any manual confirmation it may require.
+const requestTimeout = 5 * time.Minute
+
+// ExternalBackend implements accounts.Backend for a single external signer.
+type ExternalBackend struct {
+	signers []accounts.Wallet
+}
+
+// NewExternalBackend connects to the external signer listening at endpoint,
+// which may be an IPC path or an HTTP(S)/WS URL.
+func NewExternalBackend(endpoint string) (*ExternalBackend, error) {
+	signer, err := NewExternalSigner(endpoint)
+	if err != nil {
+		return nil, err
+	}
+	return &ExternalBackend{signers: []accounts.Wallet{signer}}, nil
+}
+
+// Wallets implements accounts.Backend, returning the external signer.
+func (eb *ExternalBackend) Wallets() []accounts.Wallet {
+	return eb.signers
+}
+
+// Subscribe implements accounts.Backend. The external signer is the only
+// wallet of the backend and never comes or goes, so no events are ever fired.
+func (eb *ExternalBackend) Subscribe(sink chan<- accounts.WalletEvent) event.Subscription {
+	return event.NewSubscription(func(quit <-chan struct{}) error {
+		<-quit
+		return nil
+	})
+}
+
+// ExternalSigner implements accounts.Wallet, forwarding all signing requests
+// to an external signer process. Keys never enter the node process, so the
+// wallet can neither derive accounts nor unlock them with a passphrase.
+type ExternalSigner struct {
+	client   *rpc.Client
+	endpoint string
+
+	cache []accounts.Account // Accounts last reported by the signer
+	lock  sync.RWMutex
+}
+
+// NewExternalSigner connects to the external signer listening at endpoint.
+func NewExternalSigner(endpoint string) (*ExternalSigner, error) {
+	client, err := rpc.Dial(endpoint)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to external signer: %v", err)
+	}
+	return newExternalSigner(client, endpoint), nil
+}
+
+// newExternalSigner creates a wallet forwarding its requests over client.
+func newExternalSigner(client *rpc.Client, endpoint string) *ExternalSigner {
+	return &ExternalSigner{client: client, endpoint: endpoint}
+}
+
+// URL implements accounts.Wallet, returning the endpoint of the signer.
+func (api *ExternalSigner) URL() accounts.URL {
+	return accounts.URL{Scheme: Scheme, Path: api.endpoint}
+}
+
+// Status implements accounts.Wallet, returning the version reported by the
+// signer, or an error if it can't be reached.
+func (api *ExternalSigner) Status() (string, error) {
+	var version string
+	if err := api.call(&version, "account_version"); err != nil {
+		return "Failed", err
+	}
+	return "Ok, version " + version, nil
+}
+
+// Open implements accounts.Wallet. The external signer manages its own keys,
+// so there is nothing to open.
+func (api *ExternalSigner) Open(passphrase string) error {
+	return nil
+}
+
+// Close implements accounts.Wallet. The connection is kept for the lifetime of
+// the node, so there is nothing to close.
+func (api *ExternalSigner) Close() error {
+	return nil
+}
+
+// Accounts implements accounts.Wallet, returning the accounts managed by the
+// signer. If the signer can't be reached, the last known accounts are returned.
+func (api *ExternalSigner) Accounts() []accounts.Account {
+	var addresses []common.Address
+	if err := api.call(&addresses, "account_list"); err != nil {
+		log.Warn("Failed to list external signer accounts", "endpoint", api.endpoint, "err", err)
+
+		api.lock.RLock()
+		defer api.lock.RUnlock()
+		return append([]accounts.Account(nil), api.cache...)
+	}
+	accts := make([]accounts.Account, len(addresses))
+	for i, addr := range addresses {
+		accts[i] = accounts.Account{Address: addr, URL: api.URL()}
+	}
+	api.lock.Lock()
+	api.cache = accts
+	api.lock.Unlock()
+
+	return append([]accounts.Account(nil), accts...)
+}
+
+// Contains implements accounts.Wallet, returning whether the signer manages
+// the given account.
+func (api *ExternalSigner) Contains(account accounts.Account) bool {
+	api.lock.RLock()
+	cached := api.cache
+	api.lock.RUnlock()
+
+	if cached == nil {
+		cached = api.Accounts()
+	}
+	for _, a := range cached {
+		if a.Address == account.Address && (account.URL == (accounts.URL{}) || account.URL == a.URL) {
+			return true
+		}
+	}
+	return false
+}
+
+// Derive implements accounts.Wallet, but is not supported by external signers.
+func (api *ExternalSigner) Derive(path accounts.DerivationPath, pin bool) (accounts.Account, error) {
+	return accounts.Account{}, accounts.ErrNotSupported
+}
+
+// SelfDerive implements accounts.Wallet, but is a noop for external signers.
+func (api *ExternalSigner) SelfDerive(base accounts.DerivationPath, chain aquachain.ChainStateReader) {
+}
+
+// SignHash implements accounts.Wallet, requesting the signer to sign the given
+// hash with the key of the account.
+func (api *ExternalSigner) SignHash(account accounts.Account, hash []byte) ([]byte, error) {
+	var sig hexutil.Bytes
+	if err := api.call(&sig, "account_signHash", account.Address, hexutil.Bytes(hash)); err != nil {
+		return nil, err
+	}
+	if len(sig) != 65 {
+		return nil, fmt.Errorf("external signer returned invalid signature length %d", len(sig))
+	}
+	return sig, nil
+}
+
+// SignTx implements accounts.Wallet, requesting the signer to sign the given
+// transaction with the key of the account. The returned transaction is checked
+// to be the requested one, signed by the requested account.
+func (api *ExternalSigner) SignTx(account accounts.Account, tx *types.Transaction, chainID *big.Int) (*types.Transaction, error) {
+	args := NewSignTxArgs(account.Address, tx, chainID)
+
+	var raw hexutil.Bytes
+	if err := api.call(&raw, "account_signTx", args); err != nil {
+		return nil, err
+	}
+	signed := new(types.Transaction)
+	if err := signed.UnmarshalBinary(raw); err != nil {
+		return nil, fmt.Errorf("external signer returned invalid transaction: %v", err)
+	}
+	signer := args.Signer()
+	if signer.Hash(signed) != signer.Hash(tx) {
+		return nil, errors.New("external signer returned a different transaction")
+	}
+	from, err := types.Sender(signer, signed)
+	if err != nil {
+		return nil, fmt.Errorf("external signer returned invalid signature: %v", err)
+	}
+	if from != account.Address {
+		return nil, fmt.Errorf("external signer signed with %x instead of %x", from, account.Address)
+	}
+	return signed, nil
+}
+
+// SignHashWithPassphrase implements accounts.Wallet, but is not supported as
+// the external signer handles its own authorization.
+func (api *ExternalSigner) SignHashWithPassphrase(account accounts.Account, passphrase string, hash []byte) ([]byte, error) {
+	return nil, accounts.ErrNotSupported
+}
+
+// SignTxWithPassphrase implements accounts.Wallet, but is not supported as the
+// external signer handles its own authorization.
+func (api *ExternalSigner) SignTxWithPassphrase(account accounts.Account, passphrase string, tx *types.Transaction, chainID *big.Int) (*types.Transaction, error) {
+	return nil, accounts.ErrNotSupported
+}
+
+// call invokes the given method on the signer, bounded by requestTimeout.
+func (api *ExternalSigner) call(result interface{}, method string, args ...interface{}) error {
+	ctx, cancel := context.WithTimeout(context.Background(), requestTimeout)
+	defer cancel()
+	return api.client.CallContext(ctx, result, method, args...)
+}
@@ -0,0 +1,155 @@
+// Copyright 2018 The aquachain Authors
+// This file is part of the aquachain library.
+//
+// The aquachain library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The aquachain library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the aquachain library. If not, see <http://www.gnu.org/licenses/>.
+
+package external
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"math/big"
+	"testing"
+
+	"github.com/btcsuite/btcd/btcec/v2"
+	"gitlab.com/aquachain/aquachain/aqua/accounts"
+	"gitlab.com/aquachain/aquachain/common"
+	"gitlab.com/aquachain/aquachain/common/hexutil"
+	"gitlab.com/aquachain/aquachain/core/types"
+	"gitlab.com/aquachain/aquachain/crypto"
+	"gitlab.com/aquachain/aquachain/rpc"
+	rpcclient "gitlab.com/aquachain/aquachain/rpc/rpcclient"
+)
+
+// MockSigner is a minimal external signer holding a single key.
+type MockSigner struct {
+	key    *btcec.PrivateKey
+	tamper bool // Whether to alter the transaction before signing it
+}
+
+func (s *MockSigner) Version() string { return "1.0.0" }
+
+func (s *MockSigner) List() []common.Address {
+	return []common.Address{crypto.PubkeyToAddress(s.key.PubKey())}
+}
+
+func (s *MockSigner) SignTx(args SignTxArgs) (hexutil.Bytes, error) {
+	if s.tamper {
+		args.Value = hexutil.Big(*big.NewInt(1000))
+	}
+	tx, err := types.SignTx(args.ToTransaction(), args.Signer(), s.key)
+	if err != nil {
+		return nil, err
+	}
+	return tx.MarshalBinary()
+}
+
+func (s *MockSigner) SignHash(addr common.Address, hash hexutil.Bytes) (hexutil.Bytes, error) {
+	if addr != s.List()[0] {
+		return nil, errors.New("unknown account")
+	}
+	return crypto.Sign(hash, s.key)
+}
+
+func newTestWallet(t *testing.T, signer *MockSigner) *ExternalSigner {
+	srv := rpc.NewServer()
+	if _, err := srv.RegisterName("account", signer); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(srv.Stop)
+	client := rpcclient.DialInProc(context.Background(), srv)
+	t.Cleanup(client.Close)
+	return newExternalSigner(client, "inproc")
+}
+
+func TestExternalSigner(t *testing.T) {
+	key, _ := crypto.GenerateKey()
+	signer := &MockSigner{key: key}
+	wallet := newTestWallet(t, signer)
+	addr := signer.List()[0]
+
+	if status, err := wallet.Status(); err != nil || status != "Ok, version 1.0.0" {
+		t.Fatalf("status mismatch: have %q (%v)", status, err)
+	}
+	accts := wallet.Accounts()
+	if len(accts) != 1 || accts[0].Address != addr {
+		t.Fatalf("accounts mismatch: have %v, want %x", accts, addr)
+	}
+	account := accounts.Account{Address: addr}
+	if !wallet.Contains(account) {
+		t.Fatalf("wallet does not contain %x", addr)
+	}
+	// Sign a legacy and an access list transaction and check the senders
+	to := common.HexToAddress("0x01")
+	chainID := big.NewInt(61717561)
+	txs := []*types.Transaction{
+		types.NewTransaction(1, to, big.NewInt(10), 21000, big.NewInt(1), nil),
+		types.NewContractCreation(2, big.NewInt(0), 100000, big.NewInt(1), []byte{0x60, 0x00}),
+		types.NewAccessListTransaction(chainID, 3, &to, big.NewInt(10), 30000, big.NewInt(1), nil, types.AccessList{{Address: to, StorageKeys: []common.Hash{{0x01}}}}),
+	}
+	for i, tx := range txs {
+		signed, err := wallet.SignTx(account, tx, chainID)
+		if err != nil {
+			t.Fatalf("tx %d: failed to sign: %v", i, err)
+		}
+		from, err := types.Sender(types.LatestSignerForChainID(chainID), signed)
+		if err != nil || from != addr {
+			t.Fatalf("tx %d: sender mismatch: have %x (%v), want %x", i, from, err, addr)
+		}
+		if signed.Hash() == tx.Hash() || signed.Nonce() != tx.Nonce() || signed.Type() != tx.Type() {
+			t.Fatalf("tx %d: signed transaction mismatch", i)
+		}
+	}
+	// Sign a hash and recover the signer
+	hash := crypto.Keccak256([]byte("hello"))
+	sig, err := wallet.SignHash(account, hash)
+	if err != nil {
+		t.Fatalf("failed to sign hash: %v", err)
+	}
+	pub, err := crypto.SigToPub(hash, sig)
+	if err != nil || crypto.PubkeyToAddress(pub) != addr {
+		t.Fatalf("hash signer mismatch: %v", err)
+	}
+	if _, err := wallet.SignHash(accounts.Account{Address: to}, hash); err == nil {
+		t.Fatalf("signed hash with unknown account")
+	}
+	// Unsupported operations must be refused
+	if _, err := wallet.SignTxWithPassphrase(account, "", txs[0], chainID); err != accounts.ErrNotSupported {
+		t.Fatalf("passphrase signing error mismatch: have %v, want %v", err, accounts.ErrNotSupported)
+	}
+	if _, err := wallet.Derive(accounts.DefaultBaseDerivationPath, true); err != accounts.ErrNotSupported {
+		t.Fatalf("derive error mismatch: have %v, want %v", err, accounts.ErrNotSupported)
+	}
+}
+
+func TestExternalSignerTampered(t *testing.T) {
+	key, _ := crypto.GenerateKey()
+	signer := &MockSigner{key: key, tamper: true}
+	wallet := newTestWallet(t, signer)
+
+	tx := types.NewTransaction(1, common.HexToAddress("0x01"), big.NewInt(10), 21000, big.NewInt(1), nil)
+	if _, err := wallet.SignTx(accounts.Account{Address: signer.List()[0]}, tx, big.NewInt(1)); err == nil {
+		t.Fatalf("accepted tampered transaction")
+	}
+}
+
+func TestSignTxArgsRoundtrip(t *testing.T) {
+	to := common.HexToAddress("0x02")
+	tx := types.NewTransaction(7, to, big.NewInt(5), 21000, big.NewInt(3), []byte{0xde, 0xad})
+	args := NewSignTxArgs(common.Address{}, tx, big.NewInt(1))
+	if rebuilt := args.ToTransaction(); rebuilt.Hash() != tx.Hash() || !bytes.Equal(rebuilt.Data(), tx.Data()) {
+		t.Fatalf("rebuilt transaction mismatch")
+	}
+}
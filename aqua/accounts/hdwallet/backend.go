@@ -0,0 +1,195 @@
+// Copyright 2018 The aquachain Authors
+// This file is part of the aquachain library.
+//
+// The aquachain library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The aquachain library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the aquachain library. If not, see <http://www.gnu.org/licenses/>.
+
+package hdwallet
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+
+	"gitlab.com/aquachain/aquachain/aqua/accounts"
+	"gitlab.com/aquachain/aquachain/aqua/accounts/keystore"
+	"gitlab.com/aquachain/aquachain/aqua/event"
+	"gitlab.com/aquachain/aquachain/common"
+	"gitlab.com/aquachain/aquachain/common/log"
+	"gitlab.com/aquachain/aquachain/crypto/mnemonics"
+)
+
+// Scheme is the URL scheme of HD wallets, their path being the wallet file.
+const Scheme = "hd"
+
+// BackendType is the reflect type of the HD wallet backend, which can be used
+// to retrieve it from an accounts.Manager.
+var BackendType = reflect.TypeOf(&Backend{})
+
+// walletFileSuffix is the extension of the wallet files in the backend directory.
+const walletFileSuffix = ".json"
+
+// Backend implements accounts.Backend for HD wallets derived from BIP-39
+// mnemonics, each stored encrypted in its own file within a directory.
+type Backend struct {
+	dir              string // Directory holding the wallet files
+	scryptN, scryptP int    // Scrypt parameters used to encrypt new wallets
+
+	wallets []*wallet // Wallets currently known, sorted by URL
+
+	updateFeed  event.Feed              // Event feed to notify wallet additions/removals
+	updateScope event.SubscriptionScope // Subscription scope tracking current live listeners
+
+	lock sync.Mutex
+}
+
+// NewBackend creates an HD wallet backend storing its wallets in dir, encrypting
+// the seeds of newly imported wallets with the given scrypt parameters.
+func NewBackend(dir string, scryptN, scryptP int) *Backend {
+	b := &Backend{dir: dir, scryptN: scryptN, scryptP: scryptP}
+	b.refreshWallets()
+	return b
+}
+
+// Wallets implements accounts.Backend, returning all HD wallets found in the
+// backend directory.
+func (b *Backend) Wallets() []accounts.Wallet {
+	b.refreshWallets()
+
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	cpy := make([]accounts.Wallet, len(b.wallets))
+	for i, wallet := range b.wallets {
+		cpy[i] = wallet
+	}
+	return cpy
+}
+
+// Subscribe implements accounts.Backend, creating an async subscription to
+// receive notifications on the addition or removal of HD wallets.
+func (b *Backend) Subscribe(sink chan<- accounts.WalletEvent) event.Subscription {
+	return b.updateScope.Track(b.updateFeed.Subscribe(sink))
+}
+
+// refreshWallets rescans the backend directory, loading any new wallet files
+// and dropping the ones that disappeared, then fires the matching events.
+func (b *Backend) refreshWallets() {
+	files, err := os.ReadDir(b.dir)
+	if err != nil && !os.IsNotExist(err) {
+		log.Warn("Failed to read HD wallet directory", "dir", b.dir, "err", err)
+		return
+	}
+	present := make(map[string]bool, len(files))
+	for _, file := range files {
+		name := file.Name()
+		if file.IsDir() || strings.HasPrefix(name, ".") || !strings.HasSuffix(name, walletFileSuffix) {
+			continue
+		}
+		present[filepath.Join(b.dir, name)] = true
+	}
+
+	b.lock.Lock()
+	var (
+		wallets = make([]*wallet, 0, len(present))
+		events  []accounts.WalletEvent
+	)
+	for _, wallet := range b.wallets {
+		if present[wallet.url.Path] {
+			wallets = append(wallets, wallet)
+			delete(present, wallet.url.Path)
+			continue
+		}
+		events = append(events, accounts.WalletEvent{Wallet: wallet, Kind: accounts.WalletDropped})
+	}
+	for path := range present {
+		wallet, err := loadWallet(b, path)
+		if err != nil {
+			log.Warn("Failed to load HD wallet", "path", path, "err", err)
+			continue
+		}
+		wallets = append(wallets, wallet)
+		events = append(events, accounts.WalletEvent{Wallet: wallet, Kind: accounts.WalletArrived})
+	}
+	sort.Slice(wallets, func(i, j int) bool { return wallets[i].url.Cmp(wallets[j].url) < 0 })
+	b.wallets = wallets
+	b.lock.Unlock()
+
+	for _, event := range events {
+		b.updateFeed.Send(event)
+	}
+}
+
+// Import creates a new HD wallet from the given BIP-39 mnemonic and optional
+// BIP-39 passphrase, encrypting its seed with passphrase. The account at the
+// default base derivation path is pinned into the new wallet and returned.
+func (b *Backend) Import(mnemonic, bip39Passphrase, passphrase string) (accounts.Wallet, accounts.Account, error) {
+	mnemonic = strings.Join(strings.Fields(mnemonic), " ")
+	seed, err := mnemonics.NewSeedWithErrorChecking(mnemonic, bip39Passphrase)
+	if err != nil {
+		return nil, accounts.Account{}, err
+	}
+	defer func() {
+		for i := range seed {
+			seed[i] = 0
+		}
+	}()
+	master, err := newMasterKey(seed)
+	if err != nil {
+		return nil, accounts.Account{}, err
+	}
+	defer master.zero()
+
+	key, err := master.derive(accounts.DefaultBaseDerivationPath)
+	if err != nil {
+		return nil, accounts.Account{}, err
+	}
+	account := accounts.Account{Address: key.address()}
+	key.zero()
+
+	encrypted, err := keystore.EncryptDataV3(seed, []byte(passphrase), b.scryptN, b.scryptP)
+	if err != nil {
+		return nil, accounts.Account{}, err
+	}
+	if err := os.MkdirAll(b.dir, 0700); err != nil {
+		return nil, accounts.Account{}, err
+	}
+	path := filepath.Join(b.dir, fmt.Sprintf("hd-%x%s", account.Address, walletFileSuffix))
+	if _, err := os.Stat(path); err == nil {
+		return nil, accounts.Account{}, fmt.Errorf("wallet already exists: %s", path)
+	}
+	w := &wallet{
+		url:    accounts.URL{Scheme: Scheme, Path: path},
+		crypto: encrypted,
+	}
+	account.URL = w.url
+	w.accounts = []accounts.Account{account}
+	w.paths = map[common.Address]accounts.DerivationPath{account.Address: accounts.DefaultBaseDerivationPath}
+	if err := w.store(); err != nil {
+		return nil, accounts.Account{}, err
+	}
+	b.refreshWallets()
+
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	for _, wallet := range b.wallets {
+		if wallet.url == w.url {
+			return wallet, account, nil
+		}
+	}
+	return nil, accounts.Account{}, fmt.Errorf("imported wallet %s not found", path)
+}
@@ -0,0 +1,145 @@
+// Copyright 2018 The aquachain Authors
+// This file is part of the aquachain library.
+//
+// The aquachain library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The aquachain library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the aquachain library. If not, see <http://www.gnu.org/licenses/>.
+
+package hdwallet
+
+import (
+	"crypto/hmac"
+	"crypto/sha512"
+	"encoding/binary"
+	"errors"
+	"math/big"
+
+	"github.com/btcsuite/btcd/btcec/v2"
+	"gitlab.com/aquachain/aquachain/aqua/accounts"
+	"gitlab.com/aquachain/aquachain/common"
+	"gitlab.com/aquachain/aquachain/common/math"
+	"gitlab.com/aquachain/aquachain/crypto"
+)
+
+// hardenedKeyStart is the index of the first hardened child key, as defined
+// by BIP-32.
+const hardenedKeyStart = 0x80000000
+
+var (
+	// masterKeySalt is the HMAC key used to derive the master key from a seed.
+	masterKeySalt = []byte("Bitcoin seed")
+
+	// curveN is the order of the secp256k1 curve.
+	curveN = btcec.S256().N
+
+	errInvalidSeedLen = errors.New("seed length must be between 128 and 512 bits")
+	errUnusableSeed   = errors.New("unusable seed")
+	errInvalidChild   = errors.New("derived child key is invalid")
+)
+
+// extendedKey is a BIP-32 extended private key, the private key itself along
+// with the chain code needed to derive its children.
+type extendedKey struct {
+	key       []byte // 32 byte big endian private key
+	chainCode []byte // 32 byte chain code
+}
+
+// newMasterKey derives the BIP-32 master key from the given seed.
+func newMasterKey(seed []byte) (*extendedKey, error) {
+	if len(seed) < 16 || len(seed) > 64 {
+		return nil, errInvalidSeedLen
+	}
+	mac := hmac.New(sha512.New, masterKeySalt)
+	mac.Write(seed)
+	sum := mac.Sum(nil)
+
+	if k := new(big.Int).SetBytes(sum[:32]); k.Sign() == 0 || k.Cmp(curveN) >= 0 {
+		return nil, errUnusableSeed
+	}
+	return &extendedKey{key: sum[:32], chainCode: sum[32:]}, nil
+}
+
+// child derives the private child key at the given index. Indices from
+// hardenedKeyStart upwards derive hardened children.
+func (k *extendedKey) child(index uint32) (*extendedKey, error) {
+	data := make([]byte, 0, 37)
+	if index >= hardenedKeyStart {
+		data = append(data, 0x00)
+		data = append(data, k.key...)
+	} else {
+		_, pub := btcec.PrivKeyFromBytes(k.key)
+		data = append(data, pub.SerializeCompressed()...)
+	}
+	data = binary.BigEndian.AppendUint32(data, index)
+
+	mac := hmac.New(sha512.New, k.chainCode)
+	mac.Write(data)
+	sum := mac.Sum(nil)
+
+	// The child key is parse256(IL) + kpar (mod n), which must be in range and
+	// non-zero. The spec asks to skip to the next index otherwise, we leave that
+	// to the caller as it changes the derivation path.
+	child := new(big.Int).SetBytes(sum[:32])
+	if child.Cmp(curveN) >= 0 {
+		return nil, errInvalidChild
+	}
+	child.Add(child, new(big.Int).SetBytes(k.key))
+	child.Mod(child, curveN)
+	if child.Sign() == 0 {
+		return nil, errInvalidChild
+	}
+	return &extendedKey{key: math.PaddedBigBytes(child, 32), chainCode: sum[32:]}, nil
+}
+
+// derive walks the given derivation path down from the key, returning the
+// extended key at the end of it. The returned key never aliases the receiver,
+// so it can be zeroed independently.
+func (k *extendedKey) derive(path accounts.DerivationPath) (*extendedKey, error) {
+	if len(path) == 0 {
+		return &extendedKey{
+			key:       append([]byte{}, k.key...),
+			chainCode: append([]byte{}, k.chainCode...),
+		}, nil
+	}
+	key := k
+	for _, index := range path {
+		child, err := key.child(index)
+		if key != k {
+			key.zero()
+		}
+		if err != nil {
+			return nil, err
+		}
+		key = child
+	}
+	return key, nil
+}
+
+// privateKey returns the secp256k1 private key of the extended key.
+func (k *extendedKey) privateKey() *btcec.PrivateKey {
+	return crypto.ToECDSAUnsafe(k.key)
+}
+
+// address returns the aquachain address controlled by the extended key.
+func (k *extendedKey) address() common.Address {
+	return crypto.PubkeyToAddress(k.privateKey().PubKey())
+}
+
+// zero clears the key material from memory.
+func (k *extendedKey) zero() {
+	for i := range k.key {
+		k.key[i] = 0
+	}
+	for i := range k.chainCode {
+		k.chainCode[i] = 0
+	}
+}
@@ -0,0 +1,190 @@
+// Copyright 2018 The aquachain Authors
+// This file is part of the aquachain library.
+//
+// The aquachain library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The aquachain library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the aquachain library. If not, see <http://www.gnu.org/licenses/>.
+
+package hdwallet
+
+import (
+	"context"
+	"encoding/hex"
+	"math/big"
+	"os"
+	"testing"
+	"time"
+
+	"gitlab.com/aquachain/aquachain/aqua/accounts"
+	"gitlab.com/aquachain/aquachain/aqua/accounts/keystore"
+	"gitlab.com/aquachain/aquachain/common"
+	"gitlab.com/aquachain/aquachain/crypto"
+)
+
+const testMnemonic = "test test test test test test test test test test test junk"
+
+// Tests key derivation against test vector 1 of the BIP-32 specification.
+func TestBIP32Vectors(t *testing.T) {
+	seed, _ := hex.DecodeString("000102030405060708090a0b0c0d0e0f")
+	master, err := newMasterKey(seed)
+	if err != nil {
+		t.Fatalf("failed to create master key: %v", err)
+	}
+	tests := []struct {
+		path string
+		key  string
+	}{
+		{"m/0'", "edb2e14f9ee77d26dd93b4ecede8d16ed408ce149b6cd80b0715a2d911a0afea"},
+		{"m/0'/1", "3c6cb8d0f6a264c91ea8b5030fadaa8e538b020f0a387421a12de9319dc93368"},
+		{"m/0'/1/2'", "cbce0d719ecf7431d88e6a89fa1483e02e35092af60c042b1df2ff59fa424dca"},
+		{"m/0'/1/2'/2", "0f479245fb19a38a1954c5c7c0ebab2f9bdfd96a17563ef28a6a4b1a2a764ef4"},
+		{"m/0'/1/2'/2/1000000000", "471b76e389e528d6de6d816857e012c5455051cad6660850e58372a6c3e6e7c8"},
+	}
+	if have := hex.EncodeToString(master.key); have != "e8f32e723decf4051aefac8e2c93c9c5b214313817cdb01a1494b917c8436b35" {
+		t.Errorf("master key mismatch: have %s", have)
+	}
+	for i, tt := range tests {
+		path, err := accounts.ParseDerivationPath(tt.path)
+		if err != nil {
+			t.Fatalf("test %d: invalid path %s: %v", i, tt.path, err)
+		}
+		key, err := master.derive(path)
+		if err != nil {
+			t.Fatalf("test %d: failed to derive %s: %v", i, tt.path, err)
+		}
+		if have := hex.EncodeToString(key.key); have != tt.key {
+			t.Errorf("test %d: key mismatch at %s: have %s, want %s", i, tt.path, have, tt.key)
+		}
+	}
+}
+
+func newTestBackend(t *testing.T) (*Backend, string) {
+	dir, err := os.MkdirTemp("", "aquachain-hdwallet-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return NewBackend(dir, keystore.LightScryptN, keystore.LightScryptP), dir
+}
+
+// Tests that an imported mnemonic derives the expected accounts, that pinned
+// accounts are persisted and that the wallet signs only while it is open.
+func TestImportDeriveSign(t *testing.T) {
+	backend, dir := newTestBackend(t)
+	defer os.RemoveAll(dir)
+
+	wallet, account, err := backend.Import(testMnemonic, "", "foo")
+	if err != nil {
+		t.Fatalf("failed to import mnemonic: %v", err)
+	}
+	if want := common.HexToAddress("0xf39Fd6e51aad88F6F4ce6aB8827279cffFb92266"); account.Address != want {
+		t.Fatalf("base account mismatch: have %x, want %x", account.Address, want)
+	}
+	if _, _, err := backend.Import(testMnemonic, "", "foo"); err == nil {
+		t.Fatalf("duplicate import succeeded")
+	}
+	if _, err := wallet.Derive(accounts.DefaultBaseDerivationPath, false); err != accounts.ErrWalletClosed {
+		t.Fatalf("derive on closed wallet: have %v, want %v", err, accounts.ErrWalletClosed)
+	}
+	if err := wallet.Open("bar"); err != keystore.ErrDecrypt {
+		t.Fatalf("open with bad passphrase: have %v, want %v", err, keystore.ErrDecrypt)
+	}
+	if err := wallet.Open("foo"); err != nil {
+		t.Fatalf("failed to open wallet: %v", err)
+	}
+	path, _ := accounts.ParseDerivationPath("m/44'/60'/0'/0/1")
+	derived, err := wallet.Derive(path, true)
+	if err != nil {
+		t.Fatalf("failed to derive account: %v", err)
+	}
+	if want := common.HexToAddress("0x70997970C51812dc3A010C7d01b50e0d17dc79C8"); derived.Address != want {
+		t.Fatalf("derived account mismatch: have %x, want %x", derived.Address, want)
+	}
+	hash := crypto.Keccak256([]byte("aquachain"))
+	sig, err := wallet.SignHash(derived, hash)
+	if err != nil {
+		t.Fatalf("failed to sign hash: %v", err)
+	}
+	pub, err := crypto.SigToPub(hash, sig)
+	if err != nil || crypto.PubkeyToAddress(pub) != derived.Address {
+		t.Fatalf("signature recovered to wrong signer: %v", err)
+	}
+	wallet.Close()
+	if _, err := wallet.SignHash(derived, hash); err != accounts.ErrWalletClosed {
+		t.Fatalf("sign on closed wallet: have %v, want %v", err, accounts.ErrWalletClosed)
+	}
+	if _, err := wallet.SignHashWithPassphrase(derived, "foo", hash); err != nil {
+		t.Fatalf("failed to sign with passphrase: %v", err)
+	}
+	// Reload the backend and make sure the pinned accounts survived
+	reloaded := NewBackend(dir, keystore.LightScryptN, keystore.LightScryptP).Wallets()
+	if len(reloaded) != 1 {
+		t.Fatalf("wallet count mismatch: have %d, want 1", len(reloaded))
+	}
+	accs := reloaded[0].Accounts()
+	if len(accs) != 2 || accs[0].Address != account.Address || accs[1].Address != derived.Address {
+		t.Fatalf("pinned accounts mismatch: have %v", accs)
+	}
+}
+
+// testChain is a chain state reader reporting a balance for a fixed set of
+// accounts.
+type testChain struct {
+	funded map[common.Address]bool
+}
+
+func (c *testChain) BalanceAt(ctx context.Context, account common.Address, blockNumber *big.Int) (*big.Int, error) {
+	if c.funded[account] {
+		return big.NewInt(1), nil
+	}
+	return new(big.Int), nil
+}
+
+func (c *testChain) StorageAt(ctx context.Context, account common.Address, key common.Hash, blockNumber *big.Int) ([]byte, error) {
+	return nil, nil
+}
+
+func (c *testChain) CodeAt(ctx context.Context, account common.Address, blockNumber *big.Int) ([]byte, error) {
+	return nil, nil
+}
+
+func (c *testChain) NonceAt(ctx context.Context, account common.Address, blockNumber *big.Int) (uint64, error) {
+	return 0, nil
+}
+
+// Tests that self derivation pins every used account and the first unused one.
+func TestSelfDerive(t *testing.T) {
+	backend, dir := newTestBackend(t)
+	defer os.RemoveAll(dir)
+
+	wallet, _, err := backend.Import(testMnemonic, "", "foo")
+	if err != nil {
+		t.Fatalf("failed to import mnemonic: %v", err)
+	}
+	chain := &testChain{funded: map[common.Address]bool{
+		common.HexToAddress("0xf39Fd6e51aad88F6F4ce6aB8827279cffFb92266"): true,
+		common.HexToAddress("0x70997970C51812dc3A010C7d01b50e0d17dc79C8"): true,
+	}}
+	wallet.SelfDerive(accounts.DefaultBaseDerivationPath, chain)
+	if err := wallet.Open("foo"); err != nil {
+		t.Fatalf("failed to open wallet: %v", err)
+	}
+	defer wallet.Close()
+
+	for deadline := time.Now().Add(5 * time.Second); time.Now().Before(deadline); time.Sleep(10 * time.Millisecond) {
+		if len(wallet.Accounts()) == 3 {
+			break
+		}
+	}
+	if accs := wallet.Accounts(); len(accs) != 3 {
+		t.Fatalf("self derived account count mismatch: have %d, want 3", len(accs))
+	}
+}
@@ -0,0 +1,402 @@
+// Copyright 2018 The aquachain Authors
+// This file is part of the aquachain library.
+//
+// The aquachain library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The aquachain library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the aquachain library. If not, see <http://www.gnu.org/licenses/>.
+
+package hdwallet
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"os"
+	"sync"
+	"time"
+
+	aquachain "gitlab.com/aquachain/aquachain"
+	"gitlab.com/aquachain/aquachain/aqua/accounts"
+	"gitlab.com/aquachain/aquachain/aqua/accounts/keystore"
+	"gitlab.com/aquachain/aquachain/common"
+	"gitlab.com/aquachain/aquachain/common/log"
+	"gitlab.com/aquachain/aquachain/core/types"
+	"gitlab.com/aquachain/aquachain/crypto"
+)
+
+const (
+	// walletVersion is the version of the wallet file format.
+	walletVersion = 1
+
+	// selfDeriveLimit is the maximum number of accounts a single self derivation
+	// run walks through before giving up.
+	selfDeriveLimit = 128
+
+	// selfDeriveTimeout is the time allowed for a single self derivation run to
+	// query the chain state.
+	selfDeriveTimeout = time.Minute
+)
+
+// errNoSignMode is returned by all signing operations while the node runs
+// with signing disabled.
+var errNoSignMode = errors.New("signing disabled")
+
+// walletJSON is the on-disk representation of an HD wallet. The seed is kept
+// encrypted, the pinned accounts are not secret and stored in plain.
+type walletJSON struct {
+	Version  int                 `json:"version"`
+	Crypto   keystore.CryptoJSON `json:"crypto"`
+	Accounts []accountJSON       `json:"accounts"`
+}
+
+// accountJSON is a pinned account of an HD wallet along with the derivation
+// path it was derived at.
+type accountJSON struct {
+	Address common.Address `json:"address"`
+	Path    string         `json:"path"`
+}
+
+// wallet implements accounts.Wallet for a BIP-32 hierarchical deterministic
+// wallet whose BIP-39 seed is stored encrypted at rest.
+type wallet struct {
+	url     accounts.URL // File path of the wallet, doubling as its URL
+	backend *Backend     // Backend the wallet belongs to, for event notifications
+
+	crypto   keystore.CryptoJSON                        // Encrypted seed of the wallet
+	accounts []accounts.Account                         // List of pinned accounts
+	paths    map[common.Address]accounts.DerivationPath // Derivation paths of the pinned accounts
+	master   *extendedKey                               // Master key, only present while the wallet is open
+
+	deriveNextPath accounts.DerivationPath    // Next derivation path for account auto-discovery
+	deriveChain    aquachain.ChainStateReader // Blockchain state reader to discover used account with
+
+	lock sync.RWMutex
+}
+
+// loadWallet reads the wallet stored in the given file.
+func loadWallet(backend *Backend, path string) (*wallet, error) {
+	blob, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var enc walletJSON
+	if err := json.Unmarshal(blob, &enc); err != nil {
+		return nil, err
+	}
+	if enc.Version != walletVersion {
+		return nil, errors.New("unsupported wallet version")
+	}
+	w := &wallet{
+		url:     accounts.URL{Scheme: Scheme, Path: path},
+		backend: backend,
+		crypto:  enc.Crypto,
+		paths:   make(map[common.Address]accounts.DerivationPath),
+	}
+	for _, acc := range enc.Accounts {
+		path, err := accounts.ParseDerivationPath(acc.Path)
+		if err != nil {
+			return nil, err
+		}
+		w.accounts = append(w.accounts, accounts.Account{Address: acc.Address, URL: w.url})
+		w.paths[acc.Address] = path
+	}
+	return w, nil
+}
+
+// store writes the wallet into its file. The caller must hold the lock.
+func (w *wallet) store() error {
+	enc := walletJSON{
+		Version: walletVersion,
+		Crypto:  w.crypto,
+	}
+	for _, account := range w.accounts {
+		enc.Accounts = append(enc.Accounts, accountJSON{Address: account.Address, Path: w.paths[account.Address].String()})
+	}
+	blob, err := json.MarshalIndent(enc, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp := w.url.Path + ".tmp"
+	if err := os.WriteFile(tmp, blob, 0600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, w.url.Path)
+}
+
+// URL implements accounts.Wallet, returning the path of the wallet file.
+func (w *wallet) URL() accounts.URL {
+	return w.url
+}
+
+// Status implements accounts.Wallet, returning whether the seed of the wallet
+// is currently decrypted or not.
+func (w *wallet) Status() (string, error) {
+	w.lock.RLock()
+	defer w.lock.RUnlock()
+
+	if w.master != nil {
+		return "Unlocked", nil
+	}
+	return "Locked", nil
+}
+
+// Open implements accounts.Wallet, decrypting the seed of the wallet with the
+// given passphrase and keeping the derived master key in memory until closed.
+func (w *wallet) Open(passphrase string) error {
+	w.lock.Lock()
+	if w.master != nil {
+		w.lock.Unlock()
+		return accounts.ErrWalletAlreadyOpen
+	}
+	master, err := w.decrypt(passphrase)
+	if err != nil {
+		w.lock.Unlock()
+		return err
+	}
+	w.master = master
+	selfDerive := w.deriveChain != nil
+	w.lock.Unlock()
+
+	if selfDerive {
+		go w.selfDerive()
+	}
+	w.backend.updateFeed.Send(accounts.WalletEvent{Wallet: w, Kind: accounts.WalletOpened})
+	return nil
+}
+
+// decrypt decrypts the seed of the wallet and returns the master key derived
+// from it.
+func (w *wallet) decrypt(passphrase string) (*extendedKey, error) {
+	seed, err := keystore.DecryptDataV3(w.crypto, passphrase)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		for i := range seed {
+			seed[i] = 0
+		}
+	}()
+	return newMasterKey(seed)
+}
+
+// Close implements accounts.Wallet, wiping the master key from memory.
+func (w *wallet) Close() error {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+
+	if w.master != nil {
+		w.master.zero()
+		w.master = nil
+	}
+	return nil
+}
+
+// Accounts implements accounts.Wallet, returning the list of accounts pinned to
+// the wallet.
+func (w *wallet) Accounts() []accounts.Account {
+	w.lock.RLock()
+	defer w.lock.RUnlock()
+
+	cpy := make([]accounts.Account, len(w.accounts))
+	copy(cpy, w.accounts)
+	return cpy
+}
+
+// Contains implements accounts.Wallet, returning whether a particular account is
+// or is not pinned into this wallet instance.
+func (w *wallet) Contains(account accounts.Account) bool {
+	w.lock.RLock()
+	defer w.lock.RUnlock()
+
+	_, exists := w.paths[account.Address]
+	return exists && (account.URL == (accounts.URL{}) || account.URL == w.url)
+}
+
+// Derive implements accounts.Wallet, deriving a new account at the specific
+// derivation path. If pin is set to true, the account will be added to the list
+// of tracked accounts and persisted into the wallet file.
+func (w *wallet) Derive(path accounts.DerivationPath, pin bool) (accounts.Account, error) {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+
+	if w.master == nil {
+		return accounts.Account{}, accounts.ErrWalletClosed
+	}
+	key, err := w.master.derive(path)
+	if err != nil {
+		return accounts.Account{}, err
+	}
+	account := accounts.Account{Address: key.address(), URL: w.url}
+	key.zero()
+
+	if pin {
+		if err := w.pin(account, path); err != nil {
+			return accounts.Account{}, err
+		}
+	}
+	return account, nil
+}
+
+// pin adds the account to the tracked list and persists it, unless it is already
+// tracked. The caller must hold the lock.
+func (w *wallet) pin(account accounts.Account, path accounts.DerivationPath) error {
+	if _, ok := w.paths[account.Address]; ok {
+		return nil
+	}
+	w.accounts = append(w.accounts, account)
+	w.paths[account.Address] = append(accounts.DerivationPath{}, path...)
+	if err := w.store(); err != nil {
+		w.accounts = w.accounts[:len(w.accounts)-1]
+		delete(w.paths, account.Address)
+		return err
+	}
+	return nil
+}
+
+// SelfDerive implements accounts.Wallet, setting a base account derivation path
+// from which the wallet attempts to discover accounts with on-chain activity and
+// pin them. Discovery runs in the background whenever the wallet is open.
+func (w *wallet) SelfDerive(base accounts.DerivationPath, chain aquachain.ChainStateReader) {
+	w.lock.Lock()
+	w.deriveNextPath = append(accounts.DerivationPath{}, base...)
+	w.deriveChain = chain
+	open := w.master != nil
+	w.lock.Unlock()
+
+	if open && chain != nil {
+		go w.selfDerive()
+	}
+}
+
+// selfDerive walks the accounts from the next derivation path onwards, pinning
+// every account with a non-zero balance or nonce, along with the first unused
+// one so the user has a fresh address at hand.
+func (w *wallet) selfDerive() {
+	w.lock.RLock()
+	chain, path := w.deriveChain, append(accounts.DerivationPath{}, w.deriveNextPath...)
+	w.lock.RUnlock()
+
+	if chain == nil || len(path) == 0 {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), selfDeriveTimeout)
+	defer cancel()
+
+	for i := 0; i < selfDeriveLimit; i++ {
+		account, err := w.Derive(path, false)
+		if err != nil {
+			log.Debug("HD wallet self-derivation stopped", "url", w.url, "err", err)
+			return
+		}
+		balance, err := chain.BalanceAt(ctx, account.Address, nil)
+		if err != nil {
+			log.Warn("HD wallet balance retrieval failed", "err", err)
+			return
+		}
+		nonce, err := chain.NonceAt(ctx, account.Address, nil)
+		if err != nil {
+			log.Warn("HD wallet nonce retrieval failed", "err", err)
+			return
+		}
+		w.lock.Lock()
+		if err := w.pin(account, path); err != nil {
+			w.lock.Unlock()
+			log.Warn("HD wallet failed to pin account", "address", account.Address, "err", err)
+			return
+		}
+		w.lock.Unlock()
+
+		if balance.Sign() == 0 && nonce == 0 {
+			break
+		}
+		log.Info("HD wallet discovered new account", "address", account.Address, "path", path, "balance", balance, "nonce", nonce)
+		path[len(path)-1]++
+	}
+	// Remember where discovery stopped so a later run continues from there
+	w.lock.Lock()
+	if w.deriveChain == chain {
+		w.deriveNextPath = path
+	}
+	w.lock.Unlock()
+}
+
+// signingKey derives the private key of the given pinned account, either from
+// the open master key or, if a passphrase is given, by decrypting the seed.
+func (w *wallet) signingKey(account accounts.Account, passphrase *string) (*extendedKey, error) {
+	if keystore.NoSignMode() {
+		return nil, errNoSignMode
+	}
+	w.lock.RLock()
+	defer w.lock.RUnlock()
+
+	path, ok := w.paths[account.Address]
+	if !ok || (account.URL != (accounts.URL{}) && account.URL != w.url) {
+		return nil, accounts.ErrUnknownAccount
+	}
+	if passphrase != nil {
+		master, err := w.decrypt(*passphrase)
+		if err != nil {
+			return nil, err
+		}
+		defer master.zero()
+		return master.derive(path)
+	}
+	if w.master == nil {
+		return nil, accounts.ErrWalletClosed
+	}
+	return w.master.derive(path)
+}
+
+// SignHash implements accounts.Wallet, signing the given hash with the account
+// if the wallet is open.
+func (w *wallet) SignHash(account accounts.Account, hash []byte) ([]byte, error) {
+	key, err := w.signingKey(account, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer key.zero()
+	return crypto.Sign(hash, key.privateKey())
+}
+
+// SignTx implements accounts.Wallet, signing the given transaction with the
+// account if the wallet is open.
+func (w *wallet) SignTx(account accounts.Account, tx *types.Transaction, chainID *big.Int) (*types.Transaction, error) {
+	key, err := w.signingKey(account, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer key.zero()
+	return types.SignTx(tx, types.LatestSignerForChainID(chainID), key.privateKey())
+}
+
+// SignHashWithPassphrase implements accounts.Wallet, signing the given hash with
+// the account after decrypting the seed with the given passphrase.
+func (w *wallet) SignHashWithPassphrase(account accounts.Account, passphrase string, hash []byte) ([]byte, error) {
+	key, err := w.signingKey(account, &passphrase)
+	if err != nil {
+		return nil, err
+	}
+	defer key.zero()
+	return crypto.Sign(hash, key.privateKey())
+}
+
+// SignTxWithPassphrase implements accounts.Wallet, signing the given transaction
+// with the account after decrypting the seed with the given passphrase.
+func (w *wallet) SignTxWithPassphrase(account accounts.Account, passphrase string, tx *types.Transaction, chainID *big.Int) (*types.Transaction, error) {
+	key, err := w.signingKey(account, &passphrase)
+	if err != nil {
+		return nil, err
+	}
+	defer key.zero()
+	return types.SignTx(tx, types.LatestSignerForChainID(chainID), key.privateKey())
+}
@@ -247,7 +247,7 @@ func (ac *accountCache) scanAccounts() error {
 	var (
 		buf = new(bufio.Reader)
 		key struct {
-			Address string `json:"address	"`
+			Address string `json:"address"`
 		}
 	)
 	readAccount := func(path string) *accounts.Account {
@@ -35,6 +35,10 @@ import (
 
 const (
 	version = 3
+
+	// versionArgon2id is the version of key files encrypted with Argon2id,
+	// otherwise sharing the layout of version 3.
+	versionArgon2id = 4
 )
 
 type Key struct {
@@ -66,19 +70,21 @@ type plainKeyJSON struct {
 
 type encryptedKeyJSONV3 struct {
 	Address string     `json:"address"`
-	Crypto  cryptoJSON `json:"crypto"`
+	Crypto  CryptoJSON `json:"crypto"`
 	Id      string     `json:"id"`
 	Version int        `json:"version"`
 }
 
 type encryptedKeyJSONV1 struct {
 	Address string     `json:"address"`
-	Crypto  cryptoJSON `json:"crypto"`
+	Crypto  CryptoJSON `json:"crypto"`
 	Id      string     `json:"id"`
 	Version string     `json:"version"`
 }
 
-type cryptoJSON struct {
+// CryptoJSON is the encrypted section of a key file, holding the ciphertext
+// along with the cipher and key derivation parameters needed to decrypt it.
+type CryptoJSON struct {
 	Cipher       string                 `json:"cipher"`
 	CipherText   string                 `json:"ciphertext"`
 	CipherParams cipherparamsJSON       `json:"cipherparams"`
@@ -184,7 +190,14 @@ func writeKeyFile(file string, content []byte, overwrite bool) error {
 	if err := os.MkdirAll(filepath.Dir(file), dirPerm); err != nil {
 		return err
 	}
-	return os.WriteFile(file, content, 0600)
+	// Write into a hidden temporary file ignored by the account cache, then swap
+	// it in atomically so the cache never reads a partially written key.
+	tmp := filepath.Join(filepath.Dir(file), "."+filepath.Base(file)+".tmp")
+	if err := os.WriteFile(tmp, content, 0600); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	return os.Rename(tmp, file)
 }
 
 // keyFileName implements the naming convention for keyfiles:
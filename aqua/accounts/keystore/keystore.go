@@ -79,7 +79,17 @@ type unlocked struct {
 // NewKeyStore creates a keystore for the given directory.
 func NewKeyStore(keydir string, scryptN, scryptP int) *KeyStore {
 	keydir, _ = filepath.Abs(keydir)
-	ks := &KeyStore{storage: &keyStorePassphrase{keydir, scryptN, scryptP}}
+	ks := &KeyStore{storage: &keyStorePassphrase{keysDirPath: keydir, scryptN: scryptN, scryptP: scryptP}}
+	ks.init(keydir)
+	return ks
+}
+
+// NewKeyStoreArgon2id creates a keystore for the given directory, storing new
+// and updated keys in the version 4 format using the given Argon2id parameters.
+// Keys stored in older formats are still decrypted transparently.
+func NewKeyStoreArgon2id(keydir string, memory, time uint32) *KeyStore {
+	keydir, _ = filepath.Abs(keydir)
+	ks := &KeyStore{storage: &keyStorePassphrase{keysDirPath: keydir, argon2Memory: memory, argon2Time: time}}
 	ks.init(keydir)
 	return ks
 }
@@ -335,11 +345,9 @@ func (ks *KeyStore) SignTx(a accounts.Account, tx *types.Transaction, chainID *b
 	if !found {
 		return nil, ErrLocked
 	}
-	// Depending on the presence of the chain ID, sign with EIP155 or homestead
-	if chainID != nil {
-		return types.SignTx(tx, types.NewEIP155Signer(chainID), unlockedKey.PrivateKey)
-	}
-	return types.SignTx(tx, types.HomesteadSigner{}, unlockedKey.PrivateKey)
+	// Depending on the presence of the chain ID, sign with EIP155 (EIP2930 for
+	// typed transactions) or homestead
+	return types.SignTx(tx, types.LatestSignerForChainID(chainID), unlockedKey.PrivateKey)
 }
 
 // SignHashWithPassphrase signs hash if the private key matching the given address
@@ -369,11 +377,9 @@ func (ks *KeyStore) SignTxWithPassphrase(a accounts.Account, passphrase string,
 	}
 	defer zeroKey(key.PrivateKey)
 
-	// Depending on the presence of the chain ID, sign with EIP155 or homestead
-	if chainID != nil {
-		return types.SignTx(tx, types.NewEIP155Signer(chainID), key.PrivateKey)
-	}
-	return types.SignTx(tx, types.HomesteadSigner{}, key.PrivateKey)
+	// Depending on the presence of the chain ID, sign with EIP155 (EIP2930 for
+	// typed transactions) or homestead
+	return types.SignTx(tx, types.LatestSignerForChainID(chainID), key.PrivateKey)
 }
 
 // Unlock unlocks the given account indefinitely.
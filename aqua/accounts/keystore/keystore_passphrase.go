@@ -41,6 +41,7 @@ import (
 	"gitlab.com/aquachain/aquachain/common/math"
 	"gitlab.com/aquachain/aquachain/crypto"
 	"gitlab.com/aquachain/aquachain/crypto/randentropy"
+	"golang.org/x/crypto/argon2"
 	"golang.org/x/crypto/pbkdf2"
 	"golang.org/x/crypto/scrypt"
 )
@@ -48,6 +49,9 @@ import (
 const (
 	keyHeaderKDF = "scrypt"
 
+	// keyHeaderKDFArgon2id is the KDF of version 4 key files.
+	keyHeaderKDFArgon2id = "argon2id"
+
 	// StandardScryptN is the N parameter of Scrypt encryption algorithm, using 256MB
 	// memory and taking approximately 1s CPU time on a modern processor.
 	StandardScryptN = 1 << 18
@@ -66,12 +70,43 @@ const (
 
 	scryptR     = 8
 	scryptDKLen = 32
+
+	// StandardArgon2Memory is the memory parameter of the Argon2id encryption
+	// algorithm in KiB, using 256MB memory and taking approximately 1s CPU time
+	// on a modern processor along with StandardArgon2Time.
+	StandardArgon2Memory = 256 * 1024
+
+	// StandardArgon2Time is the number of passes of the Argon2id encryption
+	// algorithm, using 256MB memory and taking approximately 1s CPU time on a
+	// modern processor along with StandardArgon2Memory.
+	StandardArgon2Time = 3
+
+	// LightArgon2Memory is the memory parameter of the Argon2id encryption
+	// algorithm in KiB, using 4MB memory and taking approximately 10ms CPU time.
+	LightArgon2Memory = 4 * 1024
+
+	// LightArgon2Time is the number of passes of the Argon2id encryption
+	// algorithm, using 4MB memory and taking approximately 10ms CPU time.
+	LightArgon2Time = 1
+
+	argon2Threads = 4
+	argon2DKLen   = 32
+
+	// maxArgon2Memory, maxArgon2Time and maxArgon2Threads cap the work a key
+	// file may request to be decrypted, so a crafted file can't exhaust the
+	// memory or stall the CPU of the node. Memory is in KiB, 1GB at most.
+	maxArgon2Memory  = 1024 * 1024
+	maxArgon2Time    = 16
+	maxArgon2Threads = 16
 )
 
 type keyStorePassphrase struct {
 	keysDirPath string
 	scryptN     int
 	scryptP     int
+
+	argon2Memory uint32 // Argon2id memory in KiB, storing version 4 keys if set
+	argon2Time   uint32 // Argon2id number of passes
 }
 
 func (ks keyStorePassphrase) GetKey(addr common.Address, filename, auth string) (*Key, error) {
@@ -93,25 +128,40 @@ func (ks keyStorePassphrase) GetKey(addr common.Address, filename, auth string)
 
 // StoreKey generates a key, encrypts with 'auth' and stores in the given directory
 func StoreKey(dir, auth string, scryptN, scryptP int) (common.Address, error) {
-	_, a, err := storeNewKey(&keyStorePassphrase{dir, scryptN, scryptP}, crand.Reader, auth)
+	_, a, err := storeNewKey(&keyStorePassphrase{keysDirPath: dir, scryptN: scryptN, scryptP: scryptP}, crand.Reader, auth)
+	return a.Address, err
+}
+
+// StoreKeyArgon2id generates a key, encrypts with 'auth' using the specified
+// Argon2id parameters and stores it in the given directory as a version 4 key.
+func StoreKeyArgon2id(dir, auth string, memory, time uint32) (common.Address, error) {
+	_, a, err := storeNewKey(&keyStorePassphrase{keysDirPath: dir, argon2Memory: memory, argon2Time: time}, crand.Reader, auth)
 	return a.Address, err
 }
 
 func (ks keyStorePassphrase) StoreKey(filename string, key *Key, auth string) error {
-	keyjson, err := EncryptKey(key, auth, ks.scryptN, ks.scryptP)
+	keyjson, err := ks.encryptKey(key, auth)
 	if err != nil {
 		return err
 	}
 	return writeKeyFile(filename, keyjson, false)
 }
 func (ks keyStorePassphrase) UpdateKey(filename string, key *Key, auth string) error {
-	keyjson, err := EncryptKey(key, auth, ks.scryptN, ks.scryptP)
+	keyjson, err := ks.encryptKey(key, auth)
 	if err != nil {
 		return err
 	}
 	return writeKeyFile(filename, keyjson, true)
 }
 
+// encryptKey encrypts the key in the format configured for the keystore.
+func (ks keyStorePassphrase) encryptKey(key *Key, auth string) ([]byte, error) {
+	if ks.argon2Memory != 0 {
+		return EncryptKeyV4(key, auth, ks.argon2Memory, ks.argon2Time)
+	}
+	return EncryptKey(key, auth, ks.scryptN, ks.scryptP)
+}
+
 var _ keyStore = keyStorePassphrase{}
 
 func (ks keyStorePassphrase) JoinPath(filename string) string {
@@ -122,22 +172,20 @@ func (ks keyStorePassphrase) JoinPath(filename string) string {
 	}
 }
 
-// EncryptKey encrypts a key using the specified scrypt parameters into a json
-// blob that can be decrypted later on.
-func EncryptKey(key *Key, auth string, scryptN, scryptP int) ([]byte, error) {
-	authArray := []byte(auth)
+// EncryptDataV3 encrypts the data given as 'data' with the password 'auth'
+// using the specified scrypt parameters.
+func EncryptDataV3(data, auth []byte, scryptN, scryptP int) (CryptoJSON, error) {
 	salt := randentropy.GetEntropyCSPRNG(32)
-	derivedKey, err := scrypt.Key(authArray, salt, scryptN, scryptR, scryptP, scryptDKLen)
+	derivedKey, err := scrypt.Key(auth, salt, scryptN, scryptR, scryptP, scryptDKLen)
 	if err != nil {
-		return nil, err
+		return CryptoJSON{}, err
 	}
 	encryptKey := derivedKey[:16]
-	keyBytes := math.PaddedBigBytes(key.PrivateKey.ToECDSA().D, 32)
 
 	iv := randentropy.GetEntropyCSPRNG(aes.BlockSize) // 16
-	cipherText, err := aesCTRXOR(encryptKey, keyBytes, iv)
+	cipherText, err := aesCTRXOR(encryptKey, data, iv)
 	if err != nil {
-		return nil, err
+		return CryptoJSON{}, err
 	}
 	mac := crypto.Keccak256(derivedKey[16:32], cipherText)
 
@@ -152,13 +200,57 @@ func EncryptKey(key *Key, auth string, scryptN, scryptP int) ([]byte, error) {
 		IV: hex.EncodeToString(iv),
 	}
 
-	cryptoStruct := cryptoJSON{
+	return CryptoJSON{
 		Cipher:       "aes-128-ctr",
 		CipherText:   hex.EncodeToString(cipherText),
 		CipherParams: cipherParamsJSON,
 		KDF:          keyHeaderKDF,
 		KDFParams:    scryptParamsJSON,
 		MAC:          hex.EncodeToString(mac),
+	}, nil
+}
+
+// EncryptDataV4 encrypts the data given as 'data' with the password 'auth'
+// using the specified Argon2id parameters, memory being given in KiB.
+func EncryptDataV4(data, auth []byte, memory, time uint32) (CryptoJSON, error) {
+	if memory == 0 || memory > maxArgon2Memory || time == 0 || time > maxArgon2Time {
+		return CryptoJSON{}, fmt.Errorf("invalid argon2id parameters: memory %d KiB, time %d", memory, time)
+	}
+	salt := randentropy.GetEntropyCSPRNG(32)
+	derivedKey := argon2.IDKey(auth, salt, time, memory, argon2Threads, argon2DKLen)
+	encryptKey := derivedKey[:16]
+
+	iv := randentropy.GetEntropyCSPRNG(aes.BlockSize) // 16
+	cipherText, err := aesCTRXOR(encryptKey, data, iv)
+	if err != nil {
+		return CryptoJSON{}, err
+	}
+	mac := crypto.Keccak256(derivedKey[16:32], cipherText)
+
+	argon2ParamsJSON := make(map[string]interface{}, 5)
+	argon2ParamsJSON["memory"] = memory
+	argon2ParamsJSON["time"] = time
+	argon2ParamsJSON["threads"] = argon2Threads
+	argon2ParamsJSON["dklen"] = argon2DKLen
+	argon2ParamsJSON["salt"] = hex.EncodeToString(salt)
+
+	return CryptoJSON{
+		Cipher:       "aes-128-ctr",
+		CipherText:   hex.EncodeToString(cipherText),
+		CipherParams: cipherparamsJSON{IV: hex.EncodeToString(iv)},
+		KDF:          keyHeaderKDFArgon2id,
+		KDFParams:    argon2ParamsJSON,
+		MAC:          hex.EncodeToString(mac),
+	}, nil
+}
+
+// EncryptKey encrypts a key using the specified scrypt parameters into a json
+// blob that can be decrypted later on.
+func EncryptKey(key *Key, auth string, scryptN, scryptP int) ([]byte, error) {
+	keyBytes := math.PaddedBigBytes(key.PrivateKey.ToECDSA().D, 32)
+	cryptoStruct, err := EncryptDataV3(keyBytes, []byte(auth), scryptN, scryptP)
+	if err != nil {
+		return nil, err
 	}
 	encryptedKeyJSONV3 := encryptedKeyJSONV3{
 		hex.EncodeToString(key.Address[:]),
@@ -169,6 +261,23 @@ func EncryptKey(key *Key, auth string, scryptN, scryptP int) ([]byte, error) {
 	return json.Marshal(encryptedKeyJSONV3)
 }
 
+// EncryptKeyV4 encrypts a key using the specified Argon2id parameters into a
+// version 4 json blob that can be decrypted later on.
+func EncryptKeyV4(key *Key, auth string, memory, time uint32) ([]byte, error) {
+	keyBytes := math.PaddedBigBytes(key.PrivateKey.ToECDSA().D, 32)
+	cryptoStruct, err := EncryptDataV4(keyBytes, []byte(auth), memory, time)
+	if err != nil {
+		return nil, err
+	}
+	encryptedKeyJSONV4 := encryptedKeyJSONV3{
+		hex.EncodeToString(key.Address[:]),
+		cryptoStruct,
+		key.Id.String(),
+		versionArgon2id,
+	}
+	return json.Marshal(encryptedKeyJSONV4)
+}
+
 // DecryptKey decrypts a key from a json blob, returning the private key itself.
 func DecryptKey(keyjson []byte, auth string) (*Key, error) {
 	// Parse the json into a simple map to fetch the key version
@@ -207,42 +316,50 @@ func DecryptKey(keyjson []byte, auth string) (*Key, error) {
 	}, nil
 }
 
-func decryptKeyV3(keyProtected *encryptedKeyJSONV3, auth string) (keyBytes []byte, keyId []byte, err error) {
-	if keyProtected.Version != version {
-		return nil, nil, fmt.Errorf("Version not supported: %v", keyProtected.Version)
-	}
-
-	if keyProtected.Crypto.Cipher != "aes-128-ctr" {
-		return nil, nil, fmt.Errorf("Cipher not supported: %v", keyProtected.Crypto.Cipher)
+// DecryptDataV3 decrypts the data protected by the given crypto section with
+// the password 'auth'.
+func DecryptDataV3(cryptoJson CryptoJSON, auth string) ([]byte, error) {
+	if cryptoJson.Cipher != "aes-128-ctr" {
+		return nil, fmt.Errorf("Cipher not supported: %v", cryptoJson.Cipher)
 	}
-
-	keyId = uuid.Parse(keyProtected.Id)
-	mac, err := hex.DecodeString(keyProtected.Crypto.MAC)
+	mac, err := hex.DecodeString(cryptoJson.MAC)
 	if err != nil {
-		return nil, nil, err
+		return nil, err
 	}
 
-	iv, err := hex.DecodeString(keyProtected.Crypto.CipherParams.IV)
+	iv, err := hex.DecodeString(cryptoJson.CipherParams.IV)
 	if err != nil {
-		return nil, nil, err
+		return nil, err
 	}
 
-	cipherText, err := hex.DecodeString(keyProtected.Crypto.CipherText)
+	cipherText, err := hex.DecodeString(cryptoJson.CipherText)
 	if err != nil {
-		return nil, nil, err
+		return nil, err
 	}
 
-	derivedKey, err := getKDFKey(keyProtected.Crypto, auth)
+	derivedKey, err := getKDFKey(cryptoJson, auth)
 	if err != nil {
-		return nil, nil, err
+		return nil, err
 	}
 
 	calculatedMAC := crypto.Keccak256(derivedKey[16:32], cipherText)
 	if !bytes.Equal(calculatedMAC, mac) {
-		return nil, nil, ErrDecrypt
+		return nil, ErrDecrypt
 	}
 
-	plainText, err := aesCTRXOR(derivedKey[:16], cipherText, iv)
+	return aesCTRXOR(derivedKey[:16], cipherText, iv)
+}
+
+func decryptKeyV3(keyProtected *encryptedKeyJSONV3, auth string) (keyBytes []byte, keyId []byte, err error) {
+	switch {
+	case keyProtected.Version == versionArgon2id && keyProtected.Crypto.KDF != keyHeaderKDFArgon2id,
+		keyProtected.Version == version && keyProtected.Crypto.KDF == keyHeaderKDFArgon2id:
+		return nil, nil, fmt.Errorf("KDF not supported by version %d: %v", keyProtected.Version, keyProtected.Crypto.KDF)
+	case keyProtected.Version != version && keyProtected.Version != versionArgon2id:
+		return nil, nil, fmt.Errorf("Version not supported: %v", keyProtected.Version)
+	}
+	keyId = uuid.Parse(keyProtected.Id)
+	plainText, err := DecryptDataV3(keyProtected.Crypto, auth)
 	if err != nil {
 		return nil, nil, err
 	}
@@ -283,13 +400,20 @@ func decryptKeyV1(keyProtected *encryptedKeyJSONV1, auth string) (keyBytes []byt
 	return plainText, keyId, err
 }
 
-func getKDFKey(cryptoJSON cryptoJSON, auth string) ([]byte, error) {
+func getKDFKey(cryptoJSON CryptoJSON, auth string) ([]byte, error) {
 	authArray := []byte(auth)
-	salt, err := hex.DecodeString(cryptoJSON.KDFParams["salt"].(string))
+	saltHex, ok := cryptoJSON.KDFParams["salt"].(string)
+	if !ok {
+		return nil, fmt.Errorf("missing or invalid KDF parameter salt")
+	}
+	salt, err := hex.DecodeString(saltHex)
+	if err != nil {
+		return nil, err
+	}
+	dkLen, err := kdfInt(cryptoJSON.KDFParams, "dklen")
 	if err != nil {
 		return nil, err
 	}
-	dkLen := ensureInt(cryptoJSON.KDFParams["dklen"])
 
 	if cryptoJSON.KDF == keyHeaderKDF {
 		n := ensureInt(cryptoJSON.KDFParams["n"])
@@ -305,11 +429,43 @@ func getKDFKey(cryptoJSON cryptoJSON, auth string) ([]byte, error) {
 		}
 		key := pbkdf2.Key(authArray, salt, c, dkLen, sha256.New)
 		return key, nil
+
+	} else if cryptoJSON.KDF == keyHeaderKDFArgon2id {
+		memory, err := kdfInt(cryptoJSON.KDFParams, "memory")
+		if err != nil {
+			return nil, err
+		}
+		time, err := kdfInt(cryptoJSON.KDFParams, "time")
+		if err != nil {
+			return nil, err
+		}
+		threads, err := kdfInt(cryptoJSON.KDFParams, "threads")
+		if err != nil {
+			return nil, err
+		}
+		if memory <= 0 || memory > maxArgon2Memory || time <= 0 || time > maxArgon2Time || threads <= 0 || threads > maxArgon2Threads || dkLen < 32 {
+			return nil, fmt.Errorf("invalid argon2id parameters: memory %d KiB, time %d, threads %d, dklen %d", memory, time, threads, dkLen)
+		}
+		return argon2.IDKey(authArray, salt, uint32(time), uint32(memory), uint8(threads), uint32(dkLen)), nil
 	}
 
 	return nil, fmt.Errorf("Unsupported KDF: %s", cryptoJSON.KDF)
 }
 
+// kdfInt reads an integer KDF parameter of a key file, which may be missing or
+// of the wrong type.
+func kdfInt(params map[string]interface{}, name string) (int, error) {
+	switch x := params[name].(type) {
+	case int:
+		return x, nil
+	case float64:
+		if x >= -(1<<31) && x < 1<<31 && x == float64(int(x)) {
+			return int(x), nil
+		}
+	}
+	return 0, fmt.Errorf("missing or invalid KDF parameter %s", name)
+}
+
 // TODO: can we do without this when unmarshalling dynamic JSON?
 // why do integers in KDF params end up as float64 and not int after
 // unmarshal?
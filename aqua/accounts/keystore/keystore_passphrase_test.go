@@ -17,8 +17,11 @@
 package keystore
 
 import (
+	"encoding/json"
 	"io/ioutil"
+	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 
 	"gitlab.com/aquachain/aquachain/common"
@@ -27,6 +30,9 @@ import (
 const (
 	veryLightScryptN = 2
 	veryLightScryptP = 1
+
+	veryLightArgon2Memory = 64
+	veryLightArgon2Time   = 1
 )
 
 // Tests that a json key file can be decrypted and encrypted in multiple rounds.
@@ -59,3 +65,154 @@ func TestKeyEncryptDecrypt(t *testing.T) {
 		}
 	}
 }
+
+// Tests that keys can be encrypted into the version 4 format and decrypted again.
+func TestKeyEncryptDecryptV4(t *testing.T) {
+	keyjson, err := ioutil.ReadFile(filepath.Join(basedir, "very-light-scrypt.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	key, err := DecryptKey(keyjson, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if keyjson, err = EncryptKeyV4(key, "foo", veryLightArgon2Memory, veryLightArgon2Time); err != nil {
+		t.Fatal(err)
+	}
+	var header struct {
+		Version int
+		Crypto  CryptoJSON
+	}
+	if err := json.Unmarshal(keyjson, &header); err != nil {
+		t.Fatal(err)
+	}
+	if header.Version != versionArgon2id || header.Crypto.KDF != keyHeaderKDFArgon2id {
+		t.Fatalf("wrong key header: version %d, kdf %q", header.Version, header.Crypto.KDF)
+	}
+	if _, err := DecryptKey(keyjson, "bar"); err != ErrDecrypt {
+		t.Fatalf("json key decrypted with bad password: %v", err)
+	}
+	have, err := DecryptKey(keyjson, "foo")
+	if err != nil {
+		t.Fatalf("json key failed to decrypt: %v", err)
+	}
+	if have.Address != key.Address {
+		t.Errorf("key address mismatch: have %x, want %x", have.Address, key.Address)
+	}
+}
+
+// Tests that key files requesting excessive Argon2id parameters are rejected
+// before any memory is allocated.
+func TestKeyDecryptV4Invalid(t *testing.T) {
+	keyjson, err := ioutil.ReadFile(filepath.Join(basedir, "very-light-scrypt.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	key, err := DecryptKey(keyjson, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := EncryptKeyV4(key, "", maxArgon2Memory+1, 1); err == nil {
+		t.Fatal("encrypted with excessive argon2id memory")
+	}
+	if _, err := EncryptKeyV4(key, "", veryLightArgon2Memory, maxArgon2Time+1); err == nil {
+		t.Fatal("encrypted with excessive argon2id time")
+	}
+	tests := []struct {
+		param string
+		value interface{} // nil removes the parameter
+		want  string
+	}{
+		{"memory", json.Number("0"), "invalid argon2id parameters"},
+		{"memory", json.Number("1048577"), "invalid argon2id parameters"},
+		{"time", json.Number("0"), "invalid argon2id parameters"},
+		{"time", json.Number("17"), "invalid argon2id parameters"},
+		{"threads", json.Number("0"), "invalid argon2id parameters"},
+		{"threads", json.Number("17"), "invalid argon2id parameters"},
+		{"dklen", json.Number("16"), "invalid argon2id parameters"},
+		{"memory", nil, "invalid KDF parameter memory"},
+		{"time", nil, "invalid KDF parameter time"},
+		{"threads", "4", "invalid KDF parameter threads"},
+		{"threads", json.Number("1e10"), "invalid KDF parameter threads"},
+		{"dklen", nil, "invalid KDF parameter dklen"},
+		{"salt", nil, "invalid KDF parameter salt"},
+	}
+	for _, tt := range tests {
+		keyjson, err := EncryptKeyV4(key, "", veryLightArgon2Memory, veryLightArgon2Time)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var m map[string]interface{}
+		if err := json.Unmarshal(keyjson, &m); err != nil {
+			t.Fatal(err)
+		}
+		params := m["crypto"].(map[string]interface{})["kdfparams"].(map[string]interface{})
+		if tt.value == nil {
+			delete(params, tt.param)
+		} else {
+			params[tt.param] = tt.value
+		}
+		if keyjson, err = json.Marshal(m); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := DecryptKey(keyjson, ""); err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("%s=%v: wrong error: %v", tt.param, tt.value, err)
+		}
+	}
+	// Argon2id is only valid in version 4 key files
+	keyjson, err = EncryptKeyV4(key, "", veryLightArgon2Memory, veryLightArgon2Time)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var m map[string]interface{}
+	if err := json.Unmarshal(keyjson, &m); err != nil {
+		t.Fatal(err)
+	}
+	m["version"] = version
+	if keyjson, err = json.Marshal(m); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := DecryptKey(keyjson, ""); err == nil || !strings.Contains(err.Error(), "KDF not supported by version 3") {
+		t.Errorf("version 3 argon2id key: wrong error: %v", err)
+	}
+}
+
+// Tests that a keystore re-encrypts existing keys into the version 4 format
+// on update, swapping the files without disturbing the account cache.
+func TestKeyStoreUpdateV4(t *testing.T) {
+	dir, ks := tmpKeyStore(t, true)
+	defer os.RemoveAll(dir)
+
+	a1, err := ks.NewAccount("foo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ks = NewKeyStoreArgon2id(dir, veryLightArgon2Memory, veryLightArgon2Time)
+	if err := ks.Update(a1, "foo", "foo"); err != nil {
+		t.Fatal(err)
+	}
+	keyjson, err := ioutil.ReadFile(a1.URL.Path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var header struct{ Version int }
+	if err := json.Unmarshal(keyjson, &header); err != nil {
+		t.Fatal(err)
+	}
+	if header.Version != versionArgon2id {
+		t.Fatalf("key not re-encrypted: version %d", header.Version)
+	}
+	if accs := ks.Accounts(); len(accs) != 1 || accs[0] != a1 {
+		t.Fatalf("accounts mismatch after update: %v", accs)
+	}
+	if err := ks.Unlock(a1, "foo"); err != nil {
+		t.Fatalf("unlock after update: %v", err)
+	}
+	files, err := ioutil.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != 1 {
+		t.Fatalf("temporary files left behind: %d files in keystore", len(files))
+	}
+}
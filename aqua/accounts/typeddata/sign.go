@@ -0,0 +1,66 @@
+// Copyright 2018 The aquachain Authors
+// This file is part of the aquachain library.
+//
+// The aquachain library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The aquachain library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the aquachain library. If not, see <http://www.gnu.org/licenses/>.
+
+package typeddata
+
+import (
+	"errors"
+
+	"github.com/btcsuite/btcd/btcec/v2"
+	"gitlab.com/aquachain/aquachain/common"
+	"gitlab.com/aquachain/aquachain/crypto"
+)
+
+// Sign calculates the EIP-712 signature of the typed data with the given key.
+//
+// Note, the produced signature conforms to the secp256k1 curve R, S and V values,
+// where the V value will be 27 or 28 for legacy reasons.
+func Sign(typedData *TypedData, key *btcec.PrivateKey) ([]byte, error) {
+	hash, err := typedData.Hash()
+	if err != nil {
+		return nil, err
+	}
+	sig, err := crypto.Sign(hash[:], key)
+	if err != nil {
+		return nil, err
+	}
+	sig[64] += 27 // Transform V from 0/1 to 27/28 according to the yellow paper
+	return sig, nil
+}
+
+// Recover returns the address of the account that produced the EIP-712
+// signature of the typed data. The V value of the signature must be 27 or 28.
+func Recover(typedData *TypedData, sig []byte) (common.Address, error) {
+	if len(sig) != 65 {
+		return common.Address{}, errors.New("signature must be 65 bytes long")
+	}
+	if sig[64] != 27 && sig[64] != 28 {
+		return common.Address{}, errors.New("invalid signature (V is not 27 or 28)")
+	}
+	hash, err := typedData.Hash()
+	if err != nil {
+		return common.Address{}, err
+	}
+	cpy := make([]byte, 65)
+	copy(cpy, sig)
+	cpy[64] -= 27
+
+	pub, err := crypto.SigToPub(hash[:], cpy)
+	if err != nil {
+		return common.Address{}, err
+	}
+	return crypto.PubkeyToAddress(pub), nil
+}
@@ -0,0 +1,473 @@
+// Copyright 2018 The aquachain Authors
+// This file is part of the aquachain library.
+//
+// The aquachain library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The aquachain library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the aquachain library. If not, see <http://www.gnu.org/licenses/>.
+
+// Package typeddata implements EIP-712 hashing and signing of typed structured
+// data, as used by permit-style contracts and the aqua_signTypedData method.
+package typeddata
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"gitlab.com/aquachain/aquachain/common"
+	"gitlab.com/aquachain/aquachain/common/hexutil"
+	"gitlab.com/aquachain/aquachain/common/math"
+	"gitlab.com/aquachain/aquachain/crypto"
+)
+
+// domainType is the name of the type describing the signing domain.
+const domainType = "EIP712Domain"
+
+// maxDepth bounds the nesting of structs and arrays, guarding against cyclic
+// type definitions.
+const maxDepth = 32
+
+var (
+	errMissingDomainType  = errors.New("missing EIP712Domain type definition")
+	errMissingPrimaryType = errors.New("missing primary type")
+	errChainIDMismatch    = errors.New("domain chain id does not match")
+	errTooDeep            = errors.New("typed data nested too deep")
+)
+
+// arrayPattern matches a type name ending in an array suffix like "[]" or "[3]".
+var arrayPattern = regexp.MustCompile(`^(.+)\[(\d*)\]$`)
+
+// Type is a single field of a struct type.
+type Type struct {
+	Name string `json:"name"`
+	Type string `json:"type"`
+}
+
+// Types maps struct type names to their fields, in declaration order.
+type Types map[string][]Type
+
+// TypedDataMessage is a struct value, mapping field names to their values.
+type TypedDataMessage = map[string]interface{}
+
+// TypedDataDomain is the signing domain, binding signatures to a specific
+// contract on a specific chain to prevent replays elsewhere.
+type TypedDataDomain struct {
+	Name              string                `json:"name"`
+	Version           string                `json:"version"`
+	ChainId           *math.HexOrDecimal256 `json:"chainId"`
+	VerifyingContract string                `json:"verifyingContract"`
+	Salt              string                `json:"salt"`
+}
+
+// Map returns the domain as a struct value, including only the set fields.
+func (domain *TypedDataDomain) Map() TypedDataMessage {
+	data := TypedDataMessage{}
+	if domain.ChainId != nil {
+		data["chainId"] = (*big.Int)(domain.ChainId)
+	}
+	if domain.Name != "" {
+		data["name"] = domain.Name
+	}
+	if domain.Version != "" {
+		data["version"] = domain.Version
+	}
+	if domain.VerifyingContract != "" {
+		data["verifyingContract"] = domain.VerifyingContract
+	}
+	if domain.Salt != "" {
+		data["salt"] = domain.Salt
+	}
+	return data
+}
+
+// TypedData is a typed structured data to hash and sign, in the JSON format
+// of eth_signTypedData_v4.
+type TypedData struct {
+	Types       Types            `json:"types"`
+	PrimaryType string           `json:"primaryType"`
+	Domain      TypedDataDomain  `json:"domain"`
+	Message     TypedDataMessage `json:"message"`
+}
+
+// UnmarshalJSON implements json.Unmarshaler, keeping numbers of the message in
+// full precision instead of decoding them as floats.
+func (typedData *TypedData) UnmarshalJSON(input []byte) error {
+	type plain TypedData
+	dec := json.NewDecoder(bytes.NewReader(input))
+	dec.UseNumber()
+	return dec.Decode((*plain)(typedData))
+}
+
+// Validate checks that all types are well formed and refer only to defined
+// struct or atomic types, and that the domain and primary types exist.
+func (typedData *TypedData) Validate() error {
+	if _, ok := typedData.Types[domainType]; !ok {
+		return errMissingDomainType
+	}
+	if _, ok := typedData.Types[typedData.PrimaryType]; !ok || typedData.PrimaryType == "" {
+		return errMissingPrimaryType
+	}
+	for name, fields := range typedData.Types {
+		if name == "" || isPrimitive(name) || strings.ContainsAny(name, "[](), ") {
+			return fmt.Errorf("invalid type name %q", name)
+		}
+		seen := make(map[string]bool, len(fields))
+		for _, field := range fields {
+			if field.Name == "" || seen[field.Name] {
+				return fmt.Errorf("type %s: invalid or duplicate field name %q", name, field.Name)
+			}
+			seen[field.Name] = true
+
+			base := stripArrays(field.Type)
+			if _, ok := typedData.Types[base]; !ok && !isPrimitive(base) {
+				return fmt.Errorf("type %s: unknown type %q of field %s", name, field.Type, field.Name)
+			}
+		}
+	}
+	return nil
+}
+
+// ValidateChainID checks that the domain, if it specifies a chain id, is bound
+// to the given chain.
+func (typedData *TypedData) ValidateChainID(chainID *big.Int) error {
+	if typedData.Domain.ChainId == nil {
+		return nil
+	}
+	if (*big.Int)(typedData.Domain.ChainId).Cmp(chainID) != 0 {
+		return fmt.Errorf("%w: have %d, want %d", errChainIDMismatch, (*big.Int)(typedData.Domain.ChainId), chainID)
+	}
+	return nil
+}
+
+// Hash returns the EIP-712 hash to be signed:
+//
+//	keccak256("\x19\x01" ‖ domainSeparator ‖ hashStruct(message))
+func (typedData *TypedData) Hash() (common.Hash, error) {
+	if err := typedData.Validate(); err != nil {
+		return common.Hash{}, err
+	}
+	domainSeparator, err := typedData.HashStruct(domainType, typedData.Domain.Map())
+	if err != nil {
+		return common.Hash{}, fmt.Errorf("domain: %v", err)
+	}
+	messageHash, err := typedData.HashStruct(typedData.PrimaryType, typedData.Message)
+	if err != nil {
+		return common.Hash{}, fmt.Errorf("message: %v", err)
+	}
+	return crypto.Keccak256Hash([]byte{0x19, 0x01}, domainSeparator[:], messageHash[:]), nil
+}
+
+// HashStruct returns the hash of a struct value of the given type:
+//
+//	keccak256(typeHash ‖ encodeData(data))
+func (typedData *TypedData) HashStruct(primaryType string, data TypedDataMessage) (common.Hash, error) {
+	return typedData.hashStruct(primaryType, data, 0)
+}
+
+func (typedData *TypedData) hashStruct(primaryType string, data TypedDataMessage, depth int) (common.Hash, error) {
+	encoded, err := typedData.encodeData(primaryType, data, depth)
+	if err != nil {
+		return common.Hash{}, err
+	}
+	return crypto.Keccak256Hash(encoded), nil
+}
+
+// TypeHash returns the hash of the encoded type.
+func (typedData *TypedData) TypeHash(primaryType string) common.Hash {
+	return crypto.Keccak256Hash([]byte(typedData.EncodeType(primaryType)))
+}
+
+// EncodeType returns the signature of the type followed by the signatures of
+// all the struct types it references, in alphabetical order, e.g.
+//
+//	Mail(Person from,Person to,string contents)Person(string name,address wallet)
+func (typedData *TypedData) EncodeType(primaryType string) string {
+	deps := typedData.Dependencies(primaryType, nil)
+	if len(deps) > 1 {
+		sort.Strings(deps[1:])
+	}
+	var buf strings.Builder
+	for _, dep := range deps {
+		buf.WriteString(dep)
+		buf.WriteByte('(')
+		for i, field := range typedData.Types[dep] {
+			if i > 0 {
+				buf.WriteByte(',')
+			}
+			buf.WriteString(field.Type)
+			buf.WriteByte(' ')
+			buf.WriteString(field.Name)
+		}
+		buf.WriteByte(')')
+	}
+	return buf.String()
+}
+
+// Dependencies returns the given type followed by all the struct types it
+// references, directly or indirectly, skipping the ones already found.
+func (typedData *TypedData) Dependencies(primaryType string, found []string) []string {
+	primaryType = stripArrays(primaryType)
+	for _, dep := range found {
+		if dep == primaryType {
+			return found
+		}
+	}
+	if _, ok := typedData.Types[primaryType]; !ok {
+		return found
+	}
+	found = append(found, primaryType)
+	for _, field := range typedData.Types[primaryType] {
+		found = typedData.Dependencies(field.Type, found)
+	}
+	return found
+}
+
+// EncodeData returns the type hash of the struct followed by the encoding of
+// each of its fields in declaration order.
+func (typedData *TypedData) EncodeData(primaryType string, data TypedDataMessage) ([]byte, error) {
+	return typedData.encodeData(primaryType, data, 0)
+}
+
+func (typedData *TypedData) encodeData(primaryType string, data TypedDataMessage, depth int) ([]byte, error) {
+	if depth > maxDepth {
+		return nil, errTooDeep
+	}
+	fields, ok := typedData.Types[primaryType]
+	if !ok {
+		return nil, fmt.Errorf("unknown type %q", primaryType)
+	}
+	if len(data) > len(fields) {
+		return nil, fmt.Errorf("%s: unexpected extra fields", primaryType)
+	}
+	buf := bytes.NewBuffer(typedData.TypeHash(primaryType).Bytes())
+	for _, field := range fields {
+		value, ok := data[field.Name]
+		if !ok {
+			return nil, fmt.Errorf("%s: missing field %s", primaryType, field.Name)
+		}
+		encoded, err := typedData.encodeValue(field.Type, value, depth+1)
+		if err != nil {
+			return nil, fmt.Errorf("%s.%s: %v", primaryType, field.Name, err)
+		}
+		buf.Write(encoded)
+	}
+	return buf.Bytes(), nil
+}
+
+// encodeValue encodes a single value of the given type into 32 bytes.
+func (typedData *TypedData) encodeValue(typ string, value interface{}, depth int) ([]byte, error) {
+	if depth > maxDepth {
+		return nil, errTooDeep
+	}
+	// Arrays are encoded as the hash of their concatenated encoded elements
+	if match := arrayPattern.FindStringSubmatch(typ); match != nil {
+		items, ok := value.([]interface{})
+		if !ok {
+			return nil, fmt.Errorf("invalid array value %v", value)
+		}
+		if match[2] != "" {
+			length, err := strconv.Atoi(match[2])
+			if err != nil || length != len(items) {
+				return nil, fmt.Errorf("array length mismatch: have %d, want %s", len(items), match[2])
+			}
+		}
+		var buf bytes.Buffer
+		for i, item := range items {
+			encoded, err := typedData.encodeValue(match[1], item, depth+1)
+			if err != nil {
+				return nil, fmt.Errorf("[%d]: %v", i, err)
+			}
+			buf.Write(encoded)
+		}
+		return crypto.Keccak256(buf.Bytes()), nil
+	}
+	// Structs are encoded as their hash
+	if _, ok := typedData.Types[typ]; ok {
+		data, ok := value.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("invalid %s value %v", typ, value)
+		}
+		hash, err := typedData.hashStruct(typ, data, depth)
+		if err != nil {
+			return nil, err
+		}
+		return hash[:], nil
+	}
+	return encodePrimitive(typ, value)
+}
+
+// encodePrimitive encodes a value of an atomic or dynamic type into 32 bytes.
+func encodePrimitive(typ string, value interface{}) ([]byte, error) {
+	switch {
+	case typ == "address":
+		s, ok := value.(string)
+		if !ok || !common.IsHexAddress(s) {
+			return nil, fmt.Errorf("invalid address %v", value)
+		}
+		return common.LeftPadBytes(common.HexToAddress(s).Bytes(), 32), nil
+
+	case typ == "bool":
+		b, ok := value.(bool)
+		if !ok {
+			return nil, fmt.Errorf("invalid bool %v", value)
+		}
+		if b {
+			return math.PaddedBigBytes(common.Big1, 32), nil
+		}
+		return make([]byte, 32), nil
+
+	case typ == "string":
+		s, ok := value.(string)
+		if !ok {
+			return nil, fmt.Errorf("invalid string %v", value)
+		}
+		return crypto.Keccak256([]byte(s)), nil
+
+	case typ == "bytes":
+		b, err := parseBytes(value)
+		if err != nil {
+			return nil, err
+		}
+		return crypto.Keccak256(b), nil
+
+	case strings.HasPrefix(typ, "bytes"):
+		length, err := strconv.Atoi(typ[len("bytes"):])
+		if err != nil || length < 1 || length > 32 {
+			return nil, fmt.Errorf("invalid type %s", typ)
+		}
+		b, err := parseBytes(value)
+		if err != nil {
+			return nil, err
+		}
+		if len(b) > length {
+			return nil, fmt.Errorf("%s value too long: %d bytes", typ, len(b))
+		}
+		return common.RightPadBytes(b, 32), nil
+
+	case strings.HasPrefix(typ, "int"), strings.HasPrefix(typ, "uint"):
+		signed := strings.HasPrefix(typ, "int")
+		bits, err := integerSize(typ)
+		if err != nil {
+			return nil, err
+		}
+		n, err := parseInteger(value)
+		if err != nil {
+			return nil, err
+		}
+		if !signed && (n.Sign() < 0 || n.BitLen() > bits) {
+			return nil, fmt.Errorf("%s value %d out of range", typ, n)
+		}
+		if signed {
+			limit := new(big.Int).Lsh(common.Big1, uint(bits-1))
+			if n.Cmp(limit) >= 0 || n.Cmp(new(big.Int).Neg(limit)) < 0 {
+				return nil, fmt.Errorf("%s value %d out of range", typ, n)
+			}
+		}
+		return math.PaddedBigBytes(math.U256(n), 32), nil
+	}
+	return nil, fmt.Errorf("unknown type %s", typ)
+}
+
+// isPrimitive returns whether the type is an atomic or dynamic type.
+func isPrimitive(typ string) bool {
+	switch {
+	case typ == "address", typ == "bool", typ == "string", typ == "bytes":
+		return true
+	case strings.HasPrefix(typ, "bytes"):
+		length, err := strconv.Atoi(typ[len("bytes"):])
+		return err == nil && length >= 1 && length <= 32 && typ == "bytes"+strconv.Itoa(length)
+	case strings.HasPrefix(typ, "int"), strings.HasPrefix(typ, "uint"):
+		_, err := integerSize(typ)
+		return err == nil
+	}
+	return false
+}
+
+// integerSize returns the bit size of an intN or uintN type.
+func integerSize(typ string) (int, error) {
+	size := strings.TrimPrefix(strings.TrimPrefix(typ, "u"), "int")
+	if size == "" {
+		return 256, nil
+	}
+	bits, err := strconv.Atoi(size)
+	if err != nil || bits < 8 || bits > 256 || bits%8 != 0 || size != strconv.Itoa(bits) {
+		return 0, fmt.Errorf("invalid type %s", typ)
+	}
+	return bits, nil
+}
+
+// stripArrays returns the element type of a (possibly nested) array type.
+func stripArrays(typ string) string {
+	for {
+		match := arrayPattern.FindStringSubmatch(typ)
+		if match == nil {
+			return typ
+		}
+		typ = match[1]
+	}
+}
+
+// parseBytes parses a 0x prefixed hex string or a byte slice.
+func parseBytes(value interface{}) ([]byte, error) {
+	switch v := value.(type) {
+	case string:
+		return hexutil.Decode(v)
+	case []byte:
+		return v, nil
+	case hexutil.Bytes:
+		return v, nil
+	}
+	return nil, fmt.Errorf("invalid bytes %v", value)
+}
+
+// parseInteger parses a JSON number, a hex or decimal string, or a Go integer.
+func parseInteger(value interface{}) (*big.Int, error) {
+	switch v := value.(type) {
+	case *big.Int:
+		return new(big.Int).Set(v), nil
+	case json.Number:
+		return parseIntegerString(string(v))
+	case string:
+		return parseIntegerString(v)
+	case float64:
+		if v != float64(int64(v)) {
+			return nil, fmt.Errorf("invalid integer %v", v)
+		}
+		return big.NewInt(int64(v)), nil
+	case int:
+		return big.NewInt(int64(v)), nil
+	case int64:
+		return big.NewInt(v), nil
+	case uint64:
+		return new(big.Int).SetUint64(v), nil
+	}
+	return nil, fmt.Errorf("invalid integer %v", value)
+}
+
+// parseIntegerString parses a possibly negative hex or decimal integer.
+func parseIntegerString(s string) (*big.Int, error) {
+	neg := strings.HasPrefix(s, "-")
+	n, ok := math.ParseBig256(strings.TrimPrefix(s, "-"))
+	if !ok || s == "" {
+		return nil, fmt.Errorf("invalid integer %q", s)
+	}
+	if neg {
+		n.Neg(n)
+	}
+	return n, nil
+}
@@ -0,0 +1,252 @@
+// Copyright 2018 The aquachain Authors
+// This file is part of the aquachain library.
+//
+// The aquachain library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The aquachain library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the aquachain library. If not, see <http://www.gnu.org/licenses/>.
+
+package typeddata
+
+import (
+	"encoding/json"
+	"math/big"
+	"strings"
+	"testing"
+
+	"gitlab.com/aquachain/aquachain/common"
+	"gitlab.com/aquachain/aquachain/common/hexutil"
+	"gitlab.com/aquachain/aquachain/crypto"
+)
+
+// mailJSON is the example of the EIP-712 specification.
+const mailJSON = `{
+	"types": {
+		"EIP712Domain": [
+			{"name": "name", "type": "string"},
+			{"name": "version", "type": "string"},
+			{"name": "chainId", "type": "uint256"},
+			{"name": "verifyingContract", "type": "address"}
+		],
+		"Person": [
+			{"name": "name", "type": "string"},
+			{"name": "wallet", "type": "address"}
+		],
+		"Mail": [
+			{"name": "from", "type": "Person"},
+			{"name": "to", "type": "Person"},
+			{"name": "contents", "type": "string"}
+		]
+	},
+	"primaryType": "Mail",
+	"domain": {
+		"name": "Ether Mail",
+		"version": "1",
+		"chainId": 1,
+		"verifyingContract": "0xCcCCccccCCCCcCCCCCCcCcCccCcCCCcCcccccccC"
+	},
+	"message": {
+		"from": {"name": "Cow", "wallet": "0xCD2a3d9F938E13CD947Ec05AbC7FE734Df8DD826"},
+		"to": {"name": "Bob", "wallet": "0xbBbBBBBbbBBBbbbBbbBbbbbBBbBbbbbBbBbbBBbB"},
+		"contents": "Hello, Bob!"
+	}
+}`
+
+func loadTypedData(t *testing.T, input string) *TypedData {
+	typedData := new(TypedData)
+	if err := json.Unmarshal([]byte(input), typedData); err != nil {
+		t.Fatalf("failed to decode typed data: %v", err)
+	}
+	return typedData
+}
+
+func TestMailExample(t *testing.T) {
+	typedData := loadTypedData(t, mailJSON)
+
+	if have, want := typedData.EncodeType("Mail"), "Mail(Person from,Person to,string contents)Person(string name,address wallet)"; have != want {
+		t.Errorf("encoded type mismatch:\nhave %s\nwant %s", have, want)
+	}
+	if have, want := typedData.TypeHash("Mail"), common.HexToHash("0xa0cedeb2dc280ba39b857546d74f5549c3a1d7bdc2dd96bf881f76108e23dac2"); have != want {
+		t.Errorf("type hash mismatch: have %x, want %x", have, want)
+	}
+	domainSeparator, err := typedData.HashStruct("EIP712Domain", typedData.Domain.Map())
+	if err != nil {
+		t.Fatalf("failed to hash domain: %v", err)
+	}
+	if want := common.HexToHash("0xf2cee375fa42b42143804025fc449deafd50cc031ca257e0b194a650a912090f"); domainSeparator != want {
+		t.Errorf("domain separator mismatch: have %x, want %x", domainSeparator, want)
+	}
+	messageHash, err := typedData.HashStruct("Mail", typedData.Message)
+	if err != nil {
+		t.Fatalf("failed to hash message: %v", err)
+	}
+	if want := common.HexToHash("0xc52c0ee5d84264471806290a3f2c4cecfc5490626bf912d01f240d7a274b371e"); messageHash != want {
+		t.Errorf("message hash mismatch: have %x, want %x", messageHash, want)
+	}
+	hash, err := typedData.Hash()
+	if err != nil {
+		t.Fatalf("failed to hash typed data: %v", err)
+	}
+	if want := common.HexToHash("0xbe609aee343fb3c4b28e1df9e632fca64fcfaede20f02e86244efddf30957bd2"); hash != want {
+		t.Errorf("hash mismatch: have %x, want %x", hash, want)
+	}
+	// Sign with the key of the specification and recover the signer
+	key := crypto.ToECDSAUnsafe(crypto.Keccak256([]byte("cow")))
+	sig, err := Sign(typedData, key)
+	if err != nil {
+		t.Fatalf("failed to sign: %v", err)
+	}
+	want := hexutil.MustDecode("0x4355c47d63924e8a72e509b65029052eb6c299d53a04e167c5775fd466751c9d07299936d304c153f6443dfa05f40ff007d72911b6f72307f996231605b915621c")
+	if hexutil.Encode(sig) != hexutil.Encode(want) {
+		t.Errorf("signature mismatch:\nhave %x\nwant %x", sig, want)
+	}
+	signer, err := Recover(typedData, sig)
+	if err != nil || signer != common.HexToAddress("0xCD2a3d9F938E13CD947Ec05AbC7FE734Df8DD826") {
+		t.Errorf("signer mismatch: have %x (%v)", signer, err)
+	}
+}
+
+func TestChainID(t *testing.T) {
+	typedData := loadTypedData(t, mailJSON)
+	if err := typedData.ValidateChainID(big.NewInt(1)); err != nil {
+		t.Errorf("matching chain id rejected: %v", err)
+	}
+	if err := typedData.ValidateChainID(big.NewInt(61717561)); err == nil {
+		t.Errorf("mismatching chain id accepted")
+	}
+	typedData.Domain.ChainId = nil
+	if err := typedData.ValidateChainID(big.NewInt(61717561)); err != nil {
+		t.Errorf("domain without chain id rejected: %v", err)
+	}
+}
+
+// arraysJSON exercises arrays of atomic, dynamic and struct types along with
+// nested arrays and fixed length arrays.
+const arraysJSON = `{
+	"types": {
+		"EIP712Domain": [{"name": "chainId", "type": "uint256"}],
+		"Item": [
+			{"name": "id", "type": "uint8"},
+			{"name": "delta", "type": "int16"},
+			{"name": "tag", "type": "bytes4"}
+		],
+		"Order": [
+			{"name": "items", "type": "Item[]"},
+			{"name": "owners", "type": "address[2]"},
+			{"name": "notes", "type": "string[]"},
+			{"name": "grid", "type": "uint256[][]"},
+			{"name": "payload", "type": "bytes"},
+			{"name": "final", "type": "bool"}
+		]
+	},
+	"primaryType": "Order",
+	"domain": {"chainId": "0x3adbc39"},
+	"message": {
+		"items": [{"id": 1, "delta": -5, "tag": "0xdeadbeef"}, {"id": "0xff", "delta": "300", "tag": "0x01"}],
+		"owners": ["0x0000000000000000000000000000000000000001", "0x0000000000000000000000000000000000000002"],
+		"notes": ["a", "b"],
+		"grid": [[1, 2], [], ["115792089237316195423570985008687907853269984665640564039457584007913129639935"]],
+		"payload": "0x0102",
+		"final": true
+	}
+}`
+
+func TestArrays(t *testing.T) {
+	typedData := loadTypedData(t, arraysJSON)
+	if have, want := typedData.EncodeType("Order"), "Order(Item[] items,address[2] owners,string[] notes,uint256[][] grid,bytes payload,bool final)Item(uint8 id,int16 delta,bytes4 tag)"; have != want {
+		t.Errorf("encoded type mismatch:\nhave %s\nwant %s", have, want)
+	}
+	if _, err := typedData.Hash(); err != nil {
+		t.Fatalf("failed to hash: %v", err)
+	}
+	if err := typedData.ValidateChainID(big.NewInt(61717561)); err != nil {
+		t.Errorf("chain id rejected: %v", err)
+	}
+	// Check the encoding of an array against its definition
+	items := typedData.Message["items"].([]interface{})
+	var concat []byte
+	for _, item := range items {
+		hash, err := typedData.HashStruct("Item", item.(map[string]interface{}))
+		if err != nil {
+			t.Fatal(err)
+		}
+		concat = append(concat, hash[:]...)
+	}
+	encoded, err := typedData.EncodeData("Order", typedData.Message)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if have, want := encoded[32:64], crypto.Keccak256(concat); hexutil.Encode(have) != hexutil.Encode(want) {
+		t.Errorf("array encoding mismatch: have %x, want %x", have, want)
+	}
+}
+
+func TestInvalid(t *testing.T) {
+	tests := []struct {
+		name     string
+		old, new string
+		err      string
+	}{
+		{"no domain type", `"EIP712Domain"`, `"Domain"`, "missing EIP712Domain"},
+		{"unknown type", `"type": "Item[]"`, `"type": "Thing[]"`, "unknown type"},
+		{"fixed length", `"address[2]"`, `"address[3]"`, "array length mismatch"},
+		{"uint8 overflow", `"0xff"`, `"0x100"`, "out of range"},
+		{"negative uint", `"id": 1`, `"id": -1`, "out of range"},
+		{"int16 overflow", `"300"`, `"32768"`, "out of range"},
+		{"bytes4 overflow", `"0xdeadbeef"`, `"0xdeadbeef00"`, "too long"},
+		{"bad address", `"0x0000000000000000000000000000000000000001"`, `"0x01"`, "invalid address"},
+		{"missing field", `"final": true`, `"other": true`, "missing field"},
+		{"extra field", `"final": true`, `"final": true, "other": 1`, "extra fields"},
+		{"bad bool", `"final": true`, `"final": 1`, "invalid bool"},
+	}
+	for _, tt := range tests {
+		typedData := loadTypedData(t, strings.Replace(arraysJSON, tt.old, tt.new, 1))
+		_, err := typedData.Hash()
+		if err == nil || !strings.Contains(err.Error(), tt.err) {
+			t.Errorf("%s: error mismatch: have %v, want %q", tt.name, err, tt.err)
+		}
+	}
+}
+
+func TestCyclicTypes(t *testing.T) {
+	typedData := loadTypedData(t, `{
+		"types": {
+			"EIP712Domain": [],
+			"Node": [{"name": "next", "type": "Node"}]
+		},
+		"primaryType": "Node",
+		"domain": {},
+		"message": {"next": {"next": {}}}
+	}`)
+	if have, want := typedData.EncodeType("Node"), "Node(Node next)"; have != want {
+		t.Errorf("encoded type mismatch: have %s, want %s", have, want)
+	}
+	if _, err := typedData.Hash(); err == nil {
+		t.Errorf("unterminated cyclic value accepted")
+	}
+}
+
+func TestJSONRoundtrip(t *testing.T) {
+	for _, input := range []string{mailJSON, arraysJSON} {
+		typedData := loadTypedData(t, input)
+		want, err := typedData.Hash()
+		if err != nil {
+			t.Fatal(err)
+		}
+		blob, err := json.Marshal(typedData)
+		if err != nil {
+			t.Fatalf("failed to encode: %v", err)
+		}
+		if have, err := loadTypedData(t, string(blob)).Hash(); err != nil || have != want {
+			t.Errorf("hash mismatch after roundtrip: have %x (%v), want %x", have, err, want)
+		}
+	}
+}
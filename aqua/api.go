@@ -34,6 +34,7 @@ import (
 	"gitlab.com/aquachain/aquachain/core/types"
 	"gitlab.com/aquachain/aquachain/internal/aquaapi"
 	"gitlab.com/aquachain/aquachain/opt/miner"
+	"gitlab.com/aquachain/aquachain/opt/stratum"
 	"gitlab.com/aquachain/aquachain/params"
 	"gitlab.com/aquachain/aquachain/rlp"
 	"gitlab.com/aquachain/aquachain/rpc"
@@ -266,6 +267,15 @@ func (api *PrivateMinerAPI) GetHashrate() uint64 {
 	return uint64(api.e.miner.HashRate())
 }
 
+// StratumWorkers returns the share statistics of workers connected to the
+// stratum server.
+func (api *PrivateMinerAPI) StratumWorkers() ([]stratum.WorkerStats, error) {
+	if api.e.stratum == nil {
+		return nil, fmt.Errorf("stratum server not enabled")
+	}
+	return api.e.stratum.Workers(), nil
+}
+
 // PrivateAdminAPI is the collection of Aquachain full node-related APIs
 // exposed over the private admin endpoint.
 type PrivateAdminAPI struct {
@@ -18,6 +18,7 @@ package aqua
 
 import (
 	"context"
+	"fmt"
 	"math/big"
 
 	"gitlab.com/aquachain/aquachain"
@@ -105,6 +106,19 @@ func (b *AquaApiBackend) StateAndHeaderByNumber(ctx context.Context, blockNr rpc
 	return stateDb, header, err
 }
 
+func (b *AquaApiBackend) StateAndHeaderByNumberOrHash(ctx context.Context, blockNrOrHash rpc.BlockNumberOrHash) (*state.StateDB, *types.Header, error) {
+	if blockNr, ok := blockNrOrHash.Number(); ok {
+		return b.StateAndHeaderByNumber(ctx, blockNr)
+	}
+	hash, _ := blockNrOrHash.Hash()
+	header := b.aqua.blockchain.GetHeaderByHash(hash)
+	if header == nil {
+		return nil, nil, fmt.Errorf("block %x not found", hash)
+	}
+	stateDb, err := b.aqua.BlockChain().StateAt(header.Root)
+	return stateDb, header, err
+}
+
 func (b *AquaApiBackend) GetBlock(ctx context.Context, blockHash common.Hash) (*types.Block, error) {
 	return b.aqua.blockchain.GetBlockByHash(blockHash), nil
 }
@@ -185,6 +199,10 @@ func (b *AquaApiBackend) Stats() (pending int, queued int) {
 	return b.aqua.txPool.Stats()
 }
 
+func (b *AquaApiBackend) TxPoolStatus() core.TxPoolStatus {
+	return b.aqua.txPool.Summary()
+}
+
 func (b *AquaApiBackend) TxPoolContent() (map[common.Address]types.Transactions, map[common.Address]types.Transactions) {
 	return b.aqua.TxPool().Content()
 }
@@ -201,6 +219,13 @@ func (b *AquaApiBackend) ProtocolVersion() int {
 	return b.aqua.AquaVersion()
 }
 
+func (b *AquaApiBackend) PeerCount() int {
+	if b.aqua.protocolManager == nil {
+		return 0
+	}
+	return b.aqua.protocolManager.peers.Len()
+}
+
 func (b *AquaApiBackend) SuggestPrice(ctx context.Context) (*big.Int, error) {
 	return b.gpo.SuggestPrice(ctx)
 }
@@ -0,0 +1,106 @@
+// Copyright 2018 The aquachain Authors
+// This file is part of the aquachain library.
+//
+// The aquachain library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The aquachain library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the aquachain library. If not, see <http://www.gnu.org/licenses/>.
+
+package aqua
+
+import (
+	"context"
+	"math/big"
+	"testing"
+
+	"gitlab.com/aquachain/aquachain/aquadb"
+	"gitlab.com/aquachain/aquachain/common"
+	"gitlab.com/aquachain/aquachain/common/hexutil"
+	"gitlab.com/aquachain/aquachain/consensus/aquahash"
+	"gitlab.com/aquachain/aquachain/core"
+	"gitlab.com/aquachain/aquachain/core/vm"
+	"gitlab.com/aquachain/aquachain/internal/aquaapi"
+	"gitlab.com/aquachain/aquachain/params"
+	"gitlab.com/aquachain/aquachain/rpc"
+	rpcclient "gitlab.com/aquachain/aquachain/rpc/rpcclient"
+)
+
+// revertingStore is a contract which, when called without input, calls itself
+// with one byte of input and returns its storage slot 0. The inner call stores
+// 2 into slot 0 and reverts.
+var revertingStore = common.FromHex("36601d57" + "6000600060016000600030" + "5af150" +
+	"6000546000526020" + "6000f3" + "5b6002600055" + "60006000fd")
+
+// newTraceClient returns a client connected in-process to the debug API of a
+// chain holding only a funded genesis block.
+func newTraceClient(t *testing.T) *rpcclient.Client {
+	var (
+		db    = aquadb.NewMemDatabase()
+		gspec = &core.Genesis{
+			Config: params.TestChainConfig,
+			Alloc:  core.GenesisAlloc{testBank: {Balance: big.NewInt(1000000)}},
+		}
+	)
+	gspec.MustCommit(db)
+	blockchain, err := core.NewBlockChain(context.TODO(), db, nil, gspec.Config, aquahash.NewFaker(), vm.Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(blockchain.Stop)
+
+	aqua := &Aquachain{chainConfig: gspec.Config, blockchain: blockchain, chainDb: db}
+	srv := rpc.NewServer()
+	if _, err := srv.RegisterName("debug", NewPrivateDebugAPI(gspec.Config, aqua)); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(srv.Stop)
+	client := rpcclient.DialInProc(context.Background(), srv)
+	t.Cleanup(client.Close)
+	return client
+}
+
+// Tests that debug_traceCall applies state overrides, and that storage writes
+// of a reverted inner call are undone even on overridden storage.
+func TestTraceCallStateOverride(t *testing.T) {
+	client := newTraceClient(t)
+	contract := common.BytesToAddress([]byte{0xc0})
+
+	tests := []struct {
+		state map[common.Hash]common.Hash
+		want  common.Hash
+	}{
+		{nil, common.Hash{}},
+		{map[common.Hash]common.Hash{{}: common.BigToHash(big.NewInt(1))}, common.BigToHash(big.NewInt(1))},
+	}
+	for i, tt := range tests {
+		code := hexutil.Bytes(revertingStore)
+		account := aquaapi.OverrideAccount{Code: &code}
+		if tt.state != nil {
+			account.State = &tt.state
+		}
+		config := &TraceCallConfig{StateOverrides: &aquaapi.StateOverride{contract: account}}
+		args := aquaapi.CallArgs{From: testBank, To: &contract, Gas: 100000, GasPrice: hexutil.Big(*big.NewInt(1))}
+
+		var res aquaapi.ExecutionResult
+		if err := client.CallContext(context.Background(), &res, "debug_traceCall", args, "latest", config); err != nil {
+			t.Fatalf("test %d: trace failed: %v", i, err)
+		}
+		if res.Failed {
+			t.Errorf("test %d: call failed", i)
+		}
+		if have := common.HexToHash(res.ReturnValue); have != tt.want {
+			t.Errorf("test %d: slot mismatch: have %x, want %x", i, have, tt.want)
+		}
+		if len(res.StructLogs) == 0 {
+			t.Errorf("test %d: no struct logs", i)
+		}
+	}
+}
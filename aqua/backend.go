@@ -48,6 +48,7 @@ import (
 	"gitlab.com/aquachain/aquachain/node"
 
 	"gitlab.com/aquachain/aquachain/opt/miner"
+	"gitlab.com/aquachain/aquachain/opt/stratum"
 	"gitlab.com/aquachain/aquachain/p2p"
 	"gitlab.com/aquachain/aquachain/params"
 	"gitlab.com/aquachain/aquachain/rlp"
@@ -82,6 +83,7 @@ type Aquachain struct {
 	ApiBackend *AquaApiBackend
 
 	miner    *miner.Miner
+	stratum  *stratum.Server
 	gasPrice *big.Int
 	aquabase common.Address
 
@@ -149,7 +151,7 @@ func New(ctx context.Context, nodectx *node.ServiceContext, config *config.Aquac
 	//}
 	var (
 		vmConfig    = vm.Config{EnablePreimageRecording: config.EnablePreimageRecording}
-		cacheConfig = &core.CacheConfig{Disabled: config.NoPruning, TrieNodeLimit: config.TrieCache, TrieTimeLimit: config.TrieTimeout}
+		cacheConfig = &core.CacheConfig{Disabled: config.NoPruning, TrieNodeLimit: config.TrieCache, TrieTimeLimit: config.TrieTimeout, AncientThreshold: config.AncientThreshold, Snapshot: config.Snapshot}
 	)
 	aqua.blockchain, err = core.NewBlockChain(ctx, chainDb, cacheConfig, aqua.chainConfig, aqua.engine, vmConfig)
 	if err != nil {
@@ -173,6 +175,16 @@ func New(ctx context.Context, nodectx *node.ServiceContext, config *config.Aquac
 	}
 	aqua.miner = miner.New(aqua, aqua.chainConfig, aqua.EventMux(), aqua.engine)
 	aqua.miner.SetExtra(makeExtraData(config.ExtraData))
+	if config.Stratum.Addr != "" {
+		agent := miner.NewRemoteAgent(aqua.blockchain, aqua.engine)
+		aqua.miner.Register(agent)
+		aqua.stratum = stratum.New(config.Stratum, agent, func() error {
+			if aqua.IsMining() {
+				return nil
+			}
+			return aqua.StartMining(false)
+		})
+	}
 
 	aqua.ApiBackend = &AquaApiBackend{aqua, nil}
 	gpoParams := config.GPO
@@ -255,11 +267,11 @@ func DecodeExtraData(extra []byte) (version [3]uint8, osname string, extradata [
 
 // CreateDB creates the chain database.
 func CreateDB(ctx *node.ServiceContext, config *config.Aquaconfig, name string) (aquadb.Database, error) {
-	db, err := ctx.OpenDatabase(name, config.DatabaseCache, config.DatabaseHandles)
+	db, err := ctx.OpenDatabaseWithFreezer(name, config.DatabaseCache, config.DatabaseHandles, config.AncientDir)
 	if err != nil {
 		return nil, err
 	}
-	if db, ok := db.(*aquadb.LDBDatabase); ok {
+	if db, ok := db.(interface{ Meter(prefix string) }); ok {
 		db.Meter("aqua/db/chaindata/")
 	}
 	return db, nil
@@ -485,12 +497,21 @@ func (s *Aquachain) Start(srvr *p2p.Server) error {
 	// Start the RPC service
 	s.netRPCService = aquaapi.NewPublicNetAPI(srvr, s.NetVersion())
 
+	// Report the state of the node along with the other metrics
+	s.registerNodeMetrics(srvr)
+
 	// Figure out a max peers count based on the server limits
 	maxPeers := srvr.MaxPeers
 	// Start the networking layer
 	if s.protocolManager != nil {
 		s.protocolManager.Start(maxPeers)
 	}
+	// Start the stratum server for remote miners
+	if s.stratum != nil {
+		if err := s.stratum.Start(); err != nil {
+			return fmt.Errorf("stratum: %w", err)
+		}
+	}
 	return nil
 }
 
@@ -508,12 +529,16 @@ func (s *Aquachain) Stop() error {
 	if s.stopDbUpgrade != nil {
 		s.stopDbUpgrade()
 	}
+	unregisterNodeMetrics()
 	s.bloomIndexer.Close()
 	s.blockchain.Stop()
 	if s.protocolManager != nil {
 		s.protocolManager.Stop()
 	}
 	s.txPool.Stop()
+	if s.stratum != nil {
+		s.stratum.Stop()
+	}
 	s.miner.Stop()
 	s.eventMux.Stop()
 
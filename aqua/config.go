@@ -28,6 +28,7 @@ import (
 	"gitlab.com/aquachain/aquachain/common/sense"
 	"gitlab.com/aquachain/aquachain/consensus/aquahash"
 	"gitlab.com/aquachain/aquachain/core"
+	"gitlab.com/aquachain/aquachain/opt/stratum"
 )
 
 type Config = config.Aquaconfig // TODO remove
@@ -55,6 +56,7 @@ func NewDefaultConfig() *Config {
 		GasPrice:      1_000_000_000, // 1.00 gwei
 		NoPruning:     true,
 		TxPool:        core.DefaultTxPoolConfig,
+		Stratum:       stratum.DefaultConfig,
 		GPO: gasprice.Config{
 			Blocks:     20,
 			Percentile: 60,
@@ -50,7 +50,7 @@ func upgradeDeduplicateData(db aquadb.Database) func() error {
 
 	go func() {
 		// Create an iterator to read the entire database and covert old lookup entires
-		it := db.(*aquadb.LDBDatabase).NewIterator()
+		it := db.(aquadb.Iteratee).NewIterator()
 		defer func() {
 			if it != nil {
 				it.Release()
@@ -100,7 +100,7 @@ func upgradeDeduplicateData(db aquadb.Database) func() error {
 			converted++
 			if converted%100000 == 0 {
 				it.Release()
-				it = db.(*aquadb.LDBDatabase).NewIterator()
+				it = db.(aquadb.Iteratee).NewIterator()
 				it.Seek(key)
 
 				log.Info("Deduplicating database entries", "deduped", converted)
@@ -0,0 +1,67 @@
+// Copyright 2018 The aquachain Authors
+// This file is part of the aquachain library.
+//
+// The aquachain library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The aquachain library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the aquachain library. If not, see <http://www.gnu.org/licenses/>.
+
+package aqua
+
+import (
+	"io"
+
+	"gitlab.com/aquachain/aquachain/core/forkid"
+	"gitlab.com/aquachain/aquachain/p2p/enr"
+	"gitlab.com/aquachain/aquachain/rlp"
+)
+
+// enrEntry is the ENR entry which advertises the aqua protocol on the discovery
+// network, with the fork ID of the node.
+type enrEntry struct {
+	ForkID forkid.ID // Fork identifier per EIP-2124
+
+	// Ignore additional fields (for forward compatibility).
+	Rest []rlp.RawValue `rlp:"tail"`
+}
+
+// ENRKey implements enr.Entry.
+func (e enrEntry) ENRKey() string {
+	return "aqua"
+}
+
+// currentENREntry is the enrEntry of the local node, encoded with the fork ID
+// at the current head of the chain whenever the node record is built.
+type currentENREntry struct {
+	chain forkid.Blockchain
+}
+
+// ENRKey implements enr.Entry.
+func (e currentENREntry) ENRKey() string {
+	return "aqua"
+}
+
+// EncodeRLP implements rlp.Encoder.
+func (e currentENREntry) EncodeRLP(w io.Writer) error {
+	return rlp.Encode(w, &enrEntry{ForkID: forkid.NewIDWithChain(e.chain)})
+}
+
+// newDialFilter creates a filter of the nodes to dial, by their node record:
+// only the nodes advertising the aqua protocol with a compatible fork ID pass.
+func newDialFilter(forkFilter forkid.Filter) func(*enr.Record) bool {
+	return func(r *enr.Record) bool {
+		var entry enrEntry
+		if err := r.Load(&entry); err != nil {
+			return false
+		}
+		return forkFilter(entry.ForkID) == nil
+	}
+}
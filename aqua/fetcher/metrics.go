@@ -41,3 +41,15 @@ var (
 	bodyFilterInMeter    = metrics.NewRegisteredMeter("aqua/fetcher/filter/bodies/in", nil)
 	bodyFilterOutMeter   = metrics.NewRegisteredMeter("aqua/fetcher/filter/bodies/out", nil)
 )
+
+var (
+	txAnnounceInMeter    = metrics.NewRegisteredMeter("aqua/fetcher/tx/announces/in", nil)
+	txAnnounceKnownMeter = metrics.NewRegisteredMeter("aqua/fetcher/tx/announces/known", nil)
+	txAnnounceDOSMeter   = metrics.NewRegisteredMeter("aqua/fetcher/tx/announces/dos", nil)
+
+	txRequestOutMeter     = metrics.NewRegisteredMeter("aqua/fetcher/tx/request/out", nil)
+	txRequestTimeoutMeter = metrics.NewRegisteredMeter("aqua/fetcher/tx/request/timeout", nil)
+
+	txReplyInMeter     = metrics.NewRegisteredMeter("aqua/fetcher/tx/replies/in", nil)
+	txBroadcastInMeter = metrics.NewRegisteredMeter("aqua/fetcher/tx/broadcasts/in", nil)
+)
@@ -0,0 +1,419 @@
+// Copyright 2018 The aquachain Authors
+// This file is part of the aquachain library.
+//
+// The aquachain library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The aquachain library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the aquachain library. If not, see <http://www.gnu.org/licenses/>.
+
+package fetcher
+
+import (
+	"time"
+
+	"gitlab.com/aquachain/aquachain/common"
+	"gitlab.com/aquachain/aquachain/common/log"
+	"gitlab.com/aquachain/aquachain/core/types"
+)
+
+const (
+	txArriveTimeout = 500 * time.Millisecond // Time allowance before an announced transaction is explicitly requested
+	txGatherSlack   = 100 * time.Millisecond // Interval used to collate almost-expired announces with fetches
+	txFetchTimeout  = 5 * time.Second        // Maximum allotted time to return an explicitly requested transaction
+	maxTxAnnounces  = 4096                   // Maximum number of unique transactions a peer may have announced
+	maxTxRetrievals = 256                    // Maximum number of transactions to request from a peer at once
+)
+
+// txPoolHasFn is a callback type for checking whether a transaction is already
+// known to the local pool.
+type txPoolHasFn func(common.Hash) bool
+
+// txPoolAddFn is a callback type for adding transactions to the local pool.
+type txPoolAddFn func([]*types.Transaction) []error
+
+// txRequesterFn is a callback type for sending a transaction retrieval request
+// to a peer.
+type txRequesterFn func(peer string, hashes []common.Hash) error
+
+// txAnnounce is the hash notification of the availability of a batch of new
+// transactions in the network.
+type txAnnounce struct {
+	origin string        // Identifier of the peer originating the notification
+	hashes []common.Hash // Hashes of the transactions being announced
+}
+
+// txDelivery is the notification of a batch of transactions having arrived,
+// either broadcast or as the reply to a retrieval request.
+type txDelivery struct {
+	origin string        // Identifier of the peer delivering the transactions
+	hashes []common.Hash // Hashes of the transactions delivered
+	direct bool          // Whether this is the reply to a retrieval request
+}
+
+// txRequest is a transaction retrieval request in flight.
+type txRequest struct {
+	hashes []common.Hash // Transactions requested from the peer
+	time   time.Time     // Timestamp of the request
+}
+
+// TxFetcher is responsible for retrieving new transactions based on hash
+// announcements. Announced transactions are given some time to arrive by
+// broadcast before being requested; each transaction is requested from only one
+// of the peers that announced it at a time, falling back to the others if that
+// peer doesn't deliver it in time.
+type TxFetcher struct {
+	notify  chan *txAnnounce
+	deliver chan *txDelivery
+	drop    chan string
+	quit    chan struct{}
+
+	// Announce states, owned by the loop
+	waitlist   map[common.Hash]map[string]struct{} // Announced transactions, waiting for a broadcast to arrive
+	waittime   map[common.Hash]time.Time           // Timestamps of the first announcement of waiting transactions
+	alternates map[common.Hash]map[string]struct{} // Announced transactions, scheduled for fetching from any of the peers
+	announces  map[string]map[common.Hash]struct{} // Per peer announced transactions, to prevent memory exhaustion
+	fetching   map[common.Hash]string              // Announced transactions, currently fetching from a peer
+	requests   map[string]*txRequest               // Per peer retrieval requests in flight
+
+	// Callbacks
+	hasTx    txPoolHasFn   // Checks whether a transaction is already in the pool
+	addTxs   txPoolAddFn   // Adds a batch of transactions to the pool
+	fetchTxs txRequesterFn // Requests a batch of transactions from a peer
+
+	clock func() time.Time // Source of the current time, replaceable for testing
+}
+
+// NewTxFetcher creates a transaction fetcher to retrieve transactions based on
+// hash announcements.
+func NewTxFetcher(hasTx txPoolHasFn, addTxs txPoolAddFn, fetchTxs txRequesterFn) *TxFetcher {
+	return &TxFetcher{
+		notify:     make(chan *txAnnounce),
+		deliver:    make(chan *txDelivery),
+		drop:       make(chan string),
+		quit:       make(chan struct{}),
+		waitlist:   make(map[common.Hash]map[string]struct{}),
+		waittime:   make(map[common.Hash]time.Time),
+		alternates: make(map[common.Hash]map[string]struct{}),
+		announces:  make(map[string]map[common.Hash]struct{}),
+		fetching:   make(map[common.Hash]string),
+		requests:   make(map[string]*txRequest),
+		hasTx:      hasTx,
+		addTxs:     addTxs,
+		fetchTxs:   fetchTxs,
+		clock:      time.Now,
+	}
+}
+
+// Start boots up the announcement based transaction retrieval.
+func (f *TxFetcher) Start() {
+	go f.loop()
+}
+
+// Stop terminates the announcement based transaction retrieval, canceling all
+// pending operations.
+func (f *TxFetcher) Stop() {
+	close(f.quit)
+}
+
+// Notify announces the fetcher of the potential availability of a batch of new
+// transactions in the network.
+func (f *TxFetcher) Notify(peer string, hashes []common.Hash) error {
+	// Skip the transactions already known, without bothering the loop
+	unknown := make([]common.Hash, 0, len(hashes))
+	for _, hash := range hashes {
+		if !f.hasTx(hash) {
+			unknown = append(unknown, hash)
+		}
+	}
+	txAnnounceInMeter.Mark(int64(len(hashes)))
+	txAnnounceKnownMeter.Mark(int64(len(hashes) - len(unknown)))
+	if len(unknown) == 0 {
+		return nil
+	}
+	select {
+	case f.notify <- &txAnnounce{origin: peer, hashes: unknown}:
+		return nil
+	case <-f.quit:
+		return errTerminated
+	}
+}
+
+// Enqueue adds a batch of transactions received from a peer to the pool, and
+// clears them from the retrieval schedule. Direct deliveries are replies to
+// retrieval requests: transactions requested but missing from them are assumed
+// not to be available from the peer.
+func (f *TxFetcher) Enqueue(peer string, txs []*types.Transaction, direct bool) error {
+	if direct {
+		txReplyInMeter.Mark(int64(len(txs)))
+	} else {
+		txBroadcastInMeter.Mark(int64(len(txs)))
+	}
+	f.addTxs(txs)
+
+	hashes := make([]common.Hash, len(txs))
+	for i, tx := range txs {
+		hashes[i] = tx.Hash()
+	}
+	select {
+	case f.deliver <- &txDelivery{origin: peer, hashes: hashes, direct: direct}:
+		return nil
+	case <-f.quit:
+		return errTerminated
+	}
+}
+
+// Drop removes all announcements and requests of a peer, rescheduling the
+// transactions it was to deliver with the other peers that announced them.
+func (f *TxFetcher) Drop(peer string) error {
+	select {
+	case f.drop <- peer:
+		return nil
+	case <-f.quit:
+		return errTerminated
+	}
+}
+
+// loop is the main fetcher loop, handling announcements, deliveries and peer
+// drops, and scheduling the retrievals.
+func (f *TxFetcher) loop() {
+	timer := time.NewTimer(0)
+	<-timer.C
+	defer timer.Stop()
+
+	for {
+		reschedule := true
+		select {
+		case <-f.quit:
+			return
+
+		case ann := <-f.notify:
+			// New announcements need scheduling only once done waiting,
+			// unless they join transactions already scheduled for fetching
+			reschedule = f.handleAnnounce(ann)
+
+		case delivery := <-f.deliver:
+			f.handleDelivery(delivery)
+
+		case peer := <-f.drop:
+			f.handleDrop(peer)
+
+		case <-timer.C:
+		}
+		if reschedule {
+			f.schedule(f.clock())
+		}
+		// Wake up again by the time the next announcement or request expires
+		timer.Stop()
+		select {
+		case <-timer.C:
+		default:
+		}
+		if next, ok := f.nextDeadline(); ok {
+			timer.Reset(next.Sub(f.clock()) + txGatherSlack)
+		}
+	}
+}
+
+// handleAnnounce adds the transactions announced by a peer to the waitlist, or
+// to the alternates of transactions past waiting, reporting the latter.
+func (f *TxFetcher) handleAnnounce(ann *txAnnounce) (alternate bool) {
+	announces := f.announces[ann.origin]
+	if announces == nil {
+		announces = make(map[common.Hash]struct{})
+		f.announces[ann.origin] = announces
+	}
+	now := f.clock()
+	for i, hash := range ann.hashes {
+		if _, ok := announces[hash]; ok {
+			continue
+		}
+		if len(announces) >= maxTxAnnounces {
+			log.Debug("Peer exceeded outstanding transaction announces", "peer", ann.origin, "limit", maxTxAnnounces)
+			txAnnounceDOSMeter.Mark(int64(len(ann.hashes) - i))
+			break
+		}
+		announces[hash] = struct{}{}
+
+		if alternates := f.alternates[hash]; alternates != nil {
+			alternates[ann.origin] = struct{}{}
+			alternate = true
+			continue
+		}
+		waitlist := f.waitlist[hash]
+		if waitlist == nil {
+			waitlist = make(map[string]struct{})
+			f.waitlist[hash] = waitlist
+			f.waittime[hash] = now
+		}
+		waitlist[ann.origin] = struct{}{}
+	}
+	return alternate
+}
+
+// handleDelivery forgets about the transactions delivered, and for replies to
+// retrieval requests, about the peer having the ones it didn't deliver.
+func (f *TxFetcher) handleDelivery(delivery *txDelivery) {
+	for _, hash := range delivery.hashes {
+		f.forget(hash)
+	}
+	if !delivery.direct {
+		return
+	}
+	req := f.requests[delivery.origin]
+	if req == nil {
+		return
+	}
+	delete(f.requests, delivery.origin)
+	for _, hash := range req.hashes {
+		if f.fetching[hash] == delivery.origin {
+			delete(f.fetching, hash)
+			f.forgetAnnounce(delivery.origin, hash)
+		}
+	}
+}
+
+// handleDrop forgets about everything a peer announced, rescheduling its
+// requests in flight.
+func (f *TxFetcher) handleDrop(peer string) {
+	for hash := range f.announces[peer] {
+		if f.fetching[hash] == peer {
+			delete(f.fetching, hash)
+		}
+		f.forgetAnnounce(peer, hash)
+	}
+	delete(f.announces, peer)
+	delete(f.requests, peer)
+}
+
+// schedule moves the announcements done waiting to the fetch schedule, times
+// out the requests of unresponsive peers and requests the scheduled
+// transactions from idle peers.
+func (f *TxFetcher) schedule(now time.Time) {
+	for hash, waittime := range f.waittime {
+		if now.Sub(waittime) < txArriveTimeout {
+			continue
+		}
+		f.alternates[hash] = f.waitlist[hash]
+		delete(f.waitlist, hash)
+		delete(f.waittime, hash)
+	}
+	for peer, req := range f.requests {
+		if now.Sub(req.time) < txFetchTimeout {
+			continue
+		}
+		log.Debug("Transaction retrieval timed out", "peer", peer, "count", len(req.hashes))
+		txRequestTimeoutMeter.Mark(int64(len(req.hashes)))
+
+		delete(f.requests, peer)
+		for _, hash := range req.hashes {
+			if f.fetching[hash] == peer {
+				delete(f.fetching, hash)
+				f.forgetAnnounce(peer, hash)
+			}
+		}
+	}
+	for peer, announces := range f.announces {
+		if f.requests[peer] != nil {
+			continue
+		}
+		var hashes []common.Hash
+		for hash := range announces {
+			if _, ok := f.alternates[hash]; !ok {
+				continue // still waiting
+			}
+			if _, ok := f.fetching[hash]; ok {
+				continue // requested from another peer
+			}
+			hashes = append(hashes, hash)
+			if len(hashes) == maxTxRetrievals {
+				break
+			}
+		}
+		if len(hashes) == 0 {
+			continue
+		}
+		for _, hash := range hashes {
+			f.fetching[hash] = peer
+		}
+		f.requests[peer] = &txRequest{hashes: hashes, time: now}
+		txRequestOutMeter.Mark(int64(len(hashes)))
+
+		go func(peer string, hashes []common.Hash) {
+			if err := f.fetchTxs(peer, hashes); err != nil {
+				log.Debug("Failed to request transactions", "peer", peer, "count", len(hashes), "err", err)
+			}
+		}(peer, hashes)
+	}
+}
+
+// nextDeadline returns the time the next waiting announcement or the next
+// request in flight expires, if any.
+func (f *TxFetcher) nextDeadline() (time.Time, bool) {
+	var (
+		next time.Time
+		ok   bool
+	)
+	for _, waittime := range f.waittime {
+		if deadline := waittime.Add(txArriveTimeout); !ok || deadline.Before(next) {
+			next, ok = deadline, true
+		}
+	}
+	for _, req := range f.requests {
+		if deadline := req.time.Add(txFetchTimeout); !ok || deadline.Before(next) {
+			next, ok = deadline, true
+		}
+	}
+	return next, ok
+}
+
+// forget removes all announcements of a transaction.
+func (f *TxFetcher) forget(hash common.Hash) {
+	for peer := range f.waitlist[hash] {
+		f.deleteAnnounce(peer, hash)
+	}
+	for peer := range f.alternates[hash] {
+		f.deleteAnnounce(peer, hash)
+	}
+	delete(f.waitlist, hash)
+	delete(f.waittime, hash)
+	delete(f.alternates, hash)
+	delete(f.fetching, hash)
+}
+
+// forgetAnnounce removes the announcement of a transaction by a peer, and the
+// transaction altogether if no other peer announced it.
+func (f *TxFetcher) forgetAnnounce(peer string, hash common.Hash) {
+	f.deleteAnnounce(peer, hash)
+	if waitlist := f.waitlist[hash]; waitlist != nil {
+		delete(waitlist, peer)
+		if len(waitlist) == 0 {
+			delete(f.waitlist, hash)
+			delete(f.waittime, hash)
+		}
+	}
+	if alternates := f.alternates[hash]; alternates != nil {
+		delete(alternates, peer)
+		if len(alternates) == 0 {
+			delete(f.alternates, hash)
+			delete(f.fetching, hash)
+		}
+	}
+}
+
+// deleteAnnounce removes a transaction from the announces of a peer.
+func (f *TxFetcher) deleteAnnounce(peer string, hash common.Hash) {
+	if announces := f.announces[peer]; announces != nil {
+		delete(announces, hash)
+		if len(announces) == 0 {
+			delete(f.announces, peer)
+		}
+	}
+}
@@ -0,0 +1,307 @@
+// Copyright 2018 The aquachain Authors
+// This file is part of the aquachain library.
+//
+// The aquachain library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The aquachain library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the aquachain library. If not, see <http://www.gnu.org/licenses/>.
+
+package fetcher
+
+import (
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	"gitlab.com/aquachain/aquachain/common"
+	"gitlab.com/aquachain/aquachain/core/types"
+)
+
+// txFetcherTester is a test simulator for mocking out the transaction pool
+// and the peers the fetcher requests transactions from.
+type txFetcherTester struct {
+	fetcher *TxFetcher
+	now     time.Time
+
+	pool     map[common.Hash]bool       // Transactions in the mock pool
+	requests map[string][][]common.Hash // Retrieval requests sent to each peer
+	lock     sync.Mutex
+}
+
+// newTxFetcherTester creates a new transaction fetcher test mocker, driven by
+// hand instead of its loop.
+func newTxFetcherTester() *txFetcherTester {
+	tester := &txFetcherTester{
+		now:      time.Unix(1000, 0),
+		pool:     make(map[common.Hash]bool),
+		requests: make(map[string][][]common.Hash),
+	}
+	tester.fetcher = NewTxFetcher(tester.hasTx, tester.addTxs, tester.fetchTxs)
+	tester.fetcher.clock = func() time.Time { return tester.now }
+	return tester
+}
+
+func (f *txFetcherTester) hasTx(hash common.Hash) bool {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	return f.pool[hash]
+}
+
+func (f *txFetcherTester) addTxs(txs []*types.Transaction) []error {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	for _, tx := range txs {
+		f.pool[tx.Hash()] = true
+	}
+	return make([]error, len(txs))
+}
+
+func (f *txFetcherTester) fetchTxs(peer string, hashes []common.Hash) error {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	f.requests[peer] = append(f.requests[peer], hashes)
+	return nil
+}
+
+// announce feeds an announcement to the fetcher, as if arrived through Notify.
+func (f *txFetcherTester) announce(peer string, hashes ...common.Hash) {
+	f.fetcher.handleAnnounce(&txAnnounce{origin: peer, hashes: hashes})
+}
+
+// deliver feeds transactions to the fetcher, as if arrived through Enqueue.
+func (f *txFetcherTester) deliver(peer string, direct bool, txs ...*types.Transaction) {
+	f.addTxs(txs)
+	hashes := make([]common.Hash, len(txs))
+	for i, tx := range txs {
+		hashes[i] = tx.Hash()
+	}
+	f.fetcher.handleDelivery(&txDelivery{origin: peer, hashes: hashes, direct: direct})
+}
+
+// advance moves the clock forward and runs the fetcher's scheduling, waiting
+// for the requests it sends out.
+func (f *txFetcherTester) advance(d time.Duration) {
+	inflight := make(map[string]*txRequest)
+	for peer, req := range f.fetcher.requests {
+		inflight[peer] = req
+	}
+	f.now = f.now.Add(d)
+	f.fetcher.schedule(f.now)
+	for peer, req := range f.fetcher.requests {
+		if inflight[peer] == req {
+			continue
+		}
+		for {
+			f.lock.Lock()
+			sent := len(f.requests[peer]) > 0
+			f.lock.Unlock()
+			if sent {
+				break
+			}
+			time.Sleep(time.Millisecond)
+		}
+	}
+}
+
+// takeRequests returns and clears the requests sent to a peer, with their hashes
+// sorted.
+func (f *txFetcherTester) takeRequests(peer string) [][]common.Hash {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	requests := f.requests[peer]
+	delete(f.requests, peer)
+	for _, hashes := range requests {
+		sort.Slice(hashes, func(i, j int) bool { return hashes[i].Big().Cmp(hashes[j].Big()) < 0 })
+	}
+	return requests
+}
+
+func newTestTx(nonce uint64) *types.Transaction {
+	return types.NewTransaction(nonce, common.Address{}, nil, 0, nil, nil)
+}
+
+// Tests that announced transactions are requested only after having been given
+// time to arrive by broadcast, and only from one of the announcing peers.
+func TestTxFetcherAnnounceWait(t *testing.T) {
+	tester := newTxFetcherTester()
+	tx := newTestTx(0)
+
+	tester.announce("A", tx.Hash())
+	tester.announce("B", tx.Hash())
+	tester.advance(txArriveTimeout / 2)
+	if requests := len(tester.takeRequests("A")) + len(tester.takeRequests("B")); requests != 0 {
+		t.Fatalf("requested %d times before the arrival timeout", requests)
+	}
+	tester.advance(txArriveTimeout / 2)
+	requests := len(tester.takeRequests("A")) + len(tester.takeRequests("B"))
+	if requests != 1 {
+		t.Fatalf("requested %d times after the arrival timeout, want 1", requests)
+	}
+}
+
+// Tests that transactions arriving by broadcast while waiting are not
+// requested at all.
+func TestTxFetcherBroadcastArrival(t *testing.T) {
+	tester := newTxFetcherTester()
+	tx := newTestTx(0)
+
+	tester.announce("A", tx.Hash())
+	tester.deliver("B", false, tx)
+	tester.advance(txArriveTimeout)
+	if requests := tester.takeRequests("A"); len(requests) != 0 {
+		t.Fatalf("requested delivered transaction: %v", requests)
+	}
+	if len(tester.fetcher.announces) != 0 || len(tester.fetcher.waitlist) != 0 {
+		t.Fatalf("delivered transaction still tracked: %v %v", tester.fetcher.announces, tester.fetcher.waitlist)
+	}
+}
+
+// Tests that transactions are batched into requests per peer, and that a peer
+// with a request in flight is not sent another one.
+func TestTxFetcherBatching(t *testing.T) {
+	tester := newTxFetcherTester()
+	txs := []*types.Transaction{newTestTx(0), newTestTx(1), newTestTx(2)}
+
+	tester.announce("A", txs[0].Hash(), txs[1].Hash())
+	tester.advance(txArriveTimeout)
+	if requests := tester.takeRequests("A"); len(requests) != 1 || len(requests[0]) != 2 {
+		t.Fatalf("unexpected requests: %v", requests)
+	}
+	tester.announce("A", txs[2].Hash())
+	tester.advance(txArriveTimeout)
+	if requests := tester.takeRequests("A"); len(requests) != 0 {
+		t.Fatalf("requested while busy: %v", requests)
+	}
+	tester.deliver("A", true, txs[0], txs[1])
+	tester.advance(0)
+	if requests := tester.takeRequests("A"); len(requests) != 1 || len(requests[0]) != 1 || requests[0][0] != txs[2].Hash() {
+		t.Fatalf("unexpected requests once idle: %v", requests)
+	}
+}
+
+// Tests that transactions requested from an unresponsive peer are requested
+// from another announcing peer once the request times out, and the announces
+// of the peer forgotten.
+func TestTxFetcherTimeout(t *testing.T) {
+	tester := newTxFetcherTester()
+	tx := newTestTx(0)
+
+	tester.announce("A", tx.Hash())
+	tester.advance(txArriveTimeout)
+	if requests := tester.takeRequests("A"); len(requests) != 1 {
+		t.Fatalf("unexpected requests: %v", requests)
+	}
+	tester.announce("B", tx.Hash())
+	tester.advance(txFetchTimeout / 2)
+	if requests := tester.takeRequests("B"); len(requests) != 0 {
+		t.Fatalf("requested from alternate before the timeout: %v", requests)
+	}
+	tester.advance(txFetchTimeout / 2)
+	if requests := tester.takeRequests("B"); len(requests) != 1 || requests[0][0] != tx.Hash() {
+		t.Fatalf("not requested from alternate after the timeout: %v", requests)
+	}
+	if _, ok := tester.fetcher.announces["A"]; ok {
+		t.Fatalf("announces of timed out peer not forgotten")
+	}
+}
+
+// Tests that transactions missing from a reply are requested from another
+// announcing peer straight away.
+func TestTxFetcherMissingReply(t *testing.T) {
+	tester := newTxFetcherTester()
+	txs := []*types.Transaction{newTestTx(0), newTestTx(1)}
+
+	tester.announce("A", txs[0].Hash(), txs[1].Hash())
+	tester.advance(txArriveTimeout)
+	tester.announce("B", txs[0].Hash(), txs[1].Hash())
+	tester.takeRequests("A")
+
+	tester.deliver("A", true, txs[0])
+	tester.advance(0)
+	if requests := tester.takeRequests("B"); len(requests) != 1 || len(requests[0]) != 1 || requests[0][0] != txs[1].Hash() {
+		t.Fatalf("missing transaction not requested from alternate: %v", requests)
+	}
+}
+
+// Tests that the requests of dropped peers are rescheduled with the other
+// announcing peers, and everything about the peer forgotten.
+func TestTxFetcherDrop(t *testing.T) {
+	tester := newTxFetcherTester()
+	txs := []*types.Transaction{newTestTx(0), newTestTx(1)}
+
+	tester.announce("A", txs[0].Hash(), txs[1].Hash())
+	tester.advance(txArriveTimeout)
+	tester.announce("B", txs[0].Hash())
+	tester.takeRequests("A")
+
+	tester.fetcher.handleDrop("A")
+	tester.advance(0)
+	if requests := tester.takeRequests("B"); len(requests) != 1 || len(requests[0]) != 1 || requests[0][0] != txs[0].Hash() {
+		t.Fatalf("dropped peer's request not rescheduled: %v", requests)
+	}
+	if _, ok := tester.fetcher.alternates[txs[1].Hash()]; ok {
+		t.Fatalf("transaction announced only by dropped peer not forgotten")
+	}
+	if _, ok := tester.fetcher.requests["A"]; ok {
+		t.Fatalf("request of dropped peer not forgotten")
+	}
+}
+
+// Tests that peers can't make the fetcher track an unbounded number of
+// announcements.
+func TestTxFetcherAnnounceDOS(t *testing.T) {
+	tester := newTxFetcherTester()
+
+	hashes := make([]common.Hash, maxTxAnnounces+10)
+	for i := range hashes {
+		hashes[i] = newTestTx(uint64(i)).Hash()
+	}
+	tester.announce("A", hashes...)
+	if n := len(tester.fetcher.announces["A"]); n != maxTxAnnounces {
+		t.Fatalf("tracked announces mismatch: have %d, want %d", n, maxTxAnnounces)
+	}
+	if n := len(tester.fetcher.waitlist); n != maxTxAnnounces {
+		t.Fatalf("waitlist size mismatch: have %d, want %d", n, maxTxAnnounces)
+	}
+	tester.advance(txArriveTimeout)
+	if requests := tester.takeRequests("A"); len(requests) != 1 || len(requests[0]) != maxTxRetrievals {
+		t.Fatalf("request size mismatch: %d requests", len(requests))
+	}
+}
+
+// Tests that the fetcher loop retrieves announced transactions on its own.
+func TestTxFetcherLoop(t *testing.T) {
+	requested := make(chan []common.Hash, 1)
+	fetcher := NewTxFetcher(
+		func(common.Hash) bool { return false },
+		func(txs []*types.Transaction) []error { return make([]error, len(txs)) },
+		func(peer string, hashes []common.Hash) error { requested <- hashes; return nil },
+	)
+	fetcher.Start()
+	defer fetcher.Stop()
+
+	tx := newTestTx(0)
+	if err := fetcher.Notify("A", []common.Hash{tx.Hash()}); err != nil {
+		t.Fatalf("failed to notify: %v", err)
+	}
+	select {
+	case hashes := <-requested:
+		if len(hashes) != 1 || hashes[0] != tx.Hash() {
+			t.Fatalf("unexpected request: %v", hashes)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatalf("announced transaction not requested")
+	}
+	if err := fetcher.Enqueue("A", []*types.Transaction{tx}, true); err != nil {
+		t.Fatalf("failed to enqueue: %v", err)
+	}
+}
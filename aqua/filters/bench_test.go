@@ -149,9 +149,8 @@ func benchmarkBloomBits(b *testing.B, sectionSize uint64) {
 }
 
 func forEachKey(db aquadb.Database, startPrefix, endPrefix []byte, fn func(key []byte)) {
-	it := db.(*aquadb.LDBDatabase).NewIterator()
-	it.Seek(startPrefix)
-	for it.Valid() {
+	it := db.(aquadb.Iteratee).NewIterator()
+	for ok := it.Seek(startPrefix); ok; ok = it.Next() {
 		key := it.Key()
 		cmpLen := len(key)
 		if len(endPrefix) < cmpLen {
@@ -161,7 +160,6 @@ func forEachKey(db aquadb.Database, startPrefix, endPrefix []byte, fn func(key [
 			break
 		}
 		fn(common.CopyBytes(key))
-		it.Next()
 	}
 	it.Release()
 }
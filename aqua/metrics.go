@@ -137,3 +137,41 @@ func (rw *meteredMsgReadWriter) WriteMsg(msg p2p.Msg) error {
 	// Send the packet to the p2p layer
 	return rw.MsgReadWriter.WriteMsg(msg)
 }
+
+// nodeMetrics are the names of the gauges reporting the state of the node.
+var nodeMetrics = []string{"chain/head/block", "p2p/peers", "txpool/pending", "txpool/queued", "aqua/syncing"}
+
+// registerNodeMetrics registers the gauges reporting the state of the node,
+// evaluated whenever the metrics are read.
+func (s *Aquachain) registerNodeMetrics(srvr *p2p.Server) {
+	if !metrics.Enabled {
+		return
+	}
+	metrics.NewRegisteredFunctionalGauge("chain/head/block", nil, func() int64 {
+		return int64(s.blockchain.CurrentBlock().NumberU64())
+	})
+	metrics.NewRegisteredFunctionalGauge("p2p/peers", nil, func() int64 {
+		return int64(srvr.PeerCount())
+	})
+	metrics.NewRegisteredFunctionalGauge("txpool/pending", nil, func() int64 {
+		pending, _ := s.txPool.Stats()
+		return int64(pending)
+	})
+	metrics.NewRegisteredFunctionalGauge("txpool/queued", nil, func() int64 {
+		_, queued := s.txPool.Stats()
+		return int64(queued)
+	})
+	metrics.NewRegisteredFunctionalGauge("aqua/syncing", nil, func() int64 {
+		if s.protocolManager != nil && s.protocolManager.downloader.Synchronising() {
+			return 1
+		}
+		return 0
+	})
+}
+
+// unregisterNodeMetrics unregisters the gauges reporting the state of the node.
+func unregisterNodeMetrics() {
+	for _, name := range nodeMetrics {
+		metrics.DefaultRegistry.Unregister(name)
+	}
+}
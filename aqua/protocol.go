@@ -24,6 +24,7 @@ import (
 	"gitlab.com/aquachain/aquachain/aqua/event"
 	"gitlab.com/aquachain/aquachain/common"
 	"gitlab.com/aquachain/aquachain/core"
+	"gitlab.com/aquachain/aquachain/core/forkid"
 	"gitlab.com/aquachain/aquachain/core/types"
 	"gitlab.com/aquachain/aquachain/rlp"
 )
@@ -32,6 +33,8 @@ import (
 const (
 	aqua64 = 64
 	aqua65 = 65
+	aqua66 = 66 // fork ID in the status message
+	aqua67 = 67 // pooled transaction announcements and retrieval
 	//eth62  = 62
 	//eth63 = 63
 )
@@ -40,10 +43,10 @@ const (
 var ProtocolName = "aqua"
 
 // Supported versions of the aqua protocol (first is primary).
-var ProtocolVersions = []uint{aqua64, aqua65}
+var ProtocolVersions = []uint{aqua67, aqua66, aqua64, aqua65}
 
 // Number of implemented message corresponding to different protocol versions.
-var ProtocolLengths = []uint64{17, 17}
+var ProtocolLengths = []uint64{17, 17, 17, 17}
 
 const ProtocolMaxMsgSize = 10 * 1024 * 1024 // Maximum cap on the size of a protocol message
 
@@ -59,6 +62,11 @@ const (
 	BlockBodiesMsg     = 0x06
 	NewBlockMsg        = 0x07
 
+	// Protocol messages belonging to aqua/67
+	NewPooledTransactionHashesMsg = 0x08
+	GetPooledTransactionsMsg      = 0x09
+	PooledTransactionsMsg         = 0x0a
+
 	// Protocol messages belonging to aqua/63
 	GetNodeDataMsg = 0x0d
 	NodeDataMsg    = 0x0e
@@ -78,6 +86,7 @@ const (
 	ErrNoStatusMsg
 	ErrExtraStatusMsg
 	ErrSuspendedPeer
+	ErrForkIDRejected
 )
 
 func (e errCode) String() string {
@@ -95,12 +104,17 @@ var errorToString = map[int]string{
 	ErrNoStatusMsg:             "No status message",
 	ErrExtraStatusMsg:          "Extra status message",
 	ErrSuspendedPeer:           "Suspended peer",
+	ErrForkIDRejected:          "Fork ID rejected",
 }
 
 type txPool interface {
 	// AddRemotes should add the given transactions to the pool.
 	AddRemotes([]*types.Transaction) []error
 
+	// Get should return the transaction with the given hash, or nil if the
+	// pool doesn't know it.
+	Get(hash common.Hash) *types.Transaction
+
 	// Pending should return pending transactions.
 	// The slice should be modifiable by the caller.
 	Pending() (map[common.Address]types.Transactions, error)
@@ -119,6 +133,17 @@ type statusData struct {
 	GenesisBlock    common.Hash
 }
 
+// statusData66 is the network packet for the status message since aqua/66,
+// adding the fork ID.
+type statusData66 struct {
+	ProtocolVersion uint32
+	ChainId         uint64
+	TD              *big.Int
+	CurrentBlock    common.Hash
+	GenesisBlock    common.Hash
+	ForkID          forkid.ID
+}
+
 // newBlockHashesData is the network packet for the block announcements.
 type newBlockHashesData []struct {
 	Hash   common.Hash // Hash of one particular block being announced
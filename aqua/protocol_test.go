@@ -25,9 +25,11 @@ import (
 	"gitlab.com/aquachain/aquachain/aqua/downloader"
 	"gitlab.com/aquachain/aquachain/common"
 	"gitlab.com/aquachain/aquachain/common/log"
+	"gitlab.com/aquachain/aquachain/core/forkid"
 	"gitlab.com/aquachain/aquachain/core/types"
 	"gitlab.com/aquachain/aquachain/crypto"
 	"gitlab.com/aquachain/aquachain/p2p"
+	"gitlab.com/aquachain/aquachain/p2p/enr"
 	"gitlab.com/aquachain/aquachain/rlp"
 )
 
@@ -93,6 +95,88 @@ func testStatusMsgErrors(t *testing.T, protocol int) {
 	}
 }
 
+// Tests that peers announcing an incompatible fork ID are rejected at the
+// handshake since aqua/66, and that compatible ones are accepted.
+func TestStatusMsgForkID66(t *testing.T) {
+	pm, _ := newTestProtocolManagerMust(t, downloader.FullSync, 0, nil, nil)
+	var (
+		genesis = pm.blockchain.Genesis()
+		head    = pm.blockchain.CurrentHeader()
+		td      = pm.blockchain.GetTd(head.Hash(), head.Number.Uint64())
+		local   = forkid.NewIDWithChain(pm.blockchain)
+	)
+	defer pm.Stop()
+
+	tests := []struct {
+		id        forkid.ID
+		wantError error
+	}{
+		{id: local},
+		{
+			id:        forkid.ID{Hash: [4]byte{0xde, 0xad, 0xbe, 0xef}},
+			wantError: errResp(ErrForkIDRejected, "%v", forkid.ErrLocalIncompatibleOrStale),
+		},
+	}
+	for i, test := range tests {
+		p, errc := newTestPeer("peer", aqua66, pm, false)
+		if err := p2p.ExpectMsg(p.app, StatusMsg, &statusData66{aqua66, DefaultConfig.ChainId, td, head.Hash(), genesis.Hash(), local}); err != nil {
+			t.Fatalf("test %d: status recv: %v", i, err)
+		}
+		go p2p.Send(p.app, StatusMsg, &statusData66{aqua66, DefaultConfig.ChainId, td, head.Hash(), genesis.Hash(), test.id})
+
+		select {
+		case err := <-errc:
+			if test.wantError == nil {
+				t.Errorf("test %d: compatible peer rejected: %v", i, err)
+			} else if err == nil || err.Error() != test.wantError.Error() {
+				t.Errorf("test %d: wrong error: got %v, want %q", i, err, test.wantError)
+			}
+		case <-time.After(500 * time.Millisecond):
+			if test.wantError != nil {
+				t.Errorf("test %d: incompatible peer not rejected", i)
+			}
+		}
+		p.close()
+	}
+}
+
+// Tests that the aqua node record entry carries the fork ID, and that the dial
+// filter rejects the nodes with an incompatible one or without the entry.
+func TestENRDialFilter(t *testing.T) {
+	pm, _ := newTestProtocolManagerMust(t, downloader.FullSync, 0, nil, nil)
+	defer pm.Stop()
+
+	key, _ := crypto.GenerateKey()
+	sign := func(entries ...enr.Entry) *enr.Record {
+		var r enr.Record
+		for _, e := range entries {
+			r.Set(e)
+		}
+		if err := r.Sign(key); err != nil {
+			t.Fatal(err)
+		}
+		return &r
+	}
+	local := sign(currentENREntry{pm.blockchain})
+	var entry enrEntry
+	if err := local.Load(&entry); err != nil {
+		t.Fatalf("can't load aqua entry: %v", err)
+	}
+	if want := forkid.NewIDWithChain(pm.blockchain); entry.ForkID != want {
+		t.Errorf("wrong fork ID in node record: got %x, want %x", entry.ForkID, want)
+	}
+	filter := newDialFilter(pm.forkFilter)
+	if !filter(local) {
+		t.Error("node with the same fork ID rejected")
+	}
+	if filter(sign(enrEntry{ForkID: forkid.ID{Hash: [4]byte{0xde, 0xad, 0xbe, 0xef}}})) {
+		t.Error("node with an incompatible fork ID accepted")
+	}
+	if filter(sign(enr.TCP(21303))) {
+		t.Error("node without the aqua entry accepted")
+	}
+}
+
 // This test checks that received transactions are added to the local pool.
 func TestRecvTransactions62(t *testing.T) { testRecvTransactions(t, 62) }
 func TestRecvTransactions63(t *testing.T) { testRecvTransactions(t, 63) }
@@ -179,6 +263,125 @@ func testSendTransactions(t *testing.T, protocol int) {
 	wg.Wait()
 }
 
+// This test checks that announced transactions are retrieved from the
+// announcing peer and added to the local pool.
+func TestRecvPooledTransactions67(t *testing.T) {
+	txAdded := make(chan []*types.Transaction)
+	pm, _ := newTestProtocolManagerMust(t, downloader.FullSync, 0, nil, txAdded)
+	pm.acceptTxs = 1 // mark synced to accept transactions
+	p, _ := newTestPeer("peer", aqua67, pm, true)
+	defer pm.Stop()
+	defer p.close()
+
+	tx := newTestTransaction(testAccount, 0, 0)
+	if err := p2p.Send(p.app, NewPooledTransactionHashesMsg, []common.Hash{tx.Hash()}); err != nil {
+		t.Fatalf("send error: %v", err)
+	}
+	if err := p2p.ExpectMsg(p.app, GetPooledTransactionsMsg, []common.Hash{tx.Hash()}); err != nil {
+		t.Fatalf("transaction not requested: %v", err)
+	}
+	if err := p2p.Send(p.app, PooledTransactionsMsg, []interface{}{tx}); err != nil {
+		t.Fatalf("send error: %v", err)
+	}
+	select {
+	case added := <-txAdded:
+		if len(added) != 1 || added[0].Hash() != tx.Hash() {
+			t.Errorf("added wrong transactions: got %v, want %v", added, tx.Hash())
+		}
+	case <-time.After(2 * time.Second):
+		t.Errorf("no transaction added within 2 seconds")
+	}
+}
+
+// This test checks that pooled transactions are served to peers requesting
+// them, skipping the unknown ones.
+func TestGetPooledTransactions67(t *testing.T) {
+	pm, _ := newTestProtocolManagerMust(t, downloader.FullSync, 0, nil, nil)
+	defer pm.Stop()
+
+	tx := newTestTransaction(testAccount, 0, 0)
+	pm.txpool.AddRemotes([]*types.Transaction{tx})
+
+	p, _ := newTestPeer("peer", aqua67, pm, true)
+	defer p.close()
+
+	if err := p2p.Send(p.app, GetPooledTransactionsMsg, []common.Hash{{0x01}, tx.Hash()}); err != nil {
+		t.Fatalf("send error: %v", err)
+	}
+	// Skip the pending transactions synced to the new peer
+	for {
+		msg, err := p.app.ReadMsg()
+		if err != nil {
+			t.Fatalf("read error: %v", err)
+		}
+		if msg.Code == TxMsg {
+			msg.Discard()
+			continue
+		}
+		if msg.Code != PooledTransactionsMsg {
+			t.Fatalf("got code %d, want PooledTransactionsMsg", msg.Code)
+		}
+		var txs []*types.Transaction
+		if err := msg.Decode(&txs); err != nil {
+			t.Fatalf("decode error: %v", err)
+		}
+		if len(txs) != 1 || txs[0].Hash() != tx.Hash() {
+			t.Fatalf("served wrong transactions: got %v, want %v", txs, tx.Hash())
+		}
+		return
+	}
+}
+
+// This test checks that transactions are sent in full to a subset of the peers
+// only, and announced to the rest.
+func TestBroadcastTxAnnounce67(t *testing.T) {
+	pm, _ := newTestProtocolManagerMust(t, downloader.FullSync, 0, nil, nil)
+	defer pm.Stop()
+
+	peers := make([]*testPeer, 4)
+	for i := range peers {
+		peers[i], _ = newTestPeer(fmt.Sprintf("peer #%d", i), aqua67, pm, true)
+		defer peers[i].close()
+	}
+	tx := newTestTransaction(testAccount, 0, 0)
+	go pm.BroadcastTx(tx.Hash(), tx)
+
+	// Read the peers concurrently, the broadcast goes out in any order
+	codes := make(chan uint64, len(peers))
+	for _, p := range peers {
+		go func(p *testPeer) {
+			msg, err := p.app.ReadMsg()
+			if err != nil {
+				t.Errorf("%v: read error: %v", p.Peer, err)
+				codes <- 0
+				return
+			}
+			if msg.Code == NewPooledTransactionHashesMsg {
+				var hashes []common.Hash
+				if err := msg.Decode(&hashes); err != nil || len(hashes) != 1 || hashes[0] != tx.Hash() {
+					t.Errorf("%v: bad announcement %v: %v", p.Peer, hashes, err)
+				}
+			}
+			msg.Discard()
+			codes <- msg.Code
+		}(p)
+	}
+	var full, announced int
+	for range peers {
+		switch code := <-codes; code {
+		case TxMsg:
+			full++
+		case NewPooledTransactionHashesMsg:
+			announced++
+		default:
+			t.Fatalf("got code %d", code)
+		}
+	}
+	if full != 2 || announced != 2 {
+		t.Errorf("sent in full to %d peers and announced to %d, want 2 and 2", full, announced)
+	}
+}
+
 // Tests that the custom union field encoder and decoder works correctly.
 func TestGetBlockHeadersDataEncodeDecode(t *testing.T) {
 	// Create a "random" hash for testing
@@ -0,0 +1,176 @@
+// Copyright 2018 The aquachain Authors
+// This file is part of the aquachain library.
+//
+// The aquachain library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The aquachain library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the aquachain library. If not, see <http://www.gnu.org/licenses/>.
+
+package tracers
+
+import (
+	"encoding/json"
+	"math"
+	"math/big"
+	"sort"
+	"strconv"
+	"sync/atomic"
+	"time"
+
+	"gitlab.com/aquachain/aquachain/common"
+	"gitlab.com/aquachain/aquachain/common/log"
+	"gitlab.com/aquachain/aquachain/core/vm"
+)
+
+// ResultTracer is a vm.Tracer that produces a JSON result, and can be
+// interrupted. Both the JavaScript and native tracers implement it.
+type ResultTracer interface {
+	vm.Tracer
+
+	// GetResult returns the JSON result of the trace, or any error
+	GetResult() (json.RawMessage, error)
+
+	// Stop terminates execution of the tracer at the first opportune moment.
+	Stop(err error)
+}
+
+// native contains the built in Go tracers by name. They are named after, and
+// produce the same output as, the JavaScript tracers they replace.
+var native = map[string]func() ResultTracer{
+	"callTracer":     func() ResultTracer { return newCallTracer() },
+	"prestateTracer": func() ResultTracer { return newPrestateTracer() },
+	"4byteTracer":    func() ResultTracer { return newFourByteTracer() },
+	"opcountTracer":  func() ResultTracer { return new(opcountTracer) },
+}
+
+// NativeTracers returns the names of the built in Go tracers.
+func NativeTracers() []string {
+	names := make([]string, 0, len(native))
+	for name := range native {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// NewTracer returns the native Go tracer with the given name if there is one,
+// otherwise the code is passed to the JavaScript tracer (see New). Pure-go
+// builds can only use the native tracers.
+func NewTracer(code string) (ResultTracer, error) {
+	if fn, ok := native[code]; ok {
+		return fn(), nil
+	}
+	tracer, err := New(code)
+	if err != nil {
+		return nil, err
+	}
+	return tracer, nil
+}
+
+// interrupter implements Stop for the native tracers
+type interrupter struct {
+	interrupt uint32 // Atomic flag to signal execution interruption
+	reason    error  // Textual reason for the interruption
+}
+
+// Stop terminates execution of the tracer at the first opportune moment.
+func (i *interrupter) Stop(err error) {
+	i.reason = err
+	atomic.StoreUint32(&i.interrupt, 1)
+}
+
+func (i *interrupter) stopped() bool {
+	return atomic.LoadUint32(&i.interrupt) > 0
+}
+
+// memorySlice returns a copy of size bytes of memory at offset, or an empty
+// slice if out of bounds, as the JavaScript slice helper does.
+func memorySlice(memory *vm.Memory, offset, size uint64) []byte {
+	data := memory.Data()
+	if offset > uint64(len(data)) || size > uint64(len(data))-offset {
+		log.Warn("Tracer accessed out of bound memory", "available", len(data), "offset", offset, "size", size)
+		return []byte{}
+	}
+	return common.CopyBytes(data[offset : offset+size])
+}
+
+// stackUint64 returns the nth item from the top of the stack, saturating at
+// the maximum uint64 value.
+func stackUint64(stack *vm.Stack, n int) uint64 {
+	v := stack.Back(n)
+	if !v.IsUint64() {
+		return math.MaxUint64
+	}
+	return v.Uint64()
+}
+
+// stackAddress returns the nth item from the top of the stack as an address
+func stackAddress(stack *vm.Stack, n int) common.Address {
+	return common.BigToAddress(stack.Back(n))
+}
+
+// isPrecompiled reports whether the address is a precompiled contract
+func isPrecompiled(addr common.Address) bool {
+	_, ok := vm.PrecompiledContractsByzantium[addr]
+	return ok
+}
+
+// hexBig formats a number the same way JavaScript's '0x' + n.toString(16) does
+func hexBig(n *big.Int) string {
+	if n == nil {
+		return "0x0"
+	}
+	return "0x" + n.Text(16)
+}
+
+// hexInt formats a number the same way JavaScript's '0x' + n.toString(16) does
+func hexInt(n int64) string {
+	return "0x" + strconv.FormatInt(n, 16)
+}
+
+// opcountTracer counts the number of EVM instructions executed.
+type opcountTracer struct {
+	interrupter
+	count uint64
+	err   error
+}
+
+func (t *opcountTracer) CaptureStart(from common.Address, to common.Address, create bool, input []byte, gas uint64, value *big.Int) error {
+	return nil
+}
+
+func (t *opcountTracer) CaptureState(env *vm.EVM, pc uint64, op vm.OpCode, gas, cost uint64, memory *vm.Memory, stack *vm.Stack, contract *vm.Contract, depth int, err error) error {
+	if t.err != nil {
+		return nil
+	}
+	if t.stopped() {
+		t.err = t.reason
+		return nil
+	}
+	t.count++
+	return nil
+}
+
+func (t *opcountTracer) CaptureFault(env *vm.EVM, pc uint64, op vm.OpCode, gas, cost uint64, memory *vm.Memory, stack *vm.Stack, contract *vm.Contract, depth int, err error) error {
+	return nil
+}
+
+func (t *opcountTracer) CaptureEnd(output []byte, gasUsed uint64, d time.Duration, err error) error {
+	return nil
+}
+
+func (t *opcountTracer) GetResult() (json.RawMessage, error) {
+	res, err := json.Marshal(t.count)
+	if err != nil {
+		return nil, err
+	}
+	return res, t.err
+}
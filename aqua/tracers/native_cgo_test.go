@@ -0,0 +1,148 @@
+// Copyright 2018 The aquachain Authors
+// This file is part of the aquachain library.
+//
+// The aquachain library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The aquachain library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the aquachain library. If not, see <http://www.gnu.org/licenses/>.
+
+//go:build gccgo || cgo
+// +build gccgo cgo
+
+package tracers
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"math/big"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"gitlab.com/aquachain/aquachain/aquadb"
+	"gitlab.com/aquachain/aquachain/common"
+	"gitlab.com/aquachain/aquachain/core"
+	"gitlab.com/aquachain/aquachain/core/types"
+	"gitlab.com/aquachain/aquachain/core/vm"
+	"gitlab.com/aquachain/aquachain/opt/tests"
+	"gitlab.com/aquachain/aquachain/rlp"
+)
+
+// sameResult compares two tracer results, ignoring the execution time
+func sameResult(t *testing.T, native, js json.RawMessage) {
+	var have, want interface{}
+	if err := json.Unmarshal(native, &have); err != nil {
+		t.Fatalf("bad native result: %v", err)
+	}
+	if err := json.Unmarshal(js, &want); err != nil {
+		t.Fatalf("bad javascript result: %v", err)
+	}
+	for _, m := range []interface{}{have, want} {
+		if m, ok := m.(map[string]interface{}); ok {
+			delete(m, "time")
+		}
+	}
+	if !reflect.DeepEqual(have, want) {
+		t.Fatalf("result mismatch:\nnative     %s\njavascript %s", native, js)
+	}
+}
+
+// Tests that the native tracers produce the same output as their JavaScript
+// counterparts.
+func TestNativeMatchesJavaScript(t *testing.T) {
+	for _, name := range NativeTracers() {
+		js, err := New(name)
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		native, _ := NewTracer(name)
+
+		want, err := runNative(t, js, []byte{0xaa, 0xbb, 0xcc, 0xdd, 0xee})
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		have, err := runNative(t, native, []byte{0xaa, 0xbb, 0xcc, 0xdd, 0xee})
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		sameResult(t, have, want)
+	}
+}
+
+// Tests the native tracers against the JavaScript ones using the transactions
+// of the callTracer test suite, including the ones with unreliable results.
+func TestNativeMatchesJavaScriptSuite(t *testing.T) {
+	files, err := filepath.Glob(filepath.Join("testdata", "*", "call_tracer_*.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	more, _ := filepath.Glob(filepath.Join("testdata", "call_tracer_*.json"))
+	for _, file := range append(files, more...) {
+		file := file // capture range variable
+		t.Run(camel(strings.TrimSuffix(strings.TrimPrefix(filepath.Base(file), "call_tracer_"), ".json")), func(t *testing.T) {
+			blob, err := ioutil.ReadFile(file)
+			if err != nil {
+				t.Fatalf("failed to read testcase: %v", err)
+			}
+			test := new(callTracerTest)
+			if err := json.Unmarshal(blob, test); err != nil {
+				t.Fatalf("failed to parse testcase: %v", err)
+			}
+			for _, name := range NativeTracers() {
+				js, err := New(name)
+				if err != nil {
+					t.Fatalf("%s: %v", name, err)
+				}
+				native, _ := NewTracer(name)
+				sameResult(t, runSuiteTx(t, test, native), runSuiteTx(t, test, js))
+			}
+		})
+	}
+}
+
+// runSuiteTx executes the transaction of a callTracer test with the tracer.
+func runSuiteTx(t *testing.T, test *callTracerTest, tracer ResultTracer) json.RawMessage {
+	tx := new(types.Transaction)
+	if err := rlp.DecodeBytes(common.FromHex(test.Input), tx); err != nil {
+		t.Fatalf("failed to parse testcase input: %v", err)
+	}
+	signer := types.MakeSigner(test.Genesis.Config, new(big.Int).SetUint64(uint64(test.Context.Number)))
+	origin, _ := signer.Sender(tx)
+
+	context := vm.Context{
+		CanTransfer: core.CanTransfer,
+		Transfer:    core.Transfer,
+		Origin:      origin,
+		Coinbase:    test.Context.Miner,
+		BlockNumber: new(big.Int).SetUint64(uint64(test.Context.Number)),
+		Time:        new(big.Int).SetUint64(uint64(test.Context.Time)),
+		Difficulty:  (*big.Int)(test.Context.Difficulty),
+		GasLimit:    uint64(test.Context.GasLimit),
+		GasPrice:    tx.GasPrice(),
+	}
+	statedb := tests.MakePreState(aquadb.NewMemDatabase(), test.Genesis.Alloc)
+	evm := vm.NewEVM(context, statedb, test.Genesis.Config, vm.Config{Debug: true, Tracer: tracer})
+
+	msg, err := tx.AsMessage(signer)
+	if err != nil {
+		t.Fatalf("failed to prepare transaction for tracing: %v", err)
+	}
+	st := core.NewStateTransition(evm, msg, new(core.GasPool).AddGas(tx.Gas()))
+	if _, _, _, err = st.TransitionDb(); err != nil {
+		t.Fatalf("failed to execute transaction: %v", err)
+	}
+	res, err := tracer.GetResult()
+	if err != nil {
+		t.Fatalf("failed to retrieve trace result: %v", err)
+	}
+	return res
+}
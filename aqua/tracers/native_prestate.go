@@ -0,0 +1,168 @@
+// Copyright 2018 The aquachain Authors
+// This file is part of the aquachain library.
+//
+// The aquachain library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The aquachain library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the aquachain library. If not, see <http://www.gnu.org/licenses/>.
+
+package tracers
+
+import (
+	"encoding/json"
+	"math/big"
+	"time"
+
+	"gitlab.com/aquachain/aquachain/common"
+	"gitlab.com/aquachain/aquachain/common/hexutil"
+	"gitlab.com/aquachain/aquachain/core/vm"
+	"gitlab.com/aquachain/aquachain/crypto"
+)
+
+// prestateAccount is an account in the prestateTracer output
+type prestateAccount struct {
+	Balance *big.Int               `json:"-"`
+	Nonce   uint64                 `json:"nonce"`
+	Code    hexutil.Bytes          `json:"code"`
+	Storage map[common.Hash]string `json:"storage"`
+}
+
+func (a *prestateAccount) MarshalJSON() ([]byte, error) {
+	type account prestateAccount
+	return json.Marshal(&struct {
+		Balance string `json:"balance"`
+		*account
+	}{hexBig(a.Balance), (*account)(a)})
+}
+
+// prestateTracer is a native implementation of prestate_tracer.js, gathering
+// every account and storage slot touched by a transaction, as they were before
+// it executed.
+type prestateTracer struct {
+	interrupter
+	prestate map[common.Address]*prestateAccount
+	db       vm.StateDB
+	err      error
+
+	create bool
+	from   common.Address
+	to     common.Address
+	value  *big.Int
+}
+
+func newPrestateTracer() *prestateTracer {
+	return new(prestateTracer)
+}
+
+// lookupAccount injects the specified account into the prestate
+func (t *prestateTracer) lookupAccount(addr common.Address) {
+	if _, ok := t.prestate[addr]; ok {
+		return
+	}
+	t.prestate[addr] = &prestateAccount{
+		Balance: new(big.Int).Set(t.db.GetBalance(addr)),
+		Nonce:   t.db.GetNonce(addr),
+		Code:    common.CopyBytes(t.db.GetCode(addr)),
+		Storage: make(map[common.Hash]string),
+	}
+}
+
+// lookupStorage injects the specified non-zero storage entry of the given
+// account into the prestate
+func (t *prestateTracer) lookupStorage(addr common.Address, key common.Hash) {
+	t.lookupAccount(addr)
+	storage := t.prestate[addr].Storage
+	if _, ok := storage[key]; ok {
+		return
+	}
+	if val := t.db.GetState(addr, key); val != (common.Hash{}) {
+		storage[key] = val.Hex()
+	}
+}
+
+func (t *prestateTracer) CaptureStart(from common.Address, to common.Address, create bool, input []byte, gas uint64, value *big.Int) error {
+	t.create = create
+	t.from = from
+	t.to = to
+	t.value = value
+	return nil
+}
+
+// CaptureState is invoked for every opcode that the VM executes.
+func (t *prestateTracer) CaptureState(env *vm.EVM, pc uint64, op vm.OpCode, gas, cost uint64, memory *vm.Memory, stack *vm.Stack, contract *vm.Contract, depth int, err error) error {
+	if t.err != nil {
+		return nil
+	}
+	if t.stopped() {
+		t.err = t.reason
+		return nil
+	}
+	// Add the current account if we just started tracing. Its balance includes
+	// the value sent along with the message, fixed in GetResult.
+	if t.prestate == nil {
+		t.prestate = make(map[common.Address]*prestateAccount)
+		t.db = env.StateDB
+		t.lookupAccount(contract.Address())
+	}
+	// Whenever new state is accessed, add it to the prestate
+	switch op {
+	case vm.EXTCODECOPY, vm.EXTCODESIZE, vm.BALANCE:
+		t.lookupAccount(stackAddress(stack, 0))
+	case vm.CREATE:
+		from := contract.Address()
+		t.lookupAccount(crypto.CreateAddress(from, t.db.GetNonce(from)))
+	case vm.CALL, vm.CALLCODE, vm.DELEGATECALL, vm.STATICCALL:
+		t.lookupAccount(stackAddress(stack, 1))
+	case vm.SSTORE, vm.SLOAD:
+		t.lookupStorage(contract.Address(), common.BigToHash(stack.Back(0)))
+	}
+	return nil
+}
+
+func (t *prestateTracer) CaptureFault(env *vm.EVM, pc uint64, op vm.OpCode, gas, cost uint64, memory *vm.Memory, stack *vm.Stack, contract *vm.Contract, depth int, err error) error {
+	return nil
+}
+
+func (t *prestateTracer) CaptureEnd(output []byte, gasUsed uint64, d time.Duration, err error) error {
+	return nil
+}
+
+// GetResult returns the accounts touched by the transaction, keyed by address.
+func (t *prestateTracer) GetResult() (json.RawMessage, error) {
+	if t.prestate == nil {
+		// no code executed, so nothing was looked up
+		return json.RawMessage(`{}`), t.err
+	}
+	// Deduct the value from the outer transaction, and move it back to the origin
+	t.lookupAccount(t.from)
+	t.lookupAccount(t.to)
+	value := t.value
+	if value == nil {
+		value = new(big.Int)
+	}
+	from, to := t.prestate[t.from], t.prestate[t.to]
+	to.Balance = new(big.Int).Sub(to.Balance, value)
+	from.Balance = new(big.Int).Add(from.Balance, value)
+
+	// Decrement the caller's nonce, and remove empty create targets. Any
+	// existing state would have made the transaction invalid in the first place.
+	if from.Nonce > 0 {
+		from.Nonce--
+	}
+	if t.create {
+		delete(t.prestate, t.to)
+	}
+	res, err := json.Marshal(t.prestate)
+	if err != nil {
+		return nil, err
+	}
+	return res, t.err
+}
@@ -0,0 +1,179 @@
+// Copyright 2018 The aquachain Authors
+// This file is part of the aquachain library.
+//
+// The aquachain library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The aquachain library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the aquachain library. If not, see <http://www.gnu.org/licenses/>.
+
+package tracers
+
+import (
+	"encoding/json"
+	"errors"
+	"math/big"
+	"reflect"
+	"testing"
+
+	"gitlab.com/aquachain/aquachain/aquadb"
+	"gitlab.com/aquachain/aquachain/common"
+	"gitlab.com/aquachain/aquachain/core/state"
+	"gitlab.com/aquachain/aquachain/core/vm"
+	"gitlab.com/aquachain/aquachain/core/vm/runtime"
+)
+
+var (
+	nativeOrigin = common.HexToAddress("0x00000000000000000000000000000000000000aa")
+	nativeCaller = common.HexToAddress("0x00000000000000000000000000000000000000bb")
+	nativeCallee = common.HexToAddress("0x00000000000000000000000000000000000000cc")
+)
+
+// runNative executes nativeCaller, which reads storage slot 0 and calls
+// nativeCallee with the 4 byte input 0x12345678. The callee returns 42.
+func runNative(t *testing.T, tracer ResultTracer, input []byte) (json.RawMessage, error) {
+	statedb, _ := state.New(common.Hash{}, state.NewDatabase(aquadb.NewMemDatabase()))
+	statedb.SetBalance(nativeOrigin, big.NewInt(1000))
+	statedb.SetNonce(nativeOrigin, 1)
+	statedb.SetState(nativeCaller, common.Hash{}, common.BigToHash(big.NewInt(7)))
+	statedb.SetCode(nativeCaller, append(append([]byte{
+		byte(vm.PUSH1), 0x00, byte(vm.SLOAD), byte(vm.POP),
+		byte(vm.PUSH4), 0x12, 0x34, 0x56, 0x78, byte(vm.PUSH1), 0x00, byte(vm.MSTORE),
+		byte(vm.PUSH1), 0x20, // out size
+		byte(vm.PUSH1), 0x00, // out offset
+		byte(vm.PUSH1), 0x04, // in size
+		byte(vm.PUSH1), 0x1c, // in offset
+		byte(vm.PUSH1), 0x00, // value
+		byte(vm.PUSH20)}, nativeCallee.Bytes()...),
+		byte(vm.PUSH2), 0xff, 0xff, byte(vm.CALL), byte(vm.STOP),
+	))
+	statedb.SetCode(nativeCallee, []byte{
+		byte(vm.PUSH1), 0x2a, byte(vm.PUSH1), 0x00, byte(vm.MSTORE),
+		byte(vm.PUSH1), 0x20, byte(vm.PUSH1), 0x00, byte(vm.RETURN),
+	})
+	cfg := &runtime.Config{
+		Origin:    nativeOrigin,
+		State:     statedb,
+		GasLimit:  100000,
+		Value:     big.NewInt(10),
+		EVMConfig: vm.Config{Debug: true, Tracer: tracer},
+	}
+	if _, _, err := runtime.Call(nativeCaller, input, cfg); err != nil {
+		t.Fatalf("execution failed: %v", err)
+	}
+	return tracer.GetResult()
+}
+
+func TestNativeTracers(t *testing.T) {
+	for _, name := range NativeTracers() {
+		tracer, err := NewTracer(name)
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		if _, ok := tracer.(*Tracer); ok {
+			t.Errorf("%s: got JavaScript tracer", name)
+		}
+	}
+}
+
+func TestNativeCallTracer(t *testing.T) {
+	res, err := runNative(t, newCallTracer(), []byte{0xaa, 0xbb, 0xcc, 0xdd, 0xee})
+	if err != nil {
+		t.Fatal(err)
+	}
+	have := new(callFrame)
+	if err := json.Unmarshal(res, have); err != nil {
+		t.Fatal(err)
+	}
+	have.Time = ""
+	want := &callFrame{
+		Type:    "CALL",
+		From:    "0x00000000000000000000000000000000000000aa",
+		To:      "0x00000000000000000000000000000000000000bb",
+		Value:   "0xa",
+		Gas:     "0x186a0",
+		GasUsed: have.GasUsed,
+		Input:   "0xaabbccddee",
+		Output:  "0x",
+		Calls: []*callFrame{{
+			Type:    "CALL",
+			From:    "0x00000000000000000000000000000000000000bb",
+			To:      "0x00000000000000000000000000000000000000cc",
+			Value:   "0x0",
+			Gas:     "0xffff",
+			GasUsed: "0x12",
+			Input:   "0x12345678",
+			Output:  "0x000000000000000000000000000000000000000000000000000000000000002a",
+		}},
+	}
+	if !reflect.DeepEqual(have, want) {
+		haveJSON, _ := json.Marshal(have)
+		wantJSON, _ := json.Marshal(want)
+		t.Fatalf("trace mismatch:\nhave %s\nwant %s", haveJSON, wantJSON)
+	}
+}
+
+func TestNativePrestateTracer(t *testing.T) {
+	res, err := runNative(t, newPrestateTracer(), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var have map[common.Address]struct {
+		Balance string                      `json:"balance"`
+		Nonce   uint64                      `json:"nonce"`
+		Code    string                      `json:"code"`
+		Storage map[common.Hash]common.Hash `json:"storage"`
+	}
+	if err := json.Unmarshal(res, &have); err != nil {
+		t.Fatal(err)
+	}
+	if len(have) != 3 {
+		t.Fatalf("expected 3 accounts, got %s", res)
+	}
+	// the value transfer and nonce increment of the transaction are reverted
+	if acc := have[nativeOrigin]; acc.Balance != "0x3e8" || acc.Nonce != 0 {
+		t.Errorf("wrong origin prestate: %+v", acc)
+	}
+	if acc := have[nativeCaller]; acc.Balance != "0x0" || acc.Storage[common.Hash{}] != common.BigToHash(big.NewInt(7)) {
+		t.Errorf("wrong caller prestate: %+v", acc)
+	}
+	if acc := have[nativeCallee]; len(acc.Code) != 22 || len(acc.Storage) != 0 {
+		t.Errorf("wrong callee prestate: %+v", acc)
+	}
+}
+
+func TestNativeFourByteTracer(t *testing.T) {
+	res, err := runNative(t, newFourByteTracer(), []byte{0xaa, 0xbb, 0xcc, 0xdd, 0xee})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := `{"0x12345678-0":1,"0xaabbccdd-1":1}`; string(res) != want {
+		t.Fatalf("have %s, want %s", res, want)
+	}
+}
+
+func TestNativeOpcountTracer(t *testing.T) {
+	res, err := runNative(t, new(opcountTracer), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(res) != "21" {
+		t.Fatalf("have %s, want 21", res)
+	}
+}
+
+func TestNativeStop(t *testing.T) {
+	stop := errors.New("stahp")
+	tracer := newCallTracer()
+	tracer.Stop(stop)
+	if _, err := runNative(t, tracer, nil); err != stop {
+		t.Fatalf("expected stop error, got %v", err)
+	}
+}
@@ -33,7 +33,7 @@ import (
 // function for each VM execution step.
 type Tracer struct{}
 
-var ErrNoCgo = errors.New("This version of aquachain has been compiled with pure go, and has no tracers.")
+var ErrNoCgo = errors.New("This version of aquachain has been compiled with pure go, and only has the native tracers (callTracer, prestateTracer, 4byteTracer, opcountTracer).")
 
 // New instantiates a new tracer instance. code specifies a Javascript snippet,
 // which must evaluate to an expression returning an object with 'step', 'fault'
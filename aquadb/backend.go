@@ -0,0 +1,134 @@
+// Copyright 2018 The aquachain Authors
+// This file is part of the aquachain library.
+//
+// The aquachain library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The aquachain library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the aquachain library. If not, see <http://www.gnu.org/licenses/>.
+
+package aquadb
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Disk database backends
+const (
+	LevelDB = "leveldb"
+	Pebble  = "pebble"
+)
+
+// DefaultBackend is the backend of new databases if none is configured
+const DefaultBackend = LevelDB
+
+// Backends lists the supported disk database backends
+var Backends = []string{LevelDB, Pebble}
+
+// backendFile records the backend inside the database directory, so a
+// database is never opened with the wrong engine.
+const backendFile = "BACKEND"
+
+// DiskBackend returns the backend of the database at the path, or an empty
+// string if there is no database.
+func DiskBackend(file string) (string, error) {
+	data, err := ioutil.ReadFile(filepath.Join(file, backendFile))
+	if err == nil {
+		return strings.TrimSpace(string(data)), nil
+	}
+	if !os.IsNotExist(err) {
+		return "", err
+	}
+	// databases from before backends were selectable are always leveldb
+	if _, err := os.Stat(filepath.Join(file, "CURRENT")); err == nil {
+		return LevelDB, nil
+	}
+	return "", nil
+}
+
+// Open opens the disk database at the path, creating it if needed. If the
+// backend is empty, an existing database is opened with the backend it was
+// created with, and new databases use DefaultBackend. Opening an existing
+// database with a different backend fails.
+func Open(backend string, file string, cache int, handles int) (Database, error) {
+	existing, err := DiskBackend(file)
+	if err != nil {
+		return nil, err
+	}
+	switch {
+	case backend == "" && existing == "":
+		backend = DefaultBackend
+	case backend == "":
+		backend = existing
+	case existing != "" && existing != backend:
+		return nil, fmt.Errorf("database %s uses the %s backend, not %s", file, existing, backend)
+	}
+	var db Database
+	switch backend {
+	case LevelDB:
+		db, err = NewLDBDatabase(file, cache, handles)
+	case Pebble:
+		db, err = NewPebbleDatabase(file, cache, handles)
+	default:
+		return nil, fmt.Errorf("unknown database backend %q, want one of %s", backend, strings.Join(Backends, ", "))
+	}
+	if err != nil {
+		return nil, err
+	}
+	if _, err := os.Stat(filepath.Join(file, backendFile)); os.IsNotExist(err) {
+		if err := ioutil.WriteFile(filepath.Join(file, backendFile), []byte(backend+"\n"), 0644); err != nil {
+			db.Close()
+			return nil, err
+		}
+	}
+	return db, nil
+}
+
+// Copy copies every key/value pair from src to dst in batches, returning the
+// number of keys copied. The progress function, if not nil, is called after
+// each batch is written.
+func Copy(dst Database, src Iteratee, progress func(keys int, last []byte)) (int, error) {
+	var (
+		it    = src.NewIterator()
+		batch = dst.NewBatch()
+		keys  int
+	)
+	defer it.Release()
+
+	for it.Next() {
+		if err := batch.Put(it.Key(), it.Value()); err != nil {
+			return keys, err
+		}
+		keys++
+		if batch.ValueSize() >= IdealBatchSize {
+			if err := batch.Write(); err != nil {
+				return keys, err
+			}
+			batch.Reset()
+			if progress != nil {
+				progress(keys, it.Key())
+			}
+		}
+	}
+	if err := it.Error(); err != nil {
+		return keys, err
+	}
+	if err := batch.Write(); err != nil {
+		return keys, err
+	}
+	if progress != nil {
+		progress(keys, nil)
+	}
+	return keys, nil
+}
@@ -0,0 +1,196 @@
+// Copyright 2018 The aquachain Authors
+// This file is part of the aquachain library.
+//
+// The aquachain library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The aquachain library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the aquachain library. If not, see <http://www.gnu.org/licenses/>.
+
+package aquadb_test
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"gitlab.com/aquachain/aquachain/aquadb"
+)
+
+type iterateeDatabase interface {
+	aquadb.Database
+	aquadb.Iteratee
+}
+
+func TestLDB_Iterator(t *testing.T) {
+	db, remove := newTestLDB()
+	defer remove()
+	testIterator(db, t)
+}
+
+func TestPebble_Iterator(t *testing.T) {
+	db, remove := newTestPebble()
+	defer remove()
+	testIterator(db, t)
+}
+
+func TestMemoryDB_Iterator(t *testing.T) {
+	testIterator(aquadb.NewMemDatabase(), t)
+}
+
+func testIterator(db iterateeDatabase, t *testing.T) {
+	keys := []string{"a", "aa", "ab", "b", "b\xff", "b\xff\xff", "c"}
+	batch := db.NewBatch()
+	for i := len(keys) - 1; i >= 0; i-- {
+		batch.Put([]byte(keys[i]), []byte("v"+keys[i]))
+	}
+	if err := batch.Write(); err != nil {
+		t.Fatal(err)
+	}
+	collect := func(it aquadb.Iterator) (have []string) {
+		defer it.Release()
+		for it.Next() {
+			if !bytes.Equal(it.Value(), append([]byte("v"), it.Key()...)) {
+				t.Errorf("wrong value for %q: %q", it.Key(), it.Value())
+			}
+			have = append(have, string(it.Key()))
+		}
+		if err := it.Error(); err != nil {
+			t.Fatal(err)
+		}
+		return have
+	}
+	tests := []struct {
+		prefix string
+		want   []string
+	}{
+		{"", keys},
+		{"a", []string{"a", "aa", "ab"}},
+		{"b\xff", []string{"b\xff", "b\xff\xff"}},
+		{"d", nil},
+	}
+	for _, tt := range tests {
+		if have := collect(db.NewIteratorWithPrefix([]byte(tt.prefix))); fmt.Sprint(have) != fmt.Sprint(tt.want) {
+			t.Errorf("prefix %q: have %q, want %q", tt.prefix, have, tt.want)
+		}
+	}
+	// seeking positions at the first key not below the seek key, and the
+	// following Next moves past it
+	it := db.NewIterator()
+	defer it.Release()
+	if !it.Seek([]byte("ac")) || string(it.Key()) != "b" {
+		t.Fatalf("seek to wrong key %q", it.Key())
+	}
+	if !it.Next() || string(it.Key()) != "b\xff" {
+		t.Fatalf("next after seek at wrong key %q", it.Key())
+	}
+}
+
+func TestBatchReset(t *testing.T) {
+	db, remove := newTestPebble()
+	defer remove()
+	db.Meter("aquadb/test/")
+
+	batch := db.NewBatch()
+	batch.Put([]byte("a"), []byte("1"))
+	batch.Delete([]byte("b"))
+	if batch.ValueSize() != 2 {
+		t.Fatalf("wrong value size %d", batch.ValueSize())
+	}
+	batch.Reset()
+	if batch.ValueSize() != 0 {
+		t.Fatalf("value size not reset")
+	}
+	batch.Put([]byte("c"), []byte("3"))
+	if err := batch.Write(); err != nil {
+		t.Fatal(err)
+	}
+	if ok, _ := db.Has([]byte("a")); ok {
+		t.Errorf("reset batch write was committed")
+	}
+	if ok, _ := db.Has([]byte("c")); !ok {
+		t.Errorf("batch write was not committed")
+	}
+}
+
+func TestOpenBackend(t *testing.T) {
+	dir, err := ioutil.TempDir("", "aquadb_test_")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	// new databases record their backend
+	db, err := aquadb.Open(aquadb.Pebble, filepath.Join(dir, "pebble"), 0, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	db.Put([]byte("key"), []byte("value"))
+	db.Close()
+	if backend, _ := aquadb.DiskBackend(filepath.Join(dir, "pebble")); backend != aquadb.Pebble {
+		t.Fatalf("wrong backend recorded: %q", backend)
+	}
+	// and can't be opened with a different one
+	if _, err := aquadb.Open(aquadb.LevelDB, filepath.Join(dir, "pebble"), 0, 0); err == nil {
+		t.Fatal("opened pebble database with leveldb")
+	}
+	// no backend opens the existing one
+	db, err = aquadb.Open("", filepath.Join(dir, "pebble"), 0, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := db.(*aquadb.PebbleDatabase); !ok {
+		t.Fatalf("wrong database type %T", db)
+	}
+	if v, _ := db.Get([]byte("key")); string(v) != "value" {
+		t.Fatalf("wrong value %q", v)
+	}
+	db.Close()
+
+	// databases from before the backend was recorded are leveldb
+	ldb, err := aquadb.NewLDBDatabase(filepath.Join(dir, "legacy"), 0, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ldb.Close()
+	if _, err := aquadb.Open(aquadb.Pebble, filepath.Join(dir, "legacy"), 0, 0); err == nil {
+		t.Fatal("opened legacy leveldb database with pebble")
+	}
+	if _, err := aquadb.Open("rocksdb", filepath.Join(dir, "unknown"), 0, 0); err == nil {
+		t.Fatal("opened database with unknown backend")
+	}
+}
+
+func TestCopy(t *testing.T) {
+	src := aquadb.NewMemDatabase()
+	for i := 0; i < 5000; i++ {
+		src.Put([]byte(fmt.Sprintf("key%05d", i)), bytes.Repeat([]byte{byte(i)}, 100))
+	}
+	dst, remove := newTestPebble()
+	defer remove()
+
+	var batches int
+	n, err := aquadb.Copy(dst, src, func(int, []byte) { batches++ })
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 5000 || batches < 2 {
+		t.Fatalf("copied %d keys in %d batches", n, batches)
+	}
+	for _, key := range src.Keys() {
+		want, _ := src.Get(key)
+		if have, err := dst.Get(key); err != nil || !bytes.Equal(have, want) {
+			t.Fatalf("key %q not copied: %v", key, err)
+		}
+	}
+}
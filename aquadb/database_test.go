@@ -44,6 +44,22 @@ func newTestLDB() (*aquadb.LDBDatabase, func()) {
 	}
 }
 
+func newTestPebble() (*aquadb.PebbleDatabase, func()) {
+	dirname, err := ioutil.TempDir(os.TempDir(), "aquadb_test_")
+	if err != nil {
+		panic("failed to create test file: " + err.Error())
+	}
+	db, err := aquadb.NewPebbleDatabase(dirname, 0, 0)
+	if err != nil {
+		panic("failed to create test database: " + err.Error())
+	}
+
+	return db, func() {
+		db.Close()
+		os.RemoveAll(dirname)
+	}
+}
+
 var test_values = []string{"", "a", "1251", "\x00123\x00"}
 
 func TestLDB_PutGet(t *testing.T) {
@@ -52,6 +68,12 @@ func TestLDB_PutGet(t *testing.T) {
 	testPutGet(db, t)
 }
 
+func TestPebble_PutGet(t *testing.T) {
+	db, remove := newTestPebble()
+	defer remove()
+	testPutGet(db, t)
+}
+
 func TestMemoryDB_PutGet(t *testing.T) {
 	testPutGet(aquadb.NewMemDatabase(), t)
 }
@@ -151,6 +173,12 @@ func TestLDB_ParallelPutGet(t *testing.T) {
 	testParallelPutGet(db, t)
 }
 
+func TestPebble_ParallelPutGet(t *testing.T) {
+	db, remove := newTestPebble()
+	defer remove()
+	testParallelPutGet(db, t)
+}
+
 func TestMemoryDB_ParallelPutGet(t *testing.T) {
 	testParallelPutGet(aquadb.NewMemDatabase(), t)
 }
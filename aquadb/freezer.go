@@ -0,0 +1,442 @@
+// Copyright 2018 The aquachain Authors
+// This file is part of the aquachain library.
+//
+// The aquachain library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The aquachain library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the aquachain library. If not, see <http://www.gnu.org/licenses/>.
+
+package aquadb
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/golang/snappy"
+	"gitlab.com/aquachain/aquachain/common/log"
+)
+
+var (
+	errUnknownTable   = errors.New("unknown ancient table")
+	errOutOfBounds    = errors.New("ancient item out of bounds")
+	errOutOfOrder     = errors.New("ancient item appended out of order")
+	errMismatchTables = errors.New("ancient items do not match the tables")
+)
+
+// Freezer is an ancient store kept in append-only flat files. Every table is
+// a data file of snappy compressed items and an index file holding the end
+// offset of each item in the data file, so item n is found with two reads.
+//
+// All tables hold the same number of items. A crash during an append can
+// leave some tables ahead of the others, which is repaired on the next open
+// by truncating every table to the shortest one.
+type Freezer struct {
+	dir    string
+	items  uint64
+	tables map[string]*freezerTable
+	lock   sync.RWMutex
+}
+
+// NewFreezer opens the ancient store in the directory, creating it if needed.
+func NewFreezer(dir string) (*Freezer, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+	indexes, err := filepath.Glob(filepath.Join(dir, "*.idx"))
+	if err != nil {
+		return nil, err
+	}
+	f := &Freezer{
+		dir:    dir,
+		tables: make(map[string]*freezerTable),
+	}
+	for i, index := range indexes {
+		name := strings.TrimSuffix(filepath.Base(index), ".idx")
+		table, err := openFreezerTable(dir, name)
+		if err != nil {
+			f.Close()
+			return nil, err
+		}
+		f.tables[name] = table
+		if i == 0 || table.items < f.items {
+			f.items = table.items
+		}
+	}
+	for _, table := range f.tables {
+		if table.items > f.items {
+			log.Warn("Truncating dangling ancient items", "table", table.name, "items", table.items, "limit", f.items)
+			if err := table.truncate(f.items); err != nil {
+				f.Close()
+				return nil, err
+			}
+		}
+	}
+	log.Info("Opened ancient store", "dir", dir, "items", f.items)
+	return f, nil
+}
+
+// Ancient retrieves the item with the given number from the named table.
+func (f *Freezer) Ancient(kind string, number uint64) ([]byte, error) {
+	f.lock.RLock()
+	defer f.lock.RUnlock()
+
+	table := f.tables[kind]
+	if table == nil {
+		return nil, errUnknownTable
+	}
+	if number >= f.items {
+		return nil, errOutOfBounds
+	}
+	return table.retrieve(number)
+}
+
+// Ancients returns the number of items in the ancient store.
+func (f *Freezer) Ancients() uint64 {
+	f.lock.RLock()
+	defer f.lock.RUnlock()
+	return f.items
+}
+
+// AncientSize returns the size of the named table on disk.
+func (f *Freezer) AncientSize(kind string) uint64 {
+	f.lock.RLock()
+	defer f.lock.RUnlock()
+
+	if table := f.tables[kind]; table != nil {
+		return table.diskSize()
+	}
+	return 0
+}
+
+// AncientTables returns the names of the tables, sorted.
+func (f *Freezer) AncientTables() []string {
+	f.lock.RLock()
+	defer f.lock.RUnlock()
+
+	names := make([]string, 0, len(f.tables))
+	for name := range f.tables {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// AppendAncient appends an item to every table. Tables are created by the
+// first append, after which every append must name the same tables. If any
+// table fails, all of them are rolled back.
+func (f *Freezer) AppendAncient(number uint64, items map[string][]byte) error {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	if number != f.items {
+		return fmt.Errorf("%w: have %d, want %d", errOutOfOrder, number, f.items)
+	}
+	if f.items == 0 {
+		for name := range items {
+			if f.tables[name] != nil {
+				continue
+			}
+			table, err := openFreezerTable(f.dir, name)
+			if err != nil {
+				return err
+			}
+			f.tables[name] = table
+		}
+	}
+	if len(items) != len(f.tables) {
+		return errMismatchTables
+	}
+	for name := range items {
+		if f.tables[name] == nil {
+			return errMismatchTables
+		}
+	}
+	for name, item := range items {
+		if err := f.tables[name].append(item); err != nil {
+			for _, table := range f.tables {
+				table.truncate(f.items)
+			}
+			return err
+		}
+	}
+	f.items++
+	return nil
+}
+
+// TruncateAncients discards all items numbered from items onwards.
+func (f *Freezer) TruncateAncients(items uint64) error {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	if items >= f.items {
+		return nil
+	}
+	for _, table := range f.tables {
+		if err := table.truncate(items); err != nil {
+			return err
+		}
+	}
+	f.items = items
+	return nil
+}
+
+// SyncAncient flushes all tables to disk.
+func (f *Freezer) SyncAncient() error {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	for _, table := range f.tables {
+		if err := table.sync(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Close flushes and closes all tables.
+func (f *Freezer) Close() error {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	var errs []error
+	for _, table := range f.tables {
+		if err := table.close(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// freezerTable is a single table of the ancient store.
+type freezerTable struct {
+	name  string
+	items uint64 // number of items in the table
+	size  uint64 // size of the data file, the end offset of the last item
+	data  *os.File
+	index *os.File
+}
+
+// openFreezerTable opens or creates the named table, discarding any partially
+// written item.
+func openFreezerTable(dir, name string) (*freezerTable, error) {
+	data, err := os.OpenFile(filepath.Join(dir, name+".dat"), os.O_RDWR|os.O_CREATE, 0644)
+	if err != nil {
+		return nil, err
+	}
+	index, err := os.OpenFile(filepath.Join(dir, name+".idx"), os.O_RDWR|os.O_CREATE, 0644)
+	if err != nil {
+		data.Close()
+		return nil, err
+	}
+	t := &freezerTable{name: name, data: data, index: index}
+	if err := t.repair(); err != nil {
+		t.close()
+		return nil, err
+	}
+	return t, nil
+}
+
+// repair drops index entries that are incomplete or point past the end of
+// the data file, then cuts the data file at the end of the last item.
+func (t *freezerTable) repair() error {
+	istat, err := t.index.Stat()
+	if err != nil {
+		return err
+	}
+	dstat, err := t.data.Stat()
+	if err != nil {
+		return err
+	}
+	t.items = uint64(istat.Size()) / 8
+	for t.items > 0 {
+		end, err := t.offset(t.items - 1)
+		if err != nil {
+			return err
+		}
+		if end <= uint64(dstat.Size()) {
+			break
+		}
+		t.items--
+	}
+	if uint64(istat.Size()) != t.items*8 {
+		log.Warn("Repairing ancient table index", "table", t.name, "items", t.items)
+		if err := t.index.Truncate(int64(t.items * 8)); err != nil {
+			return err
+		}
+	}
+	if t.items > 0 {
+		if t.size, err = t.offset(t.items - 1); err != nil {
+			return err
+		}
+	}
+	if uint64(dstat.Size()) != t.size {
+		log.Warn("Repairing ancient table data", "table", t.name, "size", t.size)
+		return t.data.Truncate(int64(t.size))
+	}
+	return nil
+}
+
+// offset returns the end offset of the item in the data file.
+func (t *freezerTable) offset(number uint64) (uint64, error) {
+	var buf [8]byte
+	if _, err := t.index.ReadAt(buf[:], int64(number*8)); err != nil {
+		return 0, err
+	}
+	return binary.BigEndian.Uint64(buf[:]), nil
+}
+
+// retrieve reads and decompresses an item.
+func (t *freezerTable) retrieve(number uint64) ([]byte, error) {
+	if number >= t.items {
+		return nil, errOutOfBounds
+	}
+	var start uint64
+	if number > 0 {
+		var err error
+		if start, err = t.offset(number - 1); err != nil {
+			return nil, err
+		}
+	}
+	end, err := t.offset(number)
+	if err != nil {
+		return nil, err
+	}
+	if end < start {
+		return nil, fmt.Errorf("corrupt ancient table %s: item %d ends before it starts", t.name, number)
+	}
+	blob := make([]byte, end-start)
+	if _, err := t.data.ReadAt(blob, int64(start)); err != nil {
+		return nil, err
+	}
+	return snappy.Decode(nil, blob)
+}
+
+// append compresses and writes an item after the last one, data first so an
+// index entry never points at missing data.
+func (t *freezerTable) append(item []byte) error {
+	blob := snappy.Encode(nil, item)
+	if _, err := t.data.WriteAt(blob, int64(t.size)); err != nil {
+		return err
+	}
+	var buf [8]byte
+	binary.BigEndian.PutUint64(buf[:], t.size+uint64(len(blob)))
+	if _, err := t.index.WriteAt(buf[:], int64(t.items*8)); err != nil {
+		return err
+	}
+	t.size += uint64(len(blob))
+	t.items++
+	return nil
+}
+
+// truncate discards all items numbered from items onwards.
+func (t *freezerTable) truncate(items uint64) error {
+	if items >= t.items {
+		return nil
+	}
+	var size uint64
+	if items > 0 {
+		var err error
+		if size, err = t.offset(items - 1); err != nil {
+			return err
+		}
+	}
+	if err := t.index.Truncate(int64(items * 8)); err != nil {
+		return err
+	}
+	if err := t.data.Truncate(int64(size)); err != nil {
+		return err
+	}
+	t.items, t.size = items, size
+	return nil
+}
+
+func (t *freezerTable) diskSize() uint64 {
+	return t.size + t.items*8
+}
+
+func (t *freezerTable) sync() error {
+	if err := t.data.Sync(); err != nil {
+		return err
+	}
+	return t.index.Sync()
+}
+
+func (t *freezerTable) close() error {
+	return errors.Join(t.sync(), t.data.Close(), t.index.Close())
+}
+
+// freezerDatabase is a key-value database with an ancient store attached.
+type freezerDatabase struct {
+	Database
+	*Freezer
+}
+
+// NewDatabaseWithFreezer attaches an ancient store to a key-value database.
+// The returned database implements AncientStore, and closing it closes both.
+func NewDatabaseWithFreezer(db Database, freezer *Freezer) Database {
+	return &freezerDatabase{Database: db, Freezer: freezer}
+}
+
+// OpenWithFreezer opens the disk database at the path like Open, with the
+// ancient store in the given directory attached.
+func OpenWithFreezer(backend string, file string, cache int, handles int, ancient string) (Database, error) {
+	db, err := Open(backend, file, cache, handles)
+	if err != nil {
+		return nil, err
+	}
+	freezer, err := NewFreezer(ancient)
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+	return NewDatabaseWithFreezer(db, freezer), nil
+}
+
+func (db *freezerDatabase) Close() {
+	if err := db.Freezer.Close(); err != nil {
+		log.Error("Failed to close ancient store", "err", err)
+	}
+	db.Database.Close()
+}
+
+func (db *freezerDatabase) NewIterator() Iterator {
+	return db.Database.(Iteratee).NewIterator()
+}
+
+func (db *freezerDatabase) NewIteratorWithPrefix(prefix []byte) Iterator {
+	return db.Database.(Iteratee).NewIteratorWithPrefix(prefix)
+}
+
+func (db *freezerDatabase) Compact(start []byte, limit []byte) error {
+	if c, ok := db.Database.(Compacter); ok {
+		return c.Compact(start, limit)
+	}
+	return nil
+}
+
+func (db *freezerDatabase) Stat(property string) (string, error) {
+	if s, ok := db.Database.(Stater); ok {
+		return s.Stat(property)
+	}
+	return "", errors.New("database statistics not supported")
+}
+
+func (db *freezerDatabase) Meter(prefix string) {
+	if m, ok := db.Database.(interface{ Meter(prefix string) }); ok {
+		m.Meter(prefix)
+	}
+}
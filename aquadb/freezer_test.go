@@ -0,0 +1,190 @@
+// Copyright 2018 The aquachain Authors
+// This file is part of the aquachain library.
+//
+// The aquachain library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The aquachain library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the aquachain library. If not, see <http://www.gnu.org/licenses/>.
+
+package aquadb_test
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"gitlab.com/aquachain/aquachain/aquadb"
+)
+
+func freezerItem(kind string, number uint64) []byte {
+	return bytes.Repeat([]byte(fmt.Sprintf("%s-%d,", kind, number)), int(number%7)+1)
+}
+
+func appendItems(t *testing.T, f *aquadb.Freezer, from, to uint64) {
+	for n := from; n < to; n++ {
+		items := map[string][]byte{
+			"headers": freezerItem("headers", n),
+			"bodies":  freezerItem("bodies", n),
+		}
+		if err := f.AppendAncient(n, items); err != nil {
+			t.Fatalf("append %d: %v", n, err)
+		}
+	}
+}
+
+func checkItems(t *testing.T, f *aquadb.Freezer, items uint64) {
+	if have := f.Ancients(); have != items {
+		t.Fatalf("wrong item count: have %d, want %d", have, items)
+	}
+	for n := uint64(0); n < items; n++ {
+		for _, kind := range []string{"headers", "bodies"} {
+			blob, err := f.Ancient(kind, n)
+			if err != nil {
+				t.Fatalf("%s %d: %v", kind, n, err)
+			}
+			if !bytes.Equal(blob, freezerItem(kind, n)) {
+				t.Fatalf("%s %d: wrong item %q", kind, n, blob)
+			}
+		}
+	}
+	if _, err := f.Ancient("headers", items); err == nil {
+		t.Fatalf("item %d beyond the end was returned", items)
+	}
+}
+
+func TestFreezer(t *testing.T) {
+	dir, err := ioutil.TempDir("", "aquadb_freezer_")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	f, err := aquadb.NewFreezer(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	appendItems(t, f, 0, 100)
+	checkItems(t, f, 100)
+
+	// appends must be in order and cover the same tables
+	if err := f.AppendAncient(50, map[string][]byte{"headers": nil, "bodies": nil}); err == nil {
+		t.Fatal("out of order append accepted")
+	}
+	if err := f.AppendAncient(100, map[string][]byte{"headers": nil}); err == nil {
+		t.Fatal("append with missing table accepted")
+	}
+	if _, err := f.Ancient("receipts", 0); err == nil {
+		t.Fatal("unknown table read")
+	}
+	if tables := fmt.Sprint(f.AncientTables()); tables != "[bodies headers]" {
+		t.Fatalf("wrong tables %s", tables)
+	}
+	if err := f.TruncateAncients(60); err != nil {
+		t.Fatal(err)
+	}
+	checkItems(t, f, 60)
+	appendItems(t, f, 60, 80)
+	checkItems(t, f, 80)
+	size := f.AncientSize("bodies")
+	if err := f.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	// reopening restores everything
+	if f, err = aquadb.NewFreezer(dir); err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	checkItems(t, f, 80)
+	if have := f.AncientSize("bodies"); have != size {
+		t.Fatalf("wrong size after reopen: have %d, want %d", have, size)
+	}
+}
+
+func TestFreezerRepair(t *testing.T) {
+	dir, err := ioutil.TempDir("", "aquadb_freezer_")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	f, err := aquadb.NewFreezer(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	appendItems(t, f, 0, 20)
+	f.Close()
+
+	// simulate a crash during an append: a partial index entry in one table,
+	// and an item whose data never made it to disk in the other
+	index, err := os.OpenFile(filepath.Join(dir, "headers.idx"), os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	index.Write([]byte{0, 0, 0})
+	index.Close()
+
+	data, err := os.OpenFile(filepath.Join(dir, "bodies.dat"), os.O_RDWR, 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	stat, _ := data.Stat()
+	data.Truncate(stat.Size() - 1)
+	data.Close()
+
+	if f, err = aquadb.NewFreezer(dir); err != nil {
+		t.Fatal(err)
+	}
+	checkItems(t, f, 19)
+	appendItems(t, f, 19, 30)
+	checkItems(t, f, 30)
+	f.Close()
+}
+
+func TestDatabaseWithFreezer(t *testing.T) {
+	dir, err := ioutil.TempDir("", "aquadb_freezer_")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	db, err := aquadb.OpenWithFreezer(aquadb.Pebble, filepath.Join(dir, "chaindata"), 0, 0, filepath.Join(dir, "ancient"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	db.Put([]byte("key"), []byte("value"))
+	f := db.(aquadb.AncientStore)
+	if err := f.AppendAncient(0, map[string][]byte{"headers": []byte("genesis")}); err != nil {
+		t.Fatal(err)
+	}
+	// the wrapper keeps the optional interfaces of the key-value store
+	if _, ok := db.(aquadb.Iteratee); !ok {
+		t.Error("database is not iterable")
+	}
+	if _, err := db.(aquadb.Stater).Stat(""); err != nil {
+		t.Error(err)
+	}
+	db.Close()
+
+	if db, err = aquadb.OpenWithFreezer("", filepath.Join(dir, "chaindata"), 0, 0, filepath.Join(dir, "ancient")); err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	if v, _ := db.Get([]byte("key")); string(v) != "value" {
+		t.Fatalf("wrong value %q", v)
+	}
+	if blob, _ := db.(aquadb.AncientReader).Ancient("headers", 0); string(blob) != "genesis" {
+		t.Fatalf("wrong ancient item %q", blob)
+	}
+}
@@ -50,3 +50,70 @@ type Batch interface {
 	// Reset resets the batch for reuse
 	Reset()
 }
+
+// Iterator iterates over a database's key/value pairs in ascending key order.
+// The key and value are only valid until the next call to Next or Seek.
+type Iterator interface {
+	// Next moves the iterator to the next key/value pair, the first call
+	// moving to the first pair. It returns false when exhausted.
+	Next() bool
+	// Seek moves the iterator to the first key/value pair whose key is greater
+	// than or equal to the given key, returning whether such pair exists.
+	Seek(key []byte) bool
+	Key() []byte
+	Value() []byte
+	Error() error
+	// Release releases associated resources. Release must be called when the
+	// iterator is no longer needed.
+	Release()
+}
+
+// Iteratee wraps the NewIterator methods of databases that can be iterated.
+type Iteratee interface {
+	NewIterator() Iterator
+	NewIteratorWithPrefix(prefix []byte) Iterator
+}
+
+// Compacter wraps the Compact method of disk databases.
+type Compacter interface {
+	// Compact flattens the underlying data store for the given key range. A nil
+	// start is treated as a key before all keys, and a nil limit as a key
+	// after all keys.
+	Compact(start []byte, limit []byte) error
+}
+
+// Stater wraps the Stat method of disk databases.
+type Stater interface {
+	// Stat returns a backend specific statistic of the database, or a summary
+	// of the database internals if the property is empty.
+	Stat(property string) (string, error)
+}
+
+// AncientReader wraps the read methods of an ancient store, an append-only
+// store of numbered items kept in named tables.
+type AncientReader interface {
+	// Ancient retrieves the item with the given number from the named table.
+	Ancient(kind string, number uint64) ([]byte, error)
+	// Ancients returns the number of items in the ancient store, which is also
+	// the number of the next item to append.
+	Ancients() uint64
+	// AncientSize returns the size of the named table on disk.
+	AncientSize(kind string) uint64
+}
+
+// AncientWriter wraps the write methods of an ancient store.
+type AncientWriter interface {
+	// AppendAncient appends an item to every table, keyed by table name. The
+	// number must be the next number of the store.
+	AppendAncient(number uint64, items map[string][]byte) error
+	// TruncateAncients discards all items numbered from items onwards.
+	TruncateAncients(items uint64) error
+	// SyncAncient flushes appended items to disk.
+	SyncAncient() error
+}
+
+// AncientStore is an ancient store that can be read and written.
+type AncientStore interface {
+	AncientReader
+	AncientWriter
+}
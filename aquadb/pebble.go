@@ -0,0 +1,406 @@
+// Copyright 2018 The aquachain Authors
+// This file is part of the aquachain library.
+//
+// The aquachain library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The aquachain library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the aquachain library. If not, see <http://www.gnu.org/licenses/>.
+
+package aquadb
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"runtime"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/cockroachdb/pebble"
+	"github.com/cockroachdb/pebble/bloom"
+	"gitlab.com/aquachain/aquachain/common/log"
+	"gitlab.com/aquachain/aquachain/common/metrics"
+)
+
+// errNotFound is returned by Get for missing keys, same as the memory database
+var errNotFound = errors.New("not found")
+
+// PebbleDatabase is a disk database backed by Pebble, a LevelDB/RocksDB
+// inspired key-value store with less severe compaction write stalls.
+type PebbleDatabase struct {
+	fn string     // filename for reporting
+	db *pebble.DB // Pebble instance
+
+	compTimeMeter    metrics.Meter // Meter for measuring the total time spent in database compaction
+	compReadMeter    metrics.Meter // Meter for measuring the data read during compaction
+	compWriteMeter   metrics.Meter // Meter for measuring the data written during compaction
+	writeDelayNMeter metrics.Meter // Meter for measuring the write delay number due to database compaction
+	writeDelayMeter  metrics.Meter // Meter for measuring the write delay duration due to database compaction
+	diskWriteMeter   metrics.Meter // Meter for measuring the effective amount of data written
+
+	writeDelayStart time.Time // Start of the current write stall, only accessed by pebble's event listener
+	writeDelayN     int64     // Total number of write stalls, atomic
+	writeDelay      int64     // Total duration of write stalls in nanoseconds, atomic
+	lastStallWarn   time.Time // Time of the last write stall warning
+
+	quitLock sync.Mutex      // Mutex protecting the quit channel access
+	quitChan chan chan error // Quit channel to stop the metrics collection before closing the database
+
+	log log.LoggerI // Contextual logger tracking the database path
+}
+
+// pebbleLogger sends pebble's log messages to the database logger
+type pebbleLogger struct {
+	log log.LoggerI
+}
+
+func (l pebbleLogger) Infof(format string, args ...interface{}) {
+	l.log.Debug(fmt.Sprintf("Pebble: "+format, args...))
+}
+
+func (l pebbleLogger) Fatalf(format string, args ...interface{}) {
+	l.log.Crit(fmt.Sprintf("Pebble: "+format, args...))
+}
+
+// NewPebbleDatabase returns a Pebble wrapped object. The cache and handles
+// have the same meaning as for NewLDBDatabase.
+func NewPebbleDatabase(file string, cache int, handles int) (*PebbleDatabase, error) {
+	logger := log.New("database", file)
+
+	// Ensure we have some minimal caching and file guarantees
+	if cache < 16 {
+		cache = 16
+	}
+	if handles < 16 {
+		handles = 16
+	}
+	logger.Info("Allocated cache and file handles", "cache", cache, "handles", handles, "backend", Pebble)
+
+	db := &PebbleDatabase{
+		fn:  file,
+		log: logger,
+	}
+	// Two memory tables are used internally, like leveldb's write buffers
+	memTableSize := cache / 4 * 1024 * 1024
+	pcache := pebble.NewCache(int64(cache / 2 * 1024 * 1024))
+	defer pcache.Unref()
+
+	opts := &pebble.Options{
+		Cache:                       pcache,
+		MaxOpenFiles:                handles,
+		MemTableSize:                uint64(memTableSize),
+		MemTableStopWritesThreshold: 2,
+		MaxConcurrentCompactions:    func() int { return runtime.NumCPU() },
+		Levels: []pebble.LevelOptions{
+			{TargetFileSize: 2 * 1024 * 1024, FilterPolicy: bloom.FilterPolicy(10)},
+			{TargetFileSize: 4 * 1024 * 1024, FilterPolicy: bloom.FilterPolicy(10)},
+			{TargetFileSize: 8 * 1024 * 1024, FilterPolicy: bloom.FilterPolicy(10)},
+			{TargetFileSize: 16 * 1024 * 1024, FilterPolicy: bloom.FilterPolicy(10)},
+			{TargetFileSize: 32 * 1024 * 1024, FilterPolicy: bloom.FilterPolicy(10)},
+			{TargetFileSize: 64 * 1024 * 1024, FilterPolicy: bloom.FilterPolicy(10)},
+			{TargetFileSize: 128 * 1024 * 1024, FilterPolicy: bloom.FilterPolicy(10)},
+		},
+		Logger: pebbleLogger{logger},
+		EventListener: &pebble.EventListener{
+			WriteStallBegin: db.onWriteStallBegin,
+			WriteStallEnd:   db.onWriteStallEnd,
+		},
+	}
+	pdb, err := pebble.Open(file, opts)
+	if err != nil {
+		return nil, err
+	}
+	db.db = pdb
+	return db, nil
+}
+
+func (db *PebbleDatabase) onWriteStallBegin(info pebble.WriteStallBeginInfo) {
+	db.writeDelayStart = time.Now()
+	atomic.AddInt64(&db.writeDelayN, 1)
+
+	// withhold subsequent warnings for one minute not to overwhelm the user
+	if time.Now().After(db.lastStallWarn.Add(writePauseWarningThrottler)) {
+		db.log.Warn("Database compacting, degraded performance", "reason", info.Reason)
+		db.lastStallWarn = time.Now()
+	}
+}
+
+func (db *PebbleDatabase) onWriteStallEnd() {
+	atomic.AddInt64(&db.writeDelay, int64(time.Since(db.writeDelayStart)))
+}
+
+// Path returns the path to the database directory.
+func (db *PebbleDatabase) Path() string {
+	return db.fn
+}
+
+// Put puts the given key / value to the queue
+func (db *PebbleDatabase) Put(key []byte, value []byte) error {
+	return db.db.Set(key, value, pebble.NoSync)
+}
+
+func (db *PebbleDatabase) Has(key []byte) (bool, error) {
+	_, closer, err := db.db.Get(key)
+	if err == pebble.ErrNotFound {
+		return false, nil
+	} else if err != nil {
+		return false, err
+	}
+	closer.Close()
+	return true, nil
+}
+
+// Get returns the given key if it's present.
+func (db *PebbleDatabase) Get(key []byte) ([]byte, error) {
+	dat, closer, err := db.db.Get(key)
+	if err == pebble.ErrNotFound {
+		return nil, errNotFound
+	} else if err != nil {
+		return nil, err
+	}
+	ret := make([]byte, len(dat))
+	copy(ret, dat)
+	closer.Close()
+	return ret, nil
+}
+
+// Delete deletes the key from the queue and database
+func (db *PebbleDatabase) Delete(key []byte) error {
+	return db.db.Delete(key, pebble.NoSync)
+}
+
+// NewIterator returns an iterator over the entire database content.
+func (db *PebbleDatabase) NewIterator() Iterator {
+	return db.newIterator(nil, nil)
+}
+
+// NewIteratorWithPrefix returns a iterator to iterate over subset of database content with a particular prefix.
+func (db *PebbleDatabase) NewIteratorWithPrefix(prefix []byte) Iterator {
+	return db.newIterator(prefix, upperBound(prefix))
+}
+
+func (db *PebbleDatabase) newIterator(lower, upper []byte) Iterator {
+	iter, err := db.db.NewIter(&pebble.IterOptions{LowerBound: lower, UpperBound: upper})
+	if err != nil {
+		return &pebbleIterator{err: err}
+	}
+	return &pebbleIterator{iter: iter}
+}
+
+// upperBound returns the smallest key greater than all keys with the prefix,
+// or nil if there is none.
+func upperBound(prefix []byte) []byte {
+	limit := bytes.TrimRight(prefix, "\xff")
+	if len(limit) == 0 {
+		return nil
+	}
+	limit = append([]byte{}, limit...)
+	limit[len(limit)-1]++
+	return limit
+}
+
+// Compact flattens the underlying data store for the given key range.
+func (db *PebbleDatabase) Compact(start []byte, limit []byte) error {
+	// Pebble has no key representing the end of the key space, so use one
+	// larger than any database key, including 32 byte 0xff.. hashes.
+	if limit == nil {
+		limit = bytes.Repeat([]byte{0xff}, 64)
+	}
+	return db.db.Compact(start, limit, true)
+}
+
+// Stat returns a summary of the pebble internals. The property is ignored.
+func (db *PebbleDatabase) Stat(property string) (string, error) {
+	return db.db.Metrics().String(), nil
+}
+
+func (db *PebbleDatabase) Close() {
+	// Stop the metrics collection to avoid internal database races
+	db.quitLock.Lock()
+	defer db.quitLock.Unlock()
+
+	if db.quitChan != nil {
+		errc := make(chan error)
+		db.quitChan <- errc
+		if err := <-errc; err != nil {
+			db.log.Error("Metrics collection failed", "err", err)
+		}
+		db.quitChan = nil
+	}
+	err := db.db.Close()
+	if err == nil {
+		db.log.Info("Database closed")
+	} else {
+		db.log.Error("Failed to close database", "err", err)
+	}
+}
+
+// Meter configures the database metrics collectors, with the same names as
+// the LevelDB ones where there is an equivalent.
+func (db *PebbleDatabase) Meter(prefix string) {
+	if metrics.Enabled {
+		db.compTimeMeter = metrics.NewRegisteredMeter(prefix+"compact/time", nil)
+		db.compReadMeter = metrics.NewRegisteredMeter(prefix+"compact/input", nil)
+		db.compWriteMeter = metrics.NewRegisteredMeter(prefix+"compact/output", nil)
+		db.diskWriteMeter = metrics.NewRegisteredMeter(prefix+"disk/write", nil)
+	}
+	db.writeDelayMeter = metrics.NewRegisteredMeter(prefix+"compact/writedelay/duration", nil)
+	db.writeDelayNMeter = metrics.NewRegisteredMeter(prefix+"compact/writedelay/counter", nil)
+
+	// Create a quit channel for the periodic collector and run it
+	db.quitLock.Lock()
+	db.quitChan = make(chan chan error)
+	db.quitLock.Unlock()
+
+	go db.meter(3 * time.Second)
+}
+
+// meter periodically retrieves internal pebble counters and reports them to
+// the metrics subsystem.
+func (db *PebbleDatabase) meter(refresh time.Duration) {
+	var (
+		errc chan error
+
+		compTime, compRead, compWrite, diskWrite [2]int64
+		delayN, delay                            [2]int64
+	)
+	for i := 1; errc == nil; i++ {
+		var (
+			m   = db.db.Metrics()
+			cur = i % 2
+			old = (i - 1) % 2
+		)
+		compTime[cur] = int64(m.Compact.Duration)
+		compRead[cur], compWrite[cur], diskWrite[cur] = 0, 0, int64(m.WAL.BytesWritten)
+		for _, level := range m.Levels {
+			compRead[cur] += int64(level.BytesRead)
+			compWrite[cur] += int64(level.BytesCompacted)
+			diskWrite[cur] += int64(level.BytesCompacted + level.BytesFlushed)
+		}
+		delayN[cur] = atomic.LoadInt64(&db.writeDelayN)
+		delay[cur] = atomic.LoadInt64(&db.writeDelay)
+
+		if db.compTimeMeter != nil {
+			db.compTimeMeter.Mark(compTime[cur] - compTime[old])
+		}
+		if db.compReadMeter != nil {
+			db.compReadMeter.Mark(compRead[cur] - compRead[old])
+		}
+		if db.compWriteMeter != nil {
+			db.compWriteMeter.Mark(compWrite[cur] - compWrite[old])
+		}
+		if db.diskWriteMeter != nil {
+			db.diskWriteMeter.Mark(diskWrite[cur] - diskWrite[old])
+		}
+		db.writeDelayNMeter.Mark(delayN[cur] - delayN[old])
+		db.writeDelayMeter.Mark(delay[cur] - delay[old])
+
+		// Sleep a bit, then repeat the stats collection
+		select {
+		case errc = <-db.quitChan:
+			// Quit requesting, stop hammering the database
+		case <-time.After(refresh):
+			// Timeout, gather a new set of stats
+		}
+	}
+	errc <- nil
+}
+
+func (db *PebbleDatabase) NewBatch() Batch {
+	return &pebbleBatch{db: db.db, b: db.db.NewBatch()}
+}
+
+type pebbleBatch struct {
+	db   *pebble.DB
+	b    *pebble.Batch
+	size int
+}
+
+func (b *pebbleBatch) Put(key, value []byte) error {
+	b.b.Set(key, value, nil)
+	b.size += len(value)
+	return nil
+}
+
+func (b *pebbleBatch) Delete(key []byte) error {
+	b.b.Delete(key, nil)
+	b.size += 1
+	return nil
+}
+
+func (b *pebbleBatch) Write() error {
+	return b.b.Commit(pebble.NoSync)
+}
+
+func (b *pebbleBatch) ValueSize() int {
+	return b.size
+}
+
+func (b *pebbleBatch) Reset() {
+	b.b.Reset()
+	b.size = 0
+}
+
+// pebbleIterator adapts a pebble iterator, which must be positioned before
+// use, to the leveldb style Iterator.
+type pebbleIterator struct {
+	iter  *pebble.Iterator
+	moved bool  // whether the iterator was positioned
+	err   error // error opening the iterator
+}
+
+func (it *pebbleIterator) Next() bool {
+	if it.iter == nil {
+		return false
+	}
+	if !it.moved {
+		it.moved = true
+		return it.iter.First()
+	}
+	return it.iter.Next()
+}
+
+func (it *pebbleIterator) Seek(key []byte) bool {
+	if it.iter == nil {
+		return false
+	}
+	it.moved = true
+	return it.iter.SeekGE(key)
+}
+
+func (it *pebbleIterator) Key() []byte {
+	if it.iter == nil || !it.iter.Valid() {
+		return nil
+	}
+	return it.iter.Key()
+}
+
+func (it *pebbleIterator) Value() []byte {
+	if it.iter == nil || !it.iter.Valid() {
+		return nil
+	}
+	return it.iter.Value()
+}
+
+func (it *pebbleIterator) Error() error {
+	if it.iter == nil {
+		return it.err
+	}
+	return it.iter.Error()
+}
+
+func (it *pebbleIterator) Release() {
+	if it.iter != nil {
+		it.iter.Close()
+		it.iter = nil
+	}
+}
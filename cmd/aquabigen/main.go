@@ -0,0 +1,191 @@
+// Copyright 2018 The aquachain Authors
+// This file is part of aquachain.
+//
+// aquachain is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// aquachain is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with aquachain. If not, see <http://www.gnu.org/licenses/>.
+
+// aquabigen generates Go (or Java) bindings for Aquachain contracts.
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"gitlab.com/aquachain/aquachain/aqua/accounts/abi/bind"
+	"gitlab.com/aquachain/aquachain/cmd/utils"
+	"gitlab.com/aquachain/aquachain/common/compiler"
+)
+
+const usage = `aquabigen generates Go contract bindings importing the aquachain packages.
+
+Bind a single contract from its ABI (and optionally its bytecode):
+    aquabigen -abi token.abi -bin token.bin -pkg token -type Token -out token.go
+
+Bind all the contracts of a solc --combined-json output:
+    aquabigen -combined-json contracts.json -pkg contracts -out contracts.go
+
+Compile and bind Solidity sources (requires solc):
+    aquabigen -sol token.sol -pkg token -out token.go
+
+`
+
+var (
+	abiFlag = flag.String("abi", "", "Path to the contract ABI json to bind, - for STDIN")
+	binFlag = flag.String("bin", "", "Path to the contract bytecode (generate deploy method)")
+	typFlag = flag.String("type", "", "Go struct name for the binding (default = package name)")
+
+	combinedFlag = flag.String("combined-json", "", "Path to the solc --combined-json output to bind, - for STDIN")
+	solFlag      = flag.String("sol", "", "Path to the Solidity source to compile and bind")
+	solcFlag     = flag.String("solc", "solc", "Solidity compiler to use if source builds are requested")
+	excFlag      = flag.String("exc", "", "Comma separated types to exclude from binding")
+
+	pkgFlag  = flag.String("pkg", "", "Package name to generate the binding into")
+	outFlag  = flag.String("out", "", "Output file for the generated binding (default = stdout)")
+	langFlag = flag.String("lang", "go", "Destination language for the bindings (go, java)")
+)
+
+func main() {
+	flag.Usage = func() {
+		fmt.Fprint(os.Stderr, usage)
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	// Make sure exactly one source is given and the package name is set
+	sources := 0
+	for _, source := range []string{*abiFlag, *combinedFlag, *solFlag} {
+		if source != "" {
+			sources++
+		}
+	}
+	if sources != 1 {
+		flag.Usage()
+		utils.Fatalf("exactly one of -abi, -combined-json or -sol is required")
+	}
+	if *pkgFlag == "" {
+		utils.Fatalf("no destination package specified (-pkg)")
+	}
+	var lang bind.Lang
+	switch *langFlag {
+	case "go":
+		lang = bind.LangGo
+	case "java":
+		lang = bind.LangJava
+	default:
+		utils.Fatalf("unsupported destination language %q (-lang)", *langFlag)
+	}
+	// Gather the contracts to bind, sorted by name for a stable output
+	var (
+		abis  []string
+		bins  []string
+		types []string
+	)
+	if *abiFlag != "" {
+		abi, err := readInput(*abiFlag)
+		if err != nil {
+			utils.Fatalf("Failed to read input ABI: %v", err)
+		}
+		abis = append(abis, string(abi))
+
+		var bin []byte
+		if *binFlag != "" {
+			if bin, err = os.ReadFile(*binFlag); err != nil {
+				utils.Fatalf("Failed to read input bytecode: %v", err)
+			}
+		}
+		bins = append(bins, string(bin))
+
+		kind := *typFlag
+		if kind == "" {
+			kind = *pkgFlag
+		}
+		types = append(types, kind)
+	} else {
+		var (
+			contracts map[string]*compiler.Contract
+			err       error
+		)
+		if *combinedFlag != "" {
+			var combined []byte
+			if combined, err = readInput(*combinedFlag); err != nil {
+				utils.Fatalf("Failed to read combined-json: %v", err)
+			}
+			contracts, err = compiler.ParseCombinedJSON(combined, "", "", "", "")
+		} else {
+			contracts, err = compiler.CompileSolidity(*solcFlag, *solFlag)
+		}
+		if err != nil {
+			utils.Fatalf("Failed to build contracts: %v", err)
+		}
+		exclude := make(map[string]bool)
+		for _, kind := range strings.Split(*excFlag, ",") {
+			exclude[strings.ToLower(strings.TrimSpace(kind))] = true
+		}
+		names := make([]string, 0, len(contracts))
+		for name := range contracts {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			// Contract names are prefixed with their source path, "path:Name"
+			nameParts := strings.Split(name, ":")
+			typeName := nameParts[len(nameParts)-1]
+			if exclude[strings.ToLower(typeName)] {
+				continue
+			}
+			contract := contracts[name]
+			abi, err := json.Marshal(contract.Info.AbiDefinition)
+			if err != nil {
+				utils.Fatalf("Failed to parse ABIs from compiler output: %v", err)
+			}
+			abis = append(abis, string(abi))
+			bins = append(bins, contract.Code)
+			types = append(types, typeName)
+		}
+		if len(types) == 0 {
+			utils.Fatalf("No contracts to bind")
+		}
+	}
+	// Generate the contract binding
+	code, err := bind.Bind(types, abis, bins, *pkgFlag, lang)
+	if err != nil {
+		utils.Fatalf("Failed to generate ABI binding: %v", err)
+	}
+	// Either flush it out to a file or display on the standard output
+	if *outFlag == "" {
+		fmt.Printf("%s\n", code)
+		return
+	}
+	if dir := filepath.Dir(*outFlag); dir != "." {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			utils.Fatalf("Failed to create output directory: %v", err)
+		}
+	}
+	if err := os.WriteFile(*outFlag, []byte(code), 0600); err != nil {
+		utils.Fatalf("Failed to write ABI binding: %v", err)
+	}
+}
+
+// readInput reads a file, or the standard input if the path is "-".
+func readInput(path string) ([]byte, error) {
+	if path == "-" {
+		return io.ReadAll(os.Stdin)
+	}
+	return os.ReadFile(path)
+}
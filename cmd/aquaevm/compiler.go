@@ -0,0 +1,114 @@
+// Copyright 2018 The aquachain Authors
+// This file is part of aquachain.
+//
+// aquachain is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// aquachain is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with aquachain. If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"context"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	cli "github.com/urfave/cli/v3"
+	"gitlab.com/aquachain/aquachain/core/asm"
+)
+
+var compileCommand = &cli.Command{
+	Action:    compileCmd,
+	Name:      "compile",
+	Usage:     "compiles easm source to evm binary",
+	ArgsUsage: "<file>",
+}
+
+func compileCmd(ctx context.Context, cmd *cli.Command) error {
+	if cmd.Args().Len() < 1 {
+		return errors.New("filename required")
+	}
+	fn := cmd.Args().First()
+	src, err := os.ReadFile(fn)
+	if err != nil {
+		return err
+	}
+	bin, err := compileAsm(fn, src, cmd.Bool(DebugFlag.Name))
+	if err != nil {
+		return err
+	}
+	fmt.Println(bin)
+	return nil
+}
+
+// compileAsm assembles easm source into EVM code, in hex.
+func compileAsm(fn string, src []byte, debug bool) (string, error) {
+	compiler := asm.NewCompiler(debug)
+	compiler.Feed(asm.Lex(fn, src, debug))
+
+	bin, compileErrors := compiler.Compile()
+	if len(compileErrors) > 0 {
+		msgs := make([]string, len(compileErrors))
+		for i, err := range compileErrors {
+			msgs[i] = err.Error()
+		}
+		return "", fmt.Errorf("compile failed:\n%s", strings.Join(msgs, "\n"))
+	}
+	return bin, nil
+}
+
+var disasmCommand = &cli.Command{
+	Action:    disasmCmd,
+	Name:      "disasm",
+	Usage:     "disassembles evm binary",
+	ArgsUsage: "<file>",
+	Flags:     []cli.Flag{CodeFlag},
+}
+
+func disasmCmd(ctx context.Context, cmd *cli.Command) error {
+	var code string
+	switch {
+	case cmd.Args().Len() > 0:
+		src, err := readFile(cmd.Args().First())
+		if err != nil {
+			return err
+		}
+		code = string(src)
+	case cmd.String(CodeFlag.Name) != "":
+		code = cmd.String(CodeFlag.Name)
+	default:
+		return errors.New("missing filename or --code")
+	}
+	return asm.PrintDisassembled(cleanHex(code))
+}
+
+// cleanHex strips whitespace and the 0x prefix of hex encoded code.
+func cleanHex(code string) string {
+	code = strings.Join(strings.Fields(code), "")
+	return strings.TrimPrefix(strings.TrimPrefix(code, "0x"), "0X")
+}
+
+// decodeHex decodes hex encoded code, ignoring whitespace.
+func decodeHex(code string) ([]byte, error) {
+	return hex.DecodeString(cleanHex(code))
+}
+
+// readFile reads a file, or the standard input if the name is "-".
+func readFile(fn string) ([]byte, error) {
+	if fn == "-" {
+		return io.ReadAll(os.Stdin)
+	}
+	return os.ReadFile(fn)
+}
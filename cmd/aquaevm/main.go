@@ -0,0 +1,122 @@
+// Copyright 2018 The aquachain Authors
+// This file is part of aquachain.
+//
+// aquachain is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// aquachain is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with aquachain. If not, see <http://www.gnu.org/licenses/>.
+
+// aquaevm executes EVM code snippets and state tests, without a node.
+package main
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"os"
+
+	cli "github.com/urfave/cli/v3"
+	"gitlab.com/aquachain/aquachain/subcommands"
+)
+
+var gitCommit = ""
+
+var (
+	CodeFlag = &cli.StringFlag{
+		Name:  "code",
+		Usage: "EVM code, in hex",
+	}
+	CodeFileFlag = &cli.StringFlag{
+		Name:  "codefile",
+		Usage: "File containing EVM code in hex, or assembly if named *.asm (- for STDIN)",
+	}
+	GasFlag = &cli.UintFlag{
+		Name:  "gas",
+		Usage: "gas limit for the execution",
+		Value: 10000000000,
+	}
+	PriceFlag = &cli.StringFlag{
+		Name:  "price",
+		Usage: "price set for the execution, in wei",
+		Value: "0",
+	}
+	ValueFlag = &cli.StringFlag{
+		Name:  "value",
+		Usage: "value set for the execution, in wei",
+		Value: "0",
+	}
+	InputFlag = &cli.StringFlag{
+		Name:  "input",
+		Usage: "input (calldata) for the execution, in hex",
+	}
+	SenderFlag = &cli.StringFlag{
+		Name:  "sender",
+		Usage: "the transaction origin and caller",
+	}
+	ReceiverFlag = &cli.StringFlag{
+		Name:  "receiver",
+		Usage: "the address of the executed code",
+	}
+	PrestateFlag = &cli.StringFlag{
+		Name:  "prestate",
+		Usage: "JSON file with prestate (genesis) config and accounts",
+	}
+	CreateFlag = &cli.BoolFlag{
+		Name:  "create",
+		Usage: "run the code as a contract creation, the input appended to it",
+	}
+	DebugFlag = &cli.BoolFlag{
+		Name:  "debug",
+		Usage: "output a struct-log trace of every executed instruction",
+	}
+	JSONFlag = &cli.BoolFlag{
+		Name:  "json",
+		Usage: "output the trace and the result as JSON",
+	}
+	DisableMemoryFlag = &cli.BoolFlag{
+		Name:  "nomemory",
+		Usage: "disable memory output in the trace",
+	}
+	DisableStackFlag = &cli.BoolFlag{
+		Name:  "nostack",
+		Usage: "disable stack output in the trace",
+	}
+	DumpFlag = &cli.BoolFlag{
+		Name:  "dump",
+		Usage: "dump the state after the run",
+	}
+)
+
+// traceFlags are the flags of the commands executing code.
+var traceFlags = []cli.Flag{DebugFlag, JSONFlag, DisableMemoryFlag, DisableStackFlag, DumpFlag}
+
+func main() {
+	app := subcommands.NewApp("aquaevm", gitCommit, "the aquachain evm command line interface")
+	app.Commands = []*cli.Command{
+		runCommand,
+		stateTestCommand,
+		compileCommand,
+		disasmCommand,
+	}
+	if err := app.Run(context.Background(), os.Args); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
+
+// parseBig parses a decimal or 0x prefixed hex number of a flag.
+func parseBig(cmd *cli.Command, flag string) (*big.Int, error) {
+	value, ok := new(big.Int).SetString(cmd.String(flag), 0)
+	if !ok {
+		return nil, fmt.Errorf("invalid number for --%s: %q", flag, cmd.String(flag))
+	}
+	return value, nil
+}
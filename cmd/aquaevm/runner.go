@@ -0,0 +1,258 @@
+// Copyright 2018 The aquachain Authors
+// This file is part of aquachain.
+//
+// aquachain is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// aquachain is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with aquachain. If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	cli "github.com/urfave/cli/v3"
+	"gitlab.com/aquachain/aquachain/aquadb"
+	"gitlab.com/aquachain/aquachain/common"
+	"gitlab.com/aquachain/aquachain/common/hexutil"
+	"gitlab.com/aquachain/aquachain/core"
+	"gitlab.com/aquachain/aquachain/core/state"
+	"gitlab.com/aquachain/aquachain/core/vm"
+	"gitlab.com/aquachain/aquachain/core/vm/runtime"
+	"gitlab.com/aquachain/aquachain/params"
+)
+
+var runCommand = &cli.Command{
+	Action:      runCmd,
+	Name:        "run",
+	Usage:       "run arbitrary evm binary",
+	ArgsUsage:   "<code>",
+	Description: `The run command runs arbitrary EVM code, given in hex as argument or with --code, or in a file with --codefile.`,
+	Flags: append([]cli.Flag{
+		CodeFlag,
+		CodeFileFlag,
+		GasFlag,
+		PriceFlag,
+		ValueFlag,
+		InputFlag,
+		SenderFlag,
+		ReceiverFlag,
+		PrestateFlag,
+		CreateFlag,
+	}, traceFlags...),
+}
+
+// execResult is the outcome of running code.
+type execResult struct {
+	Output  hexutil.Bytes  `json:"output"`
+	GasUsed hexutil.Uint64 `json:"gasUsed"`
+	Time    time.Duration  `json:"time"`
+	Error   string         `json:"error,omitempty"`
+}
+
+// execute runs code at the receiver address, or as a contract creation, in
+// the state of the config. If no code is given, the code of the receiver in
+// the state is run.
+func execute(code, input []byte, receiver common.Address, create bool, cfg *runtime.Config) *execResult {
+	var (
+		ret      []byte
+		leftOver uint64
+		err      error
+		start    = time.Now()
+	)
+	if create {
+		ret, _, leftOver, err = runtime.Create(append(code, input...), cfg)
+	} else {
+		if len(code) > 0 {
+			cfg.State.SetCode(receiver, code)
+		}
+		ret, leftOver, err = runtime.Call(receiver, input, cfg)
+	}
+	result := &execResult{
+		Output:  ret,
+		GasUsed: hexutil.Uint64(cfg.GasLimit - leftOver),
+		Time:    time.Since(start),
+	}
+	if err != nil {
+		result.Error = err.Error()
+	}
+	return result
+}
+
+func runCmd(ctx context.Context, cmd *cli.Command) error {
+	code, err := readCode(cmd)
+	if err != nil {
+		return err
+	}
+	input, err := decodeHex(cmd.String(InputFlag.Name))
+	if err != nil {
+		return fmt.Errorf("invalid --input: %v", err)
+	}
+	cfg, err := runtimeConfig(cmd)
+	if err != nil {
+		return err
+	}
+	receiver := common.StringToAddress("receiver")
+	if cmd.IsSet(ReceiverFlag.Name) {
+		receiver = common.HexToAddress(cmd.String(ReceiverFlag.Name))
+	}
+	if len(code) == 0 && (cmd.Bool(CreateFlag.Name) || cfg.State.GetCodeSize(receiver) == 0) {
+		return errors.New("no code to run: use --code, --codefile or a --prestate with code at the --receiver")
+	}
+	logger := newLogger(cmd)
+	if logger != nil {
+		cfg.EVMConfig = vm.Config{Debug: true, Tracer: logger}
+	}
+
+	result := execute(code, input, receiver, cmd.Bool(CreateFlag.Name), cfg)
+
+	if logger != nil {
+		writeTrace(os.Stderr, logger.StructLogs(), cmd.Bool(JSONFlag.Name))
+	}
+	if cmd.Bool(DumpFlag.Name) {
+		cfg.State.Commit(true)
+		fmt.Println(string(cfg.State.Dump()))
+	}
+	return writeResult(os.Stdout, result, cmd.Bool(JSONFlag.Name))
+}
+
+// readCode reads the code to run from the argument, --code or --codefile.
+// Files named *.asm are assembled.
+func readCode(cmd *cli.Command) ([]byte, error) {
+	var hexcode string
+	switch {
+	case cmd.Args().Len() > 0:
+		hexcode = cmd.Args().First()
+	case cmd.String(CodeFlag.Name) != "":
+		hexcode = cmd.String(CodeFlag.Name)
+	case cmd.String(CodeFileFlag.Name) != "":
+		fn := cmd.String(CodeFileFlag.Name)
+		src, err := readFile(fn)
+		if err != nil {
+			return nil, fmt.Errorf("could not read code file: %v", err)
+		}
+		if strings.HasSuffix(fn, ".asm") {
+			if hexcode, err = compileAsm(fn, src, false); err != nil {
+				return nil, err
+			}
+		} else {
+			hexcode = string(src)
+		}
+	}
+	code, err := decodeHex(hexcode)
+	if err != nil {
+		return nil, fmt.Errorf("invalid code: %v", err)
+	}
+	return code, nil
+}
+
+// runtimeConfig sets up the execution environment from the flags, on top of
+// the prestate if given.
+func runtimeConfig(cmd *cli.Command) (*runtime.Config, error) {
+	price, err := parseBig(cmd, PriceFlag.Name)
+	if err != nil {
+		return nil, err
+	}
+	value, err := parseBig(cmd, ValueFlag.Name)
+	if err != nil {
+		return nil, err
+	}
+	sender := common.StringToAddress("sender")
+	if cmd.IsSet(SenderFlag.Name) {
+		sender = common.HexToAddress(cmd.String(SenderFlag.Name))
+	}
+	cfg := &runtime.Config{
+		ChainConfig: params.AllAquahashProtocolChanges,
+		Origin:      sender,
+		GasLimit:    cmd.Uint(GasFlag.Name),
+		GasPrice:    price,
+		Value:       value,
+	}
+	db := aquadb.NewMemDatabase()
+	if fn := cmd.String(PrestateFlag.Name); fn != "" {
+		genesis, err := readGenesis(fn)
+		if err != nil {
+			return nil, err
+		}
+		if genesis.Config == nil {
+			genesis.Config = cfg.ChainConfig
+		}
+		block := genesis.ToBlock(db)
+		cfg.ChainConfig = genesis.Config
+		cfg.Coinbase = genesis.Coinbase
+		cfg.BlockNumber = block.Number()
+		cfg.Time = block.Time()
+		cfg.Difficulty = block.Difficulty()
+		if cfg.State, err = state.New(block.Root(), state.NewDatabase(db)); err != nil {
+			return nil, err
+		}
+	} else {
+		cfg.State, _ = state.New(common.Hash{}, state.NewDatabase(db))
+	}
+	return cfg, nil
+}
+
+// readGenesis reads a genesis-style prestate JSON file.
+func readGenesis(fn string) (*core.Genesis, error) {
+	src, err := os.ReadFile(fn)
+	if err != nil {
+		return nil, err
+	}
+	genesis := new(core.Genesis)
+	if err := json.Unmarshal(src, genesis); err != nil {
+		return nil, fmt.Errorf("invalid prestate %s: %v", fn, err)
+	}
+	return genesis, nil
+}
+
+// newLogger returns the struct logger requested by the flags, or nil.
+func newLogger(cmd *cli.Command) *vm.StructLogger {
+	if !cmd.Bool(DebugFlag.Name) {
+		return nil
+	}
+	return vm.NewStructLogger(&vm.LogConfig{
+		DisableMemory: cmd.Bool(DisableMemoryFlag.Name),
+		DisableStack:  cmd.Bool(DisableStackFlag.Name),
+	})
+}
+
+// writeTrace writes a struct-log trace, formatted or as JSON lines.
+func writeTrace(w io.Writer, logs []vm.StructLog, asJSON bool) {
+	if !asJSON {
+		vm.WriteTrace(w, logs)
+		return
+	}
+	enc := json.NewEncoder(w)
+	for i := range logs {
+		enc.Encode(&logs[i])
+	}
+}
+
+// writeResult writes the outcome of a run, formatted or as JSON.
+func writeResult(w io.Writer, result *execResult, asJSON bool) error {
+	if asJSON {
+		return json.NewEncoder(w).Encode(result)
+	}
+	fmt.Fprintf(w, "output:   %s\n", result.Output)
+	fmt.Fprintf(w, "gas used: %d\n", uint64(result.GasUsed))
+	fmt.Fprintf(w, "time:     %v\n", result.Time)
+	if result.Error != "" {
+		fmt.Fprintf(w, "error:    %s\n", result.Error)
+	}
+	return nil
+}
@@ -0,0 +1,90 @@
+// Copyright 2018 The aquachain Authors
+// This file is part of aquachain.
+//
+// aquachain is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// aquachain is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with aquachain. If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"bytes"
+	"math/big"
+	"testing"
+
+	"gitlab.com/aquachain/aquachain/aquadb"
+	"gitlab.com/aquachain/aquachain/common"
+	"gitlab.com/aquachain/aquachain/core/state"
+	"gitlab.com/aquachain/aquachain/core/vm"
+	"gitlab.com/aquachain/aquachain/core/vm/runtime"
+	"gitlab.com/aquachain/aquachain/params"
+)
+
+func newTestConfig() *runtime.Config {
+	statedb, _ := state.New(common.Hash{}, state.NewDatabase(aquadb.NewMemDatabase()))
+	return &runtime.Config{
+		ChainConfig: params.AllAquahashProtocolChanges,
+		GasLimit:    100000,
+		State:       statedb,
+	}
+}
+
+// Tests that assembled code runs, returning its output and gas used, and is
+// traced by the struct logger.
+func TestExecuteAsm(t *testing.T) {
+	bin, err := compileAsm("test.asm", []byte("push 0x2a\npush 0\nmstore\npush 32\npush 0\nreturn\n"), false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bin != "602a60005260206000f3" {
+		t.Fatalf("unexpected code %s", bin)
+	}
+	code, _ := decodeHex("0x" + bin)
+
+	cfg := newTestConfig()
+	logger := vm.NewStructLogger(nil)
+	cfg.EVMConfig = vm.Config{Debug: true, Tracer: logger}
+
+	result := execute(code, nil, common.StringToAddress("receiver"), false, cfg)
+	if result.Error != "" {
+		t.Fatal(result.Error)
+	}
+	if !bytes.Equal(result.Output, common.LeftPadBytes([]byte{0x2a}, 32)) {
+		t.Errorf("unexpected output %x", []byte(result.Output))
+	}
+	if result.GasUsed != 18 {
+		t.Errorf("unexpected gas used %d, want 18", result.GasUsed)
+	}
+	if logs := logger.StructLogs(); len(logs) != 6 || logs[5].Op != vm.RETURN {
+		t.Errorf("unexpected trace of %d steps", len(logs))
+	}
+
+	if _, err := compileAsm("test.asm", []byte("push\n"), false); err == nil {
+		t.Error("expected compile error")
+	}
+}
+
+// Tests that creations run the code as init code.
+func TestExecuteCreate(t *testing.T) {
+	// init code returning the single byte runtime code 0x00 (STOP)
+	code, _ := decodeHex("60006000536001 6000 f3")
+	cfg := newTestConfig()
+	cfg.Value = new(big.Int)
+
+	result := execute(code, nil, common.Address{}, true, cfg)
+	if result.Error != "" {
+		t.Fatal(result.Error)
+	}
+	if !bytes.Equal(result.Output, []byte{0x00}) {
+		t.Errorf("unexpected deployed code %x", []byte(result.Output))
+	}
+}
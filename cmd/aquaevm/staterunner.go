@@ -0,0 +1,128 @@
+// Copyright 2018 The aquachain Authors
+// This file is part of aquachain.
+//
+// aquachain is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// aquachain is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with aquachain. If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+
+	cli "github.com/urfave/cli/v3"
+	"gitlab.com/aquachain/aquachain/core/vm"
+	"gitlab.com/aquachain/aquachain/opt/tests"
+)
+
+var stateTestCommand = &cli.Command{
+	Action:    stateTestCmd,
+	Name:      "statetest",
+	Usage:     "executes the given state tests",
+	ArgsUsage: "<file> [<file> ...]",
+	Flags:     traceFlags,
+}
+
+// StatetestResult contains the execution status after running a state test, any
+// error that might have occurred and a dump of the final state if requested.
+type StatetestResult struct {
+	Name  string          `json:"name"`
+	Fork  string          `json:"fork"`
+	Index int             `json:"index"`
+	Pass  bool            `json:"pass"`
+	Error string          `json:"error,omitempty"`
+	State json.RawMessage `json:"state,omitempty"`
+}
+
+func stateTestCmd(ctx context.Context, cmd *cli.Command) error {
+	if cmd.Args().Len() == 0 {
+		return errors.New("path to state test file(s) required")
+	}
+	var (
+		results []StatetestResult
+		failed  int
+	)
+	for _, fn := range cmd.Args().Slice() {
+		fileResults, err := runStateTests(cmd, fn)
+		if err != nil {
+			return err
+		}
+		for _, result := range fileResults {
+			if !result.Pass {
+				failed++
+			}
+		}
+		results = append(results, fileResults...)
+	}
+	out, _ := json.MarshalIndent(results, "", "  ")
+	fmt.Println(string(out))
+	if failed > 0 {
+		return fmt.Errorf("%d of %d state tests failed", failed, len(results))
+	}
+	return nil
+}
+
+// runStateTests runs all the subtests of the state tests of a file, in a
+// stable order.
+func runStateTests(cmd *cli.Command, fn string) ([]StatetestResult, error) {
+	src, err := os.ReadFile(fn)
+	if err != nil {
+		return nil, err
+	}
+	var stateTests map[string]tests.StateTest
+	if err := json.Unmarshal(src, &stateTests); err != nil {
+		return nil, fmt.Errorf("invalid state test file %s: %v", fn, err)
+	}
+	names := make([]string, 0, len(stateTests))
+	for name := range stateTests {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var results []StatetestResult
+	for _, name := range names {
+		test := stateTests[name]
+		subtests := test.Subtests()
+		sort.Slice(subtests, func(i, j int) bool {
+			if subtests[i].Fork != subtests[j].Fork {
+				return subtests[i].Fork < subtests[j].Fork
+			}
+			return subtests[i].Index < subtests[j].Index
+		})
+		for _, st := range subtests {
+			logger := newLogger(cmd)
+			var cfg vm.Config
+			if logger != nil {
+				cfg = vm.Config{Debug: true, Tracer: logger}
+			}
+			result := StatetestResult{Name: name, Fork: st.Fork, Index: st.Index, Pass: true}
+			statedb, err := test.Run(st, cfg)
+			if err != nil {
+				result.Pass, result.Error = false, err.Error()
+			}
+			if statedb != nil && cmd.Bool(DumpFlag.Name) {
+				result.State = statedb.Dump()
+			}
+			if logger != nil {
+				fmt.Fprintf(os.Stderr, "# %s/%s/%d\n", name, st.Fork, st.Index)
+				writeTrace(os.Stderr, logger.StructLogs(), cmd.Bool(JSONFlag.Name))
+			}
+			results = append(results, result)
+		}
+	}
+	return results, nil
+}
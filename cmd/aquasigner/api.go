@@ -0,0 +1,148 @@
+// Copyright 2018 The aquachain Authors
+// This file is part of aquachain.
+//
+// aquachain is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// aquachain is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with aquachain. If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"errors"
+	"time"
+
+	"gitlab.com/aquachain/aquachain/aqua/accounts"
+	"gitlab.com/aquachain/aquachain/aqua/accounts/external"
+	"gitlab.com/aquachain/aquachain/aqua/accounts/keystore"
+	"gitlab.com/aquachain/aquachain/common"
+	"gitlab.com/aquachain/aquachain/common/hexutil"
+	"gitlab.com/aquachain/aquachain/common/log"
+)
+
+// signerVersion is the version of the signer API served in the "account"
+// namespace.
+const signerVersion = "1.0.0"
+
+var errAuditFailed = errors.New("failed to write audit log")
+
+// SignerAPI serves the external signer protocol, signing with the unlocked
+// keys of a keystore the requests passing the rules, and auditing them all.
+type SignerAPI struct {
+	ks    *keystore.KeyStore
+	rules *Rules
+	audit *auditLog
+	now   func() time.Time // Clock used for rate limiting, replaceable for tests
+}
+
+// newSignerAPI creates the signer API backed by the given keystore.
+func newSignerAPI(ks *keystore.KeyStore, rules *Rules, audit *auditLog) *SignerAPI {
+	return &SignerAPI{ks: ks, rules: rules, audit: audit, now: time.Now}
+}
+
+// Version returns the version of the signer API.
+func (api *SignerAPI) Version() string {
+	return signerVersion
+}
+
+// List returns the addresses of all the accounts in the keystore.
+func (api *SignerAPI) List() []common.Address {
+	accts := api.ks.Accounts()
+	addrs := make([]common.Address, len(accts))
+	for i, acc := range accts {
+		addrs[i] = acc.Address
+	}
+	return addrs
+}
+
+// SignTx signs the requested transaction if allowed by the rules, returning it
+// in its binary encoding.
+func (api *SignerAPI) SignTx(args external.SignTxArgs) (hexutil.Bytes, error) {
+	var (
+		now   = api.now()
+		tx    = args.ToTransaction()
+		nonce = args.Nonce
+		entry = &auditEntry{
+			Time:    now,
+			Method:  "account_signTx",
+			From:    args.From,
+			To:      args.To,
+			Value:   &args.Value,
+			Nonce:   &nonce,
+			ChainID: args.ChainID,
+			Hash:    args.Signer().Hash(tx),
+		}
+	)
+	if err := api.rules.CheckTx(&args, now); err != nil {
+		return nil, api.deny(entry, err)
+	}
+	signed, err := api.ks.SignTx(accounts.Account{Address: args.From}, tx, args.ChainID.ToInt())
+	if err != nil {
+		api.rules.Release(args.From, now)
+		return nil, api.deny(entry, err)
+	}
+	if err := api.approve(entry); err != nil {
+		api.rules.Release(args.From, now)
+		return nil, err
+	}
+	log.Info("Signed transaction", "from", args.From, "to", args.To, "value", args.Value.ToInt(), "nonce", uint64(args.Nonce), "hash", signed.Hash())
+	return signed.MarshalBinary()
+}
+
+// SignHash signs the given hash if allowed by the rules, returning the
+// signature in the [R || S || V] format.
+func (api *SignerAPI) SignHash(addr common.Address, hash hexutil.Bytes) (hexutil.Bytes, error) {
+	now := api.now()
+	entry := &auditEntry{
+		Time:   now,
+		Method: "account_signHash",
+		From:   addr,
+		Hash:   common.BytesToHash(hash),
+	}
+	if len(hash) != common.HashLength {
+		return nil, api.deny(entry, errors.New("hash must be 32 bytes"))
+	}
+	if err := api.rules.CheckHash(addr, now); err != nil {
+		return nil, api.deny(entry, err)
+	}
+	sig, err := api.ks.SignHash(accounts.Account{Address: addr}, hash)
+	if err != nil {
+		api.rules.Release(addr, now)
+		return nil, api.deny(entry, err)
+	}
+	if err := api.approve(entry); err != nil {
+		api.rules.Release(addr, now)
+		return nil, err
+	}
+	log.Info("Signed hash", "from", addr, "hash", entry.Hash)
+	return sig, nil
+}
+
+// deny records the rejection of a request, returning the reason.
+func (api *SignerAPI) deny(entry *auditEntry, reason error) error {
+	entry.Reason = reason.Error()
+	if err := api.audit.write(entry); err != nil {
+		log.Error("Failed to write audit log", "err", err)
+	}
+	log.Warn("Denied signing request", "method", entry.Method, "from", entry.From, "reason", reason)
+	return reason
+}
+
+// approve records the approval of a request. If it can't be recorded, the
+// signature must not be released.
+func (api *SignerAPI) approve(entry *auditEntry) error {
+	entry.Approved = true
+	if err := api.audit.write(entry); err != nil {
+		log.Error("Failed to write audit log", "err", err)
+		return errAuditFailed
+	}
+	return nil
+}
@@ -0,0 +1,73 @@
+// Copyright 2018 The aquachain Authors
+// This file is part of aquachain.
+//
+// aquachain is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// aquachain is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with aquachain. If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"encoding/json"
+	"io"
+	"os"
+	"sync"
+	"time"
+
+	"gitlab.com/aquachain/aquachain/common"
+	"gitlab.com/aquachain/aquachain/common/hexutil"
+)
+
+// auditEntry is a single line of the audit log, recording a signing request
+// along with the decision taken on it.
+type auditEntry struct {
+	Time     time.Time       `json:"time"`
+	Method   string          `json:"method"`
+	From     common.Address  `json:"from"`
+	To       *common.Address `json:"to,omitempty"`
+	Value    *hexutil.Big    `json:"value,omitempty"`
+	Nonce    *hexutil.Uint64 `json:"nonce,omitempty"`
+	ChainID  *hexutil.Big    `json:"chainId,omitempty"`
+	Hash     common.Hash     `json:"hash"`
+	Approved bool            `json:"approved"`
+	Reason   string          `json:"reason,omitempty"`
+}
+
+// auditLog appends every signing request as a JSON line to a writer.
+type auditLog struct {
+	w    io.Writer
+	lock sync.Mutex
+}
+
+// openAuditLog opens the audit log at the given path for appending, creating
+// it readable only by the owner if it does not exist.
+func openAuditLog(path string) (*auditLog, io.Closer, error) {
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0600)
+	if err != nil {
+		return nil, nil, err
+	}
+	return &auditLog{w: f}, f, nil
+}
+
+// write appends the entry to the log. Failing to audit a request is fatal to
+// the request, so the error must be checked before releasing any signature.
+func (l *auditLog) write(entry *auditEntry) error {
+	blob, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	_, err = l.w.Write(append(blob, '\n'))
+	return err
+}
@@ -0,0 +1,224 @@
+// Copyright 2018 The aquachain Authors
+// This file is part of aquachain.
+//
+// aquachain is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// aquachain is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with aquachain. If not, see <http://www.gnu.org/licenses/>.
+
+// aquasigner is a standalone signer daemon keeping the private keys out of the
+// RPC-exposed node process. The node forwards its signing requests to it via
+// the -signer flag, and every request is checked against a rule file and
+// recorded in an audit log before being signed.
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+
+	cli "github.com/urfave/cli/v3"
+	"gitlab.com/aquachain/aquachain/aqua/accounts"
+	"gitlab.com/aquachain/aquachain/aqua/accounts/keystore"
+	"gitlab.com/aquachain/aquachain/common"
+	"gitlab.com/aquachain/aquachain/common/log"
+	"gitlab.com/aquachain/aquachain/opt/console"
+	"gitlab.com/aquachain/aquachain/p2p/netutil"
+	"gitlab.com/aquachain/aquachain/rpc"
+	"gitlab.com/aquachain/aquachain/subcommands"
+)
+
+var gitCommit = ""
+
+var (
+	KeystoreFlag = &cli.StringFlag{
+		Name:     "keystore",
+		Usage:    "Directory of the keystore holding the signing keys",
+		Required: true,
+	}
+	UnlockFlag = &cli.StringFlag{
+		Name:  "unlock",
+		Usage: "Comma separated list of accounts to unlock (default = all accounts)",
+	}
+	PasswordFlag = &cli.StringFlag{
+		Name:  "password",
+		Usage: "Password file to unlock the accounts with, one line per account (prompts if unset)",
+	}
+	RulesFlag = &cli.StringFlag{
+		Name:     "rules",
+		Usage:    "JSON file with the rules signing requests are checked against",
+		Required: true,
+	}
+	AuditFlag = &cli.StringFlag{
+		Name:  "audit",
+		Usage: "File to append the audit log of all signing requests to",
+		Value: "aquasigner-audit.log",
+	}
+	IPCPathFlag = &cli.StringFlag{
+		Name:  "ipcpath",
+		Usage: "IPC socket/pipe to serve signing requests on (empty disables IPC)",
+		Value: "aquasigner.ipc",
+	}
+	HTTPAddrFlag = &cli.StringFlag{
+		Name:  "http.addr",
+		Usage: "HTTP listening interface to serve signing requests on",
+		Value: "127.0.0.1",
+	}
+	HTTPPortFlag = &cli.UintFlag{
+		Name:  "http.port",
+		Usage: "HTTP listening port to serve signing requests on (0 disables HTTP)",
+	}
+	AllowIPFlag = &cli.StringFlag{
+		Name:  "allowip",
+		Usage: "Comma separated list of CIDR masks allowed to send HTTP requests",
+		Value: "127.0.0.1/32",
+	}
+)
+
+func main() {
+	app := subcommands.NewApp("aquasigner", gitCommit, "the aquachain external signer daemon")
+	app.Flags = []cli.Flag{
+		KeystoreFlag,
+		UnlockFlag,
+		PasswordFlag,
+		RulesFlag,
+		AuditFlag,
+		IPCPathFlag,
+		HTTPAddrFlag,
+		HTTPPortFlag,
+		AllowIPFlag,
+	}
+	app.Action = signer
+	if err := app.Run(context.Background(), os.Args); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
+
+// signer unlocks the keys and serves signing requests until interrupted.
+func signer(ctx context.Context, cmd *cli.Command) error {
+	rules, err := loadRules(cmd.String(RulesFlag.Name))
+	if err != nil {
+		return err
+	}
+	audit, closer, err := openAuditLog(cmd.String(AuditFlag.Name))
+	if err != nil {
+		return fmt.Errorf("failed to open audit log: %v", err)
+	}
+	defer closer.Close()
+
+	ks := keystore.NewKeyStore(cmd.String(KeystoreFlag.Name), keystore.StandardScryptN, keystore.StandardScryptP)
+	if err := unlockAccounts(cmd, ks); err != nil {
+		return err
+	}
+	srv := rpc.NewServer()
+	if _, err := srv.RegisterName("account", newSignerAPI(ks, rules, audit)); err != nil {
+		return err
+	}
+	defer srv.Stop()
+
+	var listeners []net.Listener
+	defer func() {
+		for _, l := range listeners {
+			l.Close()
+		}
+	}()
+	if endpoint := cmd.String(IPCPathFlag.Name); endpoint != "" {
+		l, err := rpc.CreateIPCListener(endpoint)
+		if err != nil {
+			return err
+		}
+		listeners = append(listeners, l)
+		go srv.ServeListener(l)
+		log.Info("IPC endpoint opened", "url", endpoint)
+	}
+	if port := cmd.Uint(HTTPPortFlag.Name); port != 0 {
+		allowIP, err := netutil.ParseNetlist(cmd.String(AllowIPFlag.Name))
+		if err != nil {
+			return fmt.Errorf("invalid -%s: %v", AllowIPFlag.Name, err)
+		}
+		endpoint := net.JoinHostPort(cmd.String(HTTPAddrFlag.Name), fmt.Sprint(port))
+		l, err := net.Listen("tcp", endpoint)
+		if err != nil {
+			return err
+		}
+		listeners = append(listeners, l)
+		go rpc.NewHTTPServer(nil, []string{"localhost"}, allowIP, false, srv).Serve(l)
+		log.Info("HTTP endpoint opened", "url", "http://"+endpoint, "allowip", allowIP.String())
+	}
+	if len(listeners) == 0 {
+		return errors.New("no IPC or HTTP endpoint enabled")
+	}
+	sigc := make(chan os.Signal, 1)
+	signal.Notify(sigc, syscall.SIGINT, syscall.SIGTERM)
+	defer signal.Stop(sigc)
+
+	select {
+	case <-sigc:
+	case <-ctx.Done():
+	}
+	log.Info("Shutting down signer")
+	return nil
+}
+
+// unlockAccounts unlocks the requested accounts of the keystore indefinitely,
+// or all of them if none were requested.
+func unlockAccounts(cmd *cli.Command, ks *keystore.KeyStore) error {
+	var accts []accounts.Account
+	if unlock := strings.TrimSpace(cmd.String(UnlockFlag.Name)); unlock != "" {
+		for _, addr := range strings.Split(unlock, ",") {
+			addr = strings.TrimSpace(addr)
+			if !common.IsHexAddress(addr) {
+				return fmt.Errorf("invalid account address %q", addr)
+			}
+			accts = append(accts, accounts.Account{Address: common.HexToAddress(addr)})
+		}
+	} else {
+		accts = ks.Accounts()
+	}
+	if len(accts) == 0 {
+		return errors.New("no accounts to unlock")
+	}
+	var passwords []string
+	if path := cmd.String(PasswordFlag.Name); path != "" {
+		text, err := os.ReadFile(path)
+		if err != nil {
+			return fmt.Errorf("failed to read password file: %v", err)
+		}
+		for _, line := range strings.Split(strings.TrimRight(string(text), "\r\n"), "\n") {
+			passwords = append(passwords, strings.TrimRight(line, "\r"))
+		}
+	}
+	for i, account := range accts {
+		var password string
+		switch {
+		case i < len(passwords):
+			password = passwords[i]
+		case len(passwords) > 0:
+			password = passwords[len(passwords)-1]
+		default:
+			var err error
+			if password, err = console.Stdin.PromptPassword(fmt.Sprintf("Passphrase for %s: ", account.Address.Hex())); err != nil {
+				return err
+			}
+		}
+		if err := ks.Unlock(account, password); err != nil {
+			return fmt.Errorf("failed to unlock %s: %v", account.Address.Hex(), err)
+		}
+		log.Info("Unlocked account", "address", account.Address.Hex())
+	}
+	return nil
+}
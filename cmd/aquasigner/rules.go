@@ -0,0 +1,203 @@
+// Copyright 2018 The aquachain Authors
+// This file is part of aquachain.
+//
+// aquachain is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// aquachain is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with aquachain. If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"sync"
+	"time"
+
+	"gitlab.com/aquachain/aquachain/aqua/accounts/external"
+	"gitlab.com/aquachain/aquachain/common"
+	"gitlab.com/aquachain/aquachain/common/math"
+)
+
+var (
+	errSignHashDenied      = errors.New("hash signing not allowed")
+	errContractCreation    = errors.New("contract creation not allowed")
+	errRecipientNotAllowed = errors.New("recipient not allowed")
+	errValueExceeded       = errors.New("value exceeds limit")
+	errFeeExceeded         = errors.New("fee exceeds limit")
+	errChainIDMismatch     = errors.New("chain id not allowed")
+	errRateLimited         = errors.New("rate limit exceeded")
+	errInvalidRateLimit    = errors.New("invalid rate limit")
+	errInvalidDuration     = errors.New("invalid duration")
+)
+
+// duration is a time.Duration unmarshalled from strings like "1h30m".
+type duration time.Duration
+
+// UnmarshalJSON implements json.Unmarshaler.
+func (d *duration) UnmarshalJSON(input []byte) error {
+	var s string
+	if err := json.Unmarshal(input, &s); err != nil {
+		return errInvalidDuration
+	}
+	v, err := time.ParseDuration(s)
+	if err != nil {
+		return errInvalidDuration
+	}
+	*d = duration(v)
+	return nil
+}
+
+// RateLimit caps the number of signing requests a single account may have
+// approved within a sliding time window.
+type RateLimit struct {
+	Count  int      `json:"count"`
+	Period duration `json:"period"`
+}
+
+// Rules is the policy every signing request is checked against, loaded from
+// a JSON file like:
+//
+//	{
+//	  "chainId": 61717561,
+//	  "allowedRecipients": ["0x..."],
+//	  "maxValue": "1000000000000000000",
+//	  "maxFee": "10000000000000000",
+//	  "allowContractCreation": false,
+//	  "allowSignHash": false,
+//	  "rateLimit": {"count": 10, "period": "1h"}
+//	}
+//
+// The fee is the most a transaction may pay for gas, its gas limit times its gas
+// price. Omitted fields impose no restriction, except for contract creation and
+// hash signing which are denied unless explicitly allowed.
+type Rules struct {
+	ChainID               *uint64               `json:"chainId"`
+	AllowedRecipients     []common.Address      `json:"allowedRecipients"`
+	MaxValue              *math.HexOrDecimal256 `json:"maxValue"`
+	MaxFee                *math.HexOrDecimal256 `json:"maxFee"`
+	AllowContractCreation bool                  `json:"allowContractCreation"`
+	AllowSignHash         bool                  `json:"allowSignHash"`
+	RateLimit             *RateLimit            `json:"rateLimit"`
+
+	recent map[common.Address][]time.Time // Approval times within the rate limit period, per account
+	lock   sync.Mutex
+}
+
+// loadRules reads the signing rules from the given file.
+func loadRules(path string) (*Rules, error) {
+	blob, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	rules := new(Rules)
+	if err := json.Unmarshal(blob, rules); err != nil {
+		return nil, fmt.Errorf("invalid rules file %s: %v", path, err)
+	}
+	if rl := rules.RateLimit; rl != nil && (rl.Count <= 0 || rl.Period <= 0) {
+		return nil, errInvalidRateLimit
+	}
+	return rules, nil
+}
+
+// CheckTx checks whether the transaction request is allowed, recording it
+// against the rate limit of the sender if it is. A request failing to be signed
+// afterwards must be released.
+func (r *Rules) CheckTx(args *external.SignTxArgs, now time.Time) error {
+	if r.ChainID != nil && (args.ChainID == nil || !args.ChainID.ToInt().IsUint64() || args.ChainID.ToInt().Uint64() != *r.ChainID) {
+		return errChainIDMismatch
+	}
+	if args.To == nil {
+		if !r.AllowContractCreation {
+			return errContractCreation
+		}
+	} else if len(r.AllowedRecipients) > 0 {
+		allowed := false
+		for _, addr := range r.AllowedRecipients {
+			if addr == *args.To {
+				allowed = true
+				break
+			}
+		}
+		if !allowed {
+			return errRecipientNotAllowed
+		}
+	}
+	if r.MaxValue != nil && args.Value.ToInt().Cmp((*big.Int)(r.MaxValue)) > 0 {
+		return errValueExceeded
+	}
+	if r.MaxFee != nil {
+		fee := new(big.Int).Mul(new(big.Int).SetUint64(uint64(args.Gas)), args.GasPrice.ToInt())
+		if fee.Cmp((*big.Int)(r.MaxFee)) > 0 {
+			return errFeeExceeded
+		}
+	}
+	return r.checkRate(args.From, now)
+}
+
+// CheckHash checks whether signing an arbitrary hash is allowed, recording it
+// against the rate limit of the account if it is. A request failing to be
+// signed afterwards must be released.
+func (r *Rules) CheckHash(addr common.Address, now time.Time) error {
+	if !r.AllowSignHash {
+		return errSignHashDenied
+	}
+	return r.checkRate(addr, now)
+}
+
+// checkRate records a request of the account, failing if it already reached
+// the allowed number of requests within the rate limit period.
+func (r *Rules) checkRate(addr common.Address, now time.Time) error {
+	if r.RateLimit == nil {
+		return nil
+	}
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	if r.recent == nil {
+		r.recent = make(map[common.Address][]time.Time)
+	}
+	var (
+		cutoff = now.Add(-time.Duration(r.RateLimit.Period))
+		recent = r.recent[addr]
+	)
+	for len(recent) > 0 && !recent[0].After(cutoff) {
+		recent = recent[1:]
+	}
+	if len(recent) >= r.RateLimit.Count {
+		r.recent[addr] = recent
+		return errRateLimited
+	}
+	r.recent[addr] = append(recent, now)
+	return nil
+}
+
+// Release drops a request of the account recorded at the given time from the
+// rate limit, so that requests passing the rules but failing to be signed
+// don't use up the allowance.
+func (r *Rules) Release(addr common.Address, at time.Time) {
+	if r.RateLimit == nil {
+		return
+	}
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	recent := r.recent[addr]
+	for i := len(recent) - 1; i >= 0; i-- {
+		if recent[i].Equal(at) {
+			r.recent[addr] = append(recent[:i:i], recent[i+1:]...)
+			return
+		}
+	}
+}
@@ -0,0 +1,221 @@
+// Copyright 2018 The aquachain Authors
+// This file is part of aquachain.
+//
+// aquachain is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// aquachain is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with aquachain. If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"math/big"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"gitlab.com/aquachain/aquachain/aqua/accounts/external"
+	"gitlab.com/aquachain/aquachain/aqua/accounts/keystore"
+	"gitlab.com/aquachain/aquachain/common"
+	"gitlab.com/aquachain/aquachain/common/hexutil"
+	"gitlab.com/aquachain/aquachain/core/types"
+)
+
+const testRules = `{
+	"chainId": 61717561,
+	"allowedRecipients": ["0x0000000000000000000000000000000000000001"],
+	"maxValue": "1000",
+	"maxFee": "21000000",
+	"rateLimit": {"count": 2, "period": "1m"}
+}`
+
+func writeRules(t *testing.T, rules string) *Rules {
+	path := filepath.Join(t.TempDir(), "rules.json")
+	if err := os.WriteFile(path, []byte(rules), 0600); err != nil {
+		t.Fatal(err)
+	}
+	r, err := loadRules(path)
+	if err != nil {
+		t.Fatalf("failed to load rules: %v", err)
+	}
+	return r
+}
+
+func txArgs(to *common.Address, value int64, chainID int64) *external.SignTxArgs {
+	return &external.SignTxArgs{
+		From:     common.HexToAddress("0xaa"),
+		To:       to,
+		Value:    hexutil.Big(*big.NewInt(value)),
+		ChainID:  (*hexutil.Big)(big.NewInt(chainID)),
+		Gas:      21000,
+		GasPrice: hexutil.Big(*big.NewInt(1)),
+	}
+}
+
+func withGasPrice(args *external.SignTxArgs, price int64) *external.SignTxArgs {
+	args.GasPrice = hexutil.Big(*big.NewInt(price))
+	return args
+}
+
+func TestRules(t *testing.T) {
+	var (
+		allowed = common.HexToAddress("0x01")
+		other   = common.HexToAddress("0x02")
+		now     = time.Unix(1000000, 0)
+	)
+	tests := []struct {
+		args *external.SignTxArgs
+		err  error
+	}{
+		{txArgs(&allowed, 1000, 61717561), nil},
+		{txArgs(&allowed, 1001, 61717561), errValueExceeded},
+		{txArgs(&other, 1, 61717561), errRecipientNotAllowed},
+		{txArgs(nil, 0, 61717561), errContractCreation},
+		{txArgs(&allowed, 1, 1), errChainIDMismatch},
+		{withGasPrice(txArgs(&allowed, 1, 61717561), 1000), nil},
+		{withGasPrice(txArgs(&allowed, 1, 61717561), 1001), errFeeExceeded},
+	}
+	for i, tt := range tests {
+		rules := writeRules(t, testRules)
+		if err := rules.CheckTx(tt.args, now); err != tt.err {
+			t.Errorf("test %d: error mismatch: have %v, want %v", i, err, tt.err)
+		}
+	}
+	rules := writeRules(t, testRules)
+	if err := rules.CheckHash(allowed, now); err != errSignHashDenied {
+		t.Errorf("hash signing error mismatch: have %v, want %v", err, errSignHashDenied)
+	}
+	// Omitted restrictions should allow anything but creations and hash signing
+	rules = writeRules(t, `{"allowContractCreation": true, "allowSignHash": true}`)
+	if err := rules.CheckTx(txArgs(nil, 1e18, 1), now); err != nil {
+		t.Errorf("unrestricted creation denied: %v", err)
+	}
+	if err := rules.CheckHash(allowed, now); err != nil {
+		t.Errorf("allowed hash signing denied: %v", err)
+	}
+}
+
+func TestRulesRateLimit(t *testing.T) {
+	var (
+		rules = writeRules(t, testRules)
+		to    = common.HexToAddress("0x01")
+		now   = time.Unix(1000000, 0)
+	)
+	for i := 0; i < 2; i++ {
+		if err := rules.CheckTx(txArgs(&to, 1, 61717561), now.Add(time.Duration(i)*time.Second)); err != nil {
+			t.Fatalf("request %d denied: %v", i, err)
+		}
+	}
+	if err := rules.CheckTx(txArgs(&to, 1, 61717561), now.Add(30*time.Second)); err != errRateLimited {
+		t.Fatalf("rate limit error mismatch: have %v, want %v", err, errRateLimited)
+	}
+	// Other accounts have their own limits
+	args := txArgs(&to, 1, 61717561)
+	args.From = common.HexToAddress("0xbb")
+	if err := rules.CheckTx(args, now.Add(30*time.Second)); err != nil {
+		t.Fatalf("other account rate limited: %v", err)
+	}
+	// Released requests don't count against the limit
+	rules.Release(common.HexToAddress("0xaa"), now)
+	if err := rules.CheckTx(txArgs(&to, 1, 61717561), now.Add(30*time.Second)); err != nil {
+		t.Fatalf("request after release denied: %v", err)
+	}
+	// Once the oldest request left leaves the window, a new one is allowed
+	if err := rules.CheckTx(txArgs(&to, 1, 61717561), now.Add(time.Minute+3*time.Second/2)); err != nil {
+		t.Fatalf("request after window denied: %v", err)
+	}
+}
+
+func TestRulesInvalid(t *testing.T) {
+	for i, rules := range []string{
+		`{"rateLimit": {"count": 0, "period": "1m"}}`,
+		`{"rateLimit": {"count": 1, "period": "soon"}}`,
+		`{"maxValue": "lots"}`,
+	} {
+		path := filepath.Join(t.TempDir(), "rules.json")
+		if err := os.WriteFile(path, []byte(rules), 0600); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := loadRules(path); err == nil {
+			t.Errorf("test %d: invalid rules accepted", i)
+		}
+	}
+}
+
+func TestSignerAPI(t *testing.T) {
+	ks := keystore.NewKeyStore(t.TempDir(), keystore.LightScryptN, keystore.LightScryptP)
+	account, err := ks.NewAccount("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := ks.Unlock(account, ""); err != nil {
+		t.Fatal(err)
+	}
+	var (
+		logs bytes.Buffer
+		api  = newSignerAPI(ks, writeRules(t, testRules), &auditLog{w: &logs})
+		to   = common.HexToAddress("0x01")
+	)
+	if list := api.List(); len(list) != 1 || list[0] != account.Address {
+		t.Fatalf("account list mismatch: have %v, want %x", list, account.Address)
+	}
+	args := txArgs(&to, 10, 61717561)
+	args.From = account.Address
+
+	// Signing failures must not use up the rate limit
+	if err := ks.Lock(account.Address); err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 3; i++ {
+		if _, err := api.SignTx(*args); err == nil {
+			t.Fatalf("signed with a locked account")
+		}
+	}
+	if err := ks.Unlock(account, ""); err != nil {
+		t.Fatal(err)
+	}
+	raw, err := api.SignTx(*args)
+	if err != nil {
+		t.Fatalf("failed to sign: %v", err)
+	}
+	tx := new(types.Transaction)
+	if err := tx.UnmarshalBinary(raw); err != nil {
+		t.Fatalf("invalid signed transaction: %v", err)
+	}
+	if from, err := types.Sender(args.Signer(), tx); err != nil || from != account.Address {
+		t.Fatalf("sender mismatch: have %x (%v), want %x", from, err, account.Address)
+	}
+	args.Value = hexutil.Big(*big.NewInt(1001))
+	if _, err := api.SignTx(*args); err != errValueExceeded {
+		t.Fatalf("error mismatch: have %v, want %v", err, errValueExceeded)
+	}
+	if _, err := api.SignHash(account.Address, make([]byte, 32)); err != errSignHashDenied {
+		t.Fatalf("error mismatch: have %v, want %v", err, errSignHashDenied)
+	}
+	// Every request must have been audited along with its outcome
+	lines := strings.Split(strings.TrimSpace(logs.String()), "\n")
+	if len(lines) != 6 {
+		t.Fatalf("audit log length mismatch: have %d, want 6", len(lines))
+	}
+	for i, want := range []bool{false, false, false, true, false, false} {
+		var entry auditEntry
+		if err := json.Unmarshal([]byte(lines[i]), &entry); err != nil {
+			t.Fatalf("entry %d: invalid audit entry: %v", i, err)
+		}
+		if entry.Approved != want || entry.From != account.Address {
+			t.Errorf("entry %d: mismatch: approved %v, from %x", i, entry.Approved, entry.From)
+		}
+	}
+}
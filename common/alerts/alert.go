@@ -1,63 +1,126 @@
 package alerts
 
 import (
-	"bytes"
 	"context"
-	"encoding/json"
 	"fmt"
-	"io"
-	"time"
-
-	"net/http"
 	"os"
 	"strings"
+	"sync"
+	"time"
 
 	"gitlab.com/aquachain/aquachain/common/log"
 	"gitlab.com/aquachain/aquachain/common/sense"
 )
 
 type AlertConfig struct {
-	Platform string // tg, email, sms
+	Platform string // tg (legacy single destination, see Destinations)
 	Token    string
 	Channel  string // tg is number
+
+	// Destinations are alert destination URLs (see ParseDestination), alerts
+	// go to all of them.
+	Destinations []string `toml:",omitempty"`
+
+	// Limits of repeated alerts, zero values use DefaultLimits
+	DedupeWindow time.Duration `toml:",omitempty"`
+	TopicLimit   int           `toml:",omitempty"`
+	TopicWindow  time.Duration `toml:",omitempty"`
 }
 
 func (a AlertConfig) Enabled() bool {
-	return a.Platform != "" && a.Token != "" && a.Channel != ""
+	return len(a.Destinations) > 0 || a.Platform != "" && a.Token != "" && a.Channel != ""
 }
 
-var mainCfg AlertConfig
+// ParseDestinations returns the destinations of the config: the legacy
+// platform one, if any, followed by the destination URLs.
+func (a AlertConfig) ParseDestinations() ([]Destination, error) {
+	var destinations []Destination
+	if a.Platform != "" && a.Token != "" && a.Channel != "" {
+		destinations = append(destinations, Destination{Backend: newTelegramBackend("https", telegramHost, a.Token, a.Channel)})
+	}
+	for _, rawurl := range a.Destinations {
+		dest, err := ParseDestination(rawurl)
+		if err != nil {
+			return nil, err
+		}
+		destinations = append(destinations, dest)
+	}
+	return destinations, nil
+}
+
+// Limits returns the limits of repeated alerts of the config.
+func (a AlertConfig) Limits() Limits {
+	limits := DefaultLimits
+	if a.DedupeWindow != 0 {
+		limits.DedupeWindow = a.DedupeWindow
+	}
+	if a.TopicLimit != 0 {
+		limits.TopicLimit = a.TopicLimit
+	}
+	if a.TopicWindow != 0 {
+		limits.TopicWindow = a.TopicWindow
+	}
+	return limits
+}
+
+var (
+	mainDispatcher *Dispatcher
+	mainMu         sync.RWMutex
+)
 
 func Enabled() bool {
-	return mainCfg.Enabled()
+	mainMu.RLock()
+	defer mainMu.RUnlock()
+	return mainDispatcher != nil
 }
 
-// Infof logs a warning message and sends an alert via ALERT_PLATFORM if configured (see ParseAlertConfig)
+// Infof logs a message and sends an info alert if configured (see ParseAlertConfig)
 func Infof(f string, i ...any) {
-	if sense.Getenv("ALERTS_INFO") == "0" {
-		return
-	}
-	if !mainCfg.Enabled() {
-		log.Info("new alert system not configured", "msg", strings.TrimSpace(strings.Replace(fmt.Sprintf(f, i...), "\n", " ", -1)))
-		return
-	}
-	msg := fmt.Sprintf(f, i...)
-	log.Info("Alert Info", "message", msg)
-	mainCfg.Send(msg)
+	Alertf(SeverityInfo, "", f, i...)
 }
 
-// Warnf logs a warning message and sends an alert via ALERT_PLATFORM if configured (see ParseAlertConfig)
+// Warnf logs a warning message and sends a warning alert if configured (see ParseAlertConfig)
 func Warnf(f string, i ...any) {
-	if sense.Getenv("ALERTS_WARN") == "0" {
+	Alertf(SeverityWarning, "", f, i...)
+}
+
+// Critf logs an error message and sends a critical alert if configured (see ParseAlertConfig)
+func Critf(f string, i ...any) {
+	Alertf(SeverityCritical, "", f, i...)
+}
+
+// Alertf logs a message and sends an alert of the given severity if configured
+// (see ParseAlertConfig). Alerts of the same topic, such as "reorg", are rate
+// limited together. Info and warning alerts are disabled with $ALERTS_INFO=0 and
+// $ALERTS_WARN=0.
+func Alertf(severity Severity, topic string, f string, i ...any) {
+	switch {
+	case severity == SeverityInfo && sense.Getenv("ALERTS_INFO") == "0":
 		return
-	}
-	if !mainCfg.Enabled() {
-		log.Warn("new alert system not configured", "msg", strings.TrimSpace(strings.Replace(fmt.Sprintf(f, i...), "\n", " ", -1)))
+	case severity == SeverityWarning && sense.Getenv("ALERTS_WARN") == "0":
 		return
 	}
 	msg := fmt.Sprintf(f, i...)
-	log.Warn("Alert Warning", "message", msg)
-	mainCfg.Send(msg)
+	mainMu.RLock()
+	dispatcher := mainDispatcher
+	mainMu.RUnlock()
+	if dispatcher == nil {
+		logAlert(severity, "new alert system not configured", "msg", strings.TrimSpace(strings.Replace(msg, "\n", " ", -1)))
+		return
+	}
+	logAlert(severity, "Alert "+severity.String(), "topic", topic, "message", msg)
+	dispatcher.Dispatch(Alert{Severity: severity, Topic: topic, Message: msg, Time: time.Now()})
+}
+
+func logAlert(severity Severity, msg string, ctx ...interface{}) {
+	switch severity {
+	case SeverityInfo:
+		log.Info(msg, ctx...)
+	case SeverityWarning:
+		log.Warn(msg, ctx...)
+	default:
+		log.Error(msg, ctx...)
+	}
 }
 
 type MissingConfigError struct {
@@ -84,22 +147,33 @@ func (m *MissingConfigError) Is(err error) bool {
 	return got.MissingEnv == m.MissingEnv
 }
 
+// ParseAlertConfig reads the alert configuration from the environment and
+// starts sending alerts with it. Destinations are given as a comma separated
+// list of URLs in $ALERT_URLS (see ParseDestination), or for telegram with
+// $ALERT_PLATFORM=tg, $ALERT_TOKEN and $ALERT_CHANNEL.
 func ParseAlertConfig() (AlertConfig, error) {
-	if mainCfg.Enabled() {
+	if Enabled() {
 		panic("already configured")
 	}
 	var ac AlertConfig
 	ac.Platform = sense.Getenv("ALERT_PLATFORM")
 	ac.Token = sense.Getenv("ALERT_TOKEN")
 	ac.Channel = sense.Getenv("ALERT_CHANNEL")
-	if ac.Platform == "" {
-		return ac, fmt.Errorf("missing ALERT_PLATFORM")
+	for _, rawurl := range strings.Split(sense.Getenv("ALERT_URLS"), ",") {
+		if rawurl = strings.TrimSpace(rawurl); rawurl != "" {
+			ac.Destinations = append(ac.Destinations, rawurl)
+		}
 	}
-	if ac.Token == "" {
-		return ac, fmt.Errorf("missing ALERT_TOKEN")
+	if ac.Platform == "" && len(ac.Destinations) == 0 {
+		return ac, NewMissingConfigError("ALERT_URLS")
 	}
-	if ac.Channel == "" {
-		return ac, fmt.Errorf("missing ALERT_CHANNEL")
+	if ac.Platform != "" {
+		if ac.Token == "" {
+			return ac, NewMissingConfigError("ALERT_TOKEN")
+		}
+		if ac.Channel == "" {
+			return ac, NewMissingConfigError("ALERT_CHANNEL")
+		}
 	}
 	switch ac.Platform {
 	case "tg", "telegram": // alias
@@ -113,18 +187,33 @@ func ParseAlertConfig() (AlertConfig, error) {
 	case "none", "": // alias
 		ac.Platform = ""
 	default:
-		return ac, fmt.Errorf("unsupported ALERT_PLATFORM: %q", ac.Platform)
-	}
-	if ac.Token == "" {
-		return ac, fmt.Errorf("missing ALERT_TOKEN")
+		return ac, fmt.Errorf("unsupported ALERT_PLATFORM: %q (use ALERT_URLS for other destinations)", ac.Platform)
 	}
 	if !ac.Enabled() {
 		log.Warn("alerts not configured")
 		return ac, fmt.Errorf("alerts not configured")
 	}
-	mainCfg = ac
+	if err := SetAlertConfig(ac); err != nil {
+		return ac, err
+	}
 	return ac, nil
 }
+
+// SetAlertConfig starts sending alerts to the destinations of the config.
+func SetAlertConfig(ac AlertConfig) error {
+	destinations, err := ac.ParseDestinations()
+	if err != nil {
+		return err
+	}
+	for _, dest := range destinations {
+		log.Info("alert destination", "backend", dest.Backend, "severity", dest.MinSeverity)
+	}
+	mainMu.Lock()
+	mainDispatcher = NewDispatcher(destinations, ac.Limits())
+	mainMu.Unlock()
+	return nil
+}
+
 func MustParseAlertConfig() AlertConfig {
 	ac, err := ParseAlertConfig()
 	if err != nil {
@@ -136,103 +225,43 @@ func MustParseAlertConfig() AlertConfig {
 
 var ErrAlertTimeout = fmt.Errorf("alert timeout")
 
+// Send sends a warning to all destinations of the config, bypassing the limits
+// of repeated alerts. Without done functions, it waits for the alert to be
+// sent, otherwise it calls them once done.
 func (ac AlertConfig) Send(msg string, donefns ...func()) error {
-	wait := len(donefns) == 0
-	println("test", msg, wait, ac.Platform, ac.Token, ac.Channel)
-	switch ac.Platform {
-	case "none", "", "test":
-		println("alert notification", msg)
-		return nil
-	case "tg":
-		println("sending tg")
-		short, cancel := context.WithTimeoutCause(context.Background(), 5*time.Second, ErrAlertTimeout)
-		if wait {
-			defer cancel()
-			return _sendTelegram(short, ac.Token, ac.Channel, msg)
-		}
-		donefns = append(donefns, cancel)
-		go sendTelegram(short, ac.Token, ac.Channel, msg, donefns...)
-		return nil
-	default:
-		panic("unsupported alert platform: " + ac.Platform)
-	}
-}
-
-// Generated by curl-to-Go: https://mholt.github.io/curl-to-go
-
-// curl -X POST \
-//      -H 'Content-Type: application/json' \
-//      -d '{"chat_id": "123456789", "text": "This is a test from curl", "disable_notification": true}' \
-//      https://api.telegram.org/bot$TELEGRAM_BOT_TOKEN/sendMessage
-
-type Payload struct {
-	ChatID              string `json:"chat_id"`
-	Text                string `json:"text"`
-	DisableNotification bool   `json:"disable_notification"`
-	MarkdownMode        string `json:"parse_mode,omitempty"` // one of "Markdown" or "HTML" or "MarkdownV2"
-}
-
-func (p Payload) Bytes() []byte {
-	b, err := json.Marshal(p)
+	destinations, err := ac.ParseDestinations()
 	if err != nil {
-		panic(err.Error())
+		return err
 	}
-	return b
-}
-func (p Payload) Reader() io.Reader {
-	return bytes.NewReader(p.Bytes())
-}
-
-func sendTelegram(ctx context.Context, token string, channels string, msg string, donefns ...func()) {
-	if err := _sendTelegram(ctx, token, channels, msg); err != nil {
-		log.Error("failed to send telegram alert", "err", err.Error())
-	}
-	for _, fn := range donefns {
-		fn()
+	if len(destinations) == 0 {
+		log.Info("alert notification", "msg", msg)
+		return nil
 	}
-}
-
-var TelegramMarkdownMode = "" // default is telegram default
-
-func _sendTelegram(ctx context.Context, token string, channelsinput string, msg string) error {
-	channels := strings.Split(channelsinput, ",")
-	for _, channel := range channels {
-		channel = strings.TrimSpace(channel)
-		data := Payload{
-			ChatID:              channel,
-			Text:                msg,
-			DisableNotification: false,
-			MarkdownMode:        TelegramMarkdownMode,
-		}
-		endpoint := fmt.Sprintf("https://api.telegram.org/bot%s/sendMessage", token)
-		req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, data.Reader())
-		if err != nil {
-			if ctx.Err() != nil {
-				return context.Cause(ctx) // is timeout etc
-			}
-			return err
-		}
-		req.Header.Set("Content-Type", "application/json")
-		resp, err := http.DefaultClient.Do(req)
-		if err != nil { // maybe bad markdown, send a simple message as a fallback
-			if ctx.Err() != nil {
-				return context.Cause(ctx) // is timeout etc
+	alert := Alert{Severity: SeverityWarning, Message: msg, Time: time.Now()}
+	send := func() error {
+		short, cancel := context.WithTimeoutCause(context.Background(), sendTimeout, ErrAlertTimeout)
+		defer cancel()
+		var errs []string
+		for _, dest := range destinations {
+			if err := dest.Backend.Send(short, alert); err != nil {
+				errs = append(errs, fmt.Sprintf("%v: %v", dest.Backend, err))
 			}
-			log.Info("failed to send chain alert", "msg", data.Text, "error", err.Error())
-			return err
 		}
-		body, err := io.ReadAll(resp.Body)
-		resp.Body.Close()
-		if resp.StatusCode != http.StatusOK {
-			return fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+		if len(errs) > 0 {
+			return fmt.Errorf("failed to send alert: %s", strings.Join(errs, "; "))
 		}
-		if err != nil {
-			return fmt.Errorf("failed to read response body: %w", err)
+		return nil
+	}
+	if len(donefns) == 0 {
+		return send()
+	}
+	go func() {
+		if err := send(); err != nil {
+			log.Error("failed to send alert", "err", err)
 		}
-		if len(body) > 0 {
-			log.Info("telegram alert response", "msg", string(body))
+		for _, fn := range donefns {
+			fn()
 		}
-
-	}
+	}()
 	return nil
 }
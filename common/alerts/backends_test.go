@@ -0,0 +1,222 @@
+package alerts
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+var testAlert = Alert{Severity: SeverityWarning, Topic: "reorg", Message: "reorg of depth 7", Time: time.Unix(1500000000, 0).UTC()}
+
+type httpRequest struct {
+	method, path, auth string
+	body               map[string]interface{}
+}
+
+// newHTTPStandIn starts a server recording the JSON requests it gets.
+func newHTTPStandIn(t *testing.T) (*httptest.Server, <-chan httpRequest) {
+	requests := make(chan httpRequest, 10)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		req := httpRequest{method: r.Method, path: r.URL.EscapedPath(), auth: r.Header.Get("Authorization")}
+		if err := json.NewDecoder(r.Body).Decode(&req.body); err != nil {
+			t.Errorf("invalid request body: %v", err)
+		}
+		requests <- req
+		io.WriteString(w, "{}")
+	}))
+	t.Cleanup(server.Close)
+	return server, requests
+}
+
+func sendTestAlert(t *testing.T, rawurl string) {
+	dest, err := ParseDestination(rawurl)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(dest.Backend.String(), "secret") {
+		t.Errorf("backend description %q leaks credentials", dest.Backend)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := dest.Backend.Send(ctx, testAlert); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestWebhookBackend(t *testing.T) {
+	server, requests := newHTTPStandIn(t)
+	sendTestAlert(t, "webhook+"+server.URL+"/hooks/secret?severity=warning")
+
+	req := <-requests
+	if req.method != http.MethodPost || req.path != "/hooks/secret" {
+		t.Errorf("unexpected request %s %s", req.method, req.path)
+	}
+	if req.body["severity"] != "warning" || req.body["topic"] != "reorg" || req.body["message"] != testAlert.Message || req.body["time"] != "2017-07-14T02:40:00Z" {
+		t.Errorf("unexpected payload %v", req.body)
+	}
+}
+
+func TestWebhookBackendError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "nope", http.StatusBadGateway)
+	}))
+	defer server.Close()
+	dest, err := ParseDestination("webhook+" + server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := dest.Backend.Send(context.Background(), testAlert); err == nil || !strings.Contains(err.Error(), "502") {
+		t.Errorf("expected status error, got %v", err)
+	}
+}
+
+func TestMatrixBackend(t *testing.T) {
+	server, requests := newHTTPStandIn(t)
+	host := strings.TrimPrefix(server.URL, "http://")
+	sendTestAlert(t, "matrix+http://secrettoken@"+host+"/!room:example.org")
+
+	req := <-requests
+	prefix := "/_matrix/client/v3/rooms/%21room:example.org/send/m.room.message/"
+	if req.method != http.MethodPut || !strings.HasPrefix(req.path, prefix) || len(req.path) == len(prefix) {
+		t.Errorf("unexpected request %s %s", req.method, req.path)
+	}
+	if req.auth != "Bearer secrettoken" {
+		t.Errorf("unexpected authorization %q", req.auth)
+	}
+	if req.body["msgtype"] != "m.text" || req.body["body"] != "[WARNING] reorg of depth 7" {
+		t.Errorf("unexpected payload %v", req.body)
+	}
+}
+
+func TestTelegramBackend(t *testing.T) {
+	server, requests := newHTTPStandIn(t)
+	host := strings.TrimPrefix(server.URL, "http://")
+	sendTestAlert(t, "tg+http://123456:secret@"+host+"/-1001,-1002")
+
+	for _, chat := range []string{"-1001", "-1002"} {
+		req := <-requests
+		if req.path != "/bot123456:secret/sendMessage" {
+			t.Errorf("unexpected path %s", req.path)
+		}
+		if req.body["chat_id"] != chat || req.body["text"] != "[WARNING] reorg of depth 7" {
+			t.Errorf("unexpected payload %v", req.body)
+		}
+	}
+}
+
+// smtpStandIn is a minimal SMTP server accepting a single mail.
+func smtpStandIn(t *testing.T) (string, <-chan string) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { listener.Close() })
+	mails := make(chan string, 1)
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		r := bufio.NewReader(conn)
+		reply := func(s string) { io.WriteString(conn, s+"\r\n") }
+		reply("220 localhost ESMTP")
+		var transcript strings.Builder
+		for {
+			line, err := r.ReadString('\n')
+			if err != nil {
+				return
+			}
+			transcript.WriteString(line)
+			cmd := strings.ToUpper(strings.TrimSpace(line))
+			switch {
+			case strings.HasPrefix(cmd, "EHLO"):
+				reply("250-localhost")
+				reply("250 AUTH PLAIN")
+			case strings.HasPrefix(cmd, "AUTH PLAIN"):
+				reply("235 ok")
+			case strings.HasPrefix(cmd, "DATA"):
+				reply("354 go ahead")
+				for {
+					line, err := r.ReadString('\n')
+					if err != nil {
+						return
+					}
+					if line == ".\r\n" {
+						break
+					}
+					transcript.WriteString(line)
+				}
+				reply("250 queued")
+			case strings.HasPrefix(cmd, "QUIT"):
+				reply("221 bye")
+				mails <- transcript.String()
+				return
+			default:
+				reply("250 ok")
+			}
+		}
+	}()
+	return listener.Addr().String(), mails
+}
+
+func TestSMTPBackend(t *testing.T) {
+	addr, mails := smtpStandIn(t)
+	sendTestAlert(t, "smtp://node:secret@"+addr+"/?from=node@example.org&to=ops@example.org,oncall@example.org")
+
+	mail := <-mails
+	for _, want := range []string{
+		"AUTH PLAIN",
+		"MAIL FROM:<node@example.org>",
+		"RCPT TO:<ops@example.org>",
+		"RCPT TO:<oncall@example.org>",
+		"Subject: [aquachain warning] reorg of depth 7",
+	} {
+		if !strings.Contains(mail, want) {
+			t.Errorf("mail transcript lacks %q:\n%s", want, mail)
+		}
+	}
+}
+
+func TestParseDestination(t *testing.T) {
+	for _, test := range []struct {
+		url      string
+		backend  string
+		severity Severity
+		err      bool
+	}{
+		{url: "tg://123:abc@/-100", backend: "telegram(api.telegram.org, 1 chats)"},
+		{url: "webhook://example.org/hook?severity=critical", backend: "webhook(https://example.org)", severity: SeverityCritical},
+		{url: "webhook+http://user:pw@localhost:8080/x", backend: "webhook(http://localhost:8080)"},
+		{url: "smtps://mail.example.org/?from=a@b&to=c@d", backend: "smtp(mail.example.org:465, 1 recipients)"},
+		{url: "matrix://tok@matrix.org/!abc:matrix.org?severity=warn", backend: "matrix(https://matrix.org, !abc:matrix.org)", severity: SeverityWarning},
+		{url: "log://", backend: "log"},
+		{url: "smtp://mail.example.org/?to=c@d", err: true},
+		{url: "matrix://matrix.org/!abc:matrix.org", err: true},
+		{url: "webhook://example.org/?severity=loud", err: true},
+		{url: "webhook+ftp://example.org/", err: true},
+		{url: "sms://12345", err: true},
+	} {
+		dest, err := ParseDestination(test.url)
+		if test.err {
+			if err == nil {
+				t.Errorf("%s: expected error", test.url)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: %v", test.url, err)
+			continue
+		}
+		if dest.Backend.String() != test.backend || dest.MinSeverity != test.severity {
+			t.Errorf("%s: got %v (min %v), want %s (min %v)", test.url, dest.Backend, dest.MinSeverity, test.backend, test.severity)
+		}
+	}
+}
@@ -0,0 +1,133 @@
+package alerts
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"gitlab.com/aquachain/aquachain/common/log"
+)
+
+// sendTimeout is how long a backend may take to deliver an alert.
+const sendTimeout = 10 * time.Second
+
+// Limits bound how many alerts go out when the same thing keeps happening, such
+// as a chain reorganising back and forth.
+type Limits struct {
+	DedupeWindow time.Duration // identical alerts within the window are dropped
+	TopicLimit   int           // at most this many alerts of a topic are sent per TopicWindow
+	TopicWindow  time.Duration
+}
+
+// DefaultLimits are the limits used for zero values in the alert configuration.
+var DefaultLimits = Limits{
+	DedupeWindow: 10 * time.Minute,
+	TopicLimit:   3,
+	TopicWindow:  10 * time.Minute,
+}
+
+// Dispatcher sends alerts to the destinations whose minimum severity they
+// meet, dropping the duplicate and excess ones. The number of dropped alerts
+// is noted on the next alert of the same topic which gets through.
+type Dispatcher struct {
+	destinations []Destination
+	limits       Limits
+	now          func() time.Time // overridden in tests
+
+	mu         sync.Mutex
+	lastSent   map[string]time.Time   // dedupe key -> last sent
+	topicSent  map[string][]time.Time // topic -> send times within the topic window
+	suppressed map[string]int         // topic -> dropped alerts since the last sent one
+
+	wg sync.WaitGroup
+}
+
+// NewDispatcher creates a dispatcher sending to the given destinations.
+func NewDispatcher(destinations []Destination, limits Limits) *Dispatcher {
+	return &Dispatcher{
+		destinations: destinations,
+		limits:       limits,
+		now:          time.Now,
+		lastSent:     make(map[string]time.Time),
+		topicSent:    make(map[string][]time.Time),
+		suppressed:   make(map[string]int),
+	}
+}
+
+// Dispatch sends the alert in the background, unless it is dropped by the
+// limits. It reports whether the alert was sent.
+func (d *Dispatcher) Dispatch(alert Alert) bool {
+	alert, ok := d.admit(alert)
+	if !ok {
+		log.Debug("alert suppressed", "severity", alert.Severity, "topic", alert.Topic)
+		return false
+	}
+	for _, dest := range d.destinations {
+		if alert.Severity < dest.MinSeverity {
+			continue
+		}
+		d.wg.Add(1)
+		go func(backend Backend) {
+			defer d.wg.Done()
+			ctx, cancel := context.WithTimeoutCause(context.Background(), sendTimeout, ErrAlertTimeout)
+			defer cancel()
+			if err := backend.Send(ctx, alert); err != nil {
+				log.Error("failed to send alert", "backend", backend, "err", err)
+			}
+		}(dest.Backend)
+	}
+	return true
+}
+
+// admit applies the limits to the alert, returning it annotated with the
+// number of alerts dropped before it.
+func (d *Dispatcher) admit(alert Alert) (Alert, bool) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	now := d.now()
+	if alert.Time.IsZero() {
+		alert.Time = now
+	}
+	for key, sent := range d.lastSent {
+		if now.Sub(sent) >= d.limits.DedupeWindow {
+			delete(d.lastSent, key)
+		}
+	}
+	key := alert.Severity.String() + "\x00" + alert.Message
+	if _, ok := d.lastSent[key]; ok {
+		d.suppressed[alert.Topic]++
+		return alert, false
+	}
+	// Critical alerts always get through the topic limit
+	if alert.Topic != "" && d.limits.TopicLimit > 0 && alert.Severity < SeverityCritical {
+		recent := d.topicSent[alert.Topic][:0]
+		for _, sent := range d.topicSent[alert.Topic] {
+			if now.Sub(sent) < d.limits.TopicWindow {
+				recent = append(recent, sent)
+			}
+		}
+		d.topicSent[alert.Topic] = recent
+		if len(recent) >= d.limits.TopicLimit {
+			d.suppressed[alert.Topic]++
+			return alert, false
+		}
+	}
+	if alert.Topic != "" {
+		d.topicSent[alert.Topic] = append(d.topicSent[alert.Topic], now)
+	}
+	if d.limits.DedupeWindow > 0 {
+		d.lastSent[key] = now
+	}
+	if n := d.suppressed[alert.Topic]; n > 0 {
+		alert.Message = fmt.Sprintf("%s\n(%d similar alerts suppressed)", alert.Message, n)
+		delete(d.suppressed, alert.Topic)
+	}
+	return alert, true
+}
+
+// Wait waits for the alerts being sent.
+func (d *Dispatcher) Wait() {
+	d.wg.Wait()
+}
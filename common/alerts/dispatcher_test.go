@@ -0,0 +1,154 @@
+package alerts
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+type recordingBackend struct {
+	mu     sync.Mutex
+	alerts []Alert
+}
+
+func (b *recordingBackend) Send(ctx context.Context, alert Alert) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.alerts = append(b.alerts, alert)
+	return nil
+}
+
+func (b *recordingBackend) String() string { return "recording" }
+
+func (b *recordingBackend) messages() []string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	var msgs []string
+	for _, alert := range b.alerts {
+		msgs = append(msgs, alert.Message)
+	}
+	return msgs
+}
+
+type testClock struct{ t time.Time }
+
+func (c *testClock) now() time.Time          { return c.t }
+func (c *testClock) advance(d time.Duration) { c.t = c.t.Add(d) }
+
+func newTestDispatcher(limits Limits, destinations ...Destination) (*Dispatcher, *testClock) {
+	clock := &testClock{time.Unix(1500000000, 0)}
+	d := NewDispatcher(destinations, limits)
+	d.now = clock.now
+	return d, clock
+}
+
+// dispatch sends the alert and waits for it, keeping the recorded order.
+func dispatch(d *Dispatcher, alert Alert) bool {
+	defer d.Wait()
+	return d.Dispatch(alert)
+}
+
+func TestDispatcherDedupe(t *testing.T) {
+	backend := new(recordingBackend)
+	d, clock := newTestDispatcher(Limits{DedupeWindow: time.Minute}, Destination{Backend: backend})
+
+	if !dispatch(d, Alert{Severity: SeverityWarning, Message: "reorg at 100"}) {
+		t.Fatal("first alert dropped")
+	}
+	for i := 0; i < 3; i++ {
+		clock.advance(10 * time.Second)
+		if dispatch(d, Alert{Severity: SeverityWarning, Message: "reorg at 100"}) {
+			t.Fatalf("duplicate alert %d sent", i)
+		}
+	}
+	// Same message with another severity is not a duplicate
+	if !dispatch(d, Alert{Severity: SeverityCritical, Message: "reorg at 100"}) {
+		t.Fatal("alert of another severity dropped")
+	}
+	clock.advance(time.Minute)
+	if !dispatch(d, Alert{Severity: SeverityWarning, Message: "reorg at 100"}) {
+		t.Fatal("alert dropped after the dedupe window")
+	}
+	d.Wait()
+	msgs := backend.messages()
+	if len(msgs) != 3 {
+		t.Fatalf("expected 3 alerts, got %q", msgs)
+	}
+	if !strings.Contains(msgs[1], "(3 similar alerts suppressed)") {
+		t.Errorf("suppressed alerts not noted: %q", msgs[1])
+	}
+	if strings.Contains(msgs[2], "suppressed") {
+		t.Errorf("suppressed alerts noted twice: %q", msgs[2])
+	}
+}
+
+func TestDispatcherTopicLimit(t *testing.T) {
+	backend := new(recordingBackend)
+	d, clock := newTestDispatcher(Limits{TopicLimit: 2, TopicWindow: time.Minute}, Destination{Backend: backend})
+
+	sent := 0
+	for i := 0; i < 5; i++ {
+		if dispatch(d, Alert{Severity: SeverityWarning, Topic: "reorg", Message: strings.Repeat("x", i+1)}) {
+			sent++
+		}
+		clock.advance(time.Second)
+	}
+	if sent != 2 {
+		t.Fatalf("expected 2 alerts of the topic to be sent, got %d", sent)
+	}
+	if !dispatch(d, Alert{Severity: SeverityWarning, Topic: "other", Message: "other"}) {
+		t.Fatal("alert of another topic dropped")
+	}
+	if !dispatch(d, Alert{Severity: SeverityCritical, Topic: "reorg", Message: "deep reorg"}) {
+		t.Fatal("critical alert dropped by the topic limit")
+	}
+	clock.advance(time.Minute)
+	if !dispatch(d, Alert{Severity: SeverityWarning, Topic: "reorg", Message: "later"}) {
+		t.Fatal("alert dropped after the topic window")
+	}
+	d.Wait()
+	msgs := backend.messages()
+	// The critical alert is the next one of the topic to get through
+	if !strings.Contains(msgs[3], "(3 similar alerts suppressed)") || strings.Contains(msgs[4], "suppressed") {
+		t.Errorf("suppressed alerts not noted once: %q", msgs)
+	}
+}
+
+func TestDispatcherSeverityRouting(t *testing.T) {
+	all, warnings, critical := new(recordingBackend), new(recordingBackend), new(recordingBackend)
+	d, _ := newTestDispatcher(DefaultLimits,
+		Destination{Backend: all},
+		Destination{Backend: warnings, MinSeverity: SeverityWarning},
+		Destination{Backend: critical, MinSeverity: SeverityCritical},
+	)
+	d.Dispatch(Alert{Severity: SeverityInfo, Message: "info"})
+	d.Dispatch(Alert{Severity: SeverityWarning, Message: "warning"})
+	d.Dispatch(Alert{Severity: SeverityCritical, Message: "critical"})
+	d.Wait()
+
+	for _, test := range []struct {
+		backend *recordingBackend
+		want    string
+	}{
+		{all, "info,warning,critical"},
+		{warnings, "warning,critical"},
+		{critical, "critical"},
+	} {
+		msgs := test.backend.messages()
+		// sends are concurrent, compare as sets
+		for _, want := range strings.Split(test.want, ",") {
+			found := false
+			for _, msg := range msgs {
+				found = found || msg == want
+			}
+			if !found {
+				t.Errorf("alert %q missing, got %q", want, msgs)
+			}
+		}
+		if len(msgs) != len(strings.Split(test.want, ",")) {
+			t.Errorf("expected alerts %s, got %q", test.want, msgs)
+		}
+	}
+}
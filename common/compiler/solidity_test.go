@@ -0,0 +1,54 @@
+// Copyright 2018 The aquachain Authors
+// This file is part of the aquachain library.
+//
+// The aquachain library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The aquachain library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the aquachain library. If not, see <http://www.gnu.org/licenses/>.
+
+package compiler
+
+import (
+	"reflect"
+	"testing"
+)
+
+// Tests that the combined output of old (stringified fields) and new (raw JSON
+// fields) solc versions is parsed alike.
+func TestParseCombinedJSON(t *testing.T) {
+	abi := []interface{}{map[string]interface{}{"type": "function", "name": "f", "inputs": []interface{}{}, "outputs": []interface{}{}}}
+	tests := map[string]string{
+		"stringified": `{"contracts":{"a.sol:A":{"abi":"[{\"type\":\"function\",\"name\":\"f\",\"inputs\":[],\"outputs\":[]}]","bin":"6001","devdoc":"{}","userdoc":""}},"version":"0.4.24"}`,
+		"raw":         `{"contracts":{"a.sol:A":{"abi":[{"type":"function","name":"f","inputs":[],"outputs":[]}],"bin":"6001","devdoc":{}}},"version":"0.8.19"}`,
+	}
+	for name, output := range tests {
+		contracts, err := ParseCombinedJSON([]byte(output), "src", "0.8", "0.8", "--optimize")
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		contract, ok := contracts["a.sol:A"]
+		if !ok {
+			t.Fatalf("%s: contract missing", name)
+		}
+		if contract.Code != "0x6001" || contract.Info.Source != "src" || contract.Info.CompilerOptions != "--optimize" {
+			t.Errorf("%s: unexpected contract %+v", name, contract)
+		}
+		if !reflect.DeepEqual(contract.Info.AbiDefinition, abi) {
+			t.Errorf("%s: unexpected abi %v", name, contract.Info.AbiDefinition)
+		}
+		if contract.Info.UserDoc != nil {
+			t.Errorf("%s: unexpected user doc %v", name, contract.Info.UserDoc)
+		}
+	}
+	if _, err := ParseCombinedJSON([]byte(`{"contracts":{"a.sol:A":{"abi":"[not json"}}}`), "", "", "", ""); err == nil {
+		t.Error("expected error for invalid abi")
+	}
+}
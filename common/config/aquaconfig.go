@@ -11,6 +11,7 @@ import (
 	"gitlab.com/aquachain/aquachain/common/alerts"
 	"gitlab.com/aquachain/aquachain/common/hexutil"
 	"gitlab.com/aquachain/aquachain/consensus/aquahash"
+	"gitlab.com/aquachain/aquachain/opt/stratum"
 
 	// these imports should be reversed, and instead import this package
 	"gitlab.com/aquachain/aquachain/aqua/downloader" // TODO remove
@@ -32,6 +33,8 @@ type GaspriceConfig = gasprice.Config
 
 type TxPoolConfig = core.TxPoolConfig
 
+type StratumConfig = stratum.Config
+
 type EthstatsConfig struct {
 	URL string `toml:",omitempty"`
 }
@@ -111,6 +114,13 @@ type Aquaconfig struct {
 	DatabaseCache      int
 	TrieCache          int
 	TrieTimeout        time.Duration
+	Snapshot           bool `toml:",omitempty"` // Keep a flat state snapshot for faster state reads
+
+	// Ancient store options. Canonical blocks with AncientThreshold
+	// confirmations are moved from the database into flat files in
+	// AncientDir (default inside the chaindata directory), 0 keeps them.
+	AncientDir       string `toml:",omitempty"`
+	AncientThreshold uint64 `toml:",omitempty"`
 
 	// Mining-related options
 	Aquabase     common.Address `toml:",omitempty"`
@@ -121,6 +131,9 @@ type Aquaconfig struct {
 	// Aquahash options
 	Aquahash *AquahashConfig
 
+	// Stratum server options
+	Stratum StratumConfig `toml:",omitempty"`
+
 	// Transaction pool options
 	TxPool TxPoolConfig
 
@@ -12,6 +12,7 @@ import (
 	"gitlab.com/aquachain/aquachain/common/hexutil"
 	"gitlab.com/aquachain/aquachain/consensus/aquahash/ethashdag"
 	"gitlab.com/aquachain/aquachain/core"
+	"gitlab.com/aquachain/aquachain/opt/stratum"
 )
 
 var _ = (*AquaConfigMarshaling)(nil)
@@ -28,11 +29,15 @@ func (a Aquaconfig) MarshalTOML() (interface{}, error) {
 		DatabaseCache           int
 		TrieCache               int
 		TrieTimeout             time.Duration
+		Snapshot                bool           `toml:",omitempty"`
+		AncientDir              string         `toml:",omitempty"`
+		AncientThreshold        uint64         `toml:",omitempty"`
 		Aquabase                common.Address `toml:",omitempty"`
 		MinerThreads            int            `toml:",omitempty"`
 		ExtraData               hexutil.Bytes  `toml:",omitempty"`
 		GasPrice                uint64
 		Aquahash                *ethashdag.Config
+		Stratum                 stratum.Config `toml:",omitempty"`
 		TxPool                  core.TxPoolConfig
 		GPO                     gasprice.Config
 		EnablePreimageRecording bool
@@ -49,11 +54,15 @@ func (a Aquaconfig) MarshalTOML() (interface{}, error) {
 	enc.DatabaseCache = a.DatabaseCache
 	enc.TrieCache = a.TrieCache
 	enc.TrieTimeout = a.TrieTimeout
+	enc.Snapshot = a.Snapshot
+	enc.AncientDir = a.AncientDir
+	enc.AncientThreshold = a.AncientThreshold
 	enc.Aquabase = a.Aquabase
 	enc.MinerThreads = a.MinerThreads
 	enc.ExtraData = a.ExtraData
 	enc.GasPrice = a.GasPrice
 	enc.Aquahash = a.Aquahash
+	enc.Stratum = a.Stratum
 	enc.TxPool = a.TxPool
 	enc.GPO = a.GPO
 	enc.EnablePreimageRecording = a.EnablePreimageRecording
@@ -74,11 +83,15 @@ func (a *Aquaconfig) UnmarshalTOML(unmarshal func(interface{}) error) error {
 		DatabaseCache           *int
 		TrieCache               *int
 		TrieTimeout             *time.Duration
+		Snapshot                *bool           `toml:",omitempty"`
+		AncientDir              *string         `toml:",omitempty"`
+		AncientThreshold        *uint64         `toml:",omitempty"`
 		Aquabase                *common.Address `toml:",omitempty"`
 		MinerThreads            *int            `toml:",omitempty"`
 		ExtraData               *hexutil.Bytes  `toml:",omitempty"`
 		GasPrice                *uint64
 		Aquahash                *ethashdag.Config
+		Stratum                 *stratum.Config `toml:",omitempty"`
 		TxPool                  *core.TxPoolConfig
 		GPO                     *gasprice.Config
 		EnablePreimageRecording *bool
@@ -116,6 +129,15 @@ func (a *Aquaconfig) UnmarshalTOML(unmarshal func(interface{}) error) error {
 	if dec.TrieTimeout != nil {
 		a.TrieTimeout = *dec.TrieTimeout
 	}
+	if dec.Snapshot != nil {
+		a.Snapshot = *dec.Snapshot
+	}
+	if dec.AncientDir != nil {
+		a.AncientDir = *dec.AncientDir
+	}
+	if dec.AncientThreshold != nil {
+		a.AncientThreshold = *dec.AncientThreshold
+	}
 	if dec.Aquabase != nil {
 		a.Aquabase = *dec.Aquabase
 	}
@@ -131,6 +153,9 @@ func (a *Aquaconfig) UnmarshalTOML(unmarshal func(interface{}) error) error {
 	if dec.Aquahash != nil {
 		a.Aquahash = dec.Aquahash
 	}
+	if dec.Stratum != nil {
+		a.Stratum = *dec.Stratum
+	}
 	if dec.TxPool != nil {
 		a.TxPool = *dec.TxPool
 	}
@@ -19,6 +19,7 @@ package math
 import (
 	"bytes"
 	"encoding/hex"
+	"encoding/json"
 	"math/big"
 	"testing"
 
@@ -61,6 +62,31 @@ func TestHexOrDecimal256(t *testing.T) {
 	}
 }
 
+func TestHexOrDecimal256JSON(t *testing.T) {
+	tests := []struct {
+		input string
+		num   *big.Int
+		ok    bool
+	}{
+		{`12345678`, big.NewInt(12345678), true},
+		{`"12345678"`, big.NewInt(12345678), true},
+		{`"0x12345678"`, big.NewInt(0x12345678), true},
+		{`1.5`, nil, false},
+		{`"abcdef"`, nil, false},
+	}
+	for _, test := range tests {
+		var num HexOrDecimal256
+		err := json.Unmarshal([]byte(test.input), &num)
+		if (err == nil) != test.ok {
+			t.Errorf("Unmarshal(%s) -> (err == nil) == %t, want %t", test.input, err == nil, test.ok)
+			continue
+		}
+		if test.num != nil && (*big.Int)(&num).Cmp(test.num) != 0 {
+			t.Errorf("Unmarshal(%s) -> %d, want %d", test.input, (*big.Int)(&num), test.num)
+		}
+	}
+}
+
 func TestMustParseBig256(t *testing.T) {
 	defer func() {
 		if recover() == nil {
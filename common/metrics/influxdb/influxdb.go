@@ -0,0 +1,315 @@
+// Copyright 2018 The aquachain Authors
+// This file is part of the aquachain library.
+//
+// The aquachain library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The aquachain library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the aquachain library. If not, see <http://www.gnu.org/licenses/>.
+
+// Package influxdb pushes go-metrics to InfluxDB, in the line protocol over
+// HTTP.
+package influxdb
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"gitlab.com/aquachain/aquachain/common/log"
+	"gitlab.com/aquachain/aquachain/common/metrics"
+)
+
+// requestTimeout is the time allowed for a single push to InfluxDB.
+const requestTimeout = 10 * time.Second
+
+// quantiles are the quantiles reported of histograms and timers, along with
+// the names of their fields.
+var (
+	quantiles      = []float64{0.5, 0.75, 0.95, 0.99, 0.999, 0.9999}
+	quantileFields = []string{"p50", "p75", "p95", "p99", "p999", "p9999"}
+)
+
+// reporter periodically pushes the metrics of a registry to InfluxDB.
+type reporter struct {
+	reg      metrics.Registry
+	interval time.Duration
+
+	url       string            // Write endpoint, with the database or bucket
+	username  string            // Basic auth user (v1)
+	password  string            // Basic auth password (v1)
+	token     string            // API token (v2)
+	namespace string            // Prefix of the measurement names
+	tags      map[string]string // Tags added to every measurement
+
+	client *http.Client
+}
+
+// InfluxDBWithTags is a blocking exporter function which pushes the metrics of
+// a registry to the database of an InfluxDB v1 server every d duration, with
+// measurement names prefixed by namespace and the given tags.
+func InfluxDBWithTags(r metrics.Registry, d time.Duration, endpoint, database, username, password, namespace string, tags map[string]string) {
+	rep, err := newReporter(r, d, v1WriteURL(endpoint, database), namespace, tags)
+	if err != nil {
+		log.Warn("Unable to push metrics to InfluxDB", "err", err)
+		return
+	}
+	rep.username, rep.password = username, password
+	rep.run()
+}
+
+// InfluxDBV2WithTags is a blocking exporter function which pushes the metrics of
+// a registry to the bucket of an InfluxDB v2 organization every d duration,
+// with measurement names prefixed by namespace and the given tags.
+func InfluxDBV2WithTags(r metrics.Registry, d time.Duration, endpoint, token, bucket, organization, namespace string, tags map[string]string) {
+	rep, err := newReporter(r, d, v2WriteURL(endpoint, organization, bucket), namespace, tags)
+	if err != nil {
+		log.Warn("Unable to push metrics to InfluxDB", "err", err)
+		return
+	}
+	rep.token = token
+	rep.run()
+}
+
+func newReporter(r metrics.Registry, d time.Duration, writeURL, namespace string, tags map[string]string) (*reporter, error) {
+	if _, err := url.Parse(writeURL); err != nil {
+		return nil, fmt.Errorf("invalid endpoint: %v", err)
+	}
+	return &reporter{
+		reg:       r,
+		interval:  d,
+		url:       writeURL,
+		namespace: namespace,
+		tags:      tags,
+		client:    &http.Client{Timeout: requestTimeout},
+	}, nil
+}
+
+func v1WriteURL(endpoint, database string) string {
+	return strings.TrimSuffix(endpoint, "/") + "/write?" + url.Values{"db": {database}}.Encode()
+}
+
+func v2WriteURL(endpoint, organization, bucket string) string {
+	return strings.TrimSuffix(endpoint, "/") + "/api/v2/write?" + url.Values{"org": {organization}, "bucket": {bucket}}.Encode()
+}
+
+// run pushes the metrics every interval, forever.
+func (r *reporter) run() {
+	log.Info("Pushing metrics to InfluxDB", "url", r.url, "interval", r.interval)
+	for range time.Tick(r.interval) {
+		if err := r.send(time.Now()); err != nil {
+			log.Warn("Unable to push metrics to InfluxDB", "err", err)
+		}
+	}
+}
+
+// send pushes the current values of the metrics to InfluxDB.
+func (r *reporter) send(now time.Time) error {
+	var body bytes.Buffer
+	r.writePoints(&body, now)
+	if body.Len() == 0 {
+		return nil
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), requestTimeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, r.url, &body)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "text/plain; charset=utf-8")
+	if r.username != "" || r.password != "" {
+		req.SetBasicAuth(r.username, r.password)
+	}
+	if r.token != "" {
+		req.Header.Set("Authorization", "Token "+r.token)
+	}
+	resp, err := r.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("%s: %s", resp.Status, strings.TrimSpace(string(msg)))
+	}
+	return nil
+}
+
+// writePoints renders the metrics of the registry in the line protocol, one
+// point per metric, sorted by name.
+func (r *reporter) writePoints(w *bytes.Buffer, now time.Time) {
+	var names []string
+	r.reg.Each(func(name string, i interface{}) {
+		names = append(names, name)
+	})
+	sort.Strings(names)
+
+	tags := encodeTags(r.tags)
+	for _, name := range names {
+		fields := encodeFields(readMeter(r.reg.Get(name), "influxdb "+r.url))
+		if fields == "" {
+			continue // nothing to report, or only NaN and infinite values
+		}
+		w.WriteString(escape(r.namespace+name, ", "))
+		w.WriteString(tags)
+		w.WriteByte(' ')
+		w.WriteString(fields)
+		w.WriteByte(' ')
+		w.WriteString(strconv.FormatInt(now.UnixNano(), 10))
+		w.WriteByte('\n')
+	}
+}
+
+// readMeter returns the fields reported of a metric, nil for metric types
+// unknown to it or without anything to report. Resetting timers are read on
+// behalf of the given reader, so other reporters still see all their values.
+func readMeter(i interface{}, reader string) map[string]interface{} {
+	switch metric := i.(type) {
+	case metrics.Counter:
+		return map[string]interface{}{"count": metric.Count()}
+	case metrics.Gauge:
+		return map[string]interface{}{"value": metric.Snapshot().Value()}
+	case metrics.GaugeFloat64:
+		return map[string]interface{}{"value": metric.Snapshot().Value()}
+	case metrics.Histogram:
+		h := metric.Snapshot()
+		fields := map[string]interface{}{
+			"count":    h.Count(),
+			"max":      h.Max(),
+			"mean":     h.Mean(),
+			"min":      h.Min(),
+			"stddev":   h.StdDev(),
+			"variance": h.Variance(),
+		}
+		for i, p := range h.Percentiles(quantiles) {
+			fields[quantileFields[i]] = p
+		}
+		return fields
+	case metrics.Meter:
+		m := metric.Snapshot()
+		return map[string]interface{}{
+			"count":    m.Count(),
+			"m1":       m.Rate1(),
+			"m5":       m.Rate5(),
+			"m15":      m.Rate15(),
+			"meanrate": m.RateMean(),
+		}
+	case metrics.Timer:
+		t := metric.Snapshot()
+		fields := map[string]interface{}{
+			"count":    t.Count(),
+			"max":      t.Max(),
+			"mean":     t.Mean(),
+			"min":      t.Min(),
+			"stddev":   t.StdDev(),
+			"variance": t.Variance(),
+			"m1":       t.Rate1(),
+			"m5":       t.Rate5(),
+			"m15":      t.Rate15(),
+			"meanrate": t.RateMean(),
+		}
+		for i, p := range t.Percentiles(quantiles) {
+			fields[quantileFields[i]] = p
+		}
+		return fields
+	case metrics.ResettingTimer:
+		t := metric.SnapshotFor(reader)
+		values := t.Values()
+		if len(values) == 0 {
+			return nil
+		}
+		// Resetting timers take percentiles rather than quantiles
+		percentiles := make([]float64, len(quantiles))
+		for i, q := range quantiles {
+			percentiles[i] = q * 100
+		}
+		ps := t.Percentiles(percentiles) // sorts the values
+		fields := map[string]interface{}{
+			"count": int64(len(values)),
+			"max":   values[len(values)-1],
+			"mean":  t.Mean(),
+			"min":   values[0],
+		}
+		for i, p := range ps {
+			fields[quantileFields[i]] = p
+		}
+		return fields
+	}
+	return nil
+}
+
+// encodeTags renders tags as the tag set of a point, sorted by key.
+func encodeTags(tags map[string]string) string {
+	keys := make([]string, 0, len(tags))
+	for key := range tags {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var b strings.Builder
+	for _, key := range keys {
+		if tags[key] == "" {
+			continue // empty tag values are invalid
+		}
+		b.WriteByte(',')
+		b.WriteString(escape(key, ",= "))
+		b.WriteByte('=')
+		b.WriteString(escape(tags[key], ",= "))
+	}
+	return b.String()
+}
+
+// encodeFields renders fields as the field set of a point, sorted by key.
+func encodeFields(fields map[string]interface{}) string {
+	keys := make([]string, 0, len(fields))
+	for key := range fields {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	parts := make([]string, 0, len(keys))
+	for _, key := range keys {
+		var value string
+		switch v := fields[key].(type) {
+		case int64:
+			value = strconv.FormatInt(v, 10) + "i"
+		case float64:
+			if math.IsNaN(v) || math.IsInf(v, 0) {
+				continue // not representable in the line protocol
+			}
+			value = strconv.FormatFloat(v, 'f', -1, 64)
+		}
+		parts = append(parts, escape(key, ",= ")+"="+value)
+	}
+	return strings.Join(parts, ",")
+}
+
+// escape backslash-escapes the given special characters of the line protocol.
+func escape(s string, special string) string {
+	if !strings.ContainsAny(s, special) {
+		return s
+	}
+	var b strings.Builder
+	for _, r := range s {
+		if strings.ContainsRune(special, r) {
+			b.WriteByte('\\')
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
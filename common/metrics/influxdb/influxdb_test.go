@@ -0,0 +1,163 @@
+// Copyright 2018 The aquachain Authors
+// This file is part of the aquachain library.
+//
+// The aquachain library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The aquachain library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the aquachain library. If not, see <http://www.gnu.org/licenses/>.
+
+package influxdb
+
+import (
+	"bytes"
+	"io"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"gitlab.com/aquachain/aquachain/common/metrics"
+)
+
+func init() {
+	metrics.Enabled = true
+}
+
+func newTestRegistry() metrics.Registry {
+	reg := metrics.NewRegistry()
+	metrics.NewRegisteredCounter("test/counter", reg).Inc(12345)
+	metrics.NewRegisteredGauge("test/gauge", reg).Update(23456)
+	metrics.NewRegisteredGaugeFloat64("test/gauge float", reg).Update(34567.89)
+	metrics.NewRegisteredGaugeFloat64("test/gauge nan", reg).Update(math.NaN())
+	metrics.NewRegisteredResettingTimer("test/empty_resetting_timer", reg)
+	timer := metrics.NewRegisteredResettingTimer("test/resetting_timer", reg)
+	timer.Update(30 * time.Millisecond)
+	timer.Update(10 * time.Millisecond)
+	return reg
+}
+
+func TestWritePoints(t *testing.T) {
+	rep, err := newReporter(newTestRegistry(), time.Second, v1WriteURL("http://localhost:8086", "aquachain"), "aquachain.", map[string]string{"host": "node 1", "chain": "testnet3", "empty": ""})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	rep.writePoints(&buf, time.Unix(1, 0))
+
+	const want = `aquachain.test/counter,chain=testnet3,host=node\ 1 count=12345i 1000000000
+aquachain.test/gauge,chain=testnet3,host=node\ 1 value=23456i 1000000000
+aquachain.test/gauge\ float,chain=testnet3,host=node\ 1 value=34567.89 1000000000
+aquachain.test/resetting_timer,chain=testnet3,host=node\ 1 count=2i,max=30000000i,mean=20000000,min=10000000i,p50=10000000i,p75=30000000i,p95=30000000i,p99=30000000i,p999=30000000i,p9999=30000000i 1000000000
+`
+	if have := buf.String(); have != want {
+		t.Errorf("unexpected points\nhave:\n%s\nwant:\n%s", have, want)
+	}
+}
+
+// Tests that reading a resetting timer for another reporter doesn't take its
+// values away from InfluxDB.
+func TestWritePointsSharedTimer(t *testing.T) {
+	reg := metrics.NewRegistry()
+	timer := metrics.NewRegisteredResettingTimer("timer", reg)
+	rep, err := newReporter(reg, time.Second, v1WriteURL("http://localhost:8086", "aquachain"), "", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	rep.writePoints(&buf, time.Unix(1, 0))
+	timer.SnapshotFor("other")
+
+	timer.Update(10 * time.Millisecond)
+	if values := timer.SnapshotFor("other").Values(); len(values) != 1 {
+		t.Fatalf("other reader: have %v, want 1 value", values)
+	}
+	buf.Reset()
+	rep.writePoints(&buf, time.Unix(1, 0))
+	if want := "timer count=1i,"; !strings.HasPrefix(buf.String(), want) {
+		t.Errorf("unexpected points %q, want prefix %q", buf.String(), want)
+	}
+}
+
+func TestEscape(t *testing.T) {
+	tests := []struct {
+		in, special, want string
+	}{
+		{"plain", ",= ", "plain"},
+		{"a b,c=d", ",= ", `a\ b\,c\=d`},
+		{"name=with equals", ", ", `name=with\ equals`},
+	}
+	for _, tt := range tests {
+		if have := escape(tt.in, tt.special); have != tt.want {
+			t.Errorf("escape(%q, %q) = %q, want %q", tt.in, tt.special, have, tt.want)
+		}
+	}
+}
+
+// Tests that points are pushed to the v1 and v2 write endpoints with the
+// right parameters and credentials.
+func TestSend(t *testing.T) {
+	type request struct {
+		path, query, auth string
+		body              []byte
+	}
+	requests := make(chan request, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		requests <- request{r.URL.Path, r.URL.RawQuery, r.Header.Get("Authorization"), body}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	reg := metrics.NewRegistry()
+	metrics.NewRegisteredGauge("gauge", reg).Update(1)
+
+	// InfluxDB v1: database and basic auth
+	rep, _ := newReporter(reg, time.Second, v1WriteURL(server.URL+"/", "aqua db"), "", nil)
+	rep.username, rep.password = "user", "secret"
+	if err := rep.send(time.Unix(1, 0)); err != nil {
+		t.Fatalf("v1 send failed: %v", err)
+	}
+	req := <-requests
+	if req.path != "/write" || req.query != "db=aqua+db" || req.auth != "Basic dXNlcjpzZWNyZXQ=" {
+		t.Errorf("unexpected v1 request %s?%s, auth %q", req.path, req.query, req.auth)
+	}
+	if string(req.body) != "gauge value=1i 1000000000\n" {
+		t.Errorf("unexpected v1 body %q", req.body)
+	}
+
+	// InfluxDB v2: organization, bucket and token
+	rep, _ = newReporter(reg, time.Second, v2WriteURL(server.URL, "org", "bucket"), "", nil)
+	rep.token = "token"
+	if err := rep.send(time.Unix(1, 0)); err != nil {
+		t.Fatalf("v2 send failed: %v", err)
+	}
+	req = <-requests
+	if req.path != "/api/v2/write" || req.query != "bucket=bucket&org=org" || req.auth != "Token token" {
+		t.Errorf("unexpected v2 request %s?%s, auth %q", req.path, req.query, req.auth)
+	}
+}
+
+// Tests that errors returned by InfluxDB are reported.
+func TestSendError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, `{"error":"database not found"}`, http.StatusNotFound)
+	}))
+	defer server.Close()
+
+	reg := metrics.NewRegistry()
+	metrics.NewRegisteredGauge("gauge", reg).Update(1)
+	rep, _ := newReporter(reg, time.Second, v1WriteURL(server.URL, "missing"), "", nil)
+	if err := rep.send(time.Now()); err == nil {
+		t.Fatal("expected error for missing database")
+	}
+}
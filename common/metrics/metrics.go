@@ -42,12 +42,16 @@ var Enabled bool = false
 const MetricsEnabledFlag = "metrics"
 const DashboardEnabledFlag = "dashboard"
 
+// PrometheusEnabledFlag is the CLI flag name to use to enable metrics collection
+// and serve them to Prometheus.
+const PrometheusEnabledFlag = "prometheus"
+
 // Init enables or disables the metrics system. Since we need this to run before
 // any other code gets to create meters and timers, we'll actually do an ugly hack
 // and peek into the command line args for the metrics flag.
 func init() {
 	for _, arg := range os.Args {
-		if flag := strings.TrimLeft(arg, "-"); flag == MetricsEnabledFlag || flag == DashboardEnabledFlag {
+		if flag := strings.TrimLeft(arg, "-"); flag == MetricsEnabledFlag || flag == DashboardEnabledFlag || flag == PrometheusEnabledFlag {
 			Enabled = true
 		}
 	}
@@ -0,0 +1,161 @@
+// Copyright 2018 The aquachain Authors
+// This file is part of the aquachain library.
+//
+// The aquachain library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The aquachain library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the aquachain library. If not, see <http://www.gnu.org/licenses/>.
+
+package prometheus
+
+import (
+	"bytes"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"gitlab.com/aquachain/aquachain/common/metrics"
+)
+
+var (
+	typeGaugeTpl   = "# TYPE %s gauge\n"
+	typeCounterTpl = "# TYPE %s counter\n"
+	typeSummaryTpl = "# TYPE %s summary\n"
+	keyValueTpl    = "%s %v\n"
+	keyQuantileTpl = "%s{quantile=\"%s\"} %v\n"
+)
+
+// quantiles are the quantiles reported of histograms and timers.
+var quantiles = []float64{0.5, 0.75, 0.95, 0.99, 0.999, 0.9999}
+
+// collector aggregates the Prometheus text format report of the metrics of a
+// registry.
+type collector struct {
+	buff *bytes.Buffer
+}
+
+// newCollector creates a new Prometheus metric aggregator.
+func newCollector() *collector {
+	return &collector{
+		buff: &bytes.Buffer{},
+	}
+}
+
+// Add renders a metric of the registry, ignoring metric types unknown to it.
+func (c *collector) Add(name string, i interface{}) {
+	switch m := i.(type) {
+	case metrics.Counter:
+		c.addCounter(name, m.Snapshot())
+	case metrics.Gauge:
+		c.addGauge(name, m.Snapshot())
+	case metrics.GaugeFloat64:
+		c.addGaugeFloat64(name, m.Snapshot())
+	case metrics.Histogram:
+		c.addHistogram(name, m.Snapshot())
+	case metrics.Meter:
+		c.addMeter(name, m.Snapshot())
+	case metrics.Timer:
+		c.addTimer(name, m.Snapshot())
+	case metrics.ResettingTimer:
+		c.addResettingTimer(name, m.SnapshotFor("prometheus"))
+	}
+}
+
+// addCounter renders a counter as a gauge, as counters can be decremented.
+func (c *collector) addCounter(name string, m metrics.Counter) {
+	c.writeGauge(mutateKey(name), m.Count())
+}
+
+func (c *collector) addGauge(name string, m metrics.Gauge) {
+	c.writeGauge(mutateKey(name), m.Value())
+}
+
+func (c *collector) addGaugeFloat64(name string, m metrics.GaugeFloat64) {
+	c.writeGauge(mutateKey(name), m.Value())
+}
+
+// addMeter renders the number of events of a meter as a counter, the rates are
+// for Prometheus to derive.
+func (c *collector) addMeter(name string, m metrics.Meter) {
+	name = mutateKey(name) + "_total"
+	c.buff.WriteString(fmt.Sprintf(typeCounterTpl, name))
+	c.buff.WriteString(fmt.Sprintf(keyValueTpl, name, m.Count()))
+	c.buff.WriteRune('\n')
+}
+
+func (c *collector) addHistogram(name string, m metrics.Histogram) {
+	c.writeSummary(mutateKey(name), m.Percentiles(quantiles), m.Sum(), m.Count())
+}
+
+// addTimer renders a timer as a summary of nanoseconds.
+func (c *collector) addTimer(name string, m metrics.Timer) {
+	c.writeSummary(mutateKey(name), m.Percentiles(quantiles), m.Sum(), m.Count())
+}
+
+// addResettingTimer renders the values of a resetting timer since the previous
+// report as a summary of nanoseconds. Resetting timers without values are left
+// out.
+func (c *collector) addResettingTimer(name string, m metrics.ResettingTimer) {
+	values := m.Values()
+	if len(values) == 0 {
+		return
+	}
+	// Resetting timers take percentiles rather than quantiles
+	percentiles := make([]float64, len(quantiles))
+	for i, q := range quantiles {
+		percentiles[i] = q * 100
+	}
+	ps := make([]float64, len(quantiles))
+	for i, p := range m.Percentiles(percentiles) {
+		ps[i] = float64(p)
+	}
+	var sum int64
+	for _, v := range values {
+		sum += v
+	}
+	c.writeSummary(mutateKey(name), ps, sum, int64(len(values)))
+}
+
+func (c *collector) writeGauge(name string, value interface{}) {
+	c.buff.WriteString(fmt.Sprintf(typeGaugeTpl, name))
+	c.buff.WriteString(fmt.Sprintf(keyValueTpl, name, value))
+	c.buff.WriteRune('\n')
+}
+
+func (c *collector) writeSummary(name string, ps []float64, sum int64, count int64) {
+	c.buff.WriteString(fmt.Sprintf(typeSummaryTpl, name))
+	for i, q := range quantiles {
+		c.buff.WriteString(fmt.Sprintf(keyQuantileTpl, name, strconv.FormatFloat(q, 'f', -1, 64), ps[i]))
+	}
+	c.buff.WriteString(fmt.Sprintf(keyValueTpl, name+"_sum", sum))
+	c.buff.WriteString(fmt.Sprintf(keyValueTpl, name+"_count", count))
+	c.buff.WriteRune('\n')
+}
+
+// mutateKey turns a registry metric name into a valid Prometheus metric name,
+// replacing the characters not allowed in them with underscores.
+func mutateKey(key string) string {
+	var b strings.Builder
+	for i, r := range key {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r == '_', r == ':':
+			b.WriteRune(r)
+		case r >= '0' && r <= '9':
+			if i == 0 {
+				b.WriteRune('_') // names can't start with a digit
+			}
+			b.WriteRune(r)
+		default:
+			b.WriteRune('_')
+		}
+	}
+	return b.String()
+}
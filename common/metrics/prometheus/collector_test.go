@@ -0,0 +1,148 @@
+// Copyright 2018 The aquachain Authors
+// This file is part of the aquachain library.
+//
+// The aquachain library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The aquachain library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the aquachain library. If not, see <http://www.gnu.org/licenses/>.
+
+package prometheus
+
+import (
+	"io"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"gitlab.com/aquachain/aquachain/common/metrics"
+)
+
+func init() {
+	metrics.Enabled = true
+}
+
+func TestCollector(t *testing.T) {
+	c := newCollector()
+
+	counter := metrics.NewCounter()
+	counter.Inc(12345)
+	c.Add("test/counter", counter)
+
+	gauge := metrics.NewGauge()
+	gauge.Update(23456)
+	c.Add("test/gauge", gauge)
+
+	gaugeFloat64 := metrics.NewGaugeFloat64()
+	gaugeFloat64.Update(34567.89)
+	c.Add("test/gauge_float64", gaugeFloat64)
+
+	meter := metrics.NewMeter()
+	defer meter.Stop()
+	meter.Mark(9999999)
+	c.Add("test/meter", meter)
+
+	histogram := metrics.NewHistogram(metrics.NewUniformSample(1028))
+	c.Add("test/histogram", histogram)
+
+	timer := metrics.NewTimer()
+	defer timer.Stop()
+	timer.Update(20 * time.Millisecond)
+	c.Add("test/timer", timer)
+
+	emptyResettingTimer := metrics.NewResettingTimer().Snapshot()
+	c.Add("test/empty_resetting_timer", emptyResettingTimer)
+
+	resettingTimer := metrics.NewResettingTimer()
+	resettingTimer.Update(10 * time.Millisecond)
+	resettingTimer.Update(30 * time.Millisecond)
+	c.Add("test/resetting_timer", resettingTimer)
+
+	const expectedOutput = `# TYPE test_counter gauge
+test_counter 12345
+
+# TYPE test_gauge gauge
+test_gauge 23456
+
+# TYPE test_gauge_float64 gauge
+test_gauge_float64 34567.89
+
+# TYPE test_meter_total counter
+test_meter_total 9999999
+
+# TYPE test_histogram summary
+test_histogram{quantile="0.5"} 0
+test_histogram{quantile="0.75"} 0
+test_histogram{quantile="0.95"} 0
+test_histogram{quantile="0.99"} 0
+test_histogram{quantile="0.999"} 0
+test_histogram{quantile="0.9999"} 0
+test_histogram_sum 0
+test_histogram_count 0
+
+# TYPE test_timer summary
+test_timer{quantile="0.5"} 2e+07
+test_timer{quantile="0.75"} 2e+07
+test_timer{quantile="0.95"} 2e+07
+test_timer{quantile="0.99"} 2e+07
+test_timer{quantile="0.999"} 2e+07
+test_timer{quantile="0.9999"} 2e+07
+test_timer_sum 20000000
+test_timer_count 1
+
+# TYPE test_resetting_timer summary
+test_resetting_timer{quantile="0.5"} 1e+07
+test_resetting_timer{quantile="0.75"} 3e+07
+test_resetting_timer{quantile="0.95"} 3e+07
+test_resetting_timer{quantile="0.99"} 3e+07
+test_resetting_timer{quantile="0.999"} 3e+07
+test_resetting_timer{quantile="0.9999"} 3e+07
+test_resetting_timer_sum 40000000
+test_resetting_timer_count 2
+
+`
+	if have := c.buff.String(); have != expectedOutput {
+		t.Errorf("unexpected output\nhave:\n%s\nwant:\n%s", have, expectedOutput)
+	}
+}
+
+func TestMutateKey(t *testing.T) {
+	tests := map[string]string{
+		"chain/head/block":       "chain_head_block",
+		"aqua/fetcher/prop/in":   "aqua_fetcher_prop_in",
+		"p2p/dial.fail-count":    "p2p_dial_fail_count",
+		"0x/leading":             "_0x_leading",
+		"already_valid:Name_123": "already_valid:Name_123",
+	}
+	for key, want := range tests {
+		if have := mutateKey(key); have != want {
+			t.Errorf("mutateKey(%q) = %q, want %q", key, have, want)
+		}
+	}
+}
+
+func TestHandler(t *testing.T) {
+	reg := metrics.NewRegistry()
+	metrics.NewRegisteredGauge("b/gauge", reg).Update(2)
+	metrics.NewRegisteredCounter("a/counter", reg).Inc(1)
+
+	recorder := httptest.NewRecorder()
+	Handler(reg).ServeHTTP(recorder, httptest.NewRequest("GET", "/metrics", nil))
+	body, _ := io.ReadAll(recorder.Body)
+
+	if ct := recorder.Header().Get("Content-Type"); !strings.HasPrefix(ct, "text/plain") {
+		t.Errorf("unexpected content type %q", ct)
+	}
+	const want = "# TYPE a_counter gauge\na_counter 1\n\n# TYPE b_gauge gauge\nb_gauge 2\n\n"
+	if string(body) != want {
+		t.Errorf("unexpected response\nhave:\n%s\nwant:\n%s", body, want)
+	}
+}
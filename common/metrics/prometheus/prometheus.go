@@ -0,0 +1,60 @@
+// Copyright 2018 The aquachain Authors
+// This file is part of the aquachain library.
+//
+// The aquachain library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The aquachain library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the aquachain library. If not, see <http://www.gnu.org/licenses/>.
+
+// Package prometheus exposes go-metrics in the Prometheus text format.
+package prometheus
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+
+	"gitlab.com/aquachain/aquachain/common/log"
+	"gitlab.com/aquachain/aquachain/common/metrics"
+)
+
+// Handler returns an HTTP handler which renders all the metrics of a registry
+// in the Prometheus text format.
+func Handler(reg metrics.Registry) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// Gather and pre-sort the metrics to avoid random listings
+		var names []string
+		reg.Each(func(name string, i interface{}) {
+			names = append(names, name)
+		})
+		sort.Strings(names)
+
+		// Aggregate all the metrics into a Prometheus collector
+		c := newCollector()
+		for _, name := range names {
+			if i := reg.Get(name); i != nil {
+				c.Add(name, i)
+			}
+		}
+		w.Header().Add("Content-Type", "text/plain; version=0.0.4")
+		w.Header().Add("Content-Length", fmt.Sprint(c.buff.Len()))
+		w.Write(c.buff.Bytes())
+	})
+}
+
+// ListenAndServe serves the metrics of a registry at /metrics on a listener of
+// its own, until it fails.
+func ListenAndServe(address string, reg metrics.Registry) error {
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", Handler(reg))
+	log.Info("Starting Prometheus metrics server", "addr", fmt.Sprintf("http://%s/metrics", address))
+	return http.ListenAndServe(address, mux)
+}
@@ -30,6 +30,7 @@ const InitialResettingTimerSliceCap = 10
 type ResettingTimer interface {
 	Values() []int64
 	Snapshot() ResettingTimer
+	SnapshotFor(reader string) ResettingTimer
 	Percentiles([]float64) []int64
 	Mean() float64
 	Time(func())
@@ -62,7 +63,8 @@ func NewResettingTimer() ResettingTimer {
 		return NilResettingTimer{}
 	}
 	return &StandardResettingTimer{
-		values: make([]int64, 0, InitialResettingTimerSliceCap),
+		values:  make([]int64, 0, InitialResettingTimerSliceCap),
+		unnamed: true,
 	}
 }
 
@@ -76,6 +78,9 @@ func (NilResettingTimer) Values() []int64 { return nil }
 // Snapshot is a no-op.
 func (NilResettingTimer) Snapshot() ResettingTimer { return NilResettingTimer{} }
 
+// SnapshotFor is a no-op.
+func (NilResettingTimer) SnapshotFor(string) ResettingTimer { return NilResettingTimer{} }
+
 // Time is a no-op.
 func (NilResettingTimer) Time(func()) {}
 
@@ -98,8 +103,10 @@ func (NilResettingTimer) UpdateSince(time.Time) {}
 // StandardResettingTimer is the standard implementation of a ResettingTimer.
 // and Meter.
 type StandardResettingTimer struct {
-	values []int64
-	mutex  sync.Mutex
+	values  []int64            // values since the last Snapshot
+	unnamed bool               // whether values are collected for Snapshot
+	readers map[string][]int64 // values since the last SnapshotFor, by reader
+	mutex   sync.Mutex
 }
 
 // Values returns a slice with all measurements.
@@ -113,6 +120,29 @@ func (t *StandardResettingTimer) Snapshot() ResettingTimer {
 	defer t.mutex.Unlock()
 	currentValues := t.values
 	t.values = make([]int64, 0, InitialResettingTimerSliceCap)
+	t.unnamed = true
+
+	return &ResettingTimerSnapshot{
+		values: currentValues,
+	}
+}
+
+// SnapshotFor resets the timer for the given reader only, and returns a
+// read-only copy of the values recorded since that reader's previous call.
+// Reporters running side by side each use their own reader, so every one of
+// them sees all values instead of what the others left.
+//
+// The first reader takes over the values recorded so far. From then on values
+// are only kept for Snapshot if it is called again.
+func (t *StandardResettingTimer) SnapshotFor(reader string) ResettingTimer {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	if t.readers == nil {
+		t.readers = map[string][]int64{reader: t.values}
+		t.values, t.unnamed = nil, false
+	}
+	currentValues := t.readers[reader]
+	t.readers[reader] = make([]int64, 0, InitialResettingTimerSliceCap)
 
 	return &ResettingTimerSnapshot{
 		values: currentValues,
@@ -138,16 +168,23 @@ func (t *StandardResettingTimer) Time(f func()) {
 
 // Record the duration of an event.
 func (t *StandardResettingTimer) Update(d time.Duration) {
-	t.mutex.Lock()
-	defer t.mutex.Unlock()
-	t.values = append(t.values, int64(d))
+	t.record(int64(d))
 }
 
 // Record the duration of an event that started at a time and ends now.
 func (t *StandardResettingTimer) UpdateSince(ts time.Time) {
+	t.record(int64(time.Since(ts)))
+}
+
+func (t *StandardResettingTimer) record(v int64) {
 	t.mutex.Lock()
 	defer t.mutex.Unlock()
-	t.values = append(t.values, int64(time.Since(ts)))
+	if t.unnamed {
+		t.values = append(t.values, v)
+	}
+	for reader, values := range t.readers {
+		t.readers[reader] = append(values, v)
+	}
 }
 
 // ResettingTimerSnapshot is a point-in-time copy of another ResettingTimer.
@@ -161,6 +198,9 @@ type ResettingTimerSnapshot struct {
 // Snapshot returns the snapshot.
 func (t *ResettingTimerSnapshot) Snapshot() ResettingTimer { return t }
 
+// SnapshotFor returns the snapshot.
+func (t *ResettingTimerSnapshot) SnapshotFor(string) ResettingTimer { return t }
+
 // Time panics.
 func (*ResettingTimerSnapshot) Time(func()) {
 	panic("Time called on a ResettingTimerSnapshot")
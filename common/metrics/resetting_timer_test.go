@@ -120,3 +120,38 @@ func TestResettingTimer(t *testing.T) {
 		}
 	}
 }
+
+func TestResettingTimerReaders(t *testing.T) {
+	timer := NewResettingTimer()
+	timer.Update(1)
+
+	// the first reader gets what was recorded before it
+	if have := timer.SnapshotFor("a").Values(); len(have) != 1 {
+		t.Fatalf("first reader: have %v, want [1]", have)
+	}
+	timer.Update(2)
+	timer.Update(3)
+	if have := timer.SnapshotFor("a").Values(); len(have) != 2 {
+		t.Fatalf("reader a: have %v, want [2 3]", have)
+	}
+	// a reader starting late sees nothing, but doesn't reset the others
+	timer.Update(4)
+	if have := timer.SnapshotFor("b").Values(); len(have) != 0 {
+		t.Fatalf("new reader b: have %v, want []", have)
+	}
+	timer.Update(5)
+	if have := timer.SnapshotFor("b").Values(); len(have) != 1 || have[0] != 5 {
+		t.Fatalf("reader b: have %v, want [5]", have)
+	}
+	if have := timer.SnapshotFor("a").Values(); len(have) != 2 || have[0] != 4 || have[1] != 5 {
+		t.Fatalf("reader a: have %v, want [4 5]", have)
+	}
+	// values are no longer kept for Snapshot, until it is called again
+	if have := timer.Snapshot().Values(); len(have) != 0 {
+		t.Fatalf("unnamed reader: have %v, want []", have)
+	}
+	timer.Update(6)
+	if have := timer.Snapshot().Values(); len(have) != 1 || have[0] != 6 {
+		t.Fatalf("unnamed reader: have %v, want [6]", have)
+	}
+}
@@ -40,6 +40,7 @@ import (
 	"gitlab.com/aquachain/aquachain/common/prque"
 	"gitlab.com/aquachain/aquachain/consensus"
 	"gitlab.com/aquachain/aquachain/core/state"
+	"gitlab.com/aquachain/aquachain/core/state/snapshot"
 	"gitlab.com/aquachain/aquachain/core/types"
 	"gitlab.com/aquachain/aquachain/core/vm"
 	"gitlab.com/aquachain/aquachain/crypto"
@@ -72,6 +73,9 @@ type CacheConfig struct {
 	Disabled      bool          // Whether to disable trie write caching (archive node)
 	TrieNodeLimit int           // Memory limit (MB) at which to flush the current in-memory trie to disk
 	TrieTimeLimit time.Duration // Time limit after which to flush the current in-memory trie to disk
+
+	AncientThreshold uint64 // Confirmations after which canonical blocks move to the ancient store (0 = never)
+	Snapshot         bool   // Whether to keep a flat state snapshot for faster state reads
 }
 
 // BlockChain represents the canonical chain given a database with a genesis
@@ -114,6 +118,7 @@ type BlockChain struct {
 	currentFastBlock atomic.Value // Current head of the fast-sync chain (may be above the block chain!)
 
 	stateCache   state.Database // State database to reuse between imports (contains state cache)
+	snaps        *snapshot.Tree // Flat state snapshot, nil if disabled
 	bodyCache    *lru.Cache     // Cache for the most recent block bodies
 	bodyRLPCache *lru.Cache     // Cache for the most recent block bodies in RLP encoded format
 	blockCache   *lru.Cache     // Cache for the most recent entire blocks
@@ -190,6 +195,9 @@ func NewBlockChain(ctx context.Context, db aquadb.Database, cacheConfig *CacheCo
 	if err := bc.loadLastState(); err != nil {
 		return nil, err
 	}
+	if cacheConfig.Snapshot {
+		bc.snaps = snapshot.New(db, bc.stateCache.TrieDB(), bc.CurrentBlock().Root(), true)
+	}
 	// Check the current state of the block hashes and make sure that we do not have any of the bad blocks in our chain
 	for hash := range BadHashes {
 		if header := bc.GetHeaderByHash(hash); header != nil {
@@ -203,6 +211,14 @@ func NewBlockChain(ctx context.Context, db aquadb.Database, cacheConfig *CacheCo
 			}
 		}
 	}
+	if cacheConfig.AncientThreshold > 0 {
+		if _, ok := db.(aquadb.AncientStore); ok {
+			bc.wg.Add(1)
+			go bc.freeze()
+		} else {
+			log.Warn("Database has no ancient store, keeping all blocks", "threshold", cacheConfig.AncientThreshold)
+		}
+	}
 	// Take ownership of this particular state
 	go bc.update()
 	return bc, nil
@@ -287,6 +303,11 @@ func (bc *BlockChain) SetHead(head uint64) error {
 	bc.hc.SetHead(head, delFn)
 	currentHeader := bc.hc.CurrentHeader()
 
+	// Frozen blocks can't be deleted one by one, discard them all at once
+	if err := truncateAncients(bc.db, currentHeader.Number.Uint64()); err != nil {
+		log.Crit("Failed to truncate ancient store", "err", err)
+	}
+
 	// Clear out any stale content from the caches
 	bc.bodyCache.Purge()
 	bc.bodyRLPCache.Purge()
@@ -322,7 +343,15 @@ func (bc *BlockChain) SetHead(head uint64) error {
 	if err := WriteHeadFastBlockHash(bc.db, currentFastBlock.Hash()); err != nil {
 		log.Crit("Failed to reset head fast block", "err", err)
 	}
-	return bc.loadLastState()
+	if err := bc.loadLastState(); err != nil {
+		return err
+	}
+	// The snapshot layers above the new head are useless, start over if the
+	// head has none
+	if root := bc.CurrentBlock().Root(); bc.snaps != nil && bc.snaps.Snapshot(root) == nil {
+		bc.snaps.Rebuild(root)
+	}
+	return nil
 }
 
 // FastSyncCommitHead sets the current head block to the one defined by the hash
@@ -341,6 +370,10 @@ func (bc *BlockChain) FastSyncCommitHead(hash common.Hash) error {
 	bc.currentBlock.Store(block)
 	bc.mu.Unlock()
 
+	// The synced state was written directly to the database
+	if bc.snaps != nil {
+		bc.snaps.Rebuild(block.Root())
+	}
 	log.Info("Committed new head block", "number", block.Number(), "hash", hash)
 	return nil
 }
@@ -399,7 +432,12 @@ func (bc *BlockChain) State() (*state.StateDB, error) {
 
 // StateAt returns a new mutable state based on a particular point in time.
 func (bc *BlockChain) StateAt(root common.Hash) (*state.StateDB, error) {
-	return state.New(root, bc.stateCache)
+	return state.NewWithSnapshot(root, bc.stateCache, bc.snaps)
+}
+
+// Snapshots returns the flat state snapshot, or nil if it is disabled.
+func (bc *BlockChain) Snapshots() *snapshot.Tree {
+	return bc.snaps
 }
 
 // Reset purges the entire blockchain, restoring it to its genesis state.
@@ -565,8 +603,7 @@ func (bc *BlockChain) HasBlock(hash common.Hash, number uint64) bool {
 	if bc.blockCache.Contains(hash) {
 		return true
 	}
-	ok, _ := bc.db.Has(blockBodyKey(hash, number))
-	return ok
+	return HasBody(bc.db, hash, number)
 }
 
 // HasState checks if state trie is fully present in the database or not.
@@ -678,6 +715,13 @@ func (bc *BlockChain) Stop() {
 
 	bc.wg.Wait()
 
+	// Flatten the state snapshot onto the head state, where it resumes from
+	if bc.snaps != nil {
+		if err := bc.snaps.Persist(bc.CurrentBlock().Root()); err != nil {
+			log.Error("Failed to persist state snapshot", "err", err)
+		}
+	}
+
 	// Ensure the state of a recent block is also stored to disk before exiting.
 	// We're writing three different states to catch different restart scenarios:
 	//  - HEAD:     So we don't need to reprocess any blocks in the general case
@@ -934,6 +978,12 @@ func (bc *BlockChain) WriteBlockWithState(block *types.Block, receipts []*types.
 	if err != nil {
 		return NonStatTy, err
 	}
+	// Flatten old snapshot layers before their tries are garbage collected
+	if bc.snaps != nil && block.ParentHash() == currentBlock.Hash() && bc.snaps.Snapshot(root) != nil {
+		if err := bc.snaps.Cap(root, triesInMemory-1); err != nil {
+			log.Warn("Failed to flatten state snapshot", "root", root, "err", err)
+		}
+	}
 	triedb := bc.stateCache.TrieDB()
 
 	// If we're running an archive node, always flush
@@ -1199,7 +1249,7 @@ func (bc *BlockChain) insertChain2(chain types.Blocks, try int) (int, []interfac
 		} else {
 			parent = chain[i-1]
 		}
-		state, err := state.New(parent.Root(), bc.stateCache)
+		state, err := state.NewWithSnapshot(parent.Root(), bc.stateCache, bc.snaps)
 		if err != nil {
 			return i, events, coalescedLogs, err
 		}
@@ -1420,7 +1470,7 @@ func (bc *BlockChain) reorg(oldBlock, newBlock *types.Block) error {
 	if err != nil {
 		// send alert notification
 		go doAlert(oldChain, newChain, deletedTxs, deletedLogs, commonBlock, nil, err)
-		alerts.Warnf("Failed to insert reorged chain: %v", err)
+		alerts.Alertf(alerts.SeverityCritical, "reorg", "Failed to insert reorged chain: %v", err)
 		return err
 	}
 	// calculate the difference between deleted and added transactions
@@ -1433,7 +1483,7 @@ func (bc *BlockChain) reorg(oldBlock, newBlock *types.Block) error {
 	}
 	go doAlert(oldChain, newChain, deletedTxs, deletedLogs, commonBlock, diffs, err)
 	if len(diffs) > 0 {
-		alerts.Warnf("Deleted tx hashes:\n%q\n", diffs)
+		alerts.Alertf(alerts.SeverityWarning, "reorg", "Deleted tx hashes:\n%q\n", diffs)
 	}
 	if len(deletedLogs) > 0 {
 		go bc.rmLogsFeed.Send(RemovedLogsEvent{deletedLogs})
@@ -1457,7 +1507,7 @@ func doAlert(oldChain, newChain types.Blocks, deletedTxs types.Transactions,
 			value.Add(value, newtx.Value())
 		}
 	}
-	alerts.Warnf("⚠️WARNING: reorg detected (%s %s) after height %d: %d blocks rolled back (%d deleted logs, %d deleted txs), added %d new blocks",
+	alerts.Alertf(alerts.SeverityWarning, "reorg", "⚠️WARNING: reorg detected (%s %s) after height %d: %d blocks rolled back (%d deleted logs, %d deleted txs), added %d new blocks",
 		common.WeiToCoin(value), "AQUA",
 		commonBlock.NumberU64(), len(oldChain), len(deletedLogs), len(deletedTxs),
 		len(newChain))
@@ -1467,11 +1517,11 @@ func doAlert(oldChain, newChain types.Blocks, deletedTxs types.Transactions,
 
 	if diffs == nil {
 		if err != nil {
-			alerts.Warnf("Failed to insert reorged chain: %v", err)
+			alerts.Alertf(alerts.SeverityCritical, "reorg", "Failed to insert reorged chain: %v", err)
 		}
 		return
 	}
-	alerts.Warnf("Deleted tx hashes (appears to be %s value):\n%q\n", common.WeiToCoin(value), diffs)
+	alerts.Alertf(alerts.SeverityWarning, "reorg", "Deleted tx hashes (appears to be %s value):\n%q\n", common.WeiToCoin(value), diffs)
 }
 
 // PostChainEvents iterates over the events generated by a chain insertion and
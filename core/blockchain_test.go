@@ -28,10 +28,12 @@ import (
 	"gitlab.com/aquachain/aquachain/common"
 	"gitlab.com/aquachain/aquachain/consensus/aquahash"
 	"gitlab.com/aquachain/aquachain/core/state"
+	"gitlab.com/aquachain/aquachain/core/state/snapshot"
 	"gitlab.com/aquachain/aquachain/core/types"
 	"gitlab.com/aquachain/aquachain/core/vm"
 	"gitlab.com/aquachain/aquachain/crypto"
 	"gitlab.com/aquachain/aquachain/params"
+	"gitlab.com/aquachain/aquachain/trie"
 )
 
 // Test fork of length N starting from block i
@@ -1178,6 +1180,52 @@ func TestEIP155Transition(t *testing.T) {
 	}
 }
 
+func TestEIP2718Transition(t *testing.T) {
+	var (
+		db      = aquadb.NewMemDatabase()
+		key, _  = crypto.HexToBtcec("b71c71a67e1177ad4e901695e1b4b9ee17ae16c6668d313eac2f96dbcda3f291")
+		address = crypto.PubkeyToAddress(key.PubKey())
+		funds   = big.NewInt(1000000000)
+		gspec   = &Genesis{
+			Config: &params.ChainConfig{ChainId: big.NewInt(1), EIP155Block: new(big.Int), HomesteadBlock: new(big.Int), HF: params.ForkMap{11: big.NewInt(2)}},
+			Alloc:  GenesisAlloc{address: {Balance: funds}},
+		}
+		genesis    = gspec.MustCommit(db)
+		accessList = types.AccessList{{Address: common.Address{2}, StorageKeys: []common.Hash{{1}}}}
+	)
+	blockchain, _ := NewBlockChain(context.TODO(), db, nil, gspec.Config, aquahash.NewFaker(), vm.Config{})
+	defer blockchain.Stop()
+
+	accessListTx := func(block *BlockGen) *types.Transaction {
+		tx := types.NewAccessListTransaction(gspec.Config.ChainId, block.TxNonce(address), &common.Address{1}, new(big.Int), 50000, new(big.Int), nil, accessList)
+		tx, err := types.SignTx(tx, types.LatestSignerForChainID(gspec.Config.ChainId), key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return tx
+	}
+	// Once the fork is active, the transaction is accepted and pays for its access list
+	blocks, _ := GenerateChain(context.TODO(), gspec.Config, genesis, aquahash.NewFaker(), db, 2, func(i int, block *BlockGen) {
+		if i == 1 {
+			block.AddTx(accessListTx(block))
+		}
+	})
+	if _, err := blockchain.InsertChain(blocks); err != nil {
+		t.Fatal(err)
+	}
+	block := blockchain.GetBlockByNumber(2)
+	if len(block.Transactions()) != 1 || block.Transactions()[0].Type() != types.AccessListTxType {
+		t.Fatalf("expected one access list transaction in block 2")
+	}
+	receipts := blockchain.GetReceiptsByHash(block.Hash())
+	if len(receipts) != 1 || receipts[0].Type != types.AccessListTxType {
+		t.Fatalf("expected one typed receipt, got %v", receipts)
+	}
+	if want := params.TxGas + params.TxAccessListAddressGas + params.TxAccessListStorageKeyGas; receipts[0].GasUsed != want {
+		t.Errorf("gas used mismatch: have %d, want %d", receipts[0].GasUsed, want)
+	}
+}
+
 func TestEIP161AccountRemoval(t *testing.T) {
 	// Configure and generate a sample block chain
 	var (
@@ -1390,3 +1438,53 @@ func TestLargeReorgTrieGC(t *testing.T) {
 		}
 	}
 }
+
+// Tests that the state snapshot follows the chain, answering state reads, and
+// that the snapshot persisted on shutdown matches the head state.
+func TestSnapshotFollowsChain(t *testing.T) {
+	var (
+		db      = aquadb.NewMemDatabase()
+		key, _  = crypto.HexToBtcec("b71c71a67e1177ad4e901695e1b4b9ee17ae16c6668d313eac2f96dbcda3f291")
+		address = crypto.PubkeyToAddress(key.PubKey())
+		gspec   = &Genesis{
+			Config: params.TestChainConfig,
+			Alloc:  GenesisAlloc{address: {Balance: big.NewInt(1000000000)}},
+		}
+		genesis = gspec.MustCommit(db)
+		signer  = types.NewEIP155Signer(gspec.Config.ChainId)
+	)
+	blocks, _ := GenerateChain(context.TODO(), gspec.Config, genesis, aquahash.NewFaker(), db, 8, func(i int, block *BlockGen) {
+		block.SetCoinbase(common.Address{byte(i)})
+
+		// PUSH1 i PUSH1 0 SSTORE STOP
+		code := []byte{0x60, byte(i + 1), 0x60, 0x00, 0x55, 0x00}
+		tx, err := types.SignTx(types.NewContractCreation(block.TxNonce(address), new(big.Int), 100000, new(big.Int), code), signer, key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		block.AddTx(tx)
+	})
+	blockchain, err := NewBlockChain(context.TODO(), db, &CacheConfig{Disabled: true, Snapshot: true}, gspec.Config, aquahash.NewFaker(), vm.Config{})
+	if err != nil {
+		t.Fatalf("failed to create tester chain: %v", err)
+	}
+	if _, err := blockchain.InsertChain(blocks); err != nil {
+		t.Fatal(err)
+	}
+	head := blockchain.CurrentBlock().Root()
+	if blockchain.Snapshots().Snapshot(head) == nil {
+		t.Fatalf("no snapshot layer for head state %x", head)
+	}
+	st, _ := blockchain.State()
+	for i := 0; i < len(blocks); i++ {
+		contract := crypto.CreateAddress(address, uint64(i))
+		if have := st.GetState(contract, common.Hash{}); have != common.BigToHash(big.NewInt(int64(i+1))) {
+			t.Errorf("contract %d: storage mismatch: have %x", i, have)
+		}
+	}
+	blockchain.Stop()
+
+	if _, _, err := snapshot.Verify(db, trie.NewDatabase(db), head); err != nil {
+		t.Fatalf("persisted snapshot: %v", err)
+	}
+}
@@ -0,0 +1,246 @@
+// Copyright 2018 The aquachain Authors
+// This file is part of the aquachain library.
+//
+// The aquachain library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The aquachain library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the aquachain library. If not, see <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"gitlab.com/aquachain/aquachain/aquadb"
+	"gitlab.com/aquachain/aquachain/common"
+	"gitlab.com/aquachain/aquachain/common/log"
+	"gitlab.com/aquachain/aquachain/core/types"
+	"gitlab.com/aquachain/aquachain/rlp"
+)
+
+// Tables of the ancient store, holding the frozen part of the canonical chain.
+const (
+	ancientHashTable       = "hashes"
+	ancientHeaderTable     = "headers"
+	ancientBodyTable       = "bodies"
+	ancientReceiptTable    = "receipts"
+	ancientDifficultyTable = "diffs"
+)
+
+const (
+	freezerBatchLimit      = 2048        // Maximum number of blocks frozen while holding the chain lock
+	freezerRecheckInterval = time.Minute // Interval between checks for blocks to freeze
+)
+
+var errNoAncientStore = errors.New("database has no ancient store")
+
+// getAncientHash returns the canonical hash of a frozen block, or the zero
+// hash if the block is not frozen.
+func getAncientHash(db DatabaseReader, number uint64) common.Hash {
+	ancients, ok := db.(aquadb.AncientReader)
+	if !ok {
+		return common.Hash{}
+	}
+	data, err := ancients.Ancient(ancientHashTable, number)
+	if err != nil {
+		return common.Hash{}
+	}
+	return common.BytesToHash(data)
+}
+
+// getAncient returns an item of a frozen block, or nil if the block is not
+// frozen. Only canonical blocks are frozen, so the hash must match.
+func getAncient(db DatabaseReader, kind string, hash common.Hash, number uint64) []byte {
+	ancients, ok := db.(aquadb.AncientReader)
+	if !ok || number >= ancients.Ancients() {
+		return nil
+	}
+	if getAncientHash(db, number) != hash {
+		return nil
+	}
+	data, _ := ancients.Ancient(kind, number)
+	return data
+}
+
+// isAncient reports whether the block is in the ancient store.
+func isAncient(db DatabaseReader, hash common.Hash, number uint64) bool {
+	ancients, ok := db.(aquadb.AncientReader)
+	return ok && number < ancients.Ancients() && getAncientHash(db, number) == hash
+}
+
+// freezeBlocks moves up to max canonical blocks numbered below limit from the
+// key-value store into the ancient store, continuing where the ancient store
+// ends, and returns how many were moved. The genesis block is frozen but also
+// kept in the key-value store. Side chain blocks at the frozen heights are
+// deleted, as they can never become canonical again.
+func freezeBlocks(db aquadb.Database, limit uint64, max int) (int, error) {
+	ancients, ok := db.(aquadb.AncientStore)
+	if !ok {
+		return 0, errNoAncientStore
+	}
+	var (
+		first  = ancients.Ancients()
+		hashes []common.Hash
+		parent common.Hash
+	)
+	if first > 0 {
+		parent = getAncientHash(db, first-1)
+	}
+	for number := first; number < limit && len(hashes) < max; number++ {
+		hash := GetCanonicalHash(db, number)
+		if hash == (common.Hash{}) {
+			return len(hashes), fmt.Errorf("canonical hash #%d missing", number)
+		}
+		header, _ := db.Get(headerKey(hash, number))
+		body, _ := db.Get(blockBodyKey(hash, number))
+		td, _ := db.Get(tdKey(hash, number))
+		if len(header) == 0 || len(body) == 0 || len(td) == 0 {
+			// the block is incomplete, most likely still being synced
+			break
+		}
+		receipts, _ := db.Get(blockReceiptsKey(hash, number))
+
+		// Never extend the ancient store with a block of a different chain
+		if number > 0 {
+			var h types.Header
+			if err := rlp.DecodeBytes(header, &h); err != nil {
+				return len(hashes), fmt.Errorf("invalid header #%d: %v", number, err)
+			}
+			if h.ParentHash != parent {
+				return len(hashes), fmt.Errorf("block #%d [%x…] does not extend the ancient chain [%x…]", number, hash[:4], parent[:4])
+			}
+		}
+		err := ancients.AppendAncient(number, map[string][]byte{
+			ancientHashTable:       hash.Bytes(),
+			ancientHeaderTable:     header,
+			ancientBodyTable:       body,
+			ancientReceiptTable:    receipts,
+			ancientDifficultyTable: td,
+		})
+		if err != nil {
+			return len(hashes), err
+		}
+		hashes = append(hashes, hash)
+		parent = hash
+	}
+	if len(hashes) == 0 {
+		return 0, nil
+	}
+	// Make sure the ancient store is on disk before wiping the originals
+	if err := ancients.SyncAncient(); err != nil {
+		return 0, err
+	}
+	batch := db.NewBatch()
+	for i, hash := range hashes {
+		number := first + uint64(i)
+		if iteratee, ok := db.(aquadb.Iteratee); ok {
+			it := iteratee.NewIteratorWithPrefix(append(headerPrefix, encodeBlockNumber(number)...))
+			for it.Next() {
+				// headerPrefix + num + hash is a header, anything longer or shorter is not
+				if key := it.Key(); len(key) == len(headerPrefix)+8+common.HashLength {
+					if side := common.BytesToHash(key[len(key)-common.HashLength:]); side != hash {
+						DeleteBlock(batch, side, number)
+					}
+				}
+			}
+			it.Release()
+		}
+		if number == 0 {
+			continue
+		}
+		batch.Delete(headerKey(hash, number))
+		batch.Delete(blockBodyKey(hash, number))
+		batch.Delete(blockReceiptsKey(hash, number))
+		batch.Delete(tdKey(hash, number))
+		DeleteCanonicalHash(batch, number)
+	}
+	if err := batch.Write(); err != nil {
+		return 0, err
+	}
+	return len(hashes), nil
+}
+
+// Freeze moves the canonical blocks with at least the configured number of
+// confirmations from the key-value store into the ancient store, returning how
+// many were moved. It does nothing if no threshold is configured.
+func (bc *BlockChain) Freeze() (int, error) {
+	threshold := bc.cacheConfig.AncientThreshold
+	head := bc.CurrentBlock().NumberU64()
+	if threshold == 0 || head < threshold {
+		return 0, nil
+	}
+	var (
+		limit  = head - threshold + 1
+		frozen int
+		start  = time.Now()
+		logged = time.Now()
+	)
+	for !bc.getProcInterrupt() {
+		bc.chainmu.Lock()
+		bc.mu.Lock()
+		n, err := freezeBlocks(bc.db, limit, freezerBatchLimit)
+		bc.mu.Unlock()
+		bc.chainmu.Unlock()
+
+		frozen += n
+		if err != nil || n == 0 {
+			return frozen, err
+		}
+		if time.Since(logged) > 8*time.Second {
+			log.Info("Moving blocks to ancient store", "count", frozen, "limit", limit, "elapsed", common.PrettyDuration(time.Since(start)))
+			logged = time.Now()
+		}
+	}
+	return frozen, nil
+}
+
+// Ancients returns the number of blocks in the ancient store, or zero if the
+// database has none.
+func (bc *BlockChain) Ancients() uint64 {
+	if ancients, ok := bc.db.(aquadb.AncientReader); ok {
+		return ancients.Ancients()
+	}
+	return 0
+}
+
+// freeze periodically moves old canonical blocks into the ancient store.
+func (bc *BlockChain) freeze() {
+	defer bc.wg.Done()
+
+	ticker := time.NewTicker(freezerRecheckInterval)
+	defer ticker.Stop()
+	for {
+		start := time.Now()
+		if n, err := bc.Freeze(); err != nil {
+			log.Error("Failed to move blocks to ancient store", "err", err)
+		} else if n > 0 {
+			log.Info("Moved blocks to ancient store", "count", n, "ancients", bc.Ancients(), "elapsed", common.PrettyDuration(time.Since(start)))
+		}
+		select {
+		case <-ticker.C:
+		case <-bc.quit:
+			return
+		}
+	}
+}
+
+// truncateAncients discards frozen blocks above the head, so that rewinding
+// the chain below the ancient store leaves no stale canonical blocks behind.
+func truncateAncients(db aquadb.Database, head uint64) error {
+	ancients, ok := db.(aquadb.AncientStore)
+	if !ok || ancients.Ancients() <= head+1 {
+		return nil
+	}
+	log.Warn("Truncating ancient store", "head", head, "ancients", ancients.Ancients())
+	return ancients.TruncateAncients(head + 1)
+}
@@ -0,0 +1,186 @@
+// Copyright 2018 The aquachain Authors
+// This file is part of the aquachain library.
+//
+// The aquachain library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The aquachain library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the aquachain library. If not, see <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"math/big"
+	"os"
+	"testing"
+	"time"
+
+	"gitlab.com/aquachain/aquachain/aquadb"
+	"gitlab.com/aquachain/aquachain/common"
+	"gitlab.com/aquachain/aquachain/consensus/aquahash"
+	"gitlab.com/aquachain/aquachain/core/types"
+	"gitlab.com/aquachain/aquachain/core/vm"
+	"gitlab.com/aquachain/aquachain/crypto"
+	"gitlab.com/aquachain/aquachain/params"
+)
+
+func TestFreezeBlocks(t *testing.T) {
+	var (
+		gendb   = aquadb.NewMemDatabase()
+		key, _  = crypto.HexToBtcec("b71c71a67e1177ad4e901695e1b4b9ee17ae16c6668d313eac2f96dbcda3f291")
+		address = crypto.PubkeyToAddress(key.PubKey())
+		gspec   = &Genesis{
+			Config: params.TestChainConfig,
+			Alloc:  GenesisAlloc{address: {Balance: big.NewInt(1000000000)}},
+		}
+		genesis = gspec.MustCommit(gendb)
+		signer  = types.NewEIP155Signer(gspec.Config.ChainId)
+	)
+	blocks, _ := GenerateChain(context.TODO(), gspec.Config, genesis, aquahash.NewFaker(), gendb, 300, func(i int, block *BlockGen) {
+		if i%3 == 0 {
+			tx, err := types.SignTx(types.NewTransaction(block.TxNonce(address), common.Address{0x01}, big.NewInt(1000), params.TxGas, nil, nil), signer, key)
+			if err != nil {
+				panic(err)
+			}
+			block.AddTx(tx)
+		}
+	})
+	side, _ := GenerateChain(context.TODO(), gspec.Config, blocks[49], aquahash.NewFaker(), gendb, 5, func(i int, block *BlockGen) {
+		block.SetCoinbase(common.Address{0x02})
+	})
+
+	dir, err := ioutil.TempDir("", "ancient")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	freezer, err := aquadb.NewFreezer(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	kvdb := aquadb.NewMemDatabase()
+	db := aquadb.NewDatabaseWithFreezer(kvdb, freezer)
+	defer db.Close()
+	gspec.MustCommit(db)
+
+	cacheConfig := &CacheConfig{Disabled: true, TrieNodeLimit: 256, TrieTimeLimit: 5 * time.Minute, AncientThreshold: 100}
+	chain, err := NewBlockChain(context.TODO(), db, cacheConfig, gspec.Config, aquahash.NewFaker(), vm.Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer chain.Stop()
+	if _, err := chain.InsertChain(blocks); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := chain.InsertChain(side); err != nil {
+		t.Fatal(err)
+	}
+	// A reference chain without an ancient store to compare against
+	refdb := aquadb.NewMemDatabase()
+	gspec.MustCommit(refdb)
+	ref, _ := NewBlockChain(context.TODO(), refdb, nil, gspec.Config, aquahash.NewFaker(), vm.Config{})
+	defer ref.Stop()
+	if _, err := ref.InsertChain(blocks); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, block := range side {
+		if !HasBody(db, block.Hash(), block.NumberU64()) {
+			t.Fatalf("side block #%d not stored", block.NumberU64())
+		}
+	}
+	// Blocks 0..200 have at least 100 confirmations on a 300 block chain
+	if n, err := chain.Freeze(); err != nil || n != 201 {
+		t.Fatalf("froze %d blocks: %v", n, err)
+	}
+	if chain.Ancients() != 201 {
+		t.Fatalf("wrong ancient count %d", chain.Ancients())
+	}
+	if n, err := chain.Freeze(); err != nil || n != 0 {
+		t.Fatalf("froze %d blocks again: %v", n, err)
+	}
+	checkChain := func(head int) {
+		t.Helper()
+		for i, block := range blocks[:head] {
+			number, hash := block.NumberU64(), block.Hash()
+			if have := GetCanonicalHash(db, number); have != hash {
+				t.Fatalf("block #%d: canonical hash mismatch: have %x, want %x", number, have, hash)
+			}
+			if have := GetBlockNoVersion(db, hash, number); have == nil || have.Header().ParentHash != block.ParentHash() || len(have.Transactions()) != len(block.Transactions()) {
+				t.Fatalf("block #%d: block mismatch", number)
+			}
+			if have, want := GetTd(db, hash, number), ref.GetTd(hash, number); have == nil || have.Cmp(want) != 0 {
+				t.Fatalf("block #%d: td mismatch: have %v, want %v", number, have, want)
+			}
+			if have, want := GetBlockReceipts(db, hash, number), ref.GetReceiptsByHash(hash); len(have) != len(want) {
+				t.Fatalf("block #%d: receipt count mismatch: have %d, want %d", number, len(have), len(want))
+			}
+			if !HasHeader(db, hash, number) || !HasBody(db, hash, number) {
+				t.Fatalf("block #%d: not found", number)
+			}
+			for _, tx := range block.Transactions() {
+				if have, _, _, _ := GetTransaction(db, tx.Hash()); have == nil {
+					t.Fatalf("block #%d: transaction %x missing", number, tx.Hash())
+				}
+				if have, _, _, _ := GetReceipt(db, tx.Hash()); have == nil {
+					t.Fatalf("block #%d: receipt %x missing", number, tx.Hash())
+				}
+			}
+			// frozen blocks are gone from the key-value store
+			if ok, _ := kvdb.Has(headerKey(hash, number)); ok == (i < 200) {
+				t.Fatalf("block #%d: header in key-value store: %v", number, ok)
+			}
+		}
+	}
+	checkChain(len(blocks))
+
+	// The genesis block stays, side chains below the ancient limit go away
+	if ok, _ := kvdb.Has(headerKey(genesis.Hash(), 0)); !ok {
+		t.Fatal("genesis header removed from key-value store")
+	}
+	for _, block := range side {
+		if HasHeader(db, block.Hash(), block.NumberU64()) || HasBody(db, block.Hash(), block.NumberU64()) {
+			t.Fatalf("side block #%d not removed", block.NumberU64())
+		}
+	}
+	// Exporting reads through the ancient store
+	var have, want bytes.Buffer
+	if err := chain.Export(&have); err != nil {
+		t.Fatal(err)
+	}
+	if err := ref.Export(&want); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(have.Bytes(), want.Bytes()) {
+		t.Fatal("export differs from chain without ancient store")
+	}
+
+	// Rewinding into the ancient store truncates it
+	if err := chain.SetHead(150); err != nil {
+		t.Fatal(err)
+	}
+	if chain.Ancients() != 151 {
+		t.Fatalf("wrong ancient count after rewind: %d", chain.Ancients())
+	}
+	if block := chain.GetBlockByNumber(151); block != nil {
+		t.Fatal("block above the head still canonical")
+	}
+	checkChain(150)
+	if _, err := chain.InsertChain(blocks[150:]); err != nil {
+		t.Fatal(err)
+	}
+	if n, err := chain.Freeze(); err != nil || n != 50 {
+		t.Fatalf("froze %d blocks after rewind: %v", n, err)
+	}
+	checkChain(len(blocks))
+}
@@ -98,7 +98,7 @@ func GetCanonicalHash(db DatabaseReader, number uint64) common.Hash {
 		log.Error("Failed to retrieve canonical hash", "number", number, "err", err)
 	}
 	if len(data) == 0 {
-		return common.Hash{}
+		return getAncientHash(db, number)
 	}
 	return common.BytesToHash(data)
 }
@@ -165,9 +165,21 @@ func GetTrieSyncProgress(db DatabaseReader) uint64 {
 // if the header's not found.
 func GetHeaderRLP(db DatabaseReader, hash common.Hash, number uint64) rlp.RawValue {
 	data, _ := db.Get(headerKey(hash, number))
+	if len(data) == 0 {
+		data = getAncient(db, ancientHeaderTable, hash, number)
+	}
 	return data
 }
 
+// HasHeader checks if the block header corresponding to the hash is present in
+// the database or its ancient store.
+func HasHeader(db aquadb.Database, hash common.Hash, number uint64) bool {
+	if ok, _ := db.Has(headerKey(hash, number)); ok {
+		return true
+	}
+	return isAncient(db, hash, number)
+}
+
 // GetHeader retrieves the block header corresponding to the hash, nil if none
 // found.
 func GetHeaderNoVersion(db DatabaseReader, hash common.Hash, number uint64) *types.Header {
@@ -186,9 +198,21 @@ func GetHeaderNoVersion(db DatabaseReader, hash common.Hash, number uint64) *typ
 // GetBodyRLP retrieves the block body (transactions and uncles) in RLP encoding.
 func GetBodyRLP(db DatabaseReader, hash common.Hash, number uint64) rlp.RawValue {
 	data, _ := db.Get(blockBodyKey(hash, number))
+	if len(data) == 0 {
+		data = getAncient(db, ancientBodyTable, hash, number)
+	}
 	return data
 }
 
+// HasBody checks if the block body corresponding to the hash is present in the
+// database or its ancient store.
+func HasBody(db aquadb.Database, hash common.Hash, number uint64) bool {
+	if ok, _ := db.Has(blockBodyKey(hash, number)); ok {
+		return true
+	}
+	return isAncient(db, hash, number)
+}
+
 func headerKey(hash common.Hash, number uint64) []byte {
 	return append(append(headerPrefix, encodeBlockNumber(number)...), hash.Bytes()...)
 }
@@ -197,6 +221,14 @@ func blockBodyKey(hash common.Hash, number uint64) []byte {
 	return append(append(bodyPrefix, encodeBlockNumber(number)...), hash.Bytes()...)
 }
 
+func tdKey(hash common.Hash, number uint64) []byte {
+	return append(headerKey(hash, number), tdSuffix...)
+}
+
+func blockReceiptsKey(hash common.Hash, number uint64) []byte {
+	return append(append(blockReceiptsPrefix, encodeBlockNumber(number)...), hash.Bytes()...)
+}
+
 // GetBodyNoVersion retrieves the block body (transactons, uncles) corresponding to the
 // hash, nil if none found.
 func GetBodyNoVersion(db DatabaseReader, hash common.Hash, number uint64) *types.Body {
@@ -215,7 +247,10 @@ func GetBodyNoVersion(db DatabaseReader, hash common.Hash, number uint64) *types
 // GetTd retrieves a block's total difficulty corresponding to the hash, nil if
 // none found.
 func GetTd(db DatabaseReader, hash common.Hash, number uint64) *big.Int {
-	data, _ := db.Get(append(append(append(headerPrefix, encodeBlockNumber(number)...), hash[:]...), tdSuffix...))
+	data, _ := db.Get(tdKey(hash, number))
+	if len(data) == 0 {
+		data = getAncient(db, ancientDifficultyTable, hash, number)
+	}
 	if len(data) == 0 {
 		return nil
 	}
@@ -250,7 +285,10 @@ func GetBlockNoVersion(db DatabaseReader, hash common.Hash, number uint64) *type
 // GetBlockReceipts retrieves the receipts generated by the transactions included
 // in a block given by its hash.
 func GetBlockReceipts(db DatabaseReader, hash common.Hash, number uint64) types.Receipts {
-	data, _ := db.Get(append(append(blockReceiptsPrefix, encodeBlockNumber(number)...), hash[:]...))
+	data, _ := db.Get(blockReceiptsKey(hash, number))
+	if len(data) == 0 {
+		data = getAncient(db, ancientReceiptTable, hash, number)
+	}
 	if len(data) == 0 {
 		return nil
 	}
@@ -0,0 +1,152 @@
+// Copyright 2018 The aquachain Authors
+// This file is part of the aquachain library.
+//
+// The aquachain library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The aquachain library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the aquachain library. If not, see <http://www.gnu.org/licenses/>.
+
+package forkid
+
+import (
+	"bytes"
+	"math"
+	"math/big"
+	"reflect"
+	"testing"
+
+	"gitlab.com/aquachain/aquachain/common"
+	"gitlab.com/aquachain/aquachain/params"
+	"gitlab.com/aquachain/aquachain/rlp"
+)
+
+// Tests that fork IDs are properly calculated at the mainnet fork blocks.
+func TestCreation(t *testing.T) {
+	tests := []struct {
+		head uint64
+		want ID
+	}{
+		{0, ID{Hash: [4]byte{0xda, 0x2d, 0xa2, 0xd2}, Next: 3600}},      // Unsynced
+		{3599, ID{Hash: [4]byte{0xda, 0x2d, 0xa2, 0xd2}, Next: 3600}},   // Last block before HF1
+		{3600, ID{Hash: [4]byte{0x0a, 0x6d, 0x20, 0xb5}, Next: 7200}},   // First HF1 block
+		{36049, ID{Hash: [4]byte{0xa7, 0x3d, 0xf4, 0x05}, Next: 36050}}, // Last block before HF7
+		{36050, ID{Hash: [4]byte{0x38, 0xfb, 0xfc, 0x8d}, Next: 0}},     // First HF7 block, HF8 isn't scheduled
+		{1000000, ID{Hash: [4]byte{0x38, 0xfb, 0xfc, 0x8d}, Next: 0}},   // Future
+	}
+	for i, tt := range tests {
+		if have := NewID(params.MainnetChainConfig, params.MainnetGenesisHash, tt.head); have != tt.want {
+			t.Errorf("test %d: fork ID mismatch: have %x, want %x", i, have, tt.want)
+		}
+	}
+}
+
+// Tests that the fork blocks are gathered from the HF map, leaving out the
+// unscheduled forks and the ones active from genesis.
+func TestGatherForks(t *testing.T) {
+	config := &params.ChainConfig{HF: params.ForkMap{
+		1: big.NewInt(0),
+		2: big.NewInt(20),
+		3: big.NewInt(10),
+		4: big.NewInt(20),
+		5: nil,
+		6: big.NewInt(30),
+	}}
+	if have, want := gatherForks(config), []uint64{10, 20, 30}; !reflect.DeepEqual(have, want) {
+		t.Errorf("fork blocks mismatch: have %v, want %v", have, want)
+	}
+}
+
+// Tests that a node activating a fork with a flag gets another fork ID.
+func TestFlagActivatedFork(t *testing.T) {
+	config := &params.ChainConfig{HF: params.ForkMap{1: big.NewInt(10), 8: nil}}
+	genesis := common.Hash{1}
+	activated := &params.ChainConfig{HF: params.ForkMap{1: big.NewInt(10), 8: big.NewInt(50)}}
+
+	if NewID(config, genesis, 60) == NewID(activated, genesis, 60) {
+		t.Fatal("fork ID unchanged by the activated fork")
+	}
+	// Before the fork, the upgraded node is compatible with both
+	if err := NewStaticFilter(config, genesis)(NewID(activated, genesis, 0)); err != nil {
+		t.Errorf("upgraded node rejected before the fork: %v", err)
+	}
+	filter := newFilter(activated, genesis, func() uint64 { return 60 })
+	if err := filter(NewID(config, genesis, 60)); err != ErrRemoteStale {
+		t.Errorf("node without the fork not rejected as stale after the fork: %v", err)
+	}
+}
+
+// Tests that the fork ID filter correctly accepts and rejects remote IDs.
+func TestValidation(t *testing.T) {
+	config := params.MainnetChainConfig
+	tests := []struct {
+		head uint64
+		id   ID
+		err  error
+	}{
+		// Local is mainnet HF7, remote announces the same. No future fork is announced.
+		{40000, NewID(config, params.MainnetGenesisHash, 40000), nil},
+
+		// Local is mainnet HF7, remote announces the same, and a future fork at block
+		// math.MaxUint64. Remote is simply aware of a future fork we don't know yet.
+		{40000, ID{Hash: [4]byte{0x38, 0xfb, 0xfc, 0x8d}, Next: math.MaxUint64}, nil},
+
+		// Local is mainnet HF7, remote announces the same, and a future fork we already
+		// passed. Remote activated a fork locally unknown, reject.
+		{40000, ID{Hash: [4]byte{0x38, 0xfb, 0xfc, 0x8d}, Next: 39000}, ErrLocalIncompatibleOrStale},
+
+		// Local is mainnet before HF1, remote announces the same. Remote also knows
+		// about HF1.
+		{100, ID{Hash: [4]byte{0xda, 0x2d, 0xa2, 0xd2}, Next: 3600}, nil},
+
+		// Local is mainnet HF7, remote is at HF1 and knows about HF2. Remote is
+		// syncing, accept.
+		{40000, ID{Hash: [4]byte{0x0a, 0x6d, 0x20, 0xb5}, Next: 7200}, nil},
+
+		// Local is mainnet HF7, remote is at HF1 but doesn't know about HF2 (wrong
+		// next block). Remote needs an update.
+		{40000, ID{Hash: [4]byte{0x0a, 0x6d, 0x20, 0xb5}, Next: 7300}, ErrRemoteStale},
+
+		// Local is mainnet HF1, remote is at HF7. Local is syncing, accept.
+		{4000, ID{Hash: [4]byte{0x38, 0xfb, 0xfc, 0x8d}, Next: 0}, nil},
+
+		// Local is mainnet HF7, remote is on another chain (or another genesis).
+		{40000, ID{Hash: [4]byte{0xde, 0xad, 0xbe, 0xef}, Next: 0}, ErrLocalIncompatibleOrStale},
+	}
+	for i, tt := range tests {
+		filter := newFilter(config, params.MainnetGenesisHash, func() uint64 { return tt.head })
+		if err := filter(tt.id); err != tt.err {
+			t.Errorf("test %d: validation error mismatch: have %v, want %v", i, err, tt.err)
+		}
+	}
+}
+
+// Tests that IDs are properly RLP encoded (specifically important because we
+// use uint32 to store the hash, but we need to encode it as [4]byte).
+func TestEncoding(t *testing.T) {
+	tests := []struct {
+		id   ID
+		want []byte
+	}{
+		{ID{Hash: checksumToBytes(0), Next: 0}, common.Hex2Bytes("c6840000000080")},
+		{ID{Hash: checksumToBytes(0xdeadbeef), Next: 0xBADDCAFE}, common.Hex2Bytes("ca84deadbeef84baddcafe")},
+		{ID{Hash: checksumToBytes(math.MaxUint32), Next: math.MaxUint64}, common.Hex2Bytes("ce84ffffffff88ffffffffffffffff")},
+	}
+	for i, tt := range tests {
+		have, err := rlp.EncodeToBytes(tt.id)
+		if err != nil {
+			t.Errorf("test %d: failed to encode forkid: %v", i, err)
+			continue
+		}
+		if !bytes.Equal(have, tt.want) {
+			t.Errorf("test %d: RLP mismatch: have %x, want %x", i, have, tt.want)
+		}
+	}
+}
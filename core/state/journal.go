@@ -59,6 +59,10 @@ type (
 		account            *common.Address
 		prevcode, prevhash []byte
 	}
+	fakeStorageChange struct {
+		account *common.Address
+		prev    Storage
+	}
 
 	// Changes to other state values.
 	refundChange struct {
@@ -121,6 +125,10 @@ func (ch storageChange) undo(s *StateDB) {
 	s.getStateObject(*ch.account).setState(ch.key, ch.prevalue)
 }
 
+func (ch fakeStorageChange) undo(s *StateDB) {
+	s.getStateObject(*ch.account).fakeStorage = ch.prev
+}
+
 func (ch refundChange) undo(s *StateDB) {
 	s.refund = ch.prev
 }
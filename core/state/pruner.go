@@ -0,0 +1,125 @@
+// Copyright 2018 The aquachain Authors
+// This file is part of the aquachain library.
+//
+// The aquachain library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The aquachain library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the aquachain library. If not, see <http://www.gnu.org/licenses/>.
+
+package state
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"gitlab.com/aquachain/aquachain/aquadb"
+	"gitlab.com/aquachain/aquachain/common"
+	"gitlab.com/aquachain/aquachain/common/log"
+	"gitlab.com/aquachain/aquachain/crypto"
+)
+
+// PruneStats summarises a state pruning run.
+type PruneStats struct {
+	Reachable   int                // Trie nodes and contract codes reachable from the kept roots
+	Unreachable int                // Trie nodes and contract codes deleted (or deletable on a dry run)
+	Reclaimable common.StorageSize // Size of the keys and values of the unreachable entries
+}
+
+// Prune deletes every state trie node and contract code from the database
+// that is not reachable from one of the given state roots. If dryRun is set,
+// nothing is deleted, only counted.
+//
+// Trie nodes and codes are the database entries keyed by the hash of their
+// value; other entries are never touched. The database must not be in use
+// while pruning, and all roots must be complete, or nothing is deleted.
+func Prune(db aquadb.Database, roots []common.Hash, dryRun bool) (*PruneStats, error) {
+	iteratee, ok := db.(aquadb.Iteratee)
+	if !ok {
+		return nil, errors.New("database can't be iterated")
+	}
+	var (
+		stats  = new(PruneStats)
+		keep   = make(map[common.Hash]struct{})
+		start  = time.Now()
+		logged = time.Now()
+		sdb    = NewDatabase(db)
+	)
+	// Mark every node reachable from the kept roots
+	for _, root := range roots {
+		statedb, err := New(root, sdb)
+		if err != nil {
+			return nil, fmt.Errorf("state %x: %v", root, err)
+		}
+		it := NewNodeIterator(statedb)
+		for it.Next() {
+			if it.Hash != (common.Hash{}) {
+				keep[it.Hash] = struct{}{}
+			}
+			if time.Since(logged) > 8*time.Second {
+				log.Info("Marking reachable state", "root", root, "nodes", len(keep), "elapsed", common.PrettyDuration(time.Since(start)))
+				logged = time.Now()
+			}
+		}
+		if it.Error != nil {
+			return nil, fmt.Errorf("state %x is incomplete: %v", root, it.Error)
+		}
+	}
+	stats.Reachable = len(keep)
+	log.Info("Marked reachable state", "nodes", stats.Reachable, "elapsed", common.PrettyDuration(time.Since(start)))
+
+	// Sweep everything else that looks like a trie node or code
+	var (
+		batch = db.NewBatch()
+		it    = iteratee.NewIterator()
+	)
+	defer it.Release()
+	for it.Next() {
+		key := it.Key()
+		if len(key) != common.HashLength {
+			continue
+		}
+		if _, ok := keep[common.BytesToHash(key)]; ok {
+			continue
+		}
+		value := it.Value()
+		if crypto.Keccak256Hash(value) != common.BytesToHash(key) {
+			continue
+		}
+		stats.Unreachable++
+		stats.Reclaimable += common.StorageSize(len(key) + len(value))
+		if !dryRun {
+			if err := batch.Delete(common.CopyBytes(key)); err != nil {
+				return stats, err
+			}
+			if batch.ValueSize() >= aquadb.IdealBatchSize {
+				if err := batch.Write(); err != nil {
+					return stats, err
+				}
+				batch.Reset()
+			}
+		}
+		if time.Since(logged) > 8*time.Second {
+			log.Info("Sweeping unreachable state", "nodes", stats.Unreachable, "size", stats.Reclaimable, "elapsed", common.PrettyDuration(time.Since(start)))
+			logged = time.Now()
+		}
+	}
+	if err := it.Error(); err != nil {
+		return stats, err
+	}
+	if !dryRun {
+		if err := batch.Write(); err != nil {
+			return stats, err
+		}
+	}
+	log.Info("Swept unreachable state", "nodes", stats.Unreachable, "size", stats.Reclaimable, "dryrun", dryRun, "elapsed", common.PrettyDuration(time.Since(start)))
+	return stats, nil
+}
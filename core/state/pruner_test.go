@@ -0,0 +1,112 @@
+// Copyright 2018 The aquachain Authors
+// This file is part of the aquachain library.
+//
+// The aquachain library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The aquachain library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the aquachain library. If not, see <http://www.gnu.org/licenses/>.
+
+package state
+
+import (
+	"bytes"
+	"math/big"
+	"testing"
+
+	"gitlab.com/aquachain/aquachain/aquadb"
+	"gitlab.com/aquachain/aquachain/common"
+	"gitlab.com/aquachain/aquachain/crypto"
+)
+
+func TestPrune(t *testing.T) {
+	diskdb := aquadb.NewMemDatabase()
+	sdb := NewDatabase(diskdb)
+
+	// Write an old state, then a new one sharing part of it
+	state, _ := New(common.Hash{}, sdb)
+	for i := byte(0); i < 64; i++ {
+		addr := common.BytesToAddress([]byte{i})
+		state.SetBalance(addr, big.NewInt(int64(i)+1))
+		state.SetState(addr, common.Hash{i}, common.Hash{i + 1})
+		if i%4 == 0 {
+			state.SetCode(addr, []byte{i, 1})
+		}
+	}
+	old, _ := state.Commit(false)
+	if err := sdb.TrieDB().Commit(old, false); err != nil {
+		t.Fatal(err)
+	}
+	state, _ = New(old, sdb)
+	for i := byte(0); i < 32; i++ {
+		addr := common.BytesToAddress([]byte{i})
+		state.SetBalance(addr, big.NewInt(int64(i)+100))
+		state.SetState(addr, common.Hash{i}, common.Hash{i + 2})
+	}
+	state.SetCode(common.BytesToAddress([]byte{0}), []byte{0xff})
+	root, _ := state.Commit(false)
+	if err := sdb.TrieDB().Commit(root, false); err != nil {
+		t.Fatal(err)
+	}
+	// Entries that are not state must survive, even with hash sized keys
+	junk := bytes.Repeat([]byte{1}, common.HashLength)
+	diskdb.Put(junk, []byte("not a trie node"))
+	diskdb.Put([]byte("LastBlock"), root[:])
+
+	before := diskdb.Len()
+	dry, err := Prune(diskdb, []common.Hash{root}, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if dry.Unreachable == 0 || dry.Reclaimable == 0 || dry.Reachable == 0 {
+		t.Fatalf("nothing to prune: %+v", dry)
+	}
+	if diskdb.Len() != before {
+		t.Fatalf("dry run deleted %d entries", before-diskdb.Len())
+	}
+	stats, err := Prune(diskdb, []common.Hash{root}, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if *stats != *dry {
+		t.Fatalf("prune differs from dry run: have %+v, want %+v", stats, dry)
+	}
+	if diskdb.Len() != before-stats.Unreachable {
+		t.Fatalf("wrong entry count: have %d, want %d", diskdb.Len(), before-stats.Unreachable)
+	}
+	if err := checkStateConsistency(diskdb, root); err != nil {
+		t.Fatalf("kept state broken: %v", err)
+	}
+	if ok, _ := diskdb.Has(old[:]); ok {
+		t.Fatal("old state root kept")
+	}
+	if ok, _ := diskdb.Has(crypto.Keccak256([]byte{0, 1})); ok {
+		t.Error("unreachable code kept")
+	}
+	if ok, _ := diskdb.Has(crypto.Keccak256([]byte{4, 1})); !ok {
+		t.Error("reachable code deleted")
+	}
+	for _, key := range [][]byte{junk, []byte("LastBlock")} {
+		if ok, _ := diskdb.Has(key); !ok {
+			t.Errorf("unrelated entry %q deleted", key)
+		}
+	}
+	// Pruning again finds nothing, and an incomplete root deletes nothing
+	if again, err := Prune(diskdb, []common.Hash{root}, false); err != nil || again.Unreachable != 0 {
+		t.Fatalf("second prune: %+v, %v", again, err)
+	}
+	before = diskdb.Len()
+	if _, err := Prune(diskdb, []common.Hash{root, old}, false); err == nil {
+		t.Fatal("pruned with an incomplete state")
+	}
+	if diskdb.Len() != before {
+		t.Fatal("failed prune deleted entries")
+	}
+}
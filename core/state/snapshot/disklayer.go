@@ -0,0 +1,315 @@
+// Copyright 2018 The aquachain Authors
+// This file is part of the aquachain library.
+//
+// The aquachain library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The aquachain library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the aquachain library. If not, see <http://www.gnu.org/licenses/>.
+
+package snapshot
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"sync"
+
+	lru "github.com/hashicorp/golang-lru"
+	"gitlab.com/aquachain/aquachain/aquadb"
+	"gitlab.com/aquachain/aquachain/common"
+	"gitlab.com/aquachain/aquachain/common/log"
+	"gitlab.com/aquachain/aquachain/rlp"
+	"gitlab.com/aquachain/aquachain/trie"
+)
+
+// Number of snapshot entries cached in memory, shared by successive disk layers.
+const diskCacheItems = 128 * 1024
+
+// diskLayer is the snapshot of one state stored in the database. While the
+// generator is running, only the accounts up to the generator marker (and
+// their storage) are available.
+type diskLayer struct {
+	db     aquadb.Database
+	triedb *trie.Database
+	cache  *lru.Cache
+	root   common.Hash
+
+	genMarker  []byte             // Last account hash generated, nil when generation is done
+	genPending chan struct{}      // Closed when the generator stops
+	genErr     error              // Error the generator failed with
+	genAbort   chan chan struct{} // Stops the generator, nil if none running
+	genLock    sync.Mutex         // Serialises stopping the generator
+
+	stale bool
+	lock  sync.RWMutex
+}
+
+func newDiskLayer(db aquadb.Database, triedb *trie.Database, cache *lru.Cache, root common.Hash, marker []byte) *diskLayer {
+	if cache == nil {
+		cache, _ = lru.New(diskCacheItems)
+	}
+	return &diskLayer{
+		db:        db,
+		triedb:    triedb,
+		cache:     cache,
+		root:      root,
+		genMarker: marker,
+	}
+}
+
+// loadDiskLayer opens the snapshot stored in the database, resuming its
+// generation if it was interrupted.
+func loadDiskLayer(db aquadb.Database, triedb *trie.Database, root common.Hash) (*diskLayer, error) {
+	stored, _ := db.Get(snapshotRootKey)
+	if len(stored) == 0 {
+		return nil, errors.New("no snapshot")
+	}
+	if common.BytesToHash(stored) != root {
+		return nil, fmt.Errorf("snapshot of state %x, head state %x", stored, root)
+	}
+	dl := newDiskLayer(db, triedb, nil, root, nil)
+	if generating, _ := db.Has(snapshotGeneratorKey); generating {
+		marker, _ := db.Get(snapshotGeneratorKey)
+		dl.genMarker = append([]byte{}, marker...)
+		log.Info("Resuming state snapshot generation", "root", root, "at", common.BytesToHash(marker))
+		dl.startGeneration()
+	}
+	return dl, nil
+}
+
+// generateDiskLayer starts generating the snapshot of the given state root,
+// replacing whatever snapshot the database holds.
+func generateDiskLayer(db aquadb.Database, triedb *trie.Database, root common.Hash) *diskLayer {
+	batch := db.NewBatch()
+	batch.Put(snapshotRootKey, root[:])
+	batch.Put(snapshotGeneratorKey, []byte{})
+	if err := batch.Write(); err != nil {
+		log.Error("Failed to store snapshot generator", "err", err)
+	}
+	dl := newDiskLayer(db, triedb, nil, root, []byte{})
+	dl.startGeneration()
+	return dl
+}
+
+// covered reports whether the generator with the given marker has reached the
+// account with the given hash.
+func covered(marker []byte, hash common.Hash) bool {
+	return marker == nil || (len(marker) > 0 && bytes.Compare(hash[:], marker) <= 0)
+}
+
+// Root returns the state root the layer belongs to.
+func (dl *diskLayer) Root() common.Hash {
+	return dl.root
+}
+
+// Parent returns nil, there is no layer below the disk layer.
+func (dl *diskLayer) Parent() snapshot {
+	return nil
+}
+
+// Stale reports whether a newer disk layer replaced this one.
+func (dl *diskLayer) Stale() bool {
+	dl.lock.RLock()
+	defer dl.lock.RUnlock()
+
+	return dl.stale
+}
+
+func (dl *diskLayer) markStale() {
+	dl.lock.Lock()
+	defer dl.lock.Unlock()
+
+	dl.stale = true
+}
+
+// Account returns the account with the given address hash, or nil if it
+// does not exist.
+func (dl *diskLayer) Account(hash common.Hash) (*Account, error) {
+	data, err := dl.AccountRLP(hash)
+	if err != nil || len(data) == 0 {
+		return nil, err
+	}
+	account := new(Account)
+	if err := rlp.DecodeBytes(data, account); err != nil {
+		return nil, err
+	}
+	return account, nil
+}
+
+// AccountRLP returns the slim RLP encoding of the account with the given
+// address hash, or nil if it does not exist.
+func (dl *diskLayer) AccountRLP(hash common.Hash) ([]byte, error) {
+	dl.lock.RLock()
+	defer dl.lock.RUnlock()
+
+	if dl.stale {
+		return nil, ErrSnapshotStale
+	}
+	if !covered(dl.genMarker, hash) {
+		return nil, ErrNotCoveredYet
+	}
+	return dl.get(accountSnapshotKey(hash)), nil
+}
+
+// Storage returns the storage slot of an account, or nil if it is empty.
+func (dl *diskLayer) Storage(accountHash, storageHash common.Hash) ([]byte, error) {
+	dl.lock.RLock()
+	defer dl.lock.RUnlock()
+
+	if dl.stale {
+		return nil, ErrSnapshotStale
+	}
+	if !covered(dl.genMarker, accountHash) {
+		return nil, ErrNotCoveredYet
+	}
+	return dl.get(storageSnapshotKey(accountHash, storageHash)), nil
+}
+
+// get reads an entry through the cache, remembering absent entries as well.
+func (dl *diskLayer) get(key []byte) []byte {
+	if blob, ok := dl.cache.Get(string(key)); ok {
+		return blob.([]byte)
+	}
+	blob, _ := dl.db.Get(key)
+	dl.cache.Add(string(key), blob)
+	return blob
+}
+
+// apply writes a diff layer on top of this one into the database, returning
+// the resulting disk layer. This layer becomes stale once everything is
+// written. While generating, only the entries the generator already passed
+// are written, the generator picks up the rest from the new state trie.
+//
+// If writing fails, this layer stays usable and the diff can be applied again:
+// the writes are idempotent, and the diff layers above shadow any entry that
+// already reached the database.
+func (dl *diskLayer) apply(diff *diffLayer) (disk *diskLayer, err error) {
+	iteratee, ok := dl.db.(aquadb.Iteratee)
+	if !ok {
+		return nil, errors.New("database can't be iterated")
+	}
+	dl.stopGeneration()
+
+	dl.lock.Lock()
+	defer dl.lock.Unlock()
+	if dl.stale {
+		return nil, ErrSnapshotStale
+	}
+	marker := dl.genMarker
+	defer func() {
+		if err != nil && marker != nil {
+			dl.startGeneration()
+		}
+	}()
+
+	// Drop the root first, a crash halfway must not leave a snapshot that
+	// claims to belong to either state
+	batch := dl.db.NewBatch()
+	batch.Delete(snapshotRootKey)
+	flush := func() error {
+		if batch.ValueSize() < aquadb.IdealBatchSize {
+			return nil
+		}
+		if err := batch.Write(); err != nil {
+			return err
+		}
+		batch.Reset()
+		return nil
+	}
+	write := func(key, value []byte) error {
+		if len(value) == 0 {
+			batch.Delete(key)
+		} else {
+			batch.Put(key, value)
+		}
+		dl.cache.Add(string(key), value)
+		return flush()
+	}
+	for hash := range diff.destructs {
+		if !covered(marker, hash) {
+			continue
+		}
+		if err := write(accountSnapshotKey(hash), nil); err != nil {
+			return nil, err
+		}
+		it := iteratee.NewIteratorWithPrefix(storageSnapshotPrefix(hash))
+		for it.Next() {
+			if err := write(common.CopyBytes(it.Key()), nil); err != nil {
+				it.Release()
+				return nil, err
+			}
+		}
+		it.Release()
+	}
+	for hash, data := range diff.accounts {
+		if !covered(marker, hash) {
+			continue
+		}
+		if err := write(accountSnapshotKey(hash), data); err != nil {
+			return nil, err
+		}
+	}
+	for accountHash, slots := range diff.storage {
+		if !covered(marker, accountHash) {
+			continue
+		}
+		for storageHash, data := range slots {
+			if err := write(storageSnapshotKey(accountHash, storageHash), data); err != nil {
+				return nil, err
+			}
+		}
+	}
+	batch.Put(snapshotRootKey, diff.root[:])
+	if marker != nil {
+		batch.Put(snapshotGeneratorKey, marker)
+	}
+	if err := batch.Write(); err != nil {
+		return nil, err
+	}
+	dl.stale = true
+
+	disk = newDiskLayer(dl.db, dl.triedb, dl.cache, diff.root, marker)
+	if marker != nil {
+		disk.startGeneration()
+	}
+	return disk, nil
+}
+
+// startGeneration runs the generator in the background.
+func (dl *diskLayer) startGeneration() {
+	dl.genPending = make(chan struct{})
+	dl.genAbort = make(chan chan struct{})
+	go dl.generate(dl.genAbort)
+}
+
+// stopGeneration stops the generator, if running, after it saved its progress.
+func (dl *diskLayer) stopGeneration() {
+	dl.genLock.Lock()
+	defer dl.genLock.Unlock()
+
+	if dl.genAbort == nil {
+		return
+	}
+	stop := make(chan struct{})
+	dl.genAbort <- stop
+	<-stop
+	dl.genAbort = nil
+}
+
+// waitGeneration blocks until the generator stopped, returning the error it
+// failed with.
+func (dl *diskLayer) waitGeneration() error {
+	if dl.genPending == nil {
+		return nil
+	}
+	<-dl.genPending
+	return dl.genErr
+}
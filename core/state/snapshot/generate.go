@@ -0,0 +1,213 @@
+// Copyright 2018 The aquachain Authors
+// This file is part of the aquachain library.
+//
+// The aquachain library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The aquachain library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the aquachain library. If not, see <http://www.gnu.org/licenses/>.
+
+package snapshot
+
+import (
+	"errors"
+	"time"
+
+	"gitlab.com/aquachain/aquachain/aquadb"
+	"gitlab.com/aquachain/aquachain/common"
+	"gitlab.com/aquachain/aquachain/common/log"
+	"gitlab.com/aquachain/aquachain/rlp"
+	"gitlab.com/aquachain/aquachain/trie"
+)
+
+// Regenerate discards the snapshot stored in the database and generates the
+// snapshot of the given state root from the state trie, returning once done.
+func Regenerate(diskdb aquadb.Database, triedb *trie.Database, root common.Hash) error {
+	dl := generateDiskLayer(diskdb, triedb, root)
+	defer dl.stopGeneration()
+
+	return dl.waitGeneration()
+}
+
+// generate fills the snapshot from the state trie, continuing after the
+// generator marker, until done or stopped through abort. Either way it saves
+// its progress and waits for abort.
+func (dl *diskLayer) generate(abort chan chan struct{}) {
+	stop, err := dl.fill(abort)
+	if err != nil {
+		log.Error("State snapshot generation failed", "root", dl.root, "err", err)
+		dl.genErr = err
+	}
+	close(dl.genPending)
+	if stop == nil {
+		stop = <-abort
+	}
+	stop <- struct{}{}
+}
+
+// fill does the work of generate, returning the stop channel if aborted.
+func (dl *diskLayer) fill(abort chan chan struct{}) (chan struct{}, error) {
+	iteratee, ok := dl.db.(aquadb.Iteratee)
+	if !ok {
+		return nil, errors.New("database can't be iterated")
+	}
+	dl.lock.RLock()
+	marker := dl.genMarker
+	dl.lock.RUnlock()
+
+	var (
+		batch    = dl.db.NewBatch()
+		start    = time.Now()
+		logged   = time.Now()
+		accounts int
+		slots    int
+	)
+	// A fresh generation first wipes whatever an older snapshot left behind
+	if len(marker) == 0 {
+		for _, wipe := range []struct {
+			prefix []byte
+			length int
+		}{
+			{snapshotAccountPrefix, len(snapshotAccountPrefix) + common.HashLength},
+			{snapshotStoragePrefix, len(snapshotStoragePrefix) + 2*common.HashLength},
+		} {
+			it := iteratee.NewIteratorWithPrefix(wipe.prefix)
+			for it.Next() {
+				// other entries may share the prefix, trie nodes for one
+				if len(it.Key()) != wipe.length {
+					continue
+				}
+				batch.Delete(common.CopyBytes(it.Key()))
+				if batch.ValueSize() >= aquadb.IdealBatchSize {
+					if err := batch.Write(); err != nil {
+						it.Release()
+						return nil, err
+					}
+					batch.Reset()
+					select {
+					case stop := <-abort:
+						it.Release()
+						return stop, nil
+					default:
+					}
+				}
+			}
+			it.Release()
+			if err := it.Error(); err != nil {
+				return nil, err
+			}
+		}
+		if err := batch.Write(); err != nil {
+			return nil, err
+		}
+		batch.Reset()
+	}
+	tr, err := trie.NewSecure(dl.root, dl.triedb, 0)
+	if err != nil {
+		return nil, err
+	}
+	var startKey []byte
+	if len(marker) > 0 {
+		if startKey = nextKey(marker); startKey == nil {
+			// the last possible account was generated
+			return nil, dl.saveProgress(batch, nil)
+		}
+	}
+	accIt := trie.NewIterator(tr.NodeIterator(startKey))
+	for accIt.Next() {
+		accountHash := common.BytesToHash(accIt.Key)
+
+		var account Account
+		if err := rlp.DecodeBytes(accIt.Value, &account); err != nil {
+			return nil, err
+		}
+		root := common.BytesToHash(account.Root)
+		batch.Put(accountSnapshotKey(accountHash), SlimAccountRLP(account.Nonce, account.Balance, root, account.CodeHash))
+		accounts++
+
+		if root != emptyRoot {
+			storageTrie, err := trie.NewSecure(root, dl.triedb, 0)
+			if err != nil {
+				return nil, err
+			}
+			storageIt := trie.NewIterator(storageTrie.NodeIterator(nil))
+			for storageIt.Next() {
+				batch.Put(storageSnapshotKey(accountHash, common.BytesToHash(storageIt.Key)), storageIt.Value)
+				slots++
+
+				// Large storage tries are flushed early, the marker only
+				// moves past an account once all its storage is written
+				if batch.ValueSize() >= aquadb.IdealBatchSize {
+					if err := batch.Write(); err != nil {
+						return nil, err
+					}
+					batch.Reset()
+				}
+			}
+			if storageIt.Err != nil {
+				return nil, storageIt.Err
+			}
+		}
+		select {
+		case stop := <-abort:
+			return stop, dl.saveProgress(batch, accountHash[:])
+		default:
+		}
+		if batch.ValueSize() >= aquadb.IdealBatchSize {
+			if err := dl.saveProgress(batch, accountHash[:]); err != nil {
+				return nil, err
+			}
+		}
+		if time.Since(logged) > 8*time.Second {
+			log.Info("Generating state snapshot", "root", dl.root, "at", accountHash, "accounts", accounts, "slots", slots, "elapsed", common.PrettyDuration(time.Since(start)))
+			logged = time.Now()
+		}
+	}
+	if accIt.Err != nil {
+		return nil, accIt.Err
+	}
+	if err := dl.saveProgress(batch, nil); err != nil {
+		return nil, err
+	}
+	log.Info("Generated state snapshot", "root", dl.root, "accounts", accounts, "slots", slots, "elapsed", common.PrettyDuration(time.Since(start)))
+	return nil, nil
+}
+
+// saveProgress writes the batch along with the generator marker, and makes the
+// generated entries readable. A nil marker completes the generation.
+func (dl *diskLayer) saveProgress(batch aquadb.Batch, marker []byte) error {
+	if marker == nil {
+		batch.Delete(snapshotGeneratorKey)
+	} else {
+		marker = common.CopyBytes(marker)
+		batch.Put(snapshotGeneratorKey, marker)
+	}
+	if err := batch.Write(); err != nil {
+		return err
+	}
+	batch.Reset()
+
+	dl.lock.Lock()
+	dl.genMarker = marker
+	dl.lock.Unlock()
+	return nil
+}
+
+// nextKey returns the key following the given one in iteration order among
+// keys of the same length, or nil if there is none.
+func nextKey(key []byte) []byte {
+	next := common.CopyBytes(key)
+	for i := len(next) - 1; i >= 0; i-- {
+		if next[i]++; next[i] != 0 {
+			return next
+		}
+	}
+	return nil
+}
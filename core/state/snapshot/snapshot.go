@@ -0,0 +1,261 @@
+// Copyright 2018 The aquachain Authors
+// This file is part of the aquachain library.
+//
+// The aquachain library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The aquachain library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the aquachain library. If not, see <http://www.gnu.org/licenses/>.
+
+// Package snapshot implements a flat view of the account and storage tries,
+// allowing state reads without walking the Merkle Patricia tries.
+//
+// The snapshot of one state is kept on disk (the disk layer), keyed by the hash
+// of the account and storage slot. The changes of recent blocks are kept in
+// memory as diff layers on top of it, each identified by the state root after
+// the block. Diff layers deeper than a configured limit are flattened into the
+// disk layer as new blocks arrive.
+package snapshot
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+
+	"gitlab.com/aquachain/aquachain/aquadb"
+	"gitlab.com/aquachain/aquachain/common"
+	"gitlab.com/aquachain/aquachain/common/log"
+	"gitlab.com/aquachain/aquachain/trie"
+)
+
+var (
+	// ErrNotCoveredYet is returned for entries the snapshot generator did not
+	// reach yet. The caller should fall back to the state trie.
+	ErrNotCoveredYet = errors.New("not covered yet")
+
+	// ErrSnapshotStale is returned from layers that were flattened into a newer
+	// disk layer and may no longer be read.
+	ErrSnapshotStale = errors.New("snapshot stale")
+
+	// errSnapshotMissing is returned when updating on top of an unknown layer.
+	errSnapshotMissing = errors.New("snapshot missing")
+)
+
+var (
+	snapshotRootKey      = []byte("SnapshotRoot")      // snapshotRootKey -> state root of the disk layer
+	snapshotGeneratorKey = []byte("SnapshotGenerator") // snapshotGeneratorKey -> last generated account hash, absent when done
+
+	snapshotAccountPrefix = []byte("sa") // snapshotAccountPrefix + account hash -> slim account
+	snapshotStoragePrefix = []byte("ss") // snapshotStoragePrefix + account hash + slot hash -> slot value
+)
+
+// accountSnapshotKey = snapshotAccountPrefix + hash
+func accountSnapshotKey(hash common.Hash) []byte {
+	return append(append([]byte{}, snapshotAccountPrefix...), hash.Bytes()...)
+}
+
+// storageSnapshotKey = snapshotStoragePrefix + account hash + slot hash
+func storageSnapshotKey(accountHash, storageHash common.Hash) []byte {
+	return append(storageSnapshotPrefix(accountHash), storageHash.Bytes()...)
+}
+
+// storageSnapshotPrefix = snapshotStoragePrefix + account hash
+func storageSnapshotPrefix(accountHash common.Hash) []byte {
+	return append(append([]byte{}, snapshotStoragePrefix...), accountHash.Bytes()...)
+}
+
+// Snapshot is a flat view of the state at one state root.
+type Snapshot interface {
+	// Root returns the state root the snapshot belongs to.
+	Root() common.Hash
+
+	// Account returns the account with the given address hash, or nil if it
+	// does not exist.
+	Account(hash common.Hash) (*Account, error)
+
+	// AccountRLP returns the slim RLP encoding of the account with the given
+	// address hash, or nil if it does not exist.
+	AccountRLP(hash common.Hash) ([]byte, error)
+
+	// Storage returns the storage slot of an account, encoded as in the storage
+	// trie, or nil if the slot is empty.
+	Storage(accountHash, storageHash common.Hash) ([]byte, error)
+}
+
+// snapshot is the internal interface of disk and diff layers.
+type snapshot interface {
+	Snapshot
+
+	// Parent returns the layer below, or nil for the disk layer.
+	Parent() snapshot
+
+	// Stale reports whether the layer was flattened and may no longer be used.
+	Stale() bool
+}
+
+// Tree is the collection of snapshot layers: the disk layer and the diff
+// layers of recent blocks on top of it, possibly forming several branches.
+type Tree struct {
+	diskdb aquadb.Database
+	triedb *trie.Database
+	layers map[common.Hash]snapshot // All live layers by state root
+	lock   sync.RWMutex
+}
+
+// New opens the snapshot stored in the database. If it doesn't belong to the
+// given state root, it is discarded and regenerated from the state trie in the
+// background, unless async is false, in which case New waits for the
+// generation to finish.
+func New(diskdb aquadb.Database, triedb *trie.Database, root common.Hash, async bool) *Tree {
+	t := &Tree{
+		diskdb: diskdb,
+		triedb: triedb,
+		layers: make(map[common.Hash]snapshot),
+	}
+	disk, err := loadDiskLayer(diskdb, triedb, root)
+	if err != nil {
+		log.Warn("Regenerating state snapshot", "root", root, "reason", err)
+		disk = generateDiskLayer(diskdb, triedb, root)
+	}
+	if !async {
+		disk.waitGeneration()
+	}
+	t.layers[root] = disk
+	return t
+}
+
+// Snapshot returns the layer of the given state root, or nil if there is none.
+func (t *Tree) Snapshot(root common.Hash) Snapshot {
+	t.lock.RLock()
+	defer t.lock.RUnlock()
+
+	if layer, ok := t.layers[root]; ok {
+		return layer
+	}
+	return nil
+}
+
+// DiskRoot returns the state root of the disk layer.
+func (t *Tree) DiskRoot() common.Hash {
+	t.lock.RLock()
+	defer t.lock.RUnlock()
+
+	return t.disk().Root()
+}
+
+// disk returns the disk layer. The tree lock must be held.
+func (t *Tree) disk() *diskLayer {
+	for _, layer := range t.layers {
+		for ; layer.Parent() != nil; layer = layer.Parent() {
+		}
+		return layer.(*diskLayer)
+	}
+	return nil
+}
+
+// Update adds a diff layer for the state root blockRoot on top of the layer of
+// parentRoot. Destructed accounts lose all storage before the account and
+// storage changes apply; nil values mark deleted entries.
+func (t *Tree) Update(blockRoot, parentRoot common.Hash, destructs map[common.Hash]struct{}, accounts map[common.Hash][]byte, storage map[common.Hash]map[common.Hash][]byte) error {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	if _, ok := t.layers[blockRoot]; ok {
+		// same state reached twice, e.g. by sibling blocks
+		return nil
+	}
+	parent, ok := t.layers[parentRoot]
+	if !ok {
+		return fmt.Errorf("%v: parent %x", errSnapshotMissing, parentRoot)
+	}
+	t.layers[blockRoot] = newDiffLayer(parent, blockRoot, destructs, accounts, storage)
+	return nil
+}
+
+// Cap flattens the diff layers below the given state root into the disk layer
+// until at most the given number of diff layers remains on top of it. Layers
+// of branches that don't descend from the new disk layer are discarded.
+func (t *Tree) Cap(root common.Hash, layers int) error {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	layer, ok := t.layers[root]
+	if !ok {
+		return fmt.Errorf("%v: %x", errSnapshotMissing, root)
+	}
+	var path []*diffLayer // from root down to the layer above the disk
+	for ; layer.Parent() != nil; layer = layer.Parent() {
+		path = append(path, layer.(*diffLayer))
+	}
+	if len(path) <= layers {
+		return nil
+	}
+	for len(path) > layers {
+		bottom := path[len(path)-1]
+		path = path[:len(path)-1]
+
+		disk, err := bottom.flatten()
+		if err != nil {
+			return err
+		}
+		// Children of the flattened layer now sit on the disk layer
+		for _, layer := range t.layers {
+			if diff, ok := layer.(*diffLayer); ok && diff.Parent() == bottom {
+				diff.setParent(disk)
+			}
+		}
+		t.layers[disk.root] = disk
+	}
+	// Drop everything not built on the current disk layer
+	for root, layer := range t.layers {
+		for ; layer.Parent() != nil && !layer.Stale(); layer = layer.Parent() {
+		}
+		if layer.Stale() {
+			delete(t.layers, root)
+		}
+	}
+	return nil
+}
+
+// Rebuild discards all layers and regenerates the snapshot of the given state
+// root from the state trie in the background.
+func (t *Tree) Rebuild(root common.Hash) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	for _, layer := range t.layers {
+		switch layer := layer.(type) {
+		case *diskLayer:
+			layer.stopGeneration()
+			layer.markStale()
+		case *diffLayer:
+			layer.markStale()
+		}
+	}
+	log.Info("Rebuilding state snapshot", "root", root)
+	t.layers = map[common.Hash]snapshot{root: generateDiskLayer(t.diskdb, t.triedb, root)}
+}
+
+// Persist flattens all diff layers below the given state root into the disk
+// layer and stops a running generator, saving its progress. The tree must not
+// be used afterwards.
+func (t *Tree) Persist(root common.Hash) error {
+	var err error
+	if t.Snapshot(root) != nil {
+		err = t.Cap(root, 0)
+	}
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	if disk := t.disk(); disk != nil {
+		disk.stopGeneration()
+	}
+	return err
+}
@@ -0,0 +1,376 @@
+// Copyright 2018 The aquachain Authors
+// This file is part of the aquachain library.
+//
+// The aquachain library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The aquachain library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the aquachain library. If not, see <http://www.gnu.org/licenses/>.
+
+package snapshot
+
+import (
+	"bytes"
+	"errors"
+	"math/big"
+	"testing"
+
+	"gitlab.com/aquachain/aquachain/aquadb"
+	"gitlab.com/aquachain/aquachain/common"
+	"gitlab.com/aquachain/aquachain/crypto"
+	"gitlab.com/aquachain/aquachain/rlp"
+	"gitlab.com/aquachain/aquachain/trie"
+)
+
+// testStorage returns the storage slots of the i-th test account by slot key,
+// every third account has some.
+func testStorage(i int) map[common.Hash][]byte {
+	if i%3 != 0 {
+		return nil
+	}
+	slots := make(map[common.Hash][]byte)
+	for j := 1; j <= i%7+1; j++ {
+		slots[common.Hash{byte(j)}], _ = rlp.EncodeToBytes([]byte{byte(i), byte(j)})
+	}
+	return slots
+}
+
+// makeState writes a state of the first n test accounts to the database and
+// returns its root.
+func makeState(t *testing.T, db aquadb.Database, n int) common.Hash {
+	triedb := trie.NewDatabase(db)
+	accTrie, _ := trie.NewSecure(common.Hash{}, triedb, 0)
+	for i := 0; i < n; i++ {
+		account := Account{Nonce: uint64(i), Balance: big.NewInt(int64(i) * 100), Root: emptyRoot[:], CodeHash: emptyCode[:]}
+		if slots := testStorage(i); slots != nil {
+			storageTrie, _ := trie.NewSecure(common.Hash{}, triedb, 0)
+			for key, value := range slots {
+				storageTrie.TryUpdate(key[:], value)
+			}
+			root, err := storageTrie.Commit(nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			triedb.Commit(root, false)
+			account.Root = root[:]
+			account.CodeHash = crypto.Keccak256([]byte{byte(i)})
+		}
+		data, _ := rlp.EncodeToBytes(account)
+		accTrie.TryUpdate(common.BytesToAddress([]byte{byte(i), byte(i >> 8)}).Bytes(), data)
+	}
+	root, err := accTrie.Commit(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := triedb.Commit(root, false); err != nil {
+		t.Fatal(err)
+	}
+	return root
+}
+
+func addrHash(i int) common.Hash {
+	return crypto.Keccak256Hash(common.BytesToAddress([]byte{byte(i), byte(i >> 8)}).Bytes())
+}
+
+func slotHash(j int) common.Hash {
+	return crypto.Keccak256Hash(common.Hash{byte(j)}.Bytes())
+}
+
+func TestGenerateAndVerify(t *testing.T) {
+	db := aquadb.NewMemDatabase()
+	root := makeState(t, db, 300)
+	triedb := trie.NewDatabase(db)
+
+	// Leftovers of an older snapshot must go, other entries must stay
+	db.Put(accountSnapshotKey(common.Hash{0xff}), []byte{0x01})
+	node := append([]byte{}, snapshotAccountPrefix...)
+	node = append(node, bytes.Repeat([]byte{0x01}, common.HashLength-len(node))...)
+	db.Put(node, []byte("trie node"))
+
+	if err := Regenerate(db, triedb, root); err != nil {
+		t.Fatal(err)
+	}
+	accounts, slots, err := Verify(db, triedb, root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if accounts != 300 || slots == 0 {
+		t.Fatalf("verified %d accounts, %d slots", accounts, slots)
+	}
+	if ok, _ := db.Has(node); !ok {
+		t.Fatal("generation wiped an unrelated entry")
+	}
+	snaps := New(db, triedb, root, false)
+	snap := snaps.Snapshot(root)
+	acc, err := snap.Account(addrHash(3))
+	if err != nil || acc == nil || acc.Nonce != 3 || acc.Balance.Uint64() != 300 {
+		t.Fatalf("wrong account %+v, %v", acc, err)
+	}
+	if acc, err := snap.Account(addrHash(1000)); acc != nil || err != nil {
+		t.Fatalf("missing account returned %+v, %v", acc, err)
+	}
+	want, _ := rlp.EncodeToBytes([]byte{3, 1})
+	if have, err := snap.Storage(addrHash(3), slotHash(1)); err != nil || !bytes.Equal(have, want) {
+		t.Fatalf("wrong storage %x, %v", have, err)
+	}
+
+	// Any difference to the trie is found
+	key := storageSnapshotKey(addrHash(3), slotHash(1))
+	db.Put(key, []byte{0x01})
+	if _, _, err := Verify(db, triedb, root); err == nil {
+		t.Error("changed storage slot not found")
+	}
+	db.Put(key, want)
+	db.Put(storageSnapshotKey(addrHash(1000), slotHash(1)), want)
+	if _, _, err := Verify(db, triedb, root); err == nil {
+		t.Error("dangling storage slot not found")
+	}
+	db.Delete(storageSnapshotKey(addrHash(1000), slotHash(1)))
+	db.Delete(accountSnapshotKey(addrHash(7)))
+	if _, _, err := Verify(db, triedb, root); err == nil {
+		t.Error("missing account not found")
+	}
+	if _, _, err := Verify(db, triedb, common.Hash{0x01}); err == nil {
+		t.Error("snapshot of another state verified")
+	}
+}
+
+func TestGenerateResume(t *testing.T) {
+	db := aquadb.NewMemDatabase()
+	root := makeState(t, db, 200)
+	triedb := trie.NewDatabase(db)
+	if err := Regenerate(db, triedb, root); err != nil {
+		t.Fatal(err)
+	}
+	// Pretend generation stopped halfway
+	marker := common.Hash{0x80}
+	for i := 0; i < 200; i++ {
+		if hash := addrHash(i); bytes.Compare(hash[:], marker[:]) > 0 {
+			db.Delete(accountSnapshotKey(hash))
+			for j := 1; j <= 7; j++ {
+				db.Delete(storageSnapshotKey(hash, slotHash(j)))
+			}
+		}
+	}
+	db.Put(snapshotGeneratorKey, marker[:])
+
+	// Stopping right away saves the progress, the next start completes it
+	New(db, triedb, root, true).Persist(root)
+	New(db, triedb, root, false)
+	if _, complete := Root(db); !complete {
+		t.Fatal("generation not finished")
+	}
+	if _, _, err := Verify(db, triedb, root); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestFlattenWhileGenerating(t *testing.T) {
+	db := aquadb.NewMemDatabase()
+	root := makeState(t, db, 50)
+	next := makeState(t, db, 80) // the block adds the accounts 50..79
+	triedb := trie.NewDatabase(db)
+	if err := Regenerate(db, triedb, root); err != nil {
+		t.Fatal(err)
+	}
+	var (
+		accounts = make(map[common.Hash][]byte)
+		storage  = make(map[common.Hash]map[common.Hash][]byte)
+	)
+	for i := 50; i < 80; i++ {
+		root, codehash := emptyRoot, emptyCode[:]
+		if slots := testStorage(i); slots != nil {
+			storageTrie, _ := trie.NewSecure(common.Hash{}, trie.NewDatabase(aquadb.NewMemDatabase()), 0)
+			storage[addrHash(i)] = make(map[common.Hash][]byte)
+			for key, value := range slots {
+				storageTrie.TryUpdate(key[:], value)
+				storage[addrHash(i)][crypto.Keccak256Hash(key[:])] = value
+			}
+			root, codehash = storageTrie.Hash(), crypto.Keccak256([]byte{byte(i)})
+		}
+		accounts[addrHash(i)] = SlimAccountRLP(uint64(i), big.NewInt(int64(i)*100), root, codehash)
+	}
+	// The generator is halfway, it takes the accounts above its marker
+	// from the new state trie
+	snaps := &Tree{
+		diskdb: db,
+		triedb: triedb,
+		layers: map[common.Hash]snapshot{root: newDiskLayer(db, triedb, nil, root, common.Hash{0x7f, 0xff}.Bytes())},
+	}
+	if err := snaps.Update(next, root, nil, accounts, storage); err != nil {
+		t.Fatal(err)
+	}
+	if err := snaps.Cap(next, 0); err != nil {
+		t.Fatal(err)
+	}
+	if err := snaps.disk().waitGeneration(); err != nil {
+		t.Fatal(err)
+	}
+	snaps.Persist(next)
+	if _, _, err := Verify(db, triedb, next); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestDiffLayers(t *testing.T) {
+	db := aquadb.NewMemDatabase()
+	root := makeState(t, db, 50)
+	triedb := trie.NewDatabase(db)
+	snaps := New(db, triedb, root, false)
+
+	// Block 1 changes an account and a slot, destructs account 3 and gives
+	// it new storage
+	var (
+		root1 = common.Hash{0x01}
+		root2 = common.Hash{0x02}
+		side  = common.Hash{0x03}
+		acc1  = SlimAccountRLP(100, big.NewInt(1), emptyRoot, emptyCode[:])
+		slot  = []byte{0x82, 0x01, 0x02}
+	)
+	err := snaps.Update(root1, root,
+		map[common.Hash]struct{}{addrHash(3): {}},
+		map[common.Hash][]byte{addrHash(1): acc1},
+		map[common.Hash]map[common.Hash][]byte{
+			addrHash(3): {slotHash(2): slot},
+			addrHash(6): {slotHash(1): nil},
+		})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := snaps.Update(root2, root1, nil, map[common.Hash][]byte{addrHash(2): acc1}, nil); err != nil {
+		t.Fatal(err)
+	}
+	if err := snaps.Update(side, root, nil, map[common.Hash][]byte{addrHash(2): acc1}, nil); err != nil {
+		t.Fatal(err)
+	}
+	if err := snaps.Update(common.Hash{0x04}, common.Hash{0x05}, nil, nil, nil); err == nil {
+		t.Fatal("update on unknown parent accepted")
+	}
+	check := func(snap Snapshot) {
+		t.Helper()
+		if acc, err := snap.Account(addrHash(1)); err != nil || acc.Nonce != 100 {
+			t.Fatalf("changed account: %+v, %v", acc, err)
+		}
+		if acc, err := snap.Account(addrHash(4)); err != nil || acc.Nonce != 4 {
+			t.Fatalf("unchanged account: %+v, %v", acc, err)
+		}
+		if acc, err := snap.Account(addrHash(3)); err != nil || acc != nil {
+			t.Fatalf("destructed account: %+v, %v", acc, err)
+		}
+		if data, err := snap.Storage(addrHash(3), slotHash(1)); err != nil || data != nil {
+			t.Fatalf("destructed storage: %x, %v", data, err)
+		}
+		if data, err := snap.Storage(addrHash(3), slotHash(2)); err != nil || !bytes.Equal(data, slot) {
+			t.Fatalf("recreated storage: %x, %v", data, err)
+		}
+		if data, err := snap.Storage(addrHash(6), slotHash(1)); err != nil || data != nil {
+			t.Fatalf("deleted storage: %x, %v", data, err)
+		}
+		if data, err := snap.Storage(addrHash(6), slotHash(2)); err != nil || data == nil {
+			t.Fatalf("unchanged storage: %x, %v", data, err)
+		}
+	}
+	check(snaps.Snapshot(root2))
+	old := snaps.Snapshot(root)
+
+	// Flattening the first block keeps the reads, drops the side branch
+	if err := snaps.Cap(root2, 1); err != nil {
+		t.Fatal(err)
+	}
+	if snaps.DiskRoot() != root1 {
+		t.Fatalf("wrong disk root %x", snaps.DiskRoot())
+	}
+	check(snaps.Snapshot(root2))
+	check(snaps.Snapshot(root1))
+	if snaps.Snapshot(side) != nil || snaps.Snapshot(root) != nil {
+		t.Fatal("stale layers kept")
+	}
+	if _, err := old.Account(addrHash(1)); err != ErrSnapshotStale {
+		t.Fatalf("stale layer read: %v", err)
+	}
+	if err := snaps.Persist(root2); err != nil {
+		t.Fatal(err)
+	}
+	if stored, complete := Root(db); stored != root2 || !complete {
+		t.Fatalf("wrong stored root %x", stored)
+	}
+	if data, _ := db.Get(storageSnapshotKey(addrHash(3), slotHash(1))); data != nil {
+		t.Fatal("destructed storage left on disk")
+	}
+	// Reopening on another root regenerates
+	snaps = New(db, triedb, root, false)
+	if _, _, err := Verify(db, triedb, root); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// failingDatabase fails batch writes while fail is set
+type failingDatabase struct {
+	*aquadb.MemDatabase
+	fail bool
+}
+
+type failingBatch struct {
+	aquadb.Batch
+	db *failingDatabase
+}
+
+func (db *failingDatabase) NewBatch() aquadb.Batch {
+	return &failingBatch{db.MemDatabase.NewBatch(), db}
+}
+
+func (b *failingBatch) Write() error {
+	if b.db.fail {
+		return errors.New("write failed")
+	}
+	return b.Batch.Write()
+}
+
+func TestFlattenWriteFailure(t *testing.T) {
+	db := &failingDatabase{MemDatabase: aquadb.NewMemDatabase()}
+	root := makeState(t, db, 50)
+	triedb := trie.NewDatabase(db)
+	snaps := New(db, triedb, root, false)
+
+	var (
+		root1 = common.Hash{0x01}
+		acc1  = SlimAccountRLP(100, big.NewInt(1), emptyRoot, emptyCode[:])
+	)
+	if err := snaps.Update(root1, root, nil, map[common.Hash][]byte{addrHash(1): acc1}, nil); err != nil {
+		t.Fatal(err)
+	}
+	disk := snaps.Snapshot(root)
+
+	// A failed flatten leaves the disk layer in place
+	db.fail = true
+	if err := snaps.Cap(root1, 0); err == nil {
+		t.Fatal("flatten succeeded with failing database")
+	}
+	if snaps.DiskRoot() != root {
+		t.Fatal("disk layer replaced after failed flatten")
+	}
+	if acc, err := disk.Account(addrHash(2)); err != nil || acc.Nonce != 2 {
+		t.Fatalf("disk layer read after failed flatten: %+v, %v", acc, err)
+	}
+	// and flattening again succeeds once the database recovers
+	db.fail = false
+	if err := snaps.Cap(root1, 0); err != nil {
+		t.Fatal(err)
+	}
+	if snaps.DiskRoot() != root1 {
+		t.Fatal("disk layer not replaced")
+	}
+	if _, err := disk.Account(addrHash(2)); err != ErrSnapshotStale {
+		t.Fatalf("old disk layer read: %v", err)
+	}
+	if acc, err := snaps.Snapshot(root1).Account(addrHash(1)); err != nil || acc.Nonce != 100 {
+		t.Fatalf("flattened account: %+v, %v", acc, err)
+	}
+}
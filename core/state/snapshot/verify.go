@@ -0,0 +1,176 @@
+// Copyright 2018 The aquachain Authors
+// This file is part of the aquachain library.
+//
+// The aquachain library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The aquachain library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the aquachain library. If not, see <http://www.gnu.org/licenses/>.
+
+package snapshot
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"time"
+
+	"gitlab.com/aquachain/aquachain/aquadb"
+	"gitlab.com/aquachain/aquachain/common"
+	"gitlab.com/aquachain/aquachain/common/log"
+	"gitlab.com/aquachain/aquachain/rlp"
+	"gitlab.com/aquachain/aquachain/trie"
+)
+
+// Root returns the state root of the snapshot stored in the database, and
+// whether its generation is complete.
+func Root(diskdb aquadb.Database) (root common.Hash, complete bool) {
+	stored, _ := diskdb.Get(snapshotRootKey)
+	if len(stored) == 0 {
+		return common.Hash{}, false
+	}
+	generating, _ := diskdb.Has(snapshotGeneratorKey)
+	return common.BytesToHash(stored), !generating
+}
+
+// Verify compares the snapshot stored in the database against the state trie
+// of the given root, entry by entry, returning the number of accounts and
+// storage slots checked.
+func Verify(diskdb aquadb.Database, triedb *trie.Database, root common.Hash) (accounts, slots int, err error) {
+	iteratee, ok := diskdb.(aquadb.Iteratee)
+	if !ok {
+		return 0, 0, errors.New("database can't be iterated")
+	}
+	switch stored, complete := Root(diskdb); {
+	case stored == (common.Hash{}):
+		return 0, 0, errors.New("no state snapshot")
+	case stored != root:
+		return 0, 0, fmt.Errorf("snapshot belongs to state %x", stored)
+	case !complete:
+		return 0, 0, errors.New("snapshot generation not finished")
+	}
+	tr, err := trie.NewSecure(root, triedb, 0)
+	if err != nil {
+		return 0, 0, err
+	}
+	var (
+		start  = time.Now()
+		logged = time.Now()
+		accIt  = trie.NewIterator(tr.NodeIterator(nil))
+		snapIt = iteratee.NewIteratorWithPrefix(snapshotAccountPrefix)
+	)
+	defer snapIt.Release()
+
+	for accIt.Next() {
+		hash := common.BytesToHash(accIt.Key)
+		if err := matchEntry(snapIt, snapshotAccountPrefix, hash); err != nil {
+			return accounts, slots, fmt.Errorf("account %v", err)
+		}
+		full, err := FullAccountRLP(snapIt.Value())
+		if err != nil {
+			return accounts, slots, fmt.Errorf("account %x: %v", hash, err)
+		}
+		if !bytes.Equal(full, accIt.Value) {
+			return accounts, slots, fmt.Errorf("account %x differs from state trie", hash)
+		}
+		var account Account
+		if err := rlp.DecodeBytes(accIt.Value, &account); err != nil {
+			return accounts, slots, err
+		}
+		n, err := verifyStorage(iteratee, triedb, hash, common.BytesToHash(account.Root))
+		if err != nil {
+			return accounts, slots, fmt.Errorf("account %x: %v", hash, err)
+		}
+		accounts++
+		slots += n
+
+		if time.Since(logged) > 8*time.Second {
+			log.Info("Verifying state snapshot", "at", hash, "accounts", accounts, "slots", slots, "elapsed", common.PrettyDuration(time.Since(start)))
+			logged = time.Now()
+		}
+	}
+	if accIt.Err != nil {
+		return accounts, slots, accIt.Err
+	}
+	if nextEntry(snapIt, len(snapshotAccountPrefix)+common.HashLength) {
+		return accounts, slots, fmt.Errorf("dangling account %x in snapshot", snapIt.Key()[len(snapshotAccountPrefix):])
+	}
+	// Storage of accounts that don't exist is not reached above
+	total := 0
+	it := iteratee.NewIteratorWithPrefix(snapshotStoragePrefix)
+	for nextEntry(it, len(snapshotStoragePrefix)+2*common.HashLength) {
+		total++
+	}
+	it.Release()
+	if total != slots {
+		return accounts, slots, fmt.Errorf("%d dangling storage slots in snapshot", total-slots)
+	}
+	return accounts, slots, nil
+}
+
+// verifyStorage compares the storage of one account in the snapshot against
+// its storage trie.
+func verifyStorage(iteratee aquadb.Iteratee, triedb *trie.Database, accountHash, root common.Hash) (int, error) {
+	prefix := storageSnapshotPrefix(accountHash)
+	snapIt := iteratee.NewIteratorWithPrefix(prefix)
+	defer snapIt.Release()
+
+	slots := 0
+	if root != emptyRoot {
+		tr, err := trie.NewSecure(root, triedb, 0)
+		if err != nil {
+			return 0, err
+		}
+		it := trie.NewIterator(tr.NodeIterator(nil))
+		for it.Next() {
+			hash := common.BytesToHash(it.Key)
+			if err := matchEntry(snapIt, prefix, hash); err != nil {
+				return slots, fmt.Errorf("storage slot %v", err)
+			}
+			if !bytes.Equal(snapIt.Value(), it.Value) {
+				return slots, fmt.Errorf("storage slot %x differs from storage trie", hash)
+			}
+			slots++
+		}
+		if it.Err != nil {
+			return slots, it.Err
+		}
+	}
+	if nextEntry(snapIt, len(prefix)+common.HashLength) {
+		return slots, fmt.Errorf("dangling storage slot %x in snapshot", snapIt.Key()[len(prefix):])
+	}
+	return slots, nil
+}
+
+// nextEntry moves the iterator to the next snapshot entry, skipping other
+// entries sharing the prefix.
+func nextEntry(it aquadb.Iterator, length int) bool {
+	for it.Next() {
+		if len(it.Key()) == length {
+			return true
+		}
+	}
+	return false
+}
+
+// matchEntry moves the iterator to the next snapshot entry and checks that it
+// is the one of the given hash.
+func matchEntry(it aquadb.Iterator, prefix []byte, hash common.Hash) error {
+	if !nextEntry(it, len(prefix)+common.HashLength) {
+		return fmt.Errorf("%x missing from snapshot", hash)
+	}
+	switch have := common.BytesToHash(it.Key()[len(prefix):]); bytes.Compare(have[:], hash[:]) {
+	case -1:
+		return fmt.Errorf("%x dangling in snapshot", have)
+	case 1:
+		return fmt.Errorf("%x missing from snapshot", hash)
+	}
+	return nil
+}
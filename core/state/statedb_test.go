@@ -32,6 +32,7 @@ import (
 
 	"gitlab.com/aquachain/aquachain/aquadb"
 	"gitlab.com/aquachain/aquachain/common"
+	"gitlab.com/aquachain/aquachain/core/state/snapshot"
 	"gitlab.com/aquachain/aquachain/core/types"
 )
 
@@ -168,6 +169,42 @@ func TestCopy(t *testing.T) {
 	}
 }
 
+// Tests that a copied state keeps the storage values flushed into the trie of
+// the original, instead of reading stale values from the snapshot.
+func TestCopyFlushedStorage(t *testing.T) {
+	var (
+		diskdb = aquadb.NewMemDatabase()
+		sdb    = NewDatabase(diskdb)
+		addr   = common.BytesToAddress([]byte{0x01})
+		key    = common.BytesToHash([]byte{0x02})
+	)
+	// Commit an account with a storage slot and build a snapshot of it
+	orig, _ := New(common.Hash{}, sdb)
+	orig.SetState(addr, key, common.BytesToHash([]byte{0x01}))
+	root, err := orig.Commit(false)
+	if err != nil {
+		t.Fatalf("failed to commit state: %v", err)
+	}
+	if err := sdb.TrieDB().Commit(root, false); err != nil {
+		t.Fatalf("failed to commit trie: %v", err)
+	}
+	snaps := snapshot.New(diskdb, sdb.TrieDB(), root, false)
+
+	// Update the slot, flush it into the trie and copy the state
+	state, err := NewWithSnapshot(root, sdb, snaps)
+	if err != nil {
+		t.Fatalf("failed to open state: %v", err)
+	}
+	want := common.BytesToHash([]byte{0x02})
+	state.SetState(addr, key, want)
+	state.IntermediateRoot(false)
+
+	copy := state.Copy()
+	if have := copy.GetState(addr, key); have != want {
+		t.Fatalf("copied storage mismatch: have %x, want %x", have, want)
+	}
+}
+
 func TestSnapshotRandom(t *testing.T) {
 	config := &quick.Config{MaxCount: 1000}
 	err := quick.Check((*snapshotTest).run, config)
@@ -421,3 +458,57 @@ func (s *StateSuite) TestTouchDelete(c *check.C) {
 		c.Fatal("expected no dirty state object")
 	}
 }
+
+// Tests that SetStorage replaces the entire storage of an account, hiding any
+// slots that were committed before, and that copies keep the fake storage.
+func TestSetStorage(t *testing.T) {
+	db := NewDatabase(aquadb.NewMemDatabase())
+	state, _ := New(common.Hash{}, db)
+
+	addr := common.BytesToAddress([]byte{0x01})
+	state.SetState(addr, common.Hash{1}, common.Hash{0xaa})
+	root, _ := state.Commit(false)
+	state, _ = New(root, db)
+
+	state.SetStorage(addr, map[common.Hash]common.Hash{{2}: {0xbb}})
+	if got := state.GetState(addr, common.Hash{1}); got != (common.Hash{}) {
+		t.Errorf("overridden slot still visible: %x", got)
+	}
+	if got := state.GetState(addr, common.Hash{2}); got != (common.Hash{0xbb}) {
+		t.Errorf("fake slot mismatch: have %x, want %x", got, common.Hash{0xbb})
+	}
+	state.SetState(addr, common.Hash{3}, common.Hash{0xcc})
+	copy := state.Copy()
+	if got := copy.GetState(addr, common.Hash{3}); got != (common.Hash{0xcc}) {
+		t.Errorf("copied slot mismatch: have %x, want %x", got, common.Hash{0xcc})
+	}
+	if got := copy.GetState(addr, common.Hash{1}); got != (common.Hash{}) {
+		t.Errorf("overridden slot visible in copy: %x", got)
+	}
+}
+
+// Tests that writes to overridden storage, and the override itself, are undone
+// when reverting to an earlier snapshot.
+func TestSetStorageRevert(t *testing.T) {
+	state, _ := New(common.Hash{}, NewDatabase(aquadb.NewMemDatabase()))
+	addr := common.BytesToAddress([]byte{0x01})
+
+	state.SetState(addr, common.Hash{1}, common.Hash{0xaa})
+	outer := state.Snapshot()
+	state.SetStorage(addr, map[common.Hash]common.Hash{{2}: {0xbb}})
+
+	inner := state.Snapshot()
+	state.SetState(addr, common.Hash{2}, common.Hash{0xcc})
+	state.SetState(addr, common.Hash{3}, common.Hash{0xdd})
+	state.RevertToSnapshot(inner)
+	if got := state.GetState(addr, common.Hash{2}); got != (common.Hash{0xbb}) {
+		t.Errorf("reverted write kept: have %x, want %x", got, common.Hash{0xbb})
+	}
+	if got := state.GetState(addr, common.Hash{3}); got != (common.Hash{}) {
+		t.Errorf("reverted slot kept: %x", got)
+	}
+	state.RevertToSnapshot(outer)
+	if got := state.GetState(addr, common.Hash{1}); got != (common.Hash{0xaa}) {
+		t.Errorf("override not reverted: have %x, want %x", got, common.Hash{0xaa})
+	}
+}
@@ -21,6 +21,7 @@ import (
 	"math"
 	"math/big"
 	"sort"
+	"time"
 
 	"gitlab.com/aquachain/aquachain/common"
 	"gitlab.com/aquachain/aquachain/common/log"
@@ -506,3 +507,161 @@ func (l *txPricedList) Discard(count int, local *accountSet) types.Transactions
 	}
 	return drop
 }
+
+// scoredTx is a transaction tagged with its eviction rank.
+type scoredTx struct {
+	tx      *types.Transaction
+	arrival time.Time
+	rank    float64
+}
+
+// scoreHeap is a heap.Interface implementation over ranked transactions for
+// retrieving the lowest scoring ones to discard when the pool fills up.
+type scoreHeap []scoredTx
+
+func (h scoreHeap) Len() int           { return len(h) }
+func (h scoreHeap) Less(i, j int) bool { return h[i].rank < h[j].rank }
+func (h scoreHeap) Swap(i, j int)      { h[i], h[j] = h[j], h[i] }
+
+func (h *scoreHeap) Push(x interface{}) {
+	*h = append(*h, x.(scoredTx))
+}
+
+func (h *scoreHeap) Pop() interface{} {
+	old := *h
+	n := len(old)
+	x := old[n-1]
+	*h = old[0 : n-1]
+	return x
+}
+
+// txScoredList is an eviction score sorted heap to allow operating on the
+// transaction pool contents in a score-incrementing way.
+//
+// The score of a transaction is its gas price per byte, halving with every
+// lifetime it spends in the pool. As all scores decay at the same rate, their
+// order never changes, so transactions are ranked once by the logarithm of
+// their score at a fixed epoch.
+type txScoredList struct {
+	all      *map[common.Hash]*types.Transaction // Pointer to the map of all transactions
+	arrivals *map[common.Hash]time.Time          // Pointer to the arrival times of all transactions
+	items    *scoreHeap                          // Heap of ranks of all the stored transactions
+	stales   int                                 // Number of stale rank points to (re-heap trigger)
+	lifetime time.Duration                       // Period over which scores halve (0 = no decay)
+	epoch    time.Time                           // Reference time of the ranks
+}
+
+// newTxScoredList creates a new score-sorted transaction heap.
+func newTxScoredList(all *map[common.Hash]*types.Transaction, arrivals *map[common.Hash]time.Time, lifetime time.Duration) *txScoredList {
+	return &txScoredList{
+		all:      all,
+		arrivals: arrivals,
+		items:    new(scoreHeap),
+		lifetime: lifetime,
+		epoch:    time.Now(),
+	}
+}
+
+// rank calculates the base 2 logarithm of the score a transaction arriving at
+// the given time has at the epoch of the list.
+func (l *txScoredList) rank(tx *types.Transaction, arrival time.Time) float64 {
+	price, _ := new(big.Float).SetInt(tx.GasPrice()).Float64()
+	rank := math.Log2(price) - math.Log2(float64(tx.Size()))
+	if l.lifetime > 0 {
+		rank += float64(arrival.Sub(l.epoch)) / float64(l.lifetime)
+	}
+	return rank
+}
+
+// Put inserts a new transaction which arrived at the given time into the heap.
+func (l *txScoredList) Put(tx *types.Transaction, arrival time.Time) {
+	heap.Push(l.items, scoredTx{tx: tx, arrival: arrival, rank: l.rank(tx, arrival)})
+}
+
+// Removed notifies the scored transaction list that an old transaction dropped
+// from the pool. The list will just keep a counter of stale objects and update
+// the heap if a large enough ratio of transactions go stale.
+func (l *txScoredList) Removed() {
+	// Bump the stale counter, but exit if still too low (< 25%)
+	l.stales++
+	if l.stales <= len(*l.items)/4 {
+		return
+	}
+	// Seems we've reached a critical number of stale transactions, reheap
+	reheap := make(scoreHeap, 0, len(*l.all))
+
+	l.stales, l.items = 0, &reheap
+	for hash, tx := range *l.all {
+		arrival := (*l.arrivals)[hash]
+		*l.items = append(*l.items, scoredTx{tx: tx, arrival: arrival, rank: l.rank(tx, arrival)})
+	}
+	heap.Init(l.items)
+}
+
+// stale reports whether a ranked transaction left the pool, or was ranked
+// with a different arrival time than it is currently tracked with.
+func (l *txScoredList) stale(item scoredTx) bool {
+	hash := item.tx.Hash()
+	if _, ok := (*l.all)[hash]; !ok {
+		return true
+	}
+	return !(*l.arrivals)[hash].Equal(item.arrival)
+}
+
+// Underscored checks whether a transaction arriving at the given time scores
+// lower than (or as low as) the lowest scoring transaction currently tracked.
+func (l *txScoredList) Underscored(tx *types.Transaction, arrival time.Time, local *accountSet) bool {
+	// Local transactions cannot be underscored
+	if local.containsTx(tx) {
+		return false
+	}
+	// Discard stale rank points if found at the heap start, and find the
+	// lowest scoring transaction subject to eviction
+	save := make([]scoredTx, 0, 64)
+	defer func() {
+		for _, item := range save {
+			heap.Push(l.items, item)
+		}
+	}()
+	for len(*l.items) > 0 {
+		head := (*l.items)[0]
+		if l.stale(head) {
+			l.stales--
+			heap.Pop(l.items)
+			continue
+		}
+		if local.containsTx(head.tx) {
+			save = append(save, heap.Pop(l.items).(scoredTx))
+			continue
+		}
+		return head.rank >= l.rank(tx, arrival)
+	}
+	return false
+}
+
+// Discard finds the lowest scoring transaction not sent by a local account,
+// removes it from the scored list and returns it for further removal from the
+// entire pool. Nil is returned if only local transactions remain.
+func (l *txScoredList) Discard(local *accountSet) *types.Transaction {
+	save := make([]scoredTx, 0, 64) // Local transactions to keep
+	defer func() {
+		for _, item := range save {
+			heap.Push(l.items, item)
+		}
+	}()
+	for len(*l.items) > 0 {
+		// Discard stale transactions if found during cleanup
+		item := heap.Pop(l.items).(scoredTx)
+		if l.stale(item) {
+			l.stales--
+			continue
+		}
+		// Non stale transaction found, discard unless local
+		if local.containsTx(item.tx) {
+			save = append(save, item)
+			continue
+		}
+		return item.tx
+	}
+	return nil
+}
@@ -76,6 +76,10 @@ var (
 	// than some meaningful limit a user might use. This is not a consensus error
 	// making the transaction invalid, rather a DOS protection.
 	ErrOversizedData = errors.New("oversized data")
+
+	// ErrSenderRateLimited is returned if a remote sender submitted more
+	// transactions than the configured per-sender rate limit allows.
+	ErrSenderRateLimited = errors.New("sender rate limited")
 )
 
 var (
@@ -99,6 +103,8 @@ var (
 	// General tx metrics
 	invalidTxCounter     = metrics.NewRegisteredCounter("txpool/invalid", nil)
 	underpricedTxCounter = metrics.NewRegisteredCounter("txpool/underpriced", nil)
+	ratelimitedTxCounter = metrics.NewRegisteredCounter("txpool/ratelimited", nil) // Rejected due to the per-sender rate limit
+	oversizedTxCounter   = metrics.NewRegisteredCounter("txpool/oversized", nil)   // Evicted to honor the global size limit
 )
 
 // TxStatus is the current status of a transaction as seen by the pool.
@@ -136,8 +142,25 @@ type TxPoolConfig struct {
 	GlobalQueue  uint64 // Maximum number of non-executable transaction slots for all accounts
 
 	Lifetime time.Duration // Maximum amount of time non-executable transaction are queued
+
+	Priority []common.Address // Senders exempt from eviction and rate limits (without the pricing exemptions of locals)
+
+	SenderRateLimit  uint64        // Maximum number of transactions accepted per remote sender and period (0 = unlimited)
+	SenderRatePeriod time.Duration // Period of the per-sender rate limit
+
+	GlobalBytes uint64 // Maximum total size of all pooled transactions in bytes (0 = unlimited)
+	Eviction    string // Eviction order once the pool is full, EvictionPrice or EvictionScore
 }
 
+const (
+	// EvictionPrice evicts the cheapest transactions first once the pool is full.
+	EvictionPrice = "price"
+
+	// EvictionScore evicts the transactions paying the least per byte first, the
+	// score of a transaction halving with every Lifetime it spends in the pool.
+	EvictionScore = "score"
+)
+
 // DefaultTxPoolConfig contains the default configurations for the transaction
 // pool.
 var DefaultTxPoolConfig = TxPoolConfig{
@@ -153,6 +176,10 @@ var DefaultTxPoolConfig = TxPoolConfig{
 	GlobalQueue:  1024,
 
 	Lifetime: 3 * time.Hour,
+
+	SenderRatePeriod: time.Minute,
+
+	Eviction: EvictionPrice,
 }
 
 // sanitize checks the provided user configurations and changes anything that's
@@ -171,6 +198,18 @@ func (config *TxPoolConfig) sanitize() TxPoolConfig {
 		log.Warn("Sanitizing invalid txpool price bump", "provided", conf.PriceBump, "updated", DefaultTxPoolConfig.PriceBump)
 		conf.PriceBump = DefaultTxPoolConfig.PriceBump
 	}
+	if conf.SenderRateLimit > 0 && conf.SenderRatePeriod < time.Second {
+		log.Warn("Sanitizing invalid txpool sender rate period", "provided", conf.SenderRatePeriod, "updated", DefaultTxPoolConfig.SenderRatePeriod)
+		conf.SenderRatePeriod = DefaultTxPoolConfig.SenderRatePeriod
+	}
+	switch conf.Eviction {
+	case EvictionPrice, EvictionScore:
+	case "":
+		conf.Eviction = EvictionPrice
+	default:
+		log.Warn("Sanitizing invalid txpool eviction", "provided", conf.Eviction, "updated", DefaultTxPoolConfig.Eviction)
+		conf.Eviction = DefaultTxPoolConfig.Eviction
+	}
 	return conf
 }
 
@@ -197,18 +236,26 @@ type TxPool struct {
 	pendingState  *state.ManagedState // Pending state tracking virtual nonces
 	currentMaxGas uint64              // Current gas limit for transaction caps
 
-	locals  *accountSet // Set of local transaction to exempt from eviction rules
-	journal *txJournal  // Journal of local transaction to back up to disk
+	locals    *accountSet // Set of local transaction to exempt from eviction rules
+	priority  *accountSet // Set of configured priority senders
+	protected *accountSet // Set of local and priority senders exempt from eviction rules
+	journal   *txJournal  // Journal of local transaction to back up to disk
+
+	pending  map[common.Address]*txList         // All currently processable transactions
+	queue    map[common.Address]*txList         // Queued but non-processable transactions
+	beats    map[common.Address]time.Time       // Last heartbeat from each known account
+	all      map[common.Hash]*types.Transaction // All transactions to allow lookups
+	arrivals map[common.Hash]time.Time          // Time each transaction entered the pool
+	priced   *txPricedList                      // All transactions sorted by price
+	scored   *txScoredList                      // All transactions sorted by eviction score (score eviction only)
+	bytes    uint64                             // Total size of all transactions
 
-	pending map[common.Address]*txList         // All currently processable transactions
-	queue   map[common.Address]*txList         // Queued but non-processable transactions
-	beats   map[common.Address]time.Time       // Last heartbeat from each known account
-	all     map[common.Hash]*types.Transaction // All transactions to allow lookups
-	priced  *txPricedList                      // All transactions sorted by price
+	rates map[common.Address]*rateWindow // Transactions accepted from each remote sender
 
 	wg sync.WaitGroup // for shutdown sync
 
 	homestead bool
+	eip2718   bool // Typed transactions are accepted in the next block
 }
 
 // NewTxPool creates a new transaction pool to gather, sort and filter inbound
@@ -222,16 +269,28 @@ func NewTxPool(config TxPoolConfig, chainconfig *params.ChainConfig, chain block
 		config:      config,
 		chainconfig: chainconfig,
 		chain:       chain,
-		signer:      types.NewEIP155Signer(chainconfig.ChainId),
+		signer:      types.NewEIP2930Signer(chainconfig.ChainId),
 		pending:     make(map[common.Address]*txList),
 		queue:       make(map[common.Address]*txList),
 		beats:       make(map[common.Address]time.Time),
 		all:         make(map[common.Hash]*types.Transaction),
+		arrivals:    make(map[common.Hash]time.Time),
+		rates:       make(map[common.Address]*rateWindow),
 		chainHeadCh: make(chan ChainHeadEvent, chainHeadChanSize),
 		gasPrice:    new(big.Int).SetUint64(config.PriceLimit),
 	}
 	pool.locals = newAccountSet(pool.signer)
+	pool.priority = newAccountSet(pool.signer)
+	pool.protected = newAccountSet(pool.signer)
+	for _, addr := range config.Priority {
+		log.Info("Setting new priority account", "address", addr)
+		pool.priority.add(addr)
+		pool.protected.add(addr)
+	}
 	pool.priced = newTxPricedList(&pool.all)
+	if config.Eviction == EvictionScore {
+		pool.scored = newTxScoredList(&pool.all, &pool.arrivals, config.Lifetime)
+	}
 	pool.reset(nil, chain.CurrentBlock().Header())
 
 	// If local transactions and journaling is enabled, load from disk
@@ -311,8 +370,8 @@ func (pool *TxPool) loop() {
 		case <-evict.C:
 			pool.mu.Lock()
 			for addr := range pool.queue {
-				// Skip local transactions from the eviction mechanism
-				if pool.locals.contains(addr) {
+				// Skip local and priority transactions from the eviction mechanism
+				if pool.protected.contains(addr) {
 					continue
 				}
 				// Any non-locals old enough should be removed
@@ -322,6 +381,12 @@ func (pool *TxPool) loop() {
 					}
 				}
 			}
+			// Forget the rate limits of senders whose period has expired
+			for addr, window := range pool.rates {
+				if time.Since(window.start) >= pool.config.SenderRatePeriod {
+					delete(pool.rates, addr)
+				}
+			}
 			pool.mu.Unlock()
 
 		// Handle local transaction journal rotation
@@ -410,11 +475,12 @@ func (pool *TxPool) reset(oldHead, newHead *types.Header) {
 	pool.currentState = statedb
 	pool.pendingState = state.ManageState(statedb)
 	pool.currentMaxGas = newHead.GasLimit
+	pool.eip2718 = pool.chainconfig.IsEIP2718(new(big.Int).Add(newHead.Number, common.Big1))
 
 	// Inject any transactions discarded due to reorgs
 	if l := len(reinject); l > 0 {
 		log.Debug("Reinjecting stale transactions", "count", len(reinject))
-		pool.addTxsLocked(reinject, false)
+		pool.addTxsLocked(reinject, false, true)
 	}
 
 	// validate the pool of pending transactions, this will remove
@@ -472,7 +538,7 @@ func (pool *TxPool) SetGasPrice(price *big.Int) {
 	defer pool.mu.Unlock()
 
 	pool.gasPrice = price
-	for _, tx := range pool.priced.Cap(price, pool.locals) {
+	for _, tx := range pool.priced.Cap(price, pool.protected) {
 		pool.removeTx(tx.Hash())
 	}
 	log.Info("Transaction pool price threshold updated", "price", price)
@@ -509,6 +575,41 @@ func (pool *TxPool) stats() (int, int) {
 	return pending, queued
 }
 
+// TxPoolStatus summarizes the occupancy of the transaction pool.
+type TxPoolStatus struct {
+	Pending     int    // Number of executable transactions
+	Queued      int    // Number of non-executable transactions
+	Priority    int    // Number of transactions from priority senders
+	Bytes       uint64 // Total size of all transactions
+	RateLimited int    // Number of senders currently at their rate limit
+}
+
+// Summary retrieves the current status of the transaction pool, extending the
+// pending and queued counts of Stats.
+func (pool *TxPool) Summary() TxPoolStatus {
+	pool.mu.RLock()
+	defer pool.mu.RUnlock()
+
+	status := TxPoolStatus{Bytes: pool.bytes}
+	status.Pending, status.Queued = pool.stats()
+	for addr := range pool.priority.accounts {
+		if list := pool.pending[addr]; list != nil {
+			status.Priority += list.Len()
+		}
+		if list := pool.queue[addr]; list != nil {
+			status.Priority += list.Len()
+		}
+	}
+	if limit := pool.config.SenderRateLimit; limit > 0 {
+		for _, window := range pool.rates {
+			if window.count >= limit && time.Since(window.start) < pool.config.SenderRatePeriod {
+				status.RateLimited++
+			}
+		}
+	}
+	return status
+}
+
 // Content retrieves the data content of the transaction pool, returning all the
 // pending as well as queued transactions, grouped by account and sorted by nonce.
 func (pool *TxPool) Content() (map[common.Address]types.Transactions, map[common.Address]types.Transactions) {
@@ -559,6 +660,10 @@ func (pool *TxPool) local() map[common.Address]types.Transactions {
 // validateTx checks whether a transaction is valid according to the consensus
 // rules and adheres to some heuristic limits of the local node (price and size).
 func (pool *TxPool) validateTx(tx *types.Transaction, local bool) error {
+	// Typed transactions are only accepted once the fork enabling them is near
+	if tx.Type() != types.LegacyTxType && !pool.eip2718 {
+		return types.ErrTxTypeNotSupported
+	}
 	// Heuristic limit, reject transactions over 32KB to prevent DOS attacks
 	if tx.Size() > 32*1024 {
 		return ErrOversizedData
@@ -592,7 +697,7 @@ func (pool *TxPool) validateTx(tx *types.Transaction, local bool) error {
 	if pool.currentState.GetBalance(from).Cmp(tx.Cost()) < 0 {
 		return ErrInsufficientFunds
 	}
-	intrGas, err := IntrinsicGas(tx.Data(), tx.To() == nil, pool.homestead)
+	intrGas, err := IntrinsicGas(tx.Data(), tx.AccessList(), tx.To() == nil, pool.homestead)
 	if err != nil {
 		return err
 	}
@@ -611,6 +716,13 @@ func (pool *TxPool) validateTx(tx *types.Transaction, local bool) error {
 // whitelisted, preventing any associated transaction from being dropped out of
 // the pool due to pricing constraints.
 func (pool *TxPool) add(tx *types.Transaction, local bool) (bool, error) {
+	return pool.addLimited(tx, local, !local)
+}
+
+// addLimited adds a transaction like add, counting it against the rate limit of
+// its sender if limit is set. Transactions reinjected after a reorg are not
+// limited, as they were accepted into the pool or the chain before.
+func (pool *TxPool) addLimited(tx *types.Transaction, local, limit bool) (bool, error) {
 	// If the transaction is already known, discard it
 	hash := tx.Hash()
 	if pool.all[hash] != nil {
@@ -623,24 +735,27 @@ func (pool *TxPool) add(tx *types.Transaction, local bool) (bool, error) {
 		invalidTxCounter.Inc(1)
 		return false, err
 	}
+	// Limit the rate of transactions from remote senders
+	from, _ := types.Sender(pool.signer, tx) // already validated
+	if limit && pool.rateLimited(from) {
+		log.Trace("Discarding rate limited transaction", "hash", hash, "from", from)
+		ratelimitedTxCounter.Inc(1)
+		return false, ErrSenderRateLimited
+	}
 	// If the transaction pool is full, discard underpriced transactions
-	if uint64(len(pool.all)) >= pool.config.GlobalSlots+pool.config.GlobalQueue {
-		// If the new transaction is underpriced, don't accept it
-		if pool.priced.Underpriced(tx, pool.locals) {
-			log.Trace("Discarding underpriced transaction", "hash", hash, "price", tx.GasPrice())
-			underpricedTxCounter.Inc(1)
-			return false, ErrUnderpriced
+	if pool.full(tx) {
+		var err error
+		switch pool.config.Eviction {
+		case EvictionScore:
+			err = pool.evictByScore(tx)
+		default:
+			err = pool.evictByPrice(tx)
 		}
-		// New transaction is better than our worse ones, make room for it
-		drop := pool.priced.Discard(len(pool.all)-int(pool.config.GlobalSlots+pool.config.GlobalQueue-1), pool.locals)
-		for _, tx := range drop {
-			log.Trace("Discarding freshly underpriced transaction", "hash", tx.Hash(), "price", tx.GasPrice())
-			underpricedTxCounter.Inc(1)
-			pool.removeTx(tx.Hash())
+		if err != nil {
+			return false, err
 		}
 	}
 	// If the transaction is replacing an already pending one, do directly
-	from, _ := types.Sender(pool.signer, tx) // already validated
 	if list := pool.pending[from]; list != nil && list.Overlaps(tx) {
 		// Nonce already pending, check if required price bump is met
 		inserted, old := list.Add(tx, pool.config.PriceBump)
@@ -650,14 +765,14 @@ func (pool *TxPool) add(tx *types.Transaction, local bool) (bool, error) {
 		}
 		// New transaction is better, replace old one
 		if old != nil {
-			delete(pool.all, old.Hash())
-			pool.priced.Removed()
+			pool.untrackTx(old.Hash())
 			pendingReplaceCounter.Inc(1)
 		}
-		pool.all[tx.Hash()] = tx
-		pool.priced.Put(tx)
+		pool.trackTx(tx.Hash(), tx)
 		pool.journalTx(from, tx)
-
+		if limit {
+			pool.countRate(from)
+		}
 		log.Trace("Pooled new executable transaction", "hash", hash, "from", from, "to", tx.To())
 
 		// We've directly injected a replacement transaction, notify subsystems
@@ -673,9 +788,12 @@ func (pool *TxPool) add(tx *types.Transaction, local bool) (bool, error) {
 	// Mark local addresses and journal local transactions
 	if local {
 		pool.locals.add(from)
+		pool.protected.add(from)
 	}
 	pool.journalTx(from, tx)
-
+	if limit {
+		pool.countRate(from)
+	}
 	log.Trace("Pooled new future transaction", "hash", hash, "from", from, "to", tx.To())
 	return replace, nil
 }
@@ -697,13 +815,142 @@ func (pool *TxPool) enqueueTx(hash common.Hash, tx *types.Transaction) (bool, er
 	}
 	// Discard any previous transaction and mark this
 	if old != nil {
-		delete(pool.all, old.Hash())
-		pool.priced.Removed()
+		pool.untrackTx(old.Hash())
 		queuedReplaceCounter.Inc(1)
 	}
+	pool.trackTx(hash, tx)
+	return old != nil, nil
+}
+
+// trackTx inserts a transaction into the lookup, price and size indexes of the
+// pool.
+//
+// Note, this method assumes the pool lock is held!
+func (pool *TxPool) trackTx(hash common.Hash, tx *types.Transaction) {
+	// Demoted transactions are tracked already, keep their size and age
+	if _, ok := pool.all[hash]; !ok {
+		pool.arrivals[hash] = time.Now()
+		pool.bytes += uint64(tx.Size())
+		if pool.scored != nil {
+			pool.scored.Put(tx, pool.arrivals[hash])
+		}
+	}
 	pool.all[hash] = tx
 	pool.priced.Put(tx)
-	return old != nil, nil
+}
+
+// untrackTx removes a transaction from the lookup, price and size indexes of
+// the pool, leaving the pending and queued lists to the caller.
+//
+// Note, this method assumes the pool lock is held!
+func (pool *TxPool) untrackTx(hash common.Hash) {
+	tx, ok := pool.all[hash]
+	if !ok {
+		return
+	}
+	delete(pool.all, hash)
+	delete(pool.arrivals, hash)
+	pool.bytes -= uint64(tx.Size())
+	pool.priced.Removed()
+	if pool.scored != nil {
+		pool.scored.Removed()
+	}
+}
+
+// rateLimited reports whether a remote sender already had as many transactions
+// accepted as its rate limit allows in the current period. Local and priority
+// senders are never limited.
+//
+// Note, this method assumes the pool lock is held!
+func (pool *TxPool) rateLimited(from common.Address) bool {
+	if pool.config.SenderRateLimit == 0 || pool.protected.contains(from) {
+		return false
+	}
+	window := pool.rates[from]
+	if window == nil || time.Since(window.start) >= pool.config.SenderRatePeriod {
+		return false
+	}
+	return window.count >= pool.config.SenderRateLimit
+}
+
+// countRate counts a transaction accepted from a remote sender against its rate
+// limit, starting a new period if the last one is over.
+//
+// Note, this method assumes the pool lock is held!
+func (pool *TxPool) countRate(from common.Address) {
+	if pool.config.SenderRateLimit == 0 || pool.protected.contains(from) {
+		return
+	}
+	now := time.Now()
+	window := pool.rates[from]
+	if window == nil || now.Sub(window.start) >= pool.config.SenderRatePeriod {
+		window = &rateWindow{start: now}
+		pool.rates[from] = window
+	}
+	window.count++
+}
+
+// full reports whether the pool has to evict transactions to make room for tx.
+func (pool *TxPool) full(tx *types.Transaction) bool {
+	if uint64(len(pool.all)) >= pool.config.GlobalSlots+pool.config.GlobalQueue {
+		return true
+	}
+	return pool.config.GlobalBytes != 0 && pool.bytes+uint64(tx.Size()) > pool.config.GlobalBytes
+}
+
+// evictByPrice makes room for tx by evicting the cheapest transactions of the
+// pool, rejecting tx if it is no better than them.
+//
+// Note, this method assumes the pool lock is held!
+func (pool *TxPool) evictByPrice(tx *types.Transaction) error {
+	// If the new transaction is underpriced, don't accept it
+	if pool.priced.Underpriced(tx, pool.protected) {
+		log.Trace("Discarding underpriced transaction", "hash", tx.Hash(), "price", tx.GasPrice())
+		underpricedTxCounter.Inc(1)
+		return ErrUnderpriced
+	}
+	// New transaction is better than our worse ones, make room for it
+	if count := len(pool.all) - int(pool.config.GlobalSlots+pool.config.GlobalQueue-1); count > 0 {
+		for _, tx := range pool.priced.Discard(count, pool.protected) {
+			log.Trace("Discarding freshly underpriced transaction", "hash", tx.Hash(), "price", tx.GasPrice())
+			underpricedTxCounter.Inc(1)
+			pool.removeTx(tx.Hash())
+		}
+	}
+	for pool.config.GlobalBytes != 0 && pool.bytes+uint64(tx.Size()) > pool.config.GlobalBytes {
+		drop := pool.priced.Discard(1, pool.protected)
+		if len(drop) == 0 {
+			break // Only local and priority transactions remain
+		}
+		log.Trace("Discarding size-exceeding transaction", "hash", drop[0].Hash(), "price", drop[0].GasPrice())
+		oversizedTxCounter.Inc(1)
+		pool.removeTx(drop[0].Hash())
+	}
+	return nil
+}
+
+// evictByScore makes room for tx by evicting the transactions of the pool with
+// the lowest eviction score, rejecting tx if it scores no better than them.
+//
+// Note, this method assumes the pool lock is held!
+func (pool *TxPool) evictByScore(tx *types.Transaction) error {
+	// If the new transaction is underscored, don't accept it
+	if pool.scored.Underscored(tx, time.Now(), pool.protected) {
+		log.Trace("Discarding underscored transaction", "hash", tx.Hash(), "price", tx.GasPrice(), "size", tx.Size())
+		underpricedTxCounter.Inc(1)
+		return ErrUnderpriced
+	}
+	// New transaction is better than our worse ones, make room for it
+	for pool.full(tx) {
+		victim := pool.scored.Discard(pool.protected)
+		if victim == nil {
+			break // Only local and priority transactions remain
+		}
+		log.Trace("Discarding freshly underscored transaction", "hash", victim.Hash(), "price", victim.GasPrice(), "size", victim.Size())
+		underpricedTxCounter.Inc(1)
+		pool.removeTx(victim.Hash())
+	}
+	return nil
 }
 
 // journalTx adds the specified transaction to the local disk journal if it is
@@ -731,23 +978,20 @@ func (pool *TxPool) promoteTx(addr common.Address, hash common.Hash, tx *types.T
 	inserted, old := list.Add(tx, pool.config.PriceBump)
 	if !inserted {
 		// An older transaction was better, discard this
-		delete(pool.all, hash)
-		pool.priced.Removed()
+		pool.untrackTx(hash)
 
 		pendingDiscardCounter.Inc(1)
 		return
 	}
 	// Otherwise discard any previous transaction and mark this
 	if old != nil {
-		delete(pool.all, old.Hash())
-		pool.priced.Removed()
+		pool.untrackTx(old.Hash())
 
 		pendingReplaceCounter.Inc(1)
 	}
 	// Failsafe to work around direct pending inserts (tests)
 	if pool.all[hash] == nil {
-		pool.all[hash] = tx
-		pool.priced.Put(tx)
+		pool.trackTx(hash, tx)
 	}
 	// Set the potentially new pending nonce and notify any subsystems of the new tx
 	pool.beats[addr] = time.Now()
@@ -807,19 +1051,20 @@ func (pool *TxPool) addTxs(txs []*types.Transaction, local bool) []error {
 	pool.mu.Lock()
 	defer pool.mu.Unlock()
 
-	return pool.addTxsLocked(txs, local)
+	return pool.addTxsLocked(txs, local, false)
 }
 
 // addTxsLocked attempts to queue a batch of transactions if they are valid,
-// whilst assuming the transaction pool lock is already held.
-func (pool *TxPool) addTxsLocked(txs []*types.Transaction, local bool) []error {
+// whilst assuming the transaction pool lock is already held. Reinjected
+// transactions are exempt from the sender rate limits.
+func (pool *TxPool) addTxsLocked(txs []*types.Transaction, local, reinject bool) []error {
 	// Add the batch of transaction, tracking the accepted ones
 	dirty := make(map[common.Address]struct{})
 	errs := make([]error, len(txs))
 
 	for i, tx := range txs {
 		var replace bool
-		if replace, errs[i] = pool.add(tx, local); errs[i] == nil {
+		if replace, errs[i] = pool.addLimited(tx, local, !local && !reinject); errs[i] == nil {
 			if !replace {
 				from, _ := types.Sender(pool.signer, tx) // already validated
 				dirty[from] = struct{}{}
@@ -877,8 +1122,7 @@ func (pool *TxPool) removeTx(hash common.Hash) {
 	addr, _ := types.Sender(pool.signer, tx) // already validated during insertion
 
 	// Remove it from the list of known transactions
-	delete(pool.all, hash)
-	pool.priced.Removed()
+	pool.untrackTx(hash)
 
 	// Remove the transaction from the pending lists and reset the account nonce
 	if pending := pool.pending[addr]; pending != nil {
@@ -930,16 +1174,14 @@ func (pool *TxPool) promoteExecutables(accounts []common.Address) {
 		for _, tx := range list.Forward(pool.currentState.GetNonce(addr)) {
 			hash := tx.Hash()
 			log.Trace("Removed old queued transaction", "hash", hash)
-			delete(pool.all, hash)
-			pool.priced.Removed()
+			pool.untrackTx(hash)
 		}
 		// Drop all transactions that are too costly (low balance or out of gas)
 		drops, _ := list.Filter(pool.currentState.GetBalance(addr), pool.currentMaxGas)
 		for _, tx := range drops {
 			hash := tx.Hash()
 			log.Trace("Removed unpayable queued transaction", "hash", hash)
-			delete(pool.all, hash)
-			pool.priced.Removed()
+			pool.untrackTx(hash)
 			queuedNofundsCounter.Inc(1)
 		}
 		// Gather all executable transactions and promote them
@@ -949,11 +1191,10 @@ func (pool *TxPool) promoteExecutables(accounts []common.Address) {
 			pool.promoteTx(addr, hash, tx)
 		}
 		// Drop all transactions over the allowed limit
-		if !pool.locals.contains(addr) {
+		if !pool.protected.contains(addr) {
 			for _, tx := range list.Cap(int(pool.config.AccountQueue)) {
 				hash := tx.Hash()
-				delete(pool.all, hash)
-				pool.priced.Removed()
+				pool.untrackTx(hash)
 				queuedRateLimitCounter.Inc(1)
 				log.Trace("Removed cap-exceeding queued transaction", "hash", hash)
 			}
@@ -974,7 +1215,7 @@ func (pool *TxPool) promoteExecutables(accounts []common.Address) {
 		spammers := prque.New(nil)
 		for addr, list := range pool.pending {
 			// Only evict transactions from high rollers
-			if !pool.locals.contains(addr) && uint64(list.Len()) > pool.config.AccountSlots {
+			if !pool.protected.contains(addr) && uint64(list.Len()) > pool.config.AccountSlots {
 				spammers.Push(addr, int64(list.Len()))
 			}
 		}
@@ -997,8 +1238,7 @@ func (pool *TxPool) promoteExecutables(accounts []common.Address) {
 						for _, tx := range list.Cap(list.Len() - 1) {
 							// Drop the transaction from the global pools too
 							hash := tx.Hash()
-							delete(pool.all, hash)
-							pool.priced.Removed()
+							pool.untrackTx(hash)
 
 							// Update the account nonce to the dropped transaction
 							if nonce := tx.Nonce(); pool.pendingState.GetNonce(offenders[i]) > nonce {
@@ -1019,8 +1259,7 @@ func (pool *TxPool) promoteExecutables(accounts []common.Address) {
 					for _, tx := range list.Cap(list.Len() - 1) {
 						// Drop the transaction from the global pools too
 						hash := tx.Hash()
-						delete(pool.all, hash)
-						pool.priced.Removed()
+						pool.untrackTx(hash)
 
 						// Update the account nonce to the dropped transaction
 						if nonce := tx.Nonce(); pool.pendingState.GetNonce(addr) > nonce {
@@ -1043,13 +1282,13 @@ func (pool *TxPool) promoteExecutables(accounts []common.Address) {
 		// Sort all accounts with queued transactions by heartbeat
 		addresses := make(addresssByHeartbeat, 0, len(pool.queue))
 		for addr := range pool.queue {
-			if !pool.locals.contains(addr) { // don't drop locals
+			if !pool.protected.contains(addr) { // don't drop locals or priority senders
 				addresses = append(addresses, addressByHeartbeat{addr, pool.beats[addr]})
 			}
 		}
 		sort.Sort(addresses)
 
-		// Drop transactions until the total is below the limit or only locals remain
+		// Drop transactions until the total is below the limit or only protected senders remain
 		for drop := queued - pool.config.GlobalQueue; drop > 0 && len(addresses) > 0; {
 			addr := addresses[len(addresses)-1]
 			list := pool.queue[addr.address]
@@ -1088,16 +1327,14 @@ func (pool *TxPool) demoteUnexecutables() {
 		for _, tx := range list.Forward(nonce) {
 			hash := tx.Hash()
 			log.Trace("Removed old pending transaction", "hash", hash)
-			delete(pool.all, hash)
-			pool.priced.Removed()
+			pool.untrackTx(hash)
 		}
 		// Drop all transactions that are too costly (low balance or out of gas), and queue any invalids back for later
 		drops, invalids := list.Filter(pool.currentState.GetBalance(addr), pool.currentMaxGas)
 		for _, tx := range drops {
 			hash := tx.Hash()
 			log.Trace("Removed unpayable pending transaction", "hash", hash)
-			delete(pool.all, hash)
-			pool.priced.Removed()
+			pool.untrackTx(hash)
 			pendingNofundsCounter.Inc(1)
 		}
 		for _, tx := range invalids {
@@ -1133,6 +1370,13 @@ func (a addresssByHeartbeat) Len() int           { return len(a) }
 func (a addresssByHeartbeat) Less(i, j int) bool { return a[i].heartbeat.Before(a[j].heartbeat) }
 func (a addresssByHeartbeat) Swap(i, j int)      { a[i], a[j] = a[j], a[i] }
 
+// rateWindow counts the transactions accepted from a sender within the current
+// rate limiting period.
+type rateWindow struct {
+	start time.Time
+	count uint64
+}
+
 // accountSet is simply a set of addresses to check for existence, and a signer
 // capable of deriving addresses from transactions.
 type accountSet struct {
@@ -102,6 +102,20 @@ func validateTxPoolInternals(pool *TxPool) error {
 	if priced := pool.priced.items.Len() - pool.priced.stales; priced != pending+queued {
 		return fmt.Errorf("total priced transaction count %d != %d pending + %d queued", priced, pending, queued)
 	}
+	// Ensure the size and age indexes track exactly the pooled transactions
+	var bytes uint64
+	for hash, tx := range pool.all {
+		if _, ok := pool.arrivals[hash]; !ok {
+			return fmt.Errorf("missing arrival time of transaction %x", hash)
+		}
+		bytes += uint64(tx.Size())
+	}
+	if len(pool.arrivals) != len(pool.all) {
+		return fmt.Errorf("arrival time count %d != %d transactions", len(pool.arrivals), len(pool.all))
+	}
+	if bytes != pool.bytes {
+		return fmt.Errorf("total transaction size %d != %d tracked", bytes, pool.bytes)
+	}
 	// Ensure the next nonce to assign is the correct one
 	for addr, txs := range pool.pending {
 		// Find the last transaction
@@ -262,6 +276,41 @@ func TestInvalidTransactions(t *testing.T) {
 	}
 }
 
+func TestAccessListTransactions(t *testing.T) {
+	t.Parallel()
+
+	pool, key := setupTxPool()
+	defer pool.Stop()
+
+	accessList := types.AccessList{{Address: common.Address{1}, StorageKeys: []common.Hash{{1}, {2}}}}
+	accessListTx := func(nonce uint64, gaslimit uint64) *types.Transaction {
+		tx := types.NewAccessListTransaction(params.TestChainConfig.ChainId, nonce, &common.Address{}, big.NewInt(100), gaslimit, big.NewInt(1), nil, accessList)
+		tx, _ = types.SignTx(tx, types.LatestSignerForChainID(params.TestChainConfig.ChainId), key)
+		return tx
+	}
+	from := crypto.PubkeyToAddress(key.PubKey())
+	pool.currentState.AddBalance(from, big.NewInt(1000000))
+
+	// Typed transactions are refused until the fork
+	if err := pool.AddRemote(accessListTx(0, 100000)); err != types.ErrTxTypeNotSupported {
+		t.Fatalf("expected %v got %v", types.ErrTxTypeNotSupported, err)
+	}
+	pool.mu.Lock()
+	pool.eip2718 = true
+	pool.mu.Unlock()
+
+	// The access list is part of the intrinsic gas
+	if err := pool.AddRemote(accessListTx(0, params.TxGas+params.TxAccessListAddressGas)); err != ErrIntrinsicGas {
+		t.Errorf("expected %v got %v", ErrIntrinsicGas, err)
+	}
+	if err := pool.AddRemote(accessListTx(0, params.TxGas+params.TxAccessListAddressGas+2*params.TxAccessListStorageKeyGas)); err != nil {
+		t.Errorf("expected %v got %v", nil, err)
+	}
+	if pending, _ := pool.Stats(); pending != 1 {
+		t.Errorf("pending transactions mismatched: have %d, want %d", pending, 1)
+	}
+}
+
 func TestTransactionQueue(t *testing.T) {
 	t.Parallel()
 
@@ -1374,6 +1423,290 @@ func TestTransactionPoolUnderpricing(t *testing.T) {
 	}
 }
 
+// Tests that transactions of priority senders are never evicted from a full
+// pool, and are admitted into it even if cheaper than all remote ones.
+func TestTransactionPoolPriority(t *testing.T) {
+	t.Parallel()
+
+	// Create the pool to test the priority senders with
+	db := aquadb.NewMemDatabase()
+	statedb, _ := state.New(common.Hash{}, state.NewDatabase(db))
+	blockchain := &testBlockChain{statedb, 1000000, new(event.Feed)}
+
+	keys := make([]*btcec.PrivateKey, 2)
+	for i := 0; i < len(keys); i++ {
+		keys[i], _ = crypto.GenerateKey()
+	}
+	priority := crypto.PubkeyToAddress(keys[0].PubKey())
+
+	config := testTxPoolConfig
+	config.GlobalSlots = 2
+	config.GlobalQueue = 2
+	config.Priority = []common.Address{priority}
+
+	pool := NewTxPool(config, params.TestChainConfig, blockchain)
+	defer pool.Stop()
+
+	for _, key := range keys {
+		pool.currentState.AddBalance(crypto.PubkeyToAddress(key.PubKey()), big.NewInt(1000000))
+	}
+	// Fill the pool with cheap priority and remote transactions
+	txs := types.Transactions{
+		pricedTransaction(0, 100000, big.NewInt(1), keys[0]),
+		pricedTransaction(1, 100000, big.NewInt(1), keys[0]),
+		pricedTransaction(0, 100000, big.NewInt(1), keys[1]),
+		pricedTransaction(1, 100000, big.NewInt(1), keys[1]),
+	}
+	for i, err := range pool.AddRemotes(txs) {
+		if err != nil {
+			t.Fatalf("tx %d: failed to add transaction: %v", i, err)
+		}
+	}
+	// Push out the remote transactions with higher priced ones
+	for nonce := uint64(2); nonce < 4; nonce++ {
+		if err := pool.AddRemote(pricedTransaction(nonce, 100000, big.NewInt(2), keys[1])); err != nil && err != ErrUnderpriced {
+			t.Fatalf("failed to add well priced transaction: %v", err)
+		}
+	}
+	for _, tx := range txs[:2] {
+		if pool.Get(tx.Hash()) == nil {
+			t.Errorf("priority transaction %x evicted", tx.Hash())
+		}
+	}
+	// Ensure a cheap priority transaction still finds room in the full pool
+	if err := pool.AddRemote(pricedTransaction(2, 100000, big.NewInt(1), keys[0])); err != nil {
+		t.Fatalf("failed to add cheap priority transaction: %v", err)
+	}
+	if status := pool.Summary(); status.Priority != 3 {
+		t.Errorf("priority transactions mismatched: have %d, want %d", status.Priority, 3)
+	}
+	if len(pool.all) != 4 {
+		t.Errorf("total transaction mismatch: have %d, want %d", len(pool.all), 4)
+	}
+	if err := validateTxPoolInternals(pool); err != nil {
+		t.Fatalf("pool internal state corrupted: %v", err)
+	}
+}
+
+// Tests that remote senders are limited to the configured number of transactions
+// per period, while local and priority senders are not.
+func TestTransactionPoolSenderRateLimit(t *testing.T) {
+	t.Parallel()
+
+	// Create the pool to test the rate limiting with
+	db := aquadb.NewMemDatabase()
+	statedb, _ := state.New(common.Hash{}, state.NewDatabase(db))
+	blockchain := &testBlockChain{statedb, 1000000, new(event.Feed)}
+
+	keys := make([]*btcec.PrivateKey, 3)
+	for i := 0; i < len(keys); i++ {
+		keys[i], _ = crypto.GenerateKey()
+	}
+	config := testTxPoolConfig
+	config.SenderRateLimit = 2
+	config.Priority = []common.Address{crypto.PubkeyToAddress(keys[1].PubKey())}
+
+	pool := NewTxPool(config, params.TestChainConfig, blockchain)
+	defer pool.Stop()
+
+	for _, key := range keys {
+		pool.currentState.AddBalance(crypto.PubkeyToAddress(key.PubKey()), big.NewInt(1000000))
+	}
+	// Exceed the rate limit with a remote, a priority and a local sender
+	for nonce := uint64(0); nonce < 3; nonce++ {
+		err := pool.AddRemote(transaction(nonce, 100000, keys[0]))
+		switch {
+		case nonce < 2 && err != nil:
+			t.Fatalf("tx %d: failed to add remote transaction: %v", nonce, err)
+		case nonce == 2 && err != ErrSenderRateLimited:
+			t.Fatalf("tx %d: rate limit error mismatch: have %v, want %v", nonce, err, ErrSenderRateLimited)
+		}
+		if err := pool.AddRemote(transaction(nonce, 100000, keys[1])); err != nil {
+			t.Fatalf("tx %d: failed to add priority transaction: %v", nonce, err)
+		}
+		if err := pool.AddLocal(transaction(nonce, 100000, keys[2])); err != nil {
+			t.Fatalf("tx %d: failed to add local transaction: %v", nonce, err)
+		}
+	}
+	if status := pool.Summary(); status.RateLimited != 1 {
+		t.Errorf("rate limited senders mismatched: have %d, want %d", status.RateLimited, 1)
+	}
+	// Expire the period and ensure the remote sender is accepted again
+	pool.mu.Lock()
+	pool.rates[crypto.PubkeyToAddress(keys[0].PubKey())].start = time.Now().Add(-config.SenderRatePeriod)
+	pool.mu.Unlock()
+
+	if err := pool.AddRemote(transaction(2, 100000, keys[0])); err != nil {
+		t.Fatalf("failed to add remote transaction after the period: %v", err)
+	}
+	if status := pool.Summary(); status.RateLimited != 0 {
+		t.Errorf("rate limited senders mismatched: have %d, want %d", status.RateLimited, 0)
+	}
+	if err := validateTxPoolInternals(pool); err != nil {
+		t.Fatalf("pool internal state corrupted: %v", err)
+	}
+}
+
+// Tests that transactions rejected by the pool don't count against the rate
+// limit of their sender.
+func TestTransactionPoolSenderRateLimitRejected(t *testing.T) {
+	t.Parallel()
+
+	db := aquadb.NewMemDatabase()
+	statedb, _ := state.New(common.Hash{}, state.NewDatabase(db))
+	blockchain := &testBlockChain{statedb, 1000000, new(event.Feed)}
+
+	config := testTxPoolConfig
+	config.SenderRateLimit = 2
+
+	pool := NewTxPool(config, params.TestChainConfig, blockchain)
+	defer pool.Stop()
+
+	key, _ := crypto.GenerateKey()
+	pool.currentState.AddBalance(crypto.PubkeyToAddress(key.PubKey()), big.NewInt(1000000))
+
+	if err := pool.AddRemote(transaction(0, 100000, key)); err != nil {
+		t.Fatalf("failed to add transaction: %v", err)
+	}
+	// Underpriced replacements are rejected and must not use up the quota
+	for i := 0; i < 3; i++ {
+		if err := pool.AddRemote(pricedTransaction(0, 100001+uint64(i), big.NewInt(1), key)); err != ErrReplaceUnderpriced {
+			t.Fatalf("replacement %d: error mismatch: have %v, want %v", i, err, ErrReplaceUnderpriced)
+		}
+	}
+	if err := pool.AddRemote(transaction(1, 100000, key)); err != nil {
+		t.Fatalf("failed to add transaction within the limit: %v", err)
+	}
+	if err := pool.AddRemote(transaction(2, 100000, key)); err != ErrSenderRateLimited {
+		t.Fatalf("rate limit error mismatch: have %v, want %v", err, ErrSenderRateLimited)
+	}
+	if err := validateTxPoolInternals(pool); err != nil {
+		t.Fatalf("pool internal state corrupted: %v", err)
+	}
+}
+
+// reorgBlockChain is a test chain serving a fixed set of blocks by hash.
+type reorgBlockChain struct {
+	*testBlockChain
+	blocks map[common.Hash]*types.Block
+}
+
+func (bc *reorgBlockChain) GetBlock(hash common.Hash, number uint64) *types.Block {
+	return bc.blocks[hash]
+}
+
+// Tests that transactions reinjected into the pool after a reorg don't count
+// against the rate limit of their sender.
+func TestTransactionPoolSenderRateLimitReorg(t *testing.T) {
+	t.Parallel()
+
+	db := aquadb.NewMemDatabase()
+	statedb, _ := state.New(common.Hash{}, state.NewDatabase(db))
+	blockchain := &reorgBlockChain{&testBlockChain{statedb, 1000000, new(event.Feed)}, make(map[common.Hash]*types.Block)}
+
+	config := testTxPoolConfig
+	config.SenderRateLimit = 2
+
+	pool := NewTxPool(config, params.TestChainConfig, blockchain)
+	defer pool.Stop()
+
+	key, _ := crypto.GenerateKey()
+	pool.currentState.AddBalance(crypto.PubkeyToAddress(key.PubKey()), big.NewInt(1000000))
+
+	// Mine more transactions of the sender than its limit, then reorg them out
+	txs := types.Transactions{transaction(0, 100000, key), transaction(1, 100000, key), transaction(2, 100000, key)}
+	var (
+		parent = types.NewBlock(&types.Header{Number: big.NewInt(0), GasLimit: 1000000, Version: 1}, nil, nil, nil)
+		mined  = types.NewBlock(&types.Header{ParentHash: parent.Hash(), Number: big.NewInt(1), GasLimit: 1000000, Version: 1}, txs, nil, nil)
+		fork   = types.NewBlock(&types.Header{ParentHash: parent.Hash(), Number: big.NewInt(1), GasLimit: 1000000, Version: 1}, nil, nil, nil)
+	)
+	for _, block := range []*types.Block{parent, mined, fork} {
+		blockchain.blocks[block.Hash()] = block
+	}
+	pool.lockedReset(mined.Header(), fork.Header())
+
+	if pending, queued := pool.Stats(); pending != len(txs) || queued != 0 {
+		t.Fatalf("reinjected transactions mismatched: have %d pending, %d queued, want %d pending", pending, queued, len(txs))
+	}
+	// The sender's own allowance must be untouched
+	for nonce := uint64(3); nonce < 5; nonce++ {
+		if err := pool.AddRemote(transaction(nonce, 100000, key)); err != nil {
+			t.Fatalf("tx %d: failed to add transaction: %v", nonce, err)
+		}
+	}
+	if err := pool.AddRemote(transaction(5, 100000, key)); err != ErrSenderRateLimited {
+		t.Fatalf("rate limit error mismatch: have %v, want %v", err, ErrSenderRateLimited)
+	}
+	if err := validateTxPoolInternals(pool); err != nil {
+		t.Fatalf("pool internal state corrupted: %v", err)
+	}
+}
+
+// Tests that the score eviction policy and the global size limit evict large
+// and old transactions before small and recent ones of the same price.
+func TestTransactionPoolEvictionScore(t *testing.T) {
+	t.Parallel()
+
+	// Create the pool to test the eviction policy with
+	db := aquadb.NewMemDatabase()
+	statedb, _ := state.New(common.Hash{}, state.NewDatabase(db))
+	blockchain := &testBlockChain{statedb, 1000000, new(event.Feed)}
+
+	keys := make([]*btcec.PrivateKey, 6)
+	for i := 0; i < len(keys); i++ {
+		keys[i], _ = crypto.GenerateKey()
+	}
+	sized := func(key *btcec.PrivateKey, size int, gasprice int64) *types.Transaction {
+		tx, _ := types.SignTx(types.NewTransaction(0, common.Address{}, big.NewInt(100), 100000, big.NewInt(gasprice), make([]byte, size)), types.HomesteadSigner{}, key)
+		return tx
+	}
+	small, large, old := sized(keys[0], 0, 1), sized(keys[1], 4096, 1), sized(keys[2], 0, 1)
+
+	config := testTxPoolConfig
+	config.Eviction = EvictionScore
+	config.GlobalBytes = uint64(small.Size()+large.Size()+old.Size()) + 1
+
+	pool := NewTxPool(config, params.TestChainConfig, blockchain)
+	defer pool.Stop()
+
+	for _, key := range keys {
+		pool.currentState.AddBalance(crypto.PubkeyToAddress(key.PubKey()), big.NewInt(100000000))
+	}
+	for i, err := range pool.AddRemotes(types.Transactions{small, large, old}) {
+		if err != nil {
+			t.Fatalf("tx %d: failed to add transaction: %v", i, err)
+		}
+	}
+	pool.mu.Lock()
+	pool.arrivals[old.Hash()] = time.Now().Add(-config.Lifetime)
+	pool.scored.Put(old, pool.arrivals[old.Hash()])
+	pool.mu.Unlock()
+
+	if status := pool.Summary(); status.Bytes != config.GlobalBytes-1 {
+		t.Fatalf("pool size mismatched: have %d, want %d", status.Bytes, config.GlobalBytes-1)
+	}
+	// Ensure the large transaction is evicted first
+	if err := pool.AddRemote(sized(keys[3], 0, 1)); err != nil {
+		t.Fatalf("failed to add transaction: %v", err)
+	}
+	if pool.Get(large.Hash()) != nil || pool.Get(old.Hash()) == nil || pool.Get(small.Hash()) == nil {
+		t.Fatalf("large transaction not evicted first")
+	}
+	// Ensure large transactions need to pay for their size, evicting old ones
+	if err := pool.AddRemote(sized(keys[4], 4096, 1)); err != ErrUnderpriced {
+		t.Fatalf("adding underscored transaction error mismatch: have %v, want %v", err, ErrUnderpriced)
+	}
+	if err := pool.AddRemote(sized(keys[5], 4096, 100)); err != nil {
+		t.Fatalf("failed to add well priced transaction: %v", err)
+	}
+	if pool.Get(old.Hash()) != nil || pool.Get(small.Hash()) == nil {
+		t.Fatalf("old transaction not evicted first")
+	}
+	if err := validateTxPoolInternals(pool); err != nil {
+		t.Fatalf("pool internal state corrupted: %v", err)
+	}
+}
+
 // Tests that the pool rejects replacement transactions that don't meet the minimum
 // price bump required.
 func TestTransactionReplacement(t *testing.T) {
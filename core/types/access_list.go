@@ -0,0 +1,100 @@
+// Copyright 2018 The aquachain Authors
+// This file is part of the aquachain library.
+//
+// The aquachain library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The aquachain library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the aquachain library. If not, see <http://www.gnu.org/licenses/>.
+
+package types
+
+import (
+	"math/big"
+
+	"gitlab.com/aquachain/aquachain/common"
+)
+
+// AccessList is an EIP-2930 access list, the accounts and storage slots a
+// transaction declares it is going to touch.
+type AccessList []AccessTuple
+
+// AccessTuple is one account of an access list along with its storage slots.
+type AccessTuple struct {
+	Address     common.Address `json:"address"     gencodec:"required"`
+	StorageKeys []common.Hash  `json:"storageKeys" gencodec:"required"`
+}
+
+// StorageKeys returns the total number of storage slots in the access list.
+func (al AccessList) StorageKeys() int {
+	sum := 0
+	for _, tuple := range al {
+		sum += len(tuple.StorageKeys)
+	}
+	return sum
+}
+
+// accessListTxdata is the consensus encoding of an access list transaction,
+// following its type byte.
+type accessListTxdata struct {
+	ChainID      *big.Int
+	AccountNonce uint64
+	Price        *big.Int
+	GasLimit     uint64
+	Recipient    *common.Address `rlp:"nil"` // nil means contract creation
+	Amount       *big.Int
+	Payload      []byte
+	AccessList   AccessList
+
+	// Signature values, V is the bare recovery id
+	V *big.Int
+	R *big.Int
+	S *big.Int
+}
+
+func newAccessListTxdata(d *txdata) *accessListTxdata {
+	enc := &accessListTxdata{
+		ChainID:      d.ChainID,
+		AccountNonce: d.AccountNonce,
+		Price:        d.Price,
+		GasLimit:     d.GasLimit,
+		Recipient:    d.Recipient,
+		Amount:       d.Amount,
+		Payload:      d.Payload,
+		V:            d.V,
+		R:            d.R,
+		S:            d.S,
+	}
+	if d.AccessList != nil {
+		enc.AccessList = *d.AccessList
+	}
+	if enc.ChainID == nil {
+		enc.ChainID = new(big.Int)
+	}
+	return enc
+}
+
+func (enc *accessListTxdata) txdata() txdata {
+	accessList := enc.AccessList
+	return txdata{
+		Type:         AccessListTxType,
+		ChainID:      enc.ChainID,
+		AccountNonce: enc.AccountNonce,
+		Price:        enc.Price,
+		GasLimit:     enc.GasLimit,
+		Recipient:    enc.Recipient,
+		Amount:       enc.Amount,
+		Payload:      enc.Payload,
+		AccessList:   &accessList,
+		V:            enc.V,
+		R:            enc.R,
+		S:            enc.S,
+	}
+}
@@ -204,6 +204,16 @@ func rlpHash(version byte, x interface{}) (h common.Hash) {
 	}
 }
 
+// prefixedRlpHash hashes the RLP encoding of x after the prefix byte, as the
+// envelope of a typed transaction.
+func prefixedRlpHash(prefix byte, x interface{}) (h common.Hash) {
+	hw := sha3.NewKeccak256()
+	hw.Write([]byte{prefix})
+	rlp.Encode(hw, x)
+	hw.Sum(h[:0])
+	return h
+}
+
 // Body is a simple (mutable, non-safe) data container for storing and moving
 // a block's data contents (transactions and uncles) together.
 type Body struct {
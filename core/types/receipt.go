@@ -45,6 +45,7 @@ const (
 // Receipt represents the results of a transaction.
 type Receipt struct {
 	// Consensus fields
+	Type              uint8  `json:"type,omitempty"`
 	PostState         []byte `json:"root"`
 	Status            uint   `json:"status"`
 	CumulativeGasUsed uint64 `json:"cumulativeGasUsed" gencodec:"required"`
@@ -58,6 +59,7 @@ type Receipt struct {
 }
 
 type receiptMarshaling struct {
+	Type              hexutil.Uint64
 	PostState         hexutil.Bytes
 	Status            hexutil.Uint
 	CumulativeGasUsed hexutil.Uint64
@@ -80,6 +82,7 @@ type receiptStorageRLP struct {
 	ContractAddress   common.Address
 	Logs              []*LogForStorage
 	GasUsed           uint64
+	Type              []uint64 `rlp:"tail"` // empty for legacy receipts, keeping their encoding
 }
 
 // NewReceipt creates a barebone transaction receipt, copying the init fields.
@@ -95,17 +98,73 @@ func NewReceipt(root []byte, failed bool, cumulativeGasUsed uint64) *Receipt {
 
 // EncodeRLP implements rlp.Encoder, and flattens the consensus fields of a receipt
 // into an RLP stream. If no post state is present, byzantium fork is assumed.
+// Receipts of typed transactions are encoded as an RLP string holding their
+// envelope.
 func (r *Receipt) EncodeRLP(w io.Writer) error {
-	return rlp.Encode(w, &receiptRLP{r.statusEncoding(), r.CumulativeGasUsed, r.Bloom, r.Logs})
+	if r.Type == LegacyTxType {
+		return rlp.Encode(w, &receiptRLP{r.statusEncoding(), r.CumulativeGasUsed, r.Bloom, r.Logs})
+	}
+	enc, err := r.MarshalBinary()
+	if err != nil {
+		return err
+	}
+	return rlp.Encode(w, enc)
 }
 
 // DecodeRLP implements rlp.Decoder, and loads the consensus fields of a receipt
 // from an RLP stream.
 func (r *Receipt) DecodeRLP(s *rlp.Stream) error {
+	kind, _, err := s.Kind()
+	if err != nil {
+		return err
+	}
+	if kind == rlp.List {
+		var dec receiptRLP
+		if err := s.Decode(&dec); err != nil {
+			return err
+		}
+		r.Type = LegacyTxType
+		return r.setFromRLP(dec)
+	}
+	enc, err := s.Bytes()
+	if err != nil {
+		return err
+	}
+	return r.UnmarshalBinary(enc)
+}
+
+// MarshalBinary returns the consensus encoding of the receipt: the RLP list for
+// legacy receipts, the transaction type followed by the RLP payload for the
+// receipts of typed transactions.
+func (r *Receipt) MarshalBinary() ([]byte, error) {
+	payload, err := rlp.EncodeToBytes(&receiptRLP{r.statusEncoding(), r.CumulativeGasUsed, r.Bloom, r.Logs})
+	if err != nil || r.Type == LegacyTxType {
+		return payload, err
+	}
+	return append([]byte{r.Type}, payload...), nil
+}
+
+// UnmarshalBinary decodes the consensus encoding of a receipt.
+func (r *Receipt) UnmarshalBinary(b []byte) error {
+	if len(b) > 0 && b[0] > 0x7f {
+		// An RLP list, so a legacy receipt
+		return rlp.DecodeBytes(b, r)
+	}
+	if len(b) == 0 {
+		return errEmptyTypedTx
+	}
+	if b[0] != AccessListTxType {
+		return ErrTxTypeNotSupported
+	}
 	var dec receiptRLP
-	if err := s.Decode(&dec); err != nil {
+	if err := rlp.DecodeBytes(b[1:], &dec); err != nil {
 		return err
 	}
+	r.Type = b[0]
+	return r.setFromRLP(dec)
+}
+
+func (r *Receipt) setFromRLP(dec receiptRLP) error {
 	if err := r.setStatus(dec.PostStateOrStatus); err != nil {
 		return err
 	}
@@ -173,6 +232,9 @@ func (r *ReceiptForStorage) EncodeRLP(w io.Writer) error {
 		Logs:              make([]*LogForStorage, len(r.Logs)),
 		GasUsed:           r.GasUsed,
 	}
+	if r.Type != LegacyTxType {
+		enc.Type = []uint64{uint64(r.Type)}
+	}
 	for i, log := range r.Logs {
 		enc.Logs[i] = (*LogForStorage)(log)
 	}
@@ -197,6 +259,9 @@ func (r *ReceiptForStorage) DecodeRLP(s *rlp.Stream) error {
 	}
 	// Assign the implementation fields
 	r.TxHash, r.ContractAddress, r.GasUsed = dec.TxHash, dec.ContractAddress, dec.GasUsed
+	if len(dec.Type) > 0 {
+		r.Type = uint8(dec.Type[0])
+	}
 	return nil
 }
 
@@ -206,9 +271,10 @@ type Receipts []*Receipt
 // Len returns the number of receipts in this list.
 func (r Receipts) Len() int { return len(r) }
 
-// GetRlp returns the RLP encoding of one receipt from the list.
+// GetRlp returns the RLP encoding of one receipt from the list, or the envelope
+// of typed receipts.
 func (r Receipts) GetRlp(i int) []byte {
-	bytes, err := rlp.EncodeToBytes(r[i])
+	bytes, err := r[i].MarshalBinary()
 	if err != nil {
 		panic(err)
 	}
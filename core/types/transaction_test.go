@@ -233,3 +233,134 @@ func TestTransactionJSON(t *testing.T) {
 		}
 	}
 }
+
+func TestAccessListTransaction(t *testing.T) {
+	key, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatalf("could not generate key: %v", err)
+	}
+	var (
+		from       = crypto.PubkeyToAddress(key.PubKey())
+		to         = common.Address{1}
+		accessList = AccessList{
+			{Address: common.Address{2}, StorageKeys: []common.Hash{{1}, {2}}},
+			{Address: common.Address{3}, StorageKeys: []common.Hash{}},
+		}
+		signer = NewEIP2930Signer(common.Big2)
+	)
+	tx, err := SignTx(NewAccessListTransaction(common.Big2, 7, &to, common.Big1, 50000, common.Big2, []byte("abcdef"), accessList), signer, key)
+	if err != nil {
+		t.Fatalf("could not sign transaction: %v", err)
+	}
+	if tx.Type() != AccessListTxType || !tx.Protected() || tx.ChainId().Cmp(common.Big2) != 0 {
+		t.Fatalf("unexpected type %d, protected %v, chain id %v", tx.Type(), tx.Protected(), tx.ChainId())
+	}
+	if v, _, _ := tx.RawSignatureValues(); v.Uint64() > 1 {
+		t.Errorf("V should be the bare recovery id, got %v", v)
+	}
+	// The envelope is the type byte followed by the RLP payload
+	enc, err := tx.MarshalBinary()
+	if err != nil {
+		t.Fatalf("encode error: %v", err)
+	}
+	if enc[0] != AccessListTxType {
+		t.Fatalf("envelope starts with %#x", enc[0])
+	}
+	if want := crypto.Keccak256Hash(enc); tx.Hash() != want {
+		t.Errorf("hash mismatch: have %x, want %x", tx.Hash(), want)
+	}
+	if tx.Size() != common.StorageSize(len(enc)) {
+		t.Errorf("size mismatch: have %v, want %d", tx.Size(), len(enc))
+	}
+	var decoded Transaction
+	if err := decoded.UnmarshalBinary(enc); err != nil {
+		t.Fatalf("decode error: %v", err)
+	}
+	if decoded.Hash() != tx.Hash() {
+		t.Errorf("decoded tx differs: %v", &decoded)
+	}
+	if sender, err := Sender(signer, &decoded); err != nil || sender != from {
+		t.Errorf("sender mismatch: have %x (%v), want %x", sender, err, from)
+	}
+	// Inside a list, as in block bodies, the envelope is an RLP string
+	list, err := rlp.EncodeToBytes(Transactions{tx, rightvrsTx})
+	if err != nil {
+		t.Fatalf("list encode error: %v", err)
+	}
+	var txs Transactions
+	if err := rlp.DecodeBytes(list, &txs); err != nil {
+		t.Fatalf("list decode error: %v", err)
+	}
+	if len(txs) != 2 || txs[0].Hash() != tx.Hash() || txs[1].Hash() != rightvrsTx.Hash() {
+		t.Errorf("list round trip failed: %v", txs)
+	}
+	if !bytes.Equal(Transactions(txs).GetRlp(0), enc) {
+		t.Errorf("derivable list encoding differs from the envelope")
+	}
+	// JSON round trip
+	data, err := json.Marshal(tx)
+	if err != nil {
+		t.Fatalf("json.Marshal failed: %v", err)
+	}
+	var parsed *Transaction
+	if err := json.Unmarshal(data, &parsed); err != nil {
+		t.Fatalf("json.Unmarshal failed: %v", err)
+	}
+	if parsed.Hash() != tx.Hash() || len(parsed.AccessList()) != 2 {
+		t.Errorf("parsed tx differs from original tx, want %v, got %v", tx, parsed)
+	}
+	// Signers predating the fork refuse the transaction
+	for _, old := range []Signer{NewEIP155Signer(common.Big2), HomesteadSigner{}, FrontierSigner{}} {
+		if _, err := old.Sender(tx); err != ErrTxTypeNotSupported {
+			t.Errorf("%T: expected %v, got %v", old, ErrTxTypeNotSupported, err)
+		}
+	}
+	if _, err := NewEIP2930Signer(common.Big1).Sender(tx); err != ErrInvalidChainId {
+		t.Errorf("expected %v, got %v", ErrInvalidChainId, err)
+	}
+}
+
+func TestAccessListReceiptEncoding(t *testing.T) {
+	receipt := NewReceipt(nil, false, 21000)
+	receipt.Type = AccessListTxType
+	receipt.TxHash = common.Hash{1}
+	receipt.GasUsed = 21000
+	receipt.Logs = []*Log{{Address: common.Address{1}, Topics: []common.Hash{{2}}, Data: []byte{3}}}
+
+	enc, err := receipt.MarshalBinary()
+	if err != nil {
+		t.Fatalf("encode error: %v", err)
+	}
+	if enc[0] != AccessListTxType || !bytes.Equal(Receipts{receipt}.GetRlp(0), enc) {
+		t.Fatalf("unexpected consensus encoding %x", enc)
+	}
+	var dec Receipt
+	if err := rlp.DecodeBytes(mustEncode(t, receipt), &dec); err != nil {
+		t.Fatalf("decode error: %v", err)
+	}
+	if dec.Type != AccessListTxType || dec.Status != ReceiptStatusSuccessful || dec.CumulativeGasUsed != 21000 {
+		t.Errorf("decoded receipt differs: %v", &dec)
+	}
+	// The storage encoding keeps the type, and stays unchanged for legacy receipts
+	var stored ReceiptForStorage
+	if err := rlp.DecodeBytes(mustEncode(t, (*ReceiptForStorage)(receipt)), &stored); err != nil {
+		t.Fatalf("storage decode error: %v", err)
+	}
+	if stored.Type != AccessListTxType || stored.TxHash != receipt.TxHash || stored.GasUsed != 21000 {
+		t.Errorf("stored receipt differs: %v", (*Receipt)(&stored))
+	}
+	legacy := *receipt
+	legacy.Type = LegacyTxType
+	var fields []rlp.RawValue
+	if err := rlp.DecodeBytes(mustEncode(t, (*ReceiptForStorage)(&legacy)), &fields); err != nil || len(fields) != 7 {
+		t.Errorf("legacy storage encoding has %d fields (%v), want 7", len(fields), err)
+	}
+}
+
+func mustEncode(t *testing.T, val interface{}) []byte {
+	enc, err := rlp.EncodeToBytes(val)
+	if err != nil {
+		t.Fatalf("encode error: %v", err)
+	}
+	return enc
+}
@@ -0,0 +1,145 @@
+// Copyright 2018 The aquachain Authors
+// This file is part of the aquachain library.
+//
+// The aquachain library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The aquachain library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the aquachain library. If not, see <http://www.gnu.org/licenses/>.
+
+package vm
+
+import (
+	"bytes"
+	"math/big"
+	"sort"
+	"time"
+
+	"gitlab.com/aquachain/aquachain/common"
+	"gitlab.com/aquachain/aquachain/core/types"
+)
+
+// accessList is the set of accounts and storage slots collected by the
+// AccessListTracer.
+type accessList map[common.Address]map[common.Hash]struct{}
+
+func (al accessList) addAddress(address common.Address) {
+	if _, ok := al[address]; !ok {
+		al[address] = make(map[common.Hash]struct{})
+	}
+}
+
+func (al accessList) addSlot(address common.Address, slot common.Hash) {
+	al.addAddress(address)
+	al[address][slot] = struct{}{}
+}
+
+// AccessListTracer is a Tracer collecting the accounts and storage slots a
+// transaction touches, as the EIP-2930 access list for it.
+type AccessListTracer struct {
+	excl map[common.Address]struct{} // Accounts left out of the list
+	list accessList
+}
+
+// NewAccessListTracer creates a tracer starting from the given access list.
+// The sender, the recipient and the precompiles are left out of the list,
+// unless the given access list mentions their storage.
+func NewAccessListTracer(acl types.AccessList, from, to common.Address, precompiles []common.Address) *AccessListTracer {
+	excl := map[common.Address]struct{}{from: {}, to: {}}
+	for _, addr := range precompiles {
+		excl[addr] = struct{}{}
+	}
+	list := make(accessList)
+	for _, tuple := range acl {
+		if _, ok := excl[tuple.Address]; !ok {
+			list.addAddress(tuple.Address)
+		}
+		for _, slot := range tuple.StorageKeys {
+			list.addSlot(tuple.Address, slot)
+		}
+	}
+	return &AccessListTracer{excl: excl, list: list}
+}
+
+func (a *AccessListTracer) CaptureStart(from common.Address, to common.Address, call bool, input []byte, gas uint64, value *big.Int) error {
+	return nil
+}
+
+// CaptureState records the storage slot or the account the operation touches.
+func (a *AccessListTracer) CaptureState(env *EVM, pc uint64, op OpCode, gas, cost uint64, memory *Memory, stack *Stack, contract *Contract, depth int, err error) error {
+	stackLen := len(stack.Data())
+	switch op {
+	case SLOAD, SSTORE:
+		if stackLen >= 1 {
+			a.list.addSlot(contract.Address(), common.BigToHash(stack.Back(0)))
+		}
+	case EXTCODECOPY, EXTCODESIZE, BALANCE, SELFDESTRUCT:
+		if stackLen >= 1 {
+			a.addAddress(common.BigToAddress(stack.Back(0)))
+		}
+	case CALL, CALLCODE, DELEGATECALL, STATICCALL:
+		if stackLen >= 5 {
+			a.addAddress(common.BigToAddress(stack.Back(1)))
+		}
+	}
+	return nil
+}
+
+func (a *AccessListTracer) addAddress(address common.Address) {
+	if _, ok := a.excl[address]; !ok {
+		a.list.addAddress(address)
+	}
+}
+
+func (a *AccessListTracer) CaptureFault(env *EVM, pc uint64, op OpCode, gas, cost uint64, memory *Memory, stack *Stack, contract *Contract, depth int, err error) error {
+	return nil
+}
+
+func (a *AccessListTracer) CaptureEnd(output []byte, gasUsed uint64, t time.Duration, err error) error {
+	return nil
+}
+
+// AccessList returns the collected access list, sorted by account and slot.
+func (a *AccessListTracer) AccessList() types.AccessList {
+	acl := make(types.AccessList, 0, len(a.list))
+	for addr, slots := range a.list {
+		tuple := types.AccessTuple{Address: addr, StorageKeys: make([]common.Hash, 0, len(slots))}
+		for slot := range slots {
+			tuple.StorageKeys = append(tuple.StorageKeys, slot)
+		}
+		sort.Slice(tuple.StorageKeys, func(i, j int) bool {
+			return bytes.Compare(tuple.StorageKeys[i][:], tuple.StorageKeys[j][:]) < 0
+		})
+		acl = append(acl, tuple)
+	}
+	sort.Slice(acl, func(i, j int) bool {
+		return bytes.Compare(acl[i].Address[:], acl[j].Address[:]) < 0
+	})
+	return acl
+}
+
+// Equal reports whether both tracers collected the same access list.
+func (a *AccessListTracer) Equal(other *AccessListTracer) bool {
+	if other == nil || len(a.list) != len(other.list) {
+		return false
+	}
+	for addr, slots := range a.list {
+		otherSlots, ok := other.list[addr]
+		if !ok || len(slots) != len(otherSlots) {
+			return false
+		}
+		for slot := range slots {
+			if _, ok := otherSlots[slot]; !ok {
+				return false
+			}
+		}
+	}
+	return true
+}
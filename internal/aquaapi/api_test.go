@@ -0,0 +1,178 @@
+// Copyright 2018 The aquachain Authors
+// This file is part of the aquachain library.
+//
+// The aquachain library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The aquachain library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the aquachain library. If not, see <http://www.gnu.org/licenses/>.
+
+package aquaapi
+
+import (
+	"context"
+	"math/big"
+	"testing"
+
+	"gitlab.com/aquachain/aquachain/aquadb"
+	"gitlab.com/aquachain/aquachain/common"
+	"gitlab.com/aquachain/aquachain/common/math"
+	"gitlab.com/aquachain/aquachain/consensus/aquahash"
+	"gitlab.com/aquachain/aquachain/core"
+	"gitlab.com/aquachain/aquachain/core/state"
+	"gitlab.com/aquachain/aquachain/core/types"
+	"gitlab.com/aquachain/aquachain/core/vm"
+	"gitlab.com/aquachain/aquachain/crypto"
+	"gitlab.com/aquachain/aquachain/params"
+	"gitlab.com/aquachain/aquachain/rpc"
+)
+
+var (
+	testKey, _ = crypto.HexToBtcec("b71c71a67e1177ad4e901695e1b4b9ee17ae16c6668d313eac2f96dbcda3f291")
+	testAddr   = crypto.PubkeyToAddress(testKey.PubKey())
+)
+
+// testBackend serves the call related APIs from a local chain. The pending
+// block is the current head.
+type testBackend struct {
+	Backend
+	chain *core.BlockChain
+}
+
+// newTestBackend creates a backend on top of a chain with a funded test account
+// and the given number of generated blocks.
+func newTestBackend(t *testing.T, n int, gen func(int, *core.BlockGen)) *testBackend {
+	var (
+		db    = aquadb.NewMemDatabase()
+		gspec = &core.Genesis{
+			Config: params.TestChainConfig,
+			Alloc:  core.GenesisAlloc{testAddr: {Balance: big.NewInt(params.Aqua)}},
+		}
+		genesis = gspec.MustCommit(db)
+	)
+	blocks, _ := core.GenerateChain(context.TODO(), gspec.Config, genesis, aquahash.NewFaker(), db, n, gen)
+	chain, err := core.NewBlockChain(context.TODO(), db, nil, gspec.Config, aquahash.NewFaker(), vm.Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(chain.Stop)
+	if n > 0 {
+		if _, err := chain.InsertChain(blocks); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return &testBackend{chain: chain}
+}
+
+func (b *testBackend) ChainConfig() *params.ChainConfig { return b.chain.Config() }
+func (b *testBackend) CurrentBlock() *types.Block       { return b.chain.CurrentBlock() }
+
+func (b *testBackend) GetBlock(ctx context.Context, hash common.Hash) (*types.Block, error) {
+	return b.chain.GetBlockByHash(hash), nil
+}
+
+func (b *testBackend) BlockByNumber(ctx context.Context, blockNr rpc.BlockNumber) (*types.Block, error) {
+	if blockNr == rpc.PendingBlockNumber || blockNr == rpc.LatestBlockNumber {
+		return b.chain.CurrentBlock(), nil
+	}
+	return b.chain.GetBlockByNumber(uint64(blockNr)), nil
+}
+
+func (b *testBackend) HeaderByNumber(ctx context.Context, blockNr rpc.BlockNumber) (*types.Header, error) {
+	block, _ := b.BlockByNumber(ctx, blockNr)
+	if block == nil {
+		return nil, nil
+	}
+	return block.Header(), nil
+}
+
+func (b *testBackend) StateAndHeaderByNumber(ctx context.Context, blockNr rpc.BlockNumber) (*state.StateDB, *types.Header, error) {
+	header, _ := b.HeaderByNumber(ctx, blockNr)
+	if header == nil {
+		return nil, nil, nil
+	}
+	statedb, err := b.chain.StateAt(header.Root)
+	return statedb, header, err
+}
+
+func (b *testBackend) StateAndHeaderByNumberOrHash(ctx context.Context, blockNrOrHash rpc.BlockNumberOrHash) (*state.StateDB, *types.Header, error) {
+	if blockNr, ok := blockNrOrHash.Number(); ok {
+		return b.StateAndHeaderByNumber(ctx, blockNr)
+	}
+	hash, _ := blockNrOrHash.Hash()
+	header := b.chain.GetHeaderByHash(hash)
+	if header == nil {
+		return nil, nil, nil
+	}
+	statedb, err := b.chain.StateAt(header.Root)
+	return statedb, header, err
+}
+
+func (b *testBackend) GetEVM(ctx context.Context, msg core.Message, state *state.StateDB, header *types.Header, vmCfg vm.Config) (*vm.EVM, func() error, error) {
+	state.SetBalance(msg.From(), math.MaxBig256)
+	context := core.NewEVMContext(msg, header, b.chain, nil)
+	return vm.NewEVM(context, state, b.chain.Config(), vmCfg), func() error { return nil }, nil
+}
+
+// deployCode returns a contract creation transaction from the test account
+// deploying the given runtime code.
+func deployCode(t *testing.T, nonce uint64, code []byte) *types.Transaction {
+	if len(code) > 0xff {
+		t.Fatalf("runtime code too long: %d bytes", len(code))
+	}
+	size := byte(len(code))
+	// PUSH1 size, PUSH1 12, PUSH1 0, CODECOPY, PUSH1 size, PUSH1 0, RETURN
+	init := append([]byte{0x60, size, 0x60, 0x0c, 0x60, 0x00, 0x39, 0x60, size, 0x60, 0x00, 0xf3}, code...)
+	tx, err := types.SignTx(types.NewContractCreation(nonce, new(big.Int), 1000000, big.NewInt(1), init), types.HomesteadSigner{}, testKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return tx
+}
+
+// Tests that gas is estimated on the state of the block selected by number or
+// hash, with the state overrides applied on top.
+func TestEstimateGasBlockNrOrHash(t *testing.T) {
+	// A contract which stores 1 into slot 0, deployed in block 1
+	b := newTestBackend(t, 1, func(i int, gen *core.BlockGen) {
+		gen.AddTx(deployCode(t, 0, common.FromHex("600160005500")))
+	})
+	var (
+		api      = NewPublicBlockChainAPI(b)
+		contract = crypto.CreateAddress(testAddr, 0)
+		args     = CallArgs{From: testAddr, To: &contract}
+		genesis  = rpc.BlockNumberOrHashWithHash(b.chain.Genesis().Hash())
+		latest   = rpc.BlockNumberOrHashWithNumber(rpc.LatestBlockNumber)
+		diff     = map[common.Hash]common.Hash{{}: common.BigToHash(common.Big1)}
+	)
+	tests := []struct {
+		block     *rpc.BlockNumberOrHash
+		overrides *StateOverride
+		want      uint64
+	}{
+		{&genesis, nil, params.TxGas}, // no code yet
+		{&latest, nil, 41006},         // fresh slot
+		{nil, nil, 41006},             // pending is the head
+		{&latest, &StateOverride{contract: {StateDiff: &diff}}, 26006}, // slot already set
+	}
+	for i, tt := range tests {
+		gas, err := api.EstimateGas(context.Background(), args, tt.block, tt.overrides, nil)
+		if err != nil {
+			t.Fatalf("test %d: estimate failed: %v", i, err)
+		}
+		if uint64(gas) != tt.want {
+			t.Errorf("test %d: gas mismatch: have %d, want %d", i, gas, tt.want)
+		}
+	}
+	unknown := rpc.BlockNumberOrHashWithHash(common.Hash{1})
+	if _, err := api.EstimateGas(context.Background(), args, &unknown, nil, nil); err == nil {
+		t.Error("estimate on unknown block succeeded")
+	}
+}
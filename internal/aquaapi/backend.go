@@ -40,6 +40,7 @@ type Backend interface {
 	// General Aquachain API
 	SyncProgress() aquachain.SyncProgress
 	ProtocolVersion() int
+	PeerCount() int
 	SuggestPrice(ctx context.Context) (*big.Int, error)
 	ChainDb() aquadb.Database
 	EventMux() *event.TypeMux
@@ -50,6 +51,7 @@ type Backend interface {
 	HeaderByNumber(ctx context.Context, blockNr rpc.BlockNumber) (*types.Header, error)
 	BlockByNumber(ctx context.Context, blockNr rpc.BlockNumber) (*types.Block, error)
 	StateAndHeaderByNumber(ctx context.Context, blockNr rpc.BlockNumber) (*state.StateDB, *types.Header, error)
+	StateAndHeaderByNumberOrHash(ctx context.Context, blockNrOrHash rpc.BlockNumberOrHash) (*state.StateDB, *types.Header, error)
 	GetBlock(ctx context.Context, blockHash common.Hash) (*types.Block, error)
 	GetReceipts(ctx context.Context, blockHash common.Hash) (types.Receipts, error)
 	GetTd(blockHash common.Hash) *big.Int
@@ -64,6 +66,7 @@ type Backend interface {
 	GetPoolTransaction(txHash common.Hash) *types.Transaction
 	GetPoolNonce(ctx context.Context, addr common.Address) (uint64, error)
 	Stats() (pending int, queued int)
+	TxPoolStatus() core.TxPoolStatus
 	TxPoolContent() (map[common.Address]types.Transactions, map[common.Address]types.Transactions)
 	SubscribeTxPreEvent(chan<- core.TxPreEvent) event.Subscription
 
@@ -134,9 +137,9 @@ func GetAPIs(apiBackend Backend) []rpc.API {
 			// 	Service:   NewPublicAccountAPI(apiBackend.AccountManager()),
 			// 	Public:    true,
 		}, { // btcrpc
-			Namespace: "btc",
+			Namespace: rpc.BtcNamespace,
 			Version:   "1.0",
-			Service:   NewPublicBitcoinAPI(NewPublicBlockChainAPI(apiBackend)), // TODO: fix
+			Service:   NewPublicBitcoinAPI(apiBackend, nonceLock),
 		},
 	}
 }
@@ -16,16 +16,798 @@
 
 package aquaapi
 
+import (
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"strings"
+	"sync"
+	"time"
+
+	"gitlab.com/aquachain/aquachain/aqua/accounts"
+	"gitlab.com/aquachain/aquachain/aqua/accounts/keystore"
+	"gitlab.com/aquachain/aquachain/common"
+	"gitlab.com/aquachain/aquachain/common/hexutil"
+	"gitlab.com/aquachain/aquachain/core"
+	"gitlab.com/aquachain/aquachain/core/types"
+	"gitlab.com/aquachain/aquachain/params"
+	"gitlab.com/aquachain/aquachain/rlp"
+	"gitlab.com/aquachain/aquachain/rpc"
+)
+
+// Error codes of bitcoind, returned by the btc API so that clients built
+// against bitcoind handle failures the same way.
+const (
+	btcErrMisc                 = -1  // RPC_MISC_ERROR
+	btcErrType                 = -3  // RPC_TYPE_ERROR
+	btcErrWallet               = -4  // RPC_WALLET_ERROR
+	btcErrInvalidAddressOrKey  = -5  // RPC_INVALID_ADDRESS_OR_KEY
+	btcErrInsufficientFunds    = -6  // RPC_WALLET_INSUFFICIENT_FUNDS
+	btcErrInvalidParameter     = -8  // RPC_INVALID_PARAMETER
+	btcErrWalletUnlockNeeded   = -13 // RPC_WALLET_UNLOCK_NEEDED
+	btcErrPassphraseIncorrect  = -14 // RPC_WALLET_PASSPHRASE_INCORRECT
+	btcErrWalletNotFound       = -18 // RPC_WALLET_NOT_FOUND
+	btcErrTransactionRejected  = -26 // RPC_VERIFY_REJECTED
+	btcErrMethodDeprecated     = -32 // RPC_METHOD_DEPRECATED
+	btcMaxUnlockTimeout        = 100000000
+	btcListTransactionsMaxScan = 100000 // Blocks searched back by listtransactions
+)
+
+// btcError is an error carrying a bitcoind error code.
+type btcError struct {
+	code    int
+	message string
+}
+
+func (e *btcError) Error() string  { return e.message }
+func (e *btcError) ErrorCode() int { return e.code }
+
+var (
+	errBtcNoWallet     = &btcError{btcErrWalletNotFound, "Requested wallet does not exist or is not loaded"}
+	errBtcUnlockNeeded = &btcError{btcErrWalletUnlockNeeded, "Error: Please enter the wallet passphrase with walletpassphrase first."}
+	errBtcBadAmount    = &btcError{btcErrType, "Invalid amount"}
+)
+
+var weiPerAqua = big.NewInt(params.Aqua)
+
+// BtcAmount is an amount of wei, written as a decimal number of aqua the way
+// bitcoind writes amounts of bitcoin.
+type BtcAmount big.Int
+
+func newBtcAmount(wei *big.Int) *BtcAmount {
+	return (*BtcAmount)(new(big.Int).Set(wei))
+}
+
+// MarshalJSON implements json.Marshaler.
+func (a *BtcAmount) MarshalJSON() ([]byte, error) {
+	wei := (*big.Int)(a)
+	whole, frac := new(big.Int).QuoRem(new(big.Int).Abs(wei), weiPerAqua, new(big.Int))
+	text := whole.String()
+	if wei.Sign() < 0 {
+		text = "-" + text
+	}
+	if frac.Sign() != 0 {
+		text += "." + strings.TrimRight(fmt.Sprintf("%018s", frac.String()), "0")
+	}
+	return []byte(text), nil
+}
+
+// UnmarshalJSON implements json.Unmarshaler, taking a number of aqua with up
+// to 18 decimals, or a string holding one.
+func (a *BtcAmount) UnmarshalJSON(input []byte) error {
+	text := strings.Trim(string(input), `"`)
+	if strings.Contains(text, "/") {
+		return errBtcBadAmount
+	}
+	amount, ok := new(big.Rat).SetString(text)
+	if !ok {
+		return errBtcBadAmount
+	}
+	amount.Mul(amount, new(big.Rat).SetInt(weiPerAqua))
+	if !amount.IsInt() {
+		return errBtcBadAmount
+	}
+	*a = BtcAmount(*amount.Num())
+	return nil
+}
+
+// BtcVerbosity is the verbosity argument of getblock and getrawtransaction,
+// given as a boolean or a number like bitcoind takes it.
+type BtcVerbosity int
+
+// UnmarshalJSON implements json.Unmarshaler.
+func (v *BtcVerbosity) UnmarshalJSON(input []byte) error {
+	switch string(input) {
+	case "true":
+		*v = 1
+	case "false":
+		*v = 0
+	default:
+		var n int
+		if err := json.Unmarshal(input, &n); err != nil {
+			return err
+		}
+		*v = BtcVerbosity(n)
+	}
+	return nil
+}
+
+// btcHash formats a hash the way bitcoind does, as bare hex.
+func btcHash(hash common.Hash) string {
+	return hex.EncodeToString(hash[:])
+}
+
+// parseBtcHash parses a hash given as bare or 0x-prefixed hex.
+func parseBtcHash(name, text string) (common.Hash, error) {
+	raw, err := hex.DecodeString(strings.TrimPrefix(text, "0x"))
+	if err != nil || len(raw) != common.HashLength {
+		return common.Hash{}, &btcError{btcErrInvalidParameter, fmt.Sprintf("%s must be a hexadecimal string of length 64 (not %d, for '%s')", name, len(text), text)}
+	}
+	return common.BytesToHash(raw), nil
+}
+
+// BtcBlock is a block as returned by getblock.
+type BtcBlock struct {
+	Hash              string      `json:"hash"`
+	Confirmations     int64       `json:"confirmations"`
+	Size              int         `json:"size"`
+	Height            uint64      `json:"height"`
+	Version           int         `json:"version"`
+	Merkleroot        string      `json:"merkleroot"`
+	Tx                interface{} `json:"tx"`
+	Time              int64       `json:"time"`
+	Nonce             uint64      `json:"nonce"`
+	Difficulty        *big.Int    `json:"difficulty"`
+	Chainwork         string      `json:"chainwork"`
+	NTx               int         `json:"nTx"`
+	Previousblockhash string      `json:"previousblockhash,omitempty"`
+	Nextblockhash     string      `json:"nextblockhash,omitempty"`
+}
+
+// BtcTransaction is a transaction as returned by getrawtransaction. There is a
+// single input, the sender, spending its nonce, and a single output, the
+// recipient.
+type BtcTransaction struct {
+	Txid          string    `json:"txid"`
+	Hash          string    `json:"hash"`
+	Version       int       `json:"version"`
+	Size          int       `json:"size"`
+	Vin           []BtcVin  `json:"vin"`
+	Vout          []BtcVout `json:"vout"`
+	Hex           string    `json:"hex"`
+	Blockhash     string    `json:"blockhash,omitempty"`
+	Confirmations uint64    `json:"confirmations,omitempty"`
+	Time          int64     `json:"time,omitempty"`
+	Blocktime     int64     `json:"blocktime,omitempty"`
+}
+
+// BtcVin is the input of a BtcTransaction.
+type BtcVin struct {
+	Address  string `json:"address"`
+	Sequence uint64 `json:"sequence"`
+}
+
+// BtcVout is the output of a BtcTransaction.
+type BtcVout struct {
+	Value        *BtcAmount      `json:"value"`
+	N            int             `json:"n"`
+	ScriptPubKey BtcScriptPubKey `json:"scriptPubKey"`
+}
+
+// BtcScriptPubKey describes the recipient of a BtcTransaction: "pubkeyhash" for
+// plain transfers, "nonstandard" for contract calls and creations, with the
+// call data as hex.
+type BtcScriptPubKey struct {
+	Hex       string   `json:"hex"`
+	Type      string   `json:"type"`
+	Addresses []string `json:"addresses,omitempty"`
+}
+
+// BtcWalletTransaction is an entry of listtransactions.
+type BtcWalletTransaction struct {
+	Address       string     `json:"address"`
+	Category      string     `json:"category"`
+	Amount        *BtcAmount `json:"amount"`
+	Fee           *BtcAmount `json:"fee,omitempty"`
+	Confirmations uint64     `json:"confirmations"`
+	Blockhash     string     `json:"blockhash,omitempty"`
+	Blockheight   uint64     `json:"blockheight,omitempty"`
+	Blockindex    uint64     `json:"blockindex,omitempty"`
+	Blocktime     int64      `json:"blocktime,omitempty"`
+	Txid          string     `json:"txid"`
+	Time          int64      `json:"time"`
+	Timereceived  int64      `json:"timereceived"`
+}
+
+// BtcInfo is the result of getinfo.
+type BtcInfo struct {
+	Version         int        `json:"version"`
+	Protocolversion int        `json:"protocolversion"`
+	Balance         *BtcAmount `json:"balance,omitempty"`
+	Blocks          int64      `json:"blocks"`
+	Timeoffset      int        `json:"timeoffset"`
+	Connections     int        `json:"connections"`
+	Proxy           string     `json:"proxy"`
+	Difficulty      *big.Int   `json:"difficulty"`
+	Testnet         bool       `json:"testnet"`
+	UnlockedUntil   *int64     `json:"unlocked_until,omitempty"`
+	Paytxfee        *BtcAmount `json:"paytxfee"`
+	Errors          string     `json:"errors"`
+}
+
+// PublicBitcoinAPI offers a subset of the bitcoind JSON-RPC API, mapped onto the
+// chain and the keystore, for software built against bitcoind. Its methods are
+// served under their bitcoind names, without namespace.
 type PublicBitcoinAPI struct {
-	a *PublicBlockChainAPI
+	b         Backend
+	nonceLock *AddrLocker
+
+	mu            sync.Mutex
+	passphrase    string      // Passphrase given to walletpassphrase, for getnewaddress
+	unlockedUntil time.Time   // Expiry of the passphrase
+	forget        *time.Timer // Drops the passphrase at expiry
 }
 
-func NewPublicBitcoinAPI(a *PublicBlockChainAPI) *PublicBitcoinAPI {
-	return &PublicBitcoinAPI{a: a}
+func NewPublicBitcoinAPI(b Backend, nonceLock *AddrLocker) *PublicBitcoinAPI {
+	return &PublicBitcoinAPI{b: b, nonceLock: nonceLock}
 }
 
 // START BTC-JSON METHODS
 
 func (p *PublicBitcoinAPI) Getblockcount() int64 {
-	return p.a.BlockNumber().Int64()
+	return p.b.CurrentBlock().Number().Int64()
+}
+
+// Getbestblockhash returns the hash of the head block.
+func (p *PublicBitcoinAPI) Getbestblockhash() string {
+	return btcHash(p.b.CurrentBlock().Hash())
+}
+
+// Getblockhash returns the hash of the canonical block at the given height.
+func (p *PublicBitcoinAPI) Getblockhash(ctx context.Context, height int64) (string, error) {
+	if height < 0 || height > p.Getblockcount() {
+		return "", &btcError{btcErrInvalidParameter, "Block height out of range"}
+	}
+	header, err := p.b.HeaderByNumber(ctx, rpc.BlockNumber(height))
+	if err != nil || header == nil {
+		return "", &btcError{btcErrInvalidParameter, "Block height out of range"}
+	}
+	return btcHash(header.Hash()), nil
+}
+
+// Getblock returns the block of the given hash: as hex-encoded RLP with
+// verbosity 0, as a BtcBlock listing transaction ids with verbosity 1 (the
+// default), and listing decoded transactions with verbosity 2.
+func (p *PublicBitcoinAPI) Getblock(ctx context.Context, blockhash string, verbosity *BtcVerbosity) (interface{}, error) {
+	hash, err := parseBtcHash("blockhash", blockhash)
+	if err != nil {
+		return nil, err
+	}
+	block, err := p.b.GetBlock(ctx, hash)
+	if err != nil || block == nil {
+		return nil, &btcError{btcErrInvalidAddressOrKey, "Block not found"}
+	}
+	level := BtcVerbosity(1)
+	if verbosity != nil {
+		level = *verbosity
+	}
+	if level <= 0 {
+		enc, err := rlp.EncodeToBytes(block)
+		if err != nil {
+			return nil, &btcError{btcErrMisc, err.Error()}
+		}
+		return hex.EncodeToString(enc), nil
+	}
+	var (
+		db     = p.b.ChainDb()
+		number = block.NumberU64()
+		head   = p.b.CurrentBlock().NumberU64()
+		result = &BtcBlock{
+			Hash:          btcHash(hash),
+			Confirmations: -1,
+			Size:          int(block.Size()),
+			Height:        number,
+			Version:       int(block.Version()),
+			Merkleroot:    btcHash(block.TxHash()),
+			Time:          block.Time().Int64(),
+			Nonce:         block.Nonce(),
+			Difficulty:    block.Difficulty(),
+			NTx:           len(block.Transactions()),
+		}
+	)
+	if core.GetCanonicalHash(db, number) == hash {
+		result.Confirmations = int64(head - number + 1)
+		if next := core.GetCanonicalHash(db, number+1); next != (common.Hash{}) {
+			result.Nextblockhash = btcHash(next)
+		}
+	}
+	if number > 0 {
+		result.Previousblockhash = btcHash(block.ParentHash())
+	}
+	if td := p.b.GetTd(hash); td != nil {
+		result.Chainwork = fmt.Sprintf("%064x", td)
+	}
+	if level == 1 {
+		txids := make([]string, len(block.Transactions()))
+		for i, tx := range block.Transactions() {
+			txids[i] = btcHash(tx.Hash())
+		}
+		result.Tx = txids
+	} else {
+		txs := make([]*BtcTransaction, len(block.Transactions()))
+		for i, tx := range block.Transactions() {
+			txs[i] = p.newBtcTransaction(tx, block.Header(), head)
+		}
+		result.Tx = txs
+	}
+	return result, nil
+}
+
+// Getrawtransaction returns the transaction of the given id, mined or pending:
+// as hex (the EIP-2718 envelope for typed transactions), or as a
+// BtcTransaction when verbose.
+func (p *PublicBitcoinAPI) Getrawtransaction(ctx context.Context, txid string, verbose *BtcVerbosity) (interface{}, error) {
+	hash, err := parseBtcHash("txid", txid)
+	if err != nil {
+		return nil, err
+	}
+	var header *types.Header
+	tx, blockHash, _, _ := core.GetTransaction(p.b.ChainDb(), hash)
+	if tx != nil {
+		block, err := p.b.GetBlock(ctx, blockHash)
+		if err != nil || block == nil {
+			return nil, &btcError{btcErrMisc, "Block not available"}
+		}
+		header = block.Header()
+	} else if tx = p.b.GetPoolTransaction(hash); tx == nil {
+		return nil, &btcError{btcErrInvalidAddressOrKey, "No such mempool or blockchain transaction"}
+	}
+	if verbose == nil || *verbose <= 0 {
+		enc, err := tx.MarshalBinary()
+		if err != nil {
+			return nil, &btcError{btcErrMisc, err.Error()}
+		}
+		return hex.EncodeToString(enc), nil
+	}
+	return p.newBtcTransaction(tx, header, p.b.CurrentBlock().NumberU64()), nil
+}
+
+// newBtcTransaction converts a transaction, mined in the block of the given
+// header unless it is nil.
+func (p *PublicBitcoinAPI) newBtcTransaction(tx *types.Transaction, header *types.Header, head uint64) *BtcTransaction {
+	enc, _ := tx.MarshalBinary()
+	from, _ := types.Sender(types.NewEIP2930Signer(p.b.ChainConfig().ChainId), tx)
+	out := BtcVout{
+		Value:        newBtcAmount(tx.Value()),
+		ScriptPubKey: BtcScriptPubKey{Hex: hex.EncodeToString(tx.Data()), Type: "nonstandard"},
+	}
+	if to := tx.To(); to != nil {
+		out.ScriptPubKey.Addresses = []string{to.Hex()}
+		if len(tx.Data()) == 0 {
+			out.ScriptPubKey.Type = "pubkeyhash"
+		}
+	}
+	result := &BtcTransaction{
+		Txid:    btcHash(tx.Hash()),
+		Hash:    btcHash(tx.Hash()),
+		Version: int(tx.Type()),
+		Size:    len(enc),
+		Vin:     []BtcVin{{Address: from.Hex(), Sequence: tx.Nonce()}},
+		Vout:    []BtcVout{out},
+		Hex:     hex.EncodeToString(enc),
+	}
+	if header != nil {
+		result.Blockhash = btcHash(header.Hash())
+		result.Confirmations = head - header.Number.Uint64() + 1
+		result.Time = header.Time.Int64()
+		result.Blocktime = header.Time.Int64()
+	}
+	return result
+}
+
+// walletAccounts returns the accounts of all wallets.
+func (p *PublicBitcoinAPI) walletAccounts() ([]accounts.Account, error) {
+	am := p.b.AccountManager()
+	if am == nil {
+		return nil, errBtcNoWallet
+	}
+	var all []accounts.Account
+	for _, wallet := range am.Wallets() {
+		all = append(all, wallet.Accounts()...)
+	}
+	return all, nil
+}
+
+// Getbalance returns the total balance of the wallet accounts, in the state
+// with at least minconf confirmations (default: 1, the head block; 0 is the
+// pending state).
+func (p *PublicBitcoinAPI) Getbalance(ctx context.Context, dummy *string, minconf *int) (*BtcAmount, error) {
+	if dummy != nil && *dummy != "*" {
+		return nil, &btcError{btcErrMethodDeprecated, `dummy first argument must be excluded or set to "*".`}
+	}
+	all, err := p.walletAccounts()
+	if err != nil {
+		return nil, err
+	}
+	blockNr := rpc.PendingBlockNumber
+	if confs := 1; minconf == nil || *minconf > 0 {
+		if minconf != nil {
+			confs = *minconf
+		}
+		number := p.Getblockcount() - int64(confs-1)
+		if number < 0 {
+			return newBtcAmount(new(big.Int)), nil
+		}
+		blockNr = rpc.BlockNumber(number)
+	}
+	state, _, err := p.b.StateAndHeaderByNumber(ctx, blockNr)
+	if state == nil || err != nil {
+		return nil, &btcError{btcErrMisc, fmt.Sprintf("state not available: %v", err)}
+	}
+	total := new(big.Int)
+	for _, account := range all {
+		total.Add(total, state.GetBalance(account.Address))
+	}
+	return (*BtcAmount)(total), nil
+}
+
+// Listtransactions returns the most recent transactions sending from or to the
+// wallet accounts, skipping the first skip ones, oldest first. Pending
+// transactions come with no confirmations. Aquachain keeps no index of
+// transactions by account, so only the latest blocks are searched.
+func (p *PublicBitcoinAPI) Listtransactions(ctx context.Context, label *string, count *int, skip *int) ([]*BtcWalletTransaction, error) {
+	n, from := 10, 0
+	if count != nil {
+		n = *count
+	}
+	if skip != nil {
+		from = *skip
+	}
+	if n < 0 {
+		return nil, &btcError{btcErrInvalidParameter, "Negative count"}
+	}
+	if from < 0 {
+		return nil, &btcError{btcErrInvalidParameter, "Negative from"}
+	}
+	all, err := p.walletAccounts()
+	if err != nil {
+		return nil, err
+	}
+	mine := make(map[common.Address]bool, len(all))
+	for _, account := range all {
+		mine[account.Address] = true
+	}
+	var (
+		list   []*BtcWalletTransaction // Newest first
+		signer = types.NewEIP2930Signer(p.b.ChainConfig().ChainId)
+		now    = time.Now().Unix()
+	)
+	// add appends the entries of a transaction, mined in the block of the
+	// given header unless it is nil
+	add := func(tx *types.Transaction, header *types.Header, index uint64, receipts func() types.Receipts) {
+		sender, err := types.Sender(signer, tx)
+		if err != nil {
+			return
+		}
+		entry := func(address string, category string, amount *big.Int) *BtcWalletTransaction {
+			e := &BtcWalletTransaction{
+				Address:      address,
+				Category:     category,
+				Amount:       newBtcAmount(amount),
+				Txid:         btcHash(tx.Hash()),
+				Time:         now,
+				Timereceived: now,
+			}
+			if header != nil {
+				e.Confirmations = p.b.CurrentBlock().NumberU64() - header.Number.Uint64() + 1
+				e.Blockhash = btcHash(header.Hash())
+				e.Blockheight = header.Number.Uint64()
+				e.Blockindex = index
+				e.Blocktime = header.Time.Int64()
+				e.Time, e.Timereceived = e.Blocktime, e.Blocktime
+			}
+			return e
+		}
+		if to := tx.To(); to != nil && mine[*to] {
+			list = append(list, entry(to.Hex(), "receive", tx.Value()))
+		}
+		if mine[sender] {
+			to := ""
+			if tx.To() != nil {
+				to = tx.To().Hex()
+			}
+			send := entry(to, "send", new(big.Int).Neg(tx.Value()))
+			gas := tx.Gas()
+			if header != nil {
+				if r := receipts(); int(index) < len(r) {
+					gas = r[index].GasUsed
+				}
+			}
+			send.Fee = newBtcAmount(new(big.Int).Neg(new(big.Int).Mul(tx.GasPrice(), new(big.Int).SetUint64(gas))))
+			list = append(list, send)
+		}
+	}
+	if pending, err := p.b.GetPoolTransactions(); err == nil {
+		for i := len(pending) - 1; i >= 0; i-- {
+			add(pending[i], nil, 0, nil)
+		}
+	}
+	head := p.b.CurrentBlock().NumberU64()
+	for number := head; len(list) < n+from && head-number < btcListTransactionsMaxScan; number-- {
+		block, err := p.b.BlockByNumber(ctx, rpc.BlockNumber(number))
+		if err != nil || block == nil {
+			break
+		}
+		var blockReceipts types.Receipts
+		receipts := func() types.Receipts {
+			if blockReceipts == nil {
+				blockReceipts, _ = p.b.GetReceipts(ctx, block.Hash())
+			}
+			return blockReceipts
+		}
+		txs := block.Transactions()
+		for i := len(txs) - 1; i >= 0; i-- {
+			add(txs[i], block.Header(), uint64(i), receipts)
+		}
+		if number == 0 {
+			break
+		}
+	}
+	// Page through, and list the oldest first
+	if from >= len(list) {
+		return []*BtcWalletTransaction{}, nil
+	}
+	list = list[from:]
+	if len(list) > n {
+		list = list[:n]
+	}
+	for i, j := 0, len(list)-1; i < j; i, j = i+1, j-1 {
+		list[i], list[j] = list[j], list[i]
+	}
+	return list, nil
+}
+
+// Sendtoaddress sends the given amount of aqua to the address, from the first
+// wallet account able to pay for it, which has to be unlocked. It returns the
+// id of the transaction. The comments are ignored; with subtractfeefromamount
+// the recipient receives the amount minus the fee.
+func (p *PublicBitcoinAPI) Sendtoaddress(ctx context.Context, address string, amount BtcAmount, comment *string, commentTo *string, subtractfeefromamount *bool) (string, error) {
+	if !common.IsHexAddress(address) {
+		return "", &btcError{btcErrInvalidAddressOrKey, "Invalid Aquachain address"}
+	}
+	if (*big.Int)(&amount).Sign() <= 0 {
+		return "", &btcError{btcErrType, "Invalid amount for send"}
+	}
+	all, err := p.walletAccounts()
+	if err != nil {
+		return "", err
+	}
+	state, _, err := p.b.StateAndHeaderByNumber(ctx, rpc.PendingBlockNumber)
+	if state == nil || err != nil {
+		return "", &btcError{btcErrMisc, fmt.Sprintf("state not available: %v", err)}
+	}
+	price, err := p.b.SuggestPrice(ctx)
+	if err != nil {
+		return "", &btcError{btcErrMisc, err.Error()}
+	}
+	var (
+		to       = common.HexToAddress(address)
+		subtract = subtractfeefromamount != nil && *subtractfeefromamount
+		isCall   = state.GetCodeSize(to) > 0
+	)
+	// Pick the first account able to pay the amount and the fee
+	for _, account := range all {
+		gas := params.TxGas
+		if isCall {
+			args := CallArgs{From: account.Address, To: &to, Value: hexutil.Big(amount)}
+			estimate, err := NewPublicBlockChainAPI(p.b).EstimateGas(ctx, args, nil, nil, nil)
+			if err != nil {
+				continue
+			}
+			gas = uint64(estimate)
+		}
+		var (
+			fee   = new(big.Int).Mul(price, new(big.Int).SetUint64(gas))
+			value = new(big.Int).Set((*big.Int)(&amount))
+			cost  = new(big.Int).Add(value, fee)
+		)
+		if subtract {
+			value.Sub(value, fee)
+			cost.Sub(cost, fee)
+			if value.Sign() <= 0 {
+				return "", &btcError{btcErrWallet, "The transaction amount is too small to pay the fee"}
+			}
+		}
+		if state.GetBalance(account.Address).Cmp(cost) < 0 {
+			continue
+		}
+		return p.send(ctx, account, types.NewTransaction(0, to, value, gas, price, nil))
+	}
+	return "", &btcError{btcErrInsufficientFunds, "Insufficient funds"}
+}
+
+// send signs the transaction from the account with the next nonce, and submits
+// it to the transaction pool.
+func (p *PublicBitcoinAPI) send(ctx context.Context, account accounts.Account, tx *types.Transaction) (string, error) {
+	wallet, err := p.b.AccountManager().Find(account)
+	if err != nil {
+		return "", &btcError{btcErrWallet, err.Error()}
+	}
+	p.nonceLock.LockAddr(account.Address)
+	defer p.nonceLock.UnlockAddr(account.Address)
+
+	nonce, err := p.b.GetPoolNonce(ctx, account.Address)
+	if err != nil {
+		return "", &btcError{btcErrMisc, err.Error()}
+	}
+	tx = types.NewTransaction(nonce, *tx.To(), tx.Value(), tx.Gas(), tx.GasPrice(), nil)
+
+	var chainID *big.Int
+	if config := p.b.ChainConfig(); config.IsEIP155(p.b.CurrentBlock().Number()) {
+		chainID = config.ChainId
+	}
+	signed, err := wallet.SignTx(account, tx, chainID)
+	if err == keystore.ErrLocked {
+		return "", errBtcUnlockNeeded
+	}
+	if err != nil {
+		return "", &btcError{btcErrWallet, err.Error()}
+	}
+	if _, err := submitTransaction(ctx, p.b, signed); err != nil {
+		return "", &btcError{btcErrTransactionRejected, err.Error()}
+	}
+	return btcHash(signed.Hash()), nil
+}
+
+// Validateaddress reports whether the address is valid, and whether it belongs
+// to the wallet.
+func (p *PublicBitcoinAPI) Validateaddress(ctx context.Context, address string) (map[string]interface{}, error) {
+	if !common.IsHexAddress(address) {
+		return map[string]interface{}{"isvalid": false}, nil
+	}
+	addr := common.HexToAddress(address)
+	result := map[string]interface{}{
+		"isvalid":     true,
+		"address":     addr.Hex(),
+		"ismine":      false,
+		"iswatchonly": false,
+	}
+	if am := p.b.AccountManager(); am != nil {
+		_, err := am.Find(accounts.Account{Address: addr})
+		result["ismine"] = err == nil
+	}
+	if state, _, err := p.b.StateAndHeaderByNumber(ctx, rpc.LatestBlockNumber); state != nil && err == nil {
+		result["isscript"] = state.GetCodeSize(addr) > 0
+	}
+	return result, nil
+}
+
+// Getnewaddress creates a new keystore account, encrypted with the passphrase
+// given to walletpassphrase, which has to be unlocked. The label is ignored.
+func (p *PublicBitcoinAPI) Getnewaddress(label *string) (string, error) {
+	ks := fetchKeystore(p.b.AccountManager())
+	if ks == nil {
+		return "", errBtcNoWallet
+	}
+	p.mu.Lock()
+	passphrase, until := p.passphrase, p.unlockedUntil
+	p.mu.Unlock()
+
+	if time.Now().After(until) {
+		return "", errBtcUnlockNeeded
+	}
+	account, err := ks.NewAccount(passphrase)
+	if err != nil {
+		return "", &btcError{btcErrWallet, err.Error()}
+	}
+	if err := ks.TimedUnlock(account, passphrase, time.Until(until)); err != nil {
+		return "", &btcError{btcErrWallet, err.Error()}
+	}
+	return account.Address.Hex(), nil
+}
+
+// Walletpassphrase unlocks the keystore accounts the passphrase decrypts, for
+// timeout seconds, and keeps the passphrase as long for getnewaddress. The
+// passphrase is dropped when the timeout expires.
+func (p *PublicBitcoinAPI) Walletpassphrase(passphrase string, timeout int64) error {
+	ks := fetchKeystore(p.b.AccountManager())
+	if ks == nil {
+		return errBtcNoWallet
+	}
+	if timeout <= 0 {
+		return &btcError{btcErrInvalidParameter, "Timeout must be positive."}
+	}
+	if timeout > btcMaxUnlockTimeout {
+		timeout = btcMaxUnlockTimeout
+	}
+	duration := time.Duration(timeout) * time.Second
+	all := ks.Accounts()
+	unlocked := 0
+	for _, account := range all {
+		if err := ks.TimedUnlock(account, passphrase, duration); err == nil {
+			unlocked++
+		}
+	}
+	if unlocked == 0 && len(all) > 0 {
+		return &btcError{btcErrPassphraseIncorrect, "Error: The wallet passphrase entered was incorrect."}
+	}
+	p.mu.Lock()
+	p.forgetPassphrase()
+	p.passphrase, p.unlockedUntil = passphrase, time.Now().Add(duration)
+	p.forget = time.AfterFunc(duration, func() {
+		p.mu.Lock()
+		defer p.mu.Unlock()
+		if !time.Now().Before(p.unlockedUntil) {
+			p.forgetPassphrase()
+		}
+	})
+	p.mu.Unlock()
+	return nil
+}
+
+// forgetPassphrase drops the passphrase of walletpassphrase. The caller must
+// hold p.mu.
+func (p *PublicBitcoinAPI) forgetPassphrase() {
+	if p.forget != nil {
+		p.forget.Stop()
+		p.forget = nil
+	}
+	p.passphrase, p.unlockedUntil = "", time.Time{}
+}
+
+// Walletlock locks all keystore accounts and forgets the passphrase.
+func (p *PublicBitcoinAPI) Walletlock() error {
+	ks := fetchKeystore(p.b.AccountManager())
+	if ks == nil {
+		return errBtcNoWallet
+	}
+	p.mu.Lock()
+	p.forgetPassphrase()
+	p.mu.Unlock()
+
+	for _, account := range ks.Accounts() {
+		ks.Lock(account.Address)
+	}
+	return nil
+}
+
+// Getinfo returns an overview of the node and the wallet.
+func (p *PublicBitcoinAPI) Getinfo(ctx context.Context) (*BtcInfo, error) {
+	var (
+		head   = p.b.CurrentBlock()
+		config = p.b.ChainConfig()
+		info   = &BtcInfo{
+			Version:         params.VersionMajor*1000000 + params.VersionMinor*10000 + params.VersionPatch*100,
+			Protocolversion: p.b.ProtocolVersion(),
+			Blocks:          head.Number().Int64(),
+			Connections:     p.b.PeerCount(),
+			Difficulty:      head.Difficulty(),
+			Testnet:         config.ChainId.Cmp(params.MainnetChainConfig.ChainId) != 0,
+		}
+	)
+	if p.b.AccountManager() != nil {
+		balance, err := p.Getbalance(ctx, nil, nil)
+		if err != nil {
+			return nil, err
+		}
+		info.Balance = balance
+
+		p.mu.Lock()
+		if until := p.unlockedUntil; !until.IsZero() {
+			unix := until.Unix()
+			if time.Now().After(until) {
+				unix = 0
+			}
+			info.UnlockedUntil = &unix
+		}
+		p.mu.Unlock()
+	}
+	price, err := p.b.SuggestPrice(ctx)
+	if err != nil {
+		return nil, &btcError{btcErrMisc, err.Error()}
+	}
+	info.Paytxfee = newBtcAmount(new(big.Int).Mul(price, new(big.Int).SetUint64(params.TxGas)))
+	return info, nil
 }
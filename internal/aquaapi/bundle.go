@@ -0,0 +1,151 @@
+// Copyright 2018 The aquachain Authors
+// This file is part of the aquachain library.
+//
+// The aquachain library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The aquachain library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the aquachain library. If not, see <http://www.gnu.org/licenses/>.
+
+package aquaapi
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"gitlab.com/aquachain/aquachain/aqua/accounts/abi"
+	"gitlab.com/aquachain/aquachain/common"
+	"gitlab.com/aquachain/aquachain/common/hexutil"
+	"gitlab.com/aquachain/aquachain/common/log"
+	"gitlab.com/aquachain/aquachain/core"
+	"gitlab.com/aquachain/aquachain/core/types"
+	"gitlab.com/aquachain/aquachain/core/vm"
+	"gitlab.com/aquachain/aquachain/params"
+	"gitlab.com/aquachain/aquachain/rpc"
+)
+
+const (
+	// maxBundleCalls is the maximum number of calls a single bundle may contain.
+	maxBundleCalls = 256
+
+	// maxBundleGas is the total amount of gas all calls of a bundle may use.
+	// Calls without an explicit gas limit get whatever is left of it.
+	maxBundleGas = 50000000
+
+	// bundleTimeout is the maximum time a whole bundle may take to execute.
+	bundleTimeout = 5 * time.Second
+)
+
+// BundleCallResult is the outcome of a single call simulated as part of a
+// bundle.
+type BundleCallResult struct {
+	ReturnData   hexutil.Bytes  `json:"returnData"`
+	GasUsed      hexutil.Uint64 `json:"gasUsed"`
+	Logs         []*types.Log   `json:"logs"`
+	Error        string         `json:"error,omitempty"`
+	RevertReason string         `json:"revertReason,omitempty"`
+}
+
+// CallBundle simulates an ordered list of calls on top of the state of the
+// given block, each call seeing the state changes made by the previous ones.
+// Nothing is written to the chain. A failing call is reported in its result
+// and does not abort the remaining calls, but running out of the bundle wide
+// gas allowance or time limit aborts the whole bundle.
+//
+// As with Call, the state and the block header may be overridden beforehand.
+func (s *PublicBlockChainAPI) CallBundle(ctx context.Context, calls []CallArgs, blockNr rpc.BlockNumber, overrides *StateOverride, blockOverrides *BlockOverrides) ([]*BundleCallResult, error) {
+	defer func(start time.Time) {
+		log.Debug("Executing EVM call bundle finished", "calls", len(calls), "runtime", time.Since(start))
+	}(time.Now())
+
+	if len(calls) == 0 {
+		return nil, errors.New("empty call bundle")
+	}
+	if len(calls) > maxBundleCalls {
+		return nil, fmt.Errorf("too many calls in bundle: %d > %d", len(calls), maxBundleCalls)
+	}
+	state, header, err := s.b.StateAndHeaderByNumber(ctx, blockNr)
+	if state == nil || err != nil {
+		return nil, err
+	}
+	if err := overrides.Apply(state); err != nil {
+		return nil, err
+	}
+	header = blockOverrides.Apply(header)
+
+	// Bound the whole bundle in time, and make sure the context is cancelled
+	// when the bundle has completed so that resources are cleaned up.
+	ctx, cancel := context.WithTimeout(ctx, bundleTimeout)
+	defer cancel()
+
+	var (
+		deleteEmpty = s.b.ChainConfig().IsEIP158(header.Number)
+		blockHash   = header.Hash()
+		results     = make([]*BundleCallResult, len(calls))
+		gasLeft     = uint64(maxBundleGas)
+	)
+	for i, args := range calls {
+		// Cap the call to the gas left in the bundle
+		if gasLeft < params.TxGas {
+			return nil, fmt.Errorf("call bundle gas allowance of %d exhausted at call %d", maxBundleGas, i)
+		}
+		if args.Gas == 0 || uint64(args.Gas) > gasLeft {
+			args.Gas = hexutil.Uint64(gasLeft)
+		}
+		msg := args.ToMessage()
+		state.Prepare(common.Hash{}, blockHash, i)
+
+		evm, vmError, err := s.b.GetEVM(ctx, msg, state, header, vm.Config{})
+		if err != nil {
+			return nil, err
+		}
+		done := make(chan struct{})
+		go func() {
+			select {
+			case <-ctx.Done():
+				evm.Cancel()
+			case <-done:
+			}
+		}()
+		logOffset := len(state.GetLogs(common.Hash{}))
+		ret, gas, failed, err := core.ApplyMessage(evm, msg, new(core.GasPool).AddGas(msg.Gas()))
+		close(done)
+		if err := vmError(); err != nil {
+			return nil, err
+		}
+		if ctx.Err() != nil {
+			return nil, fmt.Errorf("call bundle aborted at call %d: %v", i, ctx.Err())
+		}
+		gasLeft -= gas
+
+		result := &BundleCallResult{
+			ReturnData: ret,
+			GasUsed:    hexutil.Uint64(gas),
+			Logs:       []*types.Log{},
+		}
+		switch {
+		case err != nil:
+			result.Error = err.Error()
+		case failed:
+			result.Error = "execution reverted or failed"
+			if reason, err := abi.UnpackRevert(ret); err == nil {
+				result.RevertReason = reason
+			}
+		}
+		result.Logs = append(result.Logs, state.GetLogs(common.Hash{})[logOffset:]...)
+		results[i] = result
+
+		// Finalise the call so the next one sees a clean journal and refund
+		state.Finalise(deleteEmpty)
+	}
+	return results, nil
+}
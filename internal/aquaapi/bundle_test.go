@@ -0,0 +1,176 @@
+// Copyright 2018 The aquachain Authors
+// This file is part of the aquachain library.
+//
+// The aquachain library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The aquachain library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the aquachain library. If not, see <http://www.gnu.org/licenses/>.
+
+package aquaapi
+
+import (
+	"context"
+	"math/big"
+	"strings"
+	"testing"
+	"time"
+
+	"gitlab.com/aquachain/aquachain/common"
+	"gitlab.com/aquachain/aquachain/common/hexutil"
+	"gitlab.com/aquachain/aquachain/rpc"
+)
+
+var (
+	// counterCode increments storage slot 0 and returns the new value
+	counterCode = common.FromHex("600054600101806000556000526020" + "6000f3")
+	// numberCode returns the block number
+	numberCode = common.FromHex("4360005260206000f3")
+	// logCode emits a log with topic 7 and no data
+	logCode = common.FromHex("600760006000a100")
+	// invalidCode burns all the gas of the call
+	invalidCode = common.FromHex("fe")
+	// loopCode spins until it runs out of gas
+	loopCode = common.FromHex("5b600056")
+)
+
+// revertCode returns code which reverts with the given reason, encoded as a
+// call to Error(string).
+func revertCode(reason string) []byte {
+	blob := append(common.FromHex("08c379a0"), common.LeftPadBytes(big.NewInt(32).Bytes(), 32)...)
+	blob = append(blob, common.LeftPadBytes(big.NewInt(int64(len(reason))).Bytes(), 32)...)
+	blob = append(blob, common.RightPadBytes([]byte(reason), (len(reason)+31)/32*32)...)
+
+	// PUSH1 len, PUSH1 12, PUSH1 0, CODECOPY, PUSH1 len, PUSH1 0, REVERT
+	size := byte(len(blob))
+	return append([]byte{0x60, size, 0x60, 0x0c, 0x60, 0x00, 0x39, 0x60, size, 0x60, 0x00, 0xfd}, blob...)
+}
+
+// bundleOverrides installs the given code at the given addresses.
+func bundleOverrides(codes map[common.Address][]byte) *StateOverride {
+	overrides := make(StateOverride)
+	for addr, code := range codes {
+		code := hexutil.Bytes(code)
+		overrides[addr] = OverrideAccount{Code: &code}
+	}
+	return &overrides
+}
+
+// Tests that each call of a bundle sees the state changes of the previous ones,
+// on top of the overridden state and block.
+func TestCallBundleState(t *testing.T) {
+	var (
+		api     = NewPublicBlockChainAPI(newTestBackend(t, 0, nil))
+		counter = common.Address{0xc0}
+		number  = common.Address{0xc1}
+		ctx     = context.Background()
+	)
+	calls := []CallArgs{{From: testAddr, To: &counter}, {From: testAddr, To: &counter}, {From: testAddr, To: &number}}
+
+	overrides := bundleOverrides(map[common.Address][]byte{counter: counterCode, number: numberCode})
+	results, err := api.CallBundle(ctx, calls, rpc.LatestBlockNumber, overrides, nil)
+	if err != nil {
+		t.Fatalf("bundle failed: %v", err)
+	}
+	for i, want := range []int64{1, 2, 0} {
+		if have := new(big.Int).SetBytes(results[i].ReturnData); have.Int64() != want {
+			t.Errorf("call %d: result mismatch: have %v, want %d", i, have, want)
+		}
+	}
+	// Start the counter from an overridden slot, at an overridden block number
+	diff := map[common.Hash]common.Hash{{}: common.BigToHash(big.NewInt(5))}
+	account := (*overrides)[counter]
+	account.StateDiff = &diff
+	(*overrides)[counter] = account
+
+	results, err = api.CallBundle(ctx, calls, rpc.LatestBlockNumber, overrides, &BlockOverrides{Number: (*hexutil.Big)(big.NewInt(42))})
+	if err != nil {
+		t.Fatalf("overridden bundle failed: %v", err)
+	}
+	for i, want := range []int64{6, 7, 42} {
+		if have := new(big.Int).SetBytes(results[i].ReturnData); have.Int64() != want {
+			t.Errorf("overridden call %d: result mismatch: have %v, want %d", i, have, want)
+		}
+	}
+}
+
+// Tests that logs and revert reasons are reported per call.
+func TestCallBundleLogsAndReverts(t *testing.T) {
+	var (
+		api      = NewPublicBlockChainAPI(newTestBackend(t, 0, nil))
+		logger   = common.Address{0xc0}
+		reverter = common.Address{0xc1}
+	)
+	calls := []CallArgs{{From: testAddr, To: &logger}, {From: testAddr, To: &reverter}, {From: testAddr, To: &logger}}
+	overrides := bundleOverrides(map[common.Address][]byte{logger: logCode, reverter: revertCode("nope")})
+
+	results, err := api.CallBundle(context.Background(), calls, rpc.LatestBlockNumber, overrides, nil)
+	if err != nil {
+		t.Fatalf("bundle failed: %v", err)
+	}
+	for _, i := range []int{0, 2} {
+		if results[i].Error != "" {
+			t.Errorf("call %d: unexpected error: %s", i, results[i].Error)
+		}
+		if logs := results[i].Logs; len(logs) != 1 || logs[0].Address != logger || logs[0].Topics[0] != common.BigToHash(big.NewInt(7)) {
+			t.Errorf("call %d: log mismatch: have %v", i, logs)
+		} else if logs[0].TxIndex != uint(i) {
+			t.Errorf("call %d: log index mismatch: have %d", i, logs[0].TxIndex)
+		}
+	}
+	if results[1].Error == "" || results[1].RevertReason != "nope" {
+		t.Errorf("revert mismatch: have error %q, reason %q", results[1].Error, results[1].RevertReason)
+	}
+	if results[1].Logs == nil || len(results[1].Logs) != 0 {
+		t.Errorf("reverted call has logs: %v", results[1].Logs)
+	}
+}
+
+// Tests that the gas used by all calls of a bundle is capped.
+func TestCallBundleGasCap(t *testing.T) {
+	var (
+		api     = NewPublicBlockChainAPI(newTestBackend(t, 0, nil))
+		burner  = common.Address{0xc0}
+		counter = common.Address{0xc1}
+		calls   = []CallArgs{
+			{From: testAddr, To: &burner, Gas: maxBundleGas / 2},
+			{From: testAddr, To: &burner, Gas: maxBundleGas},
+			{From: testAddr, To: &counter},
+		}
+		overrides = bundleOverrides(map[common.Address][]byte{burner: invalidCode, counter: counterCode})
+	)
+	_, err := api.CallBundle(context.Background(), calls, rpc.LatestBlockNumber, overrides, nil)
+	if err == nil || !strings.Contains(err.Error(), "exhausted at call 2") {
+		t.Fatalf("bundle over the gas allowance not rejected: %v", err)
+	}
+	results, err := api.CallBundle(context.Background(), calls[:2], rpc.LatestBlockNumber, overrides, nil)
+	if err != nil {
+		t.Fatalf("bundle failed: %v", err)
+	}
+	if have, want := uint64(results[1].GasUsed), uint64(maxBundleGas-maxBundleGas/2); have != want {
+		t.Errorf("capped call gas mismatch: have %d, want %d", have, want)
+	}
+}
+
+// Tests that a bundle running past its deadline is aborted.
+func TestCallBundleTimeout(t *testing.T) {
+	var (
+		api  = NewPublicBlockChainAPI(newTestBackend(t, 0, nil))
+		loop = common.Address{0xc0}
+	)
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	calls := []CallArgs{{From: testAddr, To: &loop}}
+	_, err := api.CallBundle(ctx, calls, rpc.LatestBlockNumber, bundleOverrides(map[common.Address][]byte{loop: loopCode}), nil)
+	if err == nil || !strings.Contains(err.Error(), "aborted at call 0") {
+		t.Fatalf("timed out bundle not aborted: %v", err)
+	}
+}
@@ -27,6 +27,7 @@ import (
 	"gitlab.com/aquachain/aquachain/common/log"
 	"gitlab.com/aquachain/aquachain/common/metrics"
 	"gitlab.com/aquachain/aquachain/common/metrics/exp"
+	"gitlab.com/aquachain/aquachain/common/metrics/prometheus"
 	"gitlab.com/aquachain/aquachain/common/sense"
 )
 
@@ -75,6 +76,20 @@ var (
 		Usage: "pprof HTTP server listening interface",
 		Value: "127.0.0.1",
 	}
+	prometheusFlag = &cli.BoolFlag{
+		Name:  metrics.PrometheusEnabledFlag,
+		Usage: "Enable metrics collection and the Prometheus metrics HTTP server",
+	}
+	prometheusPortFlag = &cli.IntFlag{
+		Name:  "prometheusport",
+		Usage: "Prometheus metrics HTTP server listening port",
+		Value: 6061,
+	}
+	prometheusAddrFlag = &cli.StringFlag{
+		Name:  "prometheusaddr",
+		Usage: "Prometheus metrics HTTP server listening interface",
+		Value: "127.0.0.1",
+	}
 	memprofilerateFlag = &cli.IntFlag{
 		Name:  "memprofilerate",
 		Usage: "Turn on memory profiling with the given rate",
@@ -99,6 +114,7 @@ var Flags = []cli.Flag{
 	logcolorflag, logjsonflag,
 	verbosityFlag, vmoduleFlag, backtraceAtFlag, debugFlag,
 	pprofFlag, pprofAddrFlag, pprofPortFlag,
+	prometheusFlag, prometheusAddrFlag, prometheusPortFlag,
 	memprofilerateFlag, blockprofilerateFlag, cpuprofileFlag, traceFlag,
 }
 
@@ -141,6 +157,16 @@ func Setup(ctx_ context.Context, cmd *cli.Command) error {
 			}
 		}()
 	}
+
+	// prometheus server, separate from pprof and the RPC servers
+	if cmd.Bool(prometheusFlag.Name) {
+		address := fmt.Sprintf("%s:%d", cmd.String(prometheusAddrFlag.Name), cmd.Int(prometheusPortFlag.Name))
+		go func() {
+			if err := prometheus.ListenAndServe(address, metrics.DefaultRegistry); err != nil {
+				log.Crit("Failure in running Prometheus metrics server", "err", err)
+			}
+		}()
+	}
 	return nil
 }
 
@@ -27,6 +27,8 @@ import (
 
 	"github.com/btcsuite/btcd/btcec/v2"
 	"gitlab.com/aquachain/aquachain/aqua/accounts"
+	"gitlab.com/aquachain/aquachain/aqua/accounts/external"
+	"gitlab.com/aquachain/aquachain/aqua/accounts/hdwallet"
 	"gitlab.com/aquachain/aquachain/aqua/accounts/keystore"
 	"gitlab.com/aquachain/aquachain/common"
 	"gitlab.com/aquachain/aquachain/common/log"
@@ -42,6 +44,8 @@ const (
 	datadirStaticNodes     = "static-nodes.json"  // Path within the datadir to the static node list
 	datadirTrustedNodes    = "trusted-nodes.json" // Path within the datadir to the trusted node list
 	datadirNodeDatabase    = "nodes"              // Path within the datadir to store the node infos
+	datadirBtcRPCCookie    = ".cookie"            // Path within the datadir to the bitcoind-style RPC cookie
+	datadirHDWallets       = "hd"                 // Path within the keystore to the HD wallets
 )
 
 // Config represents a small collection of configuration values to fine tune the
@@ -70,6 +74,12 @@ type Config struct {
 	// in memory.
 	DataDir string
 
+	// DBEngine is the key-value backend of new databases, one of aquadb.Backends.
+	// Existing databases are always opened with the backend that created them,
+	// and it is an error to request a different one. If empty, new databases
+	// use aquadb.DefaultBackend.
+	DBEngine string `toml:",omitempty"`
+
 	// Configuration of peer-to-peer networking.
 	P2P *p2p.Config
 
@@ -86,9 +96,25 @@ type Config struct {
 	// scrypt KDF at the expense of security.
 	UseLightweightKDF bool `toml:",omitempty"`
 
+	// KeyStoreKDF selects the KDF new and updated key files are encrypted with,
+	// either "scrypt" (version 3, the default) or "argon2id" (version 4). Key
+	// files of either format are always decrypted.
+	KeyStoreKDF string `toml:",omitempty"`
+
+	// KeyStoreArgon2Memory and KeyStoreArgon2Time tune the Argon2id KDF, the
+	// memory being given in KiB. Zero values select the standard parameters
+	// (or the light ones if UseLightweightKDF is set).
+	KeyStoreArgon2Memory uint32 `toml:",omitempty"`
+	KeyStoreArgon2Time   uint32 `toml:",omitempty"`
+
 	// UseUSB enables hardware wallet monitoring and connectivity.
 	UseUSB bool `toml:",omitempty"`
 
+	// ExternalSigner is the IPC path or HTTP(S)/WS URL of an external signer the
+	// account manager forwards signing requests to. The signer is available even
+	// with NoKeys set, keeping all private keys out of the node process.
+	ExternalSigner string `toml:",omitempty"`
+
 	// IPCPath is the requested location to place the IPC endpoint. If the path is
 	// a simple file name, it is placed inside the data directory (or on the root
 	// pipe path on Windows), whereas if it's a resolvable path name (absolute or
@@ -150,6 +176,22 @@ type Config struct {
 	// private APIs to untrusted users is a major security risk.
 	WSExposeAll bool `toml:",omitempty"`
 
+	// BtcRPCHost is the host interface on which to start the bitcoind-compatible
+	// HTTP RPC server, serving the btc API module. If this field is empty, no
+	// such endpoint will be started.
+	BtcRPCHost string `toml:",omitempty"`
+
+	// BtcRPCPort is the TCP port number on which to start the bitcoind-compatible
+	// HTTP RPC server.
+	BtcRPCPort int `toml:",omitempty"`
+
+	// BtcRPCUser and BtcRPCPassword are the HTTP basic authentication
+	// credentials of the bitcoind-compatible endpoint. Without a password, a
+	// random one is written to the cookie file of the instance directory, like
+	// bitcoind does.
+	BtcRPCUser     string `toml:",omitempty"`
+	BtcRPCPassword string `toml:",omitempty"`
+
 	// Logger is a custom logger to use with the p2p.Server.
 	Logger log.LoggerI `toml:",omitempty"`
 
@@ -234,6 +276,18 @@ func (c *Config) WSEndpoint() string {
 	return getEndpoint(c.WSHost, c.WSPort)
 }
 
+// BtcRPCEndpoint resolves the bitcoind-compatible HTTP endpoint based on the
+// configured host interface and port parameters.
+func (c *Config) BtcRPCEndpoint() string {
+	return getEndpoint(c.BtcRPCHost, c.BtcRPCPort)
+}
+
+// BtcRPCCookieFile returns the path of the file holding the credentials of the
+// bitcoind-compatible endpoint when no password is configured.
+func (c *Config) BtcRPCCookieFile() string {
+	return c.resolvePath(datadirBtcRPCCookie)
+}
+
 func getEndpoint(host string, port int) string {
 	if host == "" {
 		return ""
@@ -301,6 +355,15 @@ func (c *Config) name() string {
 	return c.Name
 }
 
+// ancientPath returns the directory of the ancient store of the named
+// database, by default inside the database directory.
+func (c *Config) ancientPath(name, ancient string) string {
+	if ancient == "" {
+		return filepath.Join(c.resolvePath(name), "ancient")
+	}
+	return c.resolvePath(ancient)
+}
+
 // resolvePath resolves path in the instance directory.
 func (c *Config) resolvePath(path string) string {
 	if filepath.IsAbs(path) {
@@ -448,11 +511,55 @@ func (c *Config) AccountConfig() (int, int, string, error) {
 	return scryptN, scryptP, keydir, err
 }
 
+// KeyStoreArgon2Config returns the Argon2id parameters new key files are to be
+// encrypted with, or zero memory if the keystore uses scrypt.
+func (c *Config) KeyStoreArgon2Config() (memory, time uint32, err error) {
+	switch c.KeyStoreKDF {
+	case "", "scrypt":
+		return 0, 0, nil
+	case "argon2id":
+	default:
+		return 0, 0, fmt.Errorf("unknown keystore KDF %q (want scrypt or argon2id)", c.KeyStoreKDF)
+	}
+	memory, time = keystore.StandardArgon2Memory, keystore.StandardArgon2Time
+	if c.UseLightweightKDF {
+		memory, time = keystore.LightArgon2Memory, keystore.LightArgon2Time
+	}
+	if c.KeyStoreArgon2Memory != 0 {
+		memory = c.KeyStoreArgon2Memory
+	}
+	if c.KeyStoreArgon2Time != 0 {
+		time = c.KeyStoreArgon2Time
+	}
+	return memory, time, nil
+}
+
+// OpenKeyStore opens the keystore in the given directory, storing keys in the
+// format selected by the configuration.
+func (c *Config) OpenKeyStore(keydir string, scryptN, scryptP int) (*keystore.KeyStore, error) {
+	memory, time, err := c.KeyStoreArgon2Config()
+	if err != nil {
+		return nil, err
+	}
+	if memory != 0 {
+		return keystore.NewKeyStoreArgon2id(keydir, memory, time), nil
+	}
+	return keystore.NewKeyStore(keydir, scryptN, scryptP), nil
+}
+
 func makeAccountManager(conf *Config) (*accounts.Manager, string, error) {
 	scryptN, scryptP, keydir, err := conf.AccountConfig()
 	if keydir == "" {
 		log.Info("node/config: no keystore directory")
-		return nil, "", nil
+		if conf.ExternalSigner == "" {
+			return nil, "", nil
+		}
+		extapi, err := external.NewExternalBackend(conf.ExternalSigner)
+		if err != nil {
+			return nil, "", err
+		}
+		log.Info("node/config: using external signer", "endpoint", conf.ExternalSigner)
+		return accounts.NewManager(extapi), "", nil
 	}
 	var ephemeral string
 	if keydir == "" {
@@ -478,8 +585,21 @@ func makeAccountManager(conf *Config) (*accounts.Manager, string, error) {
 	}
 
 	// Assemble the account manager and supported backends
+	ks, err := conf.OpenKeyStore(keydir, scryptN, scryptP)
+	if err != nil {
+		return nil, "", err
+	}
 	backends := []accounts.Backend{
-		keystore.NewKeyStore(keydir, scryptN, scryptP),
+		ks,
+		hdwallet.NewBackend(filepath.Join(keydir, datadirHDWallets), scryptN, scryptP),
+	}
+	if conf.ExternalSigner != "" {
+		extapi, err := external.NewExternalBackend(conf.ExternalSigner)
+		if err != nil {
+			return nil, "", err
+		}
+		log.Info("node/config: using external signer", "endpoint", conf.ExternalSigner)
+		backends = append(backends, extapi)
 	}
 	return accounts.NewManager(backends...), ephemeral, nil
 }
@@ -33,6 +33,9 @@ const (
 	DefaultHTTPPort = 8543        // Default TCP port for the HTTP RPC server
 	DefaultWSHost   = "127.0.0.1" // Default host interface for the websocket RPC server
 	DefaultWSPort   = 8544        // Default TCP port for the websocket RPC server
+
+	DefaultBtcRPCHost = "127.0.0.1" // Default host interface for the bitcoind-compatible RPC server
+	DefaultBtcRPCPort = 8542        // Default TCP port for the bitcoind-compatible RPC server
 )
 
 // DefaultConfig contains reasonable default settings.
@@ -47,6 +50,7 @@ func NewDefaultConfig() *Config {
 		HTTPModules: []string{"aqua", "eth", "net", "web3"},
 		WSPort:      DefaultWSPort,
 		WSModules:   []string{"aqua", "eth", "net", "web3"},
+		BtcRPCPort:  DefaultBtcRPCPort,
 		P2P: &p2p.Config{
 			ListenAddr: "0.0.0.0:21303", // tcp+udp, ipv4 only
 			MaxPeers:   20,
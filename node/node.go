@@ -18,6 +18,7 @@ package node
 
 import (
 	"context"
+	"crypto/rand"
 	"errors"
 	"fmt"
 	"math/big"
@@ -77,6 +78,11 @@ type Node struct {
 	wsListener net.Listener // Websocket RPC listener socket to server API requests
 	wsHandler  *rpc.Server  // Websocket RPC request handler to process the API requests
 
+	btcEndpoint string       // bitcoind-compatible HTTP endpoint to listen at (empty = disabled)
+	btcListener net.Listener // bitcoind-compatible HTTP listener socket to serve API requests
+	btcHandler  *rpc.Server  // bitcoind-compatible HTTP request handler to process the API requests
+	btcCookie   string       // Cookie file holding generated credentials, removed on stop
+
 	stop     chan struct{} // Channel to wait for termination notifications
 	lock     sync.RWMutex
 	chaincfg *params.ChainConfig
@@ -159,6 +165,7 @@ func New(conf *Config) (*Node, error) {
 		ipcEndpoint:       conf.IPCEndpoint(),
 		httpEndpoint:      conf.HTTPEndpoint(),
 		wsEndpoint:        conf.WSEndpoint(),
+		btcEndpoint:       conf.BtcRPCEndpoint(),
 		eventmux:          new(event.TypeMux),
 		log:               conf.Logger,
 		chaincfg:          chaincfg,
@@ -398,6 +405,13 @@ func (n *Node) startRPC(services map[reflect.Type]Service, donefunc func()) erro
 		n.stopInProc()
 		return err
 	}
+	if err := n.startBtcRPC(n.btcEndpoint, apis, allownet, n.config.RPCBehindProxy); err != nil {
+		n.stopWS()
+		n.stopHTTP()
+		n.stopIPC()
+		n.stopInProc()
+		return err
+	}
 	// All API endpoints started successfully
 	n.rpcAPIs = apis
 	return nil
@@ -441,6 +455,10 @@ func (n *Node) startIPC(apis []rpc.API) error {
 	// Register all the APIs exposed by the services
 	handler := rpc.NewServer()
 	for _, api := range apis {
+		// the wallet methods of the btc namespace are only served with basic auth
+		if api.Namespace == rpc.BtcNamespace {
+			continue
+		}
 		if _, err := handler.RegisterName(api.Namespace, api.Service); err != nil {
 			return err
 		}
@@ -516,6 +534,12 @@ func (n *Node) startHTTP(endpoint string, apis []rpc.API, whitelistModules []str
 	handler := rpc.NewServer()
 	//	var allMethods []string
 	for _, api := range apis {
+		if api.Namespace == rpc.BtcNamespace {
+			if whitelist[api.Namespace] {
+				n.log.Warn("Not serving btc methods over HTTP, use the -btcrpc endpoint")
+			}
+			continue
+		}
 		if whitelist[api.Namespace] || (len(whitelist) == 0 && api.Public) {
 			m, err := handler.RegisterName(api.Namespace, api.Service)
 			if err != nil {
@@ -576,6 +600,9 @@ func (n *Node) startWS(endpoint string, apis []rpc.API, modules []string, wsOrig
 	// Register all the APIs exposed by the services
 	handler := rpc.NewServer()
 	for _, api := range apis {
+		if api.Namespace == rpc.BtcNamespace {
+			continue
+		}
 		if exposeAll || whitelist[api.Namespace] || (len(whitelist) == 0 && api.Public) {
 			m, err := handler.RegisterName(api.Namespace, api.Service)
 			if err != nil {
@@ -617,6 +644,83 @@ func (n *Node) stopWS() {
 	}
 }
 
+// startBtcRPC initializes and starts the bitcoind-compatible HTTP RPC endpoint,
+// serving the btc API module to clients authenticating with HTTP basic auth.
+func (n *Node) startBtcRPC(endpoint string, apis []rpc.API, allownet netutil.Netlist, behindreverseproxy bool) error {
+	// Short circuit if the endpoint isn't being exposed
+	if endpoint == "" {
+		return nil
+	}
+	if len(allownet) == 0 && sense.Getenv("TESTING_TEST") != "1" {
+		return fmt.Errorf("btc rpc cant start with empty '-allowip' flag")
+	}
+	user, password := n.config.BtcRPCUser, n.config.BtcRPCPassword
+	cookie := ""
+	if password == "" {
+		// Generate credentials into the cookie file, like bitcoind
+		if cookie = n.config.BtcRPCCookieFile(); cookie == "" {
+			return errors.New("btc rpc needs a password without a data directory")
+		}
+		secret := make([]byte, 32)
+		if _, err := rand.Read(secret); err != nil {
+			return err
+		}
+		user, password = "__cookie__", common.Bytes2Hex(secret)
+		if err := os.WriteFile(cookie, []byte(user+":"+password), 0600); err != nil {
+			return fmt.Errorf("can't write btc rpc cookie: %v", err)
+		}
+	} else if user == "" {
+		return errors.New("btc rpc password set without a user")
+	}
+	handler := rpc.NewServer()
+	for _, api := range apis {
+		if api.Namespace != rpc.BtcNamespace {
+			continue
+		}
+		// clients of the bitcoind-style endpoint authenticate
+		m, err := handler.RegisterAuthenticatedName(api.Namespace, api.Service)
+		if err != nil {
+			return err
+		}
+		n.log.Info("Bitcoind-compatible methods available", "methods", common.ToJson(m))
+	}
+	listener, err := net.Listen("tcp4", endpoint)
+	if err != nil {
+		if cookie != "" {
+			os.Remove(cookie)
+		}
+		return err
+	}
+	go rpc.NewBitcoinHTTPServer(user, password, allownet, behindreverseproxy, handler).Serve(listener)
+	n.log.Warn("Bitcoind-compatible HTTP endpoint opened", "url", fmt.Sprintf("http://%s", endpoint), "user", user, "cookie", cookie, "allowip", allownet.String())
+
+	// All listeners booted successfully
+	n.btcEndpoint = endpoint
+	n.btcListener = listener
+	n.btcHandler = handler
+	n.btcCookie = cookie
+
+	return nil
+}
+
+// stopBtcRPC terminates the bitcoind-compatible HTTP RPC endpoint.
+func (n *Node) stopBtcRPC() {
+	if n.btcListener != nil {
+		n.btcListener.Close()
+		n.btcListener = nil
+
+		n.log.Info("Bitcoind-compatible HTTP endpoint closed", "url", fmt.Sprintf("http://%s", n.btcEndpoint))
+	}
+	if n.btcHandler != nil {
+		n.btcHandler.Stop()
+		n.btcHandler = nil
+	}
+	if n.btcCookie != "" {
+		os.Remove(n.btcCookie)
+		n.btcCookie = ""
+	}
+}
+
 // Stop terminates a running node along with all it's services. In the node was
 // not started, an error is returned.
 func (n *Node) Stop() error {
@@ -632,6 +736,7 @@ func (n *Node) Stop() error {
 	}
 
 	// Terminate the API, services and the p2p server.
+	n.stopBtcRPC()
 	n.stopWS()
 	n.stopHTTP()
 	n.rpcAPIs = nil
@@ -810,7 +915,19 @@ func (n *Node) OpenDatabase(name string, cache, handles int) (aquadb.Database, e
 	if n.config.DataDir == "" {
 		return aquadb.NewMemDatabase(), nil
 	}
-	return aquadb.NewLDBDatabase(n.config.resolvePath(name), cache, handles)
+	return aquadb.Open(n.config.DBEngine, n.config.resolvePath(name), cache, handles)
+}
+
+// OpenDatabaseWithFreezer opens a database like OpenDatabase, with an ancient
+// store attached for old chain segments. The ancient store is kept in the
+// given directory, resolved in the instance directory if relative, or inside
+// the database directory if empty. Ephemeral nodes get a memory database
+// without an ancient store.
+func (n *Node) OpenDatabaseWithFreezer(name string, cache, handles int, ancient string) (aquadb.Database, error) {
+	if n.config.DataDir == "" {
+		return aquadb.NewMemDatabase(), nil
+	}
+	return aquadb.OpenWithFreezer(n.config.DBEngine, n.config.resolvePath(name), cache, handles, n.config.ancientPath(name, ancient))
 }
 
 // ResolvePath returns the absolute path of a resource in the instance directory.
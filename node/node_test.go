@@ -21,6 +21,7 @@ import (
 	"errors"
 	"io/ioutil"
 	"os"
+	"path/filepath"
 	"reflect"
 	"testing"
 	"time"
@@ -28,6 +29,7 @@ import (
 	"gitlab.com/aquachain/aquachain/crypto"
 	"gitlab.com/aquachain/aquachain/p2p"
 	"gitlab.com/aquachain/aquachain/rpc"
+	rpcclient "gitlab.com/aquachain/aquachain/rpc/rpcclient"
 )
 
 var (
@@ -594,3 +596,34 @@ func TestAPIGather(t *testing.T) {
 		}
 	}
 }
+
+// Tests that the btc namespace is not served on the IPC endpoint, whose
+// clients don't authenticate.
+func TestIPCSkipsBtcNamespace(t *testing.T) {
+	stack, err := NewTestNew(testNodeConfig())
+	if err != nil {
+		t.Fatalf("failed to create protocol stack: %v", err)
+	}
+	stack.ipcEndpoint = filepath.Join(t.TempDir(), "test.ipc")
+	apis := []rpc.API{
+		{Namespace: "single", Version: "1", Service: new(OneMethodApi), Public: true},
+		{Namespace: rpc.BtcNamespace, Version: "1", Service: new(OneMethodApi)},
+	}
+	if err := stack.startIPC(apis); err != nil {
+		t.Fatalf("failed to start IPC endpoint: %v", err)
+	}
+	defer stack.stopIPC()
+
+	client, err := rpcclient.DialIPC(context.Background(), stack.ipcEndpoint)
+	if err != nil {
+		t.Fatalf("failed to dial IPC endpoint: %v", err)
+	}
+	defer client.Close()
+
+	if err := client.Call(nil, "single_theOneMethod"); err != nil {
+		t.Errorf("single_theOneMethod failed: %v", err)
+	}
+	if err := client.Call(nil, "theOneMethod"); err == nil {
+		t.Errorf("btc method served over IPC")
+	}
+}
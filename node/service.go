@@ -43,13 +43,22 @@ func (ctx *ServiceContext) OpenDatabase(name string, cache int, handles int) (aq
 	if ctx.config.DataDir == "" {
 		return aquadb.NewMemDatabase(), nil
 	}
-	db, err := aquadb.NewLDBDatabase(ctx.config.resolvePath(name), cache, handles)
+	db, err := aquadb.Open(ctx.config.DBEngine, ctx.config.resolvePath(name), cache, handles)
 	if err != nil {
 		return nil, err
 	}
 	return db, nil
 }
 
+// OpenDatabaseWithFreezer opens a database like OpenDatabase, with an ancient
+// store for old chain segments attached. See Node.OpenDatabaseWithFreezer.
+func (ctx *ServiceContext) OpenDatabaseWithFreezer(name string, cache int, handles int, ancient string) (aquadb.Database, error) {
+	if ctx.config.DataDir == "" {
+		return aquadb.NewMemDatabase(), nil
+	}
+	return aquadb.OpenWithFreezer(ctx.config.DBEngine, ctx.config.resolvePath(name), cache, handles, ctx.config.ancientPath(name, ancient))
+}
+
 // ResolvePath resolves a user path into the data directory if that was relative
 // and if the user actually uses persistent storage. It will return an empty string
 // for emphemeral storage and the user's own input for absolute paths.
@@ -25,10 +25,10 @@ import (
 	"math/big"
 
 	"gitlab.com/aquachain/aquachain"
+	"gitlab.com/aquachain/aquachain/aqua/accounts/typeddata"
 	"gitlab.com/aquachain/aquachain/common"
 	"gitlab.com/aquachain/aquachain/common/hexutil"
 	"gitlab.com/aquachain/aquachain/core/types"
-	"gitlab.com/aquachain/aquachain/rlp"
 	rpc "gitlab.com/aquachain/aquachain/rpc/rpcclient"
 )
 
@@ -485,13 +485,24 @@ func (c *Client) EstimateGas(ctx context.Context, msg aquachain.CallMsg) (uint64
 // If the transaction was a contract creation use the TransactionReceipt method to get the
 // contract address after the transaction has been mined.
 func (c *Client) SendTransaction(ctx context.Context, tx *types.Transaction) error {
-	data, err := rlp.EncodeToBytes(tx)
+	data, err := tx.MarshalBinary()
 	if err != nil {
 		return err
 	}
 	return c.c.CallContext(ctx, nil, "eth_sendRawTransaction", common.ToHex(data))
 }
 
+// SignTypedData requests the node to calculate the EIP-712 signature of the
+// typed data with the given account, which must be unlocked. The V value of the
+// returned signature is 27 or 28.
+func (c *Client) SignTypedData(ctx context.Context, account common.Address, data *typeddata.TypedData) ([]byte, error) {
+	var sig hexutil.Bytes
+	if err := c.c.CallContext(ctx, &sig, "eth_signTypedData", account, data); err != nil {
+		return nil, err
+	}
+	return sig, nil
+}
+
 // GetBlockTemplate (-rpcapi testing) returns rlp-encoded pending block,
 // for use with testing_submitBlock
 func (c *Client) GetBlockTemplate(ctx context.Context, coinbaseAddr common.Address) ([]byte, error) {
@@ -537,5 +548,8 @@ func toCallArg(msg aquachain.CallMsg) interface{} {
 	if msg.GasPrice != nil {
 		arg["gasPrice"] = (*hexutil.Big)(msg.GasPrice)
 	}
+	if msg.AccessList != nil {
+		arg["accessList"] = msg.AccessList
+	}
 	return arg
 }
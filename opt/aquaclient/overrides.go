@@ -0,0 +1,109 @@
+// Copyright 2018 The aquachain Authors
+// This file is part of the aquachain library.
+//
+// The aquachain library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The aquachain library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the aquachain library. If not, see <http://www.gnu.org/licenses/>.
+
+package aquaclient
+
+import (
+	"context"
+	"encoding/json"
+	"math/big"
+
+	"gitlab.com/aquachain/aquachain"
+	"gitlab.com/aquachain/aquachain/common"
+	"gitlab.com/aquachain/aquachain/common/hexutil"
+)
+
+// OverrideAccount specifies the state of an account to be overridden for the
+// duration of a single call. Nil fields are left untouched.
+//
+// State replaces the entire storage of the account, while StateDiff only
+// replaces the given slots. Setting both is rejected by the node.
+type OverrideAccount struct {
+	Nonce     *uint64
+	Code      []byte
+	Balance   *big.Int
+	State     map[common.Hash]common.Hash
+	StateDiff map[common.Hash]common.Hash
+}
+
+// MarshalJSON implements json.Marshaler.
+func (a OverrideAccount) MarshalJSON() ([]byte, error) {
+	type acc struct {
+		Nonce     *hexutil.Uint64             `json:"nonce,omitempty"`
+		Code      *hexutil.Bytes              `json:"code,omitempty"`
+		Balance   *hexutil.Big                `json:"balance,omitempty"`
+		State     map[common.Hash]common.Hash `json:"state,omitempty"`
+		StateDiff map[common.Hash]common.Hash `json:"stateDiff,omitempty"`
+	}
+	out := acc{
+		Balance:   (*hexutil.Big)(a.Balance),
+		State:     a.State,
+		StateDiff: a.StateDiff,
+	}
+	if a.Nonce != nil {
+		out.Nonce = (*hexutil.Uint64)(a.Nonce)
+	}
+	if a.Code != nil {
+		out.Code = (*hexutil.Bytes)(&a.Code)
+	}
+	return json.Marshal(out)
+}
+
+// BlockOverrides specifies the block header fields to be overridden for the
+// duration of a single call. Nil fields are left untouched.
+type BlockOverrides struct {
+	Number   *big.Int
+	Time     *uint64
+	Coinbase *common.Address
+}
+
+// MarshalJSON implements json.Marshaler.
+func (o BlockOverrides) MarshalJSON() ([]byte, error) {
+	type override struct {
+		Number   *hexutil.Big    `json:"number,omitempty"`
+		Time     *hexutil.Uint64 `json:"time,omitempty"`
+		Coinbase *common.Address `json:"coinbase,omitempty"`
+	}
+	return json.Marshal(override{
+		Number:   (*hexutil.Big)(o.Number),
+		Time:     (*hexutil.Uint64)(o.Time),
+		Coinbase: o.Coinbase,
+	})
+}
+
+// CallContractWithOverrides executes a message call transaction like CallContract,
+// with the given accounts and block header fields overridden for this call only.
+// Both overrides may be nil.
+func (c *Client) CallContractWithOverrides(ctx context.Context, msg aquachain.CallMsg, blockNumber *big.Int, overrides map[common.Address]OverrideAccount, blockOverrides *BlockOverrides) ([]byte, error) {
+	var hex hexutil.Bytes
+	err := c.c.CallContext(ctx, &hex, "eth_call", toCallArg(msg), toBlockNumArg(blockNumber), overrides, blockOverrides)
+	if err != nil {
+		return nil, err
+	}
+	return hex, nil
+}
+
+// EstimateGasWithOverrides estimates the gas needed to execute a specific transaction
+// like EstimateGas, on top of the given block, with the given accounts and block
+// header fields overridden. Both overrides may be nil.
+func (c *Client) EstimateGasWithOverrides(ctx context.Context, msg aquachain.CallMsg, blockNumber *big.Int, overrides map[common.Address]OverrideAccount, blockOverrides *BlockOverrides) (uint64, error) {
+	var hex hexutil.Uint64
+	err := c.c.CallContext(ctx, &hex, "eth_estimateGas", toCallArg(msg), toBlockNumArg(blockNumber), overrides, blockOverrides)
+	if err != nil {
+		return 0, err
+	}
+	return uint64(hex), nil
+}
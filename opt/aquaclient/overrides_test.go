@@ -0,0 +1,90 @@
+// Copyright 2018 The aquachain Authors
+// This file is part of the aquachain library.
+//
+// The aquachain library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The aquachain library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the aquachain library. If not, see <http://www.gnu.org/licenses/>.
+
+package aquaclient
+
+import (
+	"encoding/json"
+	"math/big"
+	"testing"
+
+	"gitlab.com/aquachain/aquachain/common"
+	"gitlab.com/aquachain/aquachain/internal/aquaapi"
+)
+
+// Tests that the client side overrides are encoded in the format the node
+// side RPC API decodes.
+func TestOverridesJSON(t *testing.T) {
+	var (
+		addr     = common.HexToAddress("0x1111111111111111111111111111111111111111")
+		coinbase = common.HexToAddress("0x2222222222222222222222222222222222222222")
+		nonce    = uint64(7)
+		time     = uint64(1600000000)
+	)
+	overrides := map[common.Address]OverrideAccount{
+		addr: {
+			Nonce:     &nonce,
+			Code:      []byte{0x60, 0x00},
+			Balance:   big.NewInt(1000),
+			StateDiff: map[common.Hash]common.Hash{{1}: {2}},
+		},
+	}
+	blob, err := json.Marshal(overrides)
+	if err != nil {
+		t.Fatalf("failed to encode state overrides: %v", err)
+	}
+	var state aquaapi.StateOverride
+	if err := json.Unmarshal(blob, &state); err != nil {
+		t.Fatalf("failed to decode state overrides: %v", err)
+	}
+	account, ok := state[addr]
+	if !ok {
+		t.Fatalf("account %x missing from decoded overrides", addr)
+	}
+	if account.Nonce == nil || uint64(*account.Nonce) != nonce {
+		t.Errorf("nonce mismatch: have %v, want %d", account.Nonce, nonce)
+	}
+	if account.Code == nil || common.Bytes2Hex(*account.Code) != "6000" {
+		t.Errorf("code mismatch: have %v", account.Code)
+	}
+	if account.Balance == nil || account.Balance.ToInt().Int64() != 1000 {
+		t.Errorf("balance mismatch: have %v", account.Balance)
+	}
+	if account.State != nil {
+		t.Errorf("unexpected full state override: %v", *account.State)
+	}
+	if account.StateDiff == nil || (*account.StateDiff)[common.Hash{1}] != (common.Hash{2}) {
+		t.Errorf("state diff mismatch: have %v", account.StateDiff)
+	}
+
+	blob, err = json.Marshal(&BlockOverrides{Number: big.NewInt(42), Time: &time, Coinbase: &coinbase})
+	if err != nil {
+		t.Fatalf("failed to encode block overrides: %v", err)
+	}
+	var block aquaapi.BlockOverrides
+	if err := json.Unmarshal(blob, &block); err != nil {
+		t.Fatalf("failed to decode block overrides: %v", err)
+	}
+	if block.Number == nil || block.Number.ToInt().Int64() != 42 {
+		t.Errorf("number mismatch: have %v", block.Number)
+	}
+	if block.Time == nil || uint64(*block.Time) != time {
+		t.Errorf("time mismatch: have %v", block.Time)
+	}
+	if block.Coinbase == nil || *block.Coinbase != coinbase {
+		t.Errorf("coinbase mismatch: have %v", block.Coinbase)
+	}
+}
@@ -0,0 +1,185 @@
+// Copyright 2018 The aquachain Authors
+// This file is part of the aquachain library.
+//
+// The aquachain library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The aquachain library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the aquachain library. If not, see <http://www.gnu.org/licenses/>.
+
+package aquaclient
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"math/big"
+
+	"gitlab.com/aquachain/aquachain/aquadb"
+	"gitlab.com/aquachain/aquachain/common"
+	"gitlab.com/aquachain/aquachain/common/hexutil"
+	"gitlab.com/aquachain/aquachain/core/state"
+	"gitlab.com/aquachain/aquachain/core/types"
+	"gitlab.com/aquachain/aquachain/crypto"
+	"gitlab.com/aquachain/aquachain/rlp"
+	"gitlab.com/aquachain/aquachain/trie"
+)
+
+// AccountResult is an account and its Merkle proof, as returned by GetProof.
+type AccountResult struct {
+	Address      common.Address
+	AccountProof [][]byte
+	Balance      *big.Int
+	CodeHash     common.Hash
+	Nonce        uint64
+	StorageHash  common.Hash
+	StorageProof []StorageResult
+}
+
+// StorageResult is a storage slot and its Merkle proof, as returned by GetProof.
+type StorageResult struct {
+	Key   common.Hash
+	Value *big.Int
+	Proof [][]byte
+}
+
+type rpcAccountResult struct {
+	Address      common.Address     `json:"address"`
+	AccountProof []hexutil.Bytes    `json:"accountProof"`
+	Balance      *hexutil.Big       `json:"balance"`
+	CodeHash     common.Hash        `json:"codeHash"`
+	Nonce        hexutil.Uint64     `json:"nonce"`
+	StorageHash  common.Hash        `json:"storageHash"`
+	StorageProof []rpcStorageResult `json:"storageProof"`
+}
+
+type rpcStorageResult struct {
+	Key   string          `json:"key"`
+	Value *hexutil.Big    `json:"value"`
+	Proof []hexutil.Bytes `json:"proof"`
+}
+
+// GetProof returns the account and storage values of the given account,
+// including their Merkle proofs. The block number can be nil, in which case
+// the values are taken from the latest known block.
+//
+// The node is not trusted: use AccountResult.Verify with the state root of a
+// header known to be valid before relying on the values.
+func (c *Client) GetProof(ctx context.Context, account common.Address, keys []common.Hash, blockNumber *big.Int) (*AccountResult, error) {
+	hexKeys := make([]string, len(keys))
+	for i, key := range keys {
+		hexKeys[i] = key.Hex()
+	}
+	var res rpcAccountResult
+	if err := c.c.CallContext(ctx, &res, "eth_getProof", account, hexKeys, toBlockNumArg(blockNumber)); err != nil {
+		return nil, err
+	}
+	if res.Balance == nil {
+		return nil, fmt.Errorf("invalid proof: missing balance")
+	}
+	result := &AccountResult{
+		Address:      res.Address,
+		AccountProof: fromHexSlice(res.AccountProof),
+		Balance:      res.Balance.ToInt(),
+		CodeHash:     res.CodeHash,
+		Nonce:        uint64(res.Nonce),
+		StorageHash:  res.StorageHash,
+		StorageProof: make([]StorageResult, len(res.StorageProof)),
+	}
+	for i, st := range res.StorageProof {
+		if st.Value == nil {
+			return nil, fmt.Errorf("invalid proof: missing value of storage key %s", st.Key)
+		}
+		result.StorageProof[i] = StorageResult{
+			Key:   common.HexToHash(st.Key),
+			Value: st.Value.ToInt(),
+			Proof: fromHexSlice(st.Proof),
+		}
+	}
+	return result, nil
+}
+
+func fromHexSlice(h []hexutil.Bytes) [][]byte {
+	b := make([][]byte, len(h))
+	for i := range h {
+		b[i] = h[i]
+	}
+	return b
+}
+
+// Verify checks the account proof against a state root, and each storage
+// proof against the account's storage root. The returned error describes the
+// first mismatch; if nil, every value in the result is proven.
+func (r *AccountResult) Verify(stateRoot common.Hash) error {
+	value, err := verifyProof(stateRoot, crypto.Keccak256(r.Address.Bytes()), r.AccountProof)
+	if err != nil {
+		return fmt.Errorf("account proof: %v", err)
+	}
+	if value == nil {
+		// absent accounts are empty
+		if r.Nonce != 0 || r.Balance.Sign() != 0 || r.StorageHash != types.EmptyRootHash || r.CodeHash != crypto.Keccak256Hash(nil) {
+			return fmt.Errorf("account proof: %x does not exist", r.Address)
+		}
+	} else {
+		var acc state.Account
+		if err := rlp.DecodeBytes(value, &acc); err != nil {
+			return fmt.Errorf("account proof: %v", err)
+		}
+		switch {
+		case acc.Nonce != r.Nonce:
+			return fmt.Errorf("account proof: nonce mismatch: have %d, proven %d", r.Nonce, acc.Nonce)
+		case acc.Balance.Cmp(r.Balance) != 0:
+			return fmt.Errorf("account proof: balance mismatch: have %v, proven %v", r.Balance, acc.Balance)
+		case acc.Root != r.StorageHash:
+			return fmt.Errorf("account proof: storage hash mismatch: have %x, proven %x", r.StorageHash, acc.Root)
+		case !bytes.Equal(acc.CodeHash, r.CodeHash[:]):
+			return fmt.Errorf("account proof: code hash mismatch: have %x, proven %x", r.CodeHash, acc.CodeHash)
+		}
+	}
+	for _, st := range r.StorageProof {
+		if err := st.Verify(r.StorageHash); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Verify checks the storage proof against a storage root.
+func (st *StorageResult) Verify(storageRoot common.Hash) error {
+	proven := new(big.Int)
+	if storageRoot != types.EmptyRootHash {
+		value, err := verifyProof(storageRoot, crypto.Keccak256(st.Key.Bytes()), st.Proof)
+		if err != nil {
+			return fmt.Errorf("storage proof %x: %v", st.Key, err)
+		}
+		if value != nil {
+			_, content, _, err := rlp.Split(value)
+			if err != nil {
+				return fmt.Errorf("storage proof %x: %v", st.Key, err)
+			}
+			proven.SetBytes(content)
+		}
+	}
+	if proven.Cmp(st.Value) != 0 {
+		return fmt.Errorf("storage proof %x: value mismatch: have %v, proven %v", st.Key, st.Value, proven)
+	}
+	return nil
+}
+
+// verifyProof returns the value proven by the list of trie nodes, or nil if
+// the proof shows the key is absent.
+func verifyProof(root common.Hash, key []byte, proof [][]byte) ([]byte, error) {
+	db := aquadb.NewMemDatabase()
+	for _, node := range proof {
+		db.Put(crypto.Keccak256(node), node)
+	}
+	value, err, _ := trie.VerifyProof(root, key, db)
+	return value, err
+}
@@ -0,0 +1,158 @@
+// Copyright 2018 The aquachain Authors
+// This file is part of the aquachain library.
+//
+// The aquachain library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The aquachain library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the aquachain library. If not, see <http://www.gnu.org/licenses/>.
+
+package aquaclient
+
+import (
+	"context"
+	"math/big"
+	"strings"
+	"testing"
+
+	"gitlab.com/aquachain/aquachain/aquadb"
+	"gitlab.com/aquachain/aquachain/common"
+	"gitlab.com/aquachain/aquachain/core/state"
+	"gitlab.com/aquachain/aquachain/core/types"
+	"gitlab.com/aquachain/aquachain/internal/aquaapi"
+	"gitlab.com/aquachain/aquachain/rpc"
+	rpcclient "gitlab.com/aquachain/aquachain/rpc/rpcclient"
+)
+
+// proofState returns a committed state with a few accounts and storage slots
+func proofState(t *testing.T) (*state.StateDB, common.Hash) {
+	statedb, _ := state.New(common.Hash{}, state.NewDatabase(aquadb.NewMemDatabase()))
+	for i := byte(1); i < 50; i++ {
+		addr := common.BytesToAddress([]byte{i})
+		statedb.SetBalance(addr, big.NewInt(int64(i)*1000))
+		statedb.SetNonce(addr, uint64(i))
+		if i%5 == 0 {
+			statedb.SetCode(addr, []byte{i, i})
+			statedb.SetState(addr, common.Hash{1}, common.BytesToHash([]byte{i}))
+			statedb.SetState(addr, common.Hash{2}, common.BytesToHash([]byte{i, i}))
+			statedb.SetState(addr, common.BytesToHash([]byte{1}), common.BytesToHash([]byte{i, i, i}))
+		}
+	}
+	root, err := statedb.Commit(false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	statedb, _ = state.New(root, statedb.Database())
+	return statedb, root
+}
+
+// proofBackend serves a fixed state to the aqua_getProof API
+type proofBackend struct {
+	aquaapi.Backend
+	db   state.Database
+	root common.Hash
+}
+
+func (b *proofBackend) StateAndHeaderByNumber(ctx context.Context, blockNr rpc.BlockNumber) (*state.StateDB, *types.Header, error) {
+	statedb, err := state.New(b.root, b.db)
+	return statedb, &types.Header{Number: new(big.Int), Root: b.root}, err
+}
+
+// proofClient returns a client connected in-process to the aqua_getProof API
+// serving the given state
+func proofClient(t *testing.T, statedb *state.StateDB, root common.Hash) *Client {
+	srv := rpc.NewServer()
+	if _, err := srv.RegisterName("aqua", aquaapi.NewPublicBlockChainAPI(&proofBackend{db: statedb.Database(), root: root})); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(srv.Stop)
+	client := rpcclient.DialInProc(context.Background(), srv)
+	t.Cleanup(client.Close)
+	return NewClient(client)
+}
+
+// makeProof requests a proof from the API
+func makeProof(t *testing.T, client *Client, addr common.Address, keys ...common.Hash) *AccountResult {
+	res, err := client.GetProof(context.Background(), addr, keys, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return res
+}
+
+func TestVerifyProof(t *testing.T) {
+	statedb, root := proofState(t)
+	client := proofClient(t, statedb, root)
+
+	tests := []struct {
+		addr common.Address
+		keys []common.Hash
+	}{
+		{common.BytesToAddress([]byte{1}), nil},
+		{common.BytesToAddress([]byte{10}), []common.Hash{{1}, {2}, {3}}},
+		{common.BytesToAddress([]byte{7}), []common.Hash{{1}}},
+		{common.BytesToAddress([]byte{200}), []common.Hash{{1}}}, // absent account
+	}
+	for i, tt := range tests {
+		res := makeProof(t, client, tt.addr, tt.keys...)
+		if err := res.Verify(root); err != nil {
+			t.Errorf("test %d: valid proof rejected: %v", i, err)
+		}
+	}
+}
+
+func TestVerifyProofTampered(t *testing.T) {
+	statedb, root := proofState(t)
+	client := proofClient(t, statedb, root)
+	addr := common.BytesToAddress([]byte{10})
+
+	tamper := []func(*AccountResult){
+		func(r *AccountResult) { r.Balance = new(big.Int).Add(r.Balance, common.Big1) },
+		func(r *AccountResult) { r.Nonce++ },
+		func(r *AccountResult) { r.CodeHash = common.Hash{} },
+		func(r *AccountResult) { r.StorageHash = common.Hash{} },
+		func(r *AccountResult) { r.StorageProof[0].Value = big.NewInt(99) },
+		func(r *AccountResult) { r.StorageProof[1].Value = big.NewInt(1) }, // claim an empty slot is set
+		func(r *AccountResult) { r.AccountProof = r.AccountProof[:len(r.AccountProof)-1] },
+		func(r *AccountResult) { r.Address = common.BytesToAddress([]byte{200}) },
+	}
+	for i, fn := range tamper {
+		res := makeProof(t, client, addr, common.Hash{1}, common.Hash{3})
+		fn(res)
+		if err := res.Verify(root); err == nil {
+			t.Errorf("test %d: tampered proof accepted", i)
+		}
+	}
+	// a proof is only valid for its own state root
+	if err := makeProof(t, client, addr).Verify(common.Hash{1}); err == nil {
+		t.Errorf("proof accepted against wrong root")
+	}
+}
+
+func TestGetProofInvalidKey(t *testing.T) {
+	statedb, root := proofState(t)
+	client := proofClient(t, statedb, root)
+	addr := common.BytesToAddress([]byte{10})
+
+	for _, key := range []string{"0xzz", "0x" + strings.Repeat("01", 33), "1 "} {
+		var res interface{}
+		if err := client.c.CallContext(context.Background(), &res, "eth_getProof", addr, []string{key}, "latest"); err == nil {
+			t.Errorf("key %q: expected error", key)
+		}
+	}
+	// short keys are left padded
+	var res rpcAccountResult
+	if err := client.c.CallContext(context.Background(), &res, "eth_getProof", addr, []string{"0x1"}, "latest"); err != nil {
+		t.Fatal(err)
+	}
+	if have := res.StorageProof[0].Value.ToInt(); have.Cmp(big.NewInt(0x0a0a0a)) != 0 {
+		t.Errorf("wrong value for short key: have %v, want %v", have, 0x0a0a0a)
+	}
+}
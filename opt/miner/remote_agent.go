@@ -25,6 +25,7 @@ import (
 	"sync/atomic"
 	"time"
 
+	"gitlab.com/aquachain/aquachain/aqua/event"
 	"gitlab.com/aquachain/aquachain/common"
 	"gitlab.com/aquachain/aquachain/common/log"
 	"gitlab.com/aquachain/aquachain/consensus"
@@ -55,6 +56,8 @@ type RemoteAgent struct {
 	hashrateMu sync.RWMutex
 	hashrate   map[common.Hash]hashrate
 
+	workFeed event.Feed // notifies subscribers (eg: stratum) of new work
+
 	running int32 // running indicates whether the agent is active. Call atomically
 }
 
@@ -187,6 +190,26 @@ func (a *RemoteAgent) GetWork() ([3]string, error) {
 	return res, errors.New("No work available yet, don't panic.")
 }
 
+// GetWorkBlock returns the block currently being worked on, and marks it
+// as pending so that a solution can be delivered with SubmitWork.
+func (a *RemoteAgent) GetWorkBlock() (*types.Block, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if a.currentWork == nil {
+		return nil, errors.New("No work available yet, don't panic.")
+	}
+	block := a.currentWork.Block
+	a.work[block.HashNoNonce()] = a.currentWork
+	return block, nil
+}
+
+// SubscribeWork registers a subscription of new work packages. The block sent
+// on the channel is unsealed, and its HashNoNonce can be used with SubmitWork.
+func (a *RemoteAgent) SubscribeWork(ch chan<- *types.Block) event.Subscription {
+	return a.workFeed.Subscribe(ch)
+}
+
 // SubmitWork tries to inject a pow solution into the remote agent, returning
 // whether the solution was accepted or not (not can be both a bad pow as well as
 // any other error, like no work pending).
@@ -243,7 +266,14 @@ func (a *RemoteAgent) loop(workCh chan *Work, quitCh chan struct{}) {
 		case work := <-workCh:
 			a.mu.Lock()
 			a.currentWork = work
+			if work != nil {
+				// mark pending for subscribers, who never call GetWork
+				a.work[work.Block.HashNoNonce()] = work
+			}
 			a.mu.Unlock()
+			if work != nil {
+				a.workFeed.Send(work.Block)
+			}
 		case <-ticker.C:
 			// cleanup
 			a.mu.Lock()
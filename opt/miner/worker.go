@@ -378,7 +378,7 @@ func (w *worker) makeCurrent(parent *types.Block, header *types.Header) error {
 
 	work := &Work{
 		config:    w.config,
-		signer:    types.NewEIP155Signer(w.config.ChainId),
+		signer:    types.NewEIP2930Signer(w.config.ChainId),
 		state:     state,
 		ancestors: set.NewSet(),
 		family:    set.NewSet(),
@@ -577,7 +577,7 @@ func (env *Work) commitTransactions(mux *event.TypeMux, txs *types.TransactionsB
 		// Error may be ignored here. The error has already been checked
 		// during transaction acceptance is the transaction pool.
 		//
-		// We use the eip155 signer regardless of the current hf.
+		// We use the eip2930 signer regardless of the current hf.
 		from, _ := types.Sender(env.signer, tx)
 		// Check whether the tx is replay protected. If we're not in the EIP155 hf
 		// phase, start ignoring the sender until we do.
@@ -586,6 +586,12 @@ func (env *Work) commitTransactions(mux *event.TypeMux, txs *types.TransactionsB
 			txs.Pop()
 			continue
 		}
+		// Typed transactions wait for the hf enabling them
+		if tx.Type() != types.LegacyTxType && !env.config.IsEIP2718(env.header.Number) {
+			log.Trace("Ignoring typed transaction", "hash", tx.Hash(), "type", tx.Type())
+			txs.Pop()
+			continue
+		}
 		// Start executing the transaction
 		env.state.Prepare(tx.Hash(), common.Hash{}, env.tcount)
 
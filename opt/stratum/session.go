@@ -0,0 +1,406 @@
+// Copyright 2018 The aquachain Authors
+// This file is part of the aquachain library.
+//
+// The aquachain library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The aquachain library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the aquachain library. If not, see <http://www.gnu.org/licenses/>.
+
+package stratum
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"io"
+	"net"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"gitlab.com/aquachain/aquachain/common"
+	"gitlab.com/aquachain/aquachain/common/hexutil"
+	"gitlab.com/aquachain/aquachain/common/log"
+	"gitlab.com/aquachain/aquachain/consensus/aquahash"
+	"gitlab.com/aquachain/aquachain/consensus/lightvalid"
+	"gitlab.com/aquachain/aquachain/core/types"
+)
+
+const (
+	// maxRequestSize is the longest line accepted from a miner
+	maxRequestSize = 4096
+
+	// sendQueueSize is the number of messages buffered for a miner. A miner
+	// that falls further behind than this is disconnected.
+	sendQueueSize = 16
+
+	// writeTimeout is the deadline for writing one message to a miner
+	writeTimeout = 10 * time.Second
+)
+
+// Error is a stratum error, using the codes from the original stratum spec
+type Error struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+func (e *Error) Error() string { return e.Message }
+
+var (
+	errOther        = &Error{20, "Other/Unknown"}
+	errStale        = &Error{21, "Job not found (=stale)"}
+	errDuplicate    = &Error{22, "Duplicate share"}
+	errLowDiff      = &Error{23, "Low difficulty share"}
+	errUnauthorized = &Error{24, "Unauthorized worker"}
+	errNoWork       = &Error{25, "No work available yet"}
+	errBadRequest   = &Error{-32600, "Invalid request"}
+	errBadMethod    = &Error{-32601, "Method not found"}
+	errBadParams    = &Error{-32602, "Invalid params"}
+)
+
+// errSlowClient is returned when a miner's send queue is full
+var errSlowClient = errors.New("stratum: send queue full")
+
+var workerPattern = regexp.MustCompile(`^[0-9a-zA-Z_-]{1,32}$`)
+
+type request struct {
+	ID     json.RawMessage `json:"id"`
+	Method string          `json:"method"`
+	Params []string        `json:"params"`
+	Worker string          `json:"worker"`
+}
+
+type response struct {
+	ID      json.RawMessage `json:"id"`
+	Version string          `json:"jsonrpc"`
+	Result  interface{}     `json:"result"`
+	Error   *Error          `json:"error,omitempty"`
+}
+
+// session is a single miner connection
+type session struct {
+	srv  *Server
+	conn net.Conn
+
+	out       chan *response // queued messages, written by writeLoop
+	quit      chan struct{}
+	closeOnce sync.Once
+
+	mu       sync.Mutex
+	login    common.Address
+	worker   string
+	authed   bool
+	diff     uint64
+	accepted uint64
+	rejected uint64
+	stale    uint64
+	blocks   uint64
+	last     time.Time
+}
+
+func newSession(srv *Server, conn net.Conn) *session {
+	return &session{
+		srv:  srv,
+		conn: conn,
+		out:  make(chan *response, sendQueueSize),
+		quit: make(chan struct{}),
+		diff: srv.cfg.Difficulty,
+	}
+}
+
+func (c *session) stats() (WorkerStats, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if !c.authed {
+		return WorkerStats{}, false
+	}
+	return WorkerStats{
+		Login:     c.login,
+		Worker:    c.worker,
+		Remote:    c.conn.RemoteAddr().String(),
+		Diff:      c.diff,
+		Accepted:  c.accepted,
+		Rejected:  c.rejected,
+		Stale:     c.stale,
+		Blocks:    c.blocks,
+		LastShare: c.last,
+	}, true
+}
+
+// serve reads requests until the connection is closed or times out
+func (c *session) serve() {
+	writerDone := make(chan struct{})
+	go func() {
+		c.writeLoop()
+		close(writerDone)
+	}()
+	defer func() {
+		// let the writer flush any final reply before closing
+		c.closeOnce.Do(func() { close(c.quit) })
+		<-writerDone
+		c.conn.Close()
+	}()
+	remote := c.conn.RemoteAddr().String()
+	log.Debug("Stratum miner connected", "remote", remote)
+	defer log.Debug("Stratum miner disconnected", "remote", remote)
+
+	r := bufio.NewReaderSize(c.conn, maxRequestSize)
+	for {
+		c.conn.SetReadDeadline(time.Now().Add(c.srv.cfg.Timeout))
+		line, isPrefix, err := r.ReadLine()
+		if err != nil {
+			if err != io.EOF {
+				log.Trace("Stratum read error", "remote", remote, "err", err)
+			}
+			return
+		}
+		if isPrefix {
+			log.Debug("Stratum request too large", "remote", remote)
+			return
+		}
+		if len(strings.TrimSpace(string(line))) == 0 {
+			continue
+		}
+		var req request
+		if err := json.Unmarshal(line, &req); err != nil {
+			c.reply(nil, nil, errBadRequest)
+			return
+		}
+		result, rerr := c.handle(&req)
+		if err := c.reply(req.ID, result, rerr); err != nil {
+			return
+		}
+	}
+}
+
+func (c *session) handle(req *request) (interface{}, *Error) {
+	switch req.Method {
+	case "eth_submitLogin", "aqua_submitLogin":
+		return c.handleLogin(req)
+	case "eth_getWork", "aqua_getWork":
+		if !c.authorized() {
+			return nil, errUnauthorized
+		}
+		j, err := c.srv.currentJob()
+		if err != nil {
+			return nil, errNoWork
+		}
+		return c.workPackage(j), nil
+	case "eth_submitWork", "aqua_submitWork":
+		if !c.authorized() {
+			return nil, errUnauthorized
+		}
+		return c.handleSubmit(req)
+	case "eth_submitHashrate", "aqua_submitHashrate":
+		return true, nil
+	default:
+		return nil, errBadMethod
+	}
+}
+
+// handleLogin accepts params [address(.worker), password]. The password may
+// contain "d=N" to request share difficulty N.
+func (c *session) handleLogin(req *request) (interface{}, *Error) {
+	if len(req.Params) == 0 {
+		return nil, errBadParams
+	}
+	login, worker := req.Params[0], req.Worker
+	if i := strings.IndexAny(login, "./"); i != -1 {
+		login, worker = login[:i], login[i+1:]
+	}
+	if !common.IsHexAddress(login) {
+		return nil, errUnauthorized
+	}
+	if worker == "" {
+		worker = "0"
+	}
+	if !workerPattern.MatchString(worker) {
+		return nil, errBadParams
+	}
+	diff := c.srv.cfg.Difficulty
+	if len(req.Params) > 1 {
+		for _, field := range strings.Split(req.Params[1], ",") {
+			if !strings.HasPrefix(field, "d=") {
+				continue
+			}
+			d, err := strconv.ParseUint(field[2:], 10, 64)
+			if err != nil || d == 0 {
+				return nil, errBadParams
+			}
+			if d < c.srv.cfg.MinDiff {
+				d = c.srv.cfg.MinDiff
+			}
+			diff = d
+		}
+	}
+	c.mu.Lock()
+	c.login = common.HexToAddress(login)
+	c.worker = worker
+	c.diff = diff
+	c.authed = true
+	c.mu.Unlock()
+	log.Info("Stratum worker logged in", "login", c.login, "worker", worker, "diff", diff, "remote", c.conn.RemoteAddr())
+	return true, nil
+}
+
+// handleSubmit accepts params [nonce, powhash, mixdigest]
+func (c *session) handleSubmit(req *request) (interface{}, *Error) {
+	if len(req.Params) != 3 {
+		return nil, errBadParams
+	}
+	var (
+		nonce     types.BlockNonce
+		hash, mix common.Hash
+	)
+	if nonce.UnmarshalText([]byte(req.Params[0])) != nil ||
+		hash.UnmarshalText([]byte(req.Params[1])) != nil ||
+		mix.UnmarshalText([]byte(req.Params[2])) != nil {
+		return nil, errBadParams
+	}
+	j, stale := c.srv.lookupJob(hash)
+	if j == nil || stale {
+		c.count(&c.stale)
+		staleShareMeter.Mark(1)
+		return nil, errStale
+	}
+	j.seenMu.Lock()
+	_, dup := j.seen[nonce.Uint64()]
+	j.seen[nonce.Uint64()] = struct{}{}
+	j.seenMu.Unlock()
+	if dup {
+		c.count(&c.rejected)
+		rejectedShareMeter.Mark(1)
+		return nil, errDuplicate
+	}
+	if mix != lightvalid.NoMixDigest {
+		c.count(&c.rejected)
+		rejectedShareMeter.Mark(1)
+		return nil, errBadParams
+	}
+	c.mu.Lock()
+	diff := c.diff
+	c.mu.Unlock()
+
+	share := shareBlock{j.block, nonce.Uint64(), shareDifficulty(diff, j.block)}
+	if err := c.srv.validator.VerifyWithError(share); err != nil {
+		c.count(&c.rejected)
+		rejectedShareMeter.Mark(1)
+		if err == lightvalid.ErrPOW {
+			return nil, errLowDiff
+		}
+		return nil, errOther
+	}
+	c.count(&c.accepted)
+	acceptedShareMeter.Mark(1)
+
+	// check against the real difficulty
+	share.difficulty = j.block.Difficulty()
+	if c.srv.validator.Verify(share) {
+		log.Info("Stratum worker found block", "number", j.block.NumberU64(), "login", c.login, "worker", c.worker)
+		if !c.srv.agent.SubmitWork(nonce, mix, hash) {
+			log.Warn("Stratum block solution rejected", "number", j.block.NumberU64(), "hash", hash)
+			return false, nil
+		}
+		c.count(&c.blocks)
+		blockFoundMeter.Mark(1)
+	}
+	return true, nil
+}
+
+func (c *session) authorized() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.authed
+}
+
+func (c *session) count(n *uint64) {
+	c.mu.Lock()
+	*n++
+	c.last = time.Now()
+	c.mu.Unlock()
+}
+
+// workPackage returns [powhash, seedhash, target, height] for the job
+func (c *session) workPackage(j *job) []string {
+	c.mu.Lock()
+	diff := c.diff
+	c.mu.Unlock()
+	block := j.block
+	seed := aquahash.SeedHash(block.NumberU64(), byte(block.Version()))
+	return []string{
+		block.HashNoNonce().Hex(),
+		common.BytesToHash(seed).Hex(),
+		target(shareDifficulty(diff, block)).Hex(),
+		hexutil.EncodeUint64(block.NumberU64()),
+	}
+}
+
+// notify queues a new job for a logged in miner
+func (c *session) notify(j *job) {
+	if !c.authorized() {
+		return
+	}
+	if err := c.reply(json.RawMessage("0"), c.workPackage(j), nil); err != nil {
+		log.Debug("Stratum miner too slow, disconnecting", "remote", c.conn.RemoteAddr())
+	}
+}
+
+// reply queues a response for the miner without blocking. A miner whose queue
+// is full is disconnected.
+func (c *session) reply(id json.RawMessage, result interface{}, err *Error) error {
+	if id == nil {
+		id = json.RawMessage("null")
+	}
+	select {
+	case c.out <- &response{ID: id, Version: "2.0", Result: result, Error: err}:
+		return nil
+	default:
+		c.close()
+		return errSlowClient
+	}
+}
+
+// close disconnects the miner, stopping both the read and write loops
+func (c *session) close() {
+	c.closeOnce.Do(func() { close(c.quit) })
+	c.conn.Close()
+}
+
+// writeLoop writes queued messages until the session is closed, then flushes
+// whatever is left in the queue.
+func (c *session) writeLoop() {
+	enc := json.NewEncoder(c.conn)
+	for {
+		select {
+		case msg := <-c.out:
+			c.conn.SetWriteDeadline(time.Now().Add(writeTimeout))
+			if err := enc.Encode(msg); err != nil {
+				log.Trace("Stratum write error", "remote", c.conn.RemoteAddr(), "err", err)
+				c.close()
+				return
+			}
+		case <-c.quit:
+			c.conn.SetWriteDeadline(time.Now().Add(writeTimeout))
+			for {
+				select {
+				case msg := <-c.out:
+					if enc.Encode(msg) != nil {
+						return
+					}
+				default:
+					return
+				}
+			}
+		}
+	}
+}
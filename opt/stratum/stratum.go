@@ -0,0 +1,402 @@
+// Copyright 2018 The aquachain Authors
+// This file is part of the aquachain library.
+//
+// The aquachain library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The aquachain library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the aquachain library. If not, see <http://www.gnu.org/licenses/>.
+
+// Package stratum implements a stratum mining endpoint built into the node.
+//
+// The wire protocol is line-delimited JSON-RPC over TCP, in the style used by
+// open-aquachain-pool ("eth-proxy" stratum):
+//
+//	-> {"id":1,"method":"eth_submitLogin","params":["0xaddress.rig1","x"]}
+//	<- {"id":1,"jsonrpc":"2.0","result":true}
+//	-> {"id":2,"method":"eth_getWork","params":[]}
+//	<- {"id":2,"jsonrpc":"2.0","result":["0xpowhash","0xseed","0xtarget","0xheight"]}
+//	<- {"id":0,"jsonrpc":"2.0","result":["0xpowhash","0xseed","0xtarget","0xheight"]} (new job)
+//	-> {"id":3,"method":"eth_submitWork","params":["0xnonce","0xpowhash","0xmixdigest"]}
+//	<- {"id":3,"jsonrpc":"2.0","result":true}
+//
+// Work is provided by a miner.RemoteAgent, shares are validated with the
+// lightvalid package, and shares meeting the block difficulty are submitted
+// to the agent as solutions.
+package stratum
+
+import (
+	"errors"
+	"math/big"
+	"net"
+	"sync"
+	"time"
+
+	"gitlab.com/aquachain/aquachain/aqua/event"
+	"gitlab.com/aquachain/aquachain/common"
+	"gitlab.com/aquachain/aquachain/common/log"
+	"gitlab.com/aquachain/aquachain/common/metrics"
+	"gitlab.com/aquachain/aquachain/consensus/lightvalid"
+	"gitlab.com/aquachain/aquachain/core/types"
+)
+
+var (
+	acceptedShareMeter = metrics.NewRegisteredMeter("stratum/shares/accepted", nil)
+	rejectedShareMeter = metrics.NewRegisteredMeter("stratum/shares/rejected", nil)
+	staleShareMeter    = metrics.NewRegisteredMeter("stratum/shares/stale", nil)
+	blockFoundMeter    = metrics.NewRegisteredMeter("stratum/blocks", nil)
+)
+
+// maxJobs is the number of recent jobs kept for validating late shares
+const maxJobs = 8
+
+// Config holds the stratum server options
+type Config struct {
+	Addr       string        // Listen address, eg: "0.0.0.0:8888" (disabled if empty)
+	Difficulty uint64        // Default share difficulty
+	MinDiff    uint64        `toml:",omitempty"` // Lowest share difficulty a miner may request with "d=N" password
+	MaxConns   int           `toml:",omitempty"` // Maximum concurrent connections (0 = 1024)
+	Timeout    time.Duration `toml:",omitempty"` // Idle connection timeout (0 = 10 minutes)
+}
+
+// DefaultConfig is the default stratum configuration (listener disabled)
+var DefaultConfig = Config{
+	Difficulty: 1000,
+	MinDiff:    100,
+	MaxConns:   1024,
+	Timeout:    10 * time.Minute,
+}
+
+// Agent provides work and accepts solutions, implemented by miner.RemoteAgent
+type Agent interface {
+	GetWorkBlock() (*types.Block, error)
+	SubmitWork(nonce types.BlockNonce, mixDigest, hash common.Hash) bool
+	SubscribeWork(ch chan<- *types.Block) event.Subscription
+}
+
+// WorkerStats is a snapshot of a connected worker's share counts
+type WorkerStats struct {
+	Login     common.Address `json:"login"`
+	Worker    string         `json:"worker"`
+	Remote    string         `json:"remote"`
+	Diff      uint64         `json:"difficulty"`
+	Accepted  uint64         `json:"accepted"`
+	Rejected  uint64         `json:"rejected"`
+	Stale     uint64         `json:"stale"`
+	Blocks    uint64         `json:"blocks"`
+	LastShare time.Time      `json:"lastShare"`
+}
+
+// job is a work package sent to miners
+type job struct {
+	block  *types.Block
+	seen   map[uint64]struct{} // submitted nonces, for duplicate detection
+	seenMu sync.Mutex
+}
+
+// Server is a stratum server backed by an Agent
+type Server struct {
+	cfg      Config
+	agent    Agent
+	prestart func() error // called on first login, eg: starting the miner
+
+	listener net.Listener
+	quit     chan struct{}
+	wg       sync.WaitGroup
+
+	mu       sync.RWMutex
+	jobs     map[common.Hash]*job
+	order    []common.Hash // Hashes of the jobs, oldest first
+	current  *job
+	sessions map[*session]struct{}
+
+	validator *lightvalid.Light
+	once      sync.Once
+}
+
+// New creates a stratum server. The prestart function, if not nil, is called
+// once before the first job is requested, and may be used to start mining.
+func New(cfg Config, agent Agent, prestart func() error) *Server {
+	if cfg.Difficulty == 0 {
+		cfg.Difficulty = DefaultConfig.Difficulty
+	}
+	if cfg.MinDiff == 0 || cfg.MinDiff > cfg.Difficulty {
+		cfg.MinDiff = cfg.Difficulty
+	}
+	if cfg.MaxConns <= 0 {
+		cfg.MaxConns = DefaultConfig.MaxConns
+	}
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = DefaultConfig.Timeout
+	}
+	return &Server{
+		cfg:       cfg,
+		agent:     agent,
+		prestart:  prestart,
+		quit:      make(chan struct{}),
+		jobs:      make(map[common.Hash]*job),
+		sessions:  make(map[*session]struct{}),
+		validator: lightvalid.New(),
+	}
+}
+
+// Start listens on the configured address and serves miners until Stop is called.
+func (s *Server) Start() error {
+	if s.cfg.Addr == "" {
+		return errors.New("stratum: no listen address")
+	}
+	l, err := net.Listen("tcp", s.cfg.Addr)
+	if err != nil {
+		return err
+	}
+	return s.Serve(l)
+}
+
+// Serve serves miners on an existing listener, returning immediately.
+func (s *Server) Serve(l net.Listener) error {
+	s.listener = l
+	workCh := make(chan *types.Block, 4)
+	sub := s.agent.SubscribeWork(workCh)
+	s.wg.Add(2)
+	go s.workLoop(workCh, sub)
+	go s.acceptLoop()
+	log.Info("Stratum server started", "addr", l.Addr(), "difficulty", s.cfg.Difficulty)
+	return nil
+}
+
+// Stop closes the listener and all miner connections.
+func (s *Server) Stop() {
+	select {
+	case <-s.quit:
+		return
+	default:
+	}
+	close(s.quit)
+	if s.listener != nil {
+		s.listener.Close()
+	}
+	for _, sess := range s.sessionList() {
+		sess.close()
+	}
+	s.wg.Wait()
+	log.Info("Stratum server stopped")
+}
+
+// Addr returns the listening address, or nil if not started.
+func (s *Server) Addr() net.Addr {
+	if s.listener == nil {
+		return nil
+	}
+	return s.listener.Addr()
+}
+
+// Workers returns a snapshot of all connected, logged in workers
+func (s *Server) Workers() []WorkerStats {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	stats := make([]WorkerStats, 0, len(s.sessions))
+	for sess := range s.sessions {
+		if st, ok := sess.stats(); ok {
+			stats = append(stats, st)
+		}
+	}
+	return stats
+}
+
+func (s *Server) acceptLoop() {
+	defer s.wg.Done()
+	for {
+		conn, err := s.listener.Accept()
+		if err != nil {
+			select {
+			case <-s.quit:
+				return
+			default:
+			}
+			if ne, ok := err.(net.Error); ok && ne.Timeout() {
+				time.Sleep(time.Second)
+				continue
+			}
+			log.Warn("Stratum accept error", "err", err)
+			return
+		}
+		s.mu.Lock()
+		if len(s.sessions) >= s.cfg.MaxConns {
+			s.mu.Unlock()
+			log.Debug("Stratum connection limit reached", "remote", conn.RemoteAddr())
+			conn.Close()
+			continue
+		}
+		sess := newSession(s, conn)
+		s.sessions[sess] = struct{}{}
+		s.mu.Unlock()
+
+		s.wg.Add(1)
+		go func() {
+			defer s.wg.Done()
+			sess.serve()
+			s.mu.Lock()
+			delete(s.sessions, sess)
+			s.mu.Unlock()
+		}()
+	}
+}
+
+// workLoop receives new work from the agent and notifies all miners
+func (s *Server) workLoop(workCh chan *types.Block, sub event.Subscription) {
+	defer s.wg.Done()
+	defer sub.Unsubscribe()
+	for {
+		select {
+		case <-s.quit:
+			return
+		case err := <-sub.Err():
+			if err != nil {
+				log.Warn("Stratum work subscription error", "err", err)
+			}
+			return
+		case block := <-workCh:
+			if j := s.setJob(block); j != nil {
+				s.broadcast(j)
+			}
+		}
+	}
+}
+
+// setJob makes the block the current job, returning nil if it was already known.
+func (s *Server) setJob(block *types.Block) *job {
+	if block == nil {
+		return nil
+	}
+	hash := block.HashNoNonce()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if j, ok := s.jobs[hash]; ok {
+		s.current = j
+		s.touchJob(hash)
+		return nil
+	}
+	j := &job{block: block, seen: make(map[uint64]struct{})}
+	s.jobs[hash] = j
+	s.order = append(s.order, hash)
+	s.current = j
+	// drop anything from a previous height, then the oldest jobs
+	order := s.order[:0]
+	for _, h := range s.order {
+		if s.jobs[h].block.NumberU64() < block.NumberU64() {
+			delete(s.jobs, h)
+			continue
+		}
+		order = append(order, h)
+	}
+	for len(order) > maxJobs {
+		delete(s.jobs, order[0])
+		order = order[1:]
+	}
+	s.order = order
+	return j
+}
+
+// touchJob moves a known job to the end of the eviction order, as it became
+// current again.
+func (s *Server) touchJob(hash common.Hash) {
+	for i, h := range s.order {
+		if h == hash {
+			s.order = append(append(s.order[:i:i], s.order[i+1:]...), hash)
+			return
+		}
+	}
+}
+
+// currentJob returns the current job, fetching it from the agent if needed.
+func (s *Server) currentJob() (*job, error) {
+	s.once.Do(func() {
+		if s.prestart != nil {
+			if err := s.prestart(); err != nil {
+				log.Warn("Stratum could not start miner", "err", err)
+			}
+		}
+	})
+	s.mu.RLock()
+	j := s.current
+	s.mu.RUnlock()
+	if j != nil {
+		return j, nil
+	}
+	block, err := s.agent.GetWorkBlock()
+	if err != nil {
+		return nil, err
+	}
+	s.setJob(block)
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.current, nil
+}
+
+// lookupJob returns the job for the pow hash, and whether it is stale
+func (s *Server) lookupJob(hash common.Hash) (j *job, stale bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	j = s.jobs[hash]
+	if j == nil {
+		return nil, true
+	}
+	return j, s.current != nil && j.block.NumberU64() < s.current.block.NumberU64()
+}
+
+// sessionList returns the connected sessions, so they can be used without
+// holding the server lock.
+func (s *Server) sessionList() []*session {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	list := make([]*session, 0, len(s.sessions))
+	for sess := range s.sessions {
+		list = append(list, sess)
+	}
+	return list
+}
+
+// broadcast queues the job for every miner. It never blocks on the network,
+// miners that fall behind are disconnected instead.
+func (s *Server) broadcast(j *job) {
+	for _, sess := range s.sessionList() {
+		sess.notify(j)
+	}
+}
+
+// shareBlock overrides the nonce and difficulty of a job block, for
+// validating shares with lightvalid.
+type shareBlock struct {
+	*types.Block
+	nonce      uint64
+	difficulty *big.Int
+}
+
+func (b shareBlock) Nonce() uint64        { return b.nonce }
+func (b shareBlock) Difficulty() *big.Int { return b.difficulty }
+
+// shareDifficulty returns the share difficulty, never above the block difficulty
+func shareDifficulty(diff uint64, block *types.Block) *big.Int {
+	d := new(big.Int).SetUint64(diff)
+	if bd := block.Difficulty(); d.Cmp(bd) > 0 {
+		return bd
+	}
+	return d
+}
+
+// target returns the boundary for the share difficulty, 2^256/difficulty
+func target(difficulty *big.Int) common.Hash {
+	n := new(big.Int).Lsh(common.Big1, 256)
+	n.Div(n, difficulty)
+	if n.BitLen() > 256 {
+		n.Sub(n, common.Big1)
+	}
+	return common.BigToHash(n)
+}
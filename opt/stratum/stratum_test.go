@@ -0,0 +1,320 @@
+// Copyright 2018 The aquachain Authors
+// This file is part of the aquachain library.
+//
+// The aquachain library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The aquachain library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the aquachain library. If not, see <http://www.gnu.org/licenses/>.
+
+package stratum
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	"gitlab.com/aquachain/aquachain/aqua/event"
+	"gitlab.com/aquachain/aquachain/common"
+	"gitlab.com/aquachain/aquachain/core/types"
+)
+
+type testAgent struct {
+	mu        sync.Mutex
+	block     *types.Block
+	feed      event.Feed
+	submitted []common.Hash
+}
+
+func (a *testAgent) GetWorkBlock() (*types.Block, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.block == nil {
+		return nil, fmt.Errorf("no work")
+	}
+	return a.block, nil
+}
+
+func (a *testAgent) SubmitWork(nonce types.BlockNonce, mixDigest, hash common.Hash) bool {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.submitted = append(a.submitted, hash)
+	return true
+}
+
+func (a *testAgent) SubscribeWork(ch chan<- *types.Block) event.Subscription {
+	return a.feed.Subscribe(ch)
+}
+
+func testBlock(number int64, difficulty *big.Int) *types.Block {
+	return types.NewBlockWithHeader(&types.Header{
+		Number:     big.NewInt(number),
+		Difficulty: difficulty,
+		Time:       big.NewInt(1536181711),
+		Version:    2,
+	})
+}
+
+type testMiner struct {
+	t    *testing.T
+	conn net.Conn
+	r    *bufio.Reader
+	id   int
+}
+
+func (m *testMiner) call(method string, params ...string) response {
+	m.id++
+	req := map[string]interface{}{"id": m.id, "method": method, "params": params}
+	if err := json.NewEncoder(m.conn).Encode(req); err != nil {
+		m.t.Fatal(err)
+	}
+	return m.read()
+}
+
+func (m *testMiner) read() response {
+	m.conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	line, err := m.r.ReadBytes('\n')
+	if err != nil {
+		m.t.Fatal(err)
+	}
+	var resp response
+	if err := json.Unmarshal(line, &resp); err != nil {
+		m.t.Fatal(err)
+	}
+	return resp
+}
+
+func startTestServer(t *testing.T, agent *testAgent, cfg Config) (*Server, *testMiner) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := New(cfg, agent, nil)
+	srv.Serve(l)
+	conn, err := net.Dial("tcp", l.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return srv, &testMiner{t: t, conn: conn, r: bufio.NewReader(conn)}
+}
+
+func TestStratumShares(t *testing.T) {
+	agent := &testAgent{block: testBlock(1, new(big.Int).Lsh(common.Big1, 200))}
+	srv, miner := startTestServer(t, agent, Config{Difficulty: 1})
+	defer srv.Stop()
+
+	if resp := miner.call("eth_getWork"); resp.Error == nil || resp.Error.Code != errUnauthorized.Code {
+		t.Fatalf("expected unauthorized error, got %+v", resp)
+	}
+	if resp := miner.call("eth_submitLogin", "0x3317e8405e75551ec7eeeb3508650e7b349665ff.rig1", "x"); resp.Result != true {
+		t.Fatalf("login failed: %+v", resp)
+	}
+	resp := miner.call("eth_getWork")
+	work, ok := resp.Result.([]interface{})
+	if !ok || len(work) != 4 {
+		t.Fatalf("bad work package: %+v", resp)
+	}
+	if work[0] != agent.block.HashNoNonce().Hex() {
+		t.Fatalf("wrong pow hash: have %v, want %v", work[0], agent.block.HashNoNonce().Hex())
+	}
+	// difficulty 1 accepts any share
+	zero := common.Hash{}.Hex()
+	if resp := miner.call("eth_submitWork", "0x0000000000000001", agent.block.HashNoNonce().Hex(), zero); resp.Result != true {
+		t.Fatalf("share rejected: %+v", resp)
+	}
+	if resp := miner.call("eth_submitWork", "0x0000000000000001", agent.block.HashNoNonce().Hex(), zero); resp.Error == nil || resp.Error.Code != errDuplicate.Code {
+		t.Fatalf("expected duplicate share error, got %+v", resp)
+	}
+	if resp := miner.call("eth_submitWork", "0x0000000000000002", common.Hash{1}.Hex(), zero); resp.Error == nil || resp.Error.Code != errStale.Code {
+		t.Fatalf("expected stale share error, got %+v", resp)
+	}
+	if len(agent.submitted) != 0 {
+		t.Fatalf("share was submitted as a block")
+	}
+	workers := srv.Workers()
+	if len(workers) != 1 || workers[0].Worker != "rig1" || workers[0].Accepted != 1 || workers[0].Rejected != 1 || workers[0].Stale != 1 {
+		t.Fatalf("unexpected worker stats: %+v", workers)
+	}
+}
+
+func TestStratumBlockFound(t *testing.T) {
+	agent := &testAgent{}
+	srv, miner := startTestServer(t, agent, Config{Difficulty: 1})
+	defer srv.Stop()
+
+	if resp := miner.call("eth_submitLogin", "0x3317e8405e75551ec7eeeb3508650e7b349665ff", "d=1"); resp.Result != true {
+		t.Fatalf("login failed: %+v", resp)
+	}
+	if resp := miner.call("eth_getWork"); resp.Error == nil || resp.Error.Code != errNoWork.Code {
+		t.Fatalf("expected no work error, got %+v", resp)
+	}
+	// new work is pushed to logged in miners
+	block := testBlock(2, big.NewInt(1))
+	agent.feed.Send(block)
+	notify := miner.read()
+	if string(notify.ID) != "0" {
+		t.Fatalf("expected notification, got %+v", notify)
+	}
+	if work := notify.Result.([]interface{}); work[0] != block.HashNoNonce().Hex() {
+		t.Fatalf("wrong pow hash in notification: %v", work[0])
+	}
+	if resp := miner.call("eth_submitWork", "0x0000000000000001", block.HashNoNonce().Hex(), common.Hash{}.Hex()); resp.Result != true {
+		t.Fatalf("solution rejected: %+v", resp)
+	}
+	if len(agent.submitted) != 1 || agent.submitted[0] != block.HashNoNonce() {
+		t.Fatalf("solution not submitted to agent: %v", agent.submitted)
+	}
+}
+
+func TestStratumLowDifficulty(t *testing.T) {
+	agent := &testAgent{block: testBlock(1, new(big.Int).Lsh(common.Big1, 250))}
+	srv, miner := startTestServer(t, agent, Config{Difficulty: 1})
+	defer srv.Stop()
+
+	if resp := miner.call("eth_submitLogin", "0x3317e8405e75551ec7eeeb3508650e7b349665ff", "d=18446744073709551615"); resp.Result != true {
+		t.Fatalf("login failed: %+v", resp)
+	}
+	miner.call("eth_getWork")
+	if resp := miner.call("eth_submitWork", "0x0000000000000001", agent.block.HashNoNonce().Hex(), common.Hash{}.Hex()); resp.Error == nil || resp.Error.Code != errLowDiff.Code {
+		t.Fatalf("expected low difficulty error, got %+v", resp)
+	}
+}
+
+// pipeListener hands out in-memory connections, which block on write until
+// the other end reads.
+type pipeListener struct {
+	conns chan net.Conn
+	quit  chan struct{}
+	once  sync.Once
+}
+
+func newPipeListener() *pipeListener {
+	return &pipeListener{conns: make(chan net.Conn), quit: make(chan struct{})}
+}
+
+func (l *pipeListener) Accept() (net.Conn, error) {
+	select {
+	case c := <-l.conns:
+		return c, nil
+	case <-l.quit:
+		return nil, fmt.Errorf("listener closed")
+	}
+}
+
+func (l *pipeListener) Close() error   { l.once.Do(func() { close(l.quit) }); return nil }
+func (l *pipeListener) Addr() net.Addr { return &net.TCPAddr{IP: net.IPv4(127, 0, 0, 1)} }
+
+func (l *pipeListener) dial(t *testing.T) *testMiner {
+	client, server := net.Pipe()
+	l.conns <- server
+	return &testMiner{t: t, conn: client, r: bufio.NewReader(client)}
+}
+
+func TestStratumSlowMiner(t *testing.T) {
+	agent := &testAgent{}
+	l := newPipeListener()
+	srv := New(Config{Difficulty: 1}, agent, nil)
+	srv.Serve(l)
+	defer srv.Stop()
+
+	slow, fast := l.dial(t), l.dial(t)
+	for _, miner := range []*testMiner{slow, fast} {
+		if resp := miner.call("eth_submitLogin", "0x3317e8405e75551ec7eeeb3508650e7b349665ff"); resp.Result != true {
+			t.Fatalf("login failed: %+v", resp)
+		}
+	}
+	// the fast miner keeps reading, the slow one never does
+	notified := make(chan string, 64)
+	go func() {
+		for {
+			line, err := fast.r.ReadBytes('\n')
+			if err != nil {
+				return
+			}
+			var resp response
+			if json.Unmarshal(line, &resp) == nil {
+				if work, ok := resp.Result.([]interface{}); ok {
+					notified <- work[0].(string)
+				}
+			}
+		}
+	}()
+	// new work must not block on the slow miner
+	timeout := time.After(5 * time.Second)
+	for i := 0; i < sendQueueSize+4; i++ {
+		block := testBlock(int64(i+1), big.NewInt(1))
+		agent.feed.Send(block)
+		select {
+		case hash := <-notified:
+			if hash != block.HashNoNonce().Hex() {
+				t.Fatalf("job %d: wrong pow hash %s", i, hash)
+			}
+		case <-timeout:
+			t.Fatalf("job %d not received, blocked on a slow miner", i)
+		}
+	}
+	for len(srv.Workers()) != 1 {
+		select {
+		case <-timeout:
+			t.Fatalf("slow miner not disconnected, have %d workers", len(srv.Workers()))
+		case <-time.After(10 * time.Millisecond):
+		}
+	}
+}
+
+func TestTarget(t *testing.T) {
+	if have := target(big.NewInt(1)); have != common.HexToHash("0xffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffff") {
+		t.Errorf("wrong target for difficulty 1: %x", have)
+	}
+	if have := target(big.NewInt(2)); have != common.HexToHash("0x8000000000000000000000000000000000000000000000000000000000000000") {
+		t.Errorf("wrong target for difficulty 2: %x", have)
+	}
+}
+
+// Tests that the oldest jobs are dropped first when more than maxJobs jobs
+// exist at the same height, and all of them on a new height.
+func TestJobEviction(t *testing.T) {
+	srv := New(DefaultConfig, &testAgent{}, nil)
+	var hashes []common.Hash
+	for i := 0; i < maxJobs+4; i++ {
+		block := types.NewBlockWithHeader(&types.Header{
+			Number:     big.NewInt(10),
+			Difficulty: big.NewInt(1000),
+			Time:       big.NewInt(1536181711 + int64(i)),
+			Version:    2,
+		})
+		if srv.setJob(block) == nil {
+			t.Fatalf("job %d: not new", i)
+		}
+		hashes = append(hashes, block.HashNoNonce())
+		// the first job becoming current again is kept over the later ones
+		if i == maxJobs-1 {
+			srv.setJob(srv.jobs[hashes[0]].block)
+		}
+	}
+	for i, hash := range hashes {
+		_, ok := srv.jobs[hash]
+		if want := i == 0 || i >= 5; ok != want {
+			t.Errorf("job %d: kept %v, want %v", i, ok, want)
+		}
+	}
+	if len(srv.jobs) != maxJobs || len(srv.order) != maxJobs {
+		t.Errorf("job count mismatch: have %d/%d, want %d", len(srv.jobs), len(srv.order), maxJobs)
+	}
+	srv.setJob(testBlock(11, big.NewInt(1000)))
+	if len(srv.jobs) != 1 || len(srv.order) != 1 {
+		t.Errorf("jobs of the previous height kept: %d/%d", len(srv.jobs), len(srv.order))
+	}
+}
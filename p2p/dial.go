@@ -26,6 +26,7 @@ import (
 
 	"gitlab.com/aquachain/aquachain/common/log"
 	"gitlab.com/aquachain/aquachain/p2p/discover"
+	"gitlab.com/aquachain/aquachain/p2p/enr"
 	"gitlab.com/aquachain/aquachain/p2p/netutil"
 	"gitlab.com/aquachain/aquachain/subcommands/mainctxs"
 )
@@ -91,6 +92,7 @@ type discoverTable interface {
 	Resolve(target discover.NodeID) *discover.Node
 	Lookup(target discover.NodeID) []*discover.Node
 	ReadRandomNodes([]*discover.Node) int
+	RequestENR(*discover.Node) (*enr.Record, error)
 }
 
 // the dial history remembers recent dials.
@@ -295,6 +297,10 @@ func (t *dialTask) Do(srv *Server) {
 			return
 		}
 	}
+	if t.flags&dynDialedConn != 0 && !t.filter(srv) {
+		log.Trace("Skipping dial, node record rejected", "task", t)
+		return
+	}
 	err := t.dial(srv, t.dest)
 	if err != nil {
 		log.Trace("Dial error", "task", t, "err", err)
@@ -341,6 +347,35 @@ func (t *dialTask) resolve(srv *Server) bool {
 	return true
 }
 
+// filter requests the node record of a dynamically dialed node and checks it
+// with the dial filters of the protocols. Nodes which don't serve their record
+// pass, the older ones don't know about node records.
+func (t *dialTask) filter(srv *Server) bool {
+	if srv.ntab == nil {
+		return true
+	}
+	var filters []func(*enr.Record) bool
+	for _, proto := range srv.Protocols {
+		if proto.DialFilter != nil {
+			filters = append(filters, proto.DialFilter)
+		}
+	}
+	if len(filters) == 0 {
+		return true
+	}
+	record, err := srv.ntab.RequestENR(t.dest)
+	if err != nil {
+		log.Trace("Node record request failed", "id", t.dest.ID, "err", err)
+		return true
+	}
+	for _, filter := range filters {
+		if !filter(record) {
+			return false
+		}
+	}
+	return true
+}
+
 type dialError struct {
 	error
 }
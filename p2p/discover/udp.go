@@ -29,6 +29,7 @@ import (
 	"gitlab.com/aquachain/aquachain/common/sense"
 	"gitlab.com/aquachain/aquachain/crypto"
 	"gitlab.com/aquachain/aquachain/internal/debug"
+	"gitlab.com/aquachain/aquachain/p2p/enr"
 	"gitlab.com/aquachain/aquachain/p2p/netutil"
 	"gitlab.com/aquachain/aquachain/rlp"
 )
@@ -45,6 +46,8 @@ var (
 	errTimeout          = errors.New("RPC timeout")
 	errClockWarp        = errors.New("reply deadline too far in the future")
 	errClosed           = errors.New("socket closed")
+	errNoRecord         = errors.New("no local node record")
+	errWrongRecord      = errors.New("node record of another node")
 )
 
 func newExpiredErr(ago time.Duration) error {
@@ -68,12 +71,16 @@ const (
 	ethpongPacket
 	ethfindnodePacket
 	ethneighborsPacket
+	ethENRRequestPacket
+	ethENRResponsePacket
 )
 const (
 	aquapingPacket byte = iota + 134 // zero is 'reserved'
 	aquapongPacket
 	aquafindnodePacket
 	aquaneighborsPacket
+	aquaENRRequestPacket
+	aquaENRResponsePacket
 )
 
 // RPC request structures
@@ -115,6 +122,21 @@ type (
 		Rest []rlp.RawValue `rlp:"tail"`
 	}
 
+	// enrRequest queries for the remote node's record (EIP-868).
+	enrRequest struct {
+		Expiration uint64
+		// Ignore additional fields (for forward compatibility).
+		Rest []rlp.RawValue `rlp:"tail"`
+	}
+
+	// enrResponse is the reply to enrRequest.
+	enrResponse struct {
+		ReplyTok []byte // Hash of the enrRequest packet.
+		Record   enr.Record
+		// Ignore additional fields (for forward compatibility).
+		Rest []rlp.RawValue `rlp:"tail"`
+	}
+
 	rpcNode struct {
 		IP  net.IP // len 4 for IPv4 or 16 for IPv6
 		UDP uint16 // for discovery protocol
@@ -194,6 +216,7 @@ type udp struct {
 
 	closing chan struct{}
 	chainid uint64
+	record  func() *enr.Record // local node record, may be nil
 
 	*Table
 }
@@ -253,6 +276,7 @@ type Config struct {
 	Bootnodes    []*Node           // list of bootstrap nodes
 	Unhandled    chan<- ReadPacket // unhandled packets are sent on this channel
 	ChainId      uint64
+	Record       func() *enr.Record // local node record served to ENR requests
 }
 
 // ListenUDP returns a new table that listens for UDP packets on laddr.
@@ -277,6 +301,7 @@ func newUDP(c conn, cfg Config) (*Table, *udp, error) {
 		gotreply:    make(chan reply),
 		addpending:  make(chan *pending),
 		chainid:     cfg.ChainId,
+		record:      cfg.Record,
 	}
 	if cfg.ChainId == 0 {
 		panic("no chain id set, no udp protocol version")
@@ -378,6 +403,44 @@ func (t *udp) findnode(toid NodeID, toaddr *net.UDPAddr, target NodeID) ([]*Node
 	return nodes, err
 }
 
+// requestENR sends an ENR request to the given node and waits for the node
+// record in reply.
+func (t *udp) requestENR(toid NodeID, toaddr *net.UDPAddr) (*enr.Record, error) {
+	requestPacket, responsePacket := aquaENRRequestPacket, aquaENRResponsePacket
+	if t.netcompat() {
+		requestPacket, responsePacket = ethENRRequestPacket, ethENRResponsePacket
+	}
+	req := &enrRequest{
+		Expiration: uint64(time.Now().Add(sendTimeout).Unix()),
+	}
+	packet, hash, err := encodePacket(t.netcompat(), t.priv, requestPacket, req)
+	if err != nil {
+		return nil, err
+	}
+	var record *enr.Record
+	errc := t.pending(toid, responsePacket, func(r interface{}) bool {
+		reply := r.(*enrResponse)
+		if !bytes.Equal(reply.ReplyTok, hash) {
+			return false
+		}
+		record = &reply.Record
+		return true
+	})
+	t.write(toaddr, req.name(), packet)
+	if err := <-errc; err != nil {
+		return nil, err
+	}
+	// The signature was checked while decoding, make sure it's the node's one
+	var pubkey enr.Secp256k1
+	if err := record.Load(&pubkey); err != nil {
+		return nil, err
+	}
+	if PubkeyID((*btcec.PublicKey)(&pubkey).ToECDSA()) != toid {
+		return nil, errWrongRecord
+	}
+	return record, nil
+}
+
 // pending adds a reply callback to the pending reply queue.
 // see the documentation of type pending for a detailed explanation.
 func (t *udp) pending(id NodeID, ptype byte, callback func(interface{}) bool) <-chan error {
@@ -628,6 +691,8 @@ func (t *udp) handlePacket(from *net.UDPAddr, buf []byte) error {
 			log.Trace("<< "+name, "addr", from, "target", x.Target)
 		case *ping:
 			log.Trace("<< "+name, "addr", from, "from", x.From.UDP, "to", x.To)
+		case *enrRequest, *enrResponse:
+			log.Trace("<< "+name, "addr", from)
 		case *pong:
 			d := time.Until(time.Unix(int64(x.Expiration), 0))
 			if d < time.Millisecond*20 {
@@ -677,6 +742,10 @@ func decodePacket(netcompat bool, buf []byte) (packet, NodeID, []byte, error) {
 		req = new(findnode)
 	case aquaneighborsPacket:
 		req = new(neighbors)
+	case aquaENRRequestPacket:
+		req = new(enrRequest)
+	case aquaENRResponsePacket:
+		req = new(enrResponse)
 	default:
 		return nil, fromID, hash, fmt.Errorf("unknown type: %d", ptype)
 	}
@@ -793,6 +862,48 @@ func (req *neighbors) handle(t *udp, from *net.UDPAddr, fromID NodeID, mac []byt
 
 func (req *neighbors) name() string { return "NEIGHBORS/v4" }
 
+func (req *enrRequest) handle(t *udp, from *net.UDPAddr, fromID NodeID, mac []byte) error {
+	if err := expired(req.Expiration); err != nil {
+		return err
+	}
+	if !t.db.hasBond(fromID) {
+		// No bond exists, we don't process the packet, for the same reason as
+		// findnode: the response is bigger than the request.
+		return errUnknownNode
+	}
+	var record *enr.Record
+	if t.record != nil {
+		record = t.record()
+	}
+	if record == nil {
+		return errNoRecord
+	}
+	responsePacket := aquaENRResponsePacket
+	if t.netcompat() {
+		responsePacket = ethENRResponsePacket
+	}
+	t.send(from, responsePacket, &enrResponse{
+		ReplyTok: mac,
+		Record:   *record,
+	})
+	return nil
+}
+
+func (req *enrRequest) name() string { return "ENRREQUEST/v4" }
+
+func (req *enrResponse) handle(t *udp, from *net.UDPAddr, fromID NodeID, mac []byte) error {
+	responsePacket := aquaENRResponsePacket
+	if t.netcompat() {
+		responsePacket = ethENRResponsePacket
+	}
+	if !t.handleReply(fromID, responsePacket, req) {
+		return errUnsolicitedReply
+	}
+	return nil
+}
+
+func (req *enrResponse) name() string { return "ENRRESPONSE/v4" }
+
 func expired(ts uint64) error {
 	x := time.Since(time.Unix(int64(ts), 0))
 	if x < 0 {
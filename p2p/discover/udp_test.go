@@ -36,6 +36,7 @@ import (
 	"github.com/davecgh/go-spew/spew"
 	"gitlab.com/aquachain/aquachain/common"
 	"gitlab.com/aquachain/aquachain/crypto"
+	"gitlab.com/aquachain/aquachain/p2p/enr"
 	"gitlab.com/aquachain/aquachain/rlp"
 )
 
@@ -51,11 +52,13 @@ var (
 	testLocalAnnounced = rpcEndpoint{IP: net.ParseIP("2.2.2.2").To4(), UDP: 3, TCP: 4}
 	testLocal          = rpcEndpoint{IP: net.ParseIP("3.3.3.3").To4(), UDP: 5, TCP: 6}
 
-	netcompat       = false
-	pingPacket      = aquapingPacket
-	pongPacket      = aquapongPacket
-	findnodePacket  = aquafindnodePacket
-	neighborsPacket = aquaneighborsPacket
+	netcompat         = false
+	pingPacket        = aquapingPacket
+	pongPacket        = aquapongPacket
+	findnodePacket    = aquafindnodePacket
+	neighborsPacket   = aquaneighborsPacket
+	enrRequestPacket  = aquaENRRequestPacket
+	enrResponsePacket = aquaENRResponsePacket
 )
 
 type udpTest struct {
@@ -327,6 +330,66 @@ func TestUDP_findnodeMultiReply(t *testing.T) {
 	}
 }
 
+func signedTestRecord(t *testing.T, key *btcec.PrivateKey) *enr.Record {
+	var r enr.Record
+	r.Set(enr.WithEntry("aqua", "test"))
+	if err := r.Sign(key); err != nil {
+		t.Fatal(err)
+	}
+	return &r
+}
+
+func TestUDP_ENRRequest(t *testing.T) {
+	test := newUDPTest(t)
+	defer test.table.Close()
+
+	// Without a bond, the request isn't answered
+	test.packetIn(errUnknownNode, enrRequestPacket, &enrRequest{Expiration: futureExp})
+
+	local := signedTestRecord(t, test.localkey)
+	test.udp.record = func() *enr.Record { return local }
+	test.table.db.updateBondTime(PubkeyID(test.remotekey.PubKey().ToECDSA()), time.Now())
+	test.packetIn(nil, enrRequestPacket, &enrRequest{Expiration: futureExp})
+	test.waitPacketOut(func(p *enrResponse) {
+		if p.Record.Seq() != local.Seq() || !bytes.Equal(p.Record.NodeAddr(), local.NodeAddr()) {
+			t.Errorf("wrong record in response: seq %d, node %x", p.Record.Seq(), p.Record.NodeAddr())
+		}
+		var value string
+		if err := p.Record.Load(enr.WithEntry("aqua", &value)); err != nil || value != "test" {
+			t.Errorf("wrong entry in response record: %q, %v", value, err)
+		}
+	})
+}
+
+func TestUDP_requestENR(t *testing.T) {
+	test := newUDPTest(t)
+	defer test.table.Close()
+
+	for _, tt := range []struct {
+		key     *btcec.PrivateKey
+		wantErr error
+	}{
+		{test.remotekey, nil},
+		{newkey(), errWrongRecord}, // record signed by another node
+	} {
+		errc := make(chan error, 1)
+		go func() {
+			_, err := test.udp.requestENR(PubkeyID(test.remotekey.PubKey().ToECDSA()), test.remoteaddr)
+			errc <- err
+		}()
+		hash, _ := test.waitPacketOut(func(p *enrRequest) {})
+		test.packetIn(nil, enrResponsePacket, &enrResponse{ReplyTok: hash, Record: *signedTestRecord(t, tt.key)})
+		select {
+		case err := <-errc:
+			if err != tt.wantErr {
+				t.Errorf("requestENR error mismatch: got %v, want %v", err, tt.wantErr)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatal("requestENR did not return within 5 seconds")
+		}
+	}
+}
+
 func TestUDP_successfulPing(t *testing.T) {
 	t.Skip()
 	test := newUDPTest(t)
@@ -61,6 +61,16 @@ type DiscPort uint16
 
 func (v DiscPort) ENRKey() string { return "discv5" }
 
+// TCP is the "tcp" key, which holds the TCP port of the node.
+type TCP uint16
+
+func (v TCP) ENRKey() string { return "tcp" }
+
+// UDP is the "udp" key, which holds the UDP port of the node.
+type UDP uint16
+
+func (v UDP) ENRKey() string { return "udp" }
+
 // ID is the "id" key, which holds the name of the identity scheme.
 type ID string
 
@@ -20,6 +20,7 @@ import (
 	"fmt"
 
 	"gitlab.com/aquachain/aquachain/p2p/discover"
+	"gitlab.com/aquachain/aquachain/p2p/enr"
 )
 
 // Protocol represents a P2P subprotocol implementation.
@@ -52,6 +53,16 @@ type Protocol struct {
 	// about a certain peer in the network. If an info retrieval function is set,
 	// but returns nil, it is assumed that the protocol handshake is still running.
 	PeerInfo func(id discover.NodeID) interface{}
+
+	// Attributes contains protocol specific information for the node record.
+	// The entries are encoded whenever the record is built, so they may change
+	// over time.
+	Attributes []enr.Entry
+
+	// DialFilter is an optional filter of the dynamically dialed nodes, by the
+	// node record they advertise on the discovery network. Nodes whose record
+	// it rejects are not dialed, nodes without a record are.
+	DialFilter func(*enr.Record) bool
 }
 
 func (p Protocol) cap() Cap {
@@ -20,6 +20,7 @@ package p2p
 import (
 	"context"
 	"crypto/ecdsa"
+	"encoding/base64"
 	"errors"
 	"fmt"
 	"math/big"
@@ -35,9 +36,11 @@ import (
 	"gitlab.com/aquachain/aquachain/common/mclock"
 	"gitlab.com/aquachain/aquachain/common/sense"
 	"gitlab.com/aquachain/aquachain/p2p/discover"
+	"gitlab.com/aquachain/aquachain/p2p/enr"
 	"gitlab.com/aquachain/aquachain/p2p/nat"
 	"gitlab.com/aquachain/aquachain/p2p/netutil"
 	"gitlab.com/aquachain/aquachain/params"
+	"gitlab.com/aquachain/aquachain/rlp"
 	"gitlab.com/aquachain/aquachain/subcommands/mainctxs"
 )
 
@@ -167,6 +170,10 @@ type Server struct {
 	ourHandshake *protoHandshake
 	lastLookup   time.Time
 
+	recordLock sync.Mutex
+	record     *enr.Record // signed local node record, see LocalRecord
+	recordKey  string      // encoded entries of record
+
 	// These are for Peers, PeerCount (and nothing else).
 	peerOp     chan peerOpFunc
 	peerOpDone chan struct{}
@@ -375,6 +382,68 @@ func (srv *Server) makeSelf(listener net.Listener, ntab discoverTable) *discover
 	return ntab.Self()
 }
 
+// LocalRecord returns the signed node record of the local node, holding its
+// endpoint and the attributes of the protocols. The record is signed again,
+// with the next sequence number, whenever any of them changed. It returns nil
+// if the server isn't running.
+func (srv *Server) LocalRecord() *enr.Record {
+	srv.lock.Lock()
+	running := srv.running
+	srv.lock.Unlock()
+	if !running {
+		return nil
+	}
+	self := srv.Self()
+	var entries []enr.Entry
+	if ip := self.IP.To4(); ip != nil && !ip.IsUnspecified() {
+		entries = append(entries, enr.IP4(ip))
+	} else if ip := self.IP.To16(); ip != nil && !ip.IsUnspecified() {
+		entries = append(entries, enr.IP6(ip))
+	}
+	if self.TCP != 0 {
+		entries = append(entries, enr.TCP(self.TCP))
+	}
+	if self.UDP != 0 {
+		entries = append(entries, enr.UDP(self.UDP))
+	}
+	for _, proto := range srv.Protocols {
+		entries = append(entries, proto.Attributes...)
+	}
+
+	var (
+		record enr.Record
+		key    []byte
+	)
+	for _, entry := range entries {
+		value, err := rlp.EncodeToBytes(entry)
+		if err != nil {
+			srv.log.Error("Can't encode node record entry", "key", entry.ENRKey(), "err", err)
+			continue
+		}
+		key = append(append(key, entry.ENRKey()...), value...)
+		record.Set(entry)
+	}
+
+	srv.recordLock.Lock()
+	defer srv.recordLock.Unlock()
+	if srv.record != nil && srv.recordKey == string(key) {
+		return srv.record
+	}
+	// The record isn't persisted, start the sequence numbers from the clock
+	// so they keep increasing across restarts.
+	seq := uint64(time.Now().Unix())
+	if srv.record != nil && srv.record.Seq() >= seq {
+		seq = srv.record.Seq()
+	}
+	record.SetSeq(seq)
+	if err := record.Sign(srv.PrivateKey); err != nil {
+		srv.log.Error("Can't sign node record", "err", err)
+		return srv.record
+	}
+	srv.record, srv.recordKey = &record, string(key)
+	return srv.record
+}
+
 // Stop terminates the server and all active peer connections.
 // It blocks until all active connections have been closed.
 func (srv *Server) Stop() {
@@ -549,6 +618,7 @@ func (srv *Server) startDiscovery() error {
 		Bootnodes:    srv.BootstrapNodes,
 		Unhandled:    unhandled,
 		ChainId:      srv.ChainId,
+		Record:       srv.LocalRecord,
 	}
 	ntab, err := discover.ListenUDP(conn, cfg)
 	if err != nil {
@@ -982,6 +1052,7 @@ type NodeInfo struct {
 		Discovery int `json:"discovery,omitempty"` // UDP listening port for discovery protocol
 		Listener  int `json:"listener,omitempty"`  // TCP listening port for RLPx
 	} `json:"ports"`
+	ENR        string                 `json:"enr,omitempty"` // Node record of the node, as advertised on the discovery network
 	ListenAddr string                 `json:"listenAddr"`
 	Protocols  map[string]interface{} `json:"protocols,omitempty"`
 }
@@ -1001,6 +1072,11 @@ func (srv *Server) NodeInfo() *NodeInfo {
 	}
 	info.Ports.Discovery = int(node.UDP)
 	info.Ports.Listener = int(node.TCP)
+	if record := srv.LocalRecord(); record != nil {
+		if enc, err := rlp.EncodeToBytes(record); err == nil {
+			info.ENR = "enr:" + base64.RawURLEncoding.EncodeToString(enc)
+		}
+	}
 
 	// Gather all the running protocol infos (only once per protocol type)
 	for _, proto := range srv.Protocols {
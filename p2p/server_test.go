@@ -20,9 +20,11 @@ import (
 	"context"
 	"crypto/ecdsa"
 	"errors"
+	"io"
 	"math/rand"
 	"net"
 	"reflect"
+	"strings"
 	"testing"
 	"time"
 
@@ -31,6 +33,8 @@ import (
 	"gitlab.com/aquachain/aquachain/crypto"
 	"gitlab.com/aquachain/aquachain/crypto/sha3"
 	"gitlab.com/aquachain/aquachain/p2p/discover"
+	"gitlab.com/aquachain/aquachain/p2p/enr"
+	"gitlab.com/aquachain/aquachain/rlp"
 )
 
 func init() {
@@ -126,6 +130,59 @@ func TestServerListen(t *testing.T) {
 	}
 }
 
+// testAttribute is a node record entry whose value can change.
+type testAttribute struct{ value *uint }
+
+func (a testAttribute) ENRKey() string { return "test" }
+
+func (a testAttribute) EncodeRLP(w io.Writer) error { return rlp.Encode(w, *a.value) }
+
+func TestServerLocalRecord(t *testing.T) {
+	value := uint(1)
+	srv := &Server{Config: &Config{
+		Name:        "test",
+		MaxPeers:    10,
+		ListenAddr:  "127.0.0.1:0",
+		PrivateKey:  newkey(),
+		ChainId:     222,
+		NoDiscovery: true,
+		Protocols:   []Protocol{{Name: "test", Attributes: []enr.Entry{testAttribute{&value}}}},
+	}}
+	if srv.LocalRecord() != nil {
+		t.Fatal("record of a stopped server")
+	}
+	if err := srv.Start(context.Background()); err != nil {
+		t.Fatalf("could not start server: %v", err)
+	}
+	defer srv.Stop()
+
+	record := srv.LocalRecord()
+	var (
+		tcp  enr.TCP
+		attr uint
+	)
+	if err := record.Load(&tcp); err != nil || uint16(tcp) != srv.Self().TCP {
+		t.Errorf("wrong tcp port in record: %d (%v), want %d", tcp, err, srv.Self().TCP)
+	}
+	if err := record.Load(enr.WithEntry("test", &attr)); err != nil || attr != 1 {
+		t.Errorf("wrong protocol attribute in record: %d (%v)", attr, err)
+	}
+	if srv.LocalRecord() != record {
+		t.Error("record signed again without changes")
+	}
+	value = 2
+	updated := srv.LocalRecord()
+	if updated.Seq() <= record.Seq() {
+		t.Errorf("sequence number not increased: %d, was %d", updated.Seq(), record.Seq())
+	}
+	if err := updated.Load(enr.WithEntry("test", &attr)); err != nil || attr != 2 {
+		t.Errorf("protocol attribute not updated in record: %d (%v)", attr, err)
+	}
+	if info := srv.NodeInfo(); !strings.HasPrefix(info.ENR, "enr:") {
+		t.Errorf("node info lacks the record: %q", info.ENR)
+	}
+}
+
 func TestServerDial(t *testing.T) {
 	// run a one-shot TCP server to handle the connection.
 	listener, err := net.Listen("tcp4", "127.0.0.1:0")
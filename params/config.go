@@ -37,7 +37,7 @@ var (
 )
 
 // KnownHF is the highest hard fork that is known by this version of Aquachain.
-const KnownHF = 9
+const KnownHF = 11
 
 var (
 	// AquachainHF is the map of hard forks (mainnet)
@@ -465,6 +465,12 @@ func (c *ChainConfig) IsConstantinople(num *big.Int) bool {
 	return isForked(c.ConstantinopleBlock, num)
 }
 
+// IsEIP2718 returns whether typed transactions (EIP-2718) with access lists
+// (EIP-2930) are accepted at num, which is the case from HF11 on.
+func (c *ChainConfig) IsEIP2718(num *big.Int) bool {
+	return c.IsHF(11, num)
+}
+
 // GasTable returns the gas table corresponding to the current phase.
 //
 // The returned GasTable's fields shouldn't, under any circumstances, be changed.
@@ -499,7 +505,7 @@ func (c *ChainConfig) CheckCompatible(newcfg *ChainConfig, height uint64) *Confi
 }
 
 func (c *ChainConfig) checkCompatible(newcfg *ChainConfig, head *big.Int) *ConfigCompatError {
-	for i := 1; i < KnownHF; i++ {
+	for i := 1; i <= KnownHF; i++ {
 		if c.HF[i] == nil && newcfg.HF[i] == nil {
 			continue
 		}
@@ -17,6 +17,7 @@
 package params
 
 import (
+	"fmt"
 	"math/big"
 	"reflect"
 	"testing"
@@ -71,6 +72,17 @@ func TestCheckCompatible(t *testing.T) {
 				RewindTo:     0,
 			},
 		},
+		{
+			stored: &ChainConfig{HF: ForkMap{KnownHF: big.NewInt(10)}},
+			new:    &ChainConfig{HF: ForkMap{KnownHF: big.NewInt(20)}},
+			head:   11,
+			wantErr: &ConfigCompatError{
+				What:         fmt.Sprintf("Aquachain HF%d block", KnownHF),
+				StoredConfig: big.NewInt(10),
+				NewConfig:    big.NewInt(20),
+				RewindTo:     9,
+			},
+		},
 		{
 			stored: &ChainConfig{HomesteadBlock: big.NewInt(30), EIP150Block: big.NewInt(10)},
 			new:    &ChainConfig{HomesteadBlock: big.NewInt(25), EIP150Block: big.NewInt(20)},
@@ -129,3 +141,23 @@ func TestChainConfigs(t *testing.T) {
 		}
 	}
 }
+
+func TestUseHFKnown(t *testing.T) {
+	cfg := &ChainConfig{HF: ForkMap{7: big.NewInt(5), KnownHF: big.NewInt(10)}}
+	if hf := cfg.UseHF(big.NewInt(10)); hf != KnownHF {
+		t.Errorf("active hard fork mismatch: have %d, want %d", hf, KnownHF)
+	}
+}
+
+func TestEIP2718Fork(t *testing.T) {
+	// HF10 only changes the difficulty algorithm
+	cfg := &ChainConfig{HF: ForkMap{10: big.NewInt(5), 11: big.NewInt(10)}}
+	for _, tt := range []struct {
+		num  int64
+		want bool
+	}{{4, false}, {5, false}, {9, false}, {10, true}, {11, true}} {
+		if have := cfg.IsEIP2718(big.NewInt(tt.num)); have != tt.want {
+			t.Errorf("block %d: have %v, want %v", tt.num, have, tt.want)
+		}
+	}
+}
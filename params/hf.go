@@ -88,7 +88,7 @@ func (c *ChainConfig) IsHF(hf int, num *big.Int) bool {
 }
 
 func (f ForkMap) Sorted() (hfs []int) {
-	for i := 0; i < KnownHF; i++ {
+	for i := 0; i <= KnownHF; i++ {
 		if f[i] != nil {
 			hfs = append(hfs, i)
 		}
@@ -68,6 +68,9 @@ const (
 	MemoryGas        uint64 = 3     // Times the address of the (highest referenced byte in memory + 1). NOTE: referencing happens on read, write and in instructions such as RETURN and CALL.
 	TxDataNonZeroGas uint64 = 68    // Per byte of data attached to a transaction that is not equal to zero. NOTE: Not payable on data of calls between transactions.
 
+	TxAccessListAddressGas    uint64 = 2400 // Per address specified in an EIP 2930 access list
+	TxAccessListStorageKeyGas uint64 = 1900 // Per storage key specified in an EIP 2930 access list
+
 	MaxCodeSize = 24576 // Maximum bytecode to permit for a contract
 
 	// Precompiled contract gas prices
@@ -0,0 +1,124 @@
+// Copyright 2018 The aquachain Authors
+// This file is part of the aquachain library.
+//
+// The aquachain library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The aquachain library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the aquachain library. If not, see <http://www.gnu.org/licenses/>.
+
+package rpc
+
+import (
+	"crypto/subtle"
+	"io"
+	"net"
+	"net/http"
+	"time"
+
+	"gitlab.com/aquachain/aquachain/common/log"
+	"gitlab.com/aquachain/aquachain/p2p/netutil"
+)
+
+// BtcNamespace is the service namespace bitcoind-style method names (without
+// a namespace, such as "getblockcount") are looked up in.
+const BtcNamespace = "btc"
+
+// btcResponse is a response the way bitcoind writes them: JSON-RPC 1.0, with
+// both the result and the error member present, one of them null.
+type btcResponse struct {
+	Result interface{} `json:"result"`
+	Error  *jsonError  `json:"error"`
+	Id     interface{} `json:"id"`
+}
+
+// btcCodec reads requests like the JSON codec, and writes bitcoind-shaped
+// responses.
+type btcCodec struct {
+	*jsonCodec
+}
+
+// CreateResponse will create a bitcoind-style success response.
+func (c *btcCodec) CreateResponse(id interface{}, reply interface{}) interface{} {
+	return &btcResponse{Id: id, Result: reply}
+}
+
+// CreateErrorResponse will create a bitcoind-style error response.
+func (c *btcCodec) CreateErrorResponse(id interface{}, err Error) interface{} {
+	return &btcResponse{Id: id, Error: &jsonError{Code: err.ErrorCode(), Message: err.Error()}}
+}
+
+// CreateErrorResponseWithInfo will create a bitcoind-style error response with
+// additional information about the error.
+func (c *btcCodec) CreateErrorResponseWithInfo(id interface{}, err Error, info interface{}) interface{} {
+	return &btcResponse{Id: id, Error: &jsonError{Code: err.ErrorCode(), Message: err.Error(), Data: info}}
+}
+
+// NewBitcoinHTTPServer creates an HTTP server speaking the bitcoind JSON-RPC
+// dialect around an API provider, for clients built against bitcoind. Requests
+// have to come from an allowed IP and carry the given credentials with HTTP
+// basic authentication.
+func NewBitcoinHTTPServer(user, password string, allowIP netutil.Netlist, behindreverseproxy bool, srv *Server) *http.Server {
+	handler := newAllowIPHandler(allowIP, behindreverseproxy, newBasicAuthHandler(user, password, behindreverseproxy, btcHandler{srv}))
+	return &http.Server{Handler: handler, ReadTimeout: 5 * time.Second, WriteTimeout: 30 * time.Second, IdleTimeout: 30 * time.Second}
+}
+
+// btcHandler serves bitcoind-style JSON-RPC requests over HTTP.
+type btcHandler struct {
+	srv *Server
+}
+
+func (h btcHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	// bitcoind doesn't look at the content type, neither do its clients
+	if r.Method != http.MethodPost {
+		http.Error(w, "JSONRPC server handles only POST requests", http.StatusMethodNotAllowed)
+		return
+	}
+	if r.ContentLength > maxHTTPRequestContentLength {
+		http.Error(w, http.StatusText(http.StatusRequestEntityTooLarge), http.StatusRequestEntityTooLarge)
+		return
+	}
+	body := io.LimitReader(r.Body, maxHTTPRequestContentLength)
+	uip := getIP(r, h.srv.reverseproxy)
+	codec := &btcCodec{NewJSONCodec(&httpReadWriteNopCloser{Reader: body, Writer: w, remoteAddr: &net.TCPAddr{IP: uip, Port: 0}})}
+	defer codec.Close()
+
+	w.Header().Set("content-type", contentType)
+	h.srv.ServeSingleRequest(codec, OptionMethodInvocation)
+}
+
+// basicAuthHandler lets through requests carrying the right credentials.
+type basicAuthHandler struct {
+	user, password []byte
+	reverseproxy   bool
+	next           http.Handler
+}
+
+func newBasicAuthHandler(user, password string, behindreverseproxy bool, next http.Handler) http.Handler {
+	return &basicAuthHandler{[]byte(user), []byte(password), behindreverseproxy, next}
+}
+
+func (h *basicAuthHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	user, password, ok := r.BasicAuth()
+	if ok {
+		userOk := subtle.ConstantTimeCompare([]byte(user), h.user)
+		passwordOk := subtle.ConstantTimeCompare([]byte(password), h.password)
+		ok = userOk&passwordOk == 1
+	}
+	if !ok {
+		log.Warn("btcrpc: incorrect password attempt", "from", getIP(r, h.reverseproxy))
+		// Slow down brute forcing, like bitcoind does
+		time.Sleep(250 * time.Millisecond)
+		w.Header().Set("WWW-Authenticate", `Basic realm="jsonrpc"`)
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+	h.next.ServeHTTP(w, r)
+}
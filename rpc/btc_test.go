@@ -0,0 +1,122 @@
+// Copyright 2018 The aquachain Authors
+// This file is part of the aquachain library.
+//
+// The aquachain library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The aquachain library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the aquachain library. If not, see <http://www.gnu.org/licenses/>.
+
+package rpc
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+type BtcTestService struct{}
+
+type btcTestError struct{}
+
+func (btcTestError) Error() string  { return "Block height out of range" }
+func (btcTestError) ErrorCode() int { return -8 }
+
+func (s *BtcTestService) Getblockcount() int { return 42 }
+
+func (s *BtcTestService) Getblockhash(height int) (string, error) {
+	return "", btcTestError{}
+}
+
+func testBtcRequest(t *testing.T, user, password, body string) (int, map[string]json.RawMessage) {
+	server := NewServer()
+	if _, err := server.RegisterName(BtcNamespace, new(BtcTestService)); err != nil {
+		t.Fatal(err)
+	}
+	defer server.Stop()
+	handler := newBasicAuthHandler("user", "secret", false, btcHandler{server})
+
+	// bitcoind clients don't necessarily set a content type
+	request := httptest.NewRequest(http.MethodPost, "http://localhost", strings.NewReader(body))
+	request.SetBasicAuth(user, password)
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, request)
+
+	var response map[string]json.RawMessage
+	if recorder.Code == http.StatusOK {
+		if err := json.Unmarshal(recorder.Body.Bytes(), &response); err != nil {
+			t.Fatalf("invalid response %q: %v", recorder.Body.String(), err)
+		}
+	}
+	return recorder.Code, response
+}
+
+func TestBtcHTTPResponse(t *testing.T) {
+	code, response := testBtcRequest(t, "user", "secret", `{"jsonrpc":"1.0","id":"curltest","method":"getblockcount","params":[]}`)
+	if code != http.StatusOK {
+		t.Fatalf("unexpected status %d", code)
+	}
+	if string(response["result"]) != "42" || string(response["error"]) != "null" || string(response["id"]) != `"curltest"` {
+		t.Errorf("unexpected response %s", response)
+	}
+	if _, ok := response["jsonrpc"]; ok {
+		t.Errorf("bitcoind-style response carries a jsonrpc member")
+	}
+}
+
+func TestBtcHTTPErrorCode(t *testing.T) {
+	_, response := testBtcRequest(t, "user", "secret", `{"id":1,"method":"getblockhash","params":[100]}`)
+	if string(response["result"]) != "null" {
+		t.Errorf("unexpected result %s", response["result"])
+	}
+	var rpcErr jsonError
+	if err := json.Unmarshal(response["error"], &rpcErr); err != nil {
+		t.Fatalf("invalid error %s: %v", response["error"], err)
+	}
+	if rpcErr.Code != -8 || rpcErr.Message != "Block height out of range" {
+		t.Errorf("unexpected error %+v", rpcErr)
+	}
+}
+
+func TestBtcHTTPBasicAuth(t *testing.T) {
+	for _, creds := range [][2]string{{"user", "wrong"}, {"other", "secret"}, {"", ""}} {
+		if code, _ := testBtcRequest(t, creds[0], creds[1], `{"id":1,"method":"getblockcount"}`); code != http.StatusUnauthorized {
+			t.Errorf("credentials %q: expected status %d, got %d", creds, http.StatusUnauthorized, code)
+		}
+	}
+}
+
+type BtcSendService struct{}
+
+func (s *BtcSendService) Sendtoaddress(address string, amount float64) string { return "txid" }
+
+func TestRegisterAuthenticatedName(t *testing.T) {
+	// protected methods are dropped unless the caller is known to be allowed
+	server := NewServer()
+	defer server.Stop()
+	if _, err := server.RegisterName(BtcNamespace, new(BtcSendService)); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := server.services[BtcNamespace].callbacks["sendtoaddress"]; ok {
+		t.Errorf("protected method registered by RegisterName")
+	}
+	// but kept for servers that authenticate their clients
+	server = NewServer()
+	defer server.Stop()
+	methods, err := server.RegisterAuthenticatedName(BtcNamespace, new(BtcSendService))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := server.services[BtcNamespace].callbacks["sendtoaddress"]; !ok {
+		t.Errorf("protected method not registered, have %v", methods)
+	}
+}
@@ -239,15 +239,7 @@ func parseRequest(incomingMsg json.RawMessage) ([]rpcRequest, bool, Error) {
 	if _DEBUG_RPC {
 		log.Info("incoming request", "method", in.Method, "payload", in.Payload)
 	}
-	// try keeping eth compatibility
-	if strings.HasPrefix(in.Method, "eth_") {
-		in.Method = "aqua_" + in.Method[4:]
-	}
-
-	// eg. getblockcount -> btc_getblockcount
-	if !strings.Contains(in.Method, "_") {
-		in.Method = "btc_" + in.Method
-	}
+	in.Method = canonicalMethod(in.Method)
 
 	// subscribe are special, they will always use `subscribeMethod` as first param in the payload
 	if strings.HasSuffix(in.Method, SubscribeMethodSuffix) {
@@ -285,6 +277,19 @@ func parseRequest(incomingMsg json.RawMessage) ([]rpcRequest, bool, Error) {
 	return []rpcRequest{{service: elems[0], method: elems[1], id: &in.Id, params: in.Payload}}, false, nil
 }
 
+// canonicalMethod returns the method name to look up for the requested one.
+func canonicalMethod(method string) string {
+	// try keeping eth compatibility
+	if strings.HasPrefix(method, "eth_") {
+		return "aqua_" + method[4:]
+	}
+	// eg. getblockcount -> btc_getblockcount
+	if !strings.Contains(method, ServiceMethodSeparator) {
+		return BtcNamespace + ServiceMethodSeparator + method
+	}
+	return method
+}
+
 // parseBatchRequest will parse a batch request into a collection of requests from the given RawMessage, an indication
 // if the request was a batch or an error when the request could not be read.
 func parseBatchRequest(incomingMsg json.RawMessage) ([]rpcRequest, bool, Error) {
@@ -330,7 +335,7 @@ func parseBatchRequest(incomingMsg json.RawMessage) ([]rpcRequest, bool, Error)
 		} else {
 			requests[i] = rpcRequest{id: id, params: r.Payload}
 		}
-		if elem := strings.Split(r.Method, ServiceMethodSeparator); len(elem) == 2 {
+		if elem := strings.Split(canonicalMethod(r.Method), ServiceMethodSeparator); len(elem) == 2 {
 			requests[i].service, requests[i].method = elem[0], elem[1]
 		} else {
 			requests[i].err = &methodNotFoundError{r.Method, ""}
@@ -96,7 +96,52 @@ var allow_sign_inProc = sense.EnvBool("UNSAFE_ALLOW_SIGN_INPROC") // testing
 // - Sign
 // - SendTransaction
 func (s *Server) RegisterName(name string, rcvr interface{}) (methodNames []string, err error) {
+	st := stack.Caller(2)
+	st1 := stack.Caller(1)
+	funcname := filepath.Base(st1.Frame().Function)
+	access := signingAccess{callertype: "???", envname: "UNSAFE_RPC_SIGNING", caller: fmt.Sprintf("%v", st), callerf: funcname}
+	if strings.HasSuffix(funcname, ".startIPC") {
+		access.callertype = "IPC"
+		access.envname = "UNSAFE_ALLOW_SIGN_IPC"
+		access.allowed = allow_sign_ipc
+	}
+	if strings.HasSuffix(funcname, ".startInProc") {
+		access.callertype = "InProc"
+		access.envname = "UNSAFE_ALLOW_SIGN_INPROC"
+		access.allowed = allow_sign_inProc
+	}
+	if strings.HasSuffix(funcname, ".startHTTP") {
+		access.callertype = "HTTP"
+		access.envname = "UNSAFE_RPC_SIGNING_HTTP"
+		access.allowed = allow_sign_http
+	}
+	if strings.HasSuffix(funcname, ".startWS") {
+		access.callertype = "WS"
+		access.envname = "UNSAFE_RPC_SIGNING_WS"
+		access.allowed = allow_sign_ws
+	}
+	return s.register(name, rcvr, access)
+}
+
+// RegisterAuthenticatedName registers a service like RegisterName, for servers
+// whose clients must authenticate before any call, such as the bitcoind-style
+// endpoint. The protected methods are served regardless of the UNSAFE_*
+// environment variables.
+func (s *Server) RegisterAuthenticatedName(name string, rcvr interface{}) (methodNames []string, err error) {
+	return s.register(name, rcvr, signingAccess{callertype: "authenticated", allowed: true, caller: fmt.Sprintf("%v", stack.Caller(1))})
+}
+
+// signingAccess describes whether the protected methods of a service are
+// served, and why, for logging.
+type signingAccess struct {
+	callertype string
+	envname    string
+	allowed    bool
+	caller     string
+	callerf    string
+}
 
+func (s *Server) register(name string, rcvr interface{}, access signingAccess) (methodNames []string, err error) {
 	if s.services == nil {
 		s.services = make(serviceRegistry)
 	}
@@ -119,42 +164,19 @@ func (s *Server) RegisterName(name string, rcvr interface{}) (methodNames []stri
 	}
 
 	methodNames = make([]string, 0, len(methods))
-	st := stack.Caller(2)
-	st1 := stack.Caller(1)
-	funcname := filepath.Base(st1.Frame().Function)
-	callertype := "???"
-	envname := "UNSAFE_RPC_SIGNING"
-	is_allowed := false
-	if strings.HasSuffix(funcname, ".startIPC") {
-		callertype = "IPC"
-		envname = "UNSAFE_ALLOW_SIGN_IPC"
-		is_allowed = allow_sign_ipc
-	}
-	if strings.HasSuffix(funcname, ".startInProc") {
-		callertype = "InProc"
-		envname = "UNSAFE_ALLOW_SIGN_INPROC"
-		is_allowed = allow_sign_inProc
-	}
-	if strings.HasSuffix(funcname, ".startHTTP") {
-		callertype = "HTTP"
-		envname = "UNSAFE_RPC_SIGNING_HTTP"
-		is_allowed = allow_sign_http
-	}
-	if strings.HasSuffix(funcname, ".startWS") {
-		callertype = "WS"
-		envname = "UNSAFE_RPC_SIGNING_WS"
-		is_allowed = allow_sign_ws
-	}
-
 	for k, m := range methods { // methods is a map
 		coolname := fmt.Sprintf("%s_%s", name, strings.ToLower(m.method.Name[:1])+m.method.Name[1:])
 		if isProtectedMethodName(m.method.Name) {
-			if !is_allowed {
-				log.Warn(fmt.Sprintf("disabling %s method (%s=0 env)", callertype, envname), "service", name, "method", coolname, "caller", fmt.Sprintf("%v", st), "callerf", funcname)
+			if !access.allowed {
+				log.Warn(fmt.Sprintf("disabling %s method (%s=0 env)", access.callertype, access.envname), "service", name, "method", coolname, "caller", access.caller, "callerf", access.callerf)
 				delete(methods, k)
 				continue
 			}
-			log.Warn(fmt.Sprintf("allowing %s method (%s=1 env)", callertype, envname), "service", name, "method", coolname, "caller", fmt.Sprintf("%v", st), "callerf", funcname)
+			if access.envname == "" {
+				log.Info(fmt.Sprintf("allowing %s method", access.callertype), "service", name, "method", coolname, "caller", access.caller)
+			} else {
+				log.Warn(fmt.Sprintf("allowing %s method (%s=1 env)", access.callertype, access.envname), "service", name, "method", coolname, "caller", access.caller, "callerf", access.callerf)
+			}
 		}
 		methodNames = append(methodNames, coolname)
 	}
@@ -181,7 +203,7 @@ func (s *Server) RegisterName(name string, rcvr interface{}) (methodNames []stri
 }
 
 func isProtectedMethodName(name string) bool {
-	return name == "SignTransaction" || name == "Sign" || name == "SendTransaction"
+	return name == "SignTransaction" || name == "Sign" || name == "SignTypedData" || name == "SendTransaction" || name == "Sendtoaddress"
 }
 
 var debugrpc = sense.EnvBool("DEBUG_RPC")
@@ -404,8 +426,12 @@ func (s *Server) handle(ctx context.Context, codec ServerCodec, req *serverReque
 	if req.callb.errPos >= 0 { // test if method returned an error
 		if !reply[req.callb.errPos].IsNil() {
 			e := reply[req.callb.errPos].Interface().(error)
-			res := codec.CreateErrorResponse(&req.id, &callbackError{e.Error()})
-			return res, nil
+			// errors carrying their own code keep it
+			rpcErr, ok := e.(Error)
+			if !ok {
+				rpcErr = &callbackError{e.Error()}
+			}
+			return codec.CreateErrorResponse(&req.id, rpcErr), nil
 		}
 	}
 	return codec.CreateResponse(req.id, reply[0].Interface()), nil
@@ -20,6 +20,7 @@ import (
 	"encoding/json"
 	"testing"
 
+	"gitlab.com/aquachain/aquachain/common"
 	"gitlab.com/aquachain/aquachain/common/math"
 )
 
@@ -64,3 +65,44 @@ func TestBlockNumberJSONUnmarshal(t *testing.T) {
 		}
 	}
 }
+
+func TestBlockNumberOrHashJSONUnmarshal(t *testing.T) {
+	hash := common.HexToHash("0x56e81f171bcc55a6ff8345e692c0f86e5b48e01b996cadc001622fb5e363b421")
+	tests := []struct {
+		input    string
+		mustFail bool
+		expected BlockNumberOrHash
+	}{
+		0: {`"0x1"`, false, BlockNumberOrHashWithNumber(1)},
+		1: {`"latest"`, false, BlockNumberOrHashWithNumber(LatestBlockNumber)},
+		2: {`"` + hash.Hex() + `"`, false, BlockNumberOrHashWithHash(hash)},
+		3: {`{"blockNumber":"0x12"}`, false, BlockNumberOrHashWithNumber(18)},
+		4: {`{"blockHash":"` + hash.Hex() + `"}`, false, BlockNumberOrHashWithHash(hash)},
+		5: {`{"blockNumber":"0x1","blockHash":"` + hash.Hex() + `"}`, true, BlockNumberOrHash{}},
+		6: {`{}`, true, BlockNumberOrHash{}},
+		7: {`"ff"`, true, BlockNumberOrHash{}},
+	}
+
+	for i, test := range tests {
+		var bnh BlockNumberOrHash
+		err := json.Unmarshal([]byte(test.input), &bnh)
+		if test.mustFail && err == nil {
+			t.Errorf("Test %d should fail", i)
+			continue
+		}
+		if !test.mustFail && err != nil {
+			t.Errorf("Test %d should pass but got err: %v", i, err)
+			continue
+		}
+		if test.mustFail {
+			continue
+		}
+		wantNum, wantIsNum := test.expected.Number()
+		gotNum, gotIsNum := bnh.Number()
+		wantHash, _ := test.expected.Hash()
+		gotHash, _ := bnh.Hash()
+		if wantIsNum != gotIsNum || wantNum != gotNum || wantHash != gotHash {
+			t.Errorf("Test %d got unexpected value, want %+v, got %+v", i, test.expected, bnh)
+		}
+	}
+}
@@ -46,6 +46,9 @@ func Subcommands() []*cli.Command {
 		importCommand,
 		exportCommand,
 		copydbCommand,
+		convertdbCommand,
+		pruneStateCommand,
+		snapshotCommand,
 		removedbCommand,
 		dumpCommand,
 		// See monitorcmd.go:
@@ -123,6 +126,8 @@ func MakeFullNode(ctx context.Context, cmd *cli.Command) *node.Node {
 	if cfg.Aquastats.URL != "" {
 		RegisterAquaStatsService(stack, cfg.Aquastats.URL)
 	}
+	// Push metrics to InfluxDB if requested.
+	SetupMetrics(cmd, ChainNameOf(cfg.Node))
 	return stack
 }
 
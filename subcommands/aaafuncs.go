@@ -6,6 +6,7 @@ import (
 	"errors"
 	"fmt"
 	"io/fs"
+	"math"
 	"math/big"
 	"os"
 	"path/filepath"
@@ -25,6 +26,8 @@ import (
 	"gitlab.com/aquachain/aquachain/common/config"
 	"gitlab.com/aquachain/aquachain/common/fdlimit"
 	"gitlab.com/aquachain/aquachain/common/log"
+	"gitlab.com/aquachain/aquachain/common/metrics"
+	"gitlab.com/aquachain/aquachain/common/metrics/influxdb"
 	"gitlab.com/aquachain/aquachain/common/sense"
 	"gitlab.com/aquachain/aquachain/common/toml"
 	"gitlab.com/aquachain/aquachain/consensus"
@@ -418,6 +421,26 @@ func setWS(cmd *cli.Command, cfg *node.Config) {
 	}
 }
 
+// setBtcRPC creates the bitcoind-compatible RPC listener interface string and
+// credentials from the set command line flags.
+func setBtcRPC(cmd *cli.Command, cfg *node.Config) {
+	if cmd.Bool(aquaflags.BtcRPCEnabledFlag.Name) && cfg.BtcRPCHost == "" {
+		cfg.BtcRPCHost = aquaflags.BtcRPCListenAddrFlag.Value
+		if cmd.IsSet(aquaflags.BtcRPCListenAddrFlag.Name) {
+			cfg.BtcRPCHost = cmd.String(aquaflags.BtcRPCListenAddrFlag.Name)
+		}
+	}
+	if cmd.IsSet(aquaflags.BtcRPCPortFlag.Name) {
+		cfg.BtcRPCPort = int(cmd.Int(aquaflags.BtcRPCPortFlag.Name))
+	}
+	if cmd.IsSet(aquaflags.BtcRPCUserFlag.Name) {
+		cfg.BtcRPCUser = cmd.String(aquaflags.BtcRPCUserFlag.Name)
+	}
+	if cmd.IsSet(aquaflags.BtcRPCPasswordFlag.Name) {
+		cfg.BtcRPCPassword = cmd.String(aquaflags.BtcRPCPasswordFlag.Name)
+	}
+}
+
 // setIPC creates an IPC path configuration from the set command line flags,
 // returning an empty string if IPC was explicitly disabled, or the set path.
 func setIPC(cmd *cli.Command, cfg *node.Config) {
@@ -721,7 +744,11 @@ func SetNodeConfig(cmd *cli.Command, cfg *node.Config) error {
 	setIPC(cmd, cfg)
 	setHTTP(cmd, cfg)
 	setWS(cmd, cfg)
+	setBtcRPC(cmd, cfg)
 	setNodeUserIdent(cmd, cfg)
+	if err := setDBEngine(cmd, cfg); err != nil {
+		return err
+	}
 	if cmd.IsSet(aquaflags.NoKeysFlag.Name) {
 		cfg.NoKeys = cmd.Bool(aquaflags.NoKeysFlag.Name)
 		log.Info("no keys mode", "enabled", cfg.NoKeys)
@@ -732,12 +759,47 @@ func SetNodeConfig(cmd *cli.Command, cfg *node.Config) error {
 	if cmd.IsSet(aquaflags.UseUSBFlag.Name) {
 		cfg.UseUSB = cmd.Bool(aquaflags.UseUSBFlag.Name)
 	}
+	if cmd.IsSet(aquaflags.ExternalSignerFlag.Name) {
+		cfg.ExternalSigner = cmd.String(aquaflags.ExternalSignerFlag.Name)
+	}
+	if cmd.IsSet(aquaflags.KeyStoreKDFFlag.Name) {
+		cfg.KeyStoreKDF = cmd.String(aquaflags.KeyStoreKDFFlag.Name)
+	}
+	if cmd.IsSet(aquaflags.KeyStoreArgon2MemoryFlag.Name) {
+		v := cmd.Uint(aquaflags.KeyStoreArgon2MemoryFlag.Name)
+		if v > math.MaxUint32 {
+			return fmt.Errorf("invalid --%s %d, too large", aquaflags.KeyStoreArgon2MemoryFlag.Name, v)
+		}
+		cfg.KeyStoreArgon2Memory = uint32(v)
+	}
+	if cmd.IsSet(aquaflags.KeyStoreArgon2TimeFlag.Name) {
+		v := cmd.Uint(aquaflags.KeyStoreArgon2TimeFlag.Name)
+		if v > math.MaxUint32 {
+			return fmt.Errorf("invalid --%s %d, too large", aquaflags.KeyStoreArgon2TimeFlag.Name, v)
+		}
+		cfg.KeyStoreArgon2Time = uint32(v)
+	}
 	if cmd.IsSet(aquaflags.RPCBehindProxyFlag.Name) || sense.EnvBool("RPC_BEHIND_PROXY") {
 		cfg.RPCBehindProxy = cmd.Bool(aquaflags.RPCBehindProxyFlag.Name)
 	}
 	return nil
 }
 
+// setDBEngine sets the backend of new databases, if given.
+func setDBEngine(cmd *cli.Command, cfg *node.Config) error {
+	if !cmd.IsSet(aquaflags.DBEngineFlag.Name) {
+		return nil
+	}
+	engine := cmd.String(aquaflags.DBEngineFlag.Name)
+	for _, backend := range aquadb.Backends {
+		if engine == backend {
+			cfg.DBEngine = engine
+			return nil
+		}
+	}
+	return fmt.Errorf("invalid --%s %q, want one of %s", aquaflags.DBEngineFlag.Name, engine, strings.Join(aquadb.Backends, ", "))
+}
+
 func setGPO(cmd *cli.Command, cfg *gasprice.Config) {
 	if cmd.IsSet(aquaflags.GpoBlocksFlag.Name) {
 		cfg.Blocks = int(cmd.Int(aquaflags.GpoBlocksFlag.Name))
@@ -778,6 +840,30 @@ func setTxPool(cmd *cli.Command, cfg *core.TxPoolConfig) {
 	if cmd.IsSet(aquaflags.TxPoolLifetimeFlag.Name) {
 		cfg.Lifetime = cmd.Duration(aquaflags.TxPoolLifetimeFlag.Name)
 	}
+	if cmd.IsSet(aquaflags.TxPoolPriorityFlag.Name) {
+		cfg.Priority = nil
+		for _, account := range strings.Split(cmd.String(aquaflags.TxPoolPriorityFlag.Name), ",") {
+			if account = strings.TrimSpace(account); account == "" {
+				continue
+			}
+			if !common.IsHexAddress(account) {
+				Fatalf("Invalid account in --%s: %s", aquaflags.TxPoolPriorityFlag.Name, account)
+			}
+			cfg.Priority = append(cfg.Priority, common.HexToAddress(account))
+		}
+	}
+	if cmd.IsSet(aquaflags.TxPoolRateLimitFlag.Name) {
+		cfg.SenderRateLimit = cmd.Uint(aquaflags.TxPoolRateLimitFlag.Name)
+	}
+	if cmd.IsSet(aquaflags.TxPoolRatePeriodFlag.Name) {
+		cfg.SenderRatePeriod = cmd.Duration(aquaflags.TxPoolRatePeriodFlag.Name)
+	}
+	if cmd.IsSet(aquaflags.TxPoolGlobalBytesFlag.Name) {
+		cfg.GlobalBytes = cmd.Uint(aquaflags.TxPoolGlobalBytesFlag.Name)
+	}
+	if cmd.IsSet(aquaflags.TxPoolEvictionFlag.Name) {
+		cfg.Eviction = cmd.String(aquaflags.TxPoolEvictionFlag.Name)
+	}
 }
 
 func setAquahash(cmd *cli.Command, cfg *aqua.Config) {
@@ -801,6 +887,18 @@ func setAquahash(cmd *cli.Command, cfg *aqua.Config) {
 	}
 }
 
+func setStratum(cmd *cli.Command, cfg *config.StratumConfig) {
+	if cmd.IsSet(aquaflags.StratumAddrFlag.Name) {
+		cfg.Addr = cmd.String(aquaflags.StratumAddrFlag.Name)
+	}
+	if cmd.IsSet(aquaflags.StratumDiffFlag.Name) {
+		cfg.Difficulty = cmd.Uint(aquaflags.StratumDiffFlag.Name)
+	}
+	if cmd.IsSet(aquaflags.StratumMinDiffFlag.Name) {
+		cfg.MinDiff = cmd.Uint(aquaflags.StratumMinDiffFlag.Name)
+	}
+}
+
 // checkExclusive verifies that only a single isntance of the provided flags was
 // set by the user. Each flag might optionally be followed by a string type to
 // specialize it further.
@@ -855,7 +953,7 @@ func SetAquaConfig(cmd *cli.Command, stack *node.Node, cfg *aqua.Config) {
 		if err != nil {
 			Fatalf("Failed to parse alert config: %v", err)
 		}
-		log.Info("alert config", "platform", cfgAlerts.Platform, "channel", cfgAlerts.Channel)
+		log.Info("alert config", "platform", cfgAlerts.Platform, "channel", cfgAlerts.Channel, "destinations", len(cfgAlerts.Destinations))
 	}
 	chaincfg := SetChainId(cmd, cfg)
 	setHardforkFlagParams(cmd, chaincfg) // modify HF map
@@ -870,6 +968,7 @@ func SetAquaConfig(cmd *cli.Command, stack *node.Node, cfg *aqua.Config) {
 	setGPO(cmd, &cfg.GPO)
 	setTxPool(cmd, &cfg.TxPool)
 	setAquahash(cmd, cfg)
+	setStratum(cmd, &cfg.Stratum)
 
 	switch {
 	default:
@@ -895,6 +994,16 @@ func SetAquaConfig(cmd *cli.Command, stack *node.Node, cfg *aqua.Config) {
 	}
 	cfg.NoPruning = cfg.NoPruning || cmd.String(aquaflags.GCModeFlag.Name) == "archive"
 
+	if cmd.IsSet(aquaflags.AncientFlag.Name) {
+		cfg.AncientDir = cmd.String(aquaflags.AncientFlag.Name)
+	}
+	if cmd.IsSet(aquaflags.AncientThresholdFlag.Name) {
+		cfg.AncientThreshold = cmd.Uint(aquaflags.AncientThresholdFlag.Name)
+	}
+
+	if cmd.IsSet(aquaflags.SnapshotFlag.Name) {
+		cfg.Snapshot = cmd.Bool(aquaflags.SnapshotFlag.Name)
+	}
 	if cmd.IsSet(aquaflags.CacheFlag.Name) || cmd.IsSet(aquaflags.CacheGCFlag.Name) {
 		cfg.TrieCache = int(cmd.Int(aquaflags.CacheFlag.Name) * cmd.Int(aquaflags.CacheGCFlag.Name) / 100)
 	}
@@ -998,14 +1107,87 @@ func RegisterAquaStatsService(stack *node.Node, url string) {
 	}
 }
 
+// SetupMetrics starts pushing metrics to InfluxDB if requested, tagged with the
+// host and chain names along with the configured tags. The chain name is the
+// one the node config was resolved to, see ChainNameOf.
+func SetupMetrics(cmd *cli.Command, chain string) {
+	enableV1 := cmd.Bool(aquaflags.MetricsEnableInfluxDBFlag.Name)
+	enableV2 := cmd.Bool(aquaflags.MetricsEnableInfluxDBV2Flag.Name)
+	if !enableV1 && !enableV2 {
+		return
+	}
+	if enableV1 && enableV2 {
+		Fatalf("Flags --%s and --%s can't be used at the same time", aquaflags.MetricsEnableInfluxDBFlag.Name, aquaflags.MetricsEnableInfluxDBV2Flag.Name)
+	}
+	if !metrics.Enabled {
+		Fatalf("Flag --%s is required to push metrics to InfluxDB", aquaflags.MetricsEnabledFlag.Name)
+	}
+	hostname, _ := os.Hostname()
+	tags := map[string]string{
+		"host":  hostname,
+		"chain": chain,
+	}
+	for _, tag := range splitAndTrim(cmd.String(aquaflags.MetricsInfluxDBTagsFlag.Name)) {
+		if tag == "" {
+			continue
+		}
+		key, value, ok := strings.Cut(tag, "=")
+		if !ok || key == "" {
+			Fatalf("Invalid InfluxDB tag %q, expected key=value", tag)
+		}
+		tags[key] = value
+	}
+	var (
+		endpoint = cmd.String(aquaflags.MetricsInfluxDBEndpointFlag.Name)
+		interval = cmd.Duration(aquaflags.MetricsInfluxDBIntervalFlag.Name)
+	)
+	if interval <= 0 {
+		Fatalf("Invalid --%s: %v", aquaflags.MetricsInfluxDBIntervalFlag.Name, interval)
+	}
+	if enableV1 {
+		var (
+			database = cmd.String(aquaflags.MetricsInfluxDBDatabaseFlag.Name)
+			username = cmd.String(aquaflags.MetricsInfluxDBUsernameFlag.Name)
+			password = cmd.String(aquaflags.MetricsInfluxDBPasswordFlag.Name)
+		)
+		log.Info("Enabling metrics export to InfluxDB", "endpoint", endpoint, "database", database)
+		go influxdb.InfluxDBWithTags(metrics.DefaultRegistry, interval, endpoint, database, username, password, "aquachain.", tags)
+	} else {
+		var (
+			token        = cmd.String(aquaflags.MetricsInfluxDBTokenFlag.Name)
+			bucket       = cmd.String(aquaflags.MetricsInfluxDBBucketFlag.Name)
+			organization = cmd.String(aquaflags.MetricsInfluxDBOrganizationFlag.Name)
+		)
+		log.Info("Enabling metrics export to InfluxDB (v2)", "endpoint", endpoint, "bucket", bucket, "organization", organization)
+		go influxdb.InfluxDBV2WithTags(metrics.DefaultRegistry, interval, endpoint, token, bucket, organization, "aquachain.", tags)
+	}
+}
+
+// ChainNameOf returns the name of the chain the node is configured for, or
+// "chainid-N" for a custom chain.
+func ChainNameOf(cfg *node.Config) string {
+	if chaincfg := params.GetChainConfigByChainId(new(big.Int).SetUint64(cfg.P2P.ChainId)); chaincfg != nil {
+		return chaincfg.Name()
+	}
+	return fmt.Sprintf("chainid-%d", cfg.P2P.ChainId)
+}
+
 // MakeChainDatabase open an LevelDB using the flags passed to the client and will hard crash if it fails.
-func MakeChainDatabase(cmd *cli.Command, stack *node.Node) aquadb.Database {
+// The ancient store is only attached if ancients is set, for commands that
+// read or write old chain segments; state-only commands leave it closed.
+func MakeChainDatabase(cmd *cli.Command, stack *node.Node, ancients bool) aquadb.Database {
 	var (
 		cache   = int(cmd.Int(aquaflags.CacheFlag.Name) * cmd.Int(aquaflags.CacheDatabaseFlag.Name) / 100)
 		handles = makeDatabaseHandles()
+		chainDb aquadb.Database
+		err     error
 	)
 	name := "chaindata"
-	chainDb, err := stack.OpenDatabase(name, cache, handles)
+	if ancients {
+		chainDb, err = stack.OpenDatabaseWithFreezer(name, cache, handles, cmd.String(aquaflags.AncientFlag.Name))
+	} else {
+		chainDb, err = stack.OpenDatabase(name, cache, handles)
+	}
 	if err != nil {
 		Fatalf("Could not open database: %v", err)
 	}
@@ -1041,7 +1223,7 @@ func MakeConsensusEngine(cmd *cli.Command, stack *node.Node) consensus.Engine {
 // MakeChain creates a chain manager from set command line flags.
 func MakeChain(cmd *cli.Command, stack *node.Node) (chain *core.BlockChain, chainDb aquadb.Database) {
 	var err error
-	chainDb = MakeChainDatabase(cmd, stack)
+	chainDb = MakeChainDatabase(cmd, stack, true)
 
 	config, _, err := core.SetupGenesisBlock(chainDb, MakeGenesis(cmd))
 	if err != nil {
@@ -1057,6 +1239,8 @@ func MakeChain(cmd *cli.Command, stack *node.Node) (chain *core.BlockChain, chai
 		Disabled:      cmd.String(aquaflags.GCModeFlag.Name) == "archive",
 		TrieNodeLimit: aqua.DefaultConfig.TrieCache,
 		TrieTimeLimit: aqua.DefaultConfig.TrieTimeout,
+
+		AncientThreshold: cmd.Uint(aquaflags.AncientThresholdFlag.Name),
 	}
 	if cmd.IsSet(aquaflags.CacheFlag.Name) || cmd.IsSet(aquaflags.CacheGCFlag.Name) {
 		cache.TrieNodeLimit = int(cmd.Int(aquaflags.CacheFlag.Name) * cmd.Int(aquaflags.CacheGCFlag.Name) / 100)
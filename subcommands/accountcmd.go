@@ -19,10 +19,15 @@ package subcommands
 import (
 	"context"
 	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
 
 	cli "github.com/urfave/cli/v3"
 	"gitlab.com/aquachain/aquachain/aqua/accounts"
 	"gitlab.com/aquachain/aquachain/aqua/accounts/keystore"
+	"gitlab.com/aquachain/aquachain/common"
 	"gitlab.com/aquachain/aquachain/common/log"
 	"gitlab.com/aquachain/aquachain/crypto"
 	"gitlab.com/aquachain/aquachain/opt/console"
@@ -75,6 +80,9 @@ Print a short summary of all accounts`,
 					aquaflags.DataDirFlag,
 					aquaflags.KeyStoreDirFlag,
 					aquaflags.PasswordFileFlag,
+					aquaflags.KeyStoreKDFFlag,
+					aquaflags.KeyStoreArgon2MemoryFlag,
+					aquaflags.KeyStoreArgon2TimeFlag,
 				},
 				Description: `
     aquachain account new
@@ -101,6 +109,53 @@ password to file or expose in any other way.
 				},
 				Description: `
     This only prints! Does not store key.
+`,
+			},
+			{
+				Name:      "import-mnemonic",
+				Usage:     "Import a BIP-39 mnemonic into a new HD wallet",
+				Action:    MigrateFlags(accountImportMnemonic),
+				ArgsUsage: "[<phraseFile>]",
+				Flags: []cli.Flag{
+					aquaflags.DataDirFlag,
+					aquaflags.KeyStoreDirFlag,
+					aquaflags.PasswordFileFlag,
+					aquaflags.BIP39PassphraseFlag,
+				},
+				Description: `
+    aquachain account import-mnemonic [options] [<phraseFile>]
+
+Imports a BIP-39 mnemonic phrase into a new hierarchical deterministic wallet
+and prints the address of its first account (m/44'/60'/0'/0/0).
+
+The phrase is read from <phraseFile> if given, otherwise you are prompted for
+it. Use -bip39passphrase to be prompted for the optional BIP-39 passphrase.
+
+The wallet seed is saved in encrypted format under <KEYSTORE>/hd, you are
+prompted for a passphrase. You must remember this passphrase to use the
+wallet in the future.
+`,
+			},
+			{
+				Name:      "derive",
+				Usage:     "Derive and pin accounts of an HD wallet",
+				Action:    MigrateFlags(accountDerive),
+				ArgsUsage: "<path> [<path>...]",
+				Flags: []cli.Flag{
+					aquaflags.DataDirFlag,
+					aquaflags.KeyStoreDirFlag,
+					aquaflags.PasswordFileFlag,
+					aquaflags.HDWalletFlag,
+				},
+				Description: `
+    aquachain account derive [options] <path> [<path>...]
+
+Derives the accounts at the given derivation paths of an HD wallet, adds them
+to the accounts tracked by the wallet and prints their addresses.
+
+Paths are either absolute (m/44'/60'/0'/0/1) or relative to m/44'/60'/0'/0,
+so "1" derives the second account. The first HD wallet is used unless one is
+selected with -wallet.
 `,
 			},
 			{
@@ -111,6 +166,9 @@ password to file or expose in any other way.
 				Flags: []cli.Flag{
 					aquaflags.DataDirFlag,
 					aquaflags.KeyStoreDirFlag,
+					aquaflags.KeyStoreKDFFlag,
+					aquaflags.KeyStoreArgon2MemoryFlag,
+					aquaflags.KeyStoreArgon2TimeFlag,
 				},
 				Description: `
     aquachain account update <address>
@@ -129,6 +187,38 @@ For non-interactive use the passphrase can be specified with the --password flag
 
 Since only one password can be given, only format update can be performed,
 changing your password is only possible interactively.
+`,
+			},
+			{
+				Name:   "reencrypt",
+				Usage:  "Re-encrypt all keystore accounts in the selected format (HD wallets are skipped)",
+				Action: MigrateFlags(accountReencrypt),
+				Flags: []cli.Flag{
+					aquaflags.DataDirFlag,
+					aquaflags.KeyStoreDirFlag,
+					aquaflags.PasswordFileFlag,
+					aquaflags.KeyStoreKDFFlag,
+					aquaflags.KeyStoreArgon2MemoryFlag,
+					aquaflags.KeyStoreArgon2TimeFlag,
+					aquaflags.KeyStoreBackupFlag,
+				},
+				Description: `
+    aquachain account reencrypt [options]
+
+Re-encrypts every account key file of the keystore with the KDF selected by
+-keystore.kdf, which defaults to argon2id for this command. Passphrases are
+kept, you are prompted for the passphrase of each account.
+
+Before any key file is touched, the keystore is copied to the -backup
+directory. Key files are replaced atomically, so a node running on the same
+keystore keeps seeing every account while the migration is in progress.
+
+For non-interactive use the passphrases can be given with the -password flag,
+one per line in the order the accounts are listed by 'aquachain account list'.
+
+Only the key files at the top of the keystore are backed up and re-encrypted.
+The HD wallets in its 'hd' subdirectory, and any other subdirectory, are
+skipped and keep their scrypt encryption.
 `,
 			},
 			{
@@ -286,9 +376,19 @@ func accountCreate(_ context.Context, cmd *cli.Command) error {
 		Fatalf("Failed to read configuration: %v", err)
 	}
 
+	argon2Memory, argon2Time, err := cfg.Node.KeyStoreArgon2Config()
+	if err != nil {
+		Fatalf("Failed to read configuration: %v", err)
+	}
+
 	password := getPassPhrase("Your new account is locked with a password. Please give a password. Do not forget this password. Backup your keystore directory.", true, 0, MakePasswordList(cmd))
 
-	address, err := keystore.StoreKey(keydir, password, scryptN, scryptP)
+	var address common.Address
+	if argon2Memory != 0 {
+		address, err = keystore.StoreKeyArgon2id(keydir, password, argon2Memory, argon2Time)
+	} else {
+		address, err = keystore.StoreKey(keydir, password, scryptN, scryptP)
+	}
 
 	if err != nil {
 		Fatalf("Failed to create account: %v", err)
@@ -316,6 +416,100 @@ func accountUpdate(ctx context.Context, cmd *cli.Command) error {
 	return nil
 }
 
+// accountReencrypt re-encrypts all accounts of the keystore in the format
+// selected by the CLI flags, after backing up the keystore directory.
+func accountReencrypt(_ context.Context, cmd *cli.Command) error {
+	cfg := AquachainConfig{Node: DefaultNodeConfig(gitCommit, clientIdentifier)}
+	if file := cmd.String(aquaflags.ConfigFileFlag.Name); file != "" {
+		if err := LoadConfigFromFile(file, &cfg); err != nil {
+			Fatalf("%v", err)
+		}
+	}
+	if err := SetNodeConfig(cmd, cfg.Node); err != nil {
+		Fatalf("%v", err)
+	}
+	if !cmd.IsSet(aquaflags.KeyStoreKDFFlag.Name) {
+		cfg.Node.KeyStoreKDF = "argon2id"
+	}
+	scryptN, scryptP, keydir, err := cfg.Node.AccountConfig()
+	if err != nil {
+		Fatalf("Failed to read configuration: %v", err)
+	}
+	if keydir == "" {
+		Fatalf("No keystore directory (-nokeys)")
+	}
+	backup := cmd.String(aquaflags.KeyStoreBackupFlag.Name)
+	if backup == "" {
+		backup = fmt.Sprintf("%s-backup-%s", filepath.Clean(keydir), time.Now().UTC().Format("20060102150405"))
+	}
+	n, skipped, err := backupKeyStore(keydir, backup)
+	if err != nil {
+		Fatalf("Failed to back up keystore: %v", err)
+	}
+	fmt.Printf("Backed up %d key files to %s\n", n, backup)
+	for _, dir := range skipped {
+		fmt.Printf("Skipping directory %s: HD wallets and other subdirectories are neither backed up nor re-encrypted\n", dir)
+	}
+
+	ks, err := cfg.Node.OpenKeyStore(keydir, scryptN, scryptP)
+	if err != nil {
+		Fatalf("Failed to open keystore: %v", err)
+	}
+	var (
+		passwords = MakePasswordList(cmd)
+		failed    int
+	)
+	for i, account := range ks.Accounts() {
+		prompt := fmt.Sprintf("Re-encrypting account 0x%x", account.Address)
+		password := getPassPhrase(prompt, false, i, passwords)
+		if err := ks.Update(account, password, password); err != nil {
+			fmt.Printf("Account #%d: 0x%x failed: %v\n", i, account.Address, err)
+			failed++
+			continue
+		}
+		fmt.Printf("Account #%d: 0x%x re-encrypted (%s)\n", i, account.Address, cfg.Node.KeyStoreKDF)
+	}
+	if failed != 0 {
+		Fatalf("Failed to re-encrypt %d accounts, the original key files are backed up in %s", failed, backup)
+	}
+	return nil
+}
+
+// backupKeyStore copies the key files of keydir into the new directory backup,
+// returning the number of files copied and the subdirectories left out.
+func backupKeyStore(keydir, backup string) (n int, skipped []string, err error) {
+	files, err := os.ReadDir(keydir)
+	if err != nil {
+		return 0, nil, err
+	}
+	if _, err := os.Stat(backup); err == nil {
+		return 0, nil, fmt.Errorf("backup directory already exists: %s", backup)
+	}
+	if err := os.MkdirAll(backup, 0700); err != nil {
+		return 0, nil, err
+	}
+	for _, fi := range files {
+		// Skip directories and the hidden or temporary files of editors, just
+		// like the account cache does.
+		name := fi.Name()
+		if fi.IsDir() && !strings.HasPrefix(name, ".") {
+			skipped = append(skipped, filepath.Join(keydir, name))
+		}
+		if !fi.Type().IsRegular() || strings.HasPrefix(name, ".") || strings.HasSuffix(name, "~") {
+			continue
+		}
+		content, err := os.ReadFile(filepath.Join(keydir, name))
+		if err != nil {
+			return n, skipped, err
+		}
+		if err := os.WriteFile(filepath.Join(backup, name), content, 0600); err != nil {
+			return n, skipped, err
+		}
+		n++
+	}
+	return n, skipped, nil
+}
+
 func accountImport(ctx context.Context, cmd *cli.Command) error {
 	keyfile := cmd.Args().First()
 	if len(keyfile) == 0 {
@@ -0,0 +1,129 @@
+// Copyright 2018 The aquachain Authors
+// This file is part of aquachain.
+//
+// aquachain is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// aquachain is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with aquachain. If not, see <http://www.gnu.org/licenses/>.
+
+package subcommands
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/urfave/cli/v3"
+	"gitlab.com/aquachain/aquachain/aqua/accounts/keystore"
+	"gitlab.com/aquachain/aquachain/subcommands/aquaflags"
+)
+
+// Tests that 'account reencrypt' backs up the keystore and re-encrypts every
+// key with argon2id, keeping the passphrases, and leaves the HD wallets alone.
+func TestAccountReencrypt(t *testing.T) {
+	var (
+		datadir = t.TempDir()
+		keydir  = filepath.Join(datadir, "keystore")
+		backup  = filepath.Join(datadir, "backup")
+		ks      = keystore.NewKeyStore(keydir, keystore.LightScryptN, keystore.LightScryptP)
+	)
+	for _, password := range []string{"foo", "bar"} {
+		if _, err := ks.NewAccount(password); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// Passwords are given in the order the accounts are listed
+	var passwords []string
+	for _, account := range ks.Accounts() {
+		for _, password := range []string{"foo", "bar"} {
+			if err := ks.Unlock(account, password); err == nil {
+				passwords = append(passwords, password)
+			}
+		}
+	}
+	hdfile := filepath.Join(keydir, "hd", "wallet.json")
+	hdblob := []byte(`{"crypto":{"kdf":"scrypt"}}`)
+	if err := os.MkdirAll(filepath.Dir(hdfile), 0700); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(hdfile, hdblob, 0600); err != nil {
+		t.Fatal(err)
+	}
+	pwfile := filepath.Join(datadir, "passwords")
+	if err := os.WriteFile(pwfile, []byte(strings.Join(passwords, "\n")), 0600); err != nil {
+		t.Fatal(err)
+	}
+	SetBuildInfo("", "", "", "aquachain")
+	app := &cli.Command{
+		Name:     "aquachain",
+		Flags:    append([]cli.Flag{aquaflags.ConfigFileFlag, aquaflags.ChainFlag}, aquaflags.NodeFlags...),
+		Commands: []*cli.Command{accountCommand},
+	}
+	err := app.Run(context.Background(), []string{"aquachain", "account", "reencrypt",
+		"-datadir", datadir, "-keystore", keydir, "-password", pwfile, "-backup", backup,
+		"-keystore.argon2.memory", "1024", "-keystore.argon2.time", "1"})
+	if err != nil {
+		t.Fatalf("reencrypt failed: %v", err)
+	}
+	checkKDF := func(dir, want string) {
+		files, err := os.ReadDir(dir)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var keys []os.DirEntry
+		for _, file := range files {
+			if !file.IsDir() {
+				keys = append(keys, file)
+			}
+		}
+		if len(keys) != 2 {
+			t.Fatalf("%s: key file count mismatch: have %d, want 2", dir, len(keys))
+		}
+		for _, file := range keys {
+			blob, err := os.ReadFile(filepath.Join(dir, file.Name()))
+			if err != nil {
+				t.Fatal(err)
+			}
+			var key struct {
+				Crypto struct {
+					KDF string `json:"kdf"`
+				} `json:"crypto"`
+			}
+			if err := json.Unmarshal(blob, &key); err != nil {
+				t.Fatalf("%s: invalid key file: %v", file.Name(), err)
+			}
+			if key.Crypto.KDF != want {
+				t.Errorf("%s: kdf mismatch: have %s, want %s", file.Name(), key.Crypto.KDF, want)
+			}
+		}
+	}
+	checkKDF(keydir, "argon2id")
+	checkKDF(backup, "scrypt")
+
+	if blob, err := os.ReadFile(hdfile); err != nil || !bytes.Equal(blob, hdblob) {
+		t.Errorf("HD wallet file modified: %s, %v", blob, err)
+	}
+	if _, err := os.Stat(filepath.Join(backup, "hd")); !os.IsNotExist(err) {
+		t.Errorf("HD wallet directory backed up: %v", err)
+	}
+
+	// The passphrases must still unlock the re-encrypted keys
+	ks = keystore.NewKeyStore(keydir, keystore.LightScryptN, keystore.LightScryptP)
+	for i, account := range ks.Accounts() {
+		if err := ks.Unlock(account, passwords[i]); err != nil {
+			t.Errorf("account %d: failed to unlock: %v", i, err)
+		}
+	}
+}
@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"os"
 	"runtime"
+	"time"
 
 	"github.com/urfave/cli/v3"
 	"gitlab.com/aquachain/aquachain/aqua"
@@ -70,10 +71,31 @@ var (
 		Name:  "keystore",
 		Usage: "Directory for the keystore (default = inside the datadir)",
 	}
+	KeyStoreKDFFlag = &cli.StringFlag{
+		Name:  "keystore.kdf",
+		Usage: "KDF to encrypt new and updated keys with (scrypt, argon2id)",
+		Value: "scrypt",
+	}
+	KeyStoreArgon2MemoryFlag = &cli.UintFlag{
+		Name:  "keystore.argon2.memory",
+		Usage: "Memory of the argon2id keystore KDF in KiB (0 = standard, 262144)",
+	}
+	KeyStoreArgon2TimeFlag = &cli.UintFlag{
+		Name:  "keystore.argon2.time",
+		Usage: "Number of passes of the argon2id keystore KDF (0 = standard, 3)",
+	}
+	KeyStoreBackupFlag = &cli.StringFlag{
+		Name:  "backup",
+		Usage: "Directory to back up the key files to (default = <keystore>-backup-<timestamp>)",
+	}
 	UseUSBFlag = &cli.BoolFlag{
 		Name:  "usb",
 		Usage: "Enables monitoring for and managing USB hardware wallets (disabled in pure-go builds)",
 	}
+	ExternalSignerFlag = &cli.StringFlag{
+		Name:  "signer",
+		Usage: "External signer (IPC path or HTTP/WS URL) to forward signing requests to, usable with -nokeys",
+	}
 	DoitNowFlag = &cli.BoolFlag{
 		Name:  "now",
 		Usage: "Start the node immediately, do not start countdown",
@@ -101,7 +123,7 @@ var (
 	}
 	AlertModeFlag = &cli.BoolFlag{
 		Name:  "alerts",
-		Usage: "Enable alert notifications (requires env $ALERT_TOKEN, $ALERT_PLATFORM, and $ALERT_CHANNEL)",
+		Usage: "Enable alert notifications (requires env $ALERT_URLS, comma separated tg://, webhook://, smtp:// or matrix:// URLs, or $ALERT_TOKEN, $ALERT_PLATFORM, and $ALERT_CHANNEL)",
 	}
 	TestnetFlag = &cli.BoolFlag{
 		Name:  "testnet",
@@ -204,6 +226,18 @@ var (
 		Usage: `GC mode to use, either "full" or "archive". Use "archive" for full accurate state (for example, 'admin.supply')`,
 		Value: "archive",
 	}
+	AncientFlag = &cli.StringFlag{
+		Name:  "ancient",
+		Usage: "Directory of the ancient block store (default = inside the chaindata directory)",
+	}
+	AncientThresholdFlag = &cli.UintFlag{
+		Name:  "ancient.threshold",
+		Usage: "Move canonical blocks with this many confirmations to the ancient store (0 = disabled)",
+	}
+	SnapshotFlag = &cli.BoolFlag{
+		Name:  "snapshot",
+		Usage: "Keep a flat state snapshot for faster state reads (generated in the background)",
+	}
 )
 var (
 	// Aquahash settings
@@ -286,6 +320,30 @@ var (
 		Usage: "Maximum amount of time non-executable transaction are queued",
 		Value: aqua.DefaultConfig.TxPool.Lifetime,
 	}
+	TxPoolPriorityFlag = &cli.StringFlag{
+		Name:  "txpool.priority",
+		Usage: "Comma separated accounts whose transactions are never evicted or rate limited",
+	}
+	TxPoolRateLimitFlag = &cli.UintFlag{
+		Name:  "txpool.ratelimit",
+		Usage: "Maximum number of transactions accepted per remote sender and rate period (0 = unlimited)",
+		Value: aqua.DefaultConfig.TxPool.SenderRateLimit,
+	}
+	TxPoolRatePeriodFlag = &cli.DurationFlag{
+		Name:  "txpool.rateperiod",
+		Usage: "Period of the per-sender transaction rate limit",
+		Value: aqua.DefaultConfig.TxPool.SenderRatePeriod,
+	}
+	TxPoolGlobalBytesFlag = &cli.UintFlag{
+		Name:  "txpool.globalbytes",
+		Usage: "Maximum total size of all pooled transactions in bytes (0 = unlimited)",
+		Value: aqua.DefaultConfig.TxPool.GlobalBytes,
+	}
+	TxPoolEvictionFlag = &cli.StringFlag{
+		Name:  "txpool.eviction",
+		Usage: `Eviction order once the pool is full ("price" cheapest first, "score" least paid per byte and oldest first)`,
+		Value: aqua.DefaultConfig.TxPool.Eviction,
+	}
 	// Performance tuning settings
 	CacheFlag = &cli.IntFlag{
 		Name:  "cache",
@@ -297,6 +355,10 @@ var (
 		Usage: "Percentage of cache memory allowance to use for database io",
 		Value: 75,
 	}
+	DBEngineFlag = &cli.StringFlag{
+		Name:  "db.engine",
+		Usage: "Backend of new databases (\"leveldb\" or \"pebble\"), existing databases keep their own",
+	}
 	CacheGCFlag = &cli.IntFlag{
 		Name:  "cache.gc",
 		Usage: "Percentage of cache memory allowance to use for trie pruning",
@@ -337,6 +399,21 @@ var (
 		Name:  "extradata",
 		Usage: "Block extra data set by the miner (default = client version)",
 	}
+	StratumAddrFlag = &cli.StringFlag{
+		Name:  "stratum",
+		Usage: "Enable the stratum mining server on the given address (eg: 0.0.0.0:8888)",
+		Value: "",
+	}
+	StratumDiffFlag = &cli.UintFlag{
+		Name:  "stratum.diff",
+		Usage: "Default share difficulty for stratum miners (miners may request more with password 'd=N')",
+		Value: aqua.DefaultConfig.Stratum.Difficulty,
+	}
+	StratumMinDiffFlag = &cli.UintFlag{
+		Name:  "stratum.mindiff",
+		Usage: "Lowest share difficulty stratum miners may request",
+		Value: aqua.DefaultConfig.Stratum.MinDiff,
+	}
 	// Account settings
 	UnlockedAccountFlag = &cli.StringFlag{
 		Name:  "unlock",
@@ -348,6 +425,15 @@ var (
 		Usage: "Password file to use for non-interactive password input",
 		Value: "",
 	}
+	HDWalletFlag = &cli.StringFlag{
+		Name:  "wallet",
+		Usage: "URL of the HD wallet to use (defaults to the first HD wallet)",
+		Value: "",
+	}
+	BIP39PassphraseFlag = &cli.BoolFlag{
+		Name:  "bip39passphrase",
+		Usage: "Prompt for the optional BIP-39 passphrase protecting the mnemonic",
+	}
 
 	VMEnableDebugFlag = &cli.BoolFlag{
 		Name:  "vmdebug",
@@ -362,6 +448,55 @@ var (
 		Name:  metrics.MetricsEnabledFlag,
 		Usage: "Enable metrics collection and reporting",
 	}
+	MetricsEnableInfluxDBFlag = &cli.BoolFlag{
+		Name:  "metrics.influxdb",
+		Usage: "Push metrics to an InfluxDB v1 database (requires -metrics)",
+	}
+	MetricsEnableInfluxDBV2Flag = &cli.BoolFlag{
+		Name:  "metrics.influxdbv2",
+		Usage: "Push metrics to an InfluxDB v2 bucket (requires -metrics)",
+	}
+	MetricsInfluxDBEndpointFlag = &cli.StringFlag{
+		Name:  "metrics.influxdb.endpoint",
+		Usage: "InfluxDB API endpoint to push metrics to",
+		Value: "http://localhost:8086",
+	}
+	MetricsInfluxDBIntervalFlag = &cli.DurationFlag{
+		Name:  "metrics.influxdb.interval",
+		Usage: "Interval between metrics pushes to InfluxDB",
+		Value: 10 * time.Second,
+	}
+	MetricsInfluxDBTagsFlag = &cli.StringFlag{
+		Name:  "metrics.influxdb.tags",
+		Usage: "Comma separated InfluxDB tags (key=value) attached to all measurements, in addition to host and chain",
+	}
+	MetricsInfluxDBDatabaseFlag = &cli.StringFlag{
+		Name:  "metrics.influxdb.database",
+		Usage: "InfluxDB v1 database name to push metrics to",
+		Value: "aquachain",
+	}
+	MetricsInfluxDBUsernameFlag = &cli.StringFlag{
+		Name:  "metrics.influxdb.username",
+		Usage: "Username to authorize access to the InfluxDB v1 database",
+	}
+	MetricsInfluxDBPasswordFlag = &cli.StringFlag{
+		Name:  "metrics.influxdb.password",
+		Usage: "Password to authorize access to the InfluxDB v1 database",
+	}
+	MetricsInfluxDBTokenFlag = &cli.StringFlag{
+		Name:  "metrics.influxdb.token",
+		Usage: "Token to authorize access to the InfluxDB v2 bucket",
+	}
+	MetricsInfluxDBBucketFlag = &cli.StringFlag{
+		Name:  "metrics.influxdb.bucket",
+		Usage: "InfluxDB v2 bucket name to push metrics to",
+		Value: "aquachain",
+	}
+	MetricsInfluxDBOrganizationFlag = &cli.StringFlag{
+		Name:  "metrics.influxdb.organization",
+		Usage: "InfluxDB v2 organization name",
+		Value: "aquachain",
+	}
 	FakePoWFlag = &cli.BoolFlag{
 		Name:  "fakepow",
 		Usage: "Disables proof-of-work verification",
@@ -370,6 +505,10 @@ var (
 		Name:  "nocompaction",
 		Usage: "Disables db compaction after import",
 	}
+	DryRunFlag = &cli.BoolFlag{
+		Name:  "dry-run",
+		Usage: "Report what would be deleted without deleting anything",
+	}
 	// RPC settings
 	RPCEnabledFlag = &cli.BoolFlag{
 		Name:  "rpc",
@@ -432,6 +571,28 @@ var (
 		Usage: "API's offered over the WS-RPC interface",
 		Value: "",
 	}
+	BtcRPCEnabledFlag = &cli.BoolFlag{
+		Name:  "btcrpc",
+		Usage: "Enable the bitcoind-compatible HTTP-RPC server (btc API, HTTP basic auth)",
+	}
+	BtcRPCListenAddrFlag = &cli.StringFlag{
+		Name:  "btcrpcaddr",
+		Usage: "Bitcoind-compatible HTTP-RPC server listening interface",
+		Value: node.DefaultBtcRPCHost,
+	}
+	BtcRPCPortFlag = &cli.IntFlag{
+		Name:  "btcrpcport",
+		Usage: "Bitcoind-compatible HTTP-RPC server listening port",
+		Value: node.DefaultBtcRPCPort,
+	}
+	BtcRPCUserFlag = &cli.StringFlag{
+		Name:  "btcrpcuser",
+		Usage: "Username for bitcoind-compatible HTTP-RPC connections",
+	}
+	BtcRPCPasswordFlag = &cli.StringFlag{
+		Name:  "btcrpcpassword",
+		Usage: "Password for bitcoind-compatible HTTP-RPC connections (default: random, written to the .cookie file)",
+	}
 	WSAllowedOriginsFlag = &cli.StringFlag{
 		Name:  "wsorigins",
 		Usage: "Origins from which to accept websockets requests (see also rpcvhosts)",
@@ -551,8 +712,15 @@ var (
 		BootnodesFlag,
 		DataDirFlag,
 		KeyStoreDirFlag,
+		KeyStoreKDFFlag,
+		KeyStoreArgon2MemoryFlag,
+		KeyStoreArgon2TimeFlag,
 		NoKeysFlag,
 		UseUSBFlag,
+		ExternalSignerFlag,
+		AncientFlag,
+		AncientThresholdFlag,
+		SnapshotFlag,
 		AquahashCacheDirFlag,
 		AquahashCachesInMemoryFlag,
 		AquahashCachesOnDiskFlag,
@@ -569,11 +737,17 @@ var (
 		TxPoolAccountQueueFlag,
 		TxPoolGlobalQueueFlag,
 		TxPoolLifetimeFlag,
+		TxPoolPriorityFlag,
+		TxPoolRateLimitFlag,
+		TxPoolRatePeriodFlag,
+		TxPoolGlobalBytesFlag,
+		TxPoolEvictionFlag,
 		FastSyncFlag,
 		SyncModeFlag,
 		// GCModeFlag,
 		CacheFlag,
 		CacheDatabaseFlag,
+		DBEngineFlag,
 		CacheGCFlag,
 		TrieCacheGenFlag,
 		ListenPortFlag,
@@ -585,6 +759,9 @@ var (
 		MinerThreadsFlag,
 		MiningEnabledFlag,
 		TargetGasLimitFlag,
+		StratumAddrFlag,
+		StratumDiffFlag,
+		StratumMinDiffFlag,
 		NATFlag,
 		NoDiscoverFlag,
 		OfflineFlag,
@@ -598,6 +775,17 @@ var (
 
 		AquaStatsURLFlag,
 		MetricsEnabledFlag,
+		MetricsEnableInfluxDBFlag,
+		MetricsEnableInfluxDBV2Flag,
+		MetricsInfluxDBEndpointFlag,
+		MetricsInfluxDBIntervalFlag,
+		MetricsInfluxDBTagsFlag,
+		MetricsInfluxDBDatabaseFlag,
+		MetricsInfluxDBUsernameFlag,
+		MetricsInfluxDBPasswordFlag,
+		MetricsInfluxDBTokenFlag,
+		MetricsInfluxDBBucketFlag,
+		MetricsInfluxDBOrganizationFlag,
 		FakePoWFlag,
 		NoCompactionFlag,
 		GpoBlocksFlag,
@@ -623,6 +811,11 @@ var (
 		WSPortFlag,
 		WSApiFlag,
 		WSAllowedOriginsFlag,
+		BtcRPCEnabledFlag,
+		BtcRPCListenAddrFlag,
+		BtcRPCPortFlag,
+		BtcRPCUserFlag,
+		BtcRPCPasswordFlag,
 		IPCDisabledFlag,
 		IPCPathFlag,
 		AlertModeFlag,
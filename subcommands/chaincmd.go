@@ -21,13 +21,13 @@ import (
 	"encoding/json"
 	"fmt"
 	"os"
+	"path/filepath"
 	"runtime"
 	"strconv"
 	"strings"
 	"sync/atomic"
 	"time"
 
-	"github.com/syndtr/goleveldb/leveldb/util"
 	"github.com/urfave/cli/v3"
 	"gitlab.com/aquachain/aquachain/aqua/downloader"
 	"gitlab.com/aquachain/aquachain/aqua/event"
@@ -86,6 +86,8 @@ It expects the genesis file as argument.`,
 			aquaflags.GCModeFlag,
 			aquaflags.CacheDatabaseFlag,
 			aquaflags.CacheGCFlag,
+			aquaflags.AncientFlag,
+			aquaflags.AncientThresholdFlag,
 		},
 		Category: "BLOCKCHAIN COMMANDS",
 		Description: `
@@ -93,7 +95,10 @@ The import command imports blocks from an RLP-encoded form. The form can be one
 with several RLP-encoded blocks, or several files can be used.
 
 If only one file is used, import error will result in failure. If several files are used,
-processing will proceed even if an individual RLP-file import failure occurs.`,
+processing will proceed even if an individual RLP-file import failure occurs.
+
+With --ancient.threshold, old blocks are moved to the ancient store once the import
+is done.`,
 	}
 	exportCommand = &cli.Command{
 		Action:    MigrateFlags(exportChain),
@@ -103,10 +108,12 @@ processing will proceed even if an individual RLP-file import failure occurs.`,
 		Flags: []cli.Flag{
 			// aquaflags.DataDirFlag,
 			aquaflags.CacheFlag,
+			aquaflags.AncientFlag,
 		},
 		Category: "BLOCKCHAIN COMMANDS",
 		Description: `
 Requires a first argument of the file to write to.
+Blocks in the ancient store are exported like any other.
 Optional second and third arguments control the first and
 last block to write. In this mode, the file will be appended
 if already existing.`,
@@ -126,6 +133,42 @@ if already existing.`,
 		Category: "BLOCKCHAIN COMMANDS",
 		Description: `
 The first argument must be the directory containing the blockchain to download from`,
+	}
+	convertdbCommand = &cli.Command{
+		Action:    MigrateFlags(convertDB),
+		Name:      "convertdb",
+		Usage:     "Convert the chain database to another backend",
+		ArgsUsage: " ",
+		Flags: []cli.Flag{
+			aquaflags.DataDirFlag,
+			aquaflags.CacheFlag,
+			aquaflags.DBEngineFlag,
+		},
+		Category: "BLOCKCHAIN COMMANDS",
+		Description: `
+The convertdb command copies the chain database into a new database using the
+backend given by --db.engine. The old database is kept next to the new one
+with a ".bak" suffix, and can be removed once the node runs fine.`,
+	}
+	pruneStateCommand = &cli.Command{
+		Action:    MigrateFlags(pruneState),
+		Name:      "prune-state",
+		Usage:     "Delete state not reachable from a recent block",
+		ArgsUsage: "[<blockNum>]",
+		Flags: []cli.Flag{
+			aquaflags.DataDirFlag,
+			aquaflags.CacheFlag,
+			aquaflags.DryRunFlag,
+		},
+		Category: "BLOCKCHAIN COMMANDS",
+		Description: `
+The prune-state command deletes every state trie node and contract code that is
+not part of the state of the given block (default: the head block) or of the
+genesis block. The node must be stopped.
+
+States of all other blocks are gone afterwards. If a block below the head is
+given, the node rewinds to it on the next start. With --dry-run, the command
+only reports how much space pruning would reclaim.`,
 	}
 	removedbCommand = &cli.Command{
 		Action:    MigrateFlags(removeDB),
@@ -234,13 +277,21 @@ func importChain(ctx context.Context, cmd *cli.Command) error {
 			}
 		}
 	}
+	if n, err := chain.Freeze(); err != nil {
+		log.Error("Failed to move blocks to ancient store", "err", err)
+	} else if n > 0 {
+		fmt.Printf("Moved %d blocks to the ancient store, which holds %d blocks.\n", n, chain.Ancients())
+	}
 	chain.Stop()
 	fmt.Printf("Import done in %v.\n\n", time.Since(start))
 
 	// Output pre-compaction stats mostly to see the import trashing
-	db := chainDb.(*aquadb.LDBDatabase)
+	db := chainDb.(interface {
+		aquadb.Stater
+		aquadb.Compacter
+	})
 
-	stats, err := db.LDB().GetProperty("leveldb.stats")
+	stats, err := db.Stat("")
 	if err != nil {
 		Fatalf("Failed to read database stats: %v", err)
 	}
@@ -264,12 +315,12 @@ func importChain(ctx context.Context, cmd *cli.Command) error {
 	// Compact the entire database to more accurately measure disk io and print the stats
 	start = time.Now()
 	fmt.Println("Compacting entire database...")
-	if err = db.LDB().CompactRange(util.Range{}); err != nil {
+	if err = db.Compact(nil, nil); err != nil {
 		Fatalf("Compaction failed: %v", err)
 	}
 	fmt.Printf("Compaction done in %v.\n\n", time.Since(start))
 
-	stats, err = db.LDB().GetProperty("leveldb.stats")
+	stats, err = db.Stat("")
 	if err != nil {
 		Fatalf("Failed to read database stats: %v", err)
 	}
@@ -332,7 +383,16 @@ func copyDb(ctx context.Context, cmd *cli.Command) error {
 	dl := downloader.New(syncmode, chainDb, new(event.TypeMux), chain, nil, nil)
 
 	// Create a source peer to satisfy downloader requests from
-	db, err := aquadb.NewLDBDatabase(cmd.Args().First(), int(cmd.Int(aquaflags.CacheFlag.Name)), 256)
+	var (
+		srcdir = cmd.Args().First()
+		cache  = int(cmd.Int(aquaflags.CacheFlag.Name))
+		db     aquadb.Database
+	)
+	if _, err := os.Stat(filepath.Join(srcdir, "ancient")); err == nil {
+		db, err = aquadb.OpenWithFreezer("", srcdir, cache, 256, filepath.Join(srcdir, "ancient"))
+	} else {
+		db, err = aquadb.Open("", srcdir, cache, 256)
+	}
 	if err != nil {
 		return err
 	}
@@ -359,7 +419,7 @@ func copyDb(ctx context.Context, cmd *cli.Command) error {
 	// Compact the entire database to remove any sync overhead
 	start = time.Now()
 	fmt.Println("Compacting entire database...")
-	if err = chainDb.(*aquadb.LDBDatabase).LDB().CompactRange(util.Range{}); err != nil {
+	if err = chainDb.(aquadb.Compacter).Compact(nil, nil); err != nil {
 		Fatalf("Compaction failed: %v", err)
 	}
 	fmt.Printf("Compaction done in %v.\n\n", time.Since(start))
@@ -367,6 +427,132 @@ func copyDb(ctx context.Context, cmd *cli.Command) error {
 	return nil
 }
 
+func convertDB(ctx context.Context, cmd *cli.Command) error {
+	engine := cmd.String(aquaflags.DBEngineFlag.Name)
+	if engine == "" {
+		Fatalf("The target backend must be given with --%s", aquaflags.DBEngineFlag.Name)
+	}
+	stack, _ := MakeConfigNode(ctx, cmd, gitCommit, clientIdentifier, mainctxs.MainCancelCause())
+
+	dbdir := stack.ResolvePath("chaindata")
+	if dbdir2, err := os.Readlink(dbdir); err == nil {
+		dbdir = dbdir2 // resolve symlink
+	}
+	backend, err := aquadb.DiskBackend(dbdir)
+	switch {
+	case err != nil:
+		Fatalf("Could not read database backend: %v", err)
+	case backend == "":
+		Fatalf("No database found at %s", dbdir)
+	case backend == engine:
+		Fatalf("Database %s already uses the %s backend", dbdir, engine)
+	}
+	var (
+		tmpdir  = dbdir + "." + engine + ".tmp"
+		bakdir  = dbdir + "." + backend + ".bak"
+		cache   = int(cmd.Int(aquaflags.CacheFlag.Name)) / 2
+		handles = makeDatabaseHandles() / 2
+	)
+	for _, dir := range []string{tmpdir, bakdir} {
+		if _, err := os.Stat(dir); err == nil {
+			Fatalf("Directory %s is in the way, remove it first", dir)
+		}
+	}
+	src, err := aquadb.Open(backend, dbdir, cache, handles)
+	if err != nil {
+		Fatalf("Could not open database: %v", err)
+	}
+	dst, err := aquadb.Open(engine, tmpdir, cache, handles)
+	if err != nil {
+		Fatalf("Could not create database: %v", err)
+	}
+	log.Info("Converting database", "path", dbdir, "from", backend, "to", engine)
+	var (
+		start  = time.Now()
+		logged = time.Now()
+	)
+	keys, err := aquadb.Copy(dst, src.(aquadb.Iteratee), func(keys int, last []byte) {
+		if time.Since(logged) > 8*time.Second {
+			log.Info("Converting database", "keys", keys, "last", common.ToHex(last), "elapsed", common.PrettyDuration(time.Since(start)))
+			logged = time.Now()
+		}
+	})
+	src.Close()
+	dst.Close()
+	if err != nil {
+		os.RemoveAll(tmpdir)
+		Fatalf("Database conversion failed: %v", err)
+	}
+	// The ancient store is independent of the backend, so it moves along
+	if _, err := os.Stat(filepath.Join(dbdir, "ancient")); err == nil {
+		if err := os.Rename(filepath.Join(dbdir, "ancient"), filepath.Join(tmpdir, "ancient")); err != nil {
+			Fatalf("Could not move ancient store: %v", err)
+		}
+	}
+	if err := os.Rename(dbdir, bakdir); err != nil {
+		Fatalf("Could not move old database: %v", err)
+	}
+	if err := os.Rename(tmpdir, dbdir); err != nil {
+		Fatalf("Could not move new database: %v", err)
+	}
+	log.Info("Database converted", "keys", keys, "backend", engine, "backup", bakdir, "elapsed", common.PrettyDuration(time.Since(start)))
+	return nil
+}
+
+func pruneState(ctx context.Context, cmd *cli.Command) error {
+	stack, _ := MakeConfigNode(ctx, cmd, gitCommit, clientIdentifier, mainctxs.MainCancelCause())
+	chainDb := MakeChainDatabase(cmd, stack, false)
+	defer chainDb.Close()
+
+	head := core.GetHeadBlockHash(chainDb)
+	if head == (common.Hash{}) {
+		Fatalf("No head block, nothing to prune")
+	}
+	number := core.GetBlockNumber(chainDb, head)
+	if cmd.Args().Len() > 0 {
+		n, err := strconv.ParseUint(cmd.Args().First(), 10, 64)
+		if err != nil {
+			Fatalf("Invalid block number %q: %v", cmd.Args().First(), err)
+		}
+		if n > number {
+			Fatalf("Block #%d is above the head block #%d", n, number)
+		}
+		number = n
+	}
+	header := core.GetHeaderNoVersion(chainDb, core.GetCanonicalHash(chainDb, number), number)
+	genesis := core.GetHeaderNoVersion(chainDb, core.GetCanonicalHash(chainDb, 0), 0)
+	if header == nil || genesis == nil {
+		Fatalf("Block #%d not found", number)
+	}
+	if _, err := state.New(header.Root, state.NewDatabase(chainDb)); err != nil {
+		Fatalf("State of block #%d is not available, choose a more recent block: %v", number, err)
+	}
+	dryRun := cmd.Bool(aquaflags.DryRunFlag.Name)
+	if !dryRun && number < core.GetBlockNumber(chainDb, head) {
+		log.Warn("Pruning below the head block, the chain will rewind", "block", number)
+	}
+	start := time.Now()
+	stats, err := state.Prune(chainDb, []common.Hash{header.Root, genesis.Root}, dryRun)
+	if err != nil {
+		Fatalf("State pruning failed: %v", err)
+	}
+	if dryRun {
+		fmt.Printf("Keeping %d state entries of block #%d, %d unreachable entries of %v could be deleted.\n", stats.Reachable, number, stats.Unreachable, stats.Reclaimable)
+		return nil
+	}
+	fmt.Printf("Kept %d state entries of block #%d, deleted %d unreachable entries of %v in %v.\n", stats.Reachable, number, stats.Unreachable, stats.Reclaimable, common.PrettyDuration(time.Since(start)))
+
+	if compacter, ok := chainDb.(aquadb.Compacter); ok && stats.Unreachable > 0 {
+		start = time.Now()
+		fmt.Println("Compacting entire database...")
+		if err := compacter.Compact(nil, nil); err != nil {
+			Fatalf("Compaction failed: %v", err)
+		}
+		fmt.Printf("Compaction done in %v.\n", time.Since(start))
+	}
+	return nil
+}
+
 func removeDB(ctx context.Context, cmd *cli.Command) error {
 	stack, _ := MakeConfigNode(ctx, cmd, gitCommit, clientIdentifier, mainctxs.MainCancelCause())
 
@@ -398,6 +584,15 @@ func removeDB(ctx context.Context, cmd *cli.Command) error {
 			Fatalf("Failed to remove database: %v", err)
 			return nil
 		}
+		// Blocks in the ancient store must go with the database, they can't
+		// be used without it
+		ancient := filepath.Join(dbdir, "ancient")
+		if cmd.IsSet(aquaflags.AncientFlag.Name) {
+			ancient = stack.ResolvePath(cmd.String(aquaflags.AncientFlag.Name))
+		}
+		if err := os.RemoveAll(ancient); err != nil {
+			Fatalf("Failed to remove ancient store: %v", err)
+		}
 		logger.Info("Database successfully deleted", "elapsed", common.PrettyDuration(time.Since(start)))
 	}
 
@@ -3,9 +3,16 @@ package subcommands
 import (
 	"context"
 	"fmt"
+	"os"
+	"strings"
 
 	cli "github.com/urfave/cli/v3"
+	"gitlab.com/aquachain/aquachain/aqua/accounts"
+	"gitlab.com/aquachain/aquachain/aqua/accounts/hdwallet"
 	"gitlab.com/aquachain/aquachain/crypto/mnemonics"
+	"gitlab.com/aquachain/aquachain/opt/console"
+	"gitlab.com/aquachain/aquachain/subcommands/aquaflags"
+	"gitlab.com/aquachain/aquachain/subcommands/mainctxs"
 )
 
 // accountGenerateMnemonic prints a phrase
@@ -14,3 +21,93 @@ func accountGenerateMnemonic(_ context.Context, cmd *cli.Command) error {
 	fmt.Printf("WRITE THIS DOWN: %v\n", phrase)
 	return nil
 }
+
+// accountImportMnemonic imports a mnemonic phrase into a new HD wallet.
+func accountImportMnemonic(ctx context.Context, cmd *cli.Command) error {
+	var phrase string
+	if file := cmd.Args().First(); file != "" {
+		blob, err := os.ReadFile(file)
+		if err != nil {
+			Fatalf("Failed to read the mnemonic: %v", err)
+		}
+		phrase = string(blob)
+	} else {
+		var err error
+		if phrase, err = console.Stdin.PromptPassword("Mnemonic: "); err != nil {
+			Fatalf("Failed to read the mnemonic: %v", err)
+		}
+	}
+	phrase = strings.Join(strings.Fields(phrase), " ")
+	if !mnemonics.IsMnemonicValid(phrase) {
+		Fatalf("Invalid mnemonic")
+	}
+	var bip39Passphrase string
+	if cmd.Bool(aquaflags.BIP39PassphraseFlag.Name) {
+		var err error
+		if bip39Passphrase, err = console.Stdin.PromptPassword("BIP-39 passphrase: "); err != nil {
+			Fatalf("Failed to read the BIP-39 passphrase: %v", err)
+		}
+	}
+	stack, _ := MakeConfigNode(ctx, cmd, gitCommit, clientIdentifier, mainctxs.MainCancelCause())
+	passphrase := getPassPhrase("Your new HD wallet is locked with a password. Please give a password. Do not forget this password.", true, 0, MakePasswordList(cmd))
+
+	backend := stack.AccountManager().Backends(hdwallet.BackendType)[0].(*hdwallet.Backend)
+	wallet, account, err := backend.Import(phrase, bip39Passphrase, passphrase)
+	if err != nil {
+		Fatalf("Could not import the mnemonic: %v", err)
+	}
+	fmt.Printf("Wallet: %s\n", wallet.URL())
+	fmt.Printf("Address: {0x%x}\n", account.Address)
+	return nil
+}
+
+// accountDerive derives and pins accounts of an HD wallet.
+func accountDerive(ctx context.Context, cmd *cli.Command) error {
+	if cmd.Args().Len() == 0 {
+		Fatalf("No derivation paths specified")
+	}
+	stack, _ := MakeConfigNode(ctx, cmd, gitCommit, clientIdentifier, mainctxs.MainCancelCause())
+	wallet := findHDWallet(stack.AccountManager(), cmd.String(aquaflags.HDWalletFlag.Name))
+
+	password := getPassPhrase(fmt.Sprintf("Unlocking HD wallet %s", wallet.URL()), false, 0, MakePasswordList(cmd))
+	if err := wallet.Open(password); err != nil {
+		Fatalf("Failed to open the HD wallet: %v", err)
+	}
+	defer wallet.Close()
+
+	for _, arg := range cmd.Args().Slice() {
+		path, err := accounts.ParseDerivationPath(arg)
+		if err != nil {
+			Fatalf("Invalid derivation path %q: %v", arg, err)
+		}
+		account, err := wallet.Derive(path, true)
+		if err != nil {
+			Fatalf("Failed to derive %s: %v", path, err)
+		}
+		fmt.Printf("Address: {0x%x} %s\n", account.Address, path)
+	}
+	return nil
+}
+
+// findHDWallet returns the HD wallet with the given URL, or the first one if no
+// URL is given.
+func findHDWallet(am *accounts.Manager, url string) accounts.Wallet {
+	backends := am.Backends(hdwallet.BackendType)
+	if len(backends) == 0 {
+		Fatalf("HD wallets are not supported")
+	}
+	wallets := backends[0].Wallets()
+	if len(wallets) == 0 {
+		Fatalf("No HD wallets found, import one with 'account import-mnemonic'")
+	}
+	if url == "" {
+		return wallets[0]
+	}
+	for _, wallet := range wallets {
+		if wallet.URL().String() == url || wallet.URL().Path == url {
+			return wallet
+		}
+	}
+	Fatalf("HD wallet %s not found", url)
+	return nil
+}
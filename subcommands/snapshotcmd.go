@@ -0,0 +1,128 @@
+// Copyright 2018 The aquachain Authors
+// This file is part of aquachain.
+//
+// aquachain is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// aquachain is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with aquachain. If not, see <http://www.gnu.org/licenses/>.
+
+package subcommands
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/urfave/cli/v3"
+	"gitlab.com/aquachain/aquachain/aquadb"
+	"gitlab.com/aquachain/aquachain/common"
+	"gitlab.com/aquachain/aquachain/common/hexutil"
+	"gitlab.com/aquachain/aquachain/core"
+	"gitlab.com/aquachain/aquachain/core/state/snapshot"
+	"gitlab.com/aquachain/aquachain/subcommands/aquaflags"
+	"gitlab.com/aquachain/aquachain/subcommands/mainctxs"
+	"gitlab.com/aquachain/aquachain/trie"
+)
+
+var snapshotCommand = &cli.Command{
+	Name:     "snapshot",
+	Usage:    "Manage the flat state snapshot",
+	Category: "BLOCKCHAIN COMMANDS",
+	Description: `
+The flat state snapshot (enabled with --snapshot) keeps every account and
+storage slot of a recent state keyed by hash, so that reads skip the state trie.
+These commands work on the snapshot of a stopped node.`,
+	Commands: []*cli.Command{
+		{
+			Name:      "verify",
+			Usage:     "Compare the snapshot against the state trie",
+			ArgsUsage: "[<root>]",
+			Action:    MigrateFlags(verifySnapshot),
+			Flags: []cli.Flag{
+				aquaflags.DataDirFlag,
+				aquaflags.CacheFlag,
+			},
+			Description: `
+    aquachain snapshot verify [<root>]
+
+Checks every account and storage slot of the snapshot against the state trie
+of the given state root (default: the state of the head block), reporting the
+first difference.`,
+		},
+		{
+			Name:      "regenerate",
+			Usage:     "Rebuild the snapshot from the state trie",
+			ArgsUsage: "[<root>]",
+			Action:    MigrateFlags(regenerateSnapshot),
+			Flags: []cli.Flag{
+				aquaflags.DataDirFlag,
+				aquaflags.CacheFlag,
+			},
+			Description: `
+    aquachain snapshot regenerate [<root>]
+
+Discards the snapshot and generates it again from the state trie of the given
+state root (default: the state of the head block). A running node does the
+same in the background when it finds no usable snapshot.`,
+		},
+	},
+}
+
+// snapshotRoot returns the state root given on the command line, or the one of
+// the head block.
+func snapshotRoot(cmd *cli.Command, chainDb aquadb.Database) common.Hash {
+	if cmd.Args().Len() > 0 {
+		root, err := hexutil.Decode(cmd.Args().First())
+		if err != nil || len(root) != common.HashLength {
+			Fatalf("Invalid state root %q", cmd.Args().First())
+		}
+		return common.BytesToHash(root)
+	}
+	head := core.GetHeadBlockHash(chainDb)
+	if head == (common.Hash{}) {
+		Fatalf("No head block")
+	}
+	number := core.GetBlockNumber(chainDb, head)
+	header := core.GetHeaderNoVersion(chainDb, core.GetCanonicalHash(chainDb, number), number)
+	if header == nil {
+		Fatalf("Head block #%d not found", number)
+	}
+	return header.Root
+}
+
+func verifySnapshot(ctx context.Context, cmd *cli.Command) error {
+	stack, _ := MakeConfigNode(ctx, cmd, gitCommit, clientIdentifier, mainctxs.MainCancelCause())
+	chainDb := MakeChainDatabase(cmd, stack, false)
+	defer chainDb.Close()
+
+	root := snapshotRoot(cmd, chainDb)
+	start := time.Now()
+	accounts, slots, err := snapshot.Verify(chainDb, trie.NewDatabase(chainDb), root)
+	if err != nil {
+		Fatalf("Snapshot of state %x is inconsistent: %v", root, err)
+	}
+	fmt.Printf("Snapshot of state %x is consistent: %d accounts, %d storage slots verified in %v.\n", root, accounts, slots, common.PrettyDuration(time.Since(start)))
+	return nil
+}
+
+func regenerateSnapshot(ctx context.Context, cmd *cli.Command) error {
+	stack, _ := MakeConfigNode(ctx, cmd, gitCommit, clientIdentifier, mainctxs.MainCancelCause())
+	chainDb := MakeChainDatabase(cmd, stack, false)
+	defer chainDb.Close()
+
+	root := snapshotRoot(cmd, chainDb)
+	start := time.Now()
+	if err := snapshot.Regenerate(chainDb, trie.NewDatabase(chainDb), root); err != nil {
+		Fatalf("Snapshot generation failed: %v", err)
+	}
+	fmt.Printf("Generated snapshot of state %x in %v.\n", root, common.PrettyDuration(time.Since(start)))
+	return nil
+}
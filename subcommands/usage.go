@@ -77,11 +77,17 @@ var AppHelpFlagGroups = []flagGroup{
 			aquaflags.ConfigFileFlag,
 			aquaflags.DataDirFlag,
 			aquaflags.KeyStoreDirFlag,
+			aquaflags.KeyStoreKDFFlag,
+			aquaflags.KeyStoreArgon2MemoryFlag,
+			aquaflags.KeyStoreArgon2TimeFlag,
 			aquaflags.UseUSBFlag,
+			aquaflags.ExternalSignerFlag,
 
 			aquaflags.SyncModeFlag,
 			aquaflags.ChainFlag,
 			aquaflags.GCModeFlag,
+			aquaflags.AncientFlag,
+			aquaflags.AncientThresholdFlag,
 			aquaflags.AquaStatsURLFlag,
 			aquaflags.IdentityFlag,
 			aquaflags.HF8MainnetFlag,
@@ -121,6 +127,11 @@ var AppHelpFlagGroups = []flagGroup{
 			aquaflags.TxPoolAccountQueueFlag,
 			aquaflags.TxPoolGlobalQueueFlag,
 			aquaflags.TxPoolLifetimeFlag,
+			aquaflags.TxPoolPriorityFlag,
+			aquaflags.TxPoolRateLimitFlag,
+			aquaflags.TxPoolRatePeriodFlag,
+			aquaflags.TxPoolGlobalBytesFlag,
+			aquaflags.TxPoolEvictionFlag,
 		},
 	},
 	{
@@ -128,8 +139,10 @@ var AppHelpFlagGroups = []flagGroup{
 		Flags: []cli.Flag{
 			aquaflags.CacheFlag,
 			aquaflags.CacheDatabaseFlag,
+			aquaflags.DBEngineFlag,
 			aquaflags.CacheGCFlag,
 			aquaflags.TrieCacheGenFlag,
+			aquaflags.SnapshotFlag,
 		},
 	},
 	{
@@ -151,6 +164,11 @@ var AppHelpFlagGroups = []flagGroup{
 			aquaflags.WSPortFlag,
 			aquaflags.WSApiFlag,
 			aquaflags.WSAllowedOriginsFlag,
+			aquaflags.BtcRPCEnabledFlag,
+			aquaflags.BtcRPCListenAddrFlag,
+			aquaflags.BtcRPCPortFlag,
+			aquaflags.BtcRPCUserFlag,
+			aquaflags.BtcRPCPasswordFlag,
 			aquaflags.IPCDisabledFlag,
 			aquaflags.IPCPathFlag,
 			aquaflags.RPCCORSDomainFlag,
@@ -184,6 +202,9 @@ var AppHelpFlagGroups = []flagGroup{
 			aquaflags.TargetGasLimitFlag,
 			aquaflags.GasPriceFlag,
 			aquaflags.ExtraDataFlag,
+			aquaflags.StratumAddrFlag,
+			aquaflags.StratumDiffFlag,
+			aquaflags.StratumMinDiffFlag,
 		},
 	},
 	{
@@ -203,6 +224,17 @@ var AppHelpFlagGroups = []flagGroup{
 		Name: "LOGGING AND DEBUGGING",
 		Flags: append([]cli.Flag{
 			aquaflags.MetricsEnabledFlag,
+			aquaflags.MetricsEnableInfluxDBFlag,
+			aquaflags.MetricsEnableInfluxDBV2Flag,
+			aquaflags.MetricsInfluxDBEndpointFlag,
+			aquaflags.MetricsInfluxDBIntervalFlag,
+			aquaflags.MetricsInfluxDBTagsFlag,
+			aquaflags.MetricsInfluxDBDatabaseFlag,
+			aquaflags.MetricsInfluxDBUsernameFlag,
+			aquaflags.MetricsInfluxDBPasswordFlag,
+			aquaflags.MetricsInfluxDBTokenFlag,
+			aquaflags.MetricsInfluxDBBucketFlag,
+			aquaflags.MetricsInfluxDBOrganizationFlag,
 			aquaflags.FakePoWFlag,
 			aquaflags.NoCompactionFlag,
 		}, debug.Flags...),
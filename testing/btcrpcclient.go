@@ -33,9 +33,9 @@ import (
 func main() {
 	// Connect to local bitcoin core RPC server using HTTP POST mode.
 	connCfg := &rpcclient.ConnConfig{
-		Host: "localhost:8543",
-		//		User:         "yourrpcuser",
-		//		Pass:         "yourrpcpass",
+		Host:         "localhost:8542", // aquachain -btcrpc -btcrpcuser yourrpcuser -btcrpcpassword yourrpcpass
+		User:         "yourrpcuser",
+		Pass:         "yourrpcpass",
 		HTTPPostMode: true, // Bitcoin core only supports HTTP POST mode
 		DisableTLS:   true, // Bitcoin core does not provide TLS by default
 	}